@@ -0,0 +1,159 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// CaptureExclusionInterface is the typed client of the CaptureExclusion resources of a namespace.
+type CaptureExclusionInterface interface {
+	Create(ctx context.Context, object *CaptureExclusion, opts metav1.CreateOptions) (*CaptureExclusion, error)
+	Update(ctx context.Context, object *CaptureExclusion, opts metav1.UpdateOptions) (*CaptureExclusion, error)
+	UpdateStatus(ctx context.Context, object *CaptureExclusion, opts metav1.UpdateOptions) (*CaptureExclusion, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions,
+		subresources ...string) (*CaptureExclusion, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*CaptureExclusion, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*CaptureExclusionList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// CaptureExclusions returns the typed client of the CaptureExclusion resources of a namespace, or of all the
+// namespaces if namespace is empty.
+func CaptureExclusions(client dynamic.Interface, namespace string) CaptureExclusionInterface {
+	return captureExclusions{client: client.Resource(CaptureExclusionGVR).Namespace(namespace)}
+}
+
+// captureExclusions converts the objects of the dynamic client from and to CaptureExclusion.
+type captureExclusions struct {
+	client dynamic.ResourceInterface
+}
+
+func (c captureExclusions) Create(ctx context.Context, object *CaptureExclusion, opts metav1.CreateOptions) (*CaptureExclusion, error) {
+	u, err := toUnstructured(object)
+	if err != nil {
+		return nil, err
+	}
+	return fromUnstructured(c.client.Create(ctx, u, opts))
+}
+
+func (c captureExclusions) Update(ctx context.Context, object *CaptureExclusion, opts metav1.UpdateOptions) (*CaptureExclusion, error) {
+	u, err := toUnstructured(object)
+	if err != nil {
+		return nil, err
+	}
+	return fromUnstructured(c.client.Update(ctx, u, opts))
+}
+
+func (c captureExclusions) UpdateStatus(ctx context.Context, object *CaptureExclusion, opts metav1.UpdateOptions) (*CaptureExclusion, error) {
+	u, err := toUnstructured(object)
+	if err != nil {
+		return nil, err
+	}
+	return fromUnstructured(c.client.UpdateStatus(ctx, u, opts))
+}
+
+func (c captureExclusions) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions,
+	subresources ...string,
+) (*CaptureExclusion, error) {
+	return fromUnstructured(c.client.Patch(ctx, name, pt, data, opts, subresources...))
+}
+
+func (c captureExclusions) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete(ctx, name, opts)
+}
+
+func (c captureExclusions) Get(ctx context.Context, name string, opts metav1.GetOptions) (*CaptureExclusion, error) {
+	return fromUnstructured(c.client.Get(ctx, name, opts))
+}
+
+func (c captureExclusions) List(ctx context.Context, opts metav1.ListOptions) (*CaptureExclusionList, error) {
+	ul, err := c.client.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	list := &CaptureExclusionList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion:    ul.GetResourceVersion(),
+			Continue:           ul.GetContinue(),
+			RemainingItemCount: ul.GetRemainingItemCount(),
+		},
+		Items: make([]CaptureExclusion, 0, len(ul.Items)),
+	}
+	for i := range ul.Items {
+		e, err := fromUnstructured(&ul.Items[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, *e)
+	}
+	list.SetGroupVersionKind(SchemeGroupVersion.WithKind("CaptureExclusionList"))
+	return list, nil
+}
+
+func (c captureExclusions) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	w, err := c.client.Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+		u, ok := in.Object.(*unstructured.Unstructured)
+		if !ok {
+			// Error and bookmark events are passed through.
+			return in, true
+		}
+		e, err := fromUnstructured(u, nil)
+		if err != nil {
+			return watch.Event{Type: watch.Error, Object: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Reason:  metav1.StatusReasonInvalid,
+				Message: err.Error(),
+			}}, true
+		}
+		in.Object = e
+		return in, true
+	}), nil
+}
+
+func toUnstructured(object *CaptureExclusion) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CaptureExclusion %s/%s: %v", object.Namespace, object.Name, err)
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(CaptureExclusionGVK)
+	return u, nil
+}
+
+func fromUnstructured(u *unstructured.Unstructured, err error) (*CaptureExclusion, error) {
+	if err != nil {
+		return nil, err
+	}
+	object := &CaptureExclusion{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), object); err != nil {
+		return nil, fmt.Errorf("invalid CaptureExclusion %s/%s: %v", u.GetNamespace(), u.GetName(), err)
+	}
+	object.SetGroupVersionKind(CaptureExclusionGVK)
+	return object, nil
+}
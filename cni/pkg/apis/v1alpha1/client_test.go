@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestCaptureExclusionsClient(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, AddToScheme(scheme))
+	client := CaptureExclusions(dynamicfake.NewSimpleDynamicClient(scheme), "test")
+
+	created, err := client.Create(ctx, &CaptureExclusion{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy-db", Namespace: "test"},
+		Spec: CaptureExclusionSpec{
+			Selector:             &metav1.LabelSelector{MatchLabels: map[string]string{"app": "reporting"}},
+			ExcludeOutboundPorts: []uint16{5432},
+			ExcludeOutboundUIDs:  []int64{1001},
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, created.GroupVersionKind(), CaptureExclusionGVK)
+	assert.Equal(t, created.Spec.ExcludeOutboundPorts, []uint16{5432})
+
+	created.Status.Conditions = []metav1.Condition{{
+		Type:   ConditionAccepted,
+		Status: metav1.ConditionTrue,
+		Reason: ReasonAccepted,
+	}}
+	_, err = client.UpdateStatus(ctx, created, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	list, err := client.List(ctx, metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, len(list.Items), 1)
+	got := list.Items[0]
+	assert.Equal(t, got.Spec.Selector.MatchLabels, map[string]string{"app": "reporting"})
+	assert.Equal(t, got.Spec.ExcludeOutboundUIDs, []int64{1001})
+	assert.Equal(t, got.Status.Conditions[0].Reason, ReasonAccepted)
+
+	assert.NoError(t, client.Delete(ctx, "legacy-db", metav1.DeleteOptions{}))
+	_, err = client.Get(ctx, "legacy-db", metav1.GetOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto copies the receiver into out.
+func (in *CaptureExclusion) DeepCopyInto(out *CaptureExclusion) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy copies the receiver into a new CaptureExclusion.
+func (in *CaptureExclusion) DeepCopy() *CaptureExclusion {
+	if in == nil {
+		return nil
+	}
+	out := new(CaptureExclusion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject copies the receiver into a new runtime.Object.
+func (in *CaptureExclusion) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *CaptureExclusionSpec) DeepCopyInto(out *CaptureExclusionSpec) {
+	*out = *in
+	if in.Selector != nil {
+		out.Selector = in.Selector.DeepCopy()
+	}
+	out.ExcludeInboundPorts = slices.Clone(in.ExcludeInboundPorts)
+	out.ExcludeOutboundPorts = slices.Clone(in.ExcludeOutboundPorts)
+	out.ExcludeOutboundIPRanges = slices.Clone(in.ExcludeOutboundIPRanges)
+	out.ExcludeOutboundUIDs = slices.Clone(in.ExcludeOutboundUIDs)
+}
+
+// DeepCopy copies the receiver into a new CaptureExclusionSpec.
+func (in *CaptureExclusionSpec) DeepCopy() *CaptureExclusionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CaptureExclusionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *CaptureExclusionStatus) DeepCopyInto(out *CaptureExclusionStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(in.Conditions))
+		for i := range in.Conditions {
+			in.Conditions[i].DeepCopyInto(&out.Conditions[i])
+		}
+	}
+}
+
+// DeepCopy copies the receiver into a new CaptureExclusionStatus.
+func (in *CaptureExclusionStatus) DeepCopy() *CaptureExclusionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CaptureExclusionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *CaptureExclusionList) DeepCopyInto(out *CaptureExclusionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]CaptureExclusion, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy copies the receiver into a new CaptureExclusionList.
+func (in *CaptureExclusionList) DeepCopy() *CaptureExclusionList {
+	if in == nil {
+		return nil
+	}
+	out := new(CaptureExclusionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject copies the receiver into a new runtime.Object.
+func (in *CaptureExclusionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v1alpha1 contains the API of the cni.istio.io/v1alpha1 group, served by the CustomResourceDefinitions
+// of manifests/charts/base/files/crd-cni.yaml.
+// +k8s:deepcopy-gen=package,register
+// +groupName=cni.istio.io
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupName is the group of the API.
+const GroupName = "cni.istio.io"
+
+// SchemeGroupVersion is the group version of the API.
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+var (
+	// CaptureExclusionGVK is the GVK of CaptureExclusion.
+	CaptureExclusionGVK = SchemeGroupVersion.WithKind("CaptureExclusion")
+	// CaptureExclusionGVR is the GVR of CaptureExclusion.
+	CaptureExclusionGVR = SchemeGroupVersion.WithResource("captureexclusions")
+)
+
+var (
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	// AddToScheme adds the types of the API to a scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&CaptureExclusion{},
+		&CaptureExclusionList{},
+	)
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+	return nil
+}
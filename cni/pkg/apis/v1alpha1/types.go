@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CaptureExclusion excludes some traffic of the ambient pods it selects, in its own namespace, from the capture by
+// ztunnel. It is only honored by the node agent when AMBIENT_CAPTURE_EXCLUSIONS is set.
+//
+//	apiVersion: cni.istio.io/v1alpha1
+//	kind: CaptureExclusion
+//	metadata:
+//	  name: legacy-db
+//	  namespace: reporting
+//	spec:
+//	  selector:
+//	    matchLabels:
+//	      app: reporting
+//	  excludeInboundPorts: [8080]
+//	  excludeOutboundPorts: [5432]
+//	  excludeOutboundIPRanges: ["10.10.0.0/16"]
+//	  excludeOutboundUIDs: [1001]
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type CaptureExclusion struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CaptureExclusionSpec `json:"spec,omitempty"`
+	// +optional
+	Status CaptureExclusionStatus `json:"status,omitempty"`
+}
+
+// CaptureExclusionSpec is the traffic of the selected pods excluded from the capture by ztunnel.
+type CaptureExclusionSpec struct {
+	// Selector selects the pods of the namespace the exclusions apply to. All the pods of the namespace if unset.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// ExcludeInboundPorts are the inbound TCP ports not redirected to ztunnel.
+	// +optional
+	ExcludeInboundPorts []uint16 `json:"excludeInboundPorts,omitempty"`
+	// ExcludeOutboundPorts are the outbound TCP destination ports not redirected to ztunnel.
+	// +optional
+	ExcludeOutboundPorts []uint16 `json:"excludeOutboundPorts,omitempty"`
+	// ExcludeOutboundIPRanges are the outbound destination IP ranges, in CIDR notation, not redirected to ztunnel.
+	// Plain addresses are accepted as single host ranges.
+	// +optional
+	ExcludeOutboundIPRanges []string `json:"excludeOutboundIPRanges,omitempty"`
+	// ExcludeOutboundUIDs are the UIDs whose outbound traffic is not redirected to ztunnel.
+	// +optional
+	ExcludeOutboundUIDs []int64 `json:"excludeOutboundUIDs,omitempty"`
+}
+
+// CaptureExclusionStatus is the status of a CaptureExclusion, as reported by the node agents.
+type CaptureExclusionStatus struct {
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionAccepted is the condition telling whether the node agents apply a CaptureExclusion.
+	ConditionAccepted = "Accepted"
+
+	// ReasonAccepted means the CaptureExclusion is applied to the pods it selects.
+	ReasonAccepted = "Accepted"
+	// ReasonInvalid means the CaptureExclusion is ignored, as its spec is invalid.
+	ReasonInvalid = "Invalid"
+)
+
+// CaptureExclusionList is a list of CaptureExclusion resources.
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type CaptureExclusionList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CaptureExclusion `json:"items"`
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -91,9 +92,14 @@ var rootCmd = &cobra.Command{
 		// Creates a basic health endpoint server that reports health status
 		// based on atomic flag, as set by installer
 		// TODO nodeagent watch server should affect this too, and drop atomic flag
-		installDaemonReady, watchServerReady := nodeagent.StartHealthServer()
+		installDaemonReady, watchServerReady, debugServer := nodeagent.StartHealthServer()
 
 		installer := install.NewInstaller(&cfg.InstallConfig, installDaemonReady)
+		if cfg.InstallConfig.ChainedCNIPlugin && cfg.InstallConfig.CNICompatibilityCheck != install.CompatibilityCheckOff {
+			if err := installer.EnableCompatibilityClient(); err != nil {
+				log.Warnf("CNI compatibility check will not use the cluster: %v", err)
+			}
+		}
 
 		if cfg.InstallConfig.AmbientEnabled {
 			// Start ambient controller
@@ -128,11 +134,18 @@ var rootCmd = &cobra.Command{
 					EnableIPv6:                 cfg.InstallConfig.AmbientIPv6,
 					ReconcilePodRulesOnStartup: cfg.InstallConfig.AmbientReconcilePodRulesOnStartup,
 					NativeNftables:             cfg.InstallConfig.NativeNftables,
+					EbpfRedirect:               cfg.InstallConfig.AmbientEbpfRedirect,
+					InpodRulesAuditInterval:    cfg.InstallConfig.AmbientInpodRulesAuditInterval,
+					InpodRulesAuditRepair:      cfg.InstallConfig.AmbientInpodRulesAuditRepair,
+					ZDSRecordFile:              cfg.InstallConfig.AmbientZDSRecordFile,
+					CaptureExclusions:          cfg.InstallConfig.AmbientCaptureExclusions,
 					ForceIptablesBinary:        cfg.InstallConfig.ForceIptablesBinary,
+					CNIConfigCheck:             installer.CheckCNIConfig,
 				})
 			if err != nil {
 				return fmt.Errorf("failed to create ambient nodeagent service: %v", err)
 			}
+			debugServer.Store(ambientAgent)
 
 			// Ambient watch server IS enabled - on shutdown
 			// we need to check and see if this is an upgrade.
@@ -227,6 +240,8 @@ func init() {
 	registerBooleanParameter(constants.ChainedCNIPlugin, true, "Whether to install CNI plugin as a chained or standalone")
 	registerStringParameter(constants.CNINetworkConfig, "", "CNI configuration template as a string")
 	registerStringParameter(constants.IstioOwnedCNIConfigFilename, "", "Filename for Istio owned CNI configuration")
+	registerStringParameter(constants.CNICompatibilityCheck, install.CompatibilityCheckWarn,
+		"Whether the primary CNI is checked for compatibility with Istio: enforce (refuse known incompatible CNIs), warn or off")
 	registerBooleanParameter(constants.IstioOwnedCNIConfig, false, "Whether an Istio owned CNI configuration is enabled")
 	registerStringParameter(constants.LogLevel, "warn", "Fallback value for log level in CNI config file, if not specified in helm template")
 
@@ -242,6 +257,16 @@ func init() {
 	registerStringParameter(constants.ZtunnelUDSAddress, "/var/run/ztunnel/ztunnel.sock", "The UDS server address which ztunnel will connect to")
 	registerBooleanParameter(constants.AmbientEnabled, false, "Whether ambient controller is enabled")
 	registerBooleanParameter(constants.EnableAmbientDetectionRetry, false, "Whether or not is ambient check is retried on error in the cni plugin")
+	registerBooleanParameter(constants.AmbientEbpfRedirect, false,
+		"Whether ambient in-pod traffic redirection uses eBPF programs instead of iptables/nftables rules (experimental)")
+	registerDurationParameter(constants.AmbientInpodRulesAuditInterval, 0,
+		"How often the in-pod traffic redirection rules of ambient pods are checked for drift (disabled if 0)")
+	registerBooleanParameter(constants.AmbientInpodRulesAuditRepair, false,
+		"Whether in-pod traffic redirection rules found to have drifted are reprogrammed")
+	registerStringParameter(constants.AmbientZDSRecordFile, "",
+		"File the ZDS messages exchanged with ztunnel are recorded to, for debugging (disabled if empty)")
+	registerBooleanParameter(constants.AmbientCaptureExclusions, false,
+		"Whether CaptureExclusion resources are watched to exclude ports, IP ranges and UIDs of ambient pods from traffic capture")
 	// Repair
 	registerBooleanParameter(constants.RepairEnabled, true, "Whether to enable race condition repair or not")
 	registerBooleanParameter(constants.RepairDeletePods, false, "Controller will delete pods when detecting pod broken by race condition")
@@ -264,6 +289,13 @@ func init() {
 		"A set of label selectors in label=value format that will be added to the pod list filters")
 	registerStringParameter(constants.RepairFieldSelectors, "",
 		"A set of field selectors in label=value format that will be added to the pod list filters")
+	registerStringParameter(constants.RepairRulesMigration, "",
+		"Backend (iptables or nftables) the redirection rules of running sidecar pods are migrated to. "+
+			"Migrating to iptables rolls back a migration to nftables. Disabled if empty")
+	registerStringParameter(constants.RepairMigrationNamespaces, "",
+		"Comma separated list of namespaces whose pods are migrated by the rules migration (all namespaces if empty)")
+	registerBooleanParameter(constants.RepairMigrationDryRun, false,
+		"Whether the rules migration only reports the pods it would migrate, without changing their rules")
 }
 
 func registerStringParameter(name, value, usage string) {
@@ -281,6 +313,11 @@ func registerBooleanParameter(name string, value bool, usage string) {
 	registerEnvironment(name, value, usage)
 }
 
+func registerDurationParameter(name string, value time.Duration, usage string) {
+	rootCmd.Flags().Duration(name, value, usage)
+	registerEnvironment(name, value, usage)
+}
+
 func registerEnvironment[T env.Parseable](name string, defaultValue T, usage string) {
 	envName := strings.Replace(strings.ToUpper(name), "-", "_", -1)
 	// Note: we do not rely on istio env package to retrieve configuration. We relies on viper.
@@ -304,6 +341,7 @@ func constructConfig() (*config.Config, error) {
 		CNIAgentRunDir:              viper.GetString(constants.CNIAgentRunDir),
 		IstioOwnedCNIConfigFilename: viper.GetString(constants.IstioOwnedCNIConfigFilename),
 		IstioOwnedCNIConfig:         viper.GetBool(constants.IstioOwnedCNIConfig),
+		CNICompatibilityCheck:       viper.GetString(constants.CNICompatibilityCheck),
 
 		// Whatever user has set (with --log_output_level) for 'cni-plugin', pass it down to the plugin. It will use this to determine
 		// what level to use for itself.
@@ -334,6 +372,11 @@ func constructConfig() (*config.Config, error) {
 		AmbientDisableSafeUpgrade:         viper.GetBool(constants.AmbientDisableSafeUpgrade),
 		AmbientReconcilePodRulesOnStartup: viper.GetBool(constants.AmbientReconcilePodRulesOnStartup),
 		EnableAmbientDetectionRetry:       viper.GetBool(constants.EnableAmbientDetectionRetry),
+		AmbientEbpfRedirect:               viper.GetBool(constants.AmbientEbpfRedirect),
+		AmbientInpodRulesAuditInterval:    viper.GetDuration(constants.AmbientInpodRulesAuditInterval),
+		AmbientInpodRulesAuditRepair:      viper.GetBool(constants.AmbientInpodRulesAuditRepair),
+		AmbientZDSRecordFile:              viper.GetString(constants.AmbientZDSRecordFile),
+		AmbientCaptureExclusions:          viper.GetBool(constants.AmbientCaptureExclusions),
 
 		NativeNftables:      viper.GetBool(constants.NativeNftables),
 		ForceIptablesBinary: os.Getenv("FORCE_IPTABLES_BINARY"),
@@ -347,6 +390,10 @@ func constructConfig() (*config.Config, error) {
 		}
 	}
 
+	if err := install.ValidateCompatibilityCheck(installCfg.CNICompatibilityCheck); err != nil {
+		return nil, err
+	}
+
 	if installCfg.IstioOwnedCNIConfig && len(installCfg.IstioOwnedCNIConfigFilename) == 0 {
 		// If Istio owned CNI config is enabled, but no filename is specified, use the default one.
 		// The filename is not set to the default value if Istio owned CNI config is not enabled.
@@ -367,6 +414,9 @@ func constructConfig() (*config.Config, error) {
 		InitExitCode:        viper.GetInt(constants.RepairInitExitCode),
 		LabelSelectors:      viper.GetString(constants.RepairLabelSelectors),
 		FieldSelectors:      viper.GetString(constants.RepairFieldSelectors),
+		RulesMigration:      viper.GetString(constants.RepairRulesMigration),
+		MigrationNamespaces: viper.GetString(constants.RepairMigrationNamespaces),
+		MigrationDryRun:     viper.GetBool(constants.RepairMigrationDryRun),
 		NativeNftables:      viper.GetBool(constants.NativeNftables),
 		ForceIptablesBinary: os.Getenv("FORCE_IPTABLES_BINARY"),
 	}
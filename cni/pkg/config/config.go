@@ -19,6 +19,7 @@ import (
 	"net/netip"
 	"os"
 	"strings"
+	"time"
 
 	"istio.io/istio/cni/pkg/constants"
 	cfg "istio.io/istio/tools/common/config"
@@ -57,6 +58,13 @@ type PodLevelOverrides struct {
 	VirtualInterfaces []string
 	IngressMode       bool
 	DNSProxy          PodDNSOverride
+
+	// Traffic excluded from capture, configured with CaptureExclusion resources.
+	// Entries are sorted and unique, so the expected rules of a pod are stable.
+	ExcludeInboundPorts     []uint16
+	ExcludeOutboundPorts    []uint16
+	ExcludeOutboundIPRanges []netip.Prefix
+	ExcludeOutboundUIDs     []int64
 }
 
 // AmbientConfig represents the "global"/per-instance configuration for Ambient mode traffic management
@@ -100,6 +108,8 @@ type InstallConfig struct {
 	IstioOwnedCNIConfigFilename string
 	// Whether an Istio owned CNI config is enabled
 	IstioOwnedCNIConfig bool
+	// Whether the primary CNI is checked for compatibility with Istio: "enforce", "warn" or "off"
+	CNICompatibilityCheck string
 
 	// Logging level for the CNI plugin
 	// Since it runs out-of-process, it has to be separately configured
@@ -163,6 +173,21 @@ type InstallConfig struct {
 	// Whether to retry checking if a pod is ambient in the cni plugin when there are errors
 	EnableAmbientDetectionRetry bool
 
+	// Whether eBPF programs should be used instead of iptables/nftables rules for in-pod traffic redirection
+	AmbientEbpfRedirect bool
+
+	// How often the in-pod traffic redirection rules of ambient pods are checked for drift, disabled if 0
+	AmbientInpodRulesAuditInterval time.Duration
+
+	// Whether in-pod traffic redirection rules found to have drifted are reprogrammed
+	AmbientInpodRulesAuditRepair bool
+
+	// File the ZDS messages exchanged with ztunnel are recorded to, for debugging. Disabled if empty
+	AmbientZDSRecordFile string
+
+	// Whether CaptureExclusion resources are watched to exclude traffic of ambient pods from capture
+	AmbientCaptureExclusions bool
+
 	// Whether native nftables should be used instead of iptable rules for traffic redirection
 	NativeNftables bool
 
@@ -210,6 +235,14 @@ type RepairConfig struct {
 	LabelSelectors string
 	FieldSelectors string
 
+	// Backend ("iptables" or "nftables") the redirection rules of running sidecar pods are migrated to, if set.
+	// Migrating to iptables rolls back a migration to nftables.
+	RulesMigration string
+	// Comma separated namespaces whose pods are migrated; all namespaces if empty.
+	MigrationNamespaces string
+	// Whether to only report the migrations that would be performed
+	MigrationDryRun bool
+
 	// Whether to repair pods by running nftables rules
 	NativeNftables bool
 
@@ -224,6 +257,7 @@ func (c InstallConfig) String() string {
 	b.WriteString("ChainedCNIPlugin: " + fmt.Sprint(c.ChainedCNIPlugin) + "\n")
 	b.WriteString("CNIAgentRunDir: " + fmt.Sprint(c.CNIAgentRunDir) + "\n")
 	b.WriteString("IstioOwnedCNIConfigFilename: " + c.IstioOwnedCNIConfigFilename + "\n")
+	b.WriteString("CNICompatibilityCheck: " + c.CNICompatibilityCheck + "\n")
 	b.WriteString("IstioOwnedCNIConfig: " + fmt.Sprint(c.IstioOwnedCNIConfig) + "\n")
 
 	b.WriteString("PluginLogLevel: " + c.PluginLogLevel + "\n")
@@ -252,6 +286,11 @@ func (c InstallConfig) String() string {
 	b.WriteString("AmbientDisableSafeUpgrade: " + fmt.Sprint(c.AmbientDisableSafeUpgrade) + "\n")
 	b.WriteString("AmbientReconcilePodRulesOnStartup: " + fmt.Sprint(c.AmbientReconcilePodRulesOnStartup) + "\n")
 	b.WriteString("EnableAmbientDetectionRetry: " + fmt.Sprint(c.EnableAmbientDetectionRetry) + "\n")
+	b.WriteString("AmbientEbpfRedirect: " + fmt.Sprint(c.AmbientEbpfRedirect) + "\n")
+	b.WriteString("AmbientInpodRulesAuditInterval: " + fmt.Sprint(c.AmbientInpodRulesAuditInterval) + "\n")
+	b.WriteString("AmbientInpodRulesAuditRepair: " + fmt.Sprint(c.AmbientInpodRulesAuditRepair) + "\n")
+	b.WriteString("AmbientZDSRecordFile: " + fmt.Sprint(c.AmbientZDSRecordFile) + "\n")
+	b.WriteString("AmbientCaptureExclusions: " + fmt.Sprint(c.AmbientCaptureExclusions) + "\n")
 
 	b.WriteString("NativeNftables: " + fmt.Sprint(c.NativeNftables) + "\n")
 	b.WriteString("ForceIptablesBinary: " + fmt.Sprint(c.ForceIptablesBinary) + "\n")
@@ -272,6 +311,9 @@ func (c RepairConfig) String() string {
 	b.WriteString("InitExitCode: " + fmt.Sprint(c.InitExitCode) + "\n")
 	b.WriteString("LabelSelectors: " + c.LabelSelectors + "\n")
 	b.WriteString("FieldSelectors: " + c.FieldSelectors + "\n")
+	b.WriteString("RulesMigration: " + c.RulesMigration + "\n")
+	b.WriteString("MigrationNamespaces: " + c.MigrationNamespaces + "\n")
+	b.WriteString("MigrationDryRun: " + fmt.Sprint(c.MigrationDryRun) + "\n")
 	b.WriteString("NativeNftables: " + fmt.Sprint(c.NativeNftables) + "\n")
 	b.WriteString("ForceIptablesBinary: " + fmt.Sprint(c.ForceIptablesBinary) + "\n")
 	return b.String()
@@ -29,6 +29,7 @@ const (
 	CNINetworkConfig                  = "cni-network-config"
 	IstioOwnedCNIConfig               = "istio-owned-cni-config"
 	IstioOwnedCNIConfigFilename       = "istio-owned-cni-config-filename"
+	CNICompatibilityCheck             = "cni-compatibility-check"
 	LogLevel                          = "log-level"
 	KubeconfigMode                    = "kubeconfig-mode"
 	CNIConfGroupRead                  = "cni-conf-group-read"
@@ -48,6 +49,11 @@ const (
 	AmbientDisableSafeUpgrade         = "ambient-disable-safe-upgrade"
 	AmbientReconcilePodRulesOnStartup = "ambient-reconcile-pod-rules-on-startup"
 	EnableAmbientDetectionRetry       = "enable-ambient-detection-retry"
+	AmbientEbpfRedirect               = "ambient-ebpf-redirect"
+	AmbientInpodRulesAuditInterval    = "ambient-inpod-rules-audit-interval"
+	AmbientInpodRulesAuditRepair      = "ambient-inpod-rules-audit-repair"
+	AmbientZDSRecordFile              = "ambient-zds-record-file"
+	AmbientCaptureExclusions          = "ambient-capture-exclusions"
 
 	NativeNftables = "native-nftables"
 
@@ -65,6 +71,11 @@ const (
 	RepairInitExitCode       = "repair-init-container-exit-code"
 	RepairLabelSelectors     = "repair-label-selectors"
 	RepairFieldSelectors     = "repair-field-selectors"
+
+	// Rules migration of running sidecar pods, run by the repair controller
+	RepairRulesMigration      = "repair-rules-migration"
+	RepairMigrationNamespaces = "repair-migration-namespaces"
+	RepairMigrationDryRun     = "repair-migration-dry-run"
 )
 
 // Internal constants
@@ -85,6 +96,7 @@ const (
 	LivenessEndpoint                   = "/healthz"
 	ReadinessEndpoint                  = "/readyz"
 	ReadinessPort                      = "8000"
+	DebugStateEndpoint                 = "/debug/state"
 	ServiceAccountPath                 = "/var/run/secrets/kubernetes.io/serviceaccount"
 	SelfNetNSPath                      = "/proc/self/ns/net"
 	DefaultIstioOwnedCNIConfigFilename = "02-istio-cni.conflist"
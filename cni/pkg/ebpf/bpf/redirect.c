@@ -0,0 +1,528 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The redirection programs are TC programs attached inside the pod network namespace. They implement the same
+// capture as the in-pod nftables rules, without netfilter and conntrack:
+//
+//   - istio_inbound: TCP SYNs arriving on the pod interfaces are assigned to the ztunnel inbound plaintext
+//     listener, except HBONE connections, the node health checks and the excluded ports.
+//   - istio_outbound: TCP packets leaving the pod interfaces without the ztunnel mark are marked and handed to the
+//     loopback interface, unless excluded from capture. The mark routes them locally, and the loopback program
+//     assigns new connections to the ztunnel outbound listener. DNS queries are rewritten to the ztunnel DNS
+//     proxy, and the original resolver is remembered in a map.
+//   - istio_loopback: assigns the redirected outbound connections, and restores the resolver address on DNS
+//     replies.
+//   - istio_virtual: TCP arriving on virtual interfaces is treated as outbound traffic, like the nftables rules do.
+//
+// Sockets are only assigned on the first SYN of a connection; the accepted socket keeps the original destination
+// as its local address, so the rest of the connection is delivered to it by the regular socket lookup.
+//
+// Packets the programs cannot parse are dropped rather than passed, as they would otherwise bypass the capture:
+// IPv6 packets with too many extension headers, truncated headers and fragmented TCP segments.
+
+#include "helpers.h"
+
+char __license[] SEC("license") = "Apache-2.0";
+
+// Settings of the pod, set by the node agent when loading the programs.
+volatile const bool enable_ipv6 = false;
+volatile const bool redirect_dns = false;
+// capture_exclusions enables the lookups in the exclusion maps.
+volatile const bool capture_exclusions = false;
+// probe_v4 and probe_v6 are the source addresses of the node health checks, which bypass ztunnel. They are all
+// zeros when unset.
+volatile const __be32 probe_v4 = 0;
+volatile const __be32 probe_v6[4] = {};
+volatile const __u32 loopback_ifindex = 0;
+
+// Ports and marks shared with the rest of the node agent.
+volatile const __u16 ztunnel_inbound_port = 0;
+volatile const __u16 ztunnel_inbound_plaintext_port = 0;
+volatile const __u16 ztunnel_outbound_port = 0;
+volatile const __u16 dns_capture_port = 0;
+volatile const __u32 inpod_mark = 0;
+volatile const __u32 inpod_mask = 0;
+volatile const __u32 tproxy_mark = 0;
+volatile const __u32 tproxy_mask = 0;
+
+#define DNS_PORT 53
+#define TCP_FLAG_SYN 0x02
+#define TCP_FLAG_ACK 0x10
+#define UDP_CSUM_OFF 6
+#define IPV4_CSUM_OFF 10
+
+// MAX_EXTENSION_HEADERS bounds the IPv6 extension headers skipped to find the transport header.
+#define MAX_EXTENSION_HEADERS 8
+
+// Directions of the excluded ports, the keys of istio_ports are direction << 16 | port.
+#define DIRECTION_INBOUND 0
+#define DIRECTION_OUTBOUND 1
+
+// dns_entry is an address and a port, the key and value of istio_dns.
+struct dns_entry {
+	__u8 addr[16];
+	__be16 port;
+	__u16 pad;
+};
+
+// istio_dns maps the address and port of a client to the resolver its query was sent to.
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, 4096);
+	__type(key, struct dns_entry);
+	__type(value, struct dns_entry);
+} istio_dns SEC(".maps");
+
+// cidr_key is the key of istio_cidrs, the prefix length covers the family and the address.
+struct cidr_key {
+	__u32 prefixlen;
+	__u8 family;
+	__u8 addr[16];
+	__u8 pad[3];
+};
+
+// The exclusion maps are sized by the node agent, they hold the exclusions of a single pod.
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, __u8);
+} istio_ports SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LPM_TRIE);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+	__uint(max_entries, 1);
+	__type(key, struct cidr_key);
+	__type(value, __u8);
+} istio_cidrs SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, __u8);
+} istio_uids SEC(".maps");
+
+enum parse_result {
+	PARSE_OK,
+	// PARSE_PASS is returned for packets the programs leave alone.
+	PARSE_PASS,
+	PARSE_DROP,
+};
+
+// packet holds the fields of a packet the programs look at. Addresses and ports are in network byte order, IPv4
+// addresses use the first 4 bytes.
+struct packet {
+	__u32 l4_off;
+	__u8 family;
+	__u8 proto;
+	__u8 tcp_flags;
+	__u8 saddr[16];
+	__u8 daddr[16];
+	__be16 sport;
+	__be16 dport;
+};
+
+static __always_inline bool is_extension_header(__u8 nexthdr)
+{
+	switch (nexthdr) {
+	case IPPROTO_HOPOPTS:
+	case IPPROTO_ROUTING:
+	case IPPROTO_FRAGMENT:
+	case IPPROTO_AH:
+	case IPPROTO_DSTOPTS:
+		return true;
+	}
+	return false;
+}
+
+// parse reads the IP header and the ports of TCP and UDP packets. Non IP packets, and IPv6 packets when IPv6 is
+// disabled, are passed.
+static __always_inline enum parse_result parse(struct __sk_buff *skb, struct packet *pkt)
+{
+	bool fragment = false;
+
+	__builtin_memset(pkt, 0, sizeof(*pkt));
+	if (skb->protocol == bpf_htons(ETH_P_IP)) {
+		struct iphdr ip;
+		if (bpf_skb_load_bytes(skb, ETH_HLEN, &ip, sizeof(ip)) < 0)
+			return PARSE_DROP;
+		__u32 ihl = (ip.ihl_version & 0x0f) * 4;
+		if (ihl < sizeof(ip))
+			return PARSE_DROP;
+		pkt->family = 4;
+		pkt->proto = ip.protocol;
+		pkt->l4_off = ETH_HLEN + ihl;
+		__builtin_memcpy(pkt->saddr, ip.saddr, 4);
+		__builtin_memcpy(pkt->daddr, ip.daddr, 4);
+		// The more fragments flag, or a fragment offset.
+		fragment = (ip.frag_off & bpf_htons(0x3fff)) != 0;
+	} else if (skb->protocol == bpf_htons(ETH_P_IPV6) && enable_ipv6) {
+		struct ipv6hdr ip6;
+		if (bpf_skb_load_bytes(skb, ETH_HLEN, &ip6, sizeof(ip6)) < 0)
+			return PARSE_DROP;
+		pkt->family = 6;
+		__builtin_memcpy(pkt->saddr, ip6.saddr, 16);
+		__builtin_memcpy(pkt->daddr, ip6.daddr, 16);
+
+		__u8 nexthdr = ip6.nexthdr;
+		__u32 off = ETH_HLEN + sizeof(ip6);
+#pragma unroll
+		for (int i = 0; i < MAX_EXTENSION_HEADERS; i++) {
+			if (!is_extension_header(nexthdr))
+				break;
+			// The next header and the length, followed by the fragment offset in a fragment header.
+			__u8 ext[4];
+			if (bpf_skb_load_bytes(skb, off, ext, sizeof(ext)) < 0)
+				return PARSE_DROP;
+			switch (nexthdr) {
+			case IPPROTO_FRAGMENT:
+				// The fragment offset, or the more fragments flag.
+				if (((ext[2] << 8) | ext[3]) & 0xfff9)
+					fragment = true;
+				off += 8;
+				break;
+			case IPPROTO_AH:
+				off += (ext[1] + 2) * 4;
+				break;
+			default:
+				off += (ext[1] + 1) * 8;
+			}
+			nexthdr = ext[0];
+		}
+		if (is_extension_header(nexthdr))
+			return PARSE_DROP;
+		pkt->proto = nexthdr;
+		pkt->l4_off = off;
+	} else {
+		return PARSE_PASS;
+	}
+
+	if (fragment) {
+		// The fragments of a packet cannot be redirected together, and only the first one holds the ports.
+		// TCP segments are not fragmented in practice, other protocols are not captured.
+		return pkt->proto == IPPROTO_TCP ? PARSE_DROP : PARSE_PASS;
+	}
+	if (pkt->proto == IPPROTO_TCP) {
+		// The ports, up to the flags.
+		__u8 tcp[14];
+		if (bpf_skb_load_bytes(skb, pkt->l4_off, tcp, sizeof(tcp)) < 0)
+			return PARSE_DROP;
+		__builtin_memcpy(&pkt->sport, &tcp[0], 2);
+		__builtin_memcpy(&pkt->dport, &tcp[2], 2);
+		pkt->tcp_flags = tcp[13];
+	} else if (pkt->proto == IPPROTO_UDP) {
+		__be16 ports[2];
+		if (bpf_skb_load_bytes(skb, pkt->l4_off, ports, sizeof(ports)) < 0)
+			return PARSE_DROP;
+		pkt->sport = ports[0];
+		pkt->dport = ports[1];
+	}
+	return PARSE_OK;
+}
+
+static __always_inline int parse_verdict(enum parse_result result)
+{
+	return result == PARSE_DROP ? TC_ACT_SHOT : TC_ACT_OK;
+}
+
+static __always_inline bool is_syn(const struct packet *pkt)
+{
+	return (pkt->tcp_flags & (TCP_FLAG_SYN | TCP_FLAG_ACK)) == TCP_FLAG_SYN;
+}
+
+static __always_inline __u32 addr_len(const struct packet *pkt)
+{
+	return pkt->family == 4 ? 4 : 16;
+}
+
+static __always_inline __u32 saddr_offset(const struct packet *pkt)
+{
+	return ETH_HLEN + (pkt->family == 4 ? 12 : 8);
+}
+
+static __always_inline __u32 daddr_offset(const struct packet *pkt)
+{
+	return ETH_HLEN + (pkt->family == 4 ? 16 : 24);
+}
+
+// is_probe reports whether addr is the source address of the node health checks.
+static __always_inline bool is_probe(const struct packet *pkt, const __u8 *addr)
+{
+	__be32 a[4];
+
+	__builtin_memcpy(a, addr, sizeof(a));
+	if (pkt->family == 4)
+		return probe_v4 != 0 && a[0] == probe_v4;
+	return (probe_v6[0] | probe_v6[1] | probe_v6[2] | probe_v6[3]) != 0 && a[0] == probe_v6[0] &&
+	       a[1] == probe_v6[1] && a[2] == probe_v6[2] && a[3] == probe_v6[3];
+}
+
+static __always_inline void loopback_addr(const struct packet *pkt, __u8 *addr)
+{
+	if (pkt->family == 4) {
+		addr[0] = 127;
+		addr[3] = 1;
+	} else {
+		addr[15] = 1;
+	}
+}
+
+static __always_inline bool is_loopback(const struct packet *pkt, const __u8 *addr)
+{
+	__u8 loopback[16] = {};
+
+	loopback_addr(pkt, loopback);
+#pragma unroll
+	for (int i = 0; i < 16; i++) {
+		if (addr[i] != loopback[i])
+			return false;
+	}
+	return true;
+}
+
+static __always_inline bool is_excluded_port(__u32 direction, __be16 port)
+{
+	__u32 key = direction << 16 | bpf_ntohs(port);
+
+	return bpf_map_lookup_elem(&istio_ports, &key) != NULL;
+}
+
+// is_excluded_outbound reports whether an outbound TCP packet is excluded from capture.
+static __always_inline bool is_excluded_outbound(struct __sk_buff *skb, const struct packet *pkt)
+{
+	if (!capture_exclusions)
+		return false;
+	if (is_excluded_port(DIRECTION_OUTBOUND, pkt->dport))
+		return true;
+	// Replies of the connections to the excluded inbound ports, which did not go through ztunnel.
+	if (is_excluded_port(DIRECTION_INBOUND, pkt->sport))
+		return true;
+
+	struct cidr_key key = {};
+	key.prefixlen = 8 + addr_len(pkt) * 8;
+	key.family = pkt->family;
+	__builtin_memcpy(key.addr, pkt->daddr, 16);
+	if (bpf_map_lookup_elem(&istio_cidrs, &key) != NULL)
+		return true;
+
+	__u32 uid = bpf_get_socket_uid(skb);
+	return bpf_map_lookup_elem(&istio_uids, &uid) != NULL;
+}
+
+// assign assigns the packet to the TCP socket listening on port. The destination address of the lookup is the
+// loopback address when loopback is set, for listeners bound to it.
+static __always_inline void assign(struct __sk_buff *skb, const struct packet *pkt, __u16 port, bool loopback)
+{
+	struct bpf_sock_tuple tuple = {};
+	struct bpf_sock *sk;
+
+	if (pkt->family == 4) {
+		__builtin_memcpy(&tuple.ipv4.saddr, pkt->saddr, 4);
+		if (loopback)
+			tuple.ipv4.daddr = bpf_htonl(0x7f000001);
+		else
+			__builtin_memcpy(&tuple.ipv4.daddr, pkt->daddr, 4);
+		tuple.ipv4.sport = pkt->sport;
+		tuple.ipv4.dport = bpf_htons(port);
+		sk = bpf_sk_lookup_tcp(skb, &tuple, sizeof(tuple.ipv4), BPF_F_CURRENT_NETNS, 0);
+	} else {
+		__builtin_memcpy(tuple.ipv6.saddr, pkt->saddr, 16);
+		if (loopback)
+			tuple.ipv6.daddr[3] = bpf_htonl(1);
+		else
+			__builtin_memcpy(tuple.ipv6.daddr, pkt->daddr, 16);
+		tuple.ipv6.sport = pkt->sport;
+		tuple.ipv6.dport = bpf_htons(port);
+		sk = bpf_sk_lookup_tcp(skb, &tuple, sizeof(tuple.ipv6), BPF_F_CURRENT_NETNS, 0);
+	}
+	if (!sk)
+		return;
+	bpf_sk_assign(skb, sk, 0);
+	bpf_sk_release(sk);
+}
+
+static __always_inline void set_tproxy_mark(struct __sk_buff *skb)
+{
+	skb->mark = (skb->mark & ~tproxy_mask) | tproxy_mark;
+}
+
+// redirect_to_loopback hands the packet to the ingress path of the loopback interface.
+static __always_inline int redirect_to_loopback(struct __sk_buff *skb)
+{
+	// The destination MAC address must be the one of the loopback interface, all zeros, or the packet is not
+	// considered for local delivery.
+	__u8 zero[6] = {};
+
+	if (bpf_skb_store_bytes(skb, 0, zero, sizeof(zero), 0) < 0)
+		return TC_ACT_SHOT;
+	return bpf_redirect(loopback_ifindex, BPF_F_INGRESS);
+}
+
+// rewrite_addr replaces the address at offset, old, with new, and updates the checksums. Only UDP is rewritten.
+static __always_inline int rewrite_addr(struct __sk_buff *skb, const struct packet *pkt, __u32 offset, __u8 *old,
+					__u8 *new)
+{
+	__u32 csum_off = pkt->l4_off + UDP_CSUM_OFF;
+
+	if (pkt->family == 4) {
+		__be32 from, to;
+		__builtin_memcpy(&from, old, 4);
+		__builtin_memcpy(&to, new, 4);
+		if (bpf_l4_csum_replace(skb, csum_off, from, to, BPF_F_PSEUDO_HDR | BPF_F_MARK_MANGLED_0 | 4) < 0)
+			return -1;
+		if (bpf_l3_csum_replace(skb, ETH_HLEN + IPV4_CSUM_OFF, from, to, 4) < 0)
+			return -1;
+		return bpf_skb_store_bytes(skb, offset, new, 4, 0);
+	}
+	__s64 diff = bpf_csum_diff((__be32 *)old, 16, (__be32 *)new, 16, 0);
+	if (bpf_l4_csum_replace(skb, csum_off, 0, diff, BPF_F_PSEUDO_HDR | BPF_F_MARK_MANGLED_0) < 0)
+		return -1;
+	return bpf_skb_store_bytes(skb, offset, new, 16, 0);
+}
+
+// rewrite_port replaces the UDP port at offset in the transport header, like rewrite_addr.
+static __always_inline int rewrite_port(struct __sk_buff *skb, const struct packet *pkt, __u32 offset, __be16 old,
+					__be16 new)
+{
+	if (bpf_l4_csum_replace(skb, pkt->l4_off + UDP_CSUM_OFF, old, new, BPF_F_MARK_MANGLED_0 | 2) < 0)
+		return -1;
+	return bpf_skb_store_bytes(skb, pkt->l4_off + offset, &new, 2, 0);
+}
+
+SEC("tc")
+int istio_inbound(struct __sk_buff *skb)
+{
+	struct packet pkt;
+	enum parse_result result = parse(skb, &pkt);
+
+	if (result != PARSE_OK)
+		return parse_verdict(result);
+	if (pkt.proto != IPPROTO_TCP || !is_syn(&pkt))
+		return TC_ACT_OK;
+	// Node health checks go to the application directly.
+	if (is_probe(&pkt, pkt.saddr))
+		return TC_ACT_OK;
+	// HBONE connections go to the ztunnel inbound listener directly.
+	if (pkt.dport == bpf_htons(ztunnel_inbound_port))
+		return TC_ACT_OK;
+	if (capture_exclusions && is_excluded_port(DIRECTION_INBOUND, pkt.dport))
+		return TC_ACT_OK;
+	assign(skb, &pkt, ztunnel_inbound_plaintext_port, false);
+	return TC_ACT_OK;
+}
+
+SEC("tc")
+int istio_outbound(struct __sk_buff *skb)
+{
+	struct packet pkt;
+	enum parse_result result;
+
+	// Traffic of ztunnel itself leaves the pod.
+	if ((skb->mark & inpod_mask) == inpod_mark)
+		return TC_ACT_OK;
+	result = parse(skb, &pkt);
+	if (result != PARSE_OK)
+		return parse_verdict(result);
+
+	if (pkt.proto == IPPROTO_TCP) {
+		// Replies to the node health checks leave the pod.
+		if (is_probe(&pkt, pkt.daddr))
+			return TC_ACT_OK;
+		if (is_excluded_outbound(skb, &pkt))
+			return TC_ACT_OK;
+		set_tproxy_mark(skb);
+		return redirect_to_loopback(skb);
+	}
+
+	// DNS is still redirected when excluded from capture, as DNS capture is configured separately.
+	if (pkt.proto != IPPROTO_UDP || !redirect_dns || pkt.dport != bpf_htons(DNS_PORT))
+		return TC_ACT_OK;
+	// Remember the resolver the client sent the query to, keyed by the client address and port.
+	struct dns_entry key = {}, value = {};
+	__builtin_memcpy(key.addr, pkt.saddr, 16);
+	key.port = pkt.sport;
+	__builtin_memcpy(value.addr, pkt.daddr, 16);
+	value.port = pkt.dport;
+	if (bpf_map_update_elem(&istio_dns, &key, &value, BPF_ANY) < 0)
+		return TC_ACT_OK;
+	// Send the query to the DNS proxy instead.
+	__u8 proxy[16] = {};
+	loopback_addr(&pkt, proxy);
+	if (rewrite_addr(skb, &pkt, daddr_offset(&pkt), pkt.daddr, proxy) < 0 ||
+	    rewrite_port(skb, &pkt, 2, pkt.dport, bpf_htons(dns_capture_port)) < 0)
+		return TC_ACT_SHOT;
+	return redirect_to_loopback(skb);
+}
+
+SEC("tc")
+int istio_loopback(struct __sk_buff *skb)
+{
+	struct packet pkt;
+	enum parse_result result;
+
+	// Only the packets redirected by the outbound program, and the replies of the DNS proxy, are of interest.
+	bool redirected = (skb->mark & tproxy_mask) == tproxy_mark;
+	if (!redirected && !redirect_dns)
+		return TC_ACT_OK;
+	result = parse(skb, &pkt);
+	if (result != PARSE_OK)
+		return parse_verdict(result);
+
+	if (redirected) {
+		if (pkt.proto != IPPROTO_TCP || !is_syn(&pkt))
+			return TC_ACT_OK;
+		if (redirect_dns && pkt.dport == bpf_htons(DNS_PORT))
+			assign(skb, &pkt, dns_capture_port, true);
+		else
+			assign(skb, &pkt, ztunnel_outbound_port, true);
+		return TC_ACT_OK;
+	}
+
+	// Replies of the DNS proxy get the address of the resolver the query was sent to.
+	if (pkt.proto != IPPROTO_UDP || pkt.sport != bpf_htons(dns_capture_port) || !is_loopback(&pkt, pkt.saddr))
+		return TC_ACT_OK;
+	struct dns_entry key = {}, resolver;
+	__builtin_memcpy(key.addr, pkt.daddr, 16);
+	key.port = pkt.dport;
+	struct dns_entry *entry = bpf_map_lookup_elem(&istio_dns, &key);
+	if (!entry)
+		return TC_ACT_OK;
+	__builtin_memcpy(&resolver, entry, sizeof(resolver));
+	if (rewrite_addr(skb, &pkt, saddr_offset(&pkt), pkt.saddr, resolver.addr) < 0 ||
+	    rewrite_port(skb, &pkt, 0, pkt.sport, resolver.port) < 0)
+		return TC_ACT_SHOT;
+	return TC_ACT_OK;
+}
+
+// istio_virtual is attached to the ingress of virtual interfaces, such as the interfaces of VMs running in the
+// pod. Their TCP traffic is sent to the ztunnel outbound listener.
+SEC("tc")
+int istio_virtual(struct __sk_buff *skb)
+{
+	struct packet pkt;
+	enum parse_result result = parse(skb, &pkt);
+
+	if (result != PARSE_OK)
+		return parse_verdict(result);
+	if (pkt.proto != IPPROTO_TCP)
+		return TC_ACT_OK;
+	set_tproxy_mark(skb);
+	if (is_syn(&pkt))
+		assign(skb, &pkt, ztunnel_outbound_port, true);
+	return TC_ACT_OK;
+}
@@ -0,0 +1,162 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/cilium/ebpf"
+
+	"istio.io/istio/cni/pkg/config"
+)
+
+// The redirection programs are written in C, see bpf/redirect.c, and compiled for both byte orders with bpf2go.
+// Regenerating them requires clang and llvm-strip.
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cflags "-Wall -Werror" -type dns_entry -type cidr_key redirect bpf/redirect.c
+
+const (
+	// dnsMapEntries bounds the number of DNS queries in flight tracked per pod.
+	dnsMapEntries = 4096
+
+	// Names of the maps, as defined in bpf/redirect.c.
+	dnsMapName   = "istio_dns"
+	portsMapName = "istio_ports"
+	cidrsMapName = "istio_cidrs"
+	uidsMapName  = "istio_uids"
+
+	// Directions of the excluded ports, the keys of the ports map are direction << 16 | port.
+	directionInbound  = 0
+	directionOutbound = 1
+)
+
+// Config holds the pod settings the redirection programs are configured with.
+type Config struct {
+	// EnableIPv6 redirects IPv6 traffic too.
+	EnableIPv6 bool
+	// RedirectDNS sends DNS queries to the ztunnel DNS proxy.
+	RedirectDNS bool
+	// IngressMode disables the capture of inbound traffic, as for ingress gateways.
+	IngressMode bool
+	// HostProbeSNATAddress and HostProbeV6SNATAddress are the source addresses of the node health checks, which
+	// bypass ztunnel.
+	HostProbeSNATAddress   netip.Addr
+	HostProbeV6SNATAddress netip.Addr
+
+	// Traffic excluded from capture, as in config.PodLevelOverrides.
+	ExcludeInboundPorts     []uint16
+	ExcludeOutboundPorts    []uint16
+	ExcludeOutboundIPRanges []netip.Prefix
+	ExcludeOutboundUIDs     []int64
+}
+
+func (cfg Config) hasExclusions() bool {
+	return len(cfg.ExcludeInboundPorts) > 0 || len(cfg.ExcludeOutboundPorts) > 0 ||
+		len(cfg.ExcludeOutboundIPRanges) > 0 || len(cfg.ExcludeOutboundUIDs) > 0
+}
+
+// collectionSpec returns the redirection programs and their maps, configured with cfg. The exclusion maps are
+// sized for the exclusions of cfg, which are added by fillExclusions once the maps are created.
+func collectionSpec(cfg Config, loopbackIndex int) (*ebpf.CollectionSpec, error) {
+	spec, err := loadRedirect()
+	if err != nil {
+		return nil, err
+	}
+	var specs redirectSpecs
+	if err := spec.Assign(&specs); err != nil {
+		return nil, fmt.Errorf("unexpected redirection programs: %v", err)
+	}
+
+	var probe [4]byte
+	if cfg.HostProbeSNATAddress.Is4() {
+		probe = cfg.HostProbeSNATAddress.As4()
+	}
+	var probe6 [16]byte
+	if cfg.HostProbeV6SNATAddress.Is6() {
+		probe6 = cfg.HostProbeV6SNATAddress.As16()
+	}
+	vars := specs.redirectVariableSpecs
+	for _, v := range []struct {
+		spec  *ebpf.VariableSpec
+		value any
+	}{
+		{vars.EnableIpv6, cfg.EnableIPv6},
+		{vars.RedirectDns, cfg.RedirectDNS},
+		{vars.CaptureExclusions, cfg.hasExclusions()},
+		{vars.ProbeV4, probe},
+		{vars.ProbeV6, probe6},
+		{vars.LoopbackIfindex, uint32(loopbackIndex)},
+		{vars.ZtunnelInboundPort, uint16(config.ZtunnelInboundPort)},
+		{vars.ZtunnelInboundPlaintextPort, uint16(config.ZtunnelInboundPlaintextPort)},
+		{vars.ZtunnelOutboundPort, uint16(config.ZtunnelOutboundPort)},
+		{vars.DnsCapturePort, uint16(config.DNSCapturePort)},
+		{vars.InpodMark, uint32(config.InpodMark)},
+		{vars.InpodMask, uint32(config.InpodMask)},
+		{vars.TproxyMark, uint32(config.InpodTProxyMark)},
+		{vars.TproxyMask, uint32(config.InpodTProxyMask)},
+	} {
+		if err := v.spec.Set(v.value); err != nil {
+			return nil, err
+		}
+	}
+
+	// Maps must hold at least one entry, even when they are not used.
+	specs.IstioDns.MaxEntries = 1
+	if cfg.RedirectDNS {
+		specs.IstioDns.MaxEntries = dnsMapEntries
+	}
+	specs.IstioPorts.MaxEntries = uint32(max(1, len(cfg.ExcludeInboundPorts)+len(cfg.ExcludeOutboundPorts)))
+	specs.IstioCidrs.MaxEntries = uint32(max(1, len(cfg.ExcludeOutboundIPRanges)))
+	specs.IstioUids.MaxEntries = uint32(max(1, len(cfg.ExcludeOutboundUIDs)))
+	return spec, nil
+}
+
+// fillExclusions adds the exclusions of cfg to the exclusion maps of coll.
+func fillExclusions(cfg Config, coll *ebpf.Collection) error {
+	const excluded = uint8(1)
+	for direction, ports := range map[uint32][]uint16{
+		directionInbound:  cfg.ExcludeInboundPorts,
+		directionOutbound: cfg.ExcludeOutboundPorts,
+	} {
+		for _, port := range ports {
+			if err := coll.Maps[portsMapName].Put(direction<<16|uint32(port), excluded); err != nil {
+				return fmt.Errorf("failed to exclude port %d: %v", port, err)
+			}
+		}
+	}
+	for _, prefix := range cfg.ExcludeOutboundIPRanges {
+		if err := coll.Maps[cidrsMapName].Put(cidrKey(prefix), excluded); err != nil {
+			return fmt.Errorf("failed to exclude %s: %v", prefix, err)
+		}
+	}
+	for _, uid := range cfg.ExcludeOutboundUIDs {
+		if err := coll.Maps[uidsMapName].Put(uint32(uid), excluded); err != nil {
+			return fmt.Errorf("failed to exclude uid %d: %v", uid, err)
+		}
+	}
+	return nil
+}
+
+// cidrKey returns the key of prefix in the CIDR map. The family byte precedes the address, so the prefix length
+// covers it too.
+func cidrKey(prefix netip.Prefix) redirectCidrKey {
+	prefix = prefix.Masked()
+	key := redirectCidrKey{Prefixlen: uint32(8 + prefix.Bits()), Family: 6}
+	if prefix.Addr().Is4() {
+		key.Family = 4
+	}
+	copy(key.Addr[:], prefix.Addr().AsSlice())
+	return key
+}
@@ -0,0 +1,458 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const (
+	tcActOK       = 0
+	tcActShot     = 2
+	tcActRedirect = 7
+
+	tcpFlagSYN = 0x02
+	tcpFlagACK = 0x10
+)
+
+var (
+	probe     = netip.MustParseAddr("169.254.7.127")
+	probe6    = netip.MustParseAddr("fd16:9254:7127:1337:ffff:ffff:ffff:ffff")
+	podIP     = netip.MustParseAddr("10.1.0.1")
+	serviceIP = netip.MustParseAddr("10.2.0.1")
+	podIP6    = netip.MustParseAddr("fd00::1")
+	service6  = netip.MustParseAddr("fd00::2:1")
+)
+
+func requirePrograms(t *testing.T) {
+	t.Helper()
+	if err := features.HaveProgramType(ebpf.SchedCLS); err != nil {
+		t.Skipf("cannot load TC programs, skipping: %v", err)
+	}
+}
+
+func TestProgramsLoad(t *testing.T) {
+	requirePrograms(t)
+
+	for _, cfg := range []Config{
+		{},
+		{EnableIPv6: true, HostProbeSNATAddress: probe, HostProbeV6SNATAddress: probe6},
+		{EnableIPv6: true, RedirectDNS: true, HostProbeSNATAddress: probe, HostProbeV6SNATAddress: probe6},
+		{RedirectDNS: true, IngressMode: true},
+		supportCheckConfig,
+	} {
+		coll, err := newCollection(cfg, 1)
+		if err != nil {
+			t.Fatalf("config %+v: %v", cfg, err)
+		}
+		coll.Close()
+	}
+}
+
+// skbContext is struct __sk_buff, BPF_PROG_TEST_RUN only reads and writes a few of its fields.
+type skbContext struct {
+	Len     uint32
+	PktType uint32
+	Mark    uint32
+	_       [180]byte
+}
+
+// run runs a program once on packet, and returns its verdict, the packet and the mark after the program ran.
+func run(t *testing.T, coll *ebpf.Collection, program string, packet []byte, mark uint32) (uint32, []byte, uint32) {
+	t.Helper()
+	in := skbContext{Mark: mark}
+	var out skbContext
+	opts := &ebpf.RunOptions{
+		Data:       packet,
+		DataOut:    make([]byte, len(packet)+256),
+		Context:    in,
+		ContextOut: &out,
+	}
+	verdict, err := coll.Programs[program].Run(opts)
+	assert.NoError(t, err)
+	return verdict, opts.DataOut, out.Mark
+}
+
+func TestPrograms(t *testing.T) {
+	requirePrograms(t)
+
+	captured := Config{
+		EnableIPv6:              true,
+		RedirectDNS:             true,
+		HostProbeSNATAddress:    probe,
+		HostProbeV6SNATAddress:  probe6,
+		ExcludeInboundPorts:     []uint16{9000},
+		ExcludeOutboundPorts:    []uint16{9001},
+		ExcludeOutboundIPRanges: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")},
+	}
+	syn := tcpHeader(40000, 8080, tcpFlagSYN)
+	ack := tcpHeader(40000, 8080, tcpFlagACK)
+	// A hop-by-hop options header followed by a destination options header, each 8 bytes long.
+	extensions := func(nexthdr uint8, payload []byte) (uint8, []byte) {
+		headers := []byte{60, 0, 1, 4, 0, 0, 0, 0, nexthdr, 0, 1, 4, 0, 0, 0, 0}
+		return 0, append(headers, payload...)
+	}
+	// More destination options headers than the programs skip.
+	tooManyExtensions := func(payload []byte) (uint8, []byte) {
+		var headers []byte
+		for i := 0; i < 9; i++ {
+			headers = append(headers, 60, 0, 1, 4, 0, 0, 0, 0)
+		}
+		headers[len(headers)-8] = 6
+		return 60, append(headers, payload...)
+	}
+	// The first fragment of a TCP segment.
+	tcpFragment := func(payload []byte) (uint8, []byte) {
+		return 44, append([]byte{6, 0, 0, 1, 0, 0, 0, 1}, payload...)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		cfg      Config
+		program  string
+		packet   []byte
+		mark     uint32
+		verdict  uint32
+		wantMark uint32
+	}{
+		{
+			name:    "inbound SYN",
+			cfg:     captured,
+			program: inboundFilter,
+			packet:  ipv4Packet(6, serviceIP, podIP, 0, syn),
+			verdict: tcActOK,
+		},
+		{
+			name:    "inbound non IP",
+			cfg:     captured,
+			program: inboundFilter,
+			packet:  ethernet(0x0806, make([]byte, 28)),
+			verdict: tcActOK,
+		},
+		{
+			name:    "inbound truncated TCP",
+			cfg:     captured,
+			program: inboundFilter,
+			packet:  ipv4Packet(6, serviceIP, podIP, 0, syn[:8]),
+			verdict: tcActShot,
+		},
+		{
+			name:    "inbound TCP fragment",
+			cfg:     captured,
+			program: inboundFilter,
+			packet:  ipv4Packet(6, serviceIP, podIP, 0x2000, syn),
+			verdict: tcActShot,
+		},
+		{
+			name:    "inbound UDP fragment",
+			cfg:     captured,
+			program: inboundFilter,
+			packet:  ipv4Packet(17, serviceIP, podIP, 0x0010, make([]byte, 8)),
+			verdict: tcActOK,
+		},
+		{
+			name:    "inbound IPv6 with extension headers",
+			cfg:     captured,
+			program: inboundFilter,
+			packet:  ipv6Packet(service6, podIP6)(extensions(6, syn)),
+			verdict: tcActOK,
+		},
+		{
+			name:    "inbound IPv6 with too many extension headers",
+			cfg:     captured,
+			program: inboundFilter,
+			packet:  ipv6Packet(service6, podIP6)(tooManyExtensions(syn)),
+			verdict: tcActShot,
+		},
+		{
+			name:     "outbound TCP",
+			cfg:      captured,
+			program:  outboundFilter,
+			packet:   ipv4Packet(6, podIP, serviceIP, 0, ack),
+			verdict:  tcActRedirect,
+			wantMark: config.InpodTProxyMark,
+		},
+		{
+			name:     "outbound ztunnel traffic",
+			cfg:      captured,
+			program:  outboundFilter,
+			packet:   ipv4Packet(6, podIP, serviceIP, 0, syn),
+			mark:     config.InpodMark,
+			verdict:  tcActOK,
+			wantMark: config.InpodMark,
+		},
+		{
+			name:    "outbound health check reply",
+			cfg:     captured,
+			program: outboundFilter,
+			packet:  ipv4Packet(6, podIP, probe, 0, ack),
+			verdict: tcActOK,
+		},
+		{
+			name:    "outbound excluded port",
+			cfg:     captured,
+			program: outboundFilter,
+			packet:  ipv4Packet(6, podIP, serviceIP, 0, tcpHeader(40000, 9001, tcpFlagSYN)),
+			verdict: tcActOK,
+		},
+		{
+			name:    "outbound excluded CIDR",
+			cfg:     captured,
+			program: outboundFilter,
+			packet:  ipv4Packet(6, podIP, netip.MustParseAddr("192.0.2.10"), 0, syn),
+			verdict: tcActOK,
+		},
+		{
+			name:    "outbound reply from excluded inbound port",
+			cfg:     captured,
+			program: outboundFilter,
+			packet:  ipv4Packet(6, podIP, serviceIP, 0, tcpHeader(9000, 40000, tcpFlagSYN|tcpFlagACK)),
+			verdict: tcActOK,
+		},
+		{
+			// BPF_PROG_TEST_RUN runs the programs on packets of a socket owned by root.
+			name:    "outbound excluded UID",
+			cfg:     Config{ExcludeOutboundUIDs: []int64{0}},
+			program: outboundFilter,
+			packet:  ipv4Packet(6, podIP, serviceIP, 0, syn),
+			verdict: tcActOK,
+		},
+		{
+			name:     "outbound IPv6 with extension headers",
+			cfg:      captured,
+			program:  outboundFilter,
+			packet:   ipv6Packet(podIP6, service6)(extensions(6, syn)),
+			verdict:  tcActRedirect,
+			wantMark: config.InpodTProxyMark,
+		},
+		{
+			name:    "outbound IPv6 with too many extension headers",
+			cfg:     captured,
+			program: outboundFilter,
+			packet:  ipv6Packet(podIP6, service6)(tooManyExtensions(syn)),
+			verdict: tcActShot,
+		},
+		{
+			name:    "outbound IPv6 TCP fragment",
+			cfg:     captured,
+			program: outboundFilter,
+			packet:  ipv6Packet(podIP6, service6)(tcpFragment(syn)),
+			verdict: tcActShot,
+		},
+		{
+			name:    "outbound IPv6 disabled",
+			cfg:     Config{},
+			program: outboundFilter,
+			packet:  ipv6Packet(podIP6, service6)(6, syn),
+			verdict: tcActOK,
+		},
+		{
+			name:     "loopback redirected SYN",
+			cfg:      captured,
+			program:  loopbackFilter,
+			packet:   ipv4Packet(6, podIP, serviceIP, 0, syn),
+			mark:     config.InpodTProxyMark,
+			verdict:  tcActOK,
+			wantMark: config.InpodTProxyMark,
+		},
+		{
+			name:    "loopback local traffic",
+			cfg:     Config{},
+			program: loopbackFilter,
+			packet:  ipv4Packet(6, podIP, serviceIP, 0, syn),
+			verdict: tcActOK,
+		},
+		{
+			name:     "virtual interface TCP",
+			cfg:      captured,
+			program:  virtualFilter,
+			packet:   ipv4Packet(6, netip.MustParseAddr("10.10.0.2"), serviceIP, 0, ack),
+			verdict:  tcActOK,
+			wantMark: config.InpodTProxyMark,
+		},
+		{
+			name:    "virtual interface UDP",
+			cfg:     captured,
+			program: virtualFilter,
+			packet:  ipv4Packet(17, netip.MustParseAddr("10.10.0.2"), serviceIP, 0, make([]byte, 8)),
+			verdict: tcActOK,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			coll, err := newCollection(tt.cfg, 1)
+			assert.NoError(t, err)
+			defer coll.Close()
+
+			verdict, out, mark := run(t, coll, tt.program, tt.packet, tt.mark)
+			assert.Equal(t, verdict, tt.verdict)
+			assert.Equal(t, mark, tt.wantMark)
+			if verdict == tcActRedirect {
+				// The packet is handed to the loopback interface, with its MAC address.
+				assert.Equal(t, out[:6], make([]byte, 6))
+			}
+		})
+	}
+}
+
+func TestDNSRedirect(t *testing.T) {
+	requirePrograms(t)
+
+	for _, tt := range []struct {
+		name               string
+		client, resolver   netip.Addr
+		loopback           netip.Addr
+		packet             func(src, dst netip.Addr) func(uint8, []byte) []byte
+		saddrOff, daddrOff int
+		udpOff             int
+	}{
+		{
+			name:   "IPv4",
+			client: podIP, resolver: serviceIP, loopback: netip.MustParseAddr("127.0.0.1"),
+			packet:   ipv4Packet4,
+			saddrOff: 14 + 12, daddrOff: 14 + 16, udpOff: 14 + 20,
+		},
+		{
+			name:   "IPv6",
+			client: podIP6, resolver: service6, loopback: netip.IPv6Loopback(),
+			packet:   ipv6Packet,
+			saddrOff: 14 + 8, daddrOff: 14 + 24, udpOff: 14 + 40,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			coll, err := newCollection(Config{EnableIPv6: true, RedirectDNS: true}, 1)
+			assert.NoError(t, err)
+			defer coll.Close()
+
+			// The query is sent to the DNS proxy.
+			query := tt.packet(tt.client, tt.resolver)(17, udpHeader(tt.client, tt.resolver, 40000, 53, []byte("query")))
+			verdict, out, _ := run(t, coll, outboundFilter, query, 0)
+			assert.Equal(t, verdict, uint32(tcActRedirect))
+			assert.Equal(t, addrAt(out, tt.daddrOff, tt.loopback).String(), tt.loopback.String())
+			assert.Equal(t, binary.BigEndian.Uint16(out[tt.udpOff+2:]), uint16(config.DNSCapturePort))
+			assert.Equal(t, udpChecksumValid(out[tt.udpOff:], tt.client, tt.loopback), true)
+
+			// The reply of the proxy gets the address of the resolver.
+			reply := tt.packet(tt.loopback, tt.client)(17, udpHeader(tt.loopback, tt.client, config.DNSCapturePort, 40000, []byte("reply")))
+			verdict, out, _ = run(t, coll, loopbackFilter, reply, 0)
+			assert.Equal(t, verdict, uint32(tcActOK))
+			assert.Equal(t, addrAt(out, tt.saddrOff, tt.resolver).String(), tt.resolver.String())
+			assert.Equal(t, binary.BigEndian.Uint16(out[tt.udpOff:]), uint16(53))
+			assert.Equal(t, udpChecksumValid(out[tt.udpOff:], tt.resolver, tt.client), true)
+		})
+	}
+}
+
+func addrAt(packet []byte, off int, like netip.Addr) netip.Addr {
+	addr, _ := netip.AddrFromSlice(packet[off : off+like.BitLen()/8])
+	return addr
+}
+
+func ethernet(etherType uint16, payload []byte) []byte {
+	b := []byte{0x02, 0, 0, 0, 0, 0x01, 0x02, 0, 0, 0, 0, 0x02}
+	b = binary.BigEndian.AppendUint16(b, etherType)
+	return append(b, payload...)
+}
+
+// ipv4Packet returns an IPv4 packet in an Ethernet frame. fragment holds the flags and the fragment offset.
+func ipv4Packet(proto uint8, src, dst netip.Addr, fragment uint16, payload []byte) []byte {
+	h := make([]byte, 20)
+	h[0] = 0x45
+	binary.BigEndian.PutUint16(h[2:], uint16(len(h)+len(payload)))
+	binary.BigEndian.PutUint16(h[6:], fragment)
+	h[8] = 64
+	h[9] = proto
+	copy(h[12:], src.AsSlice())
+	copy(h[16:], dst.AsSlice())
+	binary.BigEndian.PutUint16(h[10:], ^checksum(0, h))
+	return ethernet(0x0800, append(h, payload...))
+}
+
+// ipv4Packet4 returns a function building an unfragmented IPv4 packet, like ipv6Packet.
+func ipv4Packet4(src, dst netip.Addr) func(uint8, []byte) []byte {
+	return func(proto uint8, payload []byte) []byte {
+		return ipv4Packet(proto, src, dst, 0, payload)
+	}
+}
+
+// ipv6Packet returns a function building an IPv6 packet in an Ethernet frame, from its next header and payload.
+func ipv6Packet(src, dst netip.Addr) func(uint8, []byte) []byte {
+	return func(nexthdr uint8, payload []byte) []byte {
+		h := make([]byte, 40)
+		h[0] = 0x60
+		binary.BigEndian.PutUint16(h[4:], uint16(len(payload)))
+		h[6] = nexthdr
+		h[7] = 64
+		copy(h[8:], src.AsSlice())
+		copy(h[24:], dst.AsSlice())
+		return ethernet(0x86dd, append(h, payload...))
+	}
+}
+
+func tcpHeader(sport, dport uint16, flags uint8) []byte {
+	h := make([]byte, 20)
+	binary.BigEndian.PutUint16(h[0:], sport)
+	binary.BigEndian.PutUint16(h[2:], dport)
+	h[12] = 5 << 4
+	h[13] = flags
+	binary.BigEndian.PutUint16(h[14:], 65535)
+	return h
+}
+
+func udpHeader(src, dst netip.Addr, sport, dport uint16, payload []byte) []byte {
+	h := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(h[0:], sport)
+	binary.BigEndian.PutUint16(h[2:], dport)
+	binary.BigEndian.PutUint16(h[4:], uint16(len(h)+len(payload)))
+	h = append(h, payload...)
+	binary.BigEndian.PutUint16(h[6:], ^checksum(pseudoHeaderSum(src, dst, len(h)), h))
+	return h
+}
+
+func udpChecksumValid(datagram []byte, src, dst netip.Addr) bool {
+	length := int(binary.BigEndian.Uint16(datagram[4:]))
+	return checksum(pseudoHeaderSum(src, dst, length), datagram[:length]) == 0xffff
+}
+
+func pseudoHeaderSum(src, dst netip.Addr, length int) uint32 {
+	var b []byte
+	b = append(b, src.AsSlice()...)
+	b = append(b, dst.AsSlice()...)
+	b = append(b, 0, 17)
+	b = binary.BigEndian.AppendUint16(b, uint16(length))
+	return uint32(checksum(0, b))
+}
+
+// checksum returns the ones' complement sum of b, added to initial.
+func checksum(initial uint32, b []byte) uint16 {
+	sum := initial
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return uint16(sum)
+}
@@ -0,0 +1,71 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"net/netip"
+	"testing"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestCollectionSpec(t *testing.T) {
+	spec, err := collectionSpec(Config{
+		EnableIPv6:             true,
+		HostProbeSNATAddress:   netip.MustParseAddr("169.254.7.127"),
+		HostProbeV6SNATAddress: netip.MustParseAddr("fd16:9254:7127:1337:ffff:ffff:ffff:ffff"),
+		ExcludeInboundPorts:    []uint16{8080, 9090},
+		ExcludeOutboundUIDs:    []int64{1000},
+	}, 7)
+	assert.NoError(t, err)
+
+	for _, name := range []string{inboundFilter, outboundFilter, loopbackFilter, virtualFilter} {
+		if spec.Programs[name] == nil {
+			t.Fatalf("missing program %s", name)
+		}
+	}
+	assert.Equal(t, spec.Maps[dnsMapName].MaxEntries, uint32(1))
+	assert.Equal(t, spec.Maps[portsMapName].MaxEntries, uint32(2))
+	assert.Equal(t, spec.Maps[cidrsMapName].MaxEntries, uint32(1))
+	assert.Equal(t, spec.Maps[uidsMapName].MaxEntries, uint32(1))
+
+	var enabled bool
+	assert.NoError(t, spec.Variables["capture_exclusions"].Get(&enabled))
+	assert.Equal(t, enabled, true)
+	assert.NoError(t, spec.Variables["redirect_dns"].Get(&enabled))
+	assert.Equal(t, enabled, false)
+	var probe [4]byte
+	assert.NoError(t, spec.Variables["probe_v4"].Get(&probe))
+	assert.Equal(t, probe, [4]byte{169, 254, 7, 127})
+	var port uint16
+	assert.NoError(t, spec.Variables["ztunnel_outbound_port"].Get(&port))
+	assert.Equal(t, port, uint16(config.ZtunnelOutboundPort))
+	var ifindex uint32
+	assert.NoError(t, spec.Variables["loopback_ifindex"].Get(&ifindex))
+	assert.Equal(t, ifindex, uint32(7))
+}
+
+func TestCIDRKey(t *testing.T) {
+	key := cidrKey(netip.MustParsePrefix("10.1.2.3/16"))
+	assert.Equal(t, key.Prefixlen, uint32(8+16))
+	assert.Equal(t, key.Family, uint8(4))
+	assert.Equal(t, key.Addr, [16]uint8{10, 1})
+
+	key = cidrKey(netip.MustParsePrefix("2001:db8::/32"))
+	assert.Equal(t, key.Prefixlen, uint32(8+32))
+	assert.Equal(t, key.Family, uint8(6))
+	assert.Equal(t, key.Addr, [16]uint8{0x20, 0x01, 0x0d, 0xb8})
+}
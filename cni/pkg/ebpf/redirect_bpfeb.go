@@ -0,0 +1,195 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build mips || mips64 || ppc64 || s390x
+
+package ebpf
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"structs"
+
+	"github.com/cilium/ebpf"
+)
+
+type redirectCidrKey struct {
+	_         structs.HostLayout
+	Prefixlen uint32
+	Family    uint8
+	Addr      [16]uint8
+	Pad       [3]uint8
+}
+
+type redirectDnsEntry struct {
+	_    structs.HostLayout
+	Addr [16]uint8
+	Port uint16
+	Pad  uint16
+}
+
+// loadRedirect returns the embedded CollectionSpec for redirect.
+func loadRedirect() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_RedirectBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load redirect: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadRedirectObjects loads redirect and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*redirectObjects
+//	*redirectPrograms
+//	*redirectMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadRedirectObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadRedirect()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// redirectSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type redirectSpecs struct {
+	redirectProgramSpecs
+	redirectMapSpecs
+	redirectVariableSpecs
+}
+
+// redirectProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type redirectProgramSpecs struct {
+	IstioInbound  *ebpf.ProgramSpec `ebpf:"istio_inbound"`
+	IstioLoopback *ebpf.ProgramSpec `ebpf:"istio_loopback"`
+	IstioOutbound *ebpf.ProgramSpec `ebpf:"istio_outbound"`
+	IstioVirtual  *ebpf.ProgramSpec `ebpf:"istio_virtual"`
+}
+
+// redirectMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type redirectMapSpecs struct {
+	IstioCidrs *ebpf.MapSpec `ebpf:"istio_cidrs"`
+	IstioDns   *ebpf.MapSpec `ebpf:"istio_dns"`
+	IstioPorts *ebpf.MapSpec `ebpf:"istio_ports"`
+	IstioUids  *ebpf.MapSpec `ebpf:"istio_uids"`
+}
+
+// redirectVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type redirectVariableSpecs struct {
+	CaptureExclusions           *ebpf.VariableSpec `ebpf:"capture_exclusions"`
+	DnsCapturePort              *ebpf.VariableSpec `ebpf:"dns_capture_port"`
+	EnableIpv6                  *ebpf.VariableSpec `ebpf:"enable_ipv6"`
+	InpodMark                   *ebpf.VariableSpec `ebpf:"inpod_mark"`
+	InpodMask                   *ebpf.VariableSpec `ebpf:"inpod_mask"`
+	LoopbackIfindex             *ebpf.VariableSpec `ebpf:"loopback_ifindex"`
+	ProbeV4                     *ebpf.VariableSpec `ebpf:"probe_v4"`
+	ProbeV6                     *ebpf.VariableSpec `ebpf:"probe_v6"`
+	RedirectDns                 *ebpf.VariableSpec `ebpf:"redirect_dns"`
+	TproxyMark                  *ebpf.VariableSpec `ebpf:"tproxy_mark"`
+	TproxyMask                  *ebpf.VariableSpec `ebpf:"tproxy_mask"`
+	ZtunnelInboundPlaintextPort *ebpf.VariableSpec `ebpf:"ztunnel_inbound_plaintext_port"`
+	ZtunnelInboundPort          *ebpf.VariableSpec `ebpf:"ztunnel_inbound_port"`
+	ZtunnelOutboundPort         *ebpf.VariableSpec `ebpf:"ztunnel_outbound_port"`
+}
+
+// redirectObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadRedirectObjects or ebpf.CollectionSpec.LoadAndAssign.
+type redirectObjects struct {
+	redirectPrograms
+	redirectMaps
+	redirectVariables
+}
+
+func (o *redirectObjects) Close() error {
+	return _RedirectClose(
+		&o.redirectPrograms,
+		&o.redirectMaps,
+	)
+}
+
+// redirectMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadRedirectObjects or ebpf.CollectionSpec.LoadAndAssign.
+type redirectMaps struct {
+	IstioCidrs *ebpf.Map `ebpf:"istio_cidrs"`
+	IstioDns   *ebpf.Map `ebpf:"istio_dns"`
+	IstioPorts *ebpf.Map `ebpf:"istio_ports"`
+	IstioUids  *ebpf.Map `ebpf:"istio_uids"`
+}
+
+func (m *redirectMaps) Close() error {
+	return _RedirectClose(
+		m.IstioCidrs,
+		m.IstioDns,
+		m.IstioPorts,
+		m.IstioUids,
+	)
+}
+
+// redirectVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadRedirectObjects or ebpf.CollectionSpec.LoadAndAssign.
+type redirectVariables struct {
+	CaptureExclusions           *ebpf.Variable `ebpf:"capture_exclusions"`
+	DnsCapturePort              *ebpf.Variable `ebpf:"dns_capture_port"`
+	EnableIpv6                  *ebpf.Variable `ebpf:"enable_ipv6"`
+	InpodMark                   *ebpf.Variable `ebpf:"inpod_mark"`
+	InpodMask                   *ebpf.Variable `ebpf:"inpod_mask"`
+	LoopbackIfindex             *ebpf.Variable `ebpf:"loopback_ifindex"`
+	ProbeV4                     *ebpf.Variable `ebpf:"probe_v4"`
+	ProbeV6                     *ebpf.Variable `ebpf:"probe_v6"`
+	RedirectDns                 *ebpf.Variable `ebpf:"redirect_dns"`
+	TproxyMark                  *ebpf.Variable `ebpf:"tproxy_mark"`
+	TproxyMask                  *ebpf.Variable `ebpf:"tproxy_mask"`
+	ZtunnelInboundPlaintextPort *ebpf.Variable `ebpf:"ztunnel_inbound_plaintext_port"`
+	ZtunnelInboundPort          *ebpf.Variable `ebpf:"ztunnel_inbound_port"`
+	ZtunnelOutboundPort         *ebpf.Variable `ebpf:"ztunnel_outbound_port"`
+}
+
+// redirectPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadRedirectObjects or ebpf.CollectionSpec.LoadAndAssign.
+type redirectPrograms struct {
+	IstioInbound  *ebpf.Program `ebpf:"istio_inbound"`
+	IstioLoopback *ebpf.Program `ebpf:"istio_loopback"`
+	IstioOutbound *ebpf.Program `ebpf:"istio_outbound"`
+	IstioVirtual  *ebpf.Program `ebpf:"istio_virtual"`
+}
+
+func (p *redirectPrograms) Close() error {
+	return _RedirectClose(
+		p.IstioInbound,
+		p.IstioLoopback,
+		p.IstioOutbound,
+		p.IstioVirtual,
+	)
+}
+
+func _RedirectClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed redirect_bpfeb.o
+var _RedirectBytes []byte
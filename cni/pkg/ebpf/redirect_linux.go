@@ -0,0 +1,276 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"istio.io/istio/cni/pkg/scopes"
+)
+
+var log = scopes.CNIAgent
+
+const (
+	// filterPrefix is the prefix of the names of the TC filters managed by Istio.
+	filterPrefix = "istio_"
+
+	// The filters are named after the programs they run, see bpf/redirect.c.
+	inboundFilter  = filterPrefix + "inbound"
+	outboundFilter = filterPrefix + "outbound"
+	loopbackFilter = filterPrefix + "loopback"
+	virtualFilter  = filterPrefix + "virtual"
+
+	filterPriority = 1
+)
+
+// filter is a program to attach to one direction of an interface.
+type filter struct {
+	link   netlink.Link
+	parent uint32
+	name   string
+}
+
+// supportCheckConfig enables every code path of the programs, so that the kernel verifies all of them.
+var supportCheckConfig = Config{
+	EnableIPv6:              true,
+	RedirectDNS:             true,
+	ExcludeInboundPorts:     []uint16{1},
+	ExcludeOutboundPorts:    []uint16{1},
+	ExcludeOutboundIPRanges: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24"), netip.MustParsePrefix("2001:db8::/32")},
+	ExcludeOutboundUIDs:     []int64{1},
+}
+
+// CheckSupport returns an error if the kernel cannot run the redirection programs, for instance because it is
+// too old or because the agent lacks the privileges to load them.
+func CheckSupport() error {
+	coll, err := newCollection(supportCheckConfig, 1)
+	if err != nil {
+		return err
+	}
+	coll.Close()
+	return nil
+}
+
+// newCollection loads the redirection programs configured with cfg, and the maps they share.
+func newCollection(cfg Config, loopbackIndex int) (*ebpf.Collection, error) {
+	spec, err := collectionSpec(cfg, loopbackIndex)
+	if err != nil {
+		return nil, err
+	}
+	coll, err := ebpf.NewCollection(spec)
+	if err != nil {
+		var verifierErr *ebpf.VerifierError
+		if errors.As(err, &verifierErr) {
+			// Include the full verifier log, to report why a program was rejected.
+			return nil, fmt.Errorf("failed to load programs: %+v", verifierErr)
+		}
+		return nil, fmt.Errorf("failed to load programs: %v", err)
+	}
+	if err := fillExclusions(cfg, coll); err != nil {
+		coll.Close()
+		return nil, err
+	}
+	return coll, nil
+}
+
+// Attach loads the redirection programs and attaches them to the interfaces of the current network namespace,
+// replacing the programs attached before. It must be called from within the pod network namespace.
+func Attach(cfg Config, virtualInterfaces []string) error {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list interfaces: %v", err)
+	}
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		return fmt.Errorf("failed to find the loopback interface: %v", err)
+	}
+
+	// Routing the redirected packets through the loopback interface requires accepting local source addresses
+	// there, and the DNS queries sent to the DNS proxy have a loopback destination.
+	if err := setSysctl("net/ipv4/conf/lo/accept_local", "1"); err != nil {
+		return err
+	}
+	if cfg.RedirectDNS {
+		if err := setSysctl("net/ipv4/conf/lo/route_localnet", "1"); err != nil {
+			return err
+		}
+	}
+
+	coll, err := newCollection(cfg, lo.Attrs().Index)
+	if err != nil {
+		return err
+	}
+	// The attached filters hold a reference to the programs and to the DNS map, they live as long as they are
+	// attached.
+	defer coll.Close()
+
+	filters := expectedFilters(cfg, links, lo, virtualInterfaces)
+	for _, f := range filters {
+		if err := attachFilter(f, coll.Programs[f.name].FD()); err != nil {
+			return err
+		}
+		log.Debugf("attached %s program to %s", f.name, f.link.Attrs().Name)
+	}
+
+	// Remove the programs which are no longer needed, for instance when an interface became virtual.
+	var errs []error
+	for _, link := range links {
+		for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+			errs = append(errs, removeFilters(link, parent, func(name string) bool {
+				return !slices.Contains(filters, filter{link, parent, name})
+			}))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Verify reports whether the programs attached to the interfaces of the current network namespace differ from the
+// ones Attach would attach, for instance because they were removed, or because a new interface was added.
+func Verify(cfg Config, virtualInterfaces []string) (bool, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return false, fmt.Errorf("failed to list interfaces: %v", err)
+	}
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		return false, fmt.Errorf("failed to find the loopback interface: %v", err)
+	}
+	expected := expectedFilters(cfg, links, lo, virtualInterfaces)
+	attached := 0
+	for _, link := range links {
+		for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+			filters, err := netlink.FilterList(link, parent)
+			if err != nil && !errors.Is(err, unix.EINVAL) && !errors.Is(err, unix.ENOENT) {
+				return false, fmt.Errorf("failed to list filters of %s: %v", link.Attrs().Name, err)
+			}
+			for _, f := range filters {
+				bpfFilter, ok := f.(*netlink.BpfFilter)
+				if !ok || !strings.HasPrefix(bpfFilter.Name, filterPrefix) {
+					continue
+				}
+				if !slices.Contains(expected, filter{link, parent, bpfFilter.Name}) {
+					log.Debugf("unexpected %s program on %s", bpfFilter.Name, link.Attrs().Name)
+					return true, nil
+				}
+				attached++
+			}
+		}
+	}
+	return attached != len(expected), nil
+}
+
+// expectedFilters returns the programs to attach to the interfaces.
+func expectedFilters(cfg Config, links []netlink.Link, lo netlink.Link, virtualInterfaces []string) []filter {
+	var filters []filter
+	for _, link := range links {
+		switch {
+		case link.Attrs().Index == lo.Attrs().Index:
+			filters = append(filters, filter{link, netlink.HANDLE_MIN_INGRESS, loopbackFilter})
+		case slices.Contains(virtualInterfaces, link.Attrs().Name):
+			filters = append(filters, filter{link, netlink.HANDLE_MIN_INGRESS, virtualFilter})
+		default:
+			if !cfg.IngressMode {
+				filters = append(filters, filter{link, netlink.HANDLE_MIN_INGRESS, inboundFilter})
+			}
+			filters = append(filters, filter{link, netlink.HANDLE_MIN_EGRESS, outboundFilter})
+		}
+	}
+	return filters
+}
+
+// Detach removes the redirection programs from the interfaces of the current network namespace. The sysctls set by
+// Attach are left in place, they have no effect without the programs.
+func Detach() error {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list interfaces: %v", err)
+	}
+	var errs []error
+	for _, link := range links {
+		for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+			errs = append(errs, removeFilters(link, parent, func(string) bool { return true }))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func attachFilter(f filter, program int) error {
+	qdisc := &netlink.Clsact{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: f.link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+	}
+	if err := netlink.QdiscAdd(qdisc); err != nil && !errors.Is(err, unix.EEXIST) {
+		return fmt.Errorf("failed to add clsact qdisc to %s: %v", f.link.Attrs().Name, err)
+	}
+	bpfFilter := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: f.link.Attrs().Index,
+			Parent:    f.parent,
+			Handle:    netlink.MakeHandle(0, 1),
+			Protocol:  unix.ETH_P_ALL,
+			Priority:  filterPriority,
+		},
+		Fd:           program,
+		Name:         f.name,
+		DirectAction: true,
+	}
+	if err := netlink.FilterReplace(bpfFilter); err != nil {
+		return fmt.Errorf("failed to attach %s program to %s: %v", f.name, f.link.Attrs().Name, err)
+	}
+	return nil
+}
+
+// removeFilters removes the Istio filters of the given direction of link for which remove returns true.
+func removeFilters(link netlink.Link, parent uint32, remove func(name string) bool) error {
+	filters, err := netlink.FilterList(link, parent)
+	if err != nil {
+		if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOENT) {
+			// No clsact qdisc, hence no filters.
+			return nil
+		}
+		return fmt.Errorf("failed to list filters of %s: %v", link.Attrs().Name, err)
+	}
+	var errs []error
+	for _, f := range filters {
+		bpfFilter, ok := f.(*netlink.BpfFilter)
+		if !ok || !strings.HasPrefix(bpfFilter.Name, filterPrefix) || !remove(bpfFilter.Name) {
+			continue
+		}
+		if err := netlink.FilterDel(bpfFilter); err != nil && !errors.Is(err, unix.ENOENT) {
+			errs = append(errs, fmt.Errorf("failed to remove %s program from %s: %v", bpfFilter.Name, link.Attrs().Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setSysctl(name, value string) error {
+	if err := os.WriteFile(filepath.Join("/proc/sys", name), []byte(value), 0o644); err != nil {
+		return fmt.Errorf("failed to set %s: %v", strings.ReplaceAll(name, "/", "."), err)
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/cni/pkg/iptables"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// netnsThread runs functions on a locked OS thread within its own network namespace. The thread is never unlocked,
+// so the Go runtime discards it once the namespace is no longer needed.
+type netnsThread struct {
+	fns chan func()
+}
+
+func newNetnsThread(t *testing.T) *netnsThread {
+	n := &netnsThread{fns: make(chan func())}
+	errCh := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			errCh <- err
+			return
+		}
+		errCh <- nil
+		for fn := range n.fns {
+			fn()
+		}
+	}()
+	if err := <-errCh; err != nil {
+		t.Skipf("cannot create a network namespace, skipping: %v", err)
+	}
+	t.Cleanup(func() { close(n.fns) })
+	assert.NoError(t, n.run(func() error {
+		lo, err := netlink.LinkByName("lo")
+		if err != nil {
+			return err
+		}
+		return netlink.LinkSetUp(lo)
+	}))
+	return n
+}
+
+func (n *netnsThread) run(fn func() error) error {
+	errCh := make(chan error)
+	n.fns <- func() { errCh <- fn() }
+	return <-errCh
+}
+
+func addLink(name, cidr string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+	addr, err := netlink.ParseAddr(cidr)
+	if err != nil {
+		return err
+	}
+	if err := netlink.AddrAdd(link, addr); err != nil {
+		return err
+	}
+	return netlink.LinkSetUp(link)
+}
+
+func socketControl(level, opt, value int) func(string, string, syscall.RawConn) error {
+	return func(_, _ string, c syscall.RawConn) error {
+		var err error
+		if cerr := c.Control(func(fd uintptr) {
+			err = unix.SetsockoptInt(int(fd), level, opt, value)
+		}); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+}
+
+// listen listens like ztunnel does in the pod network namespace: the listeners are transparent, and the replies of
+// the inbound connections carry the ztunnel mark.
+func listen(network, address string, mark bool) (any, error) {
+	lc := net.ListenConfig{Control: socketControl(unix.SOL_IP, unix.IP_TRANSPARENT, 1)}
+	if mark {
+		transparent := lc.Control
+		lc.Control = func(network, address string, c syscall.RawConn) error {
+			if err := transparent(network, address, c); err != nil {
+				return err
+			}
+			return socketControl(unix.SOL_SOCKET, unix.SO_MARK, config.InpodMark)(network, address, c)
+		}
+	}
+	if network == "udp" {
+		return lc.ListenPacket(context.Background(), network, address)
+	}
+	return lc.Listen(context.Background(), network, address)
+}
+
+func accept(t *testing.T, l net.Listener) net.Conn {
+	t.Helper()
+	assert.NoError(t, l.(*net.TCPListener).SetDeadline(time.Now().Add(5*time.Second)))
+	conn, err := l.Accept()
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRedirect(t *testing.T) {
+	pod := newNetnsThread(t)
+	node := newNetnsThread(t)
+
+	if err := pod.run(CheckSupport); err != nil {
+		t.Skipf("eBPF redirection not supported, skipping: %v", err)
+	}
+
+	var nodeNetns int
+	assert.NoError(t, node.run(func() (err error) {
+		nodeNetns, err = unix.Open("/proc/thread-self/ns/net", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		return err
+	}))
+	defer unix.Close(nodeNetns)
+	assert.NoError(t, pod.run(func() error {
+		veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eth0"}, PeerName: "veth0"}
+		if err := netlink.LinkAdd(veth); err != nil {
+			return err
+		}
+		peer, err := netlink.LinkByName("veth0")
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetNsFd(peer, nodeNetns); err != nil {
+			return err
+		}
+		return addLink("eth0", "10.1.0.1/24")
+	}))
+	assert.NoError(t, node.run(func() error { return addLink("veth0", "10.1.0.2/24") }))
+
+	ambientCfg := &config.AmbientConfig{}
+	assert.NoError(t, pod.run(func() error {
+		if err := Attach(Config{
+			RedirectDNS:          true,
+			HostProbeSNATAddress: netip.MustParseAddr("169.254.7.127"),
+			ExcludeInboundPorts:  []uint16{9091},
+		}, nil); err != nil {
+			return err
+		}
+		if err := iptables.AddLoopbackRoutes(ambientCfg); err != nil {
+			return err
+		}
+		return iptables.AddInpodMarkIPRule(ambientCfg)
+	}))
+
+	t.Run("outbound", func(t *testing.T) {
+		var l net.Listener
+		assert.NoError(t, pod.run(func() error {
+			ln, err := listen("tcp", "127.0.0.1:15001", false)
+			l, _ = ln.(net.Listener)
+			return err
+		}))
+		defer l.Close()
+		assert.NoError(t, pod.run(func() error {
+			conn, err := net.DialTimeout("tcp", "10.1.0.2:8080", 5*time.Second)
+			if err == nil {
+				t.Cleanup(func() { conn.Close() })
+			}
+			return err
+		}))
+		assert.Equal(t, accept(t, l).LocalAddr().String(), "10.1.0.2:8080")
+	})
+
+	t.Run("ztunnel traffic", func(t *testing.T) {
+		var l net.Listener
+		assert.NoError(t, node.run(func() (err error) {
+			l, err = net.Listen("tcp", "10.1.0.2:8081")
+			return err
+		}))
+		defer l.Close()
+		assert.NoError(t, pod.run(func() error {
+			d := net.Dialer{Timeout: 5 * time.Second, Control: socketControl(unix.SOL_SOCKET, unix.SO_MARK, config.InpodMark)}
+			conn, err := d.Dial("tcp", "10.1.0.2:8081")
+			if err == nil {
+				t.Cleanup(func() { conn.Close() })
+			}
+			return err
+		}))
+		assert.Equal(t, accept(t, l).RemoteAddr().(*net.TCPAddr).IP.String(), "10.1.0.1")
+	})
+
+	t.Run("inbound", func(t *testing.T) {
+		var l net.Listener
+		assert.NoError(t, pod.run(func() error {
+			ln, err := listen("tcp", "0.0.0.0:15006", true)
+			l, _ = ln.(net.Listener)
+			return err
+		}))
+		defer l.Close()
+		assert.NoError(t, node.run(func() error {
+			conn, err := net.DialTimeout("tcp", "10.1.0.1:9090", 5*time.Second)
+			if err == nil {
+				t.Cleanup(func() { conn.Close() })
+			}
+			return err
+		}))
+		assert.Equal(t, accept(t, l).LocalAddr().String(), "10.1.0.1:9090")
+	})
+
+	t.Run("inbound excluded port", func(t *testing.T) {
+		var ztunnel, app net.Listener
+		assert.NoError(t, pod.run(func() error {
+			ln, err := listen("tcp", "0.0.0.0:15006", true)
+			ztunnel, _ = ln.(net.Listener)
+			if err != nil {
+				return err
+			}
+			app, err = net.Listen("tcp", "10.1.0.1:9091")
+			return err
+		}))
+		defer ztunnel.Close()
+		defer app.Close()
+		assert.NoError(t, node.run(func() error {
+			conn, err := net.DialTimeout("tcp", "10.1.0.1:9091", 5*time.Second)
+			if err == nil {
+				t.Cleanup(func() { conn.Close() })
+			}
+			return err
+		}))
+		assert.Equal(t, accept(t, app).LocalAddr().String(), "10.1.0.1:9091")
+	})
+
+	t.Run("dns", func(t *testing.T) {
+		var proxy net.PacketConn
+		assert.NoError(t, pod.run(func() error {
+			pc, err := listen("udp", "127.0.0.1:15053", false)
+			proxy, _ = pc.(net.PacketConn)
+			return err
+		}))
+		defer proxy.Close()
+		go func() {
+			buf := make([]byte, 512)
+			n, from, err := proxy.ReadFrom(buf)
+			if err == nil {
+				_, _ = proxy.WriteTo([]byte(strings.ToUpper(string(buf[:n]))), from)
+			}
+		}()
+		var conn net.Conn
+		assert.NoError(t, pod.run(func() (err error) {
+			conn, err = net.Dial("udp", "10.1.0.2:53")
+			return err
+		}))
+		defer conn.Close()
+		assert.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+		_, err := conn.Write([]byte("query"))
+		assert.NoError(t, err)
+		// The connected socket only receives the reply if it comes from the resolver it sent the query to.
+		buf := make([]byte, 512)
+		n, err := conn.Read(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, string(buf[:n]), "QUERY")
+	})
+
+	t.Run("detach", func(t *testing.T) {
+		assert.NoError(t, pod.run(func() error {
+			if err := Detach(); err != nil {
+				return err
+			}
+			links, err := netlink.LinkList()
+			if err != nil {
+				return err
+			}
+			for _, link := range links {
+				for _, parent := range []uint32{netlink.HANDLE_MIN_INGRESS, netlink.HANDLE_MIN_EGRESS} {
+					filters, err := netlink.FilterList(link, parent)
+					if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, unix.EINVAL) {
+						return err
+					}
+					if len(filters) > 0 {
+						return errors.New("filters left on " + link.Attrs().Name)
+					}
+				}
+			}
+			return nil
+		}))
+	})
+}
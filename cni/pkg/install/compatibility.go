@@ -0,0 +1,382 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/util"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient"
+	"istio.io/istio/pkg/slices"
+)
+
+// Values of the CNI compatibility check setting.
+const (
+	// CompatibilityCheckEnforce refuses to install the plugin on top of a known incompatible primary CNI.
+	CompatibilityCheckEnforce = "enforce"
+	// CompatibilityCheckWarn only reports incompatibilities.
+	CompatibilityCheckWarn = "warn"
+	// CompatibilityCheckOff disables the check.
+	CompatibilityCheckOff = "off"
+)
+
+// ValidateCompatibilityCheck returns an error if the CNI compatibility check setting is unknown.
+func ValidateCompatibilityCheck(check string) error {
+	switch check {
+	case CompatibilityCheckEnforce, CompatibilityCheckWarn, CompatibilityCheckOff:
+		return nil
+	}
+	return fmt.Errorf("invalid CNI compatibility check %q, must be one of %s, %s or %s",
+		check, CompatibilityCheckEnforce, CompatibilityCheckWarn, CompatibilityCheckOff)
+}
+
+// Severity of a compatibility finding.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	// SeverityError findings refuse the installation, unless the check only warns.
+	SeverityError Severity = "error"
+)
+
+// Primary CNIs with known interactions with Istio redirection.
+const (
+	cniCilium    = "cilium"
+	cniCalico    = "calico"
+	cniMultus    = "multus"
+	cniAWSVPCCNI = "aws-vpc-cni"
+)
+
+// Modes of the primary CNIs, as detected from the node CNI config.
+const (
+	// Cilium runs with cni.exclusive=true, and renames the configs of the other CNIs to *.cilium_bak.
+	// Only detected from the live Cilium config, see ciliumExclusive.
+	modeCiliumExclusive = "exclusive"
+	// Cilium is chained after another CNI.
+	modeCiliumChained = "chained"
+	// Multus runs as a thick plugin, with a shim binary talking to a daemon.
+	modeMultusThick = "thick"
+	modeMultusThin  = "thin"
+	// Security groups for pods are enforced in strict mode, pods with branch ENIs bypass the node routing.
+	modeBranchENIStrict   = "branch-eni-strict"
+	modeBranchENIStandard = "branch-eni-standard"
+)
+
+// PrimaryCNI is the primary CNI of the node, as detected from the node CNI config.
+type PrimaryCNI struct {
+	// Name is the well known name of the CNI, or the type of the first plugin of the config otherwise.
+	Name string
+	// Modes are the detected modes of the CNI relevant to the compatibility with Istio.
+	Modes []string
+}
+
+func (p PrimaryCNI) String() string {
+	if len(p.Modes) == 0 {
+		return p.Name
+	}
+	return p.Name + " (" + strings.Join(p.Modes, ", ") + ")"
+}
+
+// compatibilityRule describes a known interaction of a primary CNI, in one of its modes, with Istio.
+type compatibilityRule struct {
+	id  string
+	cni string
+	// mode is empty for rules applying to all the modes of the CNI.
+	mode string
+	// ambientOnly rules only apply when ambient is enabled.
+	ambientOnly bool
+	severity    Severity
+	message     string
+}
+
+// compatibilityRules is the table of the known interactions of primary CNIs with Istio.
+var compatibilityRules = []compatibilityRule{
+	{
+		id:       "cilium-exclusive",
+		cni:      cniCilium,
+		mode:     modeCiliumExclusive,
+		severity: SeverityError,
+		message: "Cilium in exclusive mode removes the CNI configs of the other plugins, including istio-cni; " +
+			"set cni.exclusive=false in the Cilium configuration",
+	},
+	{
+		id:          "cilium-socket-lb",
+		cni:         cniCilium,
+		ambientOnly: true,
+		severity:    SeverityInfo,
+		message: "Cilium socket load balancing bypasses ambient redirection unless it is restricted to the host " +
+			"namespace with socketLB.hostNamespaceOnly=true, which cannot be detected from the CNI config",
+	},
+	{
+		id:          "calico-ebpf",
+		cni:         cniCalico,
+		ambientOnly: true,
+		severity:    SeverityInfo,
+		message: "the Calico eBPF dataplane cannot be detected from the CNI config; if it is enabled, check its " +
+			"connect-time load balancing settings against the Istio platform prerequisites",
+	},
+	{
+		id:       "multus-secondary-networks",
+		cni:      cniMultus,
+		severity: SeverityWarning,
+		message: "Multus only chains istio-cni to the default network; pods attached to secondary networks " +
+			"need a NetworkAttachmentDefinition referencing istio-cni",
+	},
+	{
+		id:          "aws-vpc-cni-branch-eni-strict",
+		cni:         cniAWSVPCCNI,
+		mode:        modeBranchENIStrict,
+		ambientOnly: true,
+		severity:    SeverityWarning,
+		message: "pods using security groups for pods bypass ambient redirection in strict enforcing mode; " +
+			"set POD_SECURITY_GROUP_ENFORCING_MODE=standard on the aws-node DaemonSet",
+	},
+}
+
+// CompatibilityFinding is a compatibility rule matching the primary CNI of the node.
+type CompatibilityFinding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+// CompatibilityReport is the result of the compatibility check of the primary CNI of the node.
+type CompatibilityReport struct {
+	PrimaryCNI PrimaryCNI
+	Findings   []CompatibilityFinding
+}
+
+// Refused returns true if the primary CNI is known to be incompatible with Istio.
+func (r CompatibilityReport) Refused() bool {
+	return slices.ContainsFunc(r.Findings, func(f CompatibilityFinding) bool {
+		return f.Severity == SeverityError
+	})
+}
+
+// checkCNICompatibility matches the primary CNI of the node against the compatibility rules.
+func checkCNICompatibility(primary PrimaryCNI, ambientEnabled bool) CompatibilityReport {
+	report := CompatibilityReport{PrimaryCNI: primary}
+	for _, rule := range compatibilityRules {
+		if rule.cni != primary.Name || (rule.mode != "" && !slices.Contains(primary.Modes, rule.mode)) {
+			continue
+		}
+		if rule.ambientOnly && !ambientEnabled {
+			continue
+		}
+		report.Findings = append(report.Findings, CompatibilityFinding{
+			Rule:     rule.id,
+			Severity: rule.severity,
+			Message:  rule.message,
+		})
+	}
+	return report
+}
+
+// detectPrimaryCNI detects the primary CNI of the node, and its relevant modes, from the primary CNI config file.
+func detectPrimaryCNI(cniConfigFilepath string) (PrimaryCNI, error) {
+	raw, err := os.ReadFile(cniConfigFilepath)
+	if err != nil {
+		return PrimaryCNI{}, err
+	}
+	var cniConfig map[string]any
+	if err := json.Unmarshal(raw, &cniConfig); err != nil {
+		return PrimaryCNI{}, fmt.Errorf("error loading CNI config (JSON error): %v", err)
+	}
+	var plugins []map[string]any
+	if _, ok := cniConfig["type"]; ok {
+		plugins = append(plugins, cniConfig)
+	} else {
+		rawPlugins, err := util.GetPlugins(cniConfig)
+		if err != nil {
+			return PrimaryCNI{}, err
+		}
+		for _, rawPlugin := range rawPlugins {
+			plugin, err := util.GetPlugin(rawPlugin)
+			if err != nil {
+				return PrimaryCNI{}, err
+			}
+			if plugin["type"] != "istio-cni" {
+				plugins = append(plugins, plugin)
+			}
+		}
+	}
+	if len(plugins) == 0 {
+		return PrimaryCNI{}, fmt.Errorf("no plugins found in %s", cniConfigFilepath)
+	}
+	pluginTypes := slices.Map(plugins, func(p map[string]any) string {
+		t, _ := p["type"].(string)
+		return t
+	})
+
+	var primary PrimaryCNI
+	switch {
+	case slices.Contains(pluginTypes, "multus") || slices.Contains(pluginTypes, "multus-shim"):
+		// Multus delegates to the actual primary CNI, which is not in its own config.
+		primary.Name = cniMultus
+		if slices.Contains(pluginTypes, "multus-shim") {
+			primary.Modes = append(primary.Modes, modeMultusThick)
+		} else {
+			primary.Modes = append(primary.Modes, modeMultusThin)
+		}
+	case slices.Contains(pluginTypes, "cilium-cni"):
+		primary.Name = cniCilium
+		if pluginTypes[0] != "cilium-cni" {
+			primary.Modes = append(primary.Modes, modeCiliumChained)
+		}
+	case pluginTypes[0] == "calico":
+		primary.Name = cniCalico
+	case pluginTypes[0] == "aws-cni":
+		primary.Name = cniAWSVPCCNI
+		switch plugins[0]["podSGEnforcingMode"] {
+		case "strict":
+			primary.Modes = append(primary.Modes, modeBranchENIStrict)
+		case "standard":
+			primary.Modes = append(primary.Modes, modeBranchENIStandard)
+		}
+	default:
+		primary.Name = pluginTypes[0]
+	}
+	return primary, nil
+}
+
+// ciliumConfigName is the ConfigMap holding the live config of the Cilium agents.
+const ciliumConfigName = "cilium-config"
+
+// ciliumNamespaces are the namespaces Cilium is commonly installed in.
+var ciliumNamespaces = []string{"kube-system", "cilium"}
+
+// ciliumExclusive reads whether Cilium runs in exclusive mode from its live config. The *.cilium_bak files
+// Cilium leaves in the CNI config directory in exclusive mode are not used, as they remain after the mode is
+// disabled. ok is false if the live config could not be found.
+func ciliumExclusive(ctx context.Context, client kube.Client) (exclusive bool, ok bool) {
+	for _, ns := range ciliumNamespaces {
+		cm, err := client.Kube().CoreV1().ConfigMaps(ns).Get(ctx, ciliumConfigName, metav1.GetOptions{})
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				installLog.Warnf("failed to read the Cilium config %s/%s: %v", ns, ciliumConfigName, err)
+			}
+			continue
+		}
+		value, found := cm.Data["cni-exclusive"]
+		if !found {
+			return false, false
+		}
+		return value == "true", true
+	}
+	return false, false
+}
+
+// EnableCompatibilityClient lets the CNI compatibility check use the cluster: the findings are reported as events
+// on the node, in addition to logs and metrics, and the live config of Cilium is read to detect its exclusive mode.
+func (in *Installer) EnableCompatibilityClient() error {
+	restConfig, err := kube.DefaultRestConfig("", "")
+	if err != nil {
+		return err
+	}
+	client, err := kube.NewClient(kube.NewClientConfigForRestConfig(restConfig), "")
+	if err != nil {
+		return err
+	}
+	events := kclient.NewEventRecorder(client, "istio-cni")
+	in.kubeClient = client
+	in.events = &events
+	return nil
+}
+
+// checkCompatibility checks the primary CNI the plugin is chained to, and returns an error if it is
+// known to be incompatible and the check is enforced.
+func (in *Installer) checkCompatibility(ctx context.Context) error {
+	if !in.cfg.ChainedCNIPlugin || in.cfg.CNICompatibilityCheck == CompatibilityCheckOff {
+		return nil
+	}
+	cniConfigFilepath, err := getCNIConfigFilepath(ctx, in.cfg.CNIConfName, in.cfg.MountedCNINetDir, true)
+	if err != nil {
+		return err
+	}
+	primary, err := detectPrimaryCNI(cniConfigFilepath)
+	if err != nil {
+		// Writing the config will fail as well if it is invalid, leave the error to it.
+		installLog.Warnf("failed to detect the primary CNI from %s: %v", cniConfigFilepath, err)
+		return nil
+	}
+	if primary.Name == cniCilium && in.kubeClient != nil {
+		if exclusive, ok := ciliumExclusive(ctx, in.kubeClient); !ok {
+			installLog.Infof("Cilium config %s not found, its exclusive mode cannot be checked", ciliumConfigName)
+		} else if exclusive {
+			primary.Modes = append(primary.Modes, modeCiliumExclusive)
+		}
+	}
+
+	report := checkCNICompatibility(primary, in.cfg.AmbientEnabled)
+	in.reportCompatibility(report)
+	if report.Refused() && in.cfg.CNICompatibilityCheck != CompatibilityCheckWarn {
+		return fmt.Errorf("primary CNI %s is not compatible with Istio, set CNI_COMPATIBILITY_CHECK=%s to install anyway",
+			primary, CompatibilityCheckWarn)
+	}
+	return nil
+}
+
+// reportCompatibility reports the compatibility findings as logs, metrics and, if enabled, node events.
+func (in *Installer) reportCompatibility(report CompatibilityReport) {
+	installLog.Infof("detected primary CNI %s, %d compatibility findings", report.PrimaryCNI, len(report.Findings))
+	counts := map[Severity]int{SeverityInfo: 0, SeverityWarning: 0, SeverityError: 0}
+	for _, f := range report.Findings {
+		counts[f.Severity]++
+		log := installLog.WithLabels("primaryCNI", report.PrimaryCNI.Name, "rule", f.Rule)
+		eventType := corev1.EventTypeWarning
+		switch f.Severity {
+		case SeverityInfo:
+			log.Info(f.Message)
+			eventType = corev1.EventTypeNormal
+		case SeverityWarning:
+			log.Warn(f.Message)
+		case SeverityError:
+			log.Error(f.Message)
+		}
+		if in.events != nil {
+			in.events.Write(in.node(), eventType, ReasonCNICompatibility, "primary CNI %s: %s", report.PrimaryCNI, f.Message)
+		}
+	}
+	for severity, count := range counts {
+		compatibilityFindings.With(primaryCNILabel.Value(report.PrimaryCNI.Name), severityLabel.Value(string(severity))).
+			Record(float64(count))
+	}
+}
+
+// ReasonCNICompatibility is the reason of the events reporting compatibility findings.
+const ReasonCNICompatibility = "CNICompatibility"
+
+// node returns a reference to the node the installer runs on, for events. Nodes are referenced by name,
+// as the kubelet does.
+func (in *Installer) node() *corev1.Node {
+	return &corev1.Node{
+		TypeMeta: metav1.TypeMeta{Kind: "Node", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: in.cfg.K8sNodeName,
+			UID:  types.UID(in.cfg.K8sNodeName),
+		},
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/pkg/file"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestCNICompatibility(t *testing.T) {
+	cases := []struct {
+		// fixture is the directory of the CNI config in testdata/compatibility.
+		fixture   string
+		file      string
+		ambient   bool
+		want      PrimaryCNI
+		wantRules []string
+		refused   bool
+	}{
+		{
+			fixture:   "calico",
+			file:      "10-calico.conflist",
+			ambient:   true,
+			want:      PrimaryCNI{Name: "calico"},
+			wantRules: []string{"calico-ebpf"},
+		},
+		{
+			fixture: "calico",
+			file:    "10-calico.conflist",
+			want:    PrimaryCNI{Name: "calico"},
+		},
+		{
+			fixture:   "cilium",
+			file:      "05-cilium.conflist",
+			ambient:   true,
+			want:      PrimaryCNI{Name: "cilium"},
+			wantRules: []string{"cilium-socket-lb"},
+		},
+		{
+			// Backups of the configs of other CNIs do not imply the exclusive mode, which is read from the cluster.
+			fixture: "cilium-stale-backup",
+			file:    "05-cilium.conflist",
+			want:    PrimaryCNI{Name: "cilium"},
+		},
+		{
+			fixture:   "cilium-chained",
+			file:      "05-cilium.conflist",
+			ambient:   true,
+			want:      PrimaryCNI{Name: "cilium", Modes: []string{"chained"}},
+			wantRules: []string{"cilium-socket-lb"},
+		},
+		{
+			fixture:   "multus-thick",
+			file:      "00-multus.conf",
+			want:      PrimaryCNI{Name: "multus", Modes: []string{"thick"}},
+			wantRules: []string{"multus-secondary-networks"},
+		},
+		{
+			fixture:   "aws-vpc-cni-strict",
+			file:      "10-aws.conflist",
+			ambient:   true,
+			want:      PrimaryCNI{Name: "aws-vpc-cni", Modes: []string{"branch-eni-strict"}},
+			wantRules: []string{"aws-vpc-cni-branch-eni-strict"},
+		},
+		{
+			fixture: "aws-vpc-cni-strict",
+			file:    "10-aws.conflist",
+			want:    PrimaryCNI{Name: "aws-vpc-cni", Modes: []string{"branch-eni-strict"}},
+		},
+		{
+			fixture: "aws-vpc-cni-standard",
+			file:    "10-aws.conflist",
+			ambient: true,
+			want:    PrimaryCNI{Name: "aws-vpc-cni", Modes: []string{"branch-eni-standard"}},
+		},
+	}
+	for _, tt := range cases {
+		name := tt.fixture
+		if tt.ambient {
+			name += " ambient"
+		}
+		t.Run(name, func(t *testing.T) {
+			primary, err := detectPrimaryCNI(filepath.Join("testdata/compatibility", tt.fixture, tt.file))
+			assert.NoError(t, err)
+			assert.Equal(t, primary, tt.want)
+
+			report := checkCNICompatibility(primary, tt.ambient)
+			assert.Equal(t, slices.Map(report.Findings, func(f CompatibilityFinding) string { return f.Rule }), tt.wantRules)
+			assert.Equal(t, report.Refused(), tt.refused)
+		})
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	ciliumConfig := func(exclusive string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cilium-config", Namespace: "kube-system"},
+			Data:       map[string]string{"cni-exclusive": exclusive},
+		}
+	}
+	cases := []struct {
+		name    string
+		check   string
+		fixture string
+		// objects are the objects of the cluster, which is not used if nil.
+		objects []runtime.Object
+		wantErr bool
+	}{
+		{
+			name:    "enforce exclusive",
+			check:   CompatibilityCheckEnforce,
+			fixture: "cilium",
+			objects: []runtime.Object{ciliumConfig("true")},
+			wantErr: true,
+		},
+		{
+			name:    "warn exclusive",
+			check:   CompatibilityCheckWarn,
+			fixture: "cilium",
+			objects: []runtime.Object{ciliumConfig("true")},
+		},
+		{
+			name:    "off exclusive",
+			check:   CompatibilityCheckOff,
+			fixture: "cilium",
+			objects: []runtime.Object{ciliumConfig("true")},
+		},
+		{
+			name:    "enforce not exclusive with stale backup",
+			check:   CompatibilityCheckEnforce,
+			fixture: "cilium-stale-backup",
+			objects: []runtime.Object{ciliumConfig("false")},
+		},
+		{
+			name:    "enforce without Cilium config",
+			check:   CompatibilityCheckEnforce,
+			fixture: "cilium-stale-backup",
+			objects: []runtime.Object{},
+		},
+		{
+			name:    "enforce without cluster",
+			check:   CompatibilityCheckEnforce,
+			fixture: "cilium-stale-backup",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			entries, err := os.ReadDir(filepath.Join("testdata/compatibility", tt.fixture))
+			assert.NoError(t, err)
+			for _, e := range entries {
+				assert.NoError(t, file.Copy(filepath.Join("testdata/compatibility", tt.fixture, e.Name()), dir, e.Name()))
+			}
+
+			in := NewInstaller(&config.InstallConfig{
+				MountedCNINetDir:      dir,
+				CNIConfName:           "05-cilium.conflist",
+				ChainedCNIPlugin:      true,
+				CNICompatibilityCheck: tt.check,
+			}, nil)
+			if tt.objects != nil {
+				in.kubeClient = kube.NewFakeClient(tt.objects...)
+			}
+			err = in.checkCompatibility(context.Background())
+			assert.Equal(t, err != nil, tt.wantErr)
+		})
+	}
+}
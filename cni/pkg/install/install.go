@@ -30,6 +30,8 @@ import (
 	"istio.io/istio/cni/pkg/scopes"
 	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/file"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient"
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/sleep"
 	"istio.io/istio/pkg/util/sets"
@@ -42,6 +44,9 @@ type Installer struct {
 	isReady            *atomic.Value
 	kubeconfigFilepath string
 	cniConfigFilepath  string
+	// kubeClient and events are nil unless the compatibility check uses the cluster, see EnableCompatibilityClient.
+	kubeClient kube.Client
+	events     *kclient.EventRecorder
 }
 
 // NewInstaller returns an instance of Installer with the given config
@@ -82,6 +87,10 @@ func (in *Installer) installAll(ctx context.Context) (sets.String, error) {
 	// unless it's missing or the contents are not what we expect.
 	if err := checkValidCNIConfig(ctx, in.cfg, in.cniConfigFilepath); err != nil {
 		installLog.Infof("configuration requires updates, (re)writing CNI config file: %v", err)
+		if err := in.checkCompatibility(ctx); err != nil {
+			cniInstalls.With(resultLabel.Value(resultIncompatibleCNIFailure)).Increment()
+			return copiedFiles, fmt.Errorf("check CNI compatibility: %v", err)
+		}
 		cfgPath, err := createCNIConfigFile(ctx, in.cfg)
 		if err != nil {
 			cniInstalls.With(resultLabel.Value(resultCreateCNIConfigFailure)).Increment()
@@ -419,6 +428,40 @@ func checkValidCNIConfig(ctx context.Context, cfg *config.InstallConfig, cniConf
 	return nil
 }
 
+// CheckCNIConfig returns the highest priority CNI config file of the node, and an error if it does not invoke the
+// istio-cni plugin. Unlike checkValidCNIConfig, it only reads the CNI config, so it can be called while Run is watching it.
+func (in *Installer) CheckCNIConfig() (string, error) {
+	filenames, err := getConfigFilenames(in.cfg.MountedCNINetDir)
+	if err != nil {
+		return "", err
+	}
+	cniConfigFilepath := filepath.Join(in.cfg.MountedCNINetDir, filenames[0])
+	cniConfigMap, err := util.ReadCNIConfigMap(cniConfigFilepath)
+	if err != nil {
+		return cniConfigFilepath, err
+	}
+	if !in.cfg.ChainedCNIPlugin {
+		if cniConfigMap["type"] != "istio-cni" {
+			return cniConfigFilepath, fmt.Errorf("istio-cni is not the plugin of %s", cniConfigFilepath)
+		}
+		return cniConfigFilepath, nil
+	}
+	plugins, err := util.GetPlugins(cniConfigMap)
+	if err != nil {
+		return cniConfigFilepath, fmt.Errorf("%s: %w", cniConfigFilepath, err)
+	}
+	for _, rawPlugin := range plugins {
+		plugin, err := util.GetPlugin(rawPlugin)
+		if err != nil {
+			return cniConfigFilepath, fmt.Errorf("%s: %w", cniConfigFilepath, err)
+		}
+		if plugin["type"] == "istio-cni" {
+			return cniConfigFilepath, nil
+		}
+	}
+	return cniConfigFilepath, fmt.Errorf("istio-cni plugin not found in %s", cniConfigFilepath)
+}
+
 func useIstioOwnedCNIConfig(cfg *config.InstallConfig) bool {
 	return cfg.IstioOwnedCNIConfig && cfg.ChainedCNIPlugin && cfg.AmbientEnabled
 }
@@ -174,6 +174,57 @@ func TestCheckInstall(t *testing.T) {
 	}
 }
 
+func TestCheckCNIConfig(t *testing.T) {
+	cases := []struct {
+		name              string
+		chainedCNIPlugin  bool
+		existingConfFiles map[string]string // {srcFilename: targetFilename, ...}
+		expectedFile      string
+		expectedFailure   bool
+	}{
+		{
+			name:              "chained plugin installed",
+			chainedCNIPlugin:  true,
+			existingConfFiles: map[string]string{"bridge.conf": "bridge.conf", "list-with-istio.conflist": "10-list.conflist"},
+			expectedFile:      "10-list.conflist",
+		},
+		{
+			name:              "chained plugin missing",
+			chainedCNIPlugin:  true,
+			existingConfFiles: map[string]string{"list-no-istio.conflist": "10-list.conflist"},
+			expectedFile:      "10-list.conflist",
+			expectedFailure:   true,
+		},
+		{
+			name:              "standalone plugin installed",
+			existingConfFiles: map[string]string{"bridge.conf": "20-bridge.conf", "istio-cni.conf": "10-istio-cni.conf"},
+			expectedFile:      "10-istio-cni.conf",
+		},
+		{
+			name:            "no config",
+			expectedFailure: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			for srcFilename, targetFilename := range c.existingConfFiles {
+				if err := file.AtomicCopy(filepath.Join("testdata", srcFilename), tempDir, targetFilename); err != nil {
+					t.Fatal(err)
+				}
+			}
+			in := NewInstaller(&config.InstallConfig{MountedCNINetDir: tempDir, ChainedCNIPlugin: c.chainedCNIPlugin}, &atomic.Value{})
+			got, err := in.CheckCNIConfig()
+			if c.expectedFile != "" {
+				assert.Equal(t, got, filepath.Join(tempDir, c.expectedFile))
+			}
+			if (c.expectedFailure && err == nil) || (!c.expectedFailure && err != nil) {
+				t.Fatalf("expected failure: %t, got %v", c.expectedFailure, err)
+			}
+		})
+	}
+}
+
 // TODO(jaellio): update to check plugin equality btw Istio owned config and primary config
 func TestSleepCheckInstall(t *testing.T) {
 	cases := []struct {
@@ -22,12 +22,21 @@ var (
 	resultCopyBinariesFailure     = "COPY_BINARIES_FAILURE"
 	resultCreateKubeConfigFailure = "CREATE_KUBECONFIG_FAILURE"
 	resultCreateCNIConfigFailure  = "CREATE_CNI_CONFIG_FAILURE"
+	resultIncompatibleCNIFailure  = "INCOMPATIBLE_CNI_FAILURE"
+
+	primaryCNILabel = monitoring.CreateLabel("primary_cni")
+	severityLabel   = monitoring.CreateLabel("severity")
 
 	cniInstalls = monitoring.NewSum(
 		"istio_cni_installs_total",
 		"Total number of CNI plugins installed by the Istio CNI installer",
 	)
 
+	compatibilityFindings = monitoring.NewGauge(
+		"istio_cni_compatibility_findings",
+		"Number of compatibility findings about the primary CNI the Istio CNI plugin is chained to, by severity",
+	)
+
 	installReady = monitoring.NewGauge(
 		"istio_cni_install_ready",
 		"Whether the CNI plugin installation is ready or not",
@@ -186,6 +186,14 @@ func (cfg *IptablesConfigurator) CreateInpodRules(log *istiolog.Scope, podOverri
 	return nil
 }
 
+// VerifyInpodRules reports whether the iptables rules within a pod's network namespace have drifted from the
+// expected ones.
+// NOTE that this expects to be run from within the pod network namespace!
+func (cfg *IptablesConfigurator) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	_, deltaExists := iptablescapture.VerifyIptablesState(log, cfg.ext, cfg.AppendInpodRules(podOverrides), &cfg.iptV, &cfg.ipt6V)
+	return deltaExists, nil
+}
+
 func (cfg *IptablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOverrides) *builder.IptablesRuleBuilder {
 	var redirectDNS bool
 
@@ -317,6 +325,17 @@ func (cfg *IptablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 	)
 
 	if !podOverrides.IngressMode {
+		// CLI: -A ISTIO_PRERT -p tcp --dport <PORT> -j RETURN
+		//
+		// DESC: Inbound ports excluded from capture by a CaptureExclusion skip the ztunnel inbound redirect.
+		for _, port := range podOverrides.ExcludeInboundPorts {
+			iptablesBuilder.AppendRule(ChainInpodPrerouting, "nat",
+				"-p", "tcp",
+				"--dport", fmt.Sprint(port),
+				"-j", "RETURN",
+			)
+		}
+
 		// CLI: -A ISTIO_PRERT ! -d 127.0.0.1/32 -p tcp ! --dport 15008 -m mark ! --mark 0x539/0xfff -j REDIRECT --to-ports <INPLAINPORT>
 		//
 		// DESC: Anything that is not bound for localhost and does not have the mark, REDIRECT to ztunnel inbound plaintext port <INPLAINPORT>
@@ -419,6 +438,34 @@ func (cfg *IptablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 		"-o", "lo",
 		"-j", "ACCEPT",
 	)
+	// CLI: -A ISTIO_OUTPUT -p tcp --dport <PORT> -j RETURN
+	// CLI: -A ISTIO_OUTPUT -d <CIDR> -j RETURN
+	// CLI: -A ISTIO_OUTPUT -m owner --uid-owner <UID> -j RETURN
+	//
+	// DESC: Outbound traffic excluded from capture by a CaptureExclusion skips the ztunnel outbound redirect.
+	// DNS is still redirected above, as DNS capture is configured separately.
+	for _, port := range podOverrides.ExcludeOutboundPorts {
+		iptablesBuilder.AppendRule(ChainInpodOutput, "nat",
+			"-p", "tcp",
+			"--dport", fmt.Sprint(port),
+			"-j", "RETURN",
+		)
+	}
+	for _, cidr := range podOverrides.ExcludeOutboundIPRanges {
+		if cidr.Addr().Is6() {
+			iptablesBuilder.AppendRuleV6(ChainInpodOutput, "nat", "-d", cidr.String(), "-j", "RETURN")
+		} else {
+			iptablesBuilder.AppendRuleV4(ChainInpodOutput, "nat", "-d", cidr.String(), "-j", "RETURN")
+		}
+	}
+	for _, uid := range podOverrides.ExcludeOutboundUIDs {
+		iptablesBuilder.AppendRule(ChainInpodOutput, "nat",
+			"-m", "owner",
+			"--uid-owner", fmt.Sprint(uid),
+			"-j", "RETURN",
+		)
+	}
+
 	// CLI: -A ISTIO_OUTPUT ! -d 127.0.0.1/32 -p tcp -m mark ! --mark 0x539/0xfff -j REDIRECT --to-ports <OUTPORT>
 	//
 	// DESC: If this is outbound, not bound for localhost, and does not have our packet mark, redirect to ztunnel proxy <OUTPORT>
@@ -608,6 +655,30 @@ func (cfg *IptablesConfigurator) DeleteHostRules() {
 	}
 }
 
+// ListHostRules returns the host-level iptables rules currently in place, in iptables-save format.
+func (cfg *IptablesConfigurator) ListHostRules() ([]string, error) {
+	versions := []*dep.IptablesVersion{&cfg.iptV}
+	if cfg.cfg.EnableIPv6 {
+		versions = append(versions, &cfg.ipt6V)
+	}
+	var rules []string
+	err := util.RunAsHost(func() error {
+		for _, version := range versions {
+			output, err := cfg.ext.Run(log.WithLabels("component", "host"), true, iptablesconstants.IPTablesSave, version, nil, "-t", "nat")
+			if err != nil {
+				return err
+			}
+			for line := range strings.Lines(output.String()) {
+				if strings.Contains(line, ChainHostPostrouting) {
+					rules = append(rules, strings.TrimSpace(line))
+				}
+			}
+		}
+		return nil
+	})
+	return rules, err
+}
+
 func (cfg *IptablesConfigurator) AppendHostRules() *builder.IptablesRuleBuilder {
 	iptablesBuilder := builder.NewIptablesRuleBuilder(config.GetConfig(cfg.cfg))
 
@@ -14,7 +14,11 @@
 
 package nftables
 
-import "istio.io/istio/cni/pkg/config"
+import (
+	"net/netip"
+
+	"istio.io/istio/cni/pkg/config"
+)
 
 func GetCommonInPodTestCases() []struct {
 	name         string
@@ -70,6 +74,18 @@ func GetCommonInPodTestCases() []struct {
 			},
 			podOverrides: config.PodLevelOverrides{DNSProxy: config.PodDNSDisabled},
 		},
+		{
+			name: "capture_exclusions",
+			config: func(cfg *config.AmbientConfig) {
+				cfg.RedirectDNS = true
+			},
+			podOverrides: config.PodLevelOverrides{
+				ExcludeInboundPorts:     []uint16{8080},
+				ExcludeOutboundPorts:    []uint16{3306, 5432},
+				ExcludeOutboundIPRanges: []netip.Prefix{netip.MustParsePrefix("10.10.0.0/16"), netip.MustParsePrefix("fd00:10::/64")},
+				ExcludeOutboundUIDs:     []int64{1001},
+			},
+		},
 	}
 }
 
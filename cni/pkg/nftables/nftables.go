@@ -18,7 +18,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"sigs.k8s.io/knftables"
@@ -132,6 +134,11 @@ func (cfg *NftablesConfigurator) CreateInpodRules(log *istiolog.Scope, podOverri
 }
 
 func (cfg *NftablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOverrides) (*knftables.Transaction, error) {
+	return cfg.executeCommands(cfg.buildInpodRules(podOverrides))
+}
+
+// buildInpodRules builds the expected nftables rules for a pod's network namespace, without applying them.
+func (cfg *NftablesConfigurator) buildInpodRules(podOverrides config.PodLevelOverrides) *builder.NftablesRuleBuilder {
 	rb := builder.NewNftablesRuleBuilder(config.GetConfig(cfg.cfg))
 
 	var redirectDNS bool
@@ -249,6 +256,16 @@ func (cfg *NftablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 	)
 
 	if !podOverrides.IngressMode {
+		// CLI: nft add rule inet istio-ambient-nat istio-prerouting tcp dport <PORT> counter return
+		//
+		// DESC: Inbound ports excluded from capture by a CaptureExclusion skip the ztunnel inbound redirect.
+		for _, port := range podOverrides.ExcludeInboundPorts {
+			rb.AppendRule(IstioPreroutingChain, AmbientNatTable,
+				"tcp dport", fmt.Sprint(port), Counter,
+				"return",
+			)
+		}
+
 		// CLI: nft add rule inet istio-ambient-nat istio-prerouting ip daddr != 127.0.0.1 tcp dport != 15008 mark and 0xfff != 0x539
 		// counter redirect to :15006
 		//
@@ -366,6 +383,32 @@ func (cfg *NftablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 		"accept",
 	)
 
+	// CLI: nft add rule inet istio-ambient-nat istio-output tcp dport <PORT> counter return
+	// CLI: nft add rule inet istio-ambient-nat istio-output ip daddr <CIDR> counter return
+	// CLI: nft add rule inet istio-ambient-nat istio-output meta skuid <UID> counter return
+	//
+	// DESC: Outbound traffic excluded from capture by a CaptureExclusion skips the ztunnel outbound redirect.
+	// DNS is still redirected above, as DNS capture is configured separately.
+	for _, port := range podOverrides.ExcludeOutboundPorts {
+		rb.AppendRule(IstioOutputChain, AmbientNatTable,
+			"tcp dport", fmt.Sprint(port), Counter,
+			"return",
+		)
+	}
+	for _, cidr := range podOverrides.ExcludeOutboundIPRanges {
+		if cidr.Addr().Is6() {
+			rb.AppendV6RuleIfSupported(IstioOutputChain, AmbientNatTable, "ip6 daddr", cidr.String(), Counter, "return")
+		} else {
+			rb.AppendRule(IstioOutputChain, AmbientNatTable, "ip daddr", cidr.String(), Counter, "return")
+		}
+	}
+	for _, uid := range podOverrides.ExcludeOutboundUIDs {
+		rb.AppendRule(IstioOutputChain, AmbientNatTable,
+			"meta skuid", fmt.Sprint(uid), Counter,
+			"return",
+		)
+	}
+
 	// CLI: nft add rule inet istio-ambient-nat istio-output meta l4proto tcp ip daddr != 127.0.0.1 mark and 0xfff != 0x539 counter redirect to :15001
 	//
 	// DESC: If this is outbound, not bound for localhost, and does not have our packet mark, redirect to ztunnel proxy <OUTPORT>
@@ -389,7 +432,93 @@ func (cfg *NftablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 		"redirect to", ":"+fmt.Sprintf("%d", config.ZtunnelOutboundPort),
 	)
 
-	return cfg.executeCommands(rb)
+	return rb
+}
+
+// VerifyInpodRules reports whether the nftables rules within a pod's network namespace have drifted from the
+// expected ones. The rules of each chain are compared in order, in their canonical form, see canonicalRule.
+// This detects flushed or deleted tables and chains, and added, removed, reordered or replaced rules.
+func (cfg *NftablesConfigurator) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	rb := cfg.buildInpodRules(podOverrides)
+	for _, table := range []string{AmbientNatTable, AmbientMangleTable, AmbientRawTable} {
+		expected := make(map[string][]string)
+		for _, rule := range rb.Rules[table] {
+			expected[rule.Chain] = append(expected[rule.Chain], canonicalRule(rule.Rule))
+		}
+		if len(expected) == 0 {
+			continue
+		}
+		nft, err := cfg.nftProvider(knftables.InetFamily, table)
+		if err != nil {
+			return false, err
+		}
+		for chain, bodies := range expected {
+			rules, err := nft.ListRules(context.TODO(), chain)
+			if knftables.IsNotFound(err) {
+				log.Debugf("nftables chain %s/%s is missing", table, chain)
+				return true, nil
+			}
+			if err != nil {
+				return false, fmt.Errorf("failed to list nftables rules in %s/%s: %w", table, chain, err)
+			}
+			if len(rules) != len(bodies) {
+				log.Debugf("nftables chain %s/%s has %d rules, expected %d", table, chain, len(rules), len(bodies))
+				return true, nil
+			}
+			for i, rule := range rules {
+				if canonicalRule(rule.Rule) != bodies[i] {
+					log.Debugf("nftables rule %d of chain %s/%s was changed: %q", i, table, chain, rule.Rule)
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// canonicalRule returns the canonical form of a rule body, so that a rule as written by the rule builder compares
+// equal to the same rule as rendered by nft. nft prints the implicit "meta" keyword and "==" operator, the
+// symbolic forms of the binary operators, quoted interface names, zero-padded marks, host addresses without
+// their prefix length and the counter values, which are all normalized here.
+func canonicalRule(rule string) string {
+	if i := strings.Index(rule, ` comment "`); i >= 0 {
+		rule = rule[:i]
+	}
+	fields := strings.Fields(rule)
+	canonical := make([]string, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		field := strings.Trim(fields[i], `"`)
+		switch field {
+		case "meta", "==":
+			continue
+		case Counter:
+			// Skip the counter values, "counter packets <n> bytes <n>".
+			if i+4 < len(fields) && fields[i+1] == "packets" && fields[i+3] == "bytes" {
+				i += 4
+			}
+			continue
+		case "and":
+			field = "&"
+		case "or":
+			field = "|"
+		case "ip", "ip6":
+			// nft prints the address family of the NAT statements in inet tables.
+			if i > 0 && (fields[i-1] == "snat" || fields[i-1] == "dnat") {
+				continue
+			}
+		}
+		if n, err := strconv.ParseUint(field, 0, 64); err == nil {
+			field = strconv.FormatUint(n, 10)
+		} else if port, err := strconv.ParseUint(strings.TrimPrefix(field, ":"), 10, 16); err == nil {
+			field = ":" + strconv.FormatUint(port, 10)
+		} else if prefix, err := netip.ParsePrefix(field); err == nil && prefix.IsSingleIP() {
+			field = prefix.Addr().String()
+		} else if addr, err := netip.ParseAddr(field); err == nil {
+			field = addr.String()
+		}
+		canonical = append(canonical, field)
+	}
+	return strings.Join(canonical, " ")
 }
 
 // DeleteInpodRules removes nftables rules from a pod's network namespace
@@ -489,6 +618,29 @@ func (cfg *NftablesConfigurator) CreateHostRulesForHealthChecks() error {
 	})
 }
 
+// ListHostRules returns the host-level nftables rules currently in place.
+func (cfg *NftablesConfigurator) ListHostRules() ([]string, error) {
+	nft, err := cfg.nftProvider(knftables.InetFamily, AmbientNatTable)
+	if err != nil {
+		return nil, err
+	}
+	var rules []string
+	err = util.RunAsHost(func() error {
+		listed, err := nft.ListRules(context.TODO(), PostroutingChain)
+		if knftables.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, rule := range listed {
+			rules = append(rules, fmt.Sprintf("add rule %s %s %s %s", knftables.InetFamily, AmbientNatTable, PostroutingChain, rule.Rule))
+		}
+		return nil
+	})
+	return rules, err
+}
+
 // DeleteHostRules removes host-level nftables rules
 func (cfg *NftablesConfigurator) DeleteHostRules() {
 	log := log.WithLabels("component", "host")
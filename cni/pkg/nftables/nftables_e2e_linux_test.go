@@ -17,6 +17,7 @@ package nftables
 import (
 	"bytes"
 	"os/exec"
+	"strings"
 	"sync"
 	"testing"
 
@@ -165,12 +166,27 @@ func TestHostIdempotentEquivalentRerun(t *testing.T) {
 
 		// The ruleset should be identical, despite the rerun
 		assert.Equal(t, firstPassDump, secondPassDump, "nftables ruleset should be identical after a second run")
+
+		// The listed rules carry their bodies, as rendered by nft.
+		rules, err := nftConfiguratorHost.ListHostRules()
+		assert.NoError(t, err)
+		if len(rules) == 0 {
+			t.Fatal("expected the host rules to be listed")
+		}
+		for _, rule := range rules {
+			if !strings.Contains(rule, "snat") {
+				t.Fatalf("expected the body of host rule %q to be listed", rule)
+			}
+		}
 	})
 }
 
 var initialized = &sync.Once{}
 
 func setup(t *testing.T) {
+	if _, err := exec.LookPath("nft"); err != nil {
+		t.Skip("nft is not installed")
+	}
 	initialized.Do(func() {
 		// Setup group namespace so nftables skgid conditions will work
 		assert.NoError(t, userns.WriteGroupMap(map[uint32]uint32{userns.OriginalGID(): 0}))
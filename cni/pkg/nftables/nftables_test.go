@@ -253,3 +253,131 @@ func constructTestConfig() *config.AmbientConfig {
 		HostProbeV6SNATAddress: probeSNATipv6,
 	}
 }
+
+func TestVerifyInpodRules(t *testing.T) {
+	cfg := constructTestConfig()
+	cfg.RedirectDNS = true
+	ext := &dep.DependenciesStub{}
+
+	mock := NewMockNftablesCapture()
+	originalProvider := nftProviderVar
+	nftProviderVar = func(family knftables.Family, table string) (builder.NftablesAPI, error) {
+		if table == "" {
+			return mock, nil
+		}
+		// Listing is scoped to a single table, view it from the shared fake.
+		tableMock := builder.NewMockNftables(family, table)
+		tableMock.Tables = mock.Tables
+		tableMock.Table = mock.Tables[family][table]
+		return tableMock, nil
+	}
+	t.Cleanup(func() { nftProviderVar = originalProvider })
+
+	iptConfigurator, _, err := NewNftablesConfigurator(cfg, cfg, ext, ext, iptables.EmptyNlDeps())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drifted, err := iptConfigurator.VerifyInpodRules(scopes.CNIAgent, config.PodLevelOverrides{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drifted {
+		t.Fatal("expected missing tables to be reported as drift")
+	}
+
+	if err := iptConfigurator.CreateInpodRules(scopes.CNIAgent, config.PodLevelOverrides{}); err != nil {
+		t.Fatal(err)
+	}
+	drifted, err = iptConfigurator.VerifyInpodRules(scopes.CNIAgent, config.PodLevelOverrides{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drifted {
+		t.Fatal("expected freshly created rules not to be reported as drift")
+	}
+
+	// Replace a rule in place, keeping the number of rules of its chain.
+	outputRules := mock.Tables[knftables.InetFamily][AmbientNatTable].Chains[IstioOutputChain].Rules
+	original := outputRules[0]
+	outputRules[0] = &knftables.Rule{Chain: original.Chain, Rule: "counter accept"}
+	drifted, err = iptConfigurator.VerifyInpodRules(scopes.CNIAgent, config.PodLevelOverrides{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drifted {
+		t.Fatal("expected a replaced rule to be reported as drift")
+	}
+	outputRules[0] = original
+
+	// Flush a single chain, as a third party tool on the node would.
+	mock.Tables[knftables.InetFamily][AmbientNatTable].Chains[PreroutingChain].Rules = nil
+	drifted, err = iptConfigurator.VerifyInpodRules(scopes.CNIAgent, config.PodLevelOverrides{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drifted {
+		t.Fatal("expected a flushed chain to be reported as drift")
+	}
+}
+
+func TestListHostRules(t *testing.T) {
+	mock := builder.NewMockNftables(knftables.InetFamily, AmbientNatTable)
+	tx := mock.NewTransaction()
+	tx.Add(&knftables.Table{})
+	tx.Add(&knftables.Chain{Name: PostroutingChain})
+	tx.Add(&knftables.Rule{Chain: PostroutingChain, Rule: "meta l4proto tcp skuid 1000 counter snat to 169.254.7.127"})
+	if err := mock.Run(context.TODO(), tx); err != nil {
+		t.Fatal(err)
+	}
+	originalProvider := nftProviderVar
+	nftProviderVar = func(_ knftables.Family, _ string) (builder.NftablesAPI, error) {
+		return mock, nil
+	}
+	t.Cleanup(func() { nftProviderVar = originalProvider })
+
+	iptConfigurator, _, err := NewNftablesConfigurator(constructTestConfig(), nil, &dep.DependenciesStub{}, nil, iptables.EmptyNlDeps())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := iptConfigurator.ListHostRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "add rule inet istio-ambient-nat postrouting meta l4proto tcp skuid 1000 counter snat to 169.254.7.127"
+	if len(rules) != 1 || rules[0] != want {
+		t.Fatalf("unexpected host rules %q, want %q", rules, want)
+	}
+}
+
+func TestCanonicalRule(t *testing.T) {
+	cases := []struct {
+		written  string
+		rendered string
+	}{
+		{
+			written:  "ip daddr != 127.0.0.1/32 tcp dport != 15008 mark and 0xfff  != 0x539 counter redirect to :15006",
+			rendered: "ip daddr != 127.0.0.1 tcp dport != 15008 meta mark & 0x00000fff != 0x00000539 counter packets 2 bytes 120 redirect to :15006",
+		},
+		{
+			written:  "meta mark & 0xfff == 0x539 counter ct mark set ct mark & 0xfffff000 |  0x111",
+			rendered: "meta mark & 0x00000fff == 0x00000539 counter packets 0 bytes 0 ct mark set ct mark & 0xfffff000 | 0x00000111",
+		},
+		{
+			written:  "oifname lo ip6 daddr != ::1/128 counter accept",
+			rendered: `oifname "lo" ip6 daddr != ::1 counter packets 0 bytes 0 accept`,
+		},
+		{
+			written:  "meta l4proto tcp skuid 1000 ip daddr @istio-inpod-probes-v4 counter snat to 169.254.7.127",
+			rendered: "meta l4proto tcp meta skuid 1000 ip daddr @istio-inpod-probes-v4 counter packets 1 bytes 60 snat ip to 169.254.7.127",
+		},
+	}
+	for _, tt := range cases {
+		if got, want := canonicalRule(tt.rendered), canonicalRule(tt.written); got != want {
+			t.Errorf("canonical form of %q is %q, want %q", tt.rendered, got, want)
+		}
+	}
+	if canonicalRule("ip daddr 10.10.0.0/16 counter return") == canonicalRule("ip daddr 10.10.0.0/24 counter return") {
+		t.Error("expected rules matching different prefixes to differ")
+	}
+}
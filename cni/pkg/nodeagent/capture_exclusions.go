@@ -0,0 +1,187 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"istio.io/istio/cni/pkg/apis/v1alpha1"
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/pkg/config/schema/kubeclient"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient"
+	ktypes "istio.io/istio/pkg/kube/kubetypes"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func init() {
+	// CaptureExclusion is not part of the Istio API, so it is registered to get a typed kclient.
+	kubeclient.Register[*v1alpha1.CaptureExclusion](
+		v1alpha1.CaptureExclusionGVR,
+		v1alpha1.CaptureExclusionGVK,
+		func(c kubeclient.ClientGetter, namespace string, o metav1.ListOptions) (runtime.Object, error) {
+			return v1alpha1.CaptureExclusions(c.Dynamic(), namespace).List(context.Background(), o)
+		},
+		func(c kubeclient.ClientGetter, namespace string, o metav1.ListOptions) (watch.Interface, error) {
+			return v1alpha1.CaptureExclusions(c.Dynamic(), namespace).Watch(context.Background(), o)
+		},
+		func(c kubeclient.ClientGetter, namespace string) ktypes.WriteAPI[*v1alpha1.CaptureExclusion] {
+			return v1alpha1.CaptureExclusions(c.Dynamic(), namespace)
+		},
+	)
+}
+
+// captureExclusion is a parsed and validated CaptureExclusion.
+type captureExclusion struct {
+	selector         klabels.Selector
+	inboundPorts     []uint16
+	outboundPorts    []uint16
+	outboundIPRanges []netip.Prefix
+	outboundUIDs     []int64
+}
+
+func parseCaptureExclusion(obj *v1alpha1.CaptureExclusion) (*captureExclusion, error) {
+	spec := obj.Spec
+	e := &captureExclusion{selector: klabels.Everything()}
+	if spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %v", err)
+		}
+		e.selector = selector
+	}
+	for _, port := range slices.Concat(spec.ExcludeInboundPorts, spec.ExcludeOutboundPorts) {
+		if port == 0 {
+			return nil, fmt.Errorf("invalid port 0")
+		}
+	}
+	e.inboundPorts = spec.ExcludeInboundPorts
+	e.outboundPorts = spec.ExcludeOutboundPorts
+	for _, r := range spec.ExcludeOutboundIPRanges {
+		prefix, err := netip.ParsePrefix(r)
+		if err != nil {
+			// Plain addresses are accepted as well, as single host ranges.
+			addr, addrErr := netip.ParseAddr(r)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid IP range %q: %v", r, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		e.outboundIPRanges = append(e.outboundIPRanges, prefix.Masked())
+	}
+	for _, uid := range spec.ExcludeOutboundUIDs {
+		if uid < 0 {
+			return nil, fmt.Errorf("invalid UID %d", uid)
+		}
+	}
+	e.outboundUIDs = spec.ExcludeOutboundUIDs
+	return e, nil
+}
+
+// captureExclusions looks up the CaptureExclusion resources applying to a pod, and reports in their status
+// whether they are applied.
+type captureExclusions struct {
+	client kclient.Client[*v1alpha1.CaptureExclusion]
+}
+
+func newCaptureExclusions(client kube.Client) *captureExclusions {
+	return &captureExclusions{
+		client: kclient.New[*v1alpha1.CaptureExclusion](client),
+	}
+}
+
+// apply merges the exclusions of all the CaptureExclusion resources selecting the pod into its overrides.
+// Invalid resources are skipped, so that a single bad resource does not break the capture of the namespace.
+func (e *captureExclusions) apply(pod *corev1.Pod, overrides *config.PodLevelOverrides) {
+	mergeCaptureExclusions(pod, e.client.List(pod.Namespace, klabels.Everything()), overrides)
+}
+
+// updateStatus sets the Accepted condition of a CaptureExclusion, if it changed. All the node agents compute
+// the same condition, so conflicting writes of other node agents are ignored.
+func (e *captureExclusions) updateStatus(obj *v1alpha1.CaptureExclusion) {
+	want := e.acceptedCondition(obj)
+	if current := meta.FindStatusCondition(obj.Status.Conditions, want.Type); current != nil &&
+		current.Status == want.Status && current.Reason == want.Reason && current.Message == want.Message &&
+		current.ObservedGeneration == want.ObservedGeneration {
+		return
+	}
+	obj = obj.DeepCopy()
+	meta.SetStatusCondition(&obj.Status.Conditions, want)
+	if _, err := e.client.UpdateStatus(obj); err != nil && !kerrors.IsConflict(err) && !kerrors.IsNotFound(err) {
+		log.Warnf("failed to update the status of CaptureExclusion %s/%s: %v", obj.Namespace, obj.Name, err)
+	}
+}
+
+func (e *captureExclusions) acceptedCondition(obj *v1alpha1.CaptureExclusion) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               v1alpha1.ConditionAccepted,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.ReasonAccepted,
+		Message:            "The exclusions are applied to the selected pods",
+		ObservedGeneration: obj.Generation,
+	}
+	if _, err := parseCaptureExclusion(obj); err != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = v1alpha1.ReasonInvalid
+		cond.Message = fmt.Sprintf("The exclusions are ignored: %v", err)
+	}
+	return cond
+}
+
+func mergeCaptureExclusions(pod *corev1.Pod, objs []*v1alpha1.CaptureExclusion, overrides *config.PodLevelOverrides) {
+	inboundPorts := sets.New(overrides.ExcludeInboundPorts...)
+	outboundPorts := sets.New(overrides.ExcludeOutboundPorts...)
+	outboundIPRanges := sets.New(overrides.ExcludeOutboundIPRanges...)
+	outboundUIDs := sets.New(overrides.ExcludeOutboundUIDs...)
+
+	podLabels := klabels.Set(pod.Labels)
+	for _, obj := range objs {
+		exclusion, err := parseCaptureExclusion(obj)
+		if err != nil {
+			log.Debugf("ignoring CaptureExclusion %s/%s: %v", obj.Namespace, obj.Name, err)
+			continue
+		}
+		if !exclusion.selector.Matches(podLabels) {
+			continue
+		}
+		inboundPorts.InsertAll(exclusion.inboundPorts...)
+		outboundPorts.InsertAll(exclusion.outboundPorts...)
+		outboundIPRanges.InsertAll(exclusion.outboundIPRanges...)
+		outboundUIDs.InsertAll(exclusion.outboundUIDs...)
+	}
+
+	overrides.ExcludeInboundPorts = sets.SortedList(inboundPorts)
+	overrides.ExcludeOutboundPorts = sets.SortedList(outboundPorts)
+	overrides.ExcludeOutboundIPRanges = outboundIPRanges.UnsortedList()
+	slices.SortFunc(overrides.ExcludeOutboundIPRanges, func(a, b netip.Prefix) int {
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Bits(), b.Bits())
+	})
+	overrides.ExcludeOutboundUIDs = sets.SortedList(outboundUIDs)
+}
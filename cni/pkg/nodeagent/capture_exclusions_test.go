@@ -0,0 +1,140 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"net/netip"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/cni/pkg/apis/v1alpha1"
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func makeCaptureExclusion(name string, spec v1alpha1.CaptureExclusionSpec) *v1alpha1.CaptureExclusion {
+	return &v1alpha1.CaptureExclusion{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Generation: 2},
+		Spec:       spec,
+	}
+}
+
+func TestMergeCaptureExclusions(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "test",
+		Labels:    map[string]string{"app": "reporting"},
+	}}
+	cases := []struct {
+		name string
+		objs []*v1alpha1.CaptureExclusion
+		want config.PodLevelOverrides
+		// IP ranges are compared as strings, as netip.Prefix has no exported fields.
+		wantRanges []string
+	}{
+		{
+			name: "no exclusions",
+		},
+		{
+			name: "merged, sorted and unique",
+			objs: []*v1alpha1.CaptureExclusion{
+				makeCaptureExclusion("all", v1alpha1.CaptureExclusionSpec{
+					ExcludeOutboundPorts:    []uint16{5432, 3306},
+					ExcludeOutboundIPRanges: []string{"fd00:10::/64", "10.10.0.0/16"},
+				}),
+				makeCaptureExclusion("selected", v1alpha1.CaptureExclusionSpec{
+					Selector:                &metav1.LabelSelector{MatchLabels: map[string]string{"app": "reporting"}},
+					ExcludeInboundPorts:     []uint16{8080},
+					ExcludeOutboundPorts:    []uint16{3306},
+					ExcludeOutboundIPRanges: []string{"10.10.1.1", "10.10.0.0/16"},
+					ExcludeOutboundUIDs:     []int64{1001},
+				}),
+			},
+			want: config.PodLevelOverrides{
+				ExcludeInboundPorts:  []uint16{8080},
+				ExcludeOutboundPorts: []uint16{3306, 5432},
+				ExcludeOutboundUIDs:  []int64{1001},
+			},
+			wantRanges: []string{"10.10.0.0/16", "10.10.1.1/32", "fd00:10::/64"},
+		},
+		{
+			name: "not selected",
+			objs: []*v1alpha1.CaptureExclusion{
+				makeCaptureExclusion("other", v1alpha1.CaptureExclusionSpec{
+					Selector:             &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}},
+					ExcludeOutboundPorts: []uint16{5432},
+				}),
+			},
+		},
+		{
+			name: "invalid exclusions are skipped",
+			objs: []*v1alpha1.CaptureExclusion{
+				makeCaptureExclusion("bad-range", v1alpha1.CaptureExclusionSpec{ExcludeOutboundIPRanges: []string{"10.10.0.0/33"}}),
+				makeCaptureExclusion("bad-port", v1alpha1.CaptureExclusionSpec{ExcludeInboundPorts: []uint16{0}}),
+				makeCaptureExclusion("bad-uid", v1alpha1.CaptureExclusionSpec{ExcludeOutboundUIDs: []int64{-1}}),
+				makeCaptureExclusion("good", v1alpha1.CaptureExclusionSpec{ExcludeOutboundUIDs: []int64{1001}}),
+			},
+			want: config.PodLevelOverrides{
+				ExcludeOutboundUIDs: []int64{1001},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var overrides config.PodLevelOverrides
+			mergeCaptureExclusions(pod, tt.objs, &overrides)
+			assert.Equal(t, slices.Map(overrides.ExcludeOutboundIPRanges, netip.Prefix.String), tt.wantRanges)
+			overrides.ExcludeOutboundIPRanges = nil
+			assert.Equal(t, overrides, tt.want)
+		})
+	}
+}
+
+func TestCaptureExclusionAcceptedCondition(t *testing.T) {
+	valid := makeCaptureExclusion("valid", v1alpha1.CaptureExclusionSpec{ExcludeOutboundPorts: []uint16{5432}})
+	invalid := makeCaptureExclusion("invalid", v1alpha1.CaptureExclusionSpec{ExcludeOutboundIPRanges: []string{"10.10.0.0/33"}})
+	cases := []struct {
+		name       string
+		obj        *v1alpha1.CaptureExclusion
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "valid",
+			obj:        valid,
+			wantStatus: metav1.ConditionTrue,
+			wantReason: v1alpha1.ReasonAccepted,
+		},
+		{
+			name:       "invalid",
+			obj:        invalid,
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1alpha1.ReasonInvalid,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &captureExclusions{}
+			cond := e.acceptedCondition(tt.obj)
+			assert.Equal(t, cond.Type, v1alpha1.ConditionAccepted)
+			assert.Equal(t, cond.Status, tt.wantStatus)
+			assert.Equal(t, cond.Reason, tt.wantReason)
+			assert.Equal(t, cond.ObservedGeneration, tt.obj.Generation)
+		})
+	}
+}
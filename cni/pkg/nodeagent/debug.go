@@ -0,0 +1,56 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
+	"istio.io/istio/pkg/slices"
+)
+
+// buildDebugState builds the debug state from the pod cache and the ztunnel connections.
+// Host rules are not included, they are added by the caller owning them.
+func buildDebugState(pods PodNetnsCache, ztunnelServer ZtunnelServer) debugstate.State {
+	conns, acks := ztunnelServer.Connections()
+	snap := pods.ReadCurrentPodSnapshot()
+	state := debugstate.State{
+		Pods:               make([]debugstate.Pod, 0, len(snap)),
+		ZtunnelConnections: conns,
+		HostRules:          []string{},
+	}
+	for uid, wl := range snap {
+		pod := debugstate.Pod{UID: uid, AckedBy: acks[uid]}
+		if wl.Workload != nil {
+			pod.Name = wl.Workload.Name
+			pod.Namespace = wl.Workload.Namespace
+			pod.ServiceAccount = wl.Workload.ServiceAccount
+		}
+		switch {
+		case wl.Netns == nil:
+			pod.Status = debugstate.PodStatusNetnsUnknown
+		case pod.AckedBy == "":
+			pod.Status = debugstate.PodStatusUnacknowledged
+		default:
+			pod.Status = debugstate.PodStatusEnrolled
+		}
+		if wl.Netns != nil {
+			pod.NetnsInode = wl.Netns.Inode()
+		}
+		state.Pods = append(state.Pods, pod)
+	}
+	slices.SortBy(state.Pods, func(p debugstate.Pod) string {
+		return p.UID
+	})
+	return state
+}
@@ -0,0 +1,134 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/zdsapi"
+)
+
+func TestBuildDebugState(t *testing.T) {
+	connectedAt := time.Now()
+	ztunnel := &fakeZtunnel{
+		conns: []debugstate.ZtunnelConnection{{UUID: "conn-1", Version: "V1", ConnectedAt: connectedAt, Latest: true}},
+		acks:  map[string]string{"uid-1": "conn-1"},
+	}
+	pods := fakePodCache{pods: map[string]WorkloadInfo{
+		"uid-1": {
+			Workload: &zdsapi.WorkloadInfo{Name: "pod-1", Namespace: "ns", ServiceAccount: "sa"},
+			Netns:    newFakeNsInode(10, 100),
+		},
+		"uid-2": {
+			Workload: &zdsapi.WorkloadInfo{Name: "pod-2", Namespace: "ns", ServiceAccount: "sa"},
+			Netns:    newFakeNsInode(11, 101),
+		},
+		"uid-3": {},
+	}}
+
+	state := buildDebugState(pods, ztunnel)
+	assert.Equal(t, state, debugstate.State{
+		Pods: []debugstate.Pod{
+			{
+				UID: "uid-1", Name: "pod-1", Namespace: "ns", ServiceAccount: "sa",
+				NetnsInode: 100, Status: debugstate.PodStatusEnrolled, AckedBy: "conn-1",
+			},
+			{
+				UID: "uid-2", Name: "pod-2", Namespace: "ns", ServiceAccount: "sa",
+				NetnsInode: 101, Status: debugstate.PodStatusUnacknowledged,
+			},
+			{UID: "uid-3", Status: debugstate.PodStatusNetnsUnknown},
+		},
+		ZtunnelConnections: ztunnel.conns,
+		HostRules:          []string{},
+	})
+}
+
+type fakeHandlers struct {
+	K8sHandlers
+	pods map[string]*corev1.Pod
+}
+
+func (f fakeHandlers) GetPodIfAmbientEnabled(podName, podNamespace string) (*corev1.Pod, error) {
+	pod, ok := f.pods[podNamespace+"/"+podName]
+	if !ok {
+		return nil, fmt.Errorf("failed to find pod %s/%s", podNamespace, podName)
+	}
+	return pod, nil
+}
+
+func TestServerDebugState(t *testing.T) {
+	enrolled := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "ns", UID: "uid-1"}}
+	pending := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "ns", UID: "uid-2"}}
+	dataplane := &fakeServer{debugState: debugstate.State{Pods: []debugstate.Pod{
+		{UID: "uid-1", Status: debugstate.PodStatusEnrolled},
+		{UID: "uid-2", Status: debugstate.PodStatusNetnsUnknown},
+	}}}
+	auditedAt := time.Now()
+	s := &Server{
+		dataplane: dataplane,
+		handlers: fakeHandlers{pods: map[string]*corev1.Pod{
+			"ns/pod-1": enrolled,
+			"ns/pod-2": pending,
+			"ns/pod-3": nil,
+		}},
+		cniConfigCheck: func() (string, error) {
+			return "/host/etc/cni/net.d/10-calico.conflist", errors.New("istio-cni plugin not found")
+		},
+		rulesAuditor: &inpodRulesAuditor{results: map[types.UID]debugstate.InpodRules{
+			"uid-1": {Pod: "ns/pod-1", AuditedAt: auditedAt, Drifted: true},
+		}},
+	}
+
+	state := s.DebugState("")
+	assert.Equal(t, state.CNIConfig, &debugstate.CNIConfig{
+		File:  "/host/etc/cni/net.d/10-calico.conflist",
+		Error: "istio-cni plugin not found",
+	})
+	assert.Equal(t, state.InpodRules, nil)
+
+	cases := []struct {
+		pod      string
+		expected *debugstate.InpodRules
+	}{
+		{"ns/pod-1", &debugstate.InpodRules{Pod: "ns/pod-1", AuditedAt: auditedAt, Drifted: true}},
+		{"ns/pod-2", &debugstate.InpodRules{Pod: "ns/pod-2", Error: "pod netns is not known to the node agent"}},
+		{"ns/pod-3", &debugstate.InpodRules{Pod: "ns/pod-3", Error: "pod is not eligible for ambient enrollment"}},
+		{"ns/pod-4", &debugstate.InpodRules{Pod: "ns/pod-4", Error: "failed to find pod ns/pod-4"}},
+		{"pod-1", &debugstate.InpodRules{Pod: "pod-1", Error: "pod must be given as <namespace>/<name>"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.pod, func(t *testing.T) {
+			assert.Equal(t, s.DebugState(tc.pod).InpodRules, tc.expected)
+		})
+	}
+
+	// A pod enrolled since the last audit has no result yet, and nothing is reported when the audit is disabled.
+	s.rulesAuditor.results = nil
+	assert.Equal(t, s.DebugState("ns/pod-1").InpodRules, &debugstate.InpodRules{Pod: "ns/pod-1", Error: "pod was not audited yet"})
+	s.rulesAuditor = nil
+	assert.Equal(t, s.DebugState("ns/pod-1").InpodRules,
+		&debugstate.InpodRules{Pod: "ns/pod-1", Error: "in-pod rules are not audited, the audit interval is not set"})
+	dataplane.AssertExpectations(t)
+}
@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package debugstate defines the state of the node agent served as JSON by its debug endpoint
+// (constants.DebugStateEndpoint), and the reasons of the events it writes on pods, so that clients such as istioctl
+// can use them without depending on the node agent.
+package debugstate
+
+import "time"
+
+// PodQueryParam is the query parameter of the debug endpoint selecting, as <namespace>/<name>, a pod whose
+// last in-pod rules audit is reported.
+const PodQueryParam = "pod"
+
+// ReasonInpodRulesDrift is the reason of the events written on a pod whose in-pod rules drifted from the expected ones.
+const ReasonInpodRulesDrift = "InpodRulesDrift"
+
+// Enrollment status of a pod in the pod cache.
+const (
+	// PodStatusEnrolled means a ztunnel acknowledged the pod.
+	PodStatusEnrolled = "enrolled"
+	// PodStatusUnacknowledged means the pod is in the cache, but no connected ztunnel acknowledged it.
+	PodStatusUnacknowledged = "unacknowledged"
+	// PodStatusNetnsUnknown means the pod is in the cache, but its netns is not known (yet).
+	PodStatusNetnsUnknown = "netnsUnknown"
+)
+
+// State is a point-in-time dump of the node agent state.
+type State struct {
+	Pods               []Pod               `json:"pods"`
+	ZtunnelConnections []ZtunnelConnection `json:"ztunnelConnections"`
+	HostRules          []string            `json:"hostRules"`
+	// HostRulesError is set if the host rules could not be listed.
+	HostRulesError string `json:"hostRulesError,omitempty"`
+	// CNIConfig is nil if the node agent does not know how the CNI config of the node is checked.
+	CNIConfig *CNIConfig `json:"cniConfig,omitempty"`
+	// InpodRules is only set if a pod was selected with PodQueryParam.
+	InpodRules *InpodRules `json:"inpodRules,omitempty"`
+}
+
+// Pod describes a pod in the pod cache.
+type Pod struct {
+	UID            string `json:"uid"`
+	Name           string `json:"name,omitempty"`
+	Namespace      string `json:"namespace,omitempty"`
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	NetnsInode     uint64 `json:"netnsInode,omitempty"`
+	Status         string `json:"status"`
+	// AckedBy is the UUID of the ztunnel connection which acknowledged the pod.
+	AckedBy string `json:"ackedBy,omitempty"`
+}
+
+// ZtunnelConnection describes a ztunnel connection.
+type ZtunnelConnection struct {
+	UUID        string    `json:"uuid"`
+	Version     string    `json:"version,omitempty"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	// Latest is set on the connection new pods are sent to.
+	Latest bool `json:"latest"`
+}
+
+// CNIConfig describes the CNI config file the container runtime uses on the node.
+type CNIConfig struct {
+	// File is the highest priority CNI config file.
+	File string `json:"file,omitempty"`
+	// Error is set if the file does not invoke the istio-cni plugin, or could not be read.
+	Error string `json:"error,omitempty"`
+}
+
+// InpodRules is the result of the last periodic audit of the in-pod traffic redirection rules of a pod against
+// the expected ones.
+type InpodRules struct {
+	// Pod is the pod as <namespace>/<name>.
+	Pod string `json:"pod"`
+	// AuditedAt is the time of the last audit of the pod.
+	AuditedAt time.Time `json:"auditedAt,omitzero"`
+	Drifted   bool      `json:"drifted"`
+	// Repaired is set if the drifted rules were repaired.
+	Repaired bool `json:"repaired,omitempty"`
+	// Error is set if the rules could not be checked or repaired, or were not audited.
+	Error string `json:"error,omitempty"`
+}
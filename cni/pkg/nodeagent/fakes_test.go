@@ -28,6 +28,7 @@ import (
 
 	"istio.io/istio/cni/pkg/config"
 	"istio.io/istio/cni/pkg/iptables"
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
 )
 
 //go:embed testdata/cgroupns
@@ -38,6 +39,8 @@ type fakeZtunnel struct {
 	addedPods   atomic.Int32
 	addError    error
 	delError    error
+	conns       []debugstate.ZtunnelConnection
+	acks        map[string]string
 }
 
 func (f *fakeZtunnel) Run(ctx context.Context) {
@@ -53,6 +56,10 @@ func (f *fakeZtunnel) PodAdded(ctx context.Context, pod *corev1.Pod, netns Netns
 	return f.addError
 }
 
+func (f *fakeZtunnel) Connections() ([]debugstate.ZtunnelConnection, map[string]string) {
+	return f.conns, f.acks
+}
+
 func (f *fakeZtunnel) Close() error {
 	return nil
 }
@@ -15,34 +15,40 @@
 package nodeagent
 
 import (
+	"encoding/json"
 	"net/http"
 	"sync/atomic"
 
 	"istio.io/istio/cni/pkg/constants"
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
+	istioNetUtil "istio.io/istio/pkg/util/net"
 )
 
 // StartHealthServer initializes and starts a web server that exposes liveness and readiness endpoints at port 8000.
-func StartHealthServer() (installReady *atomic.Value, watchReady *atomic.Value) {
+// The debug state endpoint is also served there, once the ambient node agent server is stored in debugServer.
+func StartHealthServer() (installReady *atomic.Value, watchReady *atomic.Value, debugServer *atomic.Pointer[Server]) {
 	router := http.NewServeMux()
-	installReady, watchReady = initRouter(router)
+	installReady, watchReady, debugServer = initRouter(router)
 
 	go func() {
 		_ = http.ListenAndServe(":"+constants.ReadinessPort, router)
 	}()
 
-	return installReady, watchReady
+	return installReady, watchReady, debugServer
 }
 
-func initRouter(router *http.ServeMux) (installReady *atomic.Value, watchReady *atomic.Value) {
+func initRouter(router *http.ServeMux) (installReady *atomic.Value, watchReady *atomic.Value, debugServer *atomic.Pointer[Server]) {
 	installReady = &atomic.Value{}
 	watchReady = &atomic.Value{}
+	debugServer = &atomic.Pointer[Server]{}
 	installReady.Store(false)
 	watchReady.Store(false)
 
 	router.HandleFunc(constants.LivenessEndpoint, healthz)
 	router.HandleFunc(constants.ReadinessEndpoint, readyz(installReady, watchReady))
+	router.HandleFunc(constants.DebugStateEndpoint, debugState(debugServer))
 
-	return installReady, watchReady
+	return installReady, watchReady, debugServer
 }
 
 func healthz(w http.ResponseWriter, _ *http.Request) {
@@ -58,3 +64,27 @@ func readyz(installReady, watchReady *atomic.Value) http.HandlerFunc {
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// debugState dumps the state of the ambient node agent as JSON. It is unavailable until the server is started,
+// and when ambient is not enabled. The in-pod rules of the pod selected with debugstate.PodQueryParam are also checked.
+// As the node agent runs in the host network, the state is only served to localhost, e.g. through a port-forward.
+func debugState(debugServer *atomic.Pointer[Server]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !istioNetUtil.IsRequestFromLocalhost(r) {
+			http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+			return
+		}
+		server := debugServer.Load()
+		if server == nil {
+			http.Error(w, "ambient node agent is not running", http.StatusServiceUnavailable)
+			return
+		}
+		out, err := json.MarshalIndent(server.DebugState(r.URL.Query().Get(debugstate.PodQueryParam)), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(out)
+	}
+}
@@ -15,17 +15,21 @@
 package nodeagent
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"istio.io/istio/cni/pkg/constants"
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
 	"istio.io/istio/pkg/test/util/assert"
 )
 
 func TestServer(t *testing.T) {
 	router := http.NewServeMux()
-	installReady, watchReady := initRouter(router)
+	installReady, watchReady, _ := initRouter(router)
 
 	assert.Equal(t, installReady.Load(), false)
 	assert.Equal(t, watchReady.Load(), false)
@@ -52,6 +56,40 @@ func TestServer(t *testing.T) {
 	makeReq(t, server.URL, constants.ReadinessEndpoint, http.StatusServiceUnavailable)
 }
 
+func TestDebugStateEndpoint(t *testing.T) {
+	router := http.NewServeMux()
+	_, _, debugServer := initRouter(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// not available until the ambient server is stored
+	makeReq(t, server.URL, constants.DebugStateEndpoint, http.StatusServiceUnavailable)
+
+	debugServer.Store(&Server{dataplane: &fakeServer{}})
+	makeReq(t, server.URL, constants.DebugStateEndpoint, http.StatusOK)
+
+	res, err := http.Get(server.URL + constants.DebugStateEndpoint)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, res.Header.Get("Content-Type"), "application/json")
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	var state debugstate.State
+	assert.NoError(t, json.Unmarshal(body, &state))
+}
+
+func TestDebugStateEndpointLocalhostOnly(t *testing.T) {
+	debugServer := &atomic.Pointer[Server]{}
+	debugServer.Store(&Server{dataplane: &fakeServer{}})
+
+	req := httptest.NewRequest(http.MethodGet, constants.DebugStateEndpoint, nil)
+	req.RemoteAddr = "10.0.0.1:34567"
+	rec := httptest.NewRecorder()
+	debugState(debugServer)(rec, req)
+	assert.Equal(t, rec.Code, http.StatusForbidden)
+}
+
 func makeReq(t *testing.T, url, endpoint string, expectedStatusCode int) {
 	t.Helper()
 	res, err := http.Get(url + endpoint)
@@ -23,6 +23,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	corev1 "k8s.io/api/core/v1"
 
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
 	istiolog "istio.io/istio/pkg/log"
 )
 
@@ -37,7 +38,8 @@ func setupLogging() {
 
 type fakeServer struct {
 	mock.Mock
-	testWG *WaitGroup // optional waitgroup, if code under test makes a number of async calls to fakeServer
+	testWG     *WaitGroup // optional waitgroup, if code under test makes a number of async calls to fakeServer
+	debugState debugstate.State
 }
 
 func (f *fakeServer) AddPodToMesh(ctx context.Context, pod *corev1.Pod, podIPs []netip.Addr, netNs string) error {
@@ -61,6 +63,15 @@ func (f *fakeServer) SyncHostProbeIPSet(pod *corev1.Pod, podIPs []netip.Addr) er
 	return args.Error(0)
 }
 
+func (f *fakeServer) AuditPodRules(pod *corev1.Pod, repair bool) (bool, error) {
+	args := f.Called(pod, repair)
+	return args.Bool(0), args.Error(1)
+}
+
+func (f *fakeServer) DebugState() debugstate.State {
+	return f.debugState
+}
+
 func (f *fakeServer) Start(ctx context.Context) {
 }
 
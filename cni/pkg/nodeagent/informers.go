@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"time"
 
@@ -25,8 +26,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
+	"istio.io/istio/cni/pkg/apis/v1alpha1"
 	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/controllers"
@@ -59,6 +62,8 @@ type InformerHandlers struct {
 	queue      controllers.Queue
 	pods       kclient.Client[*corev1.Pod]
 	namespaces kclient.Client[*corev1.Namespace]
+	// captureExclusions is nil unless CaptureExclusion resources are watched, see watchCaptureExclusions.
+	captureExclusions *captureExclusions
 }
 
 func setupHandlers(ctx context.Context, kubeClient kube.Client, dataplane MeshDataplane,
@@ -125,6 +130,15 @@ func setupHandlers(ctx context.Context, kubeClient kube.Client, dataplane MeshDa
 	return s
 }
 
+// watchCaptureExclusions updates the status of the CaptureExclusion resources, and re-applies the inpod rules of the
+// enrolled pods of a namespace when the CaptureExclusion resources of the namespace change. It must be called before Start.
+func (s *InformerHandlers) watchCaptureExclusions(exclusions *captureExclusions) {
+	s.captureExclusions = exclusions
+	exclusions.client.AddEventHandler(controllers.FromEventHandler(func(o controllers.Event) {
+		s.queue.Add(o)
+	}))
+}
+
 // GetPodIfAmbientEnabled looks up a pod. It returns:
 // * An error if the pod cannot be found
 // * nil if the pod is found, but is not currently eligible for ambient enrollment
@@ -146,7 +160,11 @@ func (s *InformerHandlers) GetPodIfAmbientEnabled(podName, podNamespace string)
 
 func (s *InformerHandlers) Start() {
 	// Wait for all events to be queued
-	kube.WaitForCacheSync("informer", s.ctx.Done(), s.pods.HasSynced, s.namespaces.HasSynced)
+	synced := []cache.InformerSynced{s.pods.HasSynced, s.namespaces.HasSynced}
+	if s.captureExclusions != nil {
+		synced = append(synced, s.captureExclusions.client.HasSynced)
+	}
+	kube.WaitForCacheSync("informer", s.ctx.Done(), synced...)
 	go s.queue.Run(s.ctx.Done())
 	// Note that we are explicitly *not* doing
 	// 'kube.WaitForCacheSync("informer queue", s.ctx.Done(), s.queue.HasSynced)'
@@ -222,6 +240,9 @@ func (s *InformerHandlers) reconcile(input any) error {
 		return nil
 	case *corev1.Pod:
 		return s.reconcilePod(input)
+	case *v1alpha1.CaptureExclusion:
+		s.reconcileCaptureExclusion(input)
+		return nil
 	default:
 		return fmt.Errorf("unexpected event type: %+v", input)
 	}
@@ -248,6 +269,38 @@ func (s *InformerHandlers) reconcileNamespace(input any) {
 	}
 }
 
+// reconcileCaptureExclusion reports in the status of a changed CaptureExclusion whether it is applied, and
+// re-applies the inpod rules of the enrolled pods in its namespace, so that they match the exclusions now selecting them.
+func (s *InformerHandlers) reconcileCaptureExclusion(input any) {
+	event := input.(controllers.Event)
+	if event.Event != controllers.EventDelete {
+		s.captureExclusions.updateStatus(event.Latest().(*v1alpha1.CaptureExclusion))
+	}
+	namespace := event.Latest().GetNamespace()
+	log.Infof("CaptureExclusion %s/%s %s, re-applying the inpod rules of the namespace",
+		namespace, event.Latest().GetName(), event.Event)
+
+	for _, pod := range s.pods.List(namespace, klabels.Everything()) {
+		if util.IsZtunnelPod(s.systemNamespace, pod) || kube.CheckPodTerminal(pod) || !util.PodFullyEnrolled(pod) {
+			continue
+		}
+		s.reapplyInpodRules(pod)
+	}
+}
+
+// reapplyInpodRules recreates the inpod rules of an enrolled pod if they differ from the expected ones.
+// Failures are not retried: the rules in place keep capturing the traffic, and will be fixed by the
+// next change or audit.
+func (s *InformerHandlers) reapplyInpodRules(pod *corev1.Pod) {
+	log := log.WithLabels("ns", pod.Namespace, "name", pod.Name)
+	updated, err := s.dataplane.AuditPodRules(pod, true)
+	if err != nil {
+		log.Warnf("failed to re-apply inpod rules: %v", err)
+	} else if updated {
+		log.Infof("re-applied inpod rules")
+	}
+}
+
 func (s *InformerHandlers) reconcilePod(input any) error {
 	event := input.(controllers.Event)
 	latestEventPod := event.Latest().(*corev1.Pod)
@@ -345,6 +398,12 @@ func (s *InformerHandlers) reconcilePod(input any) error {
 			}
 		}
 
+		// The CaptureExclusions selecting the pod may have changed with its labels.
+		if s.captureExclusions != nil && isEnrolled && shouldBeEnabled && !isTerminated &&
+			!maps.Equal(currentPod.Labels, oldPod.Labels) {
+			s.reapplyInpodRules(currentPod)
+		}
+
 		if !changeNeeded || isTerminated {
 			log.Debugf("pod update event skipped: no change needed")
 			return nil
@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
+	"istio.io/istio/pkg/monitoring"
+)
+
+var (
+	repairedTag          = monitoring.CreateLabel("repaired")
+	inpodRulesDriftTotal = monitoring.NewSum(
+		"nodeagent_inpod_rules_drift_total",
+		"The total number of times the in-pod traffic redirection rules of an enrolled pod were found to have drifted.",
+	)
+)
+
+// eventWriter writes Kubernetes events, see kclient.EventRecorder.
+type eventWriter interface {
+	Write(object runtime.Object, eventtype, reason, messageFmt string, args ...any)
+}
+
+// inpodRulesAuditor periodically checks the in-pod traffic redirection rules of the enrolled pods against the
+// expected ones. Other tooling on the node flushing the tables inside a pod would otherwise silently make its
+// traffic bypass ztunnel. The result of the last audit of each pod is kept for the debug endpoint.
+type inpodRulesAuditor struct {
+	pods      func() []*corev1.Pod
+	dataplane MeshDataplane
+	events    eventWriter
+	interval  time.Duration
+	repair    bool
+
+	mu      sync.RWMutex
+	results map[types.UID]debugstate.InpodRules
+}
+
+func newInpodRulesAuditor(pods func() []*corev1.Pod, dataplane MeshDataplane, events eventWriter,
+	interval time.Duration, repair bool,
+) *inpodRulesAuditor {
+	return &inpodRulesAuditor{
+		pods:      pods,
+		dataplane: dataplane,
+		events:    events,
+		interval:  interval,
+		repair:    repair,
+	}
+}
+
+func (a *inpodRulesAuditor) Run(stop <-chan struct{}) {
+	log.Infof("auditing inpod rules every %v (repair: %v)", a.interval, a.repair)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			a.audit()
+		}
+	}
+}
+
+// lastResult returns the result of the last audit of a pod, if it was audited.
+func (a *inpodRulesAuditor) lastResult(uid types.UID) (debugstate.InpodRules, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	res, ok := a.results[uid]
+	return res, ok
+}
+
+// audit checks every enrolled pod once.
+func (a *inpodRulesAuditor) audit() {
+	results := make(map[types.UID]debugstate.InpodRules)
+	defer func() {
+		a.mu.Lock()
+		a.results = results
+		a.mu.Unlock()
+	}()
+	for _, pod := range a.pods() {
+		log := log.WithLabels("ns", pod.Namespace, "name", pod.Name)
+		drifted, err := a.dataplane.AuditPodRules(pod, a.repair)
+		res := debugstate.InpodRules{Pod: pod.Namespace + "/" + pod.Name, AuditedAt: time.Now(), Drifted: drifted}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		if !drifted {
+			results[pod.UID] = res
+			if err != nil {
+				log.Warnf("failed to audit inpod rules: %v", err)
+			}
+			continue
+		}
+		repaired := a.repair && err == nil
+		res.Repaired = repaired
+		results[pod.UID] = res
+		inpodRulesDriftTotal.With(repairedTag.Value(strconv.FormatBool(repaired))).Increment()
+		switch {
+		case repaired:
+			log.Warn("inpod rules drifted from the expected rules, repaired them")
+			a.events.Write(pod, corev1.EventTypeWarning, debugstate.ReasonInpodRulesDrift,
+				"in-pod traffic redirection rules drifted from the expected rules and were repaired")
+		case a.repair:
+			log.Errorf("inpod rules drifted from the expected rules, failed to repair them: %v", err)
+			a.events.Write(pod, corev1.EventTypeWarning, debugstate.ReasonInpodRulesDrift,
+				"in-pod traffic redirection rules drifted from the expected rules, repair failed: %v", err)
+		default:
+			log.Warn("inpod rules drifted from the expected rules, traffic may bypass ztunnel")
+			a.events.Write(pod, corev1.EventTypeWarning, debugstate.ReasonInpodRulesDrift,
+				"in-pod traffic redirection rules drifted from the expected rules, traffic may bypass the mesh")
+		}
+	}
+}
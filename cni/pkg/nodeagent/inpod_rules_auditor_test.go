@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+type recordedEvent struct {
+	object  runtime.Object
+	reason  string
+	message string
+}
+
+type fakeEventWriter struct {
+	events []recordedEvent
+}
+
+func (f *fakeEventWriter) Write(object runtime.Object, eventtype, reason, messageFmt string, args ...any) {
+	f.events = append(f.events, recordedEvent{object: object, reason: reason, message: fmt.Sprintf(messageFmt, args...)})
+}
+
+func auditorTestPods() []*corev1.Pod {
+	return []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "intact", Namespace: "ns", UID: "1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "drifted", Namespace: "ns", UID: "2"}},
+	}
+}
+
+func TestInpodRulesAuditorReportsDrift(t *testing.T) {
+	setupLogging()
+	mt := monitortest.New(t)
+	pods := auditorTestPods()
+	server := &fakeServer{}
+	server.On("AuditPodRules", pods[0], false).Return(false, nil)
+	server.On("AuditPodRules", pods[1], false).Return(true, nil)
+	events := &fakeEventWriter{}
+
+	a := newInpodRulesAuditor(func() []*corev1.Pod { return pods }, server, events, time.Minute, false)
+	a.audit()
+
+	server.AssertExpectations(t)
+	assert.Equal(t, len(events.events), 1)
+	assert.Equal(t, events.events[0].object, runtime.Object(pods[1]))
+	assert.Equal(t, events.events[0].reason, debugstate.ReasonInpodRulesDrift)
+	mt.Assert(inpodRulesDriftTotal.Name(), map[string]string{"repaired": "false"}, monitortest.Exactly(1))
+
+	res, ok := a.lastResult(pods[0].UID)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, res.Pod, "ns/intact")
+	assert.Equal(t, res.Drifted, false)
+	assert.Equal(t, res.AuditedAt.IsZero(), false)
+	res, ok = a.lastResult(pods[1].UID)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, res.Drifted, true)
+	assert.Equal(t, res.Repaired, false)
+}
+
+func TestInpodRulesAuditorRepairsDrift(t *testing.T) {
+	setupLogging()
+	mt := monitortest.New(t)
+	pods := auditorTestPods()
+	server := &fakeServer{}
+	server.On("AuditPodRules", pods[0], true).Return(false, nil)
+	server.On("AuditPodRules", pods[1], true).Return(true, nil)
+	events := &fakeEventWriter{}
+
+	a := newInpodRulesAuditor(func() []*corev1.Pod { return pods }, server, events, time.Minute, true)
+	a.audit()
+
+	server.AssertExpectations(t)
+	assert.Equal(t, len(events.events), 1)
+	assert.Equal(t, events.events[0].message, "in-pod traffic redirection rules drifted from the expected rules and were repaired")
+	mt.Assert(inpodRulesDriftTotal.Name(), map[string]string{"repaired": "true"}, monitortest.Exactly(1))
+
+	res, _ := a.lastResult(pods[1].UID)
+	assert.Equal(t, res.Repaired, true)
+}
+
+func TestInpodRulesAuditorRepairFailure(t *testing.T) {
+	setupLogging()
+	mt := monitortest.New(t)
+	pods := auditorTestPods()
+	server := &fakeServer{}
+	// An error without drift means the rules could not be checked at all, there is nothing to report.
+	server.On("AuditPodRules", pods[0], true).Return(false, errors.New("netns gone"))
+	server.On("AuditPodRules", pods[1], true).Return(true, errors.New("table locked"))
+	events := &fakeEventWriter{}
+
+	a := newInpodRulesAuditor(func() []*corev1.Pod { return pods }, server, events, time.Minute, true)
+	a.audit()
+
+	server.AssertExpectations(t)
+	assert.Equal(t, len(events.events), 1)
+	assert.Equal(t, events.events[0].message,
+		"in-pod traffic redirection rules drifted from the expected rules, repair failed: table locked")
+	mt.Assert(inpodRulesDriftTotal.Name(), map[string]string{"repaired": "false"}, monitortest.Exactly(1))
+
+	res, _ := a.lastResult(pods[0].UID)
+	assert.Equal(t, res.Error, "netns gone")
+	res, _ = a.lastResult(pods[1].UID)
+	assert.Equal(t, res.Repaired, false)
+	assert.Equal(t, res.Error, "table locked")
+}
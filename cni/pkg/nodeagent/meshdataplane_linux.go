@@ -24,6 +24,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	set "istio.io/istio/cni/pkg/addressset"
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
 	"istio.io/istio/cni/pkg/trafficmanager"
 	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/util/sets"
@@ -244,6 +245,23 @@ func (s *meshDataplane) SyncHostProbeIPSet(pod *corev1.Pod, podIPs []netip.Addr)
 	return err
 }
 
+// AuditPodRules only concerns the in-pod rules, which are owned by the inner NetServer.
+func (s *meshDataplane) AuditPodRules(pod *corev1.Pod, repair bool) (bool, error) {
+	return s.netServer.AuditPodRules(pod, repair)
+}
+
+// DebugState extends the state of the inner NetServer with the host rules.
+func (s *meshDataplane) DebugState() debugstate.State {
+	state := s.netServer.DebugState()
+	rules, err := s.hostTrafficManager.ListHostRules()
+	if err != nil {
+		state.HostRulesError = err.Error()
+	} else if rules != nil {
+		state.HostRules = rules
+	}
+	return state
+}
+
 // syncHostAddrSets is called after the host node ipset has been created (or found + flushed)
 // during initial snapshot creation, it will insert every snapshotted pod's IP into the set.
 //
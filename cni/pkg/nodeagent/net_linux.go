@@ -19,11 +19,15 @@ import (
 	"errors"
 	"fmt"
 	"net/netip"
+	"sync"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
 	"istio.io/istio/cni/pkg/trafficmanager"
+	istiolog "istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/slices"
 )
 
@@ -33,6 +37,11 @@ type NetServer struct {
 	currentPodSnapshot *podNetnsCache
 	trafficManager     trafficmanager.TrafficRuleManager
 	podNs              PodNetnsFinder
+	// inpodRulesMu is held for reading while the inpod rules of a pod are created or deleted, and for
+	// writing while they are audited, so that the rules of a pod being removed are never repaired.
+	inpodRulesMu sync.RWMutex
+	// captureExclusions is nil unless CaptureExclusion resources are watched.
+	captureExclusions *captureExclusions
 	// allow overriding for tests
 	netnsRunner func(fdable NetnsFd, toRun func() error) error
 }
@@ -113,26 +122,9 @@ func (s *NetServer) SyncHostProbeIPSet(_ *corev1.Pod, _ []netip.Addr) error {
 func (s *NetServer) AddPodToMesh(ctx context.Context, pod *corev1.Pod, podIPs []netip.Addr, netNs string) error {
 	log := log.WithLabels("ns", pod.Namespace, "name", pod.Name)
 	log.Info("adding pod to the mesh")
-	// make sure the cache is aware of the pod, even if we don't have the netns yet.
-	s.currentPodSnapshot.Ensure(string(pod.UID))
-	openNetns, err := s.getOrOpenNetns(pod, netNs)
+	openNetns, err := s.createInpodRules(log, pod, netNs)
 	if err != nil {
-		// if we fail, we should not leave a dangling UID in the snapshot.
-		s.currentPodSnapshot.Take(string(pod.UID))
-		return NewErrNonRetryableAdd(err)
-	}
-
-	podCfg := getPodLevelTrafficOverrides(pod)
-
-	log.Debug("calling CreateInpodRules")
-	if err := s.netnsRunner(openNetns, func() error {
-		return s.trafficManager.CreateInpodRules(log, podCfg)
-	}); err != nil {
-		// We currently treat any failure to create inpod rules as non-retryable/catastrophic,
-		// and return a NonRetryableError in this case.
-		log.Errorf("failed to update POD inpod: %s/%s %v", pod.Namespace, pod.Name, err)
-		s.currentPodSnapshot.Take(string(pod.UID))
-		return NewErrNonRetryableAdd(err)
+		return err
 	}
 
 	// For *any* other failures after a successful `CreateInpodRules` call, we must return
@@ -162,6 +154,73 @@ func (s *NetServer) RemovePodFromMesh(ctx context.Context, pod *corev1.Pod, isDe
 	log := log.WithLabels("ns", pod.Namespace, "name", pod.Name)
 	log.WithLabels("delete", isDelete).Debugf("removing pod from the mesh")
 
+	if err := s.deleteInpodRules(log, pod, isDelete); err != nil {
+		return err
+	}
+
+	log.Debug("removing pod from ztunnel")
+	if err := s.ztunnelServer.PodDeleted(ctx, string(pod.UID)); err != nil {
+		log.Errorf("failed to delete pod from ztunnel: %v", err)
+		return err
+	}
+	return nil
+}
+
+func newNetServer(ztunnelServer ZtunnelServer, podNsMap *podNetnsCache, trafficManager trafficmanager.TrafficRuleManager, podNs PodNetnsFinder) *NetServer {
+	return &NetServer{
+		ztunnelServer:      ztunnelServer,
+		currentPodSnapshot: podNsMap,
+		podNs:              podNs,
+		trafficManager:     trafficManager,
+		netnsRunner:        NetnsDo,
+	}
+}
+
+// podTrafficOverrides returns the overrides of the inpod rules of a pod, from its annotations and the
+// CaptureExclusion resources selecting it.
+func (s *NetServer) podTrafficOverrides(pod *corev1.Pod) config.PodLevelOverrides {
+	podCfg := getPodLevelTrafficOverrides(pod)
+	if s.captureExclusions != nil {
+		s.captureExclusions.apply(pod, &podCfg)
+	}
+	return podCfg
+}
+
+// createInpodRules caches the netns of the pod and creates its inpod rules.
+// It returns a NonRetryableError on failure.
+func (s *NetServer) createInpodRules(log *istiolog.Scope, pod *corev1.Pod, netNs string) (Netns, error) {
+	s.inpodRulesMu.RLock()
+	defer s.inpodRulesMu.RUnlock()
+
+	// make sure the cache is aware of the pod, even if we don't have the netns yet.
+	s.currentPodSnapshot.Ensure(string(pod.UID))
+	openNetns, err := s.getOrOpenNetns(pod, netNs)
+	if err != nil {
+		// if we fail, we should not leave a dangling UID in the snapshot.
+		s.currentPodSnapshot.Take(string(pod.UID))
+		return nil, NewErrNonRetryableAdd(err)
+	}
+
+	podCfg := s.podTrafficOverrides(pod)
+
+	log.Debug("calling CreateInpodRules")
+	if err := s.netnsRunner(openNetns, func() error {
+		return s.trafficManager.CreateInpodRules(log, podCfg)
+	}); err != nil {
+		// We currently treat any failure to create inpod rules as non-retryable/catastrophic,
+		// and return a NonRetryableError in this case.
+		log.Errorf("failed to update POD inpod: %s/%s %v", pod.Namespace, pod.Name, err)
+		s.currentPodSnapshot.Take(string(pod.UID))
+		return nil, NewErrNonRetryableAdd(err)
+	}
+	return openNetns, nil
+}
+
+// deleteInpodRules removes the netns of the pod from the cache and, if the pod is still running, deletes its inpod rules.
+func (s *NetServer) deleteInpodRules(log *istiolog.Scope, pod *corev1.Pod, isDelete bool) error {
+	s.inpodRulesMu.RLock()
+	defer s.inpodRulesMu.RUnlock()
+
 	// Whether pod is already deleted or not, we need to let go of our netns ref.
 	openNetns := s.currentPodSnapshot.Take(string(pod.UID))
 	if openNetns == nil {
@@ -180,23 +239,38 @@ func (s *NetServer) RemovePodFromMesh(ctx context.Context, pod *corev1.Pod, isDe
 			log.Warn("pod netns already gone, not deleting inpod rules")
 		}
 	}
-
-	log.Debug("removing pod from ztunnel")
-	if err := s.ztunnelServer.PodDeleted(ctx, string(pod.UID)); err != nil {
-		log.Errorf("failed to delete pod from ztunnel: %v", err)
-		return err
-	}
 	return nil
 }
 
-func newNetServer(ztunnelServer ZtunnelServer, podNsMap *podNetnsCache, trafficManager trafficmanager.TrafficRuleManager, podNs PodNetnsFinder) *NetServer {
-	return &NetServer{
-		ztunnelServer:      ztunnelServer,
-		currentPodSnapshot: podNsMap,
-		podNs:              podNs,
-		trafficManager:     trafficManager,
-		netnsRunner:        NetnsDo,
+// DebugState dumps the pod cache and the ztunnel connections. Host rules are not owned by the NetServer.
+func (s *NetServer) DebugState() debugstate.State {
+	return buildDebugState(s.currentPodSnapshot, s.ztunnelServer)
+}
+
+// AuditPodRules checks the inpod rules of an already-enrolled pod for drift, and recreates them if repair is set.
+// Pods whose netns is not known (yet) are skipped.
+func (s *NetServer) AuditPodRules(pod *corev1.Pod, repair bool) (bool, error) {
+	s.inpodRulesMu.Lock()
+	defer s.inpodRulesMu.Unlock()
+
+	openNetns := s.currentPodSnapshot.Get(string(pod.UID))
+	if openNetns == nil {
+		return false, nil
 	}
+	log := log.WithLabels("ns", pod.Namespace, "name", pod.Name)
+	podCfg := s.podTrafficOverrides(pod)
+
+	var drifted bool
+	err := s.netnsRunner(openNetns, func() error {
+		var err error
+		drifted, err = s.trafficManager.VerifyInpodRules(log, podCfg)
+		if err != nil || !drifted || !repair {
+			return err
+		}
+		log.Debug("inpod rules drifted, calling CreateInpodRules")
+		return s.trafficManager.CreateInpodRules(log, podCfg)
+	})
+	return drifted, err
 }
 
 // reconcileExistingPod is intended to run on node agent startup, for each pod that was already enrolled prior to startup.
@@ -210,7 +284,7 @@ func (s *NetServer) reconcileExistingPod(pod *corev1.Pod) error {
 		return err
 	}
 
-	podCfg := getPodLevelTrafficOverrides(pod)
+	podCfg := s.podTrafficOverrides(pod)
 
 	if err := s.netnsRunner(openNetns, func() error {
 		return s.trafficManager.CreateInpodRules(log, podCfg)
@@ -542,6 +542,40 @@ func TestReconcilePodReturnsNoErrorIfPodReconciles(t *testing.T) {
 	assert.Equal(t, (len(fakeDeps.ExecutedAll) != 0), true)
 }
 
+func TestServerAuditPodRules(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupLogging()
+
+	fakeDeps := &dependencies.DependenciesStub{}
+	fixture := getTestFixureWithIptablesConfig(ctx, fakeDeps, nil, &config.AmbientConfig{Reconcile: true})
+	netServer := fixture.netServer
+	pod := buildConvincingPod(false)
+
+	// Pods without a known netns are not audited.
+	drifted, err := netServer.AuditPodRules(pod, true)
+	assert.NoError(t, err)
+	assert.Equal(t, drifted, false)
+	assert.Equal(t, len(fakeDeps.ExecutedAll), 0)
+
+	fixture.podNsMap.UpsertPodCacheWithNetns(string(pod.UID), WorkloadInfo{
+		Workload: podToWorkload(pod),
+		Netns:    newFakeNs(123),
+	})
+
+	// The faked pod has no rules at all, so they have drifted.
+	drifted, err = netServer.AuditPodRules(pod, false)
+	assert.NoError(t, err)
+	assert.Equal(t, drifted, true)
+	audited := len(fakeDeps.ExecutedAll)
+
+	drifted, err = netServer.AuditPodRules(pod, true)
+	assert.NoError(t, err)
+	assert.Equal(t, drifted, true)
+	// Repairing executed the rule insertions on top of the audit.
+	assert.Equal(t, len(fakeDeps.ExecutedAll) > 2*audited, true)
+}
+
 var overrideTests = map[string]struct {
 	in  corev1.Pod
 	out config.PodLevelOverrides
@@ -16,6 +16,7 @@ package nodeagent
 
 import (
 	"net/netip"
+	"time"
 
 	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/config/constants"
@@ -58,5 +59,13 @@ type AmbientArgs struct {
 	EnableIPv6                 bool
 	ReconcilePodRulesOnStartup bool
 	NativeNftables             bool
+	EbpfRedirect               bool
 	ForceIptablesBinary        string
+	InpodRulesAuditInterval    time.Duration
+	InpodRulesAuditRepair      bool
+	ZDSRecordFile              string
+	CaptureExclusions          bool
+	// CNIConfigCheck returns the highest priority CNI config file of the node, and an error if it does not invoke
+	// the istio-cni plugin. It is reported by the debug endpoint, if set.
+	CNIConfigCheck func() (string, error)
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net/netip"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -28,8 +29,11 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
 
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
 	"istio.io/istio/cni/pkg/scopes"
 	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient"
+	"istio.io/istio/pkg/slices"
 )
 
 const defaultZTunnelKeepAliveCheckInterval = 5 * time.Second
@@ -53,6 +57,13 @@ type MeshDataplane interface {
 	// IP was observable (e.g. right after a node/kubelet restart).
 	SyncHostProbeIPSet(pod *corev1.Pod, podIPs []netip.Addr) error
 
+	// AuditPodRules checks an already-enrolled pod's in-pod traffic redirection rules for drift
+	// from the expected rules, and reprograms them if repair is set. It returns whether drift was found.
+	AuditPodRules(pod *corev1.Pod, repair bool) (bool, error)
+
+	// DebugState returns a dump of the pod cache, the ztunnel connections and the host rules, for debugging.
+	DebugState() debugstate.State
+
 	Stop(skipCleanup bool)
 }
 
@@ -65,6 +76,12 @@ type Server struct {
 
 	isReady *atomic.Value
 
+	// rulesAuditor is nil unless periodic auditing of the inpod rules is enabled.
+	rulesAuditor *inpodRulesAuditor
+	events       *kclient.EventRecorder
+	// cniConfigCheck is nil unless the CNI config of the node is reported by DebugState, see AmbientArgs.
+	cniConfigCheck func() (string, error)
+
 	cniServerStopFunc func()
 }
 
@@ -75,18 +92,28 @@ func NewServer(ctx context.Context, ready *atomic.Value, pluginSocket string, ar
 	}
 
 	s := &Server{
-		ctx:        ctx,
-		kubeClient: client,
-		isReady:    ready,
+		ctx:            ctx,
+		kubeClient:     client,
+		isReady:        ready,
+		cniConfigCheck: args.CNIConfigCheck,
 	}
 
-	s.dataplane, err = initMeshDataplane(client, args)
+	var exclusions *captureExclusions
+	if args.CaptureExclusions {
+		exclusions = newCaptureExclusions(client)
+	}
+
+	s.dataplane, err = initMeshDataplane(client, args, exclusions)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing mesh dataplane: %w", err)
 	}
 
 	s.NotReady()
-	s.handlers = setupHandlers(s.ctx, s.kubeClient, s.dataplane, args.SystemNamespace, args.EnablementSelector, args.ExcludeNamespaces)
+	handlers := setupHandlers(s.ctx, s.kubeClient, s.dataplane, args.SystemNamespace, args.EnablementSelector, args.ExcludeNamespaces)
+	if exclusions != nil {
+		handlers.watchCaptureExclusions(exclusions)
+	}
+	s.handlers = handlers
 
 	cniServer := startCniPluginServer(ctx, pluginSocket, s.handlers, s.dataplane)
 	err = cniServer.Start()
@@ -95,9 +122,71 @@ func NewServer(ctx context.Context, ready *atomic.Value, pluginSocket string, ar
 	}
 	s.cniServerStopFunc = cniServer.Stop
 
+	if args.InpodRulesAuditInterval > 0 {
+		events := kclient.NewEventRecorder(client, "cni-nodeagent")
+		s.events = &events
+		s.rulesAuditor = newInpodRulesAuditor(s.handlers.GetActiveAmbientPodSnapshot, s.dataplane, s.events,
+			args.InpodRulesAuditInterval, args.InpodRulesAuditRepair)
+	}
+
 	return s, nil
 }
 
+// DebugState returns a dump of the dataplane state and of the CNI config of the node, for debugging.
+// If pod is set, as <namespace>/<name>, the result of the last audit of its in-pod rules is also returned.
+func (s *Server) DebugState(pod string) debugstate.State {
+	state := s.dataplane.DebugState()
+	if s.cniConfigCheck != nil {
+		file, err := s.cniConfigCheck()
+		state.CNIConfig = &debugstate.CNIConfig{File: file}
+		if err != nil {
+			state.CNIConfig.Error = err.Error()
+		}
+	}
+	if pod != "" {
+		state.InpodRules = s.checkInpodRules(pod, state.Pods)
+	}
+	return state
+}
+
+// checkInpodRules returns the result of the last periodic audit of the in-pod rules of an enrolled pod. The rules
+// are not audited on demand, so that the debug endpoint cannot be used to make the node agent enter pod network
+// namespaces.
+func (s *Server) checkInpodRules(name string, cached []debugstate.Pod) *debugstate.InpodRules {
+	res := &debugstate.InpodRules{Pod: name}
+	namespace, podName, ok := strings.Cut(name, "/")
+	if !ok {
+		res.Error = "pod must be given as <namespace>/<name>"
+		return res
+	}
+	pod, err := s.handlers.GetPodIfAmbientEnabled(podName, namespace)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if pod == nil {
+		res.Error = "pod is not eligible for ambient enrollment"
+		return res
+	}
+	if !slices.ContainsFunc(cached, func(p debugstate.Pod) bool {
+		return p.UID == string(pod.UID) && p.Status != debugstate.PodStatusNetnsUnknown
+	}) {
+		res.Error = "pod netns is not known to the node agent"
+		return res
+	}
+	if s.rulesAuditor == nil {
+		res.Error = "in-pod rules are not audited, the audit interval is not set"
+		return res
+	}
+	audited, ok := s.rulesAuditor.lastResult(pod.UID)
+	if !ok {
+		res.Error = "pod was not audited yet"
+		return res
+	}
+	audited.Pod = name
+	return &audited
+}
+
 func (s *Server) Ready() {
 	s.isReady.Store(true)
 }
@@ -121,6 +210,9 @@ func (s *Server) Start() {
 	// Start accepting ztunnel connections
 	// (and send current snapshot when we get one)
 	s.dataplane.Start(s.ctx)
+	if s.rulesAuditor != nil {
+		go s.rulesAuditor.Run(s.ctx.Done())
+	}
 	// Everything (informer handlers, snapshot, zt server) ready to go
 	log.Info("CNI ambient server marking ready")
 	s.Ready()
@@ -129,6 +221,9 @@ func (s *Server) Start() {
 func (s *Server) Stop(skipCleanup bool) {
 	s.cniServerStopFunc()
 	s.dataplane.Stop(skipCleanup)
+	if s.events != nil {
+		s.events.Shutdown()
+	}
 }
 
 // ShouldStopCleanup of istio-cni config and binary when upgrading or on node reboot
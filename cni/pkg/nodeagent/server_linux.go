@@ -28,11 +28,12 @@ import (
 	"istio.io/istio/cni/pkg/iptables"
 	"istio.io/istio/cni/pkg/trafficmanager"
 	"istio.io/istio/cni/pkg/util"
+	"istio.io/istio/cni/pkg/zdsrecord"
 	"istio.io/istio/pkg/kube"
 	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
 )
 
-func initMeshDataplane(client kube.Client, args AmbientArgs) (*meshDataplane, error) {
+func initMeshDataplane(client kube.Client, args AmbientArgs, exclusions *captureExclusions) (*meshDataplane, error) {
 	// Linux specific startup operations
 	hostCfg := &config.AmbientConfig{
 		RedirectDNS:            args.DNSCapture,
@@ -100,9 +101,17 @@ func initMeshDataplane(client kube.Client, args AmbientArgs) (*meshDataplane, er
 	if err != nil {
 		return nil, fmt.Errorf("error initializing the ztunnel server: %w", err)
 	}
+	if args.ZDSRecordFile != "" {
+		log.Warnf("recording the ZDS messages exchanged with ztunnel to %s", args.ZDSRecordFile)
+		ztunnelServer.recorder, err = zdsrecord.NewFileRecorder(args.ZDSRecordFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	hostTrafficManager, podTrafficManager, err := trafficmanager.NewTrafficRuleManager(&trafficmanager.TrafficRuleManagerConfig{
 		NativeNftables: useNftables,
+		EbpfRedirect:   args.EbpfRedirect,
 		HostConfig:     hostCfg,
 		PodConfig:      podCfg,
 		HostDeps:       realDependenciesHost(args.ForceIptablesBinary),
@@ -138,6 +147,7 @@ func initMeshDataplane(client kube.Client, args AmbientArgs) (*meshDataplane, er
 		return nil, err
 	}
 	netServer := newNetServer(ztunnelServer, podNsMap, podTrafficManager, podNetns)
+	netServer.captureExclusions = exclusions
 
 	return &meshDataplane{
 		kubeClient:         client.Kube(),
@@ -20,13 +20,14 @@ import (
 	"context"
 	"net/netip"
 
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
 	"istio.io/istio/pkg/kube"
 	corev1 "k8s.io/api/core/v1"
 )
 
 type meshDataplane struct{}
 
-func initMeshDataplane(client kube.Client, args AmbientArgs) (*meshDataplane, error) {
+func initMeshDataplane(client kube.Client, args AmbientArgs, exclusions *captureExclusions) (*meshDataplane, error) {
 	return nil, errNotImplemented
 }
 
@@ -51,6 +52,14 @@ func (*meshDataplane) SyncHostProbeIPSet(pod *corev1.Pod, podIPs []netip.Addr) e
 	return errNotImplemented
 }
 
+func (*meshDataplane) AuditPodRules(pod *corev1.Pod, repair bool) (bool, error) {
+	return false, errNotImplemented
+}
+
+func (*meshDataplane) DebugState() debugstate.State {
+	return debugstate.State{}
+}
+
 func (*meshDataplane) Stop(skipCleanup bool) {
 	// not supported
 	return
@@ -0,0 +1,46 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"istio.io/istio/cni/pkg/zdsrecord"
+	"istio.io/istio/pkg/zdsapi"
+)
+
+// recordingConnection records the ZDS messages exchanged on a ztunnel connection.
+type recordingConnection struct {
+	ZtunnelConnection
+	recorder *zdsrecord.Recorder
+}
+
+var _ ZtunnelConnection = &recordingConnection{}
+
+func (r *recordingConnection) ReadHello() (*zdsapi.ZdsHello, error) {
+	hello, err := r.ZtunnelConnection.ReadHello()
+	if err == nil {
+		if recErr := r.recorder.RecordHello(r.UUID().String(), hello); recErr != nil {
+			log.Warnf("failed to record ZDS hello: %v", recErr)
+		}
+	}
+	return hello, err
+}
+
+func (r *recordingConnection) SendMsgAndWaitForAck(msg *zdsapi.WorkloadRequest, fd *int) (*zdsapi.WorkloadResponse, error) {
+	resp, err := r.ZtunnelConnection.SendMsgAndWaitForAck(msg, fd)
+	if recErr := r.recorder.RecordExchange(r.UUID().String(), msg, fd != nil, resp, err); recErr != nil {
+		log.Warnf("failed to record ZDS message: %v", recErr)
+	}
+	return resp, err
+}
@@ -0,0 +1,168 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/zdsrecord"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/zdsapi"
+)
+
+// startRecordingServer starts a ztunnel server recording the ZDS messages to the returned buffer.
+func startRecordingServer(ctx context.Context, pods PodNetnsCache) (*ztunnelServer, string, *bytes.Buffer) {
+	recording := &bytes.Buffer{}
+	srv := createStoppedServer(pods, uuid.New(), time.Second/10)
+	srv.ztunServer.recorder = zdsrecord.NewRecorder(recording)
+	go srv.ztunServer.Run(ctx)
+	return srv.ztunServer, srv.addr, recording
+}
+
+func waitForZtunnelConnection(t *testing.T, srv *ztunnelServer) {
+	t.Helper()
+	assert.EventuallyEqual(t, func() int {
+		conns, _ := srv.Connections()
+		return len(conns)
+	}, 1)
+}
+
+func TestZDSRecordAndReplay(t *testing.T) {
+	setupLogging()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := &fakePodCache{}
+	defer fillCacheWithFakePods(cache, 2)()
+	pod, ns, tmpFileToClose := podAndNetns()
+	defer tmpFileToClose.Close()
+
+	// record a session with a fake ztunnel acking everything but the pod deletion
+	srv, addr, recording := startRecordingServer(ctx, cache)
+	zt, err := zdsrecord.Dial(addr, nil)
+	assert.NoError(t, err)
+	for range len(cache.pods) + 1 {
+		_, _, err := zt.Receive()
+		assert.NoError(t, err)
+		assert.NoError(t, zt.Respond(zdsrecord.Ack("")))
+	}
+	errChan := make(chan error)
+	go func() {
+		errChan <- srv.PodAdded(ctx, pod, ns)
+	}()
+	req, fd, err := zt.Receive()
+	assert.NoError(t, err)
+	assert.Equal(t, req.GetAdd().GetUid(), string(pod.UID))
+	assert.Equal(t, fd, true)
+	assert.NoError(t, zt.Respond(zdsrecord.Ack("")))
+	assert.NoError(t, <-errChan)
+	go func() {
+		errChan <- srv.PodDeleted(ctx, string(pod.UID))
+	}()
+	_, _, err = zt.Receive()
+	assert.NoError(t, err)
+	assert.NoError(t, zt.Respond(zdsrecord.Ack("unknown workload")))
+	assert.NoError(t, <-errChan)
+	zt.Close()
+
+	entries, err := zdsrecord.Read(recording)
+	assert.NoError(t, err)
+	conns := zdsrecord.ByConnection(entries)
+	assert.Equal(t, len(conns), 1)
+	// hello, snapshot, add and delete
+	assert.Equal(t, len(conns[0]), 1+len(cache.pods)+1+2)
+	assert.Equal(t, zdsrecord.Hello(conns[0]).GetVersion(), zdsapi.Version_V1)
+	last := conns[0][len(conns[0])-1]
+	assert.Equal(t, last.Request.GetDel().GetUid(), string(pod.UID))
+	assert.Equal(t, last.Response.GetAck().GetError(), "unknown workload")
+
+	// replay it against a new server, in the same state
+	replayServer := startServerWithPodCache(ctx, cache)
+	replayed, err := zdsrecord.Dial(replayServer.addr, zdsrecord.Hello(conns[0]))
+	assert.NoError(t, err)
+	defer replayed.Close()
+	replayErr := make(chan error)
+	go func() {
+		replayErr <- replayed.Replay(conns[0])
+	}()
+	waitForZtunnelConnection(t, replayServer.ztunServer)
+	assert.NoError(t, replayServer.ztunServer.PodAdded(ctx, pod, ns))
+	assert.NoError(t, replayServer.ztunServer.PodDeleted(ctx, string(pod.UID)))
+	assert.NoError(t, <-replayErr)
+}
+
+func TestZDSReplayDetectsMismatch(t *testing.T) {
+	setupLogging()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries, err := zdsrecord.ReadFile(filepath.Join("testdata", "zds", "add-keep-delete.jsonl"))
+	assert.NoError(t, err)
+
+	// the recorded snapshot had two pods, but the server only knows one
+	cache := &fakePodCache{}
+	defer fillCacheWithFakePods(cache, 1)()
+	srv := startServerWithPodCache(ctx, cache)
+	zt, err := zdsrecord.Dial(srv.addr, zdsrecord.Hello(entries))
+	assert.NoError(t, err)
+	defer zt.Close()
+	assert.Error(t, zt.Replay(entries))
+}
+
+// TestZDSReplayRecording replays a recorded session, as a regression test of the messages sent to ztunnel when a pod
+// with an unknown netns is snapshotted, then a pod is added and another deleted.
+func TestZDSReplayRecording(t *testing.T) {
+	setupLogging()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries, err := zdsrecord.ReadFile(filepath.Join("testdata", "zds", "add-keep-delete.jsonl"))
+	assert.NoError(t, err)
+
+	_, ns, tmpFileToClose := podAndNetns()
+	defer tmpFileToClose.Close()
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: "uid-" + types.UID(name)},
+			Spec:       corev1.PodSpec{ServiceAccountName: "sa"},
+		}
+	}
+	cache := &fakePodCache{pods: map[string]WorkloadInfo{
+		"uid-a": {Workload: podToWorkload(newPod("a")), Netns: ns},
+		"uid-b": {Workload: podToWorkload(newPod("b"))},
+	}}
+
+	srv := startServerWithPodCache(ctx, cache)
+	zt, err := zdsrecord.Dial(srv.addr, zdsrecord.Hello(entries))
+	assert.NoError(t, err)
+	defer zt.Close()
+	replayErr := make(chan error)
+	go func() {
+		replayErr <- zt.Replay(entries)
+	}()
+	waitForZtunnelConnection(t, srv.ztunServer)
+	assert.NoError(t, srv.ztunServer.PodAdded(ctx, newPod("c"), ns))
+	assert.NoError(t, srv.ztunServer.PodDeleted(ctx, "uid-a"))
+	assert.NoError(t, <-replayErr)
+}
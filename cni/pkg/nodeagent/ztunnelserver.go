@@ -29,6 +29,8 @@ import (
 	"golang.org/x/time/rate"
 	v1 "k8s.io/api/core/v1"
 
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
+	"istio.io/istio/cni/pkg/zdsrecord"
 	"istio.io/istio/pkg/monitoring"
 	"istio.io/istio/pkg/zdsapi"
 )
@@ -42,6 +44,8 @@ type ZtunnelServer interface {
 	Run(ctx context.Context)
 	PodDeleted(ctx context.Context, uid string) error
 	PodAdded(ctx context.Context, pod *v1.Pod, netns Netns) error
+	// Connections returns the connected ztunnels, and the connection which acknowledged each pod, by pod UID.
+	Connections() ([]debugstate.ZtunnelConnection, map[string]string)
 	Close() error
 }
 
@@ -59,7 +63,11 @@ To clean up stale ztunnels
 
 type connMgr struct {
 	connectionSet []ZtunnelConnection
-	mu            sync.Mutex
+	// connections describes each connection of connectionSet, by UUID
+	connections map[uuid.UUID]*debugstate.ZtunnelConnection
+	// podAcks holds the UUID of the connection which acknowledged each pod, by pod UID
+	podAcks map[string]uuid.UUID
+	mu      sync.Mutex
 }
 
 func (c *connMgr) addConn(conn ZtunnelConnection) {
@@ -67,6 +75,10 @@ func (c *connMgr) addConn(conn ZtunnelConnection) {
 	defer c.mu.Unlock()
 	log := log.WithLabels("conn_uuid", conn.UUID())
 	c.connectionSet = append(c.connectionSet, conn)
+	if c.connections == nil {
+		c.connections = map[uuid.UUID]*debugstate.ZtunnelConnection{}
+	}
+	c.connections[conn.UUID()] = &debugstate.ZtunnelConnection{UUID: conn.UUID().String(), ConnectedAt: time.Now()}
 	log.Infof("new ztunnel connected, total connected: %v", len(c.connectionSet))
 	ztunnelConnected.RecordInt(int64(len(c.connectionSet)))
 }
@@ -99,10 +111,62 @@ func (c *connMgr) deleteConn(conn ZtunnelConnection) {
 		}
 	}
 	c.connectionSet = retainedConns
+	delete(c.connections, conn.UUID())
+	for uid, acked := range c.podAcks {
+		if acked == conn.UUID() {
+			delete(c.podAcks, uid)
+		}
+	}
 	log.Infof("ztunnel disconnected, total connected %s", len(c.connectionSet))
 	ztunnelConnected.RecordInt(int64(len(c.connectionSet)))
 }
 
+// setVersion records the version ztunnel sent in its hello message.
+func (c *connMgr) setVersion(conn ZtunnelConnection, version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info, ok := c.connections[conn.UUID()]; ok {
+		info.Version = version
+	}
+}
+
+// ackPod records that the pod was acknowledged by the connection.
+func (c *connMgr) ackPod(uid string, conn ZtunnelConnection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.podAcks == nil {
+		c.podAcks = map[string]uuid.UUID{}
+	}
+	c.podAcks[uid] = conn.UUID()
+}
+
+// forgetPod removes the acknowledgement of a deleted pod.
+func (c *connMgr) forgetPod(uid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.podAcks, uid)
+}
+
+// info returns a description of each connection, oldest first, and the connection which acknowledged each pod.
+func (c *connMgr) info() ([]debugstate.ZtunnelConnection, map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conns := make([]debugstate.ZtunnelConnection, 0, len(c.connectionSet))
+	for i, conn := range c.connectionSet {
+		info := debugstate.ZtunnelConnection{UUID: conn.UUID().String()}
+		if known, ok := c.connections[conn.UUID()]; ok {
+			info = *known
+		}
+		info.Latest = i == len(c.connectionSet)-1
+		conns = append(conns, info)
+	}
+	acks := make(map[string]string, len(c.podAcks))
+	for uid, conn := range c.podAcks {
+		acks[uid] = conn.String()
+	}
+	return conns, acks
+}
+
 // this is used in tests
 // nolint: unused
 func (c *connMgr) len() int {
@@ -120,11 +184,16 @@ type ztunnelServer struct {
 	conns             *connMgr
 	pods              PodNetnsCache
 	keepaliveInterval time.Duration
+	// recorder records the messages exchanged with ztunnel, if set
+	recorder *zdsrecord.Recorder
 }
 
 var _ ZtunnelServer = &ztunnelServer{}
 
 func (z *ztunnelServer) Close() error {
+	if z.recorder != nil {
+		_ = z.recorder.Close()
+	}
 	return z.listener.Close()
 }
 
@@ -152,6 +221,9 @@ func (z *ztunnelServer) Run(ctx context.Context) {
 			continue
 		}
 		log.Debug("connection accepted")
+		if z.recorder != nil {
+			conn = &recordingConnection{ZtunnelConnection: conn, recorder: z.recorder}
+		}
 		go func() {
 			log := log.WithLabels("conn_uuid", conn.UUID())
 			log.Debug("handling conn")
@@ -182,6 +254,7 @@ func (z *ztunnelServer) handleConn(ctx context.Context, conn ZtunnelConnection)
 	}
 
 	log.WithLabels("version", m.Version).Infof("received hello from ztunnel")
+	z.conns.setVersion(conn, m.Version.String())
 	log.Debug("sending snapshot to ztunnel")
 	if err := z.sendSnapshot(ctx, conn); err != nil {
 		return err
@@ -300,6 +373,8 @@ func (z *ztunnelServer) sendSnapshot(_ context.Context, conn ZtunnelConnection)
 		}
 		if resp.GetAck().GetError() != "" {
 			log.Errorf("add-workload: got ack error: %s", resp.GetAck().GetError())
+		} else {
+			z.conns.ackPod(uid, conn)
 		}
 	}
 	resp, err := conn.SendMsgAndWaitForAck(&zdsapi.WorkloadRequest{
@@ -341,6 +416,10 @@ type ZtunnelConnection interface {
 	Done() chan struct{}
 }
 
+func (z *ztunnelServer) Connections() ([]debugstate.ZtunnelConnection, map[string]string) {
+	return z.conns.info()
+}
+
 func (c *connMgr) snapshotConns() []ZtunnelConnection {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -366,6 +445,7 @@ func (z *ztunnelServer) PodDeleted(ctx context.Context, uid string) error {
 	}
 
 	log.Debugf("sending delete pod to all ztunnels: %s %v", uid, r)
+	z.conns.forgetPod(uid)
 
 	var delErr []error
 
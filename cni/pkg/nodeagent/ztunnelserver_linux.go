@@ -287,5 +287,6 @@ func (z *ztunnelServer) PodAdded(ctx context.Context, pod *v1.Pod, netns Netns)
 		log.Errorf("failed to add workload: %s", resp.GetAck().GetError())
 		return fmt.Errorf("got ack error: %s", resp.GetAck().GetError())
 	}
+	z.conns.ackPod(uid, latestConn)
 	return nil
 }
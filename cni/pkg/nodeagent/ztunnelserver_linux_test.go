@@ -561,6 +561,9 @@ func TestZtunnelRemovePod(t *testing.T) {
 	sendAck(ztunClient)
 
 	assert.NoError(t, <-errChan)
+	// the removed pod should no longer be acknowledged by any connection
+	_, acks := ztunnelServer.Connections()
+	assert.Equal(t, len(acks), 0)
 
 	ztunClient.Close()
 	// this will retry for a bit, so shouldn't flake
@@ -656,6 +659,16 @@ func TestZtunnelPodAdded(t *testing.T) {
 
 	assert.NoError(t, <-errChan)
 
+	// both the snapshotted pod and the new pod should be acknowledged by the connection
+	conns, acks := ztunnelServer.Connections()
+	assert.Equal(t, len(conns), 1)
+	assert.Equal(t, conns[0].Version, zdsapi.Version_V1.String())
+	assert.Equal(t, conns[0].Latest, true)
+	assert.Equal(t, acks, map[string]string{
+		fixture.uid:      conns[0].UUID,
+		string(pod2.UID): conns[0].UUID,
+	})
+
 	ztunClient.Close()
 	// this will retry for a bit, so shouldn't flake
 	mt.Assert(ztunnelConnected.Name(), nil, monitortest.Exactly(0))
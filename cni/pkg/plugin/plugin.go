@@ -133,7 +133,10 @@ func GetLoggingOptions(cfg *Config) *log.Options {
 		}
 
 		// Also tee to a rolling log on the node's local filesystem, in case the UDS server is down.
-		loggingOptions.WithTeeToRollingLocal(filepath.Join(cfg.CNIAgentRunDir, constants.LocalRollingLogName), constants.RollingLogMaxSizeMB)
+		// Without a run dir the log would be written to the working directory of the caller, so skip it.
+		if cfg.CNIAgentRunDir != "" {
+			loggingOptions.WithTeeToRollingLocal(filepath.Join(cfg.CNIAgentRunDir, constants.LocalRollingLogName), constants.RollingLogMaxSizeMB)
+		}
 
 		// Override plugin log level based on their config. Note we use "all" (OverrideScopeName) since there is no scoping in the plugin.
 		if cfg.PluginLogLevel != "" {
@@ -176,6 +176,10 @@ func (mrdir *mockInterceptRuleMgr) Program(podName, netns string, redirect *Redi
 	return nil
 }
 
+func (mrdir *mockInterceptRuleMgr) Cleanup(podName, netns string, redirect *Redirect) error {
+	return nil
+}
+
 // returns the test server URL and a dispose func for the test server
 func setupCNIEventClientWithMockServer(serverErr bool) func() bool {
 	cniAddServerCalled := false
@@ -18,6 +18,8 @@ package plugin
 // redirecting traffic to an Istio proxy.
 type InterceptRuleMgr interface {
 	Program(podName, netns string, redirect *Redirect) error
+	// Cleanup removes the rules programmed for the redirect, e.g. when moving a running pod to another backend.
+	Cleanup(podName, netns string, redirect *Redirect) error
 }
 
 // Constructor for iptables InterceptRuleMgr
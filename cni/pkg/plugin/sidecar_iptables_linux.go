@@ -33,7 +33,17 @@ var getNs = ns.GetNS
 // Program defines a method which programs iptables based on the parameters
 // provided in Redirect.
 func (ipt *iptables) Program(podName, netns string, rdrct *Redirect) error {
+	return ipt.run(podName, netns, rdrct, false)
+}
+
+// Cleanup removes the iptables rules programmed for the Redirect.
+func (ipt *iptables) Cleanup(podName, netns string, rdrct *Redirect) error {
+	return ipt.run(podName, netns, rdrct, true)
+}
+
+func (ipt *iptables) run(podName, netns string, rdrct *Redirect, cleanup bool) error {
 	cfg := config.DefaultConfig()
+	cfg.CleanupOnly = cleanup
 	cfg.HostFilesystemPodNetwork = true
 	cfg.NetworkNamespace = netns
 	cfg.ProxyPort = rdrct.targetPort
@@ -28,3 +28,8 @@ var ErrNotImplemented = errors.New("not implemented")
 func (ipt *iptables) Program(podName, netns string, rdrct *Redirect) error {
 	return ErrNotImplemented
 }
+
+// Cleanup removes the iptables rules programmed for the Redirect.
+func (ipt *iptables) Cleanup(podName, netns string, rdrct *Redirect) error {
+	return ErrNotImplemented
+}
@@ -29,7 +29,17 @@ import (
 // Program defines a method which programs nftables based on the parameters
 // provided in Redirect.
 func (n *nftables) Program(podName, netns string, rdrct *Redirect) error {
+	return n.run(podName, netns, rdrct, false)
+}
+
+// Cleanup removes the nftables rules programmed for the Redirect.
+func (n *nftables) Cleanup(podName, netns string, rdrct *Redirect) error {
+	return n.run(podName, netns, rdrct, true)
+}
+
+func (n *nftables) run(podName, netns string, rdrct *Redirect, cleanup bool) error {
 	cfg := config.DefaultConfig()
+	cfg.CleanupOnly = cleanup
 	cfg.HostFilesystemPodNetwork = true
 	cfg.NetworkNamespace = netns
 	cfg.ProxyPort = rdrct.targetPort
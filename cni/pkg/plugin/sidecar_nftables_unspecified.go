@@ -23,3 +23,8 @@ package plugin
 func (nft *nftables) Program(podName, netns string, rdrct *Redirect) error {
 	return ErrNotImplemented
 }
+
+// Cleanup removes the nftables rules programmed for the Redirect.
+func (nft *nftables) Cleanup(podName, netns string, rdrct *Redirect) error {
+	return ErrNotImplemented
+}
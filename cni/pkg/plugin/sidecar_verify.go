@@ -0,0 +1,59 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"net/netip"
+	"strings"
+)
+
+var (
+	// Documentation addresses (RFC 5737 and RFC 3849) are never routed, so a connection to them only succeeds
+	// when it is redirected to the proxy.
+	verifyProbeAddrV4 = netip.MustParseAddr("192.0.2.1")
+	verifyProbeAddrV6 = netip.MustParseAddr("2001:db8::1")
+)
+
+// verifyProbeAddr picks the destination of the connectivity check: an address of the given family that is captured
+// by the outbound rules of the Redirect. It returns false if no outbound traffic of that family is captured.
+func (rdrct *Redirect) verifyProbeAddr(ipv6 bool) (netip.Addr, bool) {
+	for _, cidr := range strings.Split(rdrct.includeIPCidrs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "*" {
+			if ipv6 {
+				return verifyProbeAddrV6, true
+			}
+			return verifyProbeAddrV4, true
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || prefix.Addr().Is6() != ipv6 {
+			continue
+		}
+		prefix = prefix.Masked()
+		if addr := prefix.Addr().Next(); prefix.Contains(addr) && !rdrct.excludesAddr(addr) {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func (rdrct *Redirect) excludesAddr(addr netip.Addr) bool {
+	for _, cidr := range strings.Split(rdrct.excludeIPCidrs, ",") {
+		if prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr)); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
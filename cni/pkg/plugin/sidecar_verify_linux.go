@@ -0,0 +1,55 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+// VerifyRedirection checks that the rules in effect in the network namespace of a running pod redirect its outbound
+// traffic to the proxy. Like the istio-validation init container, it connects to a destination that is not reachable
+// unless redirected, here on the probe port: the connection only succeeds when the proxy accepts it.
+//
+// It returns false without an error when the Redirect captures no outbound traffic of the pod's IP family,
+// as there is then nothing to check.
+func VerifyRedirection(netns string, rdrct *Redirect, podIP netip.Addr, timeout time.Duration) (bool, error) {
+	dst, ok := rdrct.verifyProbeAddr(podIP.Is6())
+	if !ok {
+		return false, nil
+	}
+	netNs, err := getNs(netns)
+	if err != nil {
+		return false, fmt.Errorf("failed to open netns %q: %s", netns, err)
+	}
+	defer netNs.Close()
+
+	addr := net.JoinHostPort(dst.String(), strconv.Itoa(constants.DefaultIptablesProbePortUint))
+	err = netNs.Do(func(_ ns.NetNS) error {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return fmt.Errorf("connection to %v was not redirected to the proxy: %v", addr, err)
+		}
+		return conn.Close()
+	})
+	return err == nil, err
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"net/netip"
+	"time"
+)
+
+// VerifyRedirection checks that the rules in effect in the network namespace of a running pod redirect its outbound
+// traffic to the proxy.
+func VerifyRedirection(netns string, rdrct *Redirect, podIP netip.Addr, timeout time.Duration) (bool, error) {
+	return false, ErrNotImplemented
+}
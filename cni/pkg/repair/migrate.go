@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repair
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/plugin"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	ReasonMigrateRules = "MigrateRedirectionRules"
+
+	backendIptables = "iptables"
+	backendNftables = "nftables"
+
+	// migrationVerifyTimeout bounds the connectivity check run once a pod uses the new rules.
+	migrationVerifyTimeout = 5 * time.Second
+)
+
+// migrationSteps are the operations a rules migration performs on a pod, replaced in tests.
+type migrationSteps struct {
+	podNetNs func(pod *corev1.Pod) (string, error)
+	ruleMgr  func(backend string) plugin.InterceptRuleMgr
+	verify   func(netns string, redirect *plugin.Redirect, podIP netip.Addr) (bool, error)
+}
+
+func ruleMgrForBackend(backend string) plugin.InterceptRuleMgr {
+	if backend == backendNftables {
+		return plugin.NftablesInterceptRuleMgr()
+	}
+	return plugin.IptablesInterceptRuleMgr()
+}
+
+func validateRulesMigration(backend string) error {
+	switch backend {
+	case "", backendIptables, backendNftables:
+		return nil
+	}
+	return fmt.Errorf("invalid rules migration backend %q: expected %s or %s", backend, backendIptables, backendNftables)
+}
+
+func parseMigrationNamespaces(namespaces string) sets.String {
+	res := sets.New[string]()
+	for _, ns := range strings.Split(namespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			res.Insert(ns)
+		}
+	}
+	return res
+}
+
+// shouldMigrate returns true if the pod is a healthy, running sidecar pod selected for the rules migration.
+func (c *Controller) shouldMigrate(pod *corev1.Pod) bool {
+	if c.cfg.RulesMigration == "" {
+		return false
+	}
+	if c.cfg.SidecarAnnotation != "" {
+		if _, ok := pod.Annotations[c.cfg.SidecarAnnotation]; !ok {
+			return false
+		}
+	}
+	if c.migrationNamespaces.Len() > 0 && !c.migrationNamespaces.Contains(pod.Namespace) {
+		return false
+	}
+	if pod.Spec.HostNetwork || pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+		return false
+	}
+	// The connectivity check needs the proxy to accept the redirected connection.
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, s := range statuses {
+			if s.Name == plugin.ISTIOPROXY {
+				return s.Ready
+			}
+		}
+	}
+	return false
+}
+
+// migratePod moves a running pod from the rules of one backend to the other: it programs the new rules, removes the
+// old ones, and checks that outbound traffic is still redirected to the proxy. If any step fails, the pod is rolled
+// back to the old rules. Each pod is migrated once; pods rolled back are not retried until the node agent restarts.
+func (c *Controller) migratePod(pod *corev1.Pod) error {
+	key := types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}
+	if uid, f := c.migratedPods[key]; f && uid == pod.UID {
+		return nil
+	}
+	from, to := backendIptables, backendNftables
+	if c.cfg.RulesMigration == backendIptables {
+		from, to = backendNftables, backendIptables
+	}
+	m := podsMigrated.With(backendLabel.Value(to))
+	log := repairLog.WithLabels("pod", pod.Namespace+"/"+pod.Name, "from", from, "to", to)
+
+	if c.cfg.MigrationDryRun {
+		log.Infof("dry-run: pod would be migrated")
+		c.events.Write(pod, corev1.EventTypeNormal, ReasonMigrateRules,
+			"dry-run: traffic redirection rules would be migrated from %s to %s", from, to)
+		m.With(resultLabel.Value(resultDryRun)).Increment()
+		c.migratedPods[key] = pod.UID
+		return nil
+	}
+
+	log.Infof("migrating pod...")
+	redirect, err := plugin.NewRedirect(plugin.ExtractPodInfo(pod))
+	if err != nil {
+		m.With(resultLabel.Value(resultFail)).Increment()
+		return fmt.Errorf("setup redirect: %v", err)
+	}
+	podIP, err := netip.ParseAddr(pod.Status.PodIP)
+	if err != nil {
+		m.With(resultLabel.Value(resultFail)).Increment()
+		return fmt.Errorf("parse pod IP: %v", err)
+	}
+	netns, err := c.migration.podNetNs(pod)
+	if err != nil {
+		m.With(resultLabel.Value(resultFail)).Increment()
+		return fmt.Errorf("get netns: %v", err)
+	}
+	log = log.WithLabels("netns", netns)
+	oldRules, newRules := c.migration.ruleMgr(from), c.migration.ruleMgr(to)
+
+	if err := newRules.Program(pod.Name, netns, redirect); err != nil {
+		// The old rules are untouched, only undo whatever part of the new rules was applied, and retry later.
+		if cerr := newRules.Cleanup(pod.Name, netns, redirect); cerr != nil {
+			log.Warnf("failed to clean up %s rules: %v", to, cerr)
+		}
+		m.With(resultLabel.Value(resultFail)).Increment()
+		return fmt.Errorf("program %s rules: %v", to, err)
+	}
+	if err := oldRules.Cleanup(pod.Name, netns, redirect); err != nil {
+		return c.rollbackMigration(pod, netns, redirect, from, to, fmt.Errorf("remove %s rules: %v", from, err))
+	}
+	verified, err := c.migration.verify(netns, redirect, podIP)
+	if err != nil {
+		return c.rollbackMigration(pod, netns, redirect, from, to, fmt.Errorf("verify %s rules: %v", to, err))
+	}
+	if !verified {
+		log.Warnf("pod captures no outbound traffic, skipped the connectivity check")
+	}
+
+	c.migratedPods[key] = pod.UID
+	log.Infof("pod migrated")
+	c.events.Write(pod, corev1.EventTypeNormal, ReasonMigrateRules, "traffic redirection rules migrated from %s to %s", from, to)
+	m.With(resultLabel.Value(resultSuccess)).Increment()
+	return nil
+}
+
+// rollbackMigration restores the rules of the backend a pod is migrated from, after the migration failed with cause.
+func (c *Controller) rollbackMigration(pod *corev1.Pod, netns string, redirect *plugin.Redirect, from, to string, cause error) error {
+	key := types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}
+	m := podsMigrated.With(backendLabel.Value(to))
+	log := repairLog.WithLabels("pod", pod.Namespace+"/"+pod.Name, "from", from, "to", to, "netns", netns)
+	log.Errorf("migration failed, rolling back: %v", cause)
+
+	if err := c.migration.ruleMgr(from).Program(pod.Name, netns, redirect); err != nil {
+		// Keep the new rules in place, they are better than no rules at all.
+		c.events.Write(pod, corev1.EventTypeWarning, ReasonMigrateRules,
+			"migration of traffic redirection rules from %s to %s failed (%v), and so did the rollback: %v", from, to, cause, err)
+		m.With(resultLabel.Value(resultFail)).Increment()
+		return fmt.Errorf("rollback to %s rules: %v", from, err)
+	}
+	if err := c.migration.ruleMgr(to).Cleanup(pod.Name, netns, redirect); err != nil {
+		log.Warnf("failed to clean up %s rules after rollback: %v", to, err)
+	}
+	c.migratedPods[key] = pod.UID
+	c.events.Write(pod, corev1.EventTypeWarning, ReasonMigrateRules,
+		"migration of traffic redirection rules from %s to %s failed and was rolled back: %v", from, to, cause)
+	m.With(resultLabel.Value(resultRollback)).Increment()
+	return nil
+}
@@ -0,0 +1,34 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repair
+
+import (
+	"net/netip"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/cni/pkg/plugin"
+)
+
+var defaultMigrationSteps = migrationSteps{
+	podNetNs: func(pod *corev1.Pod) (string, error) {
+		// As for repairs, the netns must be looked up from the host network namespace.
+		return runInHost(func() (string, error) { return getPodNetNs(pod) })
+	},
+	ruleMgr: ruleMgrForBackend,
+	verify: func(netns string, redirect *plugin.Redirect, podIP netip.Addr) (bool, error) {
+		return plugin.VerifyRedirection(netns, redirect, podIP, migrationVerifyTimeout)
+	},
+}
@@ -0,0 +1,209 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repair
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/cni/pkg/plugin"
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// fakeRuleMgr records the operations applied to a pod, as "<backend> program" or "<backend> cleanup".
+type fakeRuleMgr struct {
+	backend string
+	ops     *[]string
+	fail    map[string]bool
+}
+
+func (f fakeRuleMgr) Program(podName, netns string, redirect *plugin.Redirect) error {
+	return f.record("program")
+}
+
+func (f fakeRuleMgr) Cleanup(podName, netns string, redirect *plugin.Redirect) error {
+	return f.record("cleanup")
+}
+
+func (f fakeRuleMgr) record(op string) error {
+	op = f.backend + " " + op
+	*f.ops = append(*f.ops, op)
+	if f.fail[op] {
+		return fmt.Errorf("%s failed", op)
+	}
+	return nil
+}
+
+func makeSidecarPod(name, namespace string, proxyReady bool) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			UID:         types.UID("uid-" + name),
+			Annotations: map[string]string{"sidecar.istio.io/status": "something"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app"}, {Name: plugin.ISTIOPROXY}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: "10.0.0.1",
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", Ready: true},
+				{Name: plugin.ISTIOPROXY, Ready: proxyReady},
+			},
+		},
+	}
+}
+
+func TestShouldMigrate(t *testing.T) {
+	hostNetwork := makeSidecarPod("host", "default", true)
+	hostNetwork.Spec.HostNetwork = true
+	pending := makeSidecarPod("pending", "default", true)
+	pending.Status.Phase = corev1.PodPending
+	noSidecar := makeSidecarPod("nosidecar", "default", true)
+	noSidecar.Annotations = nil
+
+	cases := []struct {
+		name   string
+		config config.RepairConfig
+		pod    *corev1.Pod
+		want   bool
+	}{
+		{"migration disabled", config.RepairConfig{}, makeSidecarPod("pod", "default", true), false},
+		{"ready sidecar pod", config.RepairConfig{RulesMigration: "nftables"}, makeSidecarPod("pod", "default", true), true},
+		{"proxy not ready", config.RepairConfig{RulesMigration: "nftables"}, makeSidecarPod("pod", "default", false), false},
+		{"host network", config.RepairConfig{RulesMigration: "nftables"}, hostNetwork, false},
+		{"not running", config.RepairConfig{RulesMigration: "nftables"}, pending, false},
+		{
+			"no sidecar",
+			config.RepairConfig{RulesMigration: "nftables", SidecarAnnotation: "sidecar.istio.io/status"},
+			noSidecar,
+			false,
+		},
+		{
+			"namespace selected",
+			config.RepairConfig{RulesMigration: "nftables", MigrationNamespaces: "foo, default"},
+			makeSidecarPod("pod", "default", true),
+			true,
+		},
+		{
+			"namespace not selected",
+			config.RepairConfig{RulesMigration: "nftables", MigrationNamespaces: "foo,bar"},
+			makeSidecarPod("pod", "default", true),
+			false,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{cfg: tt.config, migrationNamespaces: parseMigrationNamespaces(tt.config.MigrationNamespaces)}
+			assert.Equal(t, c.shouldMigrate(tt.pod), tt.want)
+		})
+	}
+}
+
+func TestMigratePod(t *testing.T) {
+	cases := []struct {
+		name      string
+		config    config.RepairConfig
+		fail      map[string]bool
+		verifyErr error
+		wantErr   bool
+		wantOps   []string
+		wantTags  map[string]string
+	}{
+		{
+			name:     "migrate to nftables",
+			config:   config.RepairConfig{RulesMigration: "nftables"},
+			wantOps:  []string{"nftables program", "iptables cleanup"},
+			wantTags: map[string]string{"backend": "nftables", "result": resultSuccess},
+		},
+		{
+			name:     "roll back to iptables",
+			config:   config.RepairConfig{RulesMigration: "iptables"},
+			wantOps:  []string{"iptables program", "nftables cleanup"},
+			wantTags: map[string]string{"backend": "iptables", "result": resultSuccess},
+		},
+		{
+			name:     "dry-run",
+			config:   config.RepairConfig{RulesMigration: "nftables", MigrationDryRun: true},
+			wantTags: map[string]string{"backend": "nftables", "result": resultDryRun},
+		},
+		{
+			name:      "verification failure rolls back",
+			config:    config.RepairConfig{RulesMigration: "nftables"},
+			verifyErr: fmt.Errorf("connection timed out"),
+			wantOps:   []string{"nftables program", "iptables cleanup", "iptables program", "nftables cleanup"},
+			wantTags:  map[string]string{"backend": "nftables", "result": resultRollback},
+		},
+		{
+			name:     "program failure is retried",
+			config:   config.RepairConfig{RulesMigration: "nftables"},
+			fail:     map[string]bool{"nftables program": true},
+			wantErr:  true,
+			wantOps:  []string{"nftables program", "nftables cleanup"},
+			wantTags: map[string]string{"backend": "nftables", "result": resultFail},
+		},
+		{
+			name:     "failed rollback",
+			config:   config.RepairConfig{RulesMigration: "nftables"},
+			fail:     map[string]bool{"iptables cleanup": true, "iptables program": true},
+			wantErr:  true,
+			wantOps:  []string{"nftables program", "iptables cleanup", "iptables program"},
+			wantTags: map[string]string{"backend": "nftables", "result": resultFail},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := monitortest.New(t)
+			pod := makeSidecarPod("pod", "default", true)
+			c, err := NewRepairController(fakeClient(pod), tt.config)
+			assert.NoError(t, err)
+
+			var ops []string
+			c.migration = migrationSteps{
+				podNetNs: func(*corev1.Pod) (string, error) { return "/var/run/netns/pod", nil },
+				ruleMgr: func(backend string) plugin.InterceptRuleMgr {
+					return fakeRuleMgr{backend: backend, ops: &ops, fail: tt.fail}
+				},
+				verify: func(string, *plugin.Redirect, netip.Addr) (bool, error) { return tt.verifyErr == nil, tt.verifyErr },
+			}
+
+			err = c.ReconcilePod(pod)
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, ops, tt.wantOps)
+			mt.Assert(podsMigrated.Name(), tt.wantTags, monitortest.Exactly(1))
+
+			// Pods are only migrated once, failures aside which are retried.
+			if !tt.wantErr {
+				ops = nil
+				assert.NoError(t, c.ReconcilePod(pod))
+				assert.Equal(t, len(ops), 0)
+			}
+		})
+	}
+}
+
+func TestInvalidRulesMigration(t *testing.T) {
+	_, err := NewRepairController(fakeClient(), config.RepairConfig{RulesMigration: "ebpf"})
+	assert.Error(t, err)
+}
@@ -29,8 +29,19 @@ var (
 	resultSkip    = "skip"
 	resultFail    = "fail"
 
+	// Results specific to the rules migration.
+	resultRollback = "rollback"
+	resultDryRun   = "dryrun"
+
+	backendLabel = monitoring.CreateLabel("backend")
+
 	podsRepaired = monitoring.NewSum(
 		"istio_cni_repair_pods_repaired_total",
 		"Total number of pods repaired by repair controller",
 	)
+
+	podsMigrated = monitoring.NewSum(
+		"istio_cni_repair_pods_migrated_total",
+		"Total number of pods whose redirection rules were migrated to another backend by repair controller",
+	)
 )
@@ -311,7 +311,29 @@ func TestLabelPods(t *testing.T) {
 	}
 }
 
+// makeFamilyFailedPod returns a broken pod with the given addresses, whose validation failed with the message.
+func makeFamilyFailedPod(message string, podIPs ...string) *corev1.Pod {
+	pod := makePod(makePodArgs{
+		PodName:     "family-failed",
+		Annotations: map[string]string{"sidecar.istio.io/status": "something"},
+		InitContainerStatus: &corev1.ContainerStatus{
+			Name: constants.ValidationContainerName,
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Message:  message,
+					ExitCode: constants.ValidationErrorCode,
+				},
+			},
+		},
+	})
+	for _, ip := range podIPs {
+		pod.Status.PodIPs = append(pod.Status.PodIPs, corev1.PodIP{IP: ip})
+	}
+	return pod
+}
+
 func TestDeletePods(t *testing.T) {
+	ipv6FailedInIPv4Pod := makeFamilyFailedPod("redirection validation failed for: IPv6; passed: IPv4", "10.0.0.1")
 	tests := []struct {
 		name      string
 		client    kube.Client
@@ -346,6 +368,19 @@ func TestDeletePods(t *testing.T) {
 			wantCount: 1,
 			wantTags:  map[string]string{"result": resultSuccess, "type": deleteType},
 		},
+		{
+			name:   "Broken pod failing only for a family it has no address of",
+			client: fakeClient(workingPod, ipv6FailedInIPv4Pod),
+			config: config.RepairConfig{
+				SidecarAnnotation: "sidecar.istio.io/status",
+				InitContainerName: constants.ValidationContainerName,
+				InitExitCode:      constants.ValidationErrorCode,
+			},
+			wantPods:  []*corev1.Pod{ipv6FailedInIPv4Pod, workingPod},
+			wantErr:   false,
+			wantCount: 1,
+			wantTags:  map[string]string{"result": resultSkip, "type": deleteType},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -374,3 +409,71 @@ func TestDeletePods(t *testing.T) {
 		})
 	}
 }
+
+func TestBrokenDetail(t *testing.T) {
+	makeFailedPod := func(message string) *corev1.Pod {
+		return makePod(makePodArgs{
+			PodName:     "pod",
+			Annotations: map[string]string{"sidecar.istio.io/status": "something"},
+			InitContainerStatus: &corev1.ContainerStatus{
+				Name: constants.ValidationContainerName,
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						Message:  message,
+						ExitCode: constants.ValidationErrorCode,
+					},
+				},
+			},
+		})
+	}
+	c := &Controller{cfg: config.RepairConfig{
+		SidecarAnnotation: "sidecar.istio.io/status",
+		InitContainerName: constants.ValidationContainerName,
+		InitExitCode:      constants.ValidationErrorCode,
+	}}
+
+	ipv6Failed := makeFailedPod("redirection validation failed for: IPv6; passed: IPv4")
+	assert.Equal(t, c.matchesFilter(ipv6Failed), true)
+	assert.Equal(t, c.brokenDetail(ipv6Failed), " (redirection validation failed for IPv6)")
+
+	unknown := makeFailedPod("")
+	assert.Equal(t, c.matchesFilter(unknown), true)
+	assert.Equal(t, c.brokenDetail(unknown), "")
+}
+
+func TestOnlyUnconfiguredFamiliesFailed(t *testing.T) {
+	c := &Controller{cfg: config.RepairConfig{InitContainerName: constants.ValidationContainerName}}
+	cases := []struct {
+		name    string
+		pod     *corev1.Pod
+		skipped bool
+	}{
+		{
+			name:    "IPv6 failed in IPv4 pod",
+			pod:     makeFamilyFailedPod("redirection validation failed for: IPv6; passed: IPv4", "10.0.0.1"),
+			skipped: true,
+		},
+		{
+			name: "IPv6 failed in dual-stack pod",
+			pod:  makeFamilyFailedPod("redirection validation failed for: IPv6; passed: IPv4", "10.0.0.1", "fd00::1"),
+		},
+		{
+			name: "both failed in IPv6 pod",
+			pod:  makeFamilyFailedPod("redirection validation failed for: IPv4, IPv6", "fd00::1"),
+		},
+		{
+			name: "addresses not known",
+			pod:  makeFamilyFailedPod("redirection validation failed for: IPv6; passed: IPv4"),
+		},
+		{
+			name: "families not reported",
+			pod:  makeFamilyFailedPod("", "10.0.0.1"),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, skipped := c.onlyUnconfiguredFamiliesFailed(tc.pod)
+			assert.Equal(t, skipped, tc.skipped)
+		})
+	}
+}
@@ -17,6 +17,7 @@ package repair
 import (
 	"context"
 	"fmt"
+	"net/netip"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +29,8 @@ import (
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/controllers"
 	"istio.io/istio/pkg/kube/kclient"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/tools/istio-iptables/pkg/validation"
 )
 
 type Controller struct {
@@ -37,14 +40,25 @@ type Controller struct {
 	cfg          config.RepairConfig
 	events       kclient.EventRecorder
 	repairedPods map[types.NamespacedName]types.UID
+
+	// Rules migration of running sidecar pods, see migratePod.
+	migration           migrationSteps
+	migrationNamespaces sets.String
+	migratedPods        map[types.NamespacedName]types.UID
 }
 
 func NewRepairController(client kube.Client, cfg config.RepairConfig) (*Controller, error) {
+	if err := validateRulesMigration(cfg.RulesMigration); err != nil {
+		return nil, err
+	}
 	c := &Controller{
-		cfg:          cfg,
-		client:       client,
-		events:       kclient.NewEventRecorder(client, "cni-repair"),
-		repairedPods: map[types.NamespacedName]types.UID{},
+		cfg:                 cfg,
+		client:              client,
+		events:              kclient.NewEventRecorder(client, "cni-repair"),
+		repairedPods:        map[types.NamespacedName]types.UID{},
+		migration:           defaultMigrationSteps,
+		migrationNamespaces: parseMigrationNamespaces(cfg.MigrationNamespaces),
+		migratedPods:        map[types.NamespacedName]types.UID{},
 	}
 	fieldSelectors := []string{}
 	if cfg.FieldSelectors != "" {
@@ -74,6 +88,7 @@ func (c *Controller) Reconcile(key types.NamespacedName) error {
 	pod := c.pods.Get(key.Name, key.Namespace)
 	if pod == nil {
 		delete(c.repairedPods, key) // Ensure we do not leak
+		delete(c.migratedPods, key)
 		// Pod deleted, nothing to do
 		return nil
 	}
@@ -82,10 +97,23 @@ func (c *Controller) Reconcile(key types.NamespacedName) error {
 
 func (c *Controller) ReconcilePod(pod *corev1.Pod) (err error) {
 	if !c.matchesFilter(pod) {
+		if c.shouldMigrate(pod) {
+			return c.migratePod(pod)
+		}
 		return err // Skip, pod doesn't need repair
 	}
 	repairLog.Debugf("Reconciling pod %s", pod.Name)
 
+	if failed, ok := c.onlyUnconfiguredFamiliesFailed(pod); ok {
+		repairLog.Warnf("Pod %s/%s failed redirection validation only for %s, which it has no address of, skipping",
+			pod.Namespace, pod.Name, validation.FormatFamilies(failed))
+		c.events.Write(pod, corev1.EventTypeWarning, ReasonSkipBrokenPod,
+			"redirection validation failed only for %s, which the pod has no address of; the pod was left alone",
+			validation.FormatFamilies(failed))
+		podsRepaired.With(typeLabel.Value(c.repairType()), resultLabel.Value(resultSkip)).Increment()
+		return nil
+	}
+
 	if c.cfg.RepairPods {
 		return c.repairPod(pod)
 	} else if c.cfg.DeletePods {
@@ -128,11 +156,24 @@ func redirectRunningPodNFT(pod *corev1.Pod, netns string) error {
 const (
 	ReasonDeleteBrokenPod = "DeleteBrokenPod"
 	ReasonLabelBrokenPod  = "LabelBrokenPod"
+	ReasonSkipBrokenPod   = "SkipBrokenPod"
 )
 
+// repairType returns the type of repair done on broken pods, for the metrics.
+func (c *Controller) repairType() string {
+	switch {
+	case c.cfg.RepairPods:
+		return repairType
+	case c.cfg.DeletePods:
+		return deleteType
+	default:
+		return labelType
+	}
+}
+
 func (c *Controller) deleteBrokenPod(pod *corev1.Pod) error {
 	m := podsRepaired.With(typeLabel.Value(deleteType))
-	repairLog.Infof("Pod detected as broken, deleting: %s/%s", pod.Namespace, pod.Name)
+	repairLog.Infof("Pod detected as broken%s, deleting: %s/%s", c.brokenDetail(pod), pod.Namespace, pod.Name)
 
 	// Make sure we are deleting what we think we are...
 	preconditions := &metav1.Preconditions{
@@ -143,11 +184,11 @@ func (c *Controller) deleteBrokenPod(pod *corev1.Pod) error {
 		Preconditions: preconditions,
 	})
 	if err != nil {
-		c.events.Write(pod, corev1.EventTypeWarning, ReasonDeleteBrokenPod, "pod detected as broken, but failed to delete: %v", err)
+		c.events.Write(pod, corev1.EventTypeWarning, ReasonDeleteBrokenPod, "pod detected as broken%s, but failed to delete: %v", c.brokenDetail(pod), err)
 		m.With(resultLabel.Value(resultFail)).Increment()
 		return err
 	}
-	c.events.Write(pod, corev1.EventTypeWarning, ReasonDeleteBrokenPod, "pod detected as broken, deleted")
+	c.events.Write(pod, corev1.EventTypeWarning, ReasonDeleteBrokenPod, "pod detected as broken%s, deleted", c.brokenDetail(pod))
 	m.With(resultLabel.Value(resultSuccess)).Increment()
 	return nil
 }
@@ -155,7 +196,7 @@ func (c *Controller) deleteBrokenPod(pod *corev1.Pod) error {
 func (c *Controller) labelBrokenPod(pod *corev1.Pod) error {
 	// Added for safety, to make sure no healthy pods get labeled.
 	m := podsRepaired.With(typeLabel.Value(labelType))
-	repairLog.Infof("Pod detected as broken, adding label: %s/%s", pod.Namespace, pod.Name)
+	repairLog.Infof("Pod detected as broken%s, adding label: %s/%s", c.brokenDetail(pod), pod.Namespace, pod.Name)
 
 	labels := pod.GetLabels()
 	if _, ok := labels[c.cfg.LabelKey]; ok {
@@ -172,11 +213,11 @@ func (c *Controller) labelBrokenPod(pod *corev1.Pod) error {
 		[]byte(patchBytes), metav1.PatchOptions{}, "status")
 	if err != nil {
 		repairLog.Errorf("Failed to update pod: %s", err)
-		c.events.Write(pod, corev1.EventTypeWarning, ReasonLabelBrokenPod, "pod detected as broken, but failed to label: %v", err)
+		c.events.Write(pod, corev1.EventTypeWarning, ReasonLabelBrokenPod, "pod detected as broken%s, but failed to label: %v", c.brokenDetail(pod), err)
 		m.With(resultLabel.Value(resultFail)).Increment()
 		return err
 	}
-	c.events.Write(pod, corev1.EventTypeWarning, ReasonLabelBrokenPod, "pod detected as broken, labeled")
+	c.events.Write(pod, corev1.EventTypeWarning, ReasonLabelBrokenPod, "pod detected as broken%s, labeled", c.brokenDetail(pod))
 	m.With(resultLabel.Value(resultSuccess)).Increment()
 	return nil
 }
@@ -237,3 +278,58 @@ func (c *Controller) matchesFilter(pod *corev1.Pod) bool {
 	}
 	return false
 }
+
+// failedFamilies returns the IP families the validation init container of a broken pod reported as failed,
+// or nil if the termination message of the container does not tell.
+func (c *Controller) failedFamilies(pod *corev1.Pod) []validation.Family {
+	for _, container := range pod.Status.InitContainerStatuses {
+		if c.cfg.InitContainerName != "" && container.Name != c.cfg.InitContainerName {
+			continue
+		}
+		if state := container.LastTerminationState.Terminated; state != nil {
+			if families := validation.ParseFailedFamilies(state.Message); len(families) > 0 {
+				return families
+			}
+		}
+	}
+	return nil
+}
+
+// onlyUnconfiguredFamiliesFailed returns the IP families the validation init container of a broken pod reported as
+// failed, and whether the pod has no address of any of them. Such failures, e.g. the IPv6 validation of a proxy
+// configured for dual-stack in an IPv4-only pod, are not caused by missing redirection rules, so repairing, deleting
+// or labeling the pod would not fix them. Pods whose addresses are not known yet are never skipped.
+func (c *Controller) onlyUnconfiguredFamiliesFailed(pod *corev1.Pod) ([]validation.Family, bool) {
+	failed := c.failedFamilies(pod)
+	if len(failed) == 0 || len(pod.Status.PodIPs) == 0 {
+		return failed, false
+	}
+	configured := sets.New[validation.Family]()
+	for _, ip := range pod.Status.PodIPs {
+		addr, err := netip.ParseAddr(ip.IP)
+		switch {
+		case err != nil:
+			// Not expected, do not risk skipping a broken pod.
+			return failed, false
+		case addr.Is4():
+			configured.Insert(validation.IPv4)
+		default:
+			configured.Insert(validation.IPv6)
+		}
+	}
+	for _, family := range failed {
+		if configured.Contains(family) {
+			return failed, false
+		}
+	}
+	return failed, true
+}
+
+// brokenDetail describes why a pod is broken, to be appended to "pod detected as broken".
+func (c *Controller) brokenDetail(pod *corev1.Pod) string {
+	families := c.failedFamilies(pod)
+	if len(families) == 0 {
+		return ""
+	}
+	return " (redirection validation failed for " + validation.FormatFamilies(families) + ")"
+}
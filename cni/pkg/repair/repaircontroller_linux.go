@@ -19,6 +19,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/tools/istio-iptables/pkg/validation"
 )
 
 // repairPod actually dynamically repairs a pod. This is done by entering the pods network namespace and setting up rules.
@@ -45,6 +47,9 @@ func (c *Controller) repairPod(pod *corev1.Pod) error {
 		}
 		return nil
 	}
+	if families := c.failedFamilies(pod); len(families) > 0 {
+		log = log.WithLabels("failedFamilies", validation.FormatFamilies(families))
+	}
 	log.Infof("Repairing pod...")
 
 	// Fetch the pod's network namespace. This must run in the host process due to how the procfs /ns/net works.
@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trafficmanager
+
+import (
+	"errors"
+	"fmt"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/cni/pkg/ebpf"
+	"istio.io/istio/cni/pkg/iptables"
+	"istio.io/istio/cni/pkg/scopes"
+	istiolog "istio.io/istio/pkg/log"
+)
+
+// EbpfTrafficManager implements TrafficRuleManager for pods with eBPF programs attached in the pod network
+// namespace. Host rules are still managed with netfilter.
+type EbpfTrafficManager struct {
+	podCfg *config.AmbientConfig
+	nlDeps iptables.NetlinkDependencies
+}
+
+var _ TrafficRuleManager = &EbpfTrafficManager{}
+
+var log = scopes.CNIAgent
+
+// NewEbpfTrafficManager creates the host netfilter traffic manager and the pod eBPF traffic manager. If the kernel
+// cannot run the eBPF programs, the nftables traffic managers are returned instead.
+func NewEbpfTrafficManager(cfg *TrafficRuleManagerConfig) (hostManager, podManager TrafficRuleManager, err error) {
+	if err := ebpf.CheckSupport(); err != nil {
+		log.Warnf("eBPF traffic redirection is not supported on this node, falling back to nftables rules: %v", err)
+		return NewNftablesTrafficManager(cfg)
+	}
+	newNetfilterManager := NewIptablesTrafficManager
+	if cfg.NativeNftables {
+		newNetfilterManager = NewNftablesTrafficManager
+	}
+	hostManager, _, err = newNetfilterManager(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	podManager = &EbpfTrafficManager{
+		podCfg: cfg.PodConfig,
+		nlDeps: cfg.NlDeps,
+	}
+	return hostManager, podManager, nil
+}
+
+// CreateInpodRules attaches the redirection programs within a pod's network namespace
+func (m *EbpfTrafficManager) CreateInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) error {
+	log.Info("eBPF redirection enabled, attaching programs for inpod traffic redirection")
+
+	if err := ebpf.Attach(m.programConfig(podOverrides), podOverrides.VirtualInterfaces); err != nil {
+		return err
+	}
+
+	// The programs rely on the same mark based routing as the netfilter rules.
+	if err := m.nlDeps.AddLoopbackRoutes(m.podCfg); err != nil {
+		return err
+	}
+	if err := m.nlDeps.AddInpodMarkIPRule(m.podCfg); err != nil {
+		return err
+	}
+
+	log.Info("eBPF inpod programs attached")
+	return nil
+}
+
+// DeleteInpodRules detaches the redirection programs from a pod's network namespace
+func (m *EbpfTrafficManager) DeleteInpodRules(log *istiolog.Scope) error {
+	log.Info("detaching eBPF inpod programs")
+	return errors.Join(ebpf.Detach(), m.nlDeps.DelInpodMarkIPRule(m.podCfg), m.nlDeps.DelLoopbackRoutes(m.podCfg))
+}
+
+// VerifyInpodRules reports whether the programs attached within a pod's network namespace have drifted
+func (m *EbpfTrafficManager) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	return ebpf.Verify(m.programConfig(podOverrides), podOverrides.VirtualInterfaces)
+}
+
+func (m *EbpfTrafficManager) programConfig(podOverrides config.PodLevelOverrides) ebpf.Config {
+	redirectDNS := m.podCfg.RedirectDNS
+	switch podOverrides.DNSProxy {
+	case config.PodDNSEnabled:
+		redirectDNS = true
+	case config.PodDNSDisabled:
+		redirectDNS = false
+	}
+	return ebpf.Config{
+		EnableIPv6:             m.podCfg.EnableIPv6,
+		RedirectDNS:            redirectDNS,
+		IngressMode:            podOverrides.IngressMode,
+		HostProbeSNATAddress:   m.podCfg.HostProbeSNATAddress,
+		HostProbeV6SNATAddress: m.podCfg.HostProbeV6SNATAddress,
+
+		ExcludeInboundPorts:     podOverrides.ExcludeInboundPorts,
+		ExcludeOutboundPorts:    podOverrides.ExcludeOutboundPorts,
+		ExcludeOutboundIPRanges: podOverrides.ExcludeOutboundIPRanges,
+		ExcludeOutboundUIDs:     podOverrides.ExcludeOutboundUIDs,
+	}
+}
+
+// CreateHostRulesForHealthChecks is not supported, host rules are managed by the netfilter host manager
+func (m *EbpfTrafficManager) CreateHostRulesForHealthChecks() error {
+	return fmt.Errorf("host rules are not managed by the eBPF traffic manager (this is a pod-only traffic manager)")
+}
+
+// DeleteHostRules is a no-op, host rules are managed by the netfilter host manager
+func (m *EbpfTrafficManager) DeleteHostRules() {}
+
+// ListHostRules is not supported, host rules are managed by the netfilter host manager
+func (m *EbpfTrafficManager) ListHostRules() ([]string, error) {
+	return nil, fmt.Errorf("host rules are not managed by the eBPF traffic manager (this is a pod-only traffic manager)")
+}
+
+// ReconcileModeEnabled returns true if reconciliation mode is enabled
+func (m *EbpfTrafficManager) ReconcileModeEnabled() bool {
+	return m.podCfg.Reconcile
+}
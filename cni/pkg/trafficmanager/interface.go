@@ -26,8 +26,13 @@ import (
 type TrafficRuleManager interface {
 	CreateInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) error
 	DeleteInpodRules(log *istiolog.Scope) error
+	// VerifyInpodRules reports whether the live rules within a pod's network namespace have drifted from the
+	// rules CreateInpodRules would program.
+	VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error)
 	CreateHostRulesForHealthChecks() error
 	DeleteHostRules()
+	// ListHostRules returns the host-level rules currently in place, for debugging.
+	ListHostRules() ([]string, error)
 	ReconcileModeEnabled() bool
 }
 
@@ -35,6 +40,9 @@ type TrafficRuleManagerConfig struct {
 	// Use native nftables instead of iptables
 	NativeNftables bool
 
+	// Use eBPF programs instead of netfilter rules for in-pod traffic redirection
+	EbpfRedirect bool
+
 	// Host-level configuration
 	HostConfig *config.AmbientConfig
 
@@ -50,6 +58,9 @@ type TrafficRuleManagerConfig struct {
 
 // NewTrafficRuleManager creates both host and pod traffic rule managers based on configuration
 func NewTrafficRuleManager(cfg *TrafficRuleManagerConfig) (hostManager, podManager TrafficRuleManager, err error) {
+	if cfg.EbpfRedirect {
+		return NewEbpfTrafficManager(cfg)
+	}
 	if cfg.NativeNftables {
 		return NewNftablesTrafficManager(cfg)
 	}
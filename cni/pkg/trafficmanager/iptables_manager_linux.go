@@ -89,6 +89,14 @@ func (m *IptablesTrafficManager) DeleteInpodRules(log *istiolog.Scope) error {
 	return m.podIptables.DeleteInpodRules(log)
 }
 
+// VerifyInpodRules checks the iptables rules within a pod's network namespace for drift
+func (m *IptablesTrafficManager) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	if m.podIptables == nil {
+		return false, fmt.Errorf("pod iptables configurator not available (this is likely a host-only traffic manager)")
+	}
+	return m.podIptables.VerifyInpodRules(log, podOverrides)
+}
+
 // CreateHostRulesForHealthChecks creates host-level iptables rules for health check handling
 func (m *IptablesTrafficManager) CreateHostRulesForHealthChecks() error {
 	if m.hostIptables == nil {
@@ -97,6 +105,14 @@ func (m *IptablesTrafficManager) CreateHostRulesForHealthChecks() error {
 	return m.hostIptables.CreateHostRulesForHealthChecks()
 }
 
+// ListHostRules returns the host-level iptables rules currently in place
+func (m *IptablesTrafficManager) ListHostRules() ([]string, error) {
+	if m.hostIptables == nil {
+		return nil, fmt.Errorf("host iptables configurator not available (this is likely a pod-only traffic manager)")
+	}
+	return m.hostIptables.ListHostRules()
+}
+
 // DeleteHostRules removes host-level iptables rules
 func (m *IptablesTrafficManager) DeleteHostRules() {
 	if m.hostIptables != nil {
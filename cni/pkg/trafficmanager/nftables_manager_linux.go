@@ -72,6 +72,14 @@ func (m *NftablesTrafficManager) DeleteInpodRules(log *istiolog.Scope) error {
 	return m.podNftables.DeleteInpodRules(log)
 }
 
+// VerifyInpodRules checks the nftables rules within a pod's network namespace for drift
+func (m *NftablesTrafficManager) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	if m.podNftables == nil {
+		return false, fmt.Errorf("pod nftables configurator not available (this is likely a host-only traffic manager)")
+	}
+	return m.podNftables.VerifyInpodRules(log, podOverrides)
+}
+
 // CreateHostRulesForHealthChecks creates host-level nftables rules for health check handling
 func (m *NftablesTrafficManager) CreateHostRulesForHealthChecks() error {
 	if m.hostNftables == nil {
@@ -80,6 +88,14 @@ func (m *NftablesTrafficManager) CreateHostRulesForHealthChecks() error {
 	return m.hostNftables.CreateHostRulesForHealthChecks()
 }
 
+// ListHostRules returns the host-level nftables rules currently in place
+func (m *NftablesTrafficManager) ListHostRules() ([]string, error) {
+	if m.hostNftables == nil {
+		return nil, fmt.Errorf("host nftables configurator not available (this is likely a pod-only traffic manager)")
+	}
+	return m.hostNftables.ListHostRules()
+}
+
 // DeleteHostRules removes host-level nftables rules
 func (m *NftablesTrafficManager) DeleteHostRules() {
 	if m.hostNftables != nil {
@@ -0,0 +1,244 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zdsrecord records the ZDS message stream between the node agent and ztunnel, and replays it with a fake
+// ztunnel, so node agent issues can be reproduced and regression tested without a ztunnel binary.
+//
+// A recording is a file with one JSON entry per line. Each entry is either the hello message a ztunnel sent when it
+// connected, or a request the node agent sent along with the response it got. Protobuf messages are encoded with
+// protojson.
+package zdsrecord
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	lj "gopkg.in/natefinch/lumberjack.v2"
+
+	"istio.io/istio/pkg/zdsapi"
+)
+
+// Entry is a recorded ZDS message.
+type Entry struct {
+	Time time.Time
+	// Conn identifies the ztunnel connection the message was exchanged on.
+	Conn string
+	// Hello is set if the entry is the hello message sent by ztunnel when it connected.
+	Hello *zdsapi.ZdsHello
+	// Request is the request sent by the node agent.
+	Request *zdsapi.WorkloadRequest
+	// Fd is set if a file descriptor (the pod netns) was sent along with the request.
+	Fd bool
+	// Response is the response sent by ztunnel, if any.
+	Response *zdsapi.WorkloadResponse
+	// Error is set if no valid response was received.
+	Error string
+}
+
+type entryJSON struct {
+	Time     time.Time       `json:"time"`
+	Conn     string          `json:"conn"`
+	Hello    json.RawMessage `json:"hello,omitempty"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Fd       bool            `json:"fd,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+func (e Entry) MarshalJSON() ([]byte, error) {
+	out := entryJSON{Time: e.Time, Conn: e.Conn, Fd: e.Fd, Error: e.Error}
+	var err error
+	if out.Hello, err = marshalMessage(e.Hello); err != nil {
+		return nil, err
+	}
+	if out.Request, err = marshalMessage(e.Request); err != nil {
+		return nil, err
+	}
+	if out.Response, err = marshalMessage(e.Response); err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var in entryJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*e = Entry{Time: in.Time, Conn: in.Conn, Fd: in.Fd, Error: in.Error}
+	if in.Hello != nil {
+		e.Hello = &zdsapi.ZdsHello{}
+		if err := protojson.Unmarshal(in.Hello, e.Hello); err != nil {
+			return fmt.Errorf("invalid hello: %v", err)
+		}
+	}
+	if in.Request != nil {
+		e.Request = &zdsapi.WorkloadRequest{}
+		if err := protojson.Unmarshal(in.Request, e.Request); err != nil {
+			return fmt.Errorf("invalid request: %v", err)
+		}
+	}
+	if in.Response != nil {
+		e.Response = &zdsapi.WorkloadResponse{}
+		if err := protojson.Unmarshal(in.Response, e.Response); err != nil {
+			return fmt.Errorf("invalid response: %v", err)
+		}
+	}
+	return nil
+}
+
+func marshalMessage[T interface {
+	proto.Message
+	comparable
+}](m T) (json.RawMessage, error) {
+	var zero T
+	if m == zero {
+		return nil, nil
+	}
+	return protojson.Marshal(m)
+}
+
+// Recorder writes recording entries. It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewRecorder returns a recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+const (
+	// maxFileSizeMB is the size, in megabytes, after which the recording file is rotated.
+	maxFileSizeMB = 100
+	// maxFileBackups is the number of rotated recording files kept.
+	maxFileBackups = 2
+)
+
+// NewFileRecorder returns a recorder appending to the file at path, which is created if needed. The file is rotated
+// once it reaches maxFileSizeMB, keeping maxFileBackups rotated files named with the time of the rotation, so that
+// recording does not fill the disk of the node.
+func NewFileRecorder(path string) (*Recorder, error) {
+	f := &lj.Logger{
+		Filename:   path,
+		MaxSize:    maxFileSizeMB,
+		MaxBackups: maxFileBackups,
+	}
+	// lumberjack opens the file on the first write, open it now to report errors early
+	if _, err := f.Write(nil); err != nil {
+		return nil, fmt.Errorf("failed to open ZDS recording file: %v", err)
+	}
+	return &Recorder{w: f, closer: f}, nil
+}
+
+// RecordHello records the hello message sent by ztunnel on the connection.
+func (r *Recorder) RecordHello(conn string, hello *zdsapi.ZdsHello) error {
+	return r.write(Entry{Time: time.Now(), Conn: conn, Hello: hello})
+}
+
+// RecordExchange records a request sent to ztunnel on the connection, and the response (or error) it resulted in.
+func (r *Recorder) RecordExchange(conn string, req *zdsapi.WorkloadRequest, fd bool, resp *zdsapi.WorkloadResponse, err error) error {
+	e := Entry{Time: time.Now(), Conn: conn, Request: req, Fd: fd, Response: resp}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return r.write(e)
+}
+
+func (r *Recorder) write(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying file, if the recorder was created with NewFileRecorder.
+func (r *Recorder) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Read reads all the entries of a recording.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// ReadFile reads all the entries of the recording file at path.
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// ByConnection splits the entries of a recording by connection, in the order the connections were first seen.
+func ByConnection(entries []Entry) [][]Entry {
+	var conns [][]Entry
+	index := map[string]int{}
+	for _, e := range entries {
+		i, ok := index[e.Conn]
+		if !ok {
+			i = len(conns)
+			index[e.Conn] = i
+			conns = append(conns, nil)
+		}
+		conns[i] = append(conns[i], e)
+	}
+	return conns
+}
+
+// Hello returns the first hello message of the entries, or nil if there is none.
+func Hello(entries []Entry) *zdsapi.ZdsHello {
+	for _, e := range entries {
+		if e.Hello != nil {
+			return e.Hello
+		}
+	}
+	return nil
+}
+
+// Ack returns an ack response, with the given error if not empty.
+func Ack(err string) *zdsapi.WorkloadResponse {
+	return &zdsapi.WorkloadResponse{Payload: &zdsapi.WorkloadResponse_Ack{Ack: &zdsapi.Ack{Error: err}}}
+}
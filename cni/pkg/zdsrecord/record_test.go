@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zdsrecord
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/zdsapi"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	add := &zdsapi.WorkloadRequest{Payload: &zdsapi.WorkloadRequest_Add{Add: &zdsapi.AddWorkload{
+		Uid:          "uid-1",
+		WorkloadInfo: &zdsapi.WorkloadInfo{Name: "name", Namespace: "ns", ServiceAccount: "sa"},
+	}}}
+	del := &zdsapi.WorkloadRequest{Payload: &zdsapi.WorkloadRequest_Del{Del: &zdsapi.DelWorkload{Uid: "uid-1"}}}
+
+	assert.NoError(t, r.RecordHello("conn-1", &zdsapi.ZdsHello{Version: zdsapi.Version_V1}))
+	assert.NoError(t, r.RecordExchange("conn-1", add, true, Ack(""), nil))
+	assert.NoError(t, r.RecordHello("conn-2", &zdsapi.ZdsHello{Version: zdsapi.Version_V1}))
+	assert.NoError(t, r.RecordExchange("conn-1", del, false, nil, errors.New("timed out")))
+	assert.NoError(t, r.RecordExchange("conn-2", del, false, Ack("unknown workload"), nil))
+	assert.NoError(t, r.Close())
+
+	entries, err := Read(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(entries), 5)
+	assert.Equal(t, Hello(entries).GetVersion(), zdsapi.Version_V1)
+
+	conns := ByConnection(entries)
+	assert.Equal(t, len(conns), 2)
+	assert.Equal(t, len(conns[0]), 3)
+	assert.Equal(t, len(conns[1]), 2)
+
+	first := conns[0][1]
+	assert.Equal(t, proto.Equal(first.Request, add), true)
+	assert.Equal(t, first.Fd, true)
+	assert.Equal(t, proto.Equal(first.Response, Ack("")), true)
+
+	timedOut := conns[0][2]
+	assert.Equal(t, proto.Equal(timedOut.Request, del), true)
+	assert.Equal(t, timedOut.Response == nil, true)
+	assert.Equal(t, timedOut.Error, "timed out")
+
+	assert.Equal(t, conns[1][1].Response.GetAck().GetError(), "unknown workload")
+}
+
+func TestFileRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zds.jsonl")
+	for _, conn := range []string{"conn-1", "conn-2"} {
+		r, err := NewFileRecorder(path)
+		assert.NoError(t, err)
+		assert.NoError(t, r.RecordHello(conn, &zdsapi.ZdsHello{Version: zdsapi.Version_V1}))
+		assert.NoError(t, r.Close())
+	}
+
+	// the second recorder appends to the file
+	entries, err := ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, len(ByConnection(entries)), 2)
+
+	// the parent of the file is not a directory
+	_, err = NewFileRecorder(filepath.Join(path, "zds.jsonl"))
+	assert.Error(t, err)
+}
+
+func TestReadInvalid(t *testing.T) {
+	_, err := Read(bytes.NewBufferString(`{"conn":"conn-1","request":{"unknown":{}}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zdsrecord
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pkg/zdsapi"
+)
+
+// receiveTimeout is how long the fake ztunnel waits for a request from the node agent.
+const receiveTimeout = 5 * time.Second
+
+// FakeZtunnel speaks the ztunnel side of the ZDS protocol over the node agent socket, without doing anything with
+// the pods it is sent.
+type FakeZtunnel struct {
+	conn *net.UnixConn
+}
+
+// Dial connects to the node agent ZDS socket at addr, and sends the hello message. A V1 hello is sent if hello is nil.
+func Dial(addr string, hello *zdsapi.ZdsHello) (*FakeZtunnel, error) {
+	resolvedAddr, err := net.ResolveUnixAddr("unixpacket", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUnix("unixpacket", nil, resolvedAddr)
+	if err != nil {
+		return nil, err
+	}
+	if hello == nil {
+		hello = &zdsapi.ZdsHello{Version: zdsapi.Version_V1}
+	}
+	z := &FakeZtunnel{conn: conn}
+	if err := z.write(hello); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send hello: %v", err)
+	}
+	return z, nil
+}
+
+// Receive waits for the next request from the node agent. It returns whether a file descriptor was sent along with
+// it; the file descriptor itself is closed.
+func (z *FakeZtunnel) Receive() (*zdsapi.WorkloadRequest, bool, error) {
+	var buf [4096]byte
+	var oob [1024]byte
+	if err := z.conn.SetReadDeadline(time.Now().Add(receiveTimeout)); err != nil {
+		return nil, false, err
+	}
+	n, oobn, flags, _, err := z.conn.ReadMsgUnix(buf[:], oob[:])
+	if err != nil {
+		return nil, false, err
+	}
+	if flags&(unix.MSG_TRUNC|unix.MSG_CTRUNC) != 0 {
+		return nil, false, fmt.Errorf("truncated message")
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, false, err
+	}
+	fd := false
+	for _, msg := range msgs {
+		fds, err := unix.ParseUnixRights(&msg)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, f := range fds {
+			fd = true
+			_ = unix.Close(f)
+		}
+	}
+	req := &zdsapi.WorkloadRequest{}
+	if err := proto.Unmarshal(buf[:n], req); err != nil {
+		return nil, false, err
+	}
+	return req, fd, nil
+}
+
+// Respond sends a response to the last request received.
+func (z *FakeZtunnel) Respond(resp *zdsapi.WorkloadResponse) error {
+	return z.write(resp)
+}
+
+// Replay validates the requests received from the node agent against the requests of a recorded connection, and
+// sends the recorded responses. Requests for which no response was recorded are not responded to. Hello entries are
+// ignored; use Hello to dial with the recorded hello.
+//
+// The order of the workloads sent in the initial snapshot is not deterministic, so the requests preceding the first
+// SnapshotSent request are matched in any order.
+func (z *FakeZtunnel) Replay(entries []Entry) error {
+	var expected []Entry
+	snapshotEnd := 0
+	for _, e := range entries {
+		if e.Request == nil {
+			continue
+		}
+		if snapshotEnd == 0 && e.Request.GetSnapshotSent() != nil {
+			snapshotEnd = len(expected)
+		}
+		expected = append(expected, e)
+	}
+
+	pending := expected[:snapshotEnd]
+	for i := range expected {
+		req, fd, err := z.Receive()
+		if err != nil {
+			return fmt.Errorf("request %d: %v", i, err)
+		}
+		var match *Entry
+		if i < snapshotEnd {
+			for j, e := range pending {
+				if proto.Equal(req, e.Request) && fd == e.Fd {
+					match = &pending[j]
+					pending = append(pending[:j:j], pending[j+1:]...)
+					break
+				}
+			}
+			if match == nil {
+				return fmt.Errorf("request %d: got unexpected snapshot request %v (fd: %v)", i, req, fd)
+			}
+		} else {
+			match = &expected[i]
+			if !proto.Equal(req, match.Request) || fd != match.Fd {
+				return fmt.Errorf("request %d: got %v (fd: %v), expected %v (fd: %v)", i, req, fd, match.Request, match.Fd)
+			}
+		}
+		if match.Response == nil {
+			continue
+		}
+		if err := z.Respond(match.Response); err != nil {
+			return fmt.Errorf("request %d: failed to respond: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the node agent.
+func (z *FakeZtunnel) Close() error {
+	return z.conn.Close()
+}
+
+func (z *FakeZtunnel) write(m proto.Message) error {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := z.conn.SetWriteDeadline(time.Now().Add(receiveTimeout)); err != nil {
+		return err
+	}
+	_, err = z.conn.Write(data)
+	return err
+}
@@ -24,8 +24,10 @@ import (
 	"github.com/spf13/viper"
 
 	"istio.io/istio/istioctl/pkg/admin"
+	"istio.io/istio/istioctl/pkg/ambient"
 	"istio.io/istio/istioctl/pkg/analyze"
 	"istio.io/istio/istioctl/pkg/authz"
+	"istio.io/istio/istioctl/pkg/ca"
 	"istio.io/istio/istioctl/pkg/checkinject"
 	"istio.io/istio/istioctl/pkg/cli"
 	"istio.io/istio/istioctl/pkg/completion"
@@ -45,6 +47,7 @@ import (
 	"istio.io/istio/istioctl/pkg/util"
 	"istio.io/istio/istioctl/pkg/validate"
 	"istio.io/istio/istioctl/pkg/version"
+	"istio.io/istio/istioctl/pkg/wasm"
 	"istio.io/istio/istioctl/pkg/waypoint"
 	"istio.io/istio/istioctl/pkg/workload"
 	"istio.io/istio/istioctl/pkg/ztunnelconfig"
@@ -199,6 +202,9 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(precheck.Cmd(ctx))
 	experimentalCmd.AddCommand(proxyconfig.StatsConfigCmd(ctx))
 	experimentalCmd.AddCommand(checkinject.Cmd(ctx))
+	experimentalCmd.AddCommand(ca.Cmd(ctx))
+	experimentalCmd.AddCommand(ambient.Cmd(ctx))
+	experimentalCmd.AddCommand(wasm.Cmd(ctx))
 	rootCmd.AddCommand(waypoint.Cmd(ctx))
 	rootCmd.AddCommand(ztunnelconfig.ZtunnelConfig(ctx))
 
@@ -0,0 +1,31 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ambient
+
+import (
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/cli"
+)
+
+// Cmd returns the "istioctl x ambient" command.
+func Cmd(ctx cli.Context) *cobra.Command {
+	ambientCmd := &cobra.Command{
+		Use:   "ambient",
+		Short: "Commands to troubleshoot the ambient data plane",
+	}
+	ambientCmd.AddCommand(diagnoseCmd(ctx))
+	return ambientCmd
+}
@@ -0,0 +1,418 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ambient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/util/podutils"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	cniconstants "istio.io/istio/cni/pkg/constants"
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
+	cniutil "istio.io/istio/cni/pkg/util"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/istioctl/pkg/writer/table"
+	ztunnelDump "istio.io/istio/istioctl/pkg/writer/ztunnel/configdump"
+	"istio.io/istio/istioctl/pkg/ztunnelconfig"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	cniConfigMap     = "istio-cni-config"
+	cniDaemonSet     = "istio-cni-node"
+	ztunnelDaemonSet = "ztunnel"
+)
+
+const (
+	checkPodRunning         = "Pod is running"
+	checkAmbientEnabled     = "Ambient is enabled in istio-cni"
+	checkPodSelected        = "Pod is selected for ambient"
+	checkNodeAgentReady     = "istio-cni node agent is ready"
+	checkCNIConfig          = "istio-cni plugin is in the node CNI config"
+	checkPodEnrolled        = "Pod is enrolled by the node agent"
+	checkInpodRules         = "In-pod redirection rules have not drifted"
+	checkZtunnelReady       = "ztunnel is ready"
+	checkZtunnelPodState    = "ztunnel received the pod over ZDS"
+	checkZtunnelWorkload    = "ztunnel has the workload from istiod"
+	checkZtunnelCertificate = "ztunnel has a certificate for the workload"
+)
+
+type checkStatus int
+
+const (
+	statusPassed checkStatus = iota
+	statusFailed
+	statusSkipped
+)
+
+type checkResult struct {
+	Name   string
+	Status checkStatus
+	Detail string
+}
+
+type diagnosis struct {
+	results []checkResult
+}
+
+func (d *diagnosis) pass(name, format string, args ...any) {
+	d.results = append(d.results, checkResult{Name: name, Status: statusPassed, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (d *diagnosis) fail(name, format string, args ...any) {
+	d.results = append(d.results, checkResult{Name: name, Status: statusFailed, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (d *diagnosis) skip(reason string, names ...string) {
+	for _, name := range names {
+		d.results = append(d.results, checkResult{Name: name, Status: statusSkipped, Detail: reason})
+	}
+}
+
+func diagnoseCmd(ctx cli.Context) *cobra.Command {
+	var proxyAdminPort int
+	cmd := &cobra.Command{
+		Use:   "diagnose [<type>/]<name>[.<namespace>]",
+		Short: "Check every step of the enrollment of a pod in the ambient data plane",
+		Long: `Check every step of the enrollment of a pod in the ambient data plane, and report the ones that are broken.
+
+The pod and namespace labels are checked against the istio-cni configuration, then the istio-cni node agent on the
+node of the pod is queried for the CNI config of the node, its pod cache and the in-pod redirection rules of the pod,
+and finally the configuration of ztunnel is inspected for the pod, its workload and its certificate.`,
+		Example: `  # Check why a pod is not captured
+  istioctl x ambient diagnose productpage-v1-7c5b7b6d5f-8xkqp.bookinfo
+
+  # Check the pod of a deployment
+  istioctl x ambient diagnose deployment/productpage-v1 -n bookinfo`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("diagnose requires [<type>/]<name>[.<namespace>]")
+			}
+			return util.ValidatePort(proxyAdminPort)
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			podName, podNamespace, err := ctx.InferPodInfoFromTypedResource(args[0], ctx.Namespace())
+			if err != nil {
+				return err
+			}
+			pod, err := kubeClient.Kube().CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			results := diagnose(kubeClient, pod, ctx.IstioNamespace(), proxyAdminPort)
+			return printResults(c.OutOrStdout(), pod, results)
+		},
+		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
+	}
+	cmd.Long += "\n\n" + util.ExperimentalMsg
+	cmd.Flags().IntVar(&proxyAdminPort, "proxy-admin-port", util.DefaultProxyAdminPort, "Ztunnel proxy admin port")
+	return cmd
+}
+
+// diagnose runs the checks in the order in which a pod is enrolled. Checks which cannot run because one they depend on
+// failed are reported as skipped.
+func diagnose(client kube.CLIClient, pod *corev1.Pod, istioNamespace string, proxyAdminPort int) []checkResult {
+	d := &diagnosis{}
+
+	node := pod.Spec.NodeName
+	if node == "" || pod.Status.Phase != corev1.PodRunning {
+		if node == "" {
+			d.fail(checkPodRunning, "pod is not scheduled to a node")
+		} else {
+			d.fail(checkPodRunning, "pod is %s on node %s", pod.Status.Phase, node)
+		}
+		d.skip("pod is not running", checkAmbientEnabled, checkPodSelected, checkNodeAgentReady, checkCNIConfig,
+			checkPodEnrolled, checkInpodRules, checkZtunnelReady, checkZtunnelPodState, checkZtunnelWorkload,
+			checkZtunnelCertificate)
+		return d.results
+	}
+	d.pass(checkPodRunning, "pod is running on node %s", node)
+
+	cm, err := client.Kube().CoreV1().ConfigMaps(istioNamespace).Get(context.TODO(), cniConfigMap, metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		d.fail(checkAmbientEnabled, "ConfigMap %s/%s not found, istio-cni is not installed", istioNamespace, cniConfigMap)
+		d.skip("istio-cni configuration not found", checkPodSelected)
+	case err != nil:
+		d.fail(checkAmbientEnabled, "failed to get ConfigMap %s/%s: %v", istioNamespace, cniConfigMap, err)
+		d.skip("istio-cni configuration not found", checkPodSelected)
+	default:
+		if enabled, _ := strconv.ParseBool(cm.Data["AMBIENT_ENABLED"]); enabled {
+			d.pass(checkAmbientEnabled, "AMBIENT_ENABLED is true in ConfigMap %s/%s", istioNamespace, cniConfigMap)
+		} else {
+			d.fail(checkAmbientEnabled, "AMBIENT_ENABLED is %q in ConfigMap %s/%s, install istio-cni with ambient.enabled=true",
+				cm.Data["AMBIENT_ENABLED"], istioNamespace, cniConfigMap)
+		}
+		checkSelected(d, client, pod, cm)
+	}
+
+	if state, cniPod := nodeAgentState(d, client, pod, istioNamespace); state != nil {
+		checkNodeAgentState(d, pod, state, cniPod)
+	} else {
+		d.skip("istio-cni node agent state not available", checkCNIConfig, checkPodEnrolled, checkInpodRules)
+	}
+
+	dump, ztunnelPod := ztunnelConfigDump(d, client, node, istioNamespace, proxyAdminPort)
+	if dump == nil {
+		d.skip("ztunnel configuration not available", checkZtunnelPodState, checkZtunnelWorkload, checkZtunnelCertificate)
+		return d.results
+	}
+
+	if state, ok := dump.WorkloadState[string(pod.UID)]; !ok {
+		d.fail(checkZtunnelPodState, "%s does not know pod UID %s, the node agent has not sent it over ZDS", ztunnelPod, pod.UID)
+	} else if state.State != "Up" {
+		d.fail(checkZtunnelPodState, "pod state in %s is %q", ztunnelPod, state.State)
+	} else {
+		d.pass(checkZtunnelPodState, "pod state in %s is Up", ztunnelPod)
+	}
+
+	idx := slices.IndexFunc(dump.Workloads, func(w *ztunnelDump.ZtunnelWorkload) bool {
+		return w.Name == pod.Name && w.Namespace == pod.Namespace
+	})
+	if idx < 0 {
+		d.fail(checkZtunnelWorkload, "%s has no workload for the pod, check that istiod is running and connected", ztunnelPod)
+		d.skip("workload not found in ztunnel", checkZtunnelCertificate)
+		return d.results
+	}
+	workload := dump.Workloads[idx]
+	switch {
+	case workload.Protocol != "HBONE":
+		d.fail(checkZtunnelWorkload, "workload protocol is %s, istiod does not consider the pod to be captured", workload.Protocol)
+	case workload.Status != "Healthy":
+		d.fail(checkZtunnelWorkload, "workload status is %s", workload.Status)
+	default:
+		d.pass(checkZtunnelWorkload, "workload %s is Healthy and uses HBONE", workload.UID)
+	}
+
+	trustDomain := workload.TrustDomain
+	if trustDomain == "" {
+		trustDomain = constants.DefaultClusterLocalDomain
+	}
+	identity := spiffe.Identity{TrustDomain: trustDomain, Namespace: workload.Namespace, ServiceAccount: workload.ServiceAccount}.String()
+	idx = slices.IndexFunc(dump.Certificates, func(c *ztunnelDump.CertsDump) bool {
+		return c.Identity == identity
+	})
+	switch {
+	case idx < 0:
+		d.fail(checkZtunnelCertificate, "%s has no certificate for %s", ztunnelPod, identity)
+	case dump.Certificates[idx].State != "Available":
+		d.fail(checkZtunnelCertificate, "certificate for %s is %s", identity, dump.Certificates[idx].State)
+	default:
+		d.pass(checkZtunnelCertificate, "certificate for %s is Available", identity)
+	}
+	return d.results
+}
+
+// checkSelected evaluates the enablement selectors of istio-cni the way the node agent does.
+func checkSelected(d *diagnosis, client kube.CLIClient, pod *corev1.Pod, cm *corev1.ConfigMap) {
+	if slices.Contains(cniutil.SplitExcludeNamespaces(cm.Data["EXCLUDE_NAMESPACES"]), pod.Namespace) {
+		d.fail(checkPodSelected, "namespace %s is in EXCLUDE_NAMESPACES of istio-cni", pod.Namespace)
+		return
+	}
+	var selectors []cniutil.EnablementSelector
+	if err := yaml.Unmarshal([]byte(cm.Data["AMBIENT_ENABLEMENT_SELECTOR"]), &selectors); err != nil {
+		d.fail(checkPodSelected, "failed to parse AMBIENT_ENABLEMENT_SELECTOR: %v", err)
+		return
+	}
+	compiled, err := cniutil.NewCompiledEnablementSelectors(selectors)
+	if err != nil {
+		d.fail(checkPodSelected, "%v", err)
+		return
+	}
+	ns, err := client.Kube().CoreV1().Namespaces().Get(context.TODO(), pod.Namespace, metav1.GetOptions{})
+	if err != nil {
+		d.fail(checkPodSelected, "failed to get namespace %s: %v", pod.Namespace, err)
+		return
+	}
+	if compiled.Matches(pod, ns.Labels) {
+		d.pass(checkPodSelected, "pod and namespace labels match the enablement selectors")
+		return
+	}
+	_, hasSidecar := pod.Annotations[annotation.SidecarStatus.Name]
+	switch {
+	case pod.Spec.HostNetwork:
+		d.fail(checkPodSelected, "host network pods cannot be captured")
+	case hasSidecar:
+		d.fail(checkPodSelected, "pod has a sidecar, which cannot be combined with ambient")
+	case pod.Labels[label.IoIstioDataplaneMode.Name] == constants.DataplaneModeNone:
+		d.fail(checkPodSelected, "pod opted out with label %s=%s", label.IoIstioDataplaneMode.Name, constants.DataplaneModeNone)
+	default:
+		d.fail(checkPodSelected, "neither the pod nor namespace %s match the enablement selectors, label one of them with %s=%s",
+			pod.Namespace, label.IoIstioDataplaneMode.Name, constants.DataplaneModeAmbient)
+	}
+}
+
+// nodeAgentState checks that the istio-cni node agent on the node is ready and returns its debug state, with the
+// in-pod rules of the pod checked.
+func nodeAgentState(d *diagnosis, client kube.CLIClient, pod *corev1.Pod, istioNamespace string) (*debugstate.State, string) {
+	nsn, err := ztunnelconfig.PodOnNodeFromDaemonset(pod.Spec.NodeName, cniDaemonSet, istioNamespace, client)
+	if err != nil {
+		d.fail(checkNodeAgentReady, "no %s pod found on node %s: %v", cniDaemonSet, pod.Spec.NodeName, err)
+		return nil, ""
+	}
+	port, _ := strconv.Atoi(cniconstants.ReadinessPort)
+	path := strings.TrimPrefix(cniconstants.ReadinessEndpoint, "/")
+	if _, err := client.EnvoyDoWithPort(context.TODO(), nsn.Name, nsn.Namespace, "GET", path, port); err != nil {
+		d.fail(checkNodeAgentReady, "%s is not ready, the CNI plugin may not be installed on the node: %v", nsn.Name, err)
+		return nil, nsn.Name
+	}
+	query := url.Values{debugstate.PodQueryParam: []string{pod.Namespace + "/" + pod.Name}}
+	path = strings.TrimPrefix(cniconstants.DebugStateEndpoint, "/") + "?" + query.Encode()
+	out, err := client.EnvoyDoWithPort(context.TODO(), nsn.Name, nsn.Namespace, "GET", path, port)
+	if err != nil {
+		d.fail(checkNodeAgentReady, "%s is ready but its state could not be retrieved: %v", nsn.Name, err)
+		return nil, nsn.Name
+	}
+	state := &debugstate.State{}
+	if err := json.Unmarshal(out, state); err != nil {
+		d.fail(checkNodeAgentReady, "%s is ready but its state could not be parsed: %v", nsn.Name, err)
+		return nil, nsn.Name
+	}
+	d.pass(checkNodeAgentReady, "%s is ready", nsn.Name)
+	return state, nsn.Name
+}
+
+// checkNodeAgentState checks the CNI config of the node, the pod cache and the in-pod rules of the pod, as reported
+// by the node agent.
+func checkNodeAgentState(d *diagnosis, pod *corev1.Pod, state *debugstate.State, cniPod string) {
+	switch {
+	case state.CNIConfig == nil:
+		d.skip("not reported by "+cniPod, checkCNIConfig)
+	case state.CNIConfig.Error != "":
+		d.fail(checkCNIConfig, "%s, the pods created on the node are not redirected", state.CNIConfig.Error)
+	default:
+		d.pass(checkCNIConfig, "istio-cni plugin is in %s", state.CNIConfig.File)
+	}
+
+	cached := slices.FindFunc(state.Pods, func(p debugstate.Pod) bool {
+		return p.UID == string(pod.UID)
+	})
+	switch {
+	case cached == nil:
+		d.fail(checkPodEnrolled, "pod is not in the pod cache of %s, check its logs", cniPod)
+		d.skip("pod is not enrolled", checkInpodRules)
+		return
+	case cached.Status == debugstate.PodStatusNetnsUnknown:
+		d.fail(checkPodEnrolled, "the network namespace of the pod is not known to %s, check its logs", cniPod)
+		d.skip("pod is not enrolled", checkInpodRules)
+		return
+	case cached.Status == debugstate.PodStatusUnacknowledged:
+		d.fail(checkPodEnrolled, "pod was not acknowledged by ztunnel, %d ztunnel(s) connected to %s",
+			len(state.ZtunnelConnections), cniPod)
+	default:
+		d.pass(checkPodEnrolled, "pod was acknowledged by ztunnel connection %s", cached.AckedBy)
+	}
+
+	// The node agent reports the result of its last periodic audit of the in-pod rules.
+	rules := state.InpodRules
+	switch {
+	case rules == nil:
+		d.skip("not reported by "+cniPod, checkInpodRules)
+	case rules.AuditedAt.IsZero():
+		d.skip(fmt.Sprintf("not audited by %s: %s", cniPod, rules.Error), checkInpodRules)
+	case rules.Drifted && rules.Repaired:
+		d.pass(checkInpodRules, "in-pod rules drifted from the expected rules and were repaired by %s at %s",
+			cniPod, rules.AuditedAt.Format(time.RFC3339))
+	case rules.Drifted:
+		d.fail(checkInpodRules, "in-pod rules drifted from the expected rules at %s, traffic may bypass ztunnel; "+
+			"%s reports %s events and repairs the rules if enabled", rules.AuditedAt.Format(time.RFC3339), cniPod,
+			debugstate.ReasonInpodRulesDrift)
+	case rules.Error != "":
+		d.fail(checkInpodRules, "failed to audit the in-pod rules at %s: %s", rules.AuditedAt.Format(time.RFC3339), rules.Error)
+	default:
+		d.pass(checkInpodRules, "in-pod rules matched the expected rules at %s", rules.AuditedAt.Format(time.RFC3339))
+	}
+}
+
+// ztunnelConfigDump checks that the ztunnel on the node is ready and returns its configuration.
+func ztunnelConfigDump(d *diagnosis, client kube.CLIClient, node, istioNamespace string, proxyAdminPort int) (*ztunnelDump.ZtunnelDump, string) {
+	nsn, err := ztunnelconfig.PodOnNodeFromDaemonset(node, ztunnelDaemonSet, istioNamespace, client)
+	if err != nil {
+		d.fail(checkZtunnelReady, "no %s pod found on node %s: %v", ztunnelDaemonSet, node, err)
+		return nil, ""
+	}
+	pod, err := client.Kube().CoreV1().Pods(nsn.Namespace).Get(context.TODO(), nsn.Name, metav1.GetOptions{})
+	if err != nil {
+		d.fail(checkZtunnelReady, "failed to get %s: %v", nsn.Name, err)
+		return nil, ""
+	}
+	if !podutils.IsPodReady(pod) {
+		d.fail(checkZtunnelReady, "%s is not ready", nsn.Name)
+		return nil, ""
+	}
+	dump, err := ztunnelconfig.ConfigDump(client, nsn.Name, nsn.Namespace, proxyAdminPort)
+	if err != nil {
+		d.fail(checkZtunnelReady, "%s is ready but its configuration could not be retrieved: %v", nsn.Name, err)
+		return nil, ""
+	}
+	d.pass(checkZtunnelReady, "%s is ready", nsn.Name)
+	return dump, nsn.Name
+}
+
+func printResults(writer io.Writer, pod *corev1.Pod, results []checkResult) error {
+	w := table.NewStyleWriter(writer)
+	w.SetAddRowFunc(func(obj interface{}) table.Row {
+		r := obj.(checkResult)
+		var status table.Cell
+		switch r.Status {
+		case statusPassed:
+			status = table.NewCell("✔", color.FgGreen)
+		case statusFailed:
+			status = table.NewCell("✘", color.FgRed)
+		default:
+			status = table.NewCell("-")
+		}
+		return table.Row{Cells: []table.Cell{table.NewCell(r.Name), status, table.NewCell(r.Detail)}}
+	})
+	w.AddHeader("CHECK", "STATUS", "DETAIL")
+	var failed []string
+	for _, r := range results {
+		w.AddRow(r)
+		if r.Status == statusFailed {
+			failed = append(failed, r.Name)
+		}
+	}
+	w.Flush()
+	if len(failed) > 0 {
+		return fmt.Errorf("%d check(s) failed for pod %s.%s, starting with %q", len(failed), pod.Name, pod.Namespace, failed[0])
+	}
+	_, _ = fmt.Fprintf(writer, "\nPod %s.%s is enrolled in the ambient data plane.\n", pod.Name, pod.Namespace)
+	return nil
+}
@@ -0,0 +1,319 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ambient
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/cni/pkg/nodeagent/debugstate"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const testZtunnelDump = `{
+  "workloads": [{
+    "uid": "Kubernetes//Pod/default/app",
+    "name": "app",
+    "namespace": "default",
+    "serviceAccount": "app-sa",
+    "protocol": "HBONE",
+    "status": "Healthy"
+  }],
+  "certificates": [{"identity": "spiffe://cluster.local/ns/default/sa/app-sa", "state": "Available"}],
+  "workloadState": {"app-uid": {"state": "Up", "info": {"name": "app", "namespace": "default"}}}
+}`
+
+func daemonSetWithPod(name, podName string) []runtime.Object {
+	labels := map[string]string{"k8s-app": name}
+	return []runtime.Object{
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system"},
+			Spec:       appsv1.DaemonSetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: "istio-system", Labels: labels},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		},
+	}
+}
+
+func appPod(phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "app-uid"},
+		Spec:       corev1.PodSpec{NodeName: "node1"},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func cniConfig(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: cniConfigMap, Namespace: "istio-system"},
+		Data:       data,
+	}
+}
+
+const defaultSelectors = `- podSelector:
+    matchLabels: {istio.io/dataplane-mode: ambient}
+- podSelector:
+    matchExpressions:
+    - {key: istio.io/dataplane-mode, operator: NotIn, values: [none]}
+  namespaceSelector:
+    matchLabels: {istio.io/dataplane-mode: ambient}
+`
+
+// debugStateJSON returns the debug state served by the node agent for the app pod.
+func debugStateJSON(t *testing.T, cniConfigError string, rules *debugstate.InpodRules, pods ...debugstate.Pod) []byte {
+	out, err := json.Marshal(debugstate.State{
+		Pods:               pods,
+		ZtunnelConnections: []debugstate.ZtunnelConnection{{UUID: "conn-1", Latest: true}},
+		CNIConfig:          &debugstate.CNIConfig{File: "/host/etc/cni/net.d/10-calico.conflist", Error: cniConfigError},
+		InpodRules:         rules,
+	})
+	assert.NoError(t, err)
+	return out
+}
+
+func TestDiagnose(t *testing.T) {
+	auditedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ambientNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "default",
+		Labels: map[string]string{"istio.io/dataplane-mode": "ambient"},
+	}}
+	enrolled := debugstate.Pod{UID: "app-uid", Name: "app", Namespace: "default", Status: debugstate.PodStatusEnrolled, AckedBy: "conn-1"}
+	unacknowledged := debugstate.Pod{UID: "app-uid", Name: "app", Namespace: "default", Status: debugstate.PodStatusUnacknowledged}
+	cases := []struct {
+		name      string
+		pod       *corev1.Pod
+		objects   []runtime.Object
+		noZtunnel bool
+		results   map[string][]byte
+		expected  map[string]checkStatus
+	}{
+		{
+			name: "enrolled",
+			pod:  appPod(corev1.PodRunning),
+			objects: []runtime.Object{
+				ambientNamespace,
+				cniConfig(map[string]string{"AMBIENT_ENABLED": "true", "AMBIENT_ENABLEMENT_SELECTOR": defaultSelectors}),
+			},
+			results: map[string][]byte{
+				"istio-cni-node-1": debugStateJSON(t, "", &debugstate.InpodRules{Pod: "default/app", AuditedAt: auditedAt}, enrolled),
+				"ztunnel-1":        []byte(testZtunnelDump),
+			},
+			expected: map[string]checkStatus{
+				checkPodRunning:         statusPassed,
+				checkAmbientEnabled:     statusPassed,
+				checkPodSelected:        statusPassed,
+				checkNodeAgentReady:     statusPassed,
+				checkCNIConfig:          statusPassed,
+				checkPodEnrolled:        statusPassed,
+				checkInpodRules:         statusPassed,
+				checkZtunnelReady:       statusPassed,
+				checkZtunnelPodState:    statusPassed,
+				checkZtunnelWorkload:    statusPassed,
+				checkZtunnelCertificate: statusPassed,
+			},
+		},
+		{
+			name: "rules not audited",
+			pod:  appPod(corev1.PodRunning),
+			objects: []runtime.Object{
+				ambientNamespace,
+				cniConfig(map[string]string{"AMBIENT_ENABLED": "true", "AMBIENT_ENABLEMENT_SELECTOR": defaultSelectors}),
+			},
+			results: map[string][]byte{
+				"istio-cni-node-1": debugStateJSON(t, "", &debugstate.InpodRules{Pod: "default/app", Error: "pod was not audited yet"}, enrolled),
+				"ztunnel-1":        []byte(testZtunnelDump),
+			},
+			expected: map[string]checkStatus{
+				checkPodRunning:         statusPassed,
+				checkAmbientEnabled:     statusPassed,
+				checkPodSelected:        statusPassed,
+				checkNodeAgentReady:     statusPassed,
+				checkCNIConfig:          statusPassed,
+				checkPodEnrolled:        statusPassed,
+				checkInpodRules:         statusSkipped,
+				checkZtunnelReady:       statusPassed,
+				checkZtunnelPodState:    statusPassed,
+				checkZtunnelWorkload:    statusPassed,
+				checkZtunnelCertificate: statusPassed,
+			},
+		},
+		{
+			name: "not running",
+			pod:  appPod(corev1.PodPending),
+			expected: map[string]checkStatus{
+				checkPodRunning:         statusFailed,
+				checkAmbientEnabled:     statusSkipped,
+				checkPodSelected:        statusSkipped,
+				checkNodeAgentReady:     statusSkipped,
+				checkCNIConfig:          statusSkipped,
+				checkPodEnrolled:        statusSkipped,
+				checkInpodRules:         statusSkipped,
+				checkZtunnelReady:       statusSkipped,
+				checkZtunnelPodState:    statusSkipped,
+				checkZtunnelWorkload:    statusSkipped,
+				checkZtunnelCertificate: statusSkipped,
+			},
+		},
+		{
+			name: "not selected",
+			pod:  appPod(corev1.PodRunning),
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+				cniConfig(map[string]string{"AMBIENT_ENABLED": "true", "AMBIENT_ENABLEMENT_SELECTOR": defaultSelectors}),
+			},
+			results: map[string][]byte{
+				"istio-cni-node-1": debugStateJSON(t, "", &debugstate.InpodRules{Pod: "default/app", Error: "pod is not eligible"}),
+				"ztunnel-1":        []byte(`{}`),
+			},
+			expected: map[string]checkStatus{
+				checkPodRunning:         statusPassed,
+				checkAmbientEnabled:     statusPassed,
+				checkPodSelected:        statusFailed,
+				checkNodeAgentReady:     statusPassed,
+				checkCNIConfig:          statusPassed,
+				checkPodEnrolled:        statusFailed,
+				checkInpodRules:         statusSkipped,
+				checkZtunnelReady:       statusPassed,
+				checkZtunnelPodState:    statusFailed,
+				checkZtunnelWorkload:    statusFailed,
+				checkZtunnelCertificate: statusSkipped,
+			},
+		},
+		{
+			name: "rules drifted and plugin missing from the CNI config",
+			pod:  appPod(corev1.PodRunning),
+			objects: []runtime.Object{
+				ambientNamespace,
+				cniConfig(map[string]string{"AMBIENT_ENABLED": "true", "AMBIENT_ENABLEMENT_SELECTOR": defaultSelectors}),
+			},
+			results: map[string][]byte{
+				"istio-cni-node-1": debugStateJSON(t, "istio-cni plugin not found",
+					&debugstate.InpodRules{Pod: "default/app", AuditedAt: auditedAt, Drifted: true}, unacknowledged),
+				"ztunnel-1": []byte(testZtunnelDump),
+			},
+			expected: map[string]checkStatus{
+				checkPodRunning:         statusPassed,
+				checkAmbientEnabled:     statusPassed,
+				checkPodSelected:        statusPassed,
+				checkNodeAgentReady:     statusPassed,
+				checkCNIConfig:          statusFailed,
+				checkPodEnrolled:        statusFailed,
+				checkInpodRules:         statusFailed,
+				checkZtunnelReady:       statusPassed,
+				checkZtunnelPodState:    statusPassed,
+				checkZtunnelWorkload:    statusPassed,
+				checkZtunnelCertificate: statusPassed,
+			},
+		},
+		{
+			name: "istio-cni not ready",
+			pod:  appPod(corev1.PodRunning),
+			objects: []runtime.Object{
+				ambientNamespace,
+				cniConfig(map[string]string{"AMBIENT_ENABLED": "true", "AMBIENT_ENABLEMENT_SELECTOR": defaultSelectors}),
+			},
+			results: map[string][]byte{"ztunnel-1": []byte(testZtunnelDump)},
+			expected: map[string]checkStatus{
+				checkPodRunning:         statusPassed,
+				checkAmbientEnabled:     statusPassed,
+				checkPodSelected:        statusPassed,
+				checkNodeAgentReady:     statusFailed,
+				checkCNIConfig:          statusSkipped,
+				checkPodEnrolled:        statusSkipped,
+				checkInpodRules:         statusSkipped,
+				checkZtunnelReady:       statusPassed,
+				checkZtunnelPodState:    statusPassed,
+				checkZtunnelWorkload:    statusPassed,
+				checkZtunnelCertificate: statusPassed,
+			},
+		},
+		{
+			name: "istio-cni not installed",
+			pod:  appPod(corev1.PodRunning),
+			objects: []runtime.Object{
+				ambientNamespace,
+			},
+			noZtunnel: true,
+			results:   map[string][]byte{"istio-cni-node-1": debugStateJSON(t, "", nil)},
+			expected: map[string]checkStatus{
+				checkPodRunning:         statusPassed,
+				checkAmbientEnabled:     statusFailed,
+				checkPodSelected:        statusSkipped,
+				checkNodeAgentReady:     statusPassed,
+				checkCNIConfig:          statusPassed,
+				checkPodEnrolled:        statusFailed,
+				checkInpodRules:         statusSkipped,
+				checkZtunnelReady:       statusFailed,
+				checkZtunnelPodState:    statusSkipped,
+				checkZtunnelWorkload:    statusSkipped,
+				checkZtunnelCertificate: statusSkipped,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := append([]runtime.Object{tc.pod}, tc.objects...)
+			objects = append(objects, daemonSetWithPod(cniDaemonSet, "istio-cni-node-1")...)
+			if !tc.noZtunnel {
+				objects = append(objects, daemonSetWithPod(ztunnelDaemonSet, "ztunnel-1")...)
+			}
+			ctx := cli.NewFakeContext(&cli.NewFakeContextOption{
+				IstioNamespace: "istio-system",
+				Objects:        objects,
+				Results:        tc.results,
+			})
+			client, err := ctx.CLIClient()
+			assert.NoError(t, err)
+
+			got := map[string]checkStatus{}
+			for _, r := range diagnose(client, tc.pod, "istio-system", 15000) {
+				got[r.Name] = r.Status
+			}
+			assert.Equal(t, got, tc.expected)
+		})
+	}
+}
+
+func TestPrintResults(t *testing.T) {
+	pod := appPod(corev1.PodRunning)
+	var out bytes.Buffer
+	err := printResults(&out, pod, []checkResult{
+		{Name: checkPodRunning, Status: statusPassed, Detail: "pod is running on node node1"},
+		{Name: checkPodEnrolled, Status: statusFailed, Detail: "pod is not in the pod cache of istio-cni-node-1"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, err.Error(), `1 check(s) failed for pod app.default, starting with "Pod is enrolled by the node agent"`)
+
+	out.Reset()
+	err = printResults(&out, pod, []checkResult{
+		{Name: checkPodRunning, Status: statusPassed, Detail: "pod is running on node node1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, bytes.Contains(out.Bytes(), []byte("Pod app.default is enrolled in the ambient data plane.")), true)
+}
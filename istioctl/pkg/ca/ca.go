@@ -0,0 +1,202 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/istioctl/pkg/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/security/pkg/server/ca/audit"
+)
+
+const (
+	summaryOutput = "short"
+	jsonOutput    = "json"
+)
+
+// Cmd returns the "istioctl x ca" command.
+func Cmd(ctx cli.Context) *cobra.Command {
+	caCmd := &cobra.Command{
+		Use:   "ca",
+		Short: "Interact with the Istiod certificate authority",
+	}
+	caCmd.AddCommand(auditCmd(ctx))
+	return caCmd
+}
+
+func auditCmd(ctx cli.Context) *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+	var (
+		filter   audit.Filter
+		outcome  string
+		since    time.Duration
+		file     string
+		output   string
+		issuedOn bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Query the certificate issuance audit log of Istiod",
+		Long: `Query the record of certificate requests handled by the Istiod CA.
+
+Istiod only records requests when the CA_AUDIT_LOG_FILE environment variable is set. By default all Istiod
+instances are queried and their records merged; use --file to query an audit log file directly. The rotated
+files next to the audit log are queried too, so the history covers the CA_AUDIT_LOG_MAX_BACKUPS retained files.
+`,
+		Example: `  # Show all certificates issued for a service account in the last hour
+  istioctl x ca audit --san spiffe://cluster.local/ns/default/sa/productpage --since 1h --issued
+
+  # Find the request that produced a given certificate serial number
+  istioctl x ca audit --serial 5c0b9c34a2f9
+
+  # Show denied requests from an exported audit log
+  istioctl x ca audit --file audit.log --outcome Denied -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if issuedOn {
+				outcome = string(audit.OutcomeIssued)
+			}
+			filter.Outcome = audit.Outcome(outcome)
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+			var records []*audit.Record
+			if file != "" {
+				var err error
+				records, err = audit.ReadFile(file, filter)
+				if err != nil {
+					return err
+				}
+			} else {
+				kubeClient, err := ctx.CLIClientWithRevision(ctx.RevisionOrDefault(opts.Revision))
+				if err != nil {
+					return err
+				}
+				xdsRequest := discovery.DiscoveryRequest{
+					ResourceNames: []string{"caauditz?" + filter.Query().Encode()},
+					Node: &core.Node{
+						Id: "debug~0.0.0.0~istioctl~cluster.local",
+					},
+					TypeUrl: v3.DebugType,
+				}
+				xdsResponses, err := multixds.AllRequestAndProcessXds(&xdsRequest, centralOpts, ctx.IstioNamespace(),
+					"", "", kubeClient, multixds.DefaultOptions)
+				if err != nil {
+					return err
+				}
+				records, err = mergeRecords(xdsResponses, filter.Limit)
+				if err != nil {
+					return err
+				}
+			}
+			return printRecords(c.OutOrStdout(), records, output)
+		},
+	}
+
+	opts.AttachControlPlaneFlags(cmd)
+	centralOpts.AttachControlPlaneFlags(cmd)
+	cmd.Long += "\n\n" + util.ExperimentalMsg
+	cmd.Flags().StringVar(&filter.SAN, "san", "", "Only show requests for this SAN. A trailing '*' matches by prefix.")
+	cmd.Flags().StringVar(&filter.Serial, "serial", "", "Only show the certificate with this hex encoded serial number.")
+	cmd.Flags().StringVar(&filter.Requester, "requester", "", "Only show requests made by this identity. A trailing '*' matches by prefix.")
+	cmd.Flags().StringVar(&outcome, "outcome", "",
+		fmt.Sprintf("Only show requests with this outcome, one of %s.", strings.Join([]string{
+			string(audit.OutcomeIssued), string(audit.OutcomeAuthenticationFailed), string(audit.OutcomeDenied), string(audit.OutcomeSigningFailed),
+		}, ", ")))
+	cmd.Flags().BoolVar(&issuedOn, "issued", false, "Only show issued certificates. Shorthand for --outcome Issued.")
+	cmd.Flags().DurationVar(&since, "since", 0, "Only show requests newer than this duration, e.g. 1h.")
+	cmd.Flags().IntVar(&filter.Limit, "limit", 0, "Show at most this many of the most recent records. 0 shows all.")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Read records from this audit log file instead of Istiod.")
+	cmd.Flags().StringVarP(&output, "output", "o", summaryOutput, "Output format: one of short|json")
+	return cmd
+}
+
+// mergeRecords combines the records returned by each Istiod instance, ordered by time.
+func mergeRecords(responses map[string]*discovery.DiscoveryResponse, limit int) ([]*audit.Record, error) {
+	var records []*audit.Record
+	for istiod, resp := range responses {
+		for _, resource := range resp.Resources {
+			var recs []*audit.Record
+			if err := json.Unmarshal(resource.Value, &recs); err != nil {
+				return nil, fmt.Errorf("%s: %s", istiod, strings.TrimSpace(string(resource.Value)))
+			}
+			records = append(records, recs...)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	return records, nil
+}
+
+func printRecords(w io.Writer, records []*audit.Record, output string) error {
+	switch output {
+	case jsonOutput:
+		if records == nil {
+			records = []*audit.Record{}
+		}
+		b, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(w, string(b))
+		return nil
+	case summaryOutput:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "TIME\tOUTCOME\tSERIAL\tSANS\tREQUESTER\tAUTHENTICATOR\tNODE\tTTL\tREASON")
+		for _, r := range records {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.Time.UTC().Format(time.RFC3339),
+				r.Outcome,
+				valueOrNone(r.Serial),
+				valueOrNone(strings.Join(r.SANs, ",")),
+				valueOrNone(strings.Join(r.Requester, ",")),
+				valueOrNone(r.Authenticator),
+				valueOrNone(r.Node),
+				(time.Duration(r.TTLSeconds) * time.Second).String(),
+				r.Reason,
+			)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, must be one of %s|%s", output, summaryOutput, jsonOutput)
+	}
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
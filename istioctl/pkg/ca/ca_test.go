@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/security/pkg/server/ca/audit"
+)
+
+func TestMergeRecords(t *testing.T) {
+	responses := map[string]*discovery.DiscoveryResponse{
+		"istiod-a": {Resources: []*anypb.Any{{Value: []byte(`[
+			{"time":"2026-01-01T00:00:00Z","outcome":"Issued","serial":"1"},
+			{"time":"2026-01-01T00:02:00Z","outcome":"Issued","serial":"3"}]`)}}},
+		"istiod-b": {Resources: []*anypb.Any{{Value: []byte(`[
+			{"time":"2026-01-01T00:01:00Z","outcome":"Denied","reason":"policy"}]`)}}},
+	}
+	records, err := mergeRecords(responses, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, len(records), 3)
+	assert.Equal(t, []audit.Outcome{records[0].Outcome, records[1].Outcome, records[2].Outcome},
+		[]audit.Outcome{audit.OutcomeIssued, audit.OutcomeDenied, audit.OutcomeIssued})
+
+	records, err = mergeRecords(responses, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, len(records), 1)
+	assert.Equal(t, records[0].Serial, "3")
+
+	_, err = mergeRecords(map[string]*discovery.DiscoveryResponse{
+		"istiod-a": {Resources: []*anypb.Any{{Value: []byte("CA audit log is not enabled")}}},
+	}, 0)
+	if err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Fatalf("expected error from istiod to be surfaced, got %v", err)
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, printRecords(&out, records, summaryOutput))
+	if !strings.Contains(out.String(), "SERIAL") || !strings.Contains(out.String(), "Issued") {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+}
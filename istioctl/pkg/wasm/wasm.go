@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/pkg/util/sets"
+	istiowasm "istio.io/istio/pkg/wasm"
+)
+
+// Cmd returns the "istioctl x wasm" command.
+func Cmd(_ cli.Context) *cobra.Command {
+	wasmCmd := &cobra.Command{
+		Use:   "wasm",
+		Short: "Inspect Wasm modules used by WasmPlugins",
+	}
+	wasmCmd.AddCommand(validateCmd())
+	return wasmCmd
+}
+
+func validateCmd() *cobra.Command {
+	var (
+		insecure   bool
+		checksum   string
+		pullSecret string
+		timeout    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "validate <url|file>",
+		Short: "Check that a Wasm module can be loaded by the proxy",
+		Long: `Fetch a Wasm module and statically check that the proxy can load it.
+
+The module must be a valid Wasm binary exporting a proxy-wasm ABI version supported by the proxy, its memory and a
+memory allocation function, and only import host functions provided by the proxy. The module is fetched like the
+proxy does for a WasmPlugin with the same URL, or read from a local file.
+`,
+		Example: `  # Validate the module of a WasmPlugin
+  istioctl x wasm validate oci://ghcr.io/istio-ecosystem/wasm-extensions/basic_auth:1.12.0
+
+  # Validate a module built locally
+  istioctl x wasm validate ./plugin.wasm`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			var b []byte
+			var err error
+			if isRemote(args[0]) {
+				var secret []byte
+				if pullSecret != "" {
+					if secret, err = os.ReadFile(pullSecret); err != nil {
+						return err
+					}
+				}
+				b, err = fetchModule(args[0], insecure, istiowasm.GetOptions{
+					Checksum:       checksum,
+					PullSecret:     secret,
+					RequestTimeout: timeout,
+				})
+			} else {
+				b, err = os.ReadFile(strings.TrimPrefix(args[0], "file://"))
+			}
+			if err != nil {
+				return err
+			}
+			return validateModule(c.OutOrStdout(), args[0], b)
+		},
+	}
+
+	cmd.Long += "\n\n" + util.ExperimentalMsg
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "Allow fetching the module from an insecure registry or server.")
+	cmd.Flags().StringVar(&checksum, "sha256", "", "Expected SHA256 checksum of the module, as in the WasmPlugin.")
+	cmd.Flags().StringVar(&pullSecret, "pull-secret", "",
+		"Path to a docker config JSON file with the credentials of the registry, as in the imagePullSecret of the WasmPlugin.")
+	cmd.Flags().DurationVar(&timeout, "timeout", istiowasm.DefaultHTTPRequestTimeout, "Timeout of the module download.")
+	return cmd
+}
+
+func isRemote(url string) bool {
+	return strings.HasPrefix(url, "oci://") || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// fetchModule downloads the module with the fetch logic of the proxy, through a throwaway cache.
+func fetchModule(url string, insecure bool, opts istiowasm.GetOptions) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "istioctl-wasm-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	cacheOpts := istiowasm.Options{
+		PurgeInterval:         istiowasm.DefaultPurgeInterval,
+		ModuleExpiry:          istiowasm.DefaultModuleExpiry,
+		InsecureRegistries:    sets.New[string](),
+		HTTPRequestTimeout:    opts.RequestTimeout,
+		HTTPRequestMaxRetries: istiowasm.DefaultHTTPRequestMaxRetries,
+	}
+	if insecure {
+		cacheOpts.InsecureRegistries.Insert("*")
+	}
+	cache := istiowasm.NewLocalFileCache(dir, cacheOpts)
+	defer cache.Cleanup()
+
+	opts.ResourceName = "istioctl"
+	opts.PullPolicy = istiowasm.Always
+	path, err := cache.Get(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// validateModule prints the findings of the inspection of the module, and fails if the proxy cannot load it.
+func validateModule(w io.Writer, name string, b []byte) error {
+	info, err := istiowasm.InspectModule(b)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid Wasm module: %v", name, err)
+	}
+	findings := info.Check()
+	versions := info.ABIVersions()
+	if len(versions) == 0 {
+		versions = []string{"none"}
+	}
+	_, _ = fmt.Fprintf(w, "Module: %s\n", name)
+	_, _ = fmt.Fprintf(w, "ABI versions: %s\n", strings.Join(versions, ", "))
+	_, _ = fmt.Fprintf(w, "Imports: %d, exports: %d\n", len(info.Imports), len(info.Exports))
+	for _, f := range findings {
+		_, _ = fmt.Fprintln(w, f)
+	}
+	if istiowasm.HasErrors(findings) {
+		return fmt.Errorf("%s cannot be loaded by the proxy", name)
+	}
+	_, _ = fmt.Fprintln(w, "The module can be loaded by the proxy.")
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// module returns a Wasm binary exporting the given functions and its memory.
+func module(functions ...string) []byte {
+	exports := []byte{byte(len(functions) + 1), 6}
+	exports = append(exports, "memory"...)
+	exports = append(exports, 2, 0)
+	for i, f := range functions {
+		exports = append(exports, byte(len(f)))
+		exports = append(exports, f...)
+		exports = append(exports, 0, byte(i))
+	}
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 7, byte(len(exports))}
+	return append(b, exports...)
+}
+
+func TestValidate(t *testing.T) {
+	valid := module("proxy_abi_version_0_2_1", "malloc")
+	invalid := module("malloc")
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "valid.wasm"), valid, 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.wasm"), invalid, 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "garbage.wasm"), []byte("garbage"), 0o644))
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(valid)
+	}))
+	defer s.Close()
+
+	cases := []struct {
+		name    string
+		arg     string
+		wantOut string
+		wantErr string
+	}{
+		{
+			name:    "valid file",
+			arg:     filepath.Join(dir, "valid.wasm"),
+			wantOut: "ABI versions: proxy_abi_version_0_2_1\nImports: 0, exports: 3\nThe module can be loaded by the proxy.",
+		},
+		{
+			name:    "valid url",
+			arg:     s.URL + "/valid.wasm",
+			wantOut: "The module can be loaded by the proxy.",
+		},
+		{
+			name:    "invalid file",
+			arg:     "file://" + filepath.Join(dir, "invalid.wasm"),
+			wantOut: "ABI versions: none\nImports: 0, exports: 2\nError: the module does not export a proxy-wasm ABI version",
+			wantErr: "cannot be loaded by the proxy",
+		},
+		{
+			name:    "not a module",
+			arg:     filepath.Join(dir, "garbage.wasm"),
+			wantErr: "is not a valid Wasm module",
+		},
+		{
+			name:    "missing file",
+			arg:     filepath.Join(dir, "missing.wasm"),
+			wantErr: "no such file",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := Cmd(cli.NewFakeContext(nil))
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&out)
+			cmd.SetArgs([]string{"validate", tc.arg})
+			err := cmd.Execute()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if !strings.Contains(out.String(), tc.wantOut) {
+				t.Fatalf("expected output to contain %q, got:\n%s", tc.wantOut, out.String())
+			}
+		})
+	}
+}
@@ -567,6 +567,15 @@ func setupZtunnelConfigDumpWriter(kubeClient kube.CLIClient, podName, podNamespa
 	return setupConfigdumpZtunnelConfigWriter(debug, out)
 }
 
+// ConfigDump fetches and parses the config dump of the given ztunnel pod.
+func ConfigDump(kubeClient kube.CLIClient, podName, podNamespace string, port int) (*ztunnelDump.ZtunnelDump, error) {
+	cw, err := setupZtunnelConfigDumpWriter(kubeClient, podName, podNamespace, io.Discard, port)
+	if err != nil {
+		return nil, err
+	}
+	return cw.Dump(), nil
+}
+
 func readFile(filename string) ([]byte, error) {
 	file := os.Stdin
 	if filename != "-" {
@@ -21,6 +21,8 @@ import (
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pkg/bootstrap/platform"
+	dnsClient "istio.io/istio/pkg/dns/client"
+	"istio.io/istio/pkg/envoy"
 	istioagent "istio.io/istio/pkg/istio-agent"
 	"istio.io/istio/pkg/ptr"
 	"istio.io/istio/pkg/util/sets"
@@ -49,6 +51,9 @@ func NewAgentOptions(proxy *ProxyArgs, cfg *meshconfig.ProxyConfig, sds istioage
 			PurgeInterval:         wasmPurgeInterval,
 			HTTPRequestTimeout:    wasmHTTPRequestTimeout,
 			HTTPRequestMaxRetries: wasmHTTPRequestMaxRetries,
+			NodeCacheDir:          wasmNodeCacheDir,
+			NodeCacheMaxBytes:     wasmNodeCacheMaxBytes,
+			NodeCacheTagTTL:       wasmNodeCacheTagTTL,
 		},
 		ProxyIPAddresses:             proxy.IPAddresses,
 		ServiceNode:                  proxy.ServiceNode(),
@@ -58,6 +63,8 @@ func NewAgentOptions(proxy *ProxyArgs, cfg *meshconfig.ProxyConfig, sds istioage
 		EnvoySecureMergedMetricsPort: envoySecureMergedMetricsPortEnv,
 		MinimumDrainDuration:         minimumDrainDurationEnv,
 		ExitOnZeroActiveConnections:  exitOnZeroActiveConnectionsEnv,
+		ClusterHealthStats:           compositeAppProbersVar.Get() != "",
+		DrainMode:                    envoy.DrainMode(drainModeEnv),
 		Platform:                     platform.Discover(proxy.SupportsIPv6()),
 		GRPCBootstrapPath:            grpcBootstrapEnv,
 		DisableEnvoy:                 disableEnvoyEnv,
@@ -73,6 +80,30 @@ func NewAgentOptions(proxy *ProxyArgs, cfg *meshconfig.ProxyConfig, sds istioage
 		WorkloadIdentitySocketFile:   workloadIdentitySocketFile,
 		EnvoySkipDeprecatedLogs:      envoySkipDeprecatedLogsEnv,
 	}
+	o.DNSCache = dnsClient.ResponseCacheOptions{
+		MaxEntries:  DNSCacheSize.Get(),
+		MaxTTL:      DNSCacheMaxTTL.Get(),
+		NegativeTTL: DNSCacheNegativeTTL.Get(),
+		Prefetch:    DNSCachePrefetch.Get(),
+	}
+	o.DNSQueryLog = dnsClient.QueryLogOptions{
+		Log:         DNSQueryLog.Get(),
+		HistorySize: DNSQueryHistorySize.Get(),
+		MaxHosts:    DNSQueryMetricsMaxHosts.Get(),
+	}
+	o.EnvoyDiagnostics = envoy.DiagnosticsOptions{
+		Dir:         envoyDiagnosticsDirEnv,
+		MaxBundles:  envoyDiagnosticsMaxBundlesEnv,
+		StderrLines: envoyDiagnosticsStderrLinesEnv,
+		MaxNACKs:    envoyDiagnosticsMaxNACKsEnv,
+	}
+	if DNSUpstreams.Get() != "" {
+		o.DNSEncryptedUpstreams = dnsClient.EncryptedUpstreamOptions{
+			Servers:  strings.Split(DNSUpstreams.Get(), ","),
+			CAFile:   DNSUpstreamCAFile.Get(),
+			Fallback: dnsClient.UpstreamFallbackPolicy(DNSUpstreamFallback.Get()),
+		}
+	}
 	if enableWDSEnvWasSet {
 		o.MetadataDiscovery = ptr.Of(enableWDSEnv)
 	}
@@ -44,6 +44,11 @@ var (
 	// Provider for XDS auth, e.g., gcp. By default, it is empty, meaning no auth provider.
 	xdsAuthProvider = env.Register("XDS_AUTH_PROVIDER", "", "Provider for XDS auth")
 
+	compositeAppProbersVar = env.Register(status.CompositeAppProberEnvName, "",
+		"JSON encoded composite application probes, served on the /app-health/ paths of the status server. Each probe is "+
+			"ready when the app probe rewritten for the path, the listed app probes and the listed upstream conditions pass, "+
+			`e.g. {"/app-health/app/readyz":{"upstreams":[{"host":"db.ns.svc.cluster.local","port":5432}]}}`)
+
 	jwtPolicy = env.Register("JWT_POLICY", jwt.PolicyThirdParty,
 		"The JWT validation policy.")
 	// ProvCert is the environment controlling the use of pre-provisioned certs, for VMs.
@@ -67,6 +72,12 @@ var (
 		"The output directory for the key and certificate. If empty, key and certificate will not be saved. "+
 			"Must be set for VMs using provisioning certificates.").Get()
 
+	workloadIdentitiesEnv = env.Register("WORKLOAD_IDENTITIES", "",
+		"Comma separated list of additional identities of the workload, in the form name=spiffe://trust-domain/ns/namespace/sa/account. "+
+			"Ignored if the security.istio.io/workloadIdentities pod annotation is set. "+
+			"A certificate for each is served over SDS as identity://name, which DestinationRules and Gateways may reference "+
+			"as credentialName. Istiod only issues identities of the workload's own service account in a trust domain alias.").Get()
+
 	caProviderEnv = env.Register("CA_PROVIDER", "Citadel", "name of authentication provider").Get()
 	caEndpointEnv = env.Register("CA_ADDR", "", "Address of the spiffe certificate provider. Defaults to discoveryAddress").Get()
 
@@ -93,9 +104,13 @@ var (
 	eccCurvEnv          = env.Register("ECC_CURVE", "P256", "The elliptic curve to use when ECC_SIGNATURE_ALGORITHM is set to ECDSA").Get()
 	fileMountedCertsEnv = env.Register("FILE_MOUNTED_CERTS", false, "").Get()
 	credFetcherTypeEnv  = env.Register("CREDENTIAL_FETCHER_TYPE", security.JWT,
-		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine").Get()
+		"The type of the credential fetcher. Currently supported types include JWT, GoogleComputeEngine, AWSEC2 and AzureVM").Get()
 	credIdentityProvider = env.Register("CREDENTIAL_IDENTITY_PROVIDER", "GoogleComputeEngine",
 		"The identity provider for credential. Currently default supported identity provider is GoogleComputeEngine").Get()
+	credAudience = env.Register("CREDENTIAL_AUDIENCE", "",
+		"The audience of the credentials of the AWSEC2 and AzureVM credential fetchers, which must match CA_AWS_AUDIENCE "+
+			"or CA_AZURE_AUDIENCE in Istiod. Defaults to the trust domain for AWSEC2. Required for AzureVM, "+
+			"as it must be an application ID URI of the Azure tenant.").Get()
 	// EnableSelfDiscovery controls whether pilot-agent adds a local_cluster static cluster to the bootstrap
 	// for zone-aware routing support. Set ISTIO_META_ENABLE_SELF_DISCOVERY=true via proxyMetadata.
 	EnableSelfDiscovery = env.Register("ISTIO_META_ENABLE_SELF_DISCOVERY", false,
@@ -120,6 +135,44 @@ var (
 	DNSForwardTimeout = env.Register("DNS_FORWARD_TIMEOUT", dnsClient.DefaultUpstreamTimeout,
 		"Timeout for upstream DNS queries. Defaults to 5 seconds")
 
+	DNSCacheSize = env.Register("DNS_CACHE_SIZE", 0,
+		"Maximum number of upstream DNS responses cached by the agent. Defaults to 0, disabling the cache. "+
+			"Like the other DNS proxy settings, it can be set in the proxyMetadata of the ProxyConfig")
+
+	DNSCacheMaxTTL = env.Register("DNS_CACHE_MAX_TTL", dnsClient.DefaultCacheMaxTTL,
+		"Maximum time upstream DNS responses are cached, regardless of their TTL")
+
+	DNSCacheNegativeTTL = env.Register("DNS_CACHE_NEGATIVE_TTL", dnsClient.DefaultCacheNegativeTTL,
+		"Maximum time negative upstream DNS responses (NXDOMAIN or no answer) are cached. 0 disables their caching")
+
+	DNSCachePrefetch = env.Register("DNS_CACHE_PREFETCH", true,
+		"If set to true, agent refreshes frequently requested upstream DNS responses shortly before they expire")
+
+	DNSUpstreams = env.Register("DNS_UPSTREAMS", "",
+		"Comma separated list of encrypted upstream DNS servers queried instead of the resolv.conf servers, as "+
+			"tls://host[:port] for DNS-over-TLS or https://host[:port]/path for DNS-over-HTTPS. "+
+			"The TLS server name can be set with the servername query parameter")
+
+	DNSUpstreamCAFile = env.Register("DNS_UPSTREAM_CA_FILE", "",
+		"Path to the PEM encoded certificates of the CAs trusted for the encrypted upstream DNS servers. "+
+			"If set, the system roots are not trusted")
+
+	DNSUpstreamFallback = env.Register("DNS_UPSTREAM_FALLBACK", string(dnsClient.FallbackNone),
+		"What to do when no encrypted upstream DNS server answers: 'none' fails the query, "+
+			"'resolv-conf' forwards it in clear text to the resolv.conf servers")
+
+	DNSQueryLog = env.Register("DNS_QUERY_LOG", false,
+		"If set to true, agent logs every DNS query with its name, type, source, response code and latency "+
+			"to the dnsquery scope")
+
+	DNSQueryHistorySize = env.Register("DNS_QUERY_HISTORY_SIZE", 0,
+		"Number of recent DNS queries listed by the /debug/dnsz endpoint of the status server. Defaults to 0, "+
+			"disabling the history")
+
+	DNSQueryMetricsMaxHosts = env.Register("DNS_QUERY_METRICS_MAX_HOSTS", 0,
+		"Maximum number of distinct names resolved upstream with their own host label in the dns_host_queries_total "+
+			"metric, the further names are recorded as 'other'. Defaults to 0, disabling the metric")
+
 	// Ability of istio-agent to retrieve proxyConfig via XDS for dynamic configuration updates
 	enableProxyConfigXdsEnv = env.Register("PROXY_CONFIG_XDS_AGENT", false,
 		"If set to true, agent retrieves dynamic proxy-config updates via xds channel").Get()
@@ -139,6 +192,17 @@ var (
 	wasmHTTPRequestMaxRetries = env.Register("WASM_HTTP_REQUEST_MAX_RETRIES", wasm.DefaultHTTPRequestMaxRetries,
 		"maximum number of HTTP/HTTPS request retries for pulling a Wasm module via http/https").Get()
 
+	wasmNodeCacheDir = env.Register("WASM_NODE_CACHE_DIR", "",
+		"directory, typically a hostPath volume, of the Wasm module cache shared by the proxies of the node. "+
+			"Modules are looked up in it before being fetched from the network. Empty disables the node cache").Get()
+
+	wasmNodeCacheMaxBytes = env.Register[int64]("WASM_NODE_CACHE_MAX_BYTES", wasm.DefaultNodeCacheMaxBytes,
+		"size limit in bytes of the Wasm module cache shared by the proxies of the node, 0 for no limit").Get()
+
+	wasmNodeCacheTagTTL = env.Register("WASM_NODE_CACHE_TAG_TTL", wasm.DefaultNodeCacheTagTTL,
+		"how long the proxies of the node reuse the resolution of a Wasm image tag recorded in the node cache "+
+			"instead of fetching the image manifest from the registry").Get()
+
 	enableWDSEnv, enableWDSEnvWasSet = env.Register("PEER_METADATA_DISCOVERY", false,
 		"If set to true, enable the peer metadata discovery extension in Envoy").Lookup()
 
@@ -177,6 +241,33 @@ var (
 		false,
 		"When set to true, terminates proxy when number of active connections become zero during draining").Get()
 
+	drainModeEnv = env.Register("DRAIN_MODE",
+		"duration",
+		"Decides when the proxy is terminated once draining started. With 'duration', the proxy is terminated after "+
+			"the termination drain duration, or when there is no active connection if EXIT_ON_ZERO_ACTIVE_CONNECTIONS is set. "+
+			"With 'requests', the proxy is terminated once the HTTP listeners have no in-flight request, after at least "+
+			"MINIMUM_DRAIN_DURATION and at most the termination drain duration. The progress is reported on the "+
+			"/drain/status endpoint of the status server").Get()
+
+	envoyDiagnosticsDirEnv = env.Register("ENVOY_DIAGNOSTICS_DIR",
+		"",
+		"Directory of the crash diagnostics of Envoy, written when it exits abnormally, e.g. "+
+			filepath.Join(constants.IstioDataDir, "diagnostics")+". Each bundle holds the last lines of the Envoy "+
+			"stderr, its bootstrap, its config dump at the last successful start and the recent xDS NACKs. "+
+			"The crash diagnostics are disabled by default").Get()
+
+	envoyDiagnosticsMaxBundlesEnv = env.Register("ENVOY_DIAGNOSTICS_MAX_BUNDLES",
+		3,
+		"Number of crash diagnostics bundles of Envoy kept, the oldest are removed").Get()
+
+	envoyDiagnosticsStderrLinesEnv = env.Register("ENVOY_DIAGNOSTICS_STDERR_LINES",
+		200,
+		"Number of last lines of the Envoy stderr kept in the crash diagnostics").Get()
+
+	envoyDiagnosticsMaxNACKsEnv = env.Register("ENVOY_DIAGNOSTICS_MAX_NACKS",
+		20,
+		"Number of recent xDS NACKs kept in the crash diagnostics").Get()
+
 	envoySkipDeprecatedLogsEnv = env.Register("ENVOY_SKIP_DEPRECATED_LOGS",
 		true,
 		"By default, deprecated log messages are skipped, Set to 'false' to display all deprecated log messages.").Get()
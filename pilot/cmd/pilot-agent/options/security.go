@@ -21,10 +21,12 @@ import (
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/bootstrap"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/jwt"
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/security/pkg/credentialfetcher"
 	"istio.io/istio/security/pkg/nodeagent/cafile"
 )
@@ -63,18 +65,48 @@ func NewSecurityOptions(proxyConfig *meshconfig.ProxyConfig, stsPort int, tokenM
 	}
 
 	o, err := SetupSecurityOptions(proxyConfig, o, jwtPolicy.Get(),
-		credFetcherTypeEnv, credIdentityProvider)
+		credFetcherTypeEnv, credIdentityProvider, credAudience)
 	if err != nil {
 		return o, err
 	}
 
 	extractCAHeadersFromEnv(o)
 
+	o.WorkloadIdentities, err = workloadIdentities()
+	if err != nil {
+		return o, err
+	}
+
 	return o, err
 }
 
+// workloadIdentities returns the additional identities requested by the workload annotation, falling back
+// to WORKLOAD_IDENTITIES when the annotation is not set.
+func workloadIdentities() (map[string]string, error) {
+	annotations, err := bootstrap.ReadPodAnnotations("")
+	if err != nil {
+		log.Debugf("failed to read pod annotations for workload identities: %v", err)
+	}
+	return parseWorkloadIdentities(annotations, workloadIdentitiesEnv)
+}
+
+func parseWorkloadIdentities(annotations map[string]string, env string) (map[string]string, error) {
+	if value, ok := annotations[constants.WorkloadIdentitiesAnnotation]; ok {
+		res, err := spiffe.ParseNamedIdentities(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %v", constants.WorkloadIdentitiesAnnotation, err)
+		}
+		return res, nil
+	}
+	res, err := spiffe.ParseNamedIdentities(env)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WORKLOAD_IDENTITIES: %v", err)
+	}
+	return res, nil
+}
+
 func SetupSecurityOptions(proxyConfig *meshconfig.ProxyConfig, secOpt *security.Options, jwtPolicy,
-	credFetcherTypeEnv, credIdentityProvider string,
+	credFetcherTypeEnv, credIdentityProvider, credAudience string,
 ) (*security.Options, error) {
 	jwtPath := constants.ThirdPartyJwtPath
 	switch jwtPolicy {
@@ -97,7 +129,7 @@ func SetupSecurityOptions(proxyConfig *meshconfig.ProxyConfig, secOpt *security.
 	}
 
 	o.CredIdentityProvider = credIdentityProvider
-	credFetcher, err := credentialfetcher.NewCredFetcher(credFetcherTypeEnv, o.TrustDomain, jwtPath, o.CredIdentityProvider)
+	credFetcher, err := credentialfetcher.NewCredFetcher(credFetcherTypeEnv, o.TrustDomain, jwtPath, o.CredIdentityProvider, credAudience)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create credential fetcher: %v", err)
 	}
@@ -18,6 +18,7 @@ import (
 	"os"
 	"testing"
 
+	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/security"
 )
 
@@ -137,3 +138,51 @@ func TestExtractCAHeadersFromEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWorkloadIdentities(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		env         string
+		expected    map[string]string
+		wantErr     bool
+	}{
+		{name: "empty"},
+		{
+			name: "env",
+			env:  "legacy=spiffe://legacy.example/ns/foo/sa/bar, app=spiffe://cluster.local/ns/foo/sa/app",
+			expected: map[string]string{
+				"legacy": "spiffe://legacy.example/ns/foo/sa/bar",
+				"app":    "spiffe://cluster.local/ns/foo/sa/app",
+			},
+		},
+		{
+			name:        "annotation overrides env",
+			annotations: map[string]string{constants.WorkloadIdentitiesAnnotation: "legacy=spiffe://legacy.example/ns/foo/sa/bar"},
+			env:         "app=spiffe://cluster.local/ns/foo/sa/app",
+			expected:    map[string]string{"legacy": "spiffe://legacy.example/ns/foo/sa/bar"},
+		},
+		{name: "invalid env", env: "legacy=foo", wantErr: true},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{constants.WorkloadIdentitiesAnnotation: "=spiffe://cluster.local/ns/foo/sa/bar"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWorkloadIdentities(tt.annotations, tt.env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for k, v := range tt.expected {
+				if got[k] != v {
+					t.Errorf("expected identity %s to be %s, got %s", k, v, got[k])
+				}
+			}
+		})
+	}
+}
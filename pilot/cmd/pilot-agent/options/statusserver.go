@@ -33,6 +33,7 @@ func NewStatusServerOptions(ipv6 bool, t model.NodeType, proxyConfig *meshconfig
 		Probes:         []ready.Prober{agent},
 		NoEnvoy:        agent.EnvoyDisabled(),
 		FetchDNS:       agent.GetDNSTable,
+		FetchDNSLog:    agent.GetDNSQueries,
 		GRPCBootstrap:  agent.GRPCBootstrapPath(),
 		TriggerDrain: func() {
 			agent.DrainNow()
@@ -40,5 +41,9 @@ func NewStatusServerOptions(ipv6 bool, t model.NodeType, proxyConfig *meshconfig
 		DisableDrain: func() {
 			agent.SkipDrain()
 		},
+		CompositeProbers: compositeAppProbersVar.Get(),
+		FetchDrainStatus: agent.GetDrainStatus,
+		FetchCrashes:     agent.GetEnvoyCrashes,
+		OpenCrashFile:    agent.OpenEnvoyCrashFile,
 	}
 }
@@ -0,0 +1,198 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/status/util"
+)
+
+// defaultCompositeProbeTimeoutSeconds is the timeout of the app probes of a composite prober without one, matching
+// the Kubernetes default.
+const defaultCompositeProbeTimeoutSeconds = 1
+
+// CompositeProbers holds the composite probers declared by the users.
+// It's a map from the prober URL path to the composite prober config.
+// For example, "/app-health/hello-world/readyz" entry makes the readiness of container "hello-world" depend on
+// its upstreams.
+type CompositeProbers map[string]*CompositeProber
+
+// CompositeProber passes when the Kubernetes prober of its path, if any, all of its app probes and all of its
+// upstream conditions pass.
+type CompositeProber struct {
+	// Probes are app probes, in addition to the Kubernetes prober of the path.
+	Probes []*Prober `json:"probes,omitempty"`
+	// Upstreams are the upstream services which must have healthy endpoints in Envoy.
+	Upstreams []*UpstreamCondition `json:"upstreams,omitempty"`
+}
+
+// UpstreamCondition is met when an Envoy cluster has enough healthy endpoints.
+type UpstreamCondition struct {
+	// Host and Port of the upstream service, selecting its outbound cluster.
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// Cluster is the name of the Envoy cluster, e.g. outbound|5432|v1|db.ns.svc.cluster.local for a subset.
+	// Defaults to the outbound cluster of Host and Port.
+	Cluster string `json:"cluster,omitempty"`
+	// MinHealthy is the minimum number of healthy endpoints. Defaults to 1.
+	MinHealthy uint64 `json:"minHealthy,omitempty"`
+}
+
+// initCompositeProbers decodes and validates the composite probers, and constructs the clients of their HTTP probes.
+func (s *Server) initCompositeProbers(config string) error {
+	if err := json.Unmarshal([]byte(config), &s.compositeProbers); err != nil {
+		return fmt.Errorf("failed to decode composite app prober err = %v, json string = %v", err, config)
+	}
+	for path, composite := range s.compositeProbers {
+		if !appProberPattern.MatchString(path) {
+			return fmt.Errorf(`invalid composite prober path %v, must be in form of regex pattern %v`, path, appProberPattern)
+		}
+		if composite == nil || len(composite.Probes)+len(composite.Upstreams) == 0 {
+			return fmt.Errorf("invalid composite prober config for %v, no probes nor upstreams", path)
+		}
+		for i, prober := range composite.Probes {
+			key := compositeProbeKey(path, i)
+			if prober == nil {
+				return fmt.Errorf("invalid composite prober config for %v, empty probe", key)
+			}
+			if err := validateAppKubeProber(path, prober); err != nil {
+				return fmt.Errorf("invalid composite prober config for %v: %v", key, err)
+			}
+			if prober.TimeoutSeconds == 0 {
+				prober.TimeoutSeconds = defaultCompositeProbeTimeoutSeconds
+			}
+			if prober.HTTPGet != nil {
+				client, err := s.newAppProbeClient(prober)
+				if err != nil {
+					return err
+				}
+				s.appProbeClient[key] = client
+			}
+		}
+		if len(composite.Upstreams) > 0 && s.config.NoEnvoy {
+			return fmt.Errorf("invalid composite prober config for %v, upstreams require Envoy", path)
+		}
+		for _, upstream := range composite.Upstreams {
+			if upstream == nil {
+				return fmt.Errorf("invalid composite prober config for %v, empty upstream", path)
+			}
+			if upstream.Cluster == "" {
+				if upstream.Host == "" || upstream.Port <= 0 || upstream.Port > 65535 {
+					return fmt.Errorf("invalid composite prober config for %v, upstreams must have a host and a port, "+
+						"or a cluster", path)
+				}
+				upstream.Cluster = fmt.Sprintf("outbound|%d||%s", upstream.Port, upstream.Host)
+			}
+			if upstream.MinHealthy == 0 {
+				upstream.MinHealthy = 1
+			}
+		}
+	}
+	return nil
+}
+
+// compositeProbeKey is the key of the http client of an app probe of a composite prober.
+func compositeProbeKey(path string, i int) string {
+	return fmt.Sprintf("%s#%d", path, i)
+}
+
+// handleCompositeProbe runs the Kubernetes prober of the path, if any, then the app probes and upstream conditions
+// of the composite prober, stopping at the first failure.
+func (s *Server) handleCompositeProbe(w http.ResponseWriter, req *http.Request, path string, prober *Prober, composite *CompositeProber) {
+	if err := s.checkComposite(req, path, prober, composite); err != nil {
+		probes.Errorf("Composite probe %v failed: %v", path, err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) checkComposite(req *http.Request, path string, prober *Prober, composite *CompositeProber) error {
+	if prober != nil {
+		if err := s.checkAppProbe(req, prober, path); err != nil {
+			return err
+		}
+	}
+	for i, p := range composite.Probes {
+		if err := s.checkAppProbe(req, p, compositeProbeKey(path, i)); err != nil {
+			return err
+		}
+	}
+	if len(composite.Upstreams) == 0 {
+		return nil
+	}
+
+	clusters := make([]string, 0, len(composite.Upstreams))
+	for _, upstream := range composite.Upstreams {
+		clusters = append(clusters, upstream.Cluster)
+	}
+	healthy, err := util.GetClusterHealthyMembers(s.adminHost, s.config.AdminPort, clusters)
+	if err != nil {
+		return fmt.Errorf("failed to get the upstream clusters health from Envoy: %v", err)
+	}
+	var unhealthy []string
+	for _, upstream := range composite.Upstreams {
+		count, found := healthy[upstream.Cluster]
+		if !found {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s: cluster not found", upstream.Cluster))
+		} else if count < upstream.MinHealthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s: %d healthy endpoints, expected at least %d",
+				upstream.Cluster, count, upstream.MinHealthy))
+		}
+	}
+	if len(unhealthy) > 0 {
+		return fmt.Errorf("upstreams are not ready: %s", strings.Join(unhealthy, "; "))
+	}
+	return nil
+}
+
+// checkAppProbe runs an app probe, key being the key of the http client of HTTP probes, and returns an error
+// unless the app responded with a success status code.
+func (s *Server) checkAppProbe(req *http.Request, prober *Prober, key string) error {
+	rec := &statusRecorder{header: http.Header{}}
+	s.runAppProbe(rec, req, prober, key)
+	if rec.code < http.StatusOK || rec.code >= http.StatusBadRequest {
+		return fmt.Errorf("app probe %v failed with status code %d", key, rec.code)
+	}
+	return nil
+}
+
+// statusRecorder is a http.ResponseWriter recording the status code of an app probe, discarding its body.
+type statusRecorder struct {
+	header http.Header
+	code   int
+}
+
+func (r *statusRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/status/testserver"
+	"istio.io/istio/pkg/kube/apimirror"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestCompositeAppProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	go http.Serve(listener, &handler{lastAlpn: atomic.NewString("")})
+	appPort := listener.Addr().(*net.TCPAddr).Port
+
+	envoy := testserver.CreateAndStartServer(liveServerStats +
+		"\ncluster.outbound|5432||db.ns.svc.cluster.local.membership_healthy: 2" +
+		"\ncluster.outbound|80||down.ns.svc.cluster.local.membership_healthy: 0" +
+		"\ncluster.outbound|80|v1|web.ns.svc.cluster.local.membership_healthy: 1\n")
+	defer envoy.Close()
+	adminPort := uint16(envoy.Listener.Addr().(*net.TCPAddr).Port)
+
+	httpProbe := func(path string) *Prober {
+		return &Prober{HTTPGet: &apimirror.HTTPGetAction{Path: path, Port: apimirror.IntOrString{IntVal: int32(appPort)}}}
+	}
+	kubeProbers := KubeAppProbers{
+		"/app-health/app/readyz": httpProbe("/hello/sunnyvale"),
+		"/app-health/app/livez":  httpProbe("/status/500"),
+	}
+	cases := []struct {
+		name       string
+		path       string
+		composite  *CompositeProber
+		statusCode int
+		body       string
+	}{
+		{
+			name:       "upstream healthy",
+			path:       "/app-health/app/readyz",
+			composite:  &CompositeProber{Upstreams: []*UpstreamCondition{{Host: "db.ns.svc.cluster.local", Port: 5432}}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "upstream cluster and min healthy",
+			path: "/app-health/app/readyz",
+			composite: &CompositeProber{Upstreams: []*UpstreamCondition{
+				{Cluster: "outbound|80|v1|web.ns.svc.cluster.local"},
+				{Host: "db.ns.svc.cluster.local", Port: 5432, MinHealthy: 2},
+			}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "upstream not enough healthy endpoints",
+			path: "/app-health/app/readyz",
+			composite: &CompositeProber{Upstreams: []*UpstreamCondition{
+				{Host: "db.ns.svc.cluster.local", Port: 5432, MinHealthy: 3},
+				{Host: "down.ns.svc.cluster.local", Port: 80},
+			}},
+			statusCode: http.StatusServiceUnavailable,
+			body: "upstreams are not ready: outbound|5432||db.ns.svc.cluster.local: 2 healthy endpoints, expected at least 3; " +
+				"outbound|80||down.ns.svc.cluster.local: 0 healthy endpoints, expected at least 1",
+		},
+		{
+			name:       "upstream unknown",
+			path:       "/app-health/app/readyz",
+			composite:  &CompositeProber{Upstreams: []*UpstreamCondition{{Host: "missing.ns.svc.cluster.local", Port: 80}}},
+			statusCode: http.StatusServiceUnavailable,
+			body:       "upstreams are not ready: outbound|80||missing.ns.svc.cluster.local: cluster not found",
+		},
+		{
+			name:       "kube prober fails",
+			path:       "/app-health/app/livez",
+			composite:  &CompositeProber{Upstreams: []*UpstreamCondition{{Host: "db.ns.svc.cluster.local", Port: 5432}}},
+			statusCode: http.StatusServiceUnavailable,
+			body:       "app probe /app-health/app/livez failed with status code 500",
+		},
+		{
+			name: "app probes",
+			path: "/app-health/other/readyz",
+			composite: &CompositeProber{Probes: []*Prober{
+				httpProbe("/redirect"),
+				{TCPSocket: &apimirror.TCPSocketAction{Port: apimirror.IntOrString{IntVal: int32(appPort)}}},
+			}},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "app probe fails",
+			path:       "/app-health/other/readyz",
+			composite:  &CompositeProber{Probes: []*Prober{httpProbe("/hello/sunnyvale"), httpProbe("/status/404")}},
+			statusCode: http.StatusServiceUnavailable,
+			body:       "app probe /app-health/other/readyz#1 failed with status code 404",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			kube, err := json.Marshal(kubeProbers)
+			assert.NoError(t, err)
+			composite, err := json.Marshal(CompositeProbers{tc.path: tc.composite})
+			assert.NoError(t, err)
+			server := NewTestServer(t, Options{
+				KubeAppProbers:   string(kube),
+				CompositeProbers: string(composite),
+				AdminPort:        adminPort,
+			})
+
+			resp, err := http.Get(fmt.Sprintf("http://localhost:%v%s", server.statusPort, tc.path))
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, resp.StatusCode, tc.statusCode)
+			assert.Equal(t, string(body), tc.body)
+		})
+	}
+}
+
+func TestCompositeAppProbeValidation(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  string
+		noEnvoy bool
+		err     string
+	}{
+		{
+			name:   "invalid json",
+			config: `{"/app-health/app/readyz":[]}`,
+			err:    "failed to decode composite app prober",
+		},
+		{
+			name:   "invalid path",
+			config: `{"/app-health/app/ready":{"upstreams":[{"host":"db","port":80}]}}`,
+			err:    "invalid composite prober path /app-health/app/ready",
+		},
+		{
+			name:   "empty",
+			config: `{"/app-health/app/readyz":{}}`,
+			err:    "no probes nor upstreams",
+		},
+		{
+			name:   "invalid probe",
+			config: `{"/app-health/app/readyz":{"probes":[{"httpGet":{"port":80},"tcpSocket":{"port":80}}]}}`,
+			err:    "invalid composite prober config for /app-health/app/readyz#0: invalid prober type",
+		},
+		{
+			name:   "upstream without port",
+			config: `{"/app-health/app/readyz":{"upstreams":[{"host":"db"}]}}`,
+			err:    "upstreams must have a host and a port, or a cluster",
+		},
+		{
+			name:    "upstream without envoy",
+			config:  `{"/app-health/app/readyz":{"upstreams":[{"cluster":"db"}]}}`,
+			noEnvoy: true,
+			err:     "upstreams require Envoy",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewServer(Options{CompositeProbers: tc.config, NoEnvoy: tc.noEnvoy, PrometheusRegistry: TestingRegistry(t)})
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
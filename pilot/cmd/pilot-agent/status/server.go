@@ -52,8 +52,10 @@ import (
 	"istio.io/istio/pilot/cmd/pilot-agent/status/grpcready"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/pkg/features"
+	dnsClient "istio.io/istio/pkg/dns/client"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 	"istio.io/istio/pkg/env"
+	"istio.io/istio/pkg/envoy"
 	commonFeatures "istio.io/istio/pkg/features"
 	"istio.io/istio/pkg/kube/apimirror"
 	"istio.io/istio/pkg/log"
@@ -71,6 +73,8 @@ const (
 	// quitPath is to notify the pilot agent to quit.
 	quitPath  = "/quitquitquit"
 	drainPath = "/drain"
+	// drainStatusPath reports the progress of the drain of the proxy, for preStop hooks to wait for it.
+	drainStatusPath = "/drain/status"
 	// KubeAppProberEnvName is the name of the command line flag for pilot agent to pass app prober config.
 	// The json encoded string to pass app HTTP probe information from injector(istioctl or webhook).
 	// For example, ISTIO_KUBE_APP_PROBERS='{"/app-health/httpbin/livez":{"httpGet":{"path": "/hello", "port": 8080}}.
@@ -78,6 +82,13 @@ const (
 	// This environment variable should never be set manually.
 	KubeAppProberEnvName = "ISTIO_KUBE_APP_PROBERS"
 
+	// CompositeAppProberEnvName is the name of the environment variable with the composite app prober config, declared
+	// by the users through the proxy config, e.g. the proxyMetadata of the proxy.istio.io/config annotation.
+	// For example, ISTIO_COMPOSITE_APP_PROBERS='{"/app-health/httpbin/readyz":{"upstreams":[{"host":"db.ns.svc.cluster.local","port":5432}]}}'
+	// indicates that httpbin container is ready when its readiness prober passes and Envoy has a healthy endpoint of
+	// the db service.
+	CompositeAppProberEnvName = "ISTIO_COMPOSITE_APP_PROBERS"
+
 	localHostIPv4     = "127.0.0.1"
 	localHostIPv6     = "::1"
 	maxRespBodyLength = 10 * 1 << 10
@@ -144,6 +155,7 @@ type Options struct {
 	PodIP string
 	// KubeAppProbers is a json with Kubernetes application prober config encoded.
 	KubeAppProbers      string
+	CompositeProbers    string
 	NodeType            model.NodeType
 	StatusPort          uint16
 	AdminPort           uint16
@@ -152,6 +164,7 @@ type Options struct {
 	EnvoyPrometheusPort int
 	Context             context.Context
 	FetchDNS            func() *dnsProto.NameTable
+	FetchDNSLog         func() []dnsClient.QueryRecord
 	NoEnvoy             bool
 	GRPCBootstrap       string
 	EnableProfiling     bool
@@ -160,6 +173,9 @@ type Options struct {
 	Shutdown           context.CancelCauseFunc
 	TriggerDrain       func()
 	DisableDrain       func()
+	FetchDrainStatus   func() *envoy.DrainStatus
+	FetchCrashes       func() ([]envoy.CrashBundle, error)
+	OpenCrashFile      func(bundle, file string) (*os.File, error)
 }
 
 // Server provides an endpoint for handling status probes.
@@ -170,10 +186,13 @@ type Server struct {
 	appProbersDestination string
 	appKubeProbers        KubeAppProbers
 	appProbeClient        map[string]*http.Client
+	compositeProbers      CompositeProbers
+	adminHost             string
 	statusPort            uint16
 	lastProbeSuccessful   bool
 	envoyStatsPort        int
 	fetchDNS              func() *dnsProto.NameTable
+	fetchDNSLog           func() []dnsClient.QueryRecord
 	upstreamLocalAddress  *net.TCPAddr
 	config                Options
 	http                  *http.Client
@@ -182,6 +201,9 @@ type Server struct {
 	shutdown              context.CancelCauseFunc
 	drain                 func()
 	disableDrain          func()
+	fetchDrainStatus      func() *envoy.DrainStatus
+	fetchCrashes          func() ([]envoy.CrashBundle, error)
+	openCrashFile         func(bundle, file string) (*os.File, error)
 
 	// maxAppBodyBytes caps the per-target app metrics body to bound agent memory
 	// across N concurrent scrape targets. Reads above the cap are dropped as failures
@@ -249,8 +271,11 @@ func NewServer(config Options) (*Server, error) {
 		ready:                 probes,
 		http:                  &http.Client{},
 		appProbersDestination: config.PodIP,
+		appProbeClient:        map[string]*http.Client{},
+		adminHost:             localhost,
 		envoyStatsPort:        config.EnvoyPrometheusPort,
 		fetchDNS:              config.FetchDNS,
+		fetchDNSLog:           config.FetchDNSLog,
 		upstreamLocalAddress:  upstreamLocalAddress,
 		config:                config,
 		enableProfiling:       config.EnableProfiling,
@@ -258,6 +283,9 @@ func NewServer(config Options) (*Server, error) {
 		shutdown:              config.Shutdown,
 		drain:                 config.TriggerDrain,
 		disableDrain:          config.DisableDrain,
+		fetchDrainStatus:      config.FetchDrainStatus,
+		fetchCrashes:          config.FetchCrashes,
+		openCrashFile:         config.OpenCrashFile,
 		maxAppBodyBytes:       defaultMaxAppMetricsBodyBytes,
 	}
 	if LegacyLocalhostProbeDestination.Get() {
@@ -324,6 +352,12 @@ func NewServer(config Options) (*Server, error) {
 		}
 	}
 
+	if config.CompositeProbers != "" {
+		if err := s.initCompositeProbers(config.CompositeProbers); err != nil {
+			return nil, err
+		}
+	}
+
 	if config.KubeAppProbers == "" {
 		return s, nil
 	}
@@ -331,7 +365,6 @@ func NewServer(config Options) (*Server, error) {
 		return nil, fmt.Errorf("failed to decode app prober err = %v, json string = %v", err, config.KubeAppProbers)
 	}
 
-	s.appProbeClient = make(map[string]*http.Client, len(s.appKubeProbers))
 	// Validate the map key matching the regex pattern.
 	for path, prober := range s.appKubeProbers {
 		err := validateAppKubeProber(path, prober)
@@ -339,34 +372,42 @@ func NewServer(config Options) (*Server, error) {
 			return nil, err
 		}
 		if prober.HTTPGet != nil {
-			d := ProbeDialer()
-			d.LocalAddr = s.upstreamLocalAddress
-			// nolint: gosec
-			// This is matching Kubernetes. It is a reasonable usage of this, as it is just a health check over localhost.
-			transport, err := setTransportDefaults(&http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-				DialContext:     d.DialContext,
-				// https://github.com/kubernetes/kubernetes/blob/0153febd9f0098d4b8d0d484927710eaf899ef40/pkg/probe/http/http.go#L55
-				// Match Kubernetes logic. This also ensures idle timeouts do not trigger probe failures
-				DisableKeepAlives: !ProbeKeepaliveConnections,
-			})
+			client, err := s.newAppProbeClient(prober)
 			if err != nil {
 				return nil, err
 			}
-			// Construct a http client and cache it in order to reuse the connection.
-			s.appProbeClient[path] = &http.Client{
-				Timeout: time.Duration(prober.TimeoutSeconds) * time.Second,
-				// We skip the verification since kubelet skips the verification for HTTPS prober as well
-				// https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-probes/#configure-probes
-				Transport:     transport,
-				CheckRedirect: redirectChecker(),
-			}
+			s.appProbeClient[path] = client
 		}
 	}
 
 	return s, nil
 }
 
+// newAppProbeClient constructs the http client of an HTTP app prober, cached in order to reuse the connection.
+func (s *Server) newAppProbeClient(prober *Prober) (*http.Client, error) {
+	d := ProbeDialer()
+	d.LocalAddr = s.upstreamLocalAddress
+	// nolint: gosec
+	// This is matching Kubernetes. It is a reasonable usage of this, as it is just a health check over localhost.
+	transport, err := setTransportDefaults(&http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		DialContext:     d.DialContext,
+		// https://github.com/kubernetes/kubernetes/blob/0153febd9f0098d4b8d0d484927710eaf899ef40/pkg/probe/http/http.go#L55
+		// Match Kubernetes logic. This also ensures idle timeouts do not trigger probe failures
+		DisableKeepAlives: !ProbeKeepaliveConnections,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Timeout: time.Duration(prober.TimeoutSeconds) * time.Second,
+		// We skip the verification since kubelet skips the verification for HTTPS prober as well
+		// https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-probes/#configure-probes
+		Transport:     transport,
+		CheckRedirect: redirectChecker(),
+	}, nil
+}
+
 // Copies logic from https://github.com/kubernetes/kubernetes/blob/b152001f459/pkg/probe/http/http.go#L129-L130
 func isRedirect(code int) bool {
 	return code >= http.StatusMultipleChoices && code < http.StatusBadRequest
@@ -441,6 +482,7 @@ func (s *Server) Run(ctx context.Context) {
 	mux.HandleFunc(`/stats/prometheus`, s.handleStats)
 	mux.HandleFunc(quitPath, s.handleQuit)
 	mux.HandleFunc(drainPath, s.handleDrain)
+	mux.HandleFunc(drainStatusPath, s.handleDrainStatus)
 	mux.HandleFunc("/app-health/", s.handleAppProbe)
 	mux.HandleFunc("/app-lifecycle/", s.handleAppProbe)
 
@@ -453,6 +495,9 @@ func (s *Server) Run(ctx context.Context) {
 		mux.HandleFunc("/debug/pprof/trace", s.handlePprofTrace)
 	}
 	mux.HandleFunc("/debug/ndsz", s.handleNdsz)
+	mux.HandleFunc("/debug/dnsz", s.handleDnsz)
+	mux.HandleFunc("/debug/crashz", s.handleCrashz)
+	mux.HandleFunc("/debug/crashz/", s.handleCrashz)
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.statusPort))
 	if err != nil {
@@ -1085,6 +1130,35 @@ func (s *Server) handleDrain(w http.ResponseWriter, r *http.Request) {
 	s.drain()
 }
 
+// handleDrainStatus reports the progress of the drain of the proxy, responding 200 once it is drained and 503 until
+// then.
+func (s *Server) handleDrainStatus(w http.ResponseWriter, r *http.Request) {
+	if !istioNetUtil.IsRequestFromLocalhost(r) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	var status *envoy.DrainStatus
+	if s.fetchDrainStatus != nil {
+		status = s.fetchDrainStatus()
+	}
+	if status == nil {
+		http.Error(w, "proxy drain status is not available", http.StatusNotFound)
+		return
+	}
+	b, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if status.State == envoy.DrainComplete {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(b)
+}
+
 func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	// Validate the request first.
 	path := req.URL.Path
@@ -1092,13 +1166,21 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 		path = "/" + req.URL.Path
 	}
 	prober, exists := s.appKubeProbers[path]
+	if composite, ok := s.compositeProbers[path]; ok {
+		s.handleCompositeProbe(w, req, path, prober, composite)
+		return
+	}
 	if !exists {
 		log.Errorf("Prober does not exists url %v", path)
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte(fmt.Sprintf("app prober config does not exists for %v", path)))
 		return
 	}
+	s.runAppProbe(w, req, prober, path)
+}
 
+// runAppProbe probes the app, path being the key of the http client of HTTP probers.
+func (s *Server) runAppProbe(w http.ResponseWriter, req *http.Request, prober *Prober, path string) {
 	switch {
 	case prober.HTTPGet != nil:
 		s.handleAppProbeHTTPGet(w, req, prober, path)
@@ -1277,6 +1359,91 @@ func (s *Server) handleNdsz(w http.ResponseWriter, r *http.Request) {
 	writeJSONProto(w, nametable)
 }
 
+// handleDnsz lists the DNS queries recently answered by the DNS proxy.
+func (s *Server) handleDnsz(w http.ResponseWriter, r *http.Request) {
+	if !istioNetUtil.IsRequestFromLocalhost(r) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	var queries []dnsClient.QueryRecord
+	if s.fetchDNSLog != nil {
+		queries = s.fetchDNSLog()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if queries == nil {
+		// The DNS proxy or its query history is disabled.
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`[]`))
+		return
+	}
+	b, err := json.MarshalIndent(queries, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+// handleCrashz lists the crash diagnostics bundles of Envoy, including those written before the container restarted.
+// The files of the bundles are served on /debug/crashz/<bundle>/<file>.
+func (s *Server) handleCrashz(w http.ResponseWriter, r *http.Request) {
+	if !istioNetUtil.IsRequestFromLocalhost(r) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	if file := strings.Trim(strings.TrimPrefix(r.URL.Path, "/debug/crashz"), "/"); file != "" {
+		s.handleCrashFile(w, r, file)
+		return
+	}
+	var bundles []envoy.CrashBundle
+	if s.fetchCrashes != nil {
+		var err error
+		bundles, err = s.fetchCrashes()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if bundles == nil {
+		// The crash diagnostics are disabled.
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`[]`))
+		return
+	}
+	b, err := json.MarshalIndent(bundles, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+// handleCrashFile streams a file of a crash diagnostics bundle of Envoy, named <bundle>/<file>.
+func (s *Server) handleCrashFile(w http.ResponseWriter, r *http.Request, name string) {
+	bundle, file, ok := strings.Cut(name, "/")
+	if !ok || s.openCrashFile == nil {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := s.openCrashFile(bundle, file)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, file, info.ModTime(), f)
+}
+
 // writeJSONProto writes a protobuf to a json payload, handling content type, marshaling, and errors
 func writeJSONProto(w http.ResponseWriter, obj proto.Message) {
 	w.Header().Set("Content-Type", "application/json")
@@ -25,6 +25,8 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -45,6 +47,8 @@ import (
 
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/testserver"
+	dnsClient "istio.io/istio/pkg/dns/client"
+	"istio.io/istio/pkg/envoy"
 	"istio.io/istio/pkg/kube/apimirror"
 	"istio.io/istio/pkg/lazy"
 	"istio.io/istio/pkg/log"
@@ -2128,6 +2132,218 @@ func TestHandleQuit(t *testing.T) {
 	}
 }
 
+func TestHandleDnsz(t *testing.T) {
+	queries := []dnsClient.QueryRecord{{Name: "www.example.com.", Type: "A", Source: "upstream", Rcode: "NOERROR"}}
+	tests := []struct {
+		name       string
+		fetch      func() []dnsClient.QueryRecord
+		remoteAddr string
+		expected   int
+		want       []dnsClient.QueryRecord
+	}{
+		{
+			name:       "lists the recent queries",
+			fetch:      func() []dnsClient.QueryRecord { return queries },
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusOK,
+			want:       queries,
+		},
+		{
+			name:       "history disabled",
+			fetch:      func() []dnsClient.QueryRecord { return nil },
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusNotFound,
+			want:       []dnsClient.QueryRecord{},
+		},
+		{
+			name:       "dns proxy disabled",
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusNotFound,
+			want:       []dnsClient.QueryRecord{},
+		},
+		{
+			name:     "should require localhost",
+			fetch:    func() []dnsClient.QueryRecord { return queries },
+			expected: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewTestServer(t, Options{FetchDNSLog: tt.fetch})
+			req := httptest.NewRequest(http.MethodGet, "/debug/dnsz", nil)
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr + ":" + fmt.Sprint(s.statusPort)
+			}
+			resp := httptest.NewRecorder()
+			s.handleDnsz(resp, req)
+			assert.Equal(t, resp.Code, tt.expected)
+			if tt.want != nil {
+				var got []dnsClient.QueryRecord
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				assert.Equal(t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleDrainStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     *envoy.DrainStatus
+		remoteAddr string
+		expected   int
+	}{
+		{
+			name:       "not started",
+			status:     &envoy.DrainStatus{Mode: envoy.DrainModeRequests, State: envoy.DrainNotStarted},
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusServiceUnavailable,
+		},
+		{
+			name: "draining",
+			status: &envoy.DrainStatus{
+				Mode: envoy.DrainModeRequests, State: envoy.DrainInProgress,
+				Listeners: map[string]uint64{"inbound_0.0.0.0_8080": 2},
+			},
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusServiceUnavailable,
+		},
+		{
+			name:       "complete",
+			status:     &envoy.DrainStatus{Mode: envoy.DrainModeRequests, State: envoy.DrainComplete},
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusOK,
+		},
+		{
+			name:       "envoy disabled",
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusNotFound,
+		},
+		{
+			name:     "should require localhost",
+			status:   &envoy.DrainStatus{Mode: envoy.DrainModeRequests, State: envoy.DrainComplete},
+			expected: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewTestServer(t, Options{FetchDrainStatus: func() *envoy.DrainStatus { return tt.status }})
+			req := httptest.NewRequest(http.MethodGet, drainStatusPath, nil)
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr + ":" + fmt.Sprint(s.statusPort)
+			}
+			resp := httptest.NewRecorder()
+			s.handleDrainStatus(resp, req)
+			assert.Equal(t, resp.Code, tt.expected)
+			if tt.expected != http.StatusForbidden && tt.status != nil {
+				var got envoy.DrainStatus
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				assert.Equal(t, got, *tt.status)
+			}
+		})
+	}
+}
+
+func TestHandleCrashz(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "crash-1"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "crash-1", "stderr.log"), []byte("crashed\n"), 0o644))
+	open := func(bundle, file string) (*os.File, error) {
+		return os.Open(filepath.Join(dir, bundle, file))
+	}
+	bundles := []envoy.CrashBundle{{Name: "crash-1", Files: []string{"stderr.log"}}}
+	tests := []struct {
+		name       string
+		path       string
+		fetch      func() ([]envoy.CrashBundle, error)
+		remoteAddr string
+		expected   int
+		want       []envoy.CrashBundle
+		wantBody   string
+	}{
+		{
+			name:       "lists the crashes",
+			fetch:      func() ([]envoy.CrashBundle, error) { return bundles, nil },
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusOK,
+			want:       bundles,
+		},
+		{
+			name:       "no crash",
+			fetch:      func() ([]envoy.CrashBundle, error) { return []envoy.CrashBundle{}, nil },
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusOK,
+			want:       []envoy.CrashBundle{},
+		},
+		{
+			name:       "diagnostics disabled",
+			fetch:      func() ([]envoy.CrashBundle, error) { return nil, nil },
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusNotFound,
+			want:       []envoy.CrashBundle{},
+		},
+		{
+			name:       "read error",
+			fetch:      func() ([]envoy.CrashBundle, error) { return nil, errors.New("permission denied") },
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusInternalServerError,
+		},
+		{
+			name:       "streams a file",
+			path:       "/debug/crashz/crash-1/stderr.log",
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusOK,
+			wantBody:   "crashed\n",
+		},
+		{
+			name:       "missing file",
+			path:       "/debug/crashz/crash-1/config_dump.json",
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusNotFound,
+		},
+		{
+			name:       "file without bundle",
+			path:       "/debug/crashz/stderr.log",
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusNotFound,
+		},
+		{
+			name:     "should require localhost",
+			fetch:    func() ([]envoy.CrashBundle, error) { return bundles, nil },
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "should require localhost for files",
+			path:     "/debug/crashz/crash-1/stderr.log",
+			expected: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewTestServer(t, Options{FetchCrashes: tt.fetch, OpenCrashFile: open})
+			path := tt.path
+			if path == "" {
+				path = "/debug/crashz"
+			}
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr + ":" + fmt.Sprint(s.statusPort)
+			}
+			resp := httptest.NewRecorder()
+			s.handleCrashz(resp, req)
+			assert.Equal(t, resp.Code, tt.expected)
+			if tt.want != nil {
+				var got []envoy.CrashBundle
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+				assert.Equal(t, got, tt.want)
+			}
+			if tt.wantBody != "" {
+				assert.Equal(t, resp.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
 func TestAdditionalProbes(t *testing.T) {
 	rp := readyProbe{}
 	urp := unreadyProbe{}
@@ -18,6 +18,8 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -36,6 +38,8 @@ const (
 	statWorkersStarted = "listener_manager.workers_started"
 	readyStatsRegex    = "^(server\\.state|listener_manager\\.workers_started)"
 	updateStatsRegex   = "^(cluster_manager\\.cds|listener_manager\\.lds)\\.(update_success|update_rejected)$"
+
+	statMembershipHealthy = "membership_healthy"
 )
 
 var readinessTimeout = time.Second * 3 // Default Readiness timeout. It is set the same in helm charts.
@@ -127,6 +131,52 @@ func GetUpdateStatusStats(localHostAddr string, adminPort uint16) (*Stats, error
 	return s, nil
 }
 
+// GetClusterHealthyMembers returns the number of healthy members of the Envoy clusters, by checking their
+// "membership_healthy" stat. The clusters unknown to Envoy are missing from the result. The stat is rejected by the
+// stats matcher of the bootstrap unless the agent enables the cluster health stats.
+func GetClusterHealthyMembers(localHostAddr string, adminPort uint16, clusters []string) (map[string]uint64, error) {
+	// If the localHostAddr was not set, we use 'localhost' to void empty host in URL.
+	if localHostAddr == "" {
+		localHostAddr = "localhost"
+	}
+
+	names := make([]string, 0, len(clusters))
+	for _, c := range clusters {
+		names = append(names, regexp.QuoteMeta(c))
+	}
+	filter := fmt.Sprintf("^cluster\\.(%s)\\.%s$", strings.Join(names, "|"), statMembershipHealthy)
+	hostPort := net.JoinHostPort(localHostAddr, strconv.Itoa(int(adminPort)))
+	stats, err := http.DoHTTPGetWithTimeout(
+		fmt.Sprintf("http://%s/stats?filter=%s", hostPort, url.QueryEscape(filter)), readinessTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	healthy := make(map[string]uint64, len(clusters))
+	for stats.Len() > 0 {
+		line, _ := stats.ReadString('\n')
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ": ")
+		if !ok {
+			continue
+		}
+		// Cluster names contain dots, e.g. outbound|80||httpbin.default.svc.cluster.local.
+		cluster, ok := strings.CutPrefix(name, "cluster.")
+		if !ok {
+			continue
+		}
+		cluster, ok = strings.CutSuffix(cluster, "."+statMembershipHealthy)
+		if !ok {
+			continue
+		}
+		val, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing Envoy stat %s (error: %s) line: %s", name, err.Error(), line)
+		}
+		healthy[cluster] = val
+	}
+	return healthy, nil
+}
+
 func parseStats(input *bytes.Buffer, stats []*stat) (err error) {
 	for input.Len() > 0 {
 		line, _ := input.ReadString('\n')
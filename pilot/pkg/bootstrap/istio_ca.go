@@ -35,6 +35,8 @@ import (
 	securityModel "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/env"
+	kubelib "istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/watcher/configmapwatcher"
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/util/sets"
@@ -42,6 +44,7 @@ import (
 	"istio.io/istio/security/pkg/pki/ca"
 	"istio.io/istio/security/pkg/pki/ra"
 	caserver "istio.io/istio/security/pkg/server/ca"
+	"istio.io/istio/security/pkg/server/ca/audit"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
 	"istio.io/istio/security/pkg/util"
 )
@@ -146,6 +149,30 @@ func (s *Server) initCAServer(ca caserver.CertificateAuthority, opts *caOptions)
 	if startErr != nil {
 		log.Fatalf("failed to create istio ca server: %v", startErr)
 	}
+	if features.CACSRPolicyConfigMap != "" && s.kubeClient != nil {
+		policy := caserver.NewDynamicCSRPolicy()
+		watcher := configmapwatcher.NewController(s.kubeClient, opts.Namespace, features.CACSRPolicyConfigMap, policy.UpdateFromConfigMap)
+		s.addStartFunc("ca csr policy", func(stop <-chan struct{}) error {
+			go watcher.Run(stop)
+			// Do not serve certificates until the policy has been loaded, to avoid issuing certs the policy would deny.
+			kubelib.WaitForCacheSync("ca csr policy", stop, watcher.HasSynced)
+			return nil
+		})
+		caServer.CSRAuthorizer = policy
+		caServer.DefaultCertTTL = workloadCertTTL.Get()
+	}
+	if features.CAAuditLogFile != "" {
+		auditor := audit.NewFileSink(features.CAAuditLogFile, features.CAAuditLogMaxSizeMB, features.CAAuditLogMaxBackups)
+		s.addStartFunc("ca audit log", func(stop <-chan struct{}) error {
+			go func() {
+				<-stop
+				_ = auditor.Close()
+			}()
+			return nil
+		})
+		caServer.Auditor = auditor
+	}
+	caServer.MeshHolder = s.environment.Watcher
 	s.caServer = caServer
 }
 
@@ -42,6 +42,7 @@ import (
 	"istio.io/istio/pilot/pkg/config/kube/agentgateway"
 	"istio.io/istio/pilot/pkg/controllers/ipallocate"
 	"istio.io/istio/pilot/pkg/controllers/untaint"
+	"istio.io/istio/pilot/pkg/controllers/wasmvalidation"
 	kubecredentials "istio.io/istio/pilot/pkg/credentials/kube"
 	"istio.io/istio/pilot/pkg/features"
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
@@ -377,6 +378,9 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		if err := s.initConfigValidation(args); err != nil {
 			return nil, fmt.Errorf("error initializing config validator: %v", err)
 		}
+		if features.MultiRootMesh && features.EnableSpiffeBundleEndpoint {
+			s.httpsMux.Handle(tb.BundleEndpointPath, s.workloadTrustBundle.BundleEndpointHandler())
+		}
 	}
 
 	// This should be called only after controllers are initialized.
@@ -400,6 +404,11 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		}
 		authenticators = append(authenticators, jwtAuthn)
 	}
+	platformAuthns, err := initPlatformAuthenticators(s.environment.Watcher)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing platform authenticators: %v", err)
+	}
+	authenticators = append(authenticators, platformAuthns...)
 	// The k8s JWT authenticator requires the multicluster registry to be initialized,
 	// so we build it later.
 	if s.kubeClient != nil {
@@ -455,6 +464,41 @@ func initOIDC(args *PilotArgs, meshWatcher mesh.Watcher) (security.Authenticator
 	return jwtAuthn, nil
 }
 
+// initPlatformAuthenticators creates the authenticators for VMs presenting cloud platform credentials.
+func initPlatformAuthenticators(meshWatcher mesh.Watcher) ([]security.Authenticator, error) {
+	if !features.CAAWSAuthentication && features.CAAzureTenantID == "" {
+		return nil, nil
+	}
+	if features.CAPlatformIdentityMapping == "" {
+		return nil, fmt.Errorf("CA_PLATFORM_IDENTITY_MAPPING is required for platform authentication")
+	}
+	mapping, err := authenticate.LoadPlatformIdentityMapping(features.CAPlatformIdentityMapping)
+	if err != nil {
+		return nil, err
+	}
+	var authenticators []security.Authenticator
+	if features.CAAWSAuthentication {
+		audience := features.CAAWSAudience
+		if audience == "" {
+			audience = meshWatcher.Mesh().GetTrustDomain()
+		}
+		log.Infof("Istiod authenticating AWS instance roles for audience %s", audience)
+		authenticators = append(authenticators, authenticate.NewAWSAuthenticator(audience, mapping, meshWatcher))
+	}
+	if features.CAAzureTenantID != "" {
+		if features.CAAzureAudience == "" {
+			return nil, fmt.Errorf("CA_AZURE_AUDIENCE is required for Azure authentication")
+		}
+		log.Infof("Istiod authenticating Azure managed identities of tenant %s", features.CAAzureTenantID)
+		azureAuthn, err := authenticate.NewAzureAuthenticator(features.CAAzureTenantID, features.CAAzureAudience, mapping, meshWatcher)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the Azure authenticator: %v", err)
+		}
+		authenticators = append(authenticators, azureAuthn)
+	}
+	return authenticators, nil
+}
+
 func getClusterID(args *PilotArgs) cluster.ID {
 	clusterID := args.RegistryOptions.KubeOptions.ClusterID
 	if clusterID == "" {
@@ -1187,6 +1231,10 @@ func (s *Server) initControllers(args *PilotArgs) error {
 		s.initIPAutoallocateController(args)
 	}
 
+	if features.EnableWasmModuleValidation {
+		s.initWasmValidationController(args)
+	}
+
 	s.initKubeOptions(args)
 
 	if err := s.initConfigController(args); err != nil {
@@ -1228,6 +1276,25 @@ func (s *Server) initIPAutoallocateController(args *PilotArgs) {
 	})
 }
 
+func (s *Server) initWasmValidationController(args *PilotArgs) {
+	if s.kubeClient == nil {
+		return
+	}
+	s.addStartFunc("wasm validation controller", func(stop <-chan struct{}) error {
+		go leaderelection.
+			NewLeaderElection(args.Namespace, args.PodName, leaderelection.WasmValidationController, args.Revision, s.kubeClient).
+			AddRunFunction(func(leaderStop <-chan struct{}) {
+				controller, err := wasmvalidation.NewController(s.kubeClient)
+				if err != nil {
+					log.Errorf("failed to start the wasm validation controller: %v", err)
+					return
+				}
+				controller.Run(leaderStop)
+			}).Run(stop)
+		return nil
+	})
+}
+
 func (s *Server) initMulticluster(args *PilotArgs) {
 	if s.kubeClient == nil {
 		return
@@ -69,6 +69,7 @@ func translateWasmPlugin(cfg config.Config) *config.Config {
 			GroupVersionKind:  gvk.TrafficExtension,
 			Name:              cfg.Name + syntheticMarker,
 			Namespace:         cfg.Namespace,
+			Annotations:       cfg.Annotations,
 			ResourceVersion:   cfg.ResourceVersion,
 			CreationTimestamp: cfg.CreationTimestamp,
 		},
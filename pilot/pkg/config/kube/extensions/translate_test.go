@@ -59,6 +59,7 @@ func TestTranslateWasmPlugin(t *testing.T) {
 				Meta: config.Meta{
 					Name:              "my-plugin",
 					Namespace:         "my-ns",
+					Annotations:       map[string]string{"extensions.istio.io/required-signers": "[]"},
 					ResourceVersion:   "42",
 					CreationTimestamp: ts,
 				},
@@ -69,6 +70,7 @@ func TestTranslateWasmPlugin(t *testing.T) {
 					GroupVersionKind:  gvk.TrafficExtension,
 					Name:              "my-plugin" + syntheticMarker,
 					Namespace:         "my-ns",
+					Annotations:       map[string]string{"extensions.istio.io/required-signers": "[]"},
 					ResourceVersion:   "42",
 					CreationTimestamp: ts,
 				},
@@ -0,0 +1,268 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasmvalidation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/api/meta/v1alpha1"
+	extensionsv1alpha1 "istio.io/client-go/pkg/apis/extensions/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pkg/config/schema/gvr"
+	kubelib "istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/kube/kclient"
+	"istio.io/istio/pkg/kube/kubetypes"
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/wasm"
+)
+
+var log = istiolog.RegisterScope("wasm-validation", "WasmPlugin module validation controller")
+
+const (
+	controllerName = "WasmPlugin module validator"
+
+	// ConditionType is the WasmPlugin status condition reporting whether the proxy can load the module.
+	ConditionType = "ModuleValidated"
+	// ReasonValidated is set when the module passed the validation.
+	ReasonValidated = "Validated"
+	// ReasonInvalidModule is set when the proxy would reject the module.
+	ReasonInvalidModule = "InvalidModule"
+	// ReasonFetchFailed is set when the module could not be fetched, and its validity is unknown.
+	ReasonFetchFailed = "FetchFailed"
+	// ReasonFetchNotAllowed is set when istiod is not allowed to fetch the module, and its validity is unknown.
+	ReasonFetchNotAllowed = "FetchNotAllowed"
+
+	statusUnknown = "Unknown"
+)
+
+// Controller fetches the modules of WasmPlugins and statically checks that the proxy can load them, reporting the
+// result in the ModuleValidated condition of the WasmPlugin status. This surfaces bad modules before they are
+// rejected by the proxies. Modules are validated once per generation of the WasmPlugin, so a tag updated in the
+// registry is not validated again until the WasmPlugin changes.
+//
+// As WasmPlugins may be written by namespace owners, istiod only fetches the modules of the hosts allowed by
+// PILOT_WASM_MODULE_VALIDATION_ALLOWED_HOSTS, over oci, http or https, so that it cannot be used to reach arbitrary
+// hosts of its network.
+type Controller struct {
+	wasmPlugins  kclient.Informer[*extensionsv1alpha1.WasmPlugin]
+	writer       kclient.Writer[*extensionsv1alpha1.WasmPlugin]
+	client       kubelib.Client
+	cacheDir     string
+	cache        *wasm.LocalFileCache
+	allowedHosts []string
+	queue        controllers.Queue
+}
+
+func NewController(c kubelib.Client) (*Controller, error) {
+	dir, err := os.MkdirTemp("", "istiod-wasm-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Wasm module cache directory: %v", err)
+	}
+	wasmPlugins := kclient.NewDelayedInformer[*extensionsv1alpha1.WasmPlugin](c, gvr.WasmPlugin, kubetypes.StandardInformer, kclient.Filter{
+		ObjectFilter: c.ObjectFilter(),
+	})
+	controller := &Controller{
+		wasmPlugins:  wasmPlugins,
+		writer:       kclient.NewWriteClient[*extensionsv1alpha1.WasmPlugin](c),
+		client:       c,
+		cacheDir:     dir,
+		allowedHosts: features.WasmModuleValidationAllowedHosts,
+		cache: wasm.NewLocalFileCache(dir, wasm.Options{
+			PurgeInterval:         wasm.DefaultPurgeInterval,
+			ModuleExpiry:          wasm.DefaultModuleExpiry,
+			HTTPRequestTimeout:    wasm.DefaultHTTPRequestTimeout,
+			HTTPRequestMaxRetries: wasm.DefaultHTTPRequestMaxRetries,
+		}),
+	}
+	controller.queue = controllers.NewQueue(controllerName, controllers.WithReconciler(controller.reconcile), controllers.WithMaxAttempts(5))
+	wasmPlugins.AddEventHandler(controllers.ObjectHandler(controller.queue.AddObject))
+	return controller, nil
+}
+
+// HasSynced returns true when the controller has processed the initial WasmPlugins.
+func (c *Controller) HasSynced() bool {
+	return c.queue.HasSynced()
+}
+
+func (c *Controller) Run(stop <-chan struct{}) {
+	log.Debugf("starting %s controller", controllerName)
+	kubelib.WaitForCacheSync(controllerName, stop, c.wasmPlugins.HasSynced)
+	c.queue.Run(stop)
+	c.wasmPlugins.ShutdownHandlers()
+	c.cache.Cleanup()
+	_ = os.RemoveAll(c.cacheDir)
+}
+
+func (c *Controller) reconcile(key types.NamespacedName) error {
+	log := log.WithLabels("wasmplugin", key)
+	plugin := c.wasmPlugins.Get(key.Name, key.Namespace)
+	if plugin == nil || plugin.Spec.Url == "" {
+		return nil
+	}
+	current := status.GetCondition(plugin.Status.Conditions, ConditionType)
+	// Modules which could not be fetched are retried, until the queue gives up.
+	if current != nil && current.ObservedGeneration == plugin.Generation && current.Status != statusUnknown {
+		return nil
+	}
+
+	condition, fetchErr := c.validate(plugin)
+	condition.Type = ConditionType
+	condition.ObservedGeneration = plugin.Generation
+	if current == nil || !sameCondition(current, condition) {
+		log.Debugf("module validation: %s: %s", condition.Reason, condition.Message)
+		condition.LastTransitionTime = timestamppb.Now()
+		updated := plugin.DeepCopy()
+		updated.Status.Conditions = slices.FilterInPlace(updated.Status.Conditions, func(c *v1alpha1.IstioCondition) bool {
+			return c.Type != ConditionType
+		})
+		updated.Status.Conditions = append(updated.Status.Conditions, condition)
+		if _, err := c.writer.UpdateStatus(updated); err != nil {
+			return err
+		}
+	}
+	return fetchErr
+}
+
+// validate fetches and checks the module of the plugin. The returned error is only set if the module could not be
+// fetched, for the validation to be retried.
+func (c *Controller) validate(plugin *extensionsv1alpha1.WasmPlugin) (*v1alpha1.IstioCondition, error) {
+	u, err := url.Parse(plugin.Spec.Url)
+	if err != nil {
+		return &v1alpha1.IstioCondition{
+			Status:  status.StatusFalse,
+			Reason:  ReasonInvalidModule,
+			Message: fmt.Sprintf("invalid module url: %v", err),
+		}, nil
+	}
+	// When no scheme is given, default to oci:// like the proxy.
+	if u.Scheme == "" {
+		u.Scheme = "oci"
+	}
+	if err := c.allowed(u); err != nil {
+		return &v1alpha1.IstioCondition{
+			Status:  statusUnknown,
+			Reason:  ReasonFetchNotAllowed,
+			Message: fmt.Sprintf("the module is not fetched by istiod: %v", err),
+		}, nil
+	}
+	b, err := c.fetch(plugin, u)
+	if err != nil {
+		return &v1alpha1.IstioCondition{
+			Status:  statusUnknown,
+			Reason:  ReasonFetchFailed,
+			Message: fmt.Sprintf("failed to fetch the module: %v", err),
+		}, err
+	}
+	findings, err := wasm.CheckModule(b)
+	if err != nil {
+		return &v1alpha1.IstioCondition{
+			Status:  status.StatusFalse,
+			Reason:  ReasonInvalidModule,
+			Message: fmt.Sprintf("the module is not a valid Wasm binary: %v", err),
+		}, nil
+	}
+	messages := slices.Map(findings, wasm.ModuleFinding.String)
+	if wasm.HasErrors(findings) {
+		return &v1alpha1.IstioCondition{
+			Status:  status.StatusFalse,
+			Reason:  ReasonInvalidModule,
+			Message: strings.Join(messages, "; "),
+		}, nil
+	}
+	return &v1alpha1.IstioCondition{
+		Status:  status.StatusTrue,
+		Reason:  ReasonValidated,
+		Message: strings.Join(append([]string{"the module can be loaded by the proxy"}, messages...), "; "),
+	}, nil
+}
+
+// allowed returns an error if istiod is not allowed to fetch the module at u.
+func (c *Controller) allowed(u *url.URL) error {
+	switch u.Scheme {
+	case "oci", "http", "https":
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if host == "" {
+		// References without a scheme are parsed as a path starting with the registry.
+		host, _, _ = strings.Cut(u.Path, "/")
+	}
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, allowed := range c.allowedHosts {
+		if allowed == "*" || allowed == host || allowed == hostname ||
+			(strings.HasPrefix(allowed, "*.") && strings.HasSuffix(hostname, allowed[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not allowed by PILOT_WASM_MODULE_VALIDATION_ALLOWED_HOSTS", host)
+}
+
+func (c *Controller) fetch(plugin *extensionsv1alpha1.WasmPlugin, u *url.URL) ([]byte, error) {
+	var err error
+	opts := wasm.GetOptions{
+		Checksum:        plugin.Spec.Sha256,
+		ResourceName:    plugin.Namespace + "." + plugin.Name,
+		ResourceVersion: strconv.FormatInt(plugin.Generation, 10),
+		RequestTimeout:  wasm.DefaultHTTPRequestTimeout,
+		PullPolicy:      wasm.PullPolicyValues[plugin.Spec.ImagePullPolicy.String()],
+	}
+	if plugin.Spec.ImagePullSecret != "" {
+		if opts.PullSecret, err = c.pullSecret(plugin.Spec.ImagePullSecret, plugin.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	path, err := c.cache.Get(u.String(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (c *Controller) pullSecret(name, namespace string) ([]byte, error) {
+	secret, err := c.client.Kube().CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		return nil, fmt.Errorf("type of secret %v/%v is not %v", namespace, name, corev1.SecretTypeDockerConfigJson)
+	}
+	cred, found := secret.Data[corev1.DockerConfigJsonKey]
+	if !found {
+		return nil, fmt.Errorf("cannot find docker config at secret %v/%v", namespace, name)
+	}
+	return cred, nil
+}
+
+func sameCondition(a, b *v1alpha1.IstioCondition) bool {
+	return a.Status == b.Status && a.Reason == b.Reason && a.Message == b.Message && a.ObservedGeneration == b.ObservedGeneration
+}
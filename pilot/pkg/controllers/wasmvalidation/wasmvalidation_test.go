@@ -0,0 +1,141 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasmvalidation_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/extensions/v1alpha1"
+	metav1alpha1 "istio.io/api/meta/v1alpha1"
+	extensionsv1alpha1 "istio.io/client-go/pkg/apis/extensions/v1alpha1"
+	"istio.io/istio/pilot/pkg/controllers/wasmvalidation"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pkg/config/schema/gvr"
+	kubelib "istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient/clienttest"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// module returns a Wasm binary exporting the given functions and its memory.
+func module(functions ...string) []byte {
+	exports := []byte{byte(len(functions) + 1), 6}
+	exports = append(exports, "memory"...)
+	exports = append(exports, 2, 0)
+	for i, f := range functions {
+		exports = append(exports, byte(len(f)))
+		exports = append(exports, f...)
+		exports = append(exports, 0, byte(i))
+	}
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 7, byte(len(exports))}
+	return append(b, exports...)
+}
+
+func TestWasmValidation(t *testing.T) {
+	modules := map[string][]byte{
+		"/valid.wasm":   module("proxy_abi_version_0_2_1", "proxy_on_memory_allocate"),
+		"/invalid.wasm": module("proxy_on_memory_allocate"),
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := modules[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+	test.SetForTest(t, &features.WasmModuleValidationAllowedHosts, []string{"*.example.com", u.Hostname()})
+
+	stop := test.NewStop(t)
+	c := kubelib.NewFakeClient()
+	clienttest.MakeCRD(t, c, gvr.WasmPlugin)
+	plugins := clienttest.NewDirectClient[*extensionsv1alpha1.WasmPlugin, extensionsv1alpha1.WasmPlugin, *extensionsv1alpha1.WasmPluginList](t, c)
+	controller, err := wasmvalidation.NewController(c)
+	assert.NoError(t, err)
+	go controller.Run(stop)
+	c.RunAndWait(stop)
+	retry.UntilOrFail(t, controller.HasSynced, retry.Delay(time.Millisecond))
+
+	createURL := func(name, url, sha string) {
+		plugins.Create(&extensionsv1alpha1.WasmPlugin{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Generation: 1},
+			Spec:       v1alpha1.WasmPlugin{Url: url, Sha256: sha},
+		})
+	}
+	create := func(name, path, sha string) {
+		createURL(name, s.URL+path, sha)
+	}
+	condition := func(name string) *metav1alpha1.IstioCondition {
+		p := plugins.Get(name, "default")
+		if p == nil {
+			return nil
+		}
+		return status.GetCondition(p.Status.Conditions, wasmvalidation.ConditionType)
+	}
+	sum := sha256.Sum256(modules["/valid.wasm"])
+
+	create("valid", "/valid.wasm", hex.EncodeToString(sum[:]))
+	create("invalid", "/invalid.wasm", "")
+	create("missing", "/missing.wasm", "")
+	createURL("other-host", "http://metadata.internal/computeMetadata/v1/", "")
+	createURL("other-registry", "registry.internal/plugin:v1", "")
+	createURL("lookalike-host", "https://evil-example.com/plugin.wasm", "")
+	createURL("file", "file:///etc/passwd", "")
+	assertCondition := func(name string, generation int64, wantStatus, wantReason, wantMessage string) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			got := condition(name)
+			if got == nil || got.Status != wantStatus || got.Reason != wantReason || !strings.Contains(got.Message, wantMessage) {
+				return fmt.Errorf("unexpected condition %v", got)
+			}
+			if got.ObservedGeneration != generation {
+				return fmt.Errorf("unexpected observed generation %d", got.ObservedGeneration)
+			}
+			return nil
+		}, retry.Timeout(10*time.Second))
+	}
+	assertCondition("valid", 1, status.StatusTrue, wasmvalidation.ReasonValidated, "the module can be loaded by the proxy")
+	assertCondition("invalid", 1, status.StatusFalse, wasmvalidation.ReasonInvalidModule,
+		"Error: the module does not export a proxy-wasm ABI version")
+	assertCondition("missing", 1, "Unknown", wasmvalidation.ReasonFetchFailed, "failed to fetch the module")
+	assertCondition("other-host", 1, "Unknown", wasmvalidation.ReasonFetchNotAllowed, `host "metadata.internal" is not allowed`)
+	assertCondition("other-registry", 1, "Unknown", wasmvalidation.ReasonFetchNotAllowed, `host "registry.internal" is not allowed`)
+	assertCondition("lookalike-host", 1, "Unknown", wasmvalidation.ReasonFetchNotAllowed, `host "evil-example.com" is not allowed`)
+	assertCondition("file", 1, "Unknown", wasmvalidation.ReasonFetchNotAllowed, `unsupported scheme "file"`)
+
+	// A new generation of the plugin is validated again.
+	p := plugins.Get("valid", "default")
+	p.Generation = 2
+	p.Spec.Url = s.URL + "/invalid.wasm"
+	p.Spec.Sha256 = ""
+	plugins.Update(p)
+	assertCondition("valid", 2, status.StatusFalse, wasmvalidation.ReasonInvalidModule, "proxy-wasm ABI version")
+}
@@ -436,6 +436,31 @@ var (
 		"Maximum size of a Wasm binary in bytes. Default is 256MB.",
 	).Get()
 
+	EnableWasmModuleValidation = env.Register(
+		"PILOT_ENABLE_WASM_MODULE_VALIDATION",
+		false,
+		"If enabled, pilot will start a controller fetching the modules of WasmPlugins, checking that the proxy can load them, "+
+			"and reporting the result in the ModuleValidated condition of the WasmPlugin status. Istiod must be able to reach the "+
+			"registries and servers hosting the modules. Only the modules of PILOT_WASM_MODULE_VALIDATION_ALLOWED_HOSTS are fetched.",
+	).Get()
+
+	WasmModuleValidationAllowedHosts = func() []string {
+		v := env.Register(
+			"PILOT_WASM_MODULE_VALIDATION_ALLOWED_HOSTS",
+			"",
+			"Comma separated list of the registries and servers istiod fetches WasmPlugin modules from for their validation, "+
+				"for example ghcr.io,*.example.com. A host prefixed with *. matches its subdomains, and * matches all hosts. "+
+				"The modules of other hosts are not fetched, so that WasmPlugin authors cannot make istiod connect to arbitrary "+
+				"hosts. Redirects are followed, but link-local addresses and BLOCKED_CIDRS_IN_WASM_FETCH are always blocked.").Get()
+		var hosts []string
+		for _, host := range strings.Split(v, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+		return hosts
+	}()
+
 	SidecarPickBestServiceNamespace = env.Register(
 		"PILOT_SIDECAR_PICK_BEST_SERVICE_NAMESPACE",
 		true,
@@ -61,6 +61,67 @@ var (
 		return strings.Split(cidr, ",")
 	}()
 
+	CACSRPolicyConfigMap = env.Register(
+		"CA_CSR_POLICY_CONFIGMAP",
+		"",
+		"If set, the name of a ConfigMap in the Istiod namespace holding a CSR authorization policy under the key 'policy.yaml'. "+
+			"The policy can restrict the identities, TTL and key type of certificates issued per namespace and service account.",
+	).Get()
+
+	CAAuditLogFile = env.Register(
+		"CA_AUDIT_LOG_FILE",
+		"",
+		"If set, Istiod appends a JSON record of every certificate request handled by the CA to this file. "+
+			"Records can be queried with the /debug/caauditz endpoint or 'istioctl x ca audit'.",
+	).Get()
+
+	CAAuditLogMaxSizeMB = env.Register(
+		"CA_AUDIT_LOG_MAX_SIZE_MB",
+		100,
+		"The size in megabytes at which the CA audit log file is rotated.",
+	).Get()
+
+	CAAuditLogMaxBackups = env.Register(
+		"CA_AUDIT_LOG_MAX_BACKUPS",
+		5,
+		"The number of rotated CA audit log files to retain. Audit queries search the retained files, so this "+
+			"bounds the history they cover along with CA_AUDIT_LOG_MAX_SIZE_MB.",
+	).Get()
+
+	CAPlatformIdentityMapping = env.Register(
+		"CA_PLATFORM_IDENTITY_MAPPING",
+		"",
+		"If set, the path to a YAML file mapping cloud platform identities (AWS instances, Azure managed identities) "+
+			"to a namespace and service account. Required to enable the AWS and Azure authenticators.",
+	).Get()
+
+	CAAWSAuthentication = env.Register(
+		"CA_AWS_AUTHENTICATION",
+		false,
+		"If enabled, Istiod authenticates VMs using the AWSEC2 credential fetcher, by sending the STS GetCallerIdentity "+
+			"requests they signed with their instance role to STS.",
+	).Get()
+
+	CAAWSAudience = env.Register(
+		"CA_AWS_AUDIENCE",
+		"",
+		"The audience required in the STS GetCallerIdentity requests signed by AWS VMs. Defaults to the trust domain.",
+	).Get()
+
+	CAAzureTenantID = env.Register(
+		"CA_AZURE_TENANT_ID",
+		"",
+		"If set, the Azure tenant whose managed identity tokens are accepted. "+
+			"Enables authentication of VMs using the AzureVM credential fetcher.",
+	).Get()
+
+	CAAzureAudience = env.Register(
+		"CA_AZURE_AUDIENCE",
+		"",
+		"The audience required in Azure managed identity tokens, an application ID URI of the Azure tenant. "+
+			"Required with CA_AZURE_TENANT_ID.",
+	).Get()
+
 	CATrustedNodeAccounts = func() sets.Set[types.NamespacedName] {
 		accounts := env.Register(
 			"CA_TRUSTED_NODE_ACCOUNTS",
@@ -100,4 +161,9 @@ var (
 		"If enabled, allows multiple CUSTOM authorization providers per workload, "+
 			"enabling different authentication schemes (OAuth, LDAP, API keys) for different API paths. "+
 			"Each provider gets its own filter chain with provider-specific metadata matching.").Get()
+
+	EnableSpiffeBundleEndpoint = env.Register("PILOT_ENABLE_SPIFFE_BUNDLE_ENDPOINT", false,
+		"If enabled along with ISTIO_MULTIROOT_MESH, istiod serves the trust anchors of the mesh trust domain "+
+			"in SPIFFE bundle endpoint format at /spiffe-bundle on the HTTPS webhook port, "+
+			"so that other SPIFFE trust domains can federate with the mesh.").Get()
 )
@@ -59,6 +59,7 @@ const (
 	InferencePoolController     = "istio-gateway-inferencepool"
 	NodeUntaintController       = "istio-node-untaint"
 	IPAutoallocateController    = "istio-ip-autoallocate"
+	WasmValidationController    = "istio-wasm-validation"
 )
 
 // Leader election key prefix for remote istiod managed clusters
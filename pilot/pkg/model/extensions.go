@@ -47,6 +47,7 @@ const (
 	WasmSecretEnv          = pm.WasmSecretEnv
 	WasmPolicyEnv          = pm.WasmPolicyEnv
 	WasmResourceVersionEnv = pm.WasmResourceVersionEnv
+	WasmRequiredSignersEnv = pm.WasmRequiredSignersEnv
 
 	// TrafficExtensionResourceNamePrefix is the prefix of the resource name of TrafficExtension,
 	// preventing the name collision with other resources.
@@ -170,6 +171,8 @@ type TrafficExtensionWrapper struct {
 	Namespace       string
 	ResourceName    string
 	ResourceVersion string
+	// RequiredSigners is the JSON list of signers required on the Wasm image, see pm.WasmRequiredSignersAnnotation.
+	RequiredSigners string
 }
 
 // GetTargetRef returns nil; TrafficExtension uses GetTargetRefs (plural) only.
@@ -256,6 +259,9 @@ func (e *TrafficExtensionWrapper) buildWasmConfig() *wasmextensions.PluginConfig
 		Vm: buildVMConfig(datasource, e.ResourceVersion,
 			e.GetWasm().ImagePullSecret, e.GetWasm().ImagePullPolicy, e.GetWasm().VmConfig),
 	}
+	if e.RequiredSigners != "" {
+		wasmConfig.GetVmConfig().EnvironmentVariables.KeyValues[WasmRequiredSignersEnv] = e.RequiredSigners
+	}
 
 	switch e.GetWasm().FailStrategy {
 	case extensions.FailStrategy_FAIL_OPEN:
@@ -352,6 +358,7 @@ func convertToTrafficExtensionWrapper(originConfig config.Config) *TrafficExtens
 		ResourceName:     TrafficExtensionResourceNamePrefix + plugin.Namespace + "." + plugin.Name,
 		TrafficExtension: trafficExt,
 		ResourceVersion:  plugin.ResourceVersion,
+		RequiredSigners:  plugin.Annotations[pm.WasmRequiredSignersAnnotation],
 	}
 }
 
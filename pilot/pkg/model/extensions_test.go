@@ -27,6 +27,7 @@ import (
 	"istio.io/api/type/v1beta1"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pkg/config"
+	pm "istio.io/istio/pkg/model"
 	"istio.io/istio/pkg/test/util/assert"
 )
 
@@ -228,6 +229,32 @@ func TestFailurePolicy(t *testing.T) {
 	}
 }
 
+func TestRequiredSigners(t *testing.T) {
+	signers := `[{"issuer": "https://token.actions.githubusercontent.com", "subject": "https://github.com/acme/filters"}]`
+	in := config.Config{
+		Meta: config.Meta{
+			Name:        "signed",
+			Namespace:   "default",
+			Annotations: map[string]string{pm.WasmRequiredSignersAnnotation: signers},
+		},
+		Spec: &extensions.TrafficExtension{
+			FilterConfig: &extensions.TrafficExtension_Wasm{Wasm: &extensions.WasmConfig{
+				Url: "oci://example.com/wasm:v1",
+			}},
+		},
+	}
+	filter := convertToTrafficExtensionWrapper(in).BuildHTTPWasmFilter()
+	if got := filter.Config.GetVmConfig().EnvironmentVariables.KeyValues[WasmRequiredSignersEnv]; got != signers {
+		t.Errorf("got required signers %q, want %q", got, signers)
+	}
+
+	in.Annotations = nil
+	filter = convertToTrafficExtensionWrapper(in).BuildHTTPWasmFilter()
+	if _, found := filter.Config.GetVmConfig().EnvironmentVariables.KeyValues[WasmRequiredSignersEnv]; found {
+		t.Errorf("unexpected required signers for an extension without signature policy")
+	}
+}
+
 func TestConvertToTrafficExtensionWrapper(t *testing.T) {
 	cases := []struct {
 		desc       string
@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/monitoring"
 	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/istio/pkg/util/sets"
 )
@@ -247,8 +248,9 @@ func mergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 					continue
 				}
 
-				// Ignore BuiltinGatewaySecretTypeURI, as it is not referencing a Secret at all
-				if !strings.HasPrefix(cn, credentials.BuiltinGatewaySecretTypeURI) {
+				// Ignore BuiltinGatewaySecretTypeURI and workload identities, as they are not referencing a Secret at all
+				if !strings.HasPrefix(cn, credentials.BuiltinGatewaySecretTypeURI) &&
+					!strings.HasPrefix(cn, security.WorkloadIdentityResourcePrefix) {
 					rn := credentials.ToResourceName(cn)
 					parse, err := credentials.ParseResourceName(rn, proxy.VerifiedIdentity.Namespace, "", "")
 					if err == nil && configAndProxyAllowed && parse.Namespace == lookupNamespace {
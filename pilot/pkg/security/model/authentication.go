@@ -74,6 +74,13 @@ func ConstructSdsSecretConfigForCredential(name string, credentialSocketExist bo
 	if name == credentials.BuiltinGatewaySecretTypeURI+SdsCaSuffix {
 		return ConstructSdsSecretConfig(SDSRootResourceName)
 	}
+	// Additional workload identities are issued by the local agent, like the builtin certificate.
+	if strings.HasPrefix(name, security.WorkloadIdentityResourcePrefix) {
+		if strings.HasSuffix(name, SdsCaSuffix) {
+			return ConstructSdsSecretConfig(SDSRootResourceName)
+		}
+		return ConstructSdsSecretConfig(name)
+	}
 	// External SDS: credentialName format is "sds://<resource-name>".
 	// The resource name (after stripping the sds:// prefix) is sent as-is to the SDS server,
 	// allowing the server to differentiate between different certificates.
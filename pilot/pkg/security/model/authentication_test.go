@@ -712,6 +712,18 @@ func TestConstructSdsSecretConfigForCredential(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:                   "identity://legacy",
+			credentialSocketExists: false,
+			push:                   &model.PushContext{Mesh: &meshconfig.MeshConfig{}},
+			expected:               ConstructSdsSecretConfig("identity://legacy"),
+		},
+		{
+			name:                   "identity://legacy-cacert",
+			credentialSocketExists: false,
+			push:                   &model.PushContext{Mesh: &meshconfig.MeshConfig{}},
+			expected:               ConstructSdsSecretConfig(SDSRootResourceName),
+		},
 		{
 			name:                   "sds://my-credential",
 			credentialSocketExists: true,
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustbundle
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"time"
+
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	// BundleEndpointPath is the path the SPIFFE bundle of the mesh trust domain is served on.
+	BundleEndpointPath = "/spiffe-bundle"
+
+	// bundleRefreshHint is how often consumers of the bundle endpoint are advised to poll it.
+	bundleRefreshHint = 5 * time.Minute
+)
+
+// localBundle returns the trustAnchors of the mesh trust domain along with their sequence number.
+func (tb *TrustBundle) localBundle() (uint64, []string) {
+	td := tb.meshConfig.Mesh().GetTrustDomain()
+	tb.mutex.RLock()
+	defer tb.mutex.RUnlock()
+	return tb.sequence, mergeCerts(tb.localCerts, tb.trustDomainCerts[td])
+}
+
+// BundleEndpointHandler returns a handler serving the trustAnchors of the mesh trust domain as a SPIFFE
+// bundle, allowing SPIFFE implementations such as SPIRE to federate with the mesh.
+func (tb *TrustBundle) BundleEndpointHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		sequence, anchors := tb.localBundle()
+		certs := make([]*x509.Certificate, 0, len(anchors))
+		for _, anchor := range anchors {
+			block, _ := pem.Decode([]byte(anchor))
+			if block == nil {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				trustBundleLog.Warnf("skipping invalid trustAnchor in SPIFFE bundle: %v", err)
+				continue
+			}
+			certs = append(certs, cert)
+		}
+		bundle, err := spiffe.MarshalBundle(certs, sequence, bundleRefreshHint)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(bundle)
+	})
+}
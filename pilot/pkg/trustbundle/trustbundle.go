@@ -18,7 +18,6 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +25,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/pkg/util/sets"
@@ -59,7 +59,14 @@ func (s Source) String() string {
 }
 
 type TrustAnchorConfig struct {
+	// Certs anchor the mesh trust domain and its aliases.
 	Certs []string
+	// TrustDomainCerts anchor only the trust domain they are keyed by, such as a federated trust domain.
+	TrustDomainCerts map[string][]string
+}
+
+func (c TrustAnchorConfig) equal(o TrustAnchorConfig) bool {
+	return slices.Equal(c.Certs, o.Certs) && maps.EqualFunc(c.TrustDomainCerts, o.TrustDomainCerts, slices.Equal[string])
 }
 
 type TrustAnchorUpdate struct {
@@ -71,9 +78,13 @@ type TrustBundle struct {
 	sourceConfig       map[Source]TrustAnchorConfig
 	mutex              sync.RWMutex
 	mergedCerts        []string
+	localCerts         []string
+	trustDomainCerts   map[string][]string
+	sequence           uint64
 	updatecb           func()
 	endpointMutex      sync.RWMutex
 	endpoints          []string
+	endpointDomains    map[string][]string
 	endpointUpdateChan chan struct{}
 	remoteCaCertPool   *x509.CertPool
 	meshConfig         mesh.Watcher
@@ -94,10 +105,15 @@ func NewTrustBundle(remoteCaCertPool *x509.CertPool, meshConfig mesh.Watcher) *T
 			SourceIstioRA:         {Certs: []string{}},
 			sourceSpiffeEndpoints: {Certs: []string{}},
 		},
-		mergedCerts:        []string{},
+		mergedCerts:      []string{},
+		localCerts:       []string{},
+		trustDomainCerts: map[string][]string{},
+		// Seed the sequence with the start time, so that it keeps increasing across restarts.
+		sequence:           uint64(time.Now().Unix()),
 		updatecb:           nil,
 		endpointUpdateChan: make(chan struct{}, 1),
 		endpoints:          []string{},
+		endpointDomains:    map[string][]string{},
 		meshConfig:         meshConfig,
 	}
 	if remoteCaCertPool == nil {
@@ -124,6 +140,30 @@ func (tb *TrustBundle) GetTrustBundle() []string {
 	return trustedCerts
 }
 
+// GetTrustBundles : Retrieves the trustAnchors keyed by the trust domain they anchor. Anchors not scoped to a
+// trust domain anchor the mesh trust domain and its aliases. Returns nil if no anchors are scoped to a trust
+// domain, in which case all anchors from GetTrustBundle are trusted for any trust domain.
+func (tb *TrustBundle) GetTrustBundles() map[string][]string {
+	var localDomains []string
+	if tb.meshConfig != nil {
+		m := tb.meshConfig.Mesh()
+		localDomains = append([]string{m.GetTrustDomain()}, m.GetTrustDomainAliases()...)
+	}
+	tb.mutex.RLock()
+	defer tb.mutex.RUnlock()
+	if len(tb.trustDomainCerts) == 0 {
+		return nil
+	}
+	bundles := make(map[string][]string, len(tb.trustDomainCerts)+len(localDomains))
+	for td, certs := range tb.trustDomainCerts {
+		bundles[td] = slices.Clone(certs)
+	}
+	for _, td := range localDomains {
+		bundles[td] = mergeCerts(tb.localCerts, bundles[td])
+	}
+	return bundles
+}
+
 func verifyTrustAnchor(trustAnchor string) error {
 	block, _ := pem.Decode([]byte(trustAnchor))
 	if block == nil {
@@ -139,22 +179,38 @@ func verifyTrustAnchor(trustAnchor string) error {
 	return nil
 }
 
-func (tb *TrustBundle) mergeInternal() {
-	var mergeCerts []string
+// mergeCerts returns the sorted union of the given certificate lists.
+func mergeCerts(certLists ...[]string) []string {
 	certMap := sets.New[string]()
+	for _, certs := range certLists {
+		certMap.InsertAll(certs...)
+	}
+	return sets.SortedList(certMap)
+}
+
+func (tb *TrustBundle) mergeInternal() {
+	var localCerts []string
+	var allCerts [][]string
+	scopedCerts := map[string][][]string{}
 
 	tb.mutex.Lock()
 	defer tb.mutex.Unlock()
 
 	for _, configSource := range tb.sourceConfig {
-		for _, cert := range configSource.Certs {
-			if !certMap.InsertContains(cert) {
-				mergeCerts = append(mergeCerts, cert)
-			}
+		localCerts = append(localCerts, configSource.Certs...)
+		allCerts = append(allCerts, configSource.Certs)
+		for td, certs := range configSource.TrustDomainCerts {
+			scopedCerts[td] = append(scopedCerts[td], certs)
+			allCerts = append(allCerts, certs)
 		}
 	}
-	tb.mergedCerts = mergeCerts
-	sort.Strings(tb.mergedCerts)
+	tb.mergedCerts = mergeCerts(allCerts...)
+	tb.localCerts = mergeCerts(localCerts)
+	tb.trustDomainCerts = make(map[string][]string, len(scopedCerts))
+	for td, certs := range scopedCerts {
+		tb.trustDomainCerts[td] = mergeCerts(certs...)
+	}
+	tb.sequence++
 }
 
 // UpdateTrustAnchor : External Function to merge a TrustAnchor config with the existing TrustBundle
@@ -170,7 +226,7 @@ func (tb *TrustBundle) UpdateTrustAnchor(anchorConfig *TrustAnchorUpdate) error
 	}
 
 	// Check if anything needs to be changed at all
-	if slices.Equal(anchorConfig.Certs, cachedConfig.Certs) {
+	if anchorConfig.TrustAnchorConfig.equal(cachedConfig) {
 		trustBundleLog.Debugf("no change to trustAnchor configuration after recent update")
 		return nil
 	}
@@ -181,6 +237,13 @@ func (tb *TrustBundle) UpdateTrustAnchor(anchorConfig *TrustAnchorUpdate) error
 			return err
 		}
 	}
+	for td, certs := range anchorConfig.TrustDomainCerts {
+		for _, cert := range certs {
+			if err = verifyTrustAnchor(cert); err != nil {
+				return fmt.Errorf("invalid trustAnchor for trust domain %s: %v", td, err)
+			}
+		}
+	}
 	tb.mutex.Lock()
 	tb.sourceConfig[anchorConfig.Source] = anchorConfig.TrustAnchorConfig
 	tb.mutex.Unlock()
@@ -196,17 +259,21 @@ func (tb *TrustBundle) UpdateTrustAnchor(anchorConfig *TrustAnchorUpdate) error
 	return nil
 }
 
-func (tb *TrustBundle) updateRemoteEndpoint(spiffeEndpoints []string) {
+// updateRemoteEndpoint updates the SPIFFE bundle endpoints to fetch trustAnchors from. endpointDomains holds the
+// trust domains served by an endpoint; endpoints without trust domains serve the mesh trust domain.
+func (tb *TrustBundle) updateRemoteEndpoint(spiffeEndpoints []string, endpointDomains map[string][]string) {
 	tb.endpointMutex.RLock()
 	remoteEndpoints := tb.endpoints
+	remoteDomains := tb.endpointDomains
 	tb.endpointMutex.RUnlock()
 
-	if slices.Equal(spiffeEndpoints, remoteEndpoints) {
+	if slices.Equal(spiffeEndpoints, remoteEndpoints) && maps.EqualFunc(endpointDomains, remoteDomains, slices.Equal[string]) {
 		return
 	}
 	trustBundleLog.Infof("updated remote endpoints  :%v", spiffeEndpoints)
 	tb.endpointMutex.Lock()
 	tb.endpoints = spiffeEndpoints
+	tb.endpointDomains = endpointDomains
 	tb.endpointMutex.Unlock()
 	tb.endpointUpdateChan <- struct{}{}
 }
@@ -216,18 +283,32 @@ func (tb *TrustBundle) AddMeshConfigUpdate(cfg *meshconfig.MeshConfig) error {
 	var err error
 	if cfg != nil {
 		certs := []string{}
+		var trustDomainCerts map[string][]string
 		endpoints := []string{}
+		endpointDomains := map[string][]string{}
 		for _, pemCert := range cfg.GetCaCertificates() {
 			cert := pemCert.GetPem()
 			if cert != "" {
-				certs = append(certs, cert)
+				if len(pemCert.GetTrustDomains()) == 0 {
+					certs = append(certs, cert)
+					continue
+				}
+				if trustDomainCerts == nil {
+					trustDomainCerts = map[string][]string{}
+				}
+				for _, td := range pemCert.GetTrustDomains() {
+					trustDomainCerts[td] = append(trustDomainCerts[td], cert)
+				}
 			} else if pemCert.GetSpiffeBundleUrl() != "" {
 				endpoints = append(endpoints, pemCert.GetSpiffeBundleUrl())
+				if len(pemCert.GetTrustDomains()) > 0 {
+					endpointDomains[pemCert.GetSpiffeBundleUrl()] = pemCert.GetTrustDomains()
+				}
 			}
 		}
 
 		err = tb.UpdateTrustAnchor(&TrustAnchorUpdate{
-			TrustAnchorConfig: TrustAnchorConfig{Certs: certs},
+			TrustAnchorConfig: TrustAnchorConfig{Certs: certs, TrustDomainCerts: trustDomainCerts},
 			Source:            SourceMeshConfig,
 		})
 		if err != nil {
@@ -235,7 +316,7 @@ func (tb *TrustBundle) AddMeshConfigUpdate(cfg *meshconfig.MeshConfig) error {
 			return err
 		}
 
-		tb.updateRemoteEndpoint(endpoints)
+		tb.updateRemoteEndpoint(endpoints, endpointDomains)
 	}
 	return nil
 }
@@ -245,26 +326,42 @@ func (tb *TrustBundle) fetchRemoteTrustAnchors() {
 
 	tb.endpointMutex.RLock()
 	remoteEndpoints := tb.endpoints
+	remoteDomains := tb.endpointDomains
 	tb.endpointMutex.RUnlock()
 	remoteCerts := []string{}
+	var trustDomainCerts map[string][]string
 
 	currentTrustDomain := tb.meshConfig.Mesh().GetTrustDomain()
 	for _, endpoint := range remoteEndpoints {
+		trustDomains := remoteDomains[endpoint]
+		trustDomain := currentTrustDomain
+		if len(trustDomains) > 0 {
+			trustDomain = trustDomains[0]
+		}
 		trustDomainAnchorMap, err := spiffe.RetrieveSpiffeBundleRootCerts(
-			map[string]string{currentTrustDomain: endpoint}, tb.remoteCaCertPool, remoteTimeout)
+			map[string]string{trustDomain: endpoint}, tb.remoteCaCertPool, remoteTimeout)
 		if err != nil {
 			trustBundleLog.Errorf("unable to fetch trust Anchors from endpoint %s: %s", endpoint, err)
 			continue
 		}
-		certs := trustDomainAnchorMap[currentTrustDomain]
+		certs := trustDomainAnchorMap[trustDomain]
 		for _, cert := range certs {
 			certStr := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
 			trustBundleLog.Debugf("from endpoint %v, fetched trust anchor cert: %v", endpoint, certStr)
-			remoteCerts = append(remoteCerts, certStr)
+			if len(trustDomains) == 0 {
+				remoteCerts = append(remoteCerts, certStr)
+				continue
+			}
+			if trustDomainCerts == nil {
+				trustDomainCerts = map[string][]string{}
+			}
+			for _, td := range trustDomains {
+				trustDomainCerts[td] = append(trustDomainCerts[td], certStr)
+			}
 		}
 	}
 	err = tb.UpdateTrustAnchor(&TrustAnchorUpdate{
-		TrustAnchorConfig: TrustAnchorConfig{Certs: remoteCerts},
+		TrustAnchorConfig: TrustAnchorConfig{Certs: remoteCerts, TrustDomainCerts: trustDomainCerts},
 		Source:            sourceSpiffeEndpoints,
 	})
 	if err != nil {
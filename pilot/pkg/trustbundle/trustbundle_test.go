@@ -16,6 +16,7 @@ package trustbundle
 
 import (
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -335,3 +336,114 @@ func TestAddMeshConfigUpdate(t *testing.T) {
 	tb.AddMeshConfigUpdate(&meshconfig.MeshConfig{CaCertificates: []*meshconfig.MeshConfig_CertificateData{}})
 	expectTbCount(t, tb, 0, 3*time.Second, "trustAnchor not updated in bundle after meshConfig cleared")
 }
+
+func TestGetTrustBundles(t *testing.T) {
+	stop := test.NewStop(t)
+	caCertPool := x509.NewCertPool()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(validSpiffeX509Bundle))
+	}))
+	caCertPool.AddCert(server.Certificate())
+	defer server.Close()
+
+	remoteTimeout = 30 * time.Millisecond
+	tb := NewTrustBundle(caCertPool, meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{
+		TrustDomain:        "cluster.local",
+		TrustDomainAliases: []string{"alias.local"},
+	}))
+	if err := tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: []string{rootCACert}},
+		Source:            SourceIstioCA,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if bundles := tb.GetTrustBundles(); bundles != nil {
+		t.Fatalf("expected no trust domain bundles without scoped trustAnchors, got %v", bundles)
+	}
+
+	go tb.ProcessRemoteTrustAnchors(stop, 200*time.Millisecond)
+	if err := tb.AddMeshConfigUpdate(&meshconfig.MeshConfig{CaCertificates: []*meshconfig.MeshConfig_CertificateData{
+		{
+			CertificateData: &meshconfig.MeshConfig_CertificateData_Pem{Pem: intermediateCACert},
+			TrustDomains:    []string{"remote.domain"},
+		},
+		{
+			CertificateData: &meshconfig.MeshConfig_CertificateData_SpiffeBundleUrl{SpiffeBundleUrl: server.Listener.Addr().String()},
+			TrustDomains:    []string{"spire.domain"},
+		},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	// All trustAnchors are still part of the merged bundle.
+	expectTbCount(t, tb, 3, 3*time.Second, "federated trustAnchors not merged in bundle")
+
+	bundles := tb.GetTrustBundles()
+	for _, td := range []string{"cluster.local", "alias.local"} {
+		if !slices.Equal(bundles[td], []string{rootCACert}) {
+			t.Errorf("unexpected trustAnchors for %s: %v", td, bundles[td])
+		}
+	}
+	if !slices.Equal(bundles["remote.domain"], []string{intermediateCACert}) {
+		t.Errorf("unexpected trustAnchors for remote.domain: %v", bundles["remote.domain"])
+	}
+	if len(bundles["spire.domain"]) != 1 {
+		t.Errorf("unexpected trustAnchors for spire.domain: %v", bundles["spire.domain"])
+	}
+}
+
+func TestBundleEndpointHandler(t *testing.T) {
+	tb := NewTrustBundle(nil, meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{TrustDomain: "cluster.local"}))
+	fetch := func() (doc struct {
+		Sequence uint64 `json:"spiffe_sequence"`
+		Keys     []struct {
+			Use string   `json:"use"`
+			X5c []string `json:"x5c"`
+		} `json:"keys"`
+	},
+	) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		tb.BundleEndpointHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, BundleEndpointPath, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	if err := tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: []string{rootCACert}},
+		Source:            SourceIstioCA,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	first := fetch()
+	if len(first.Keys) != 1 || first.Keys[0].Use != "x509-svid" || len(first.Keys[0].X5c) != 1 {
+		t.Fatalf("unexpected bundle keys: %+v", first.Keys)
+	}
+
+	// Anchors of federated trust domains are not served.
+	if err := tb.AddMeshConfigUpdate(&meshconfig.MeshConfig{CaCertificates: []*meshconfig.MeshConfig_CertificateData{
+		{
+			CertificateData: &meshconfig.MeshConfig_CertificateData_Pem{Pem: intermediateCACert},
+			TrustDomains:    []string{"remote.domain"},
+		},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	second := fetch()
+	if len(second.Keys) != 1 {
+		t.Fatalf("unexpected bundle keys: %+v", second.Keys)
+	}
+	if second.Sequence <= first.Sequence {
+		t.Errorf("expected sequence to increase after an update, got %d then %d", first.Sequence, second.Sequence)
+	}
+
+	rec := httptest.NewRecorder()
+	tb.BundleEndpointHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, BundleEndpointPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected POST to be rejected, got %d", rec.Code)
+	}
+}
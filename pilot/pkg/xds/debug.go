@@ -55,6 +55,7 @@ import (
 	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/istio/pkg/workloadapi"
+	"istio.io/istio/security/pkg/server/ca/audit"
 )
 
 // CallerNamespaceKey is used to store caller namespace in request context
@@ -225,6 +226,7 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
 	s.addDebugHandler(mux, internalMux, "/debug/mcsz", "List information about Kubernetes MCS services", s.mcsz)
+	s.addDebugHandler(mux, internalMux, "/debug/caauditz", "Query the CA certificate issuance audit log", s.caAuditz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/list", "List all supported debug commands in json", s.list)
 }
@@ -1116,6 +1118,31 @@ func sortMCSServices(svcs []model.MCSServiceInfo) []model.MCSServiceInfo {
 	return svcs
 }
 
+// caAuditz returns the CA audit records matching the filter in the query parameters.
+// It is mapped to /debug/caauditz.
+func (s *DiscoveryServer) caAuditz(w http.ResponseWriter, req *http.Request) {
+	if features.CAAuditLogFile == "" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("CA audit log is not enabled, set CA_AUDIT_LOG_FILE to enable it\n"))
+		return
+	}
+	filter, err := audit.ParseFilter(req.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	records, err := audit.ReadFile(features.CAAuditLogFile, filter)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	if records == nil {
+		records = []*audit.Record{}
+	}
+	writeJSON(w, records, req)
+}
+
 func (s *DiscoveryServer) clusterz(w http.ResponseWriter, req *http.Request) {
 	if s.ListRemoteClusters == nil {
 		w.WriteHeader(http.StatusBadRequest)
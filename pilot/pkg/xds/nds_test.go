@@ -38,6 +38,7 @@ func TestNDS(t *testing.T) {
 	// The "auto allocate" test only needs a special case for the legacy auto allocation mode, so we disable the new one here
 	// and only test the old one. The new one appears identically to manually-allocated SE from NDS perspective.
 	test.SetForTest(t, &features.EnableIPAutoallocate, false)
+	httpPorts := []*dnsProto.NameTable_Port{{Name: "http", Number: 80, Protocol: "tcp"}}
 	cases := []struct {
 		name     string
 		meta     model.NodeMetadata
@@ -54,14 +55,17 @@ func TestNDS(t *testing.T) {
 					"random-1.host.example": {
 						Ips:      []string{"240.240.116.21"},
 						Registry: "External",
+						Ports:    httpPorts,
 					},
 					"random-2.host.example": {
 						Ips:      []string{"9.9.9.9"},
 						Registry: "External",
+						Ports:    httpPorts,
 					},
 					"random-3.host.example": {
 						Ips:      []string{"240.240.81.100"},
 						Registry: "External",
+						Ports:    httpPorts,
 					},
 				},
 			},
@@ -76,6 +80,7 @@ func TestNDS(t *testing.T) {
 					"random-2.host.example": {
 						Ips:      []string{"9.9.9.9"},
 						Registry: "External",
+						Ports:    httpPorts,
 					},
 				},
 			},
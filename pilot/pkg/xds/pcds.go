@@ -15,6 +15,10 @@
 package xds
 
 import (
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
 	mesh "istio.io/api/mesh/v1alpha1"
@@ -23,6 +27,8 @@ import (
 	tb "istio.io/istio/pilot/pkg/trustbundle"
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/util/sets"
 )
 
 // PcdsGenerator generates proxy configuration for proxies to consume
@@ -32,6 +38,13 @@ type PcdsGenerator struct {
 
 var _ model.XdsResourceGenerator = &PcdsGenerator{}
 
+// TbdsGenerator generates the trust anchors of each trust domain for proxies to consume
+type TbdsGenerator struct {
+	TrustBundle *tb.TrustBundle
+}
+
+var _ model.XdsResourceGenerator = &TbdsGenerator{}
+
 func pcdsNeedsPush(req *model.PushRequest) bool {
 	if !features.MultiRootMesh {
 		return false
@@ -67,3 +80,25 @@ func (e *PcdsGenerator) Generate(proxy *model.Proxy, w *model.WatchedResource, r
 	}
 	return model.Resources{&discovery.Resource{Resource: protoconv.MessageToAny(pc)}}, model.DefaultXdsLogDetails, nil
 }
+
+// Generate returns the trust anchors of each trust domain. The resource is always sent, even when empty, so
+// that proxies drop trust domains that are no longer configured.
+func (e *TbdsGenerator) Generate(proxy *model.Proxy, w *model.WatchedResource, req *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	if !pcdsNeedsPush(req) {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	if e.TrustBundle == nil {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	bundles := e.TrustBundle.GetTrustBundles()
+	cfg := &tls.SPIFFECertValidatorConfig{}
+	for _, td := range sets.SortedList(sets.New(maps.Keys(bundles)...)) {
+		cfg.TrustDomains = append(cfg.TrustDomains, &tls.SPIFFECertValidatorConfig_TrustDomain{
+			Name: td,
+			TrustBundle: &core.DataSource{
+				Specifier: &core.DataSource_InlineString{InlineString: strings.Join(bundles[td], "\n")},
+			},
+		})
+	}
+	return model.Resources{&discovery.Resource{Resource: protoconv.MessageToAny(cfg)}}, model.DefaultXdsLogDetails, nil
+}
@@ -0,0 +1,75 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pkg/config/mesh/meshwatcher"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestTbdsGenerator(t *testing.T) {
+	test.SetForTest(t, &features.MultiRootMesh, true)
+	root, err := os.ReadFile(filepath.Join(env.IstioSrc, "samples/certs/root-cert.pem"))
+	assert.NoError(t, err)
+	intermediate, err := os.ReadFile(filepath.Join(env.IstioSrc, "samples/certs/ca-cert.pem"))
+	assert.NoError(t, err)
+
+	tb := trustbundle.NewTrustBundle(nil, meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{TrustDomain: "cluster.local"}))
+	assert.NoError(t, tb.UpdateTrustAnchor(&trustbundle.TrustAnchorUpdate{
+		TrustAnchorConfig: trustbundle.TrustAnchorConfig{Certs: []string{string(root)}},
+		Source:            trustbundle.SourceIstioCA,
+	}))
+	gen := &TbdsGenerator{TrustBundle: tb}
+
+	generate := func() *tls.SPIFFECertValidatorConfig {
+		t.Helper()
+		res, _, err := gen.Generate(nil, nil, &model.PushRequest{Forced: true})
+		assert.NoError(t, err)
+		assert.Equal(t, len(res), 1)
+		cfg := &tls.SPIFFECertValidatorConfig{}
+		assert.NoError(t, res[0].Resource.UnmarshalTo(cfg))
+		return cfg
+	}
+
+	// Without scoped trust anchors an empty resource is sent, clearing any bundles the proxy has.
+	assert.Equal(t, len(generate().GetTrustDomains()), 0)
+
+	assert.NoError(t, tb.UpdateTrustAnchor(&trustbundle.TrustAnchorUpdate{
+		TrustAnchorConfig: trustbundle.TrustAnchorConfig{
+			TrustDomainCerts: map[string][]string{"remote.domain": {string(intermediate)}},
+		},
+		Source: trustbundle.SourceMeshConfig,
+	}))
+	got := map[string]string{}
+	for _, td := range generate().GetTrustDomains() {
+		got[td.GetName()] = td.GetTrustBundle().GetInlineString()
+	}
+	assert.Equal(t, got, map[string]string{
+		"cluster.local": string(root),
+		"remote.domain": string(intermediate),
+	})
+}
@@ -28,6 +28,7 @@ const (
 	NameTableType              = model.NameTableType
 	HealthInfoType             = model.HealthInfoType
 	ProxyConfigType            = model.ProxyConfigType
+	TrustDomainBundleType      = model.TrustDomainBundleType
 	DebugType                  = model.DebugType
 	BootstrapType              = model.BootstrapType
 	AddressType                = model.AddressType
@@ -65,7 +65,10 @@ const (
 
 	rbacEnvoyStatsMatcherInclusionSuffix = "rbac.allowed,rbac.denied,shadow_allowed,shadow_denied"
 
-	requiredEnvoyStatsMatcherInclusionSuffixes = rbacEnvoyStatsMatcherInclusionSuffix + ",downstream_cx_active" // Needed for draining.
+	requiredEnvoyStatsMatcherInclusionSuffixes = rbacEnvoyStatsMatcherInclusionSuffix + ",downstream_cx_active,downstream_rq_active" // Needed for draining.
+
+	// required for the upstream conditions of the composite app probes.
+	clusterHealthEnvoyStatsMatcherInclusionSuffix = "membership_healthy"
 
 	// required for metrics based on stat_prefix in virtual service.
 	requiredEnvoyStatsMatcherInclusionRegexes = `vhost\..*\.route\..*`
@@ -293,9 +296,12 @@ func getStatsOptions(meta *model.BootstrapNodeMetadata) []option.Instance {
 		proxyConfigRegexps = config.ProxyStatsMatcher.InclusionRegexps
 	}
 	inclusionSuffixes := rbacEnvoyStatsMatcherInclusionSuffix
-	if meta.ExitOnZeroActiveConnections {
+	if bool(meta.ExitOnZeroActiveConnections) || meta.DrainRequests {
 		inclusionSuffixes = requiredEnvoyStatsMatcherInclusionSuffixes
 	}
+	if meta.ClusterHealthStats {
+		inclusionSuffixes += "," + clusterHealthEnvoyStatsMatcherInclusionSuffix
+	}
 
 	var buckets []option.HistogramBucket
 	if bucketsAnno, ok := meta.Annotations[annotation.SidecarStatsHistogramBuckets.Name]; ok {
@@ -665,6 +671,8 @@ type MetadataOptions struct {
 	EnvoySecureMetricsPort       int
 	EnvoySecureMergedMetricsPort int
 	ExitOnZeroActiveConnections  bool
+	ClusterHealthStats           bool
+	DrainRequests                bool
 	MetadataDiscovery            *bool
 	EnvoySkipDeprecatedLogs      bool
 	WorkloadIdentitySocketFile   string
@@ -730,6 +738,8 @@ func GetNodeMetaData(options MetadataOptions) (*model.Node, error) {
 	meta.EnvoySecureMetricsPort = options.EnvoySecureMetricsPort
 	meta.EnvoySecureMergedMetricsPort = options.EnvoySecureMergedMetricsPort
 	meta.ExitOnZeroActiveConnections = model.StringBool(options.ExitOnZeroActiveConnections)
+	meta.ClusterHealthStats = options.ClusterHealthStats
+	meta.DrainRequests = options.DrainRequests
 	if options.MetadataDiscovery == nil {
 		meta.MetadataDiscovery = nil
 	} else {
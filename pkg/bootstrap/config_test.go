@@ -184,7 +184,23 @@ func TestGetStatOptions(t *testing.T) {
 				ProxyConfig:                 &v1alpha1.ProxyConfig{},
 				ExitOnZeroActiveConnections: true,
 			},
-			wantInclusionSuffixes: []string{"rbac.allowed", "rbac.denied", "shadow_allowed", "shadow_denied", "downstream_cx_active"},
+			wantInclusionSuffixes: []string{
+				"rbac.allowed", "rbac.denied", "shadow_allowed", "shadow_denied", "downstream_cx_active",
+				"downstream_rq_active",
+			},
+		},
+		{
+			name: "with requests drain mode",
+			metadataOptions: MetadataOptions{
+				ID:            "test",
+				Envs:          os.Environ(),
+				ProxyConfig:   &v1alpha1.ProxyConfig{},
+				DrainRequests: true,
+			},
+			wantInclusionSuffixes: []string{
+				"rbac.allowed", "rbac.denied", "shadow_allowed", "shadow_denied", "downstream_cx_active",
+				"downstream_rq_active",
+			},
 		},
 		{
 			name: "with exit on zero connections disabled",
@@ -463,6 +463,108 @@ func checkListStringMatcher(t *testing.T, got *matcher.ListStringMatcher, want s
 	}
 }
 
+// TestStatsMatcherAgentStats checks that the stats read by the agent from the Envoy admin API are not rejected by the
+// stats matcher of the generated bootstrap.
+func TestStatsMatcherAgentStats(t *testing.T) {
+	cases := []struct {
+		name     string
+		options  func(*MetadataOptions)
+		included []string
+		excluded []string
+	}{
+		{
+			name:     "default",
+			included: []string{"cluster.xds-grpc.membership_healthy", "server.state"},
+			excluded: []string{
+				"cluster.outbound|5432||db.ns.svc.cluster.local.membership_healthy",
+				"http.inbound_0.0.0.0_8080.downstream_rq_active",
+			},
+		},
+		{
+			name:     "cluster health stats",
+			options:  func(o *MetadataOptions) { o.ClusterHealthStats = true },
+			included: []string{"cluster.outbound|5432||db.ns.svc.cluster.local.membership_healthy"},
+			excluded: []string{"cluster.outbound|5432||db.ns.svc.cluster.local.membership_total"},
+		},
+		{
+			name:     "requests drain mode",
+			options:  func(o *MetadataOptions) { o.DrainRequests = true },
+			included: []string{"http.admin.downstream_rq_active", "http.inbound_0.0.0.0_8080.downstream_rq_active"},
+			excluded: []string{"http.inbound_0.0.0.0_8080.downstream_rq_total"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := t.TempDir()
+			proxyConfig, err := loadProxyConfig("default", out, t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			options := MetadataOptions{
+				ID:          "sidecar~1.2.3.4~foo~bar",
+				Platform:    &fakePlatform{},
+				InstanceIPs: []string{"1.2.3.4"},
+				ProxyConfig: proxyConfig,
+			}
+			if c.options != nil {
+				c.options(&options)
+			}
+			node, err := GetNodeMetaData(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fn, err := New(Config{Node: node}).CreateFile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			read, err := os.ReadFile(fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &bootstrap.Bootstrap{}
+			if err := protomarshal.Unmarshal(read, got); err != nil {
+				t.Fatalf("invalid json %v\n%s", err, string(read))
+			}
+			inclusions := got.GetStatsConfig().GetStatsMatcher().GetInclusionList()
+			for _, stat := range c.included {
+				if !listStringMatches(t, inclusions, stat) {
+					t.Errorf("stat %s is rejected by the stats matcher", stat)
+				}
+			}
+			for _, stat := range c.excluded {
+				if listStringMatches(t, inclusions, stat) {
+					t.Errorf("stat %s is accepted by the stats matcher", stat)
+				}
+			}
+		})
+	}
+}
+
+// listStringMatches matches a stat name against a list of string matchers, the way Envoy does.
+func listStringMatches(t *testing.T, list *matcher.ListStringMatcher, name string) bool {
+	for _, pattern := range list.GetPatterns() {
+		switch {
+		case pattern.GetExact() != "" && pattern.GetExact() == name:
+			return true
+		case pattern.GetPrefix() != "" && strings.HasPrefix(name, pattern.GetPrefix()):
+			return true
+		case pattern.GetSuffix() != "" && strings.HasSuffix(name, pattern.GetSuffix()):
+			return true
+		case pattern.GetContains() != "" && strings.Contains(name, pattern.GetContains()):
+			return true
+		case pattern.GetSafeRegex() != nil:
+			re, err := regexp.Compile("^(?:" + pattern.GetSafeRegex().GetRegex() + ")$")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if re.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // nolint: staticcheck
 func checkOpencensusConfig(t *testing.T, got, want *bootstrap.Bootstrap) {
 	if want.Tracing == nil {
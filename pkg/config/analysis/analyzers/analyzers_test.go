@@ -1065,6 +1065,7 @@ var testGrid = []testCase{
 			{msg.NegativeConditionStatus, "HTTPRoute default/negative-condition-httproute"},
 			{msg.NegativeConditionStatus, "GRPCRoute default/negative-condition-grpcroute"},
 			{msg.NegativeConditionStatus, "AuthorizationPolicy default/negative-condition-authz-partially-invalid"},
+			{msg.NegativeConditionStatus, "WasmPlugin default/negative-condition-wasmplugin"},
 		},
 	},
 	{
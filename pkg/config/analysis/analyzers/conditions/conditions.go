@@ -46,6 +46,7 @@ func (c *ConditionAnalyzer) Metadata() analysis.Metadata {
 			groupVersionKind.KubernetesGateway,
 			groupVersionKind.HTTPRoute,
 			groupVersionKind.GRPCRoute,
+			groupVersionKind.WasmPlugin,
 		},
 	}
 }
@@ -60,6 +61,7 @@ func (c *ConditionAnalyzer) Analyze(ctx analysis.Context) {
 		groupVersionKind.AuthorizationPolicy,
 		groupVersionKind.KubernetesGateway,
 		groupVersionKind.HTTPRoute, groupVersionKind.GRPCRoute,
+		groupVersionKind.WasmPlugin,
 	} {
 		ctx.ForEach(gvk, func(r *resource.Instance) bool {
 			conditions := extractConditions(r)
@@ -119,6 +121,9 @@ var negativeConditionsToReport = map[config.GroupVersionKind]map[string]metav1.C
 		"Programmed":   metav1.ConditionFalse,
 		"ResolvedRefs": metav1.ConditionFalse,
 	},
+	groupVersionKind.WasmPlugin: {
+		"ModuleValidated": metav1.ConditionFalse,
+	},
 }
 
 // shouldReportCondition returns true if the condition is considered negative
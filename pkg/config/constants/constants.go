@@ -103,6 +103,11 @@ const (
 	// This is typically set by the downward API
 	PodInfoAnnotationsPath = "./etc/istio/pod/annotations"
 
+	// WorkloadIdentitiesAnnotation requests additional identities for a workload, as a comma separated list of
+	// name=spiffe://trust-domain/ns/namespace/sa/account pairs. A certificate for each is served over SDS as
+	// identity://name.
+	WorkloadIdentitiesAnnotation = "security.istio.io/workloadIdentities"
+
 	// DefaultServiceAccountName is the default service account to use for remote cluster access.
 	DefaultServiceAccountName = "istio-reader-service-account"
 
@@ -0,0 +1,250 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"istio.io/istio/pkg/slices"
+)
+
+const (
+	// DefaultCacheMaxTTL is the default cap of the TTL of cached upstream responses.
+	DefaultCacheMaxTTL = 5 * time.Minute
+	// DefaultCacheNegativeTTL is the default cap of the TTL of cached negative upstream responses.
+	DefaultCacheNegativeTTL = 30 * time.Second
+
+	// Entries are prefetched when a request hits them in the last tenth of their TTL...
+	prefetchWindow = 10
+	// ... and they were requested at least this many times.
+	prefetchMinHits = 2
+)
+
+// ResponseCacheOptions configures the cache of upstream DNS responses.
+type ResponseCacheOptions struct {
+	// MaxEntries is the maximum number of cached responses. Zero disables the cache.
+	MaxEntries int
+	// MaxTTL caps the time positive responses are cached, regardless of their TTL.
+	MaxTTL time.Duration
+	// NegativeTTL caps the time negative responses (NXDOMAIN, or no answer) are cached. Zero disables the caching of
+	// negative responses.
+	NegativeTTL time.Duration
+	// Prefetch enables refreshing frequently requested entries shortly before they expire.
+	Prefetch bool
+}
+
+// WithResponseCache enables the caching of upstream responses.
+func WithResponseCache(opts ResponseCacheOptions) LocalDNSServerOption {
+	return func(s *LocalDNSServer) {
+		if opts.MaxEntries > 0 {
+			s.cache = newResponseCache(opts)
+		}
+	}
+}
+
+// responseCache is a size bounded LRU cache of upstream responses, respecting the TTL of the responses as
+// described in RFC 1035 and, for negative responses, RFC 2308.
+type responseCache struct {
+	opts ResponseCacheOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	// lru holds the *cacheEntry, the most recently used first.
+	lru *list.List
+}
+
+// cacheKey identifies the responses which can be served for a request. Requests with and without EDNS, or with the
+// DNSSEC related bits set, get different responses from the upstream servers.
+type cacheKey struct {
+	name  string
+	qtype uint16
+	class uint16
+	edns  bool
+	do    bool
+	cd    bool
+}
+
+type cacheEntry struct {
+	key      cacheKey
+	msg      *dns.Msg
+	stored   time.Time
+	expires  time.Time
+	hits     int
+	prefetch bool
+}
+
+func newResponseCache(opts ResponseCacheOptions) *responseCache {
+	return &responseCache{
+		opts:    opts,
+		now:     time.Now,
+		entries: map[cacheKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func keyFor(req *dns.Msg) cacheKey {
+	q := req.Question[0]
+	key := cacheKey{
+		name:  strings.ToLower(q.Name),
+		qtype: q.Qtype,
+		class: q.Qclass,
+		cd:    req.CheckingDisabled,
+	}
+	if opt := req.IsEdns0(); opt != nil {
+		key.edns = true
+		key.do = opt.Do()
+	}
+	return key
+}
+
+// get returns the cached response to the request, with its TTLs decremented by the time spent in the cache, and
+// whether the entry should be prefetched.
+func (c *responseCache) get(req *dns.Msg) (*dns.Msg, bool) {
+	key := keyFor(req)
+	now := c.now()
+
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		cacheLookups.With(resultLabel.Value("miss")).Increment()
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		c.remove(elem)
+		c.mu.Unlock()
+		cacheLookups.With(resultLabel.Value("miss")).Increment()
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	entry.hits++
+	prefetch := false
+	if c.opts.Prefetch && !entry.prefetch && entry.hits >= prefetchMinHits &&
+		entry.expires.Sub(now) <= entry.expires.Sub(entry.stored)/prefetchWindow {
+		entry.prefetch = true
+		prefetch = true
+	}
+	response := entry.msg.Copy()
+	elapsed := uint32(now.Sub(entry.stored) / time.Second)
+	c.mu.Unlock()
+
+	cacheLookups.With(resultLabel.Value("hit")).Increment()
+	response.Id = req.Id
+	// Reply with the question as asked, the case of the name may differ.
+	response.Question = slices.Clone(req.Question)
+	for _, rr := range allRecords(response) {
+		if hdr := rr.Header(); hdr.Ttl > elapsed {
+			hdr.Ttl -= elapsed
+		} else {
+			hdr.Ttl = 0
+		}
+	}
+	return response, prefetch
+}
+
+// add caches the upstream response to the request, if it is cacheable.
+func (c *responseCache) add(req, response *dns.Msg) {
+	ttl, ok := c.ttl(response)
+	if !ok {
+		return
+	}
+	key := keyFor(req)
+	now := c.now()
+	entry := &cacheEntry{
+		key:     key,
+		msg:     response.Copy(),
+		stored:  now,
+		expires: now.Add(ttl),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.opts.MaxEntries {
+		c.remove(c.lru.Back())
+		cacheEvictions.Increment()
+	}
+	cacheEntries.Record(float64(c.lru.Len()))
+}
+
+// ttl returns how long the response can be cached.
+func (c *responseCache) ttl(response *dns.Msg) (time.Duration, bool) {
+	if response.Truncated || len(response.Question) != 1 {
+		return 0, false
+	}
+	var ttl time.Duration
+	var limit time.Duration
+	switch {
+	case response.Rcode == dns.RcodeSuccess && len(response.Answer) > 0:
+		limit = c.opts.MaxTTL
+		ttl = minTTL(allRecords(response))
+	case response.Rcode == dns.RcodeSuccess || response.Rcode == dns.RcodeNameError:
+		// Negative responses are cached for the TTL of the SOA record of the authority section, bounded by its
+		// minimum field, see RFC 2308 section 5.
+		limit = c.opts.NegativeTTL
+		ttl = limit
+		for _, rr := range response.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				ttl = time.Duration(min(soa.Hdr.Ttl, soa.Minttl)) * time.Second
+			}
+		}
+	default:
+		// Server failures and refusals are not cached.
+		return 0, false
+	}
+	ttl = min(ttl, limit)
+	return ttl, ttl > 0
+}
+
+func (c *responseCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+	cacheEntries.Record(float64(c.lru.Len()))
+}
+
+func minTTL(records []dns.RR) time.Duration {
+	var ttl uint32
+	for i, rr := range records {
+		if i == 0 || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// allRecords returns the records of the response carrying a TTL, excluding the EDNS OPT pseudo record.
+func allRecords(msg *dns.Msg) []dns.RR {
+	records := make([]dns.RR, 0, len(msg.Answer)+len(msg.Ns)+len(msg.Extra))
+	records = append(records, msg.Answer...)
+	records = append(records, msg.Ns...)
+	for _, rr := range msg.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			records = append(records, rr)
+		}
+	}
+	return records
+}
@@ -0,0 +1,225 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func newTestCache(opts ResponseCacheOptions) (*responseCache, *time.Time) {
+	c := newResponseCache(opts)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func question(name string, qtype uint16) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(name, qtype)
+	return req
+}
+
+func answer(req *dns.Msg, ttl uint32, ips ...string) *dns.Msg {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	for _, ip := range ips {
+		rr := a(req.Question[0].Name, []netip.Addr{netip.MustParseAddr(ip)})[0]
+		rr.Header().Ttl = ttl
+		resp.Answer = append(resp.Answer, rr)
+	}
+	return resp
+}
+
+func TestResponseCache(t *testing.T) {
+	c, now := newTestCache(ResponseCacheOptions{MaxEntries: 10, MaxTTL: time.Minute, NegativeTTL: 10 * time.Second})
+
+	req := question("www.example.com.", dns.TypeA)
+	got, _ := c.get(req)
+	assert.Equal(t, got == nil, true)
+	c.add(req, answer(req, 30, "1.1.1.1"))
+
+	// The response is served with the ID and question of the request, and TTLs decremented.
+	*now = now.Add(10 * time.Second)
+	req2 := question("WWW.example.com.", dns.TypeA)
+	got, _ = c.get(req2)
+	assert.Equal(t, got.Id, req2.Id)
+	assert.Equal(t, got.Question[0].Name, "WWW.example.com.")
+	assert.Equal(t, got.Answer[0].Header().Ttl, uint32(20))
+
+	// Other types, or requests with EDNS, are cached separately.
+	got, _ = c.get(question("www.example.com.", dns.TypeAAAA))
+	assert.Equal(t, got == nil, true)
+	edns := question("www.example.com.", dns.TypeA)
+	edns.SetEdns0(1232, false)
+	got, _ = c.get(edns)
+	assert.Equal(t, got == nil, true)
+
+	// Expired responses are not served.
+	*now = now.Add(20 * time.Second)
+	got, _ = c.get(req)
+	assert.Equal(t, got == nil, true)
+	assert.Equal(t, c.lru.Len(), 0)
+
+	// The TTL is capped.
+	c.add(req, answer(req, 3600, "1.1.1.1"))
+	*now = now.Add(time.Minute)
+	got, _ = c.get(req)
+	assert.Equal(t, got == nil, true)
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	c, _ := newTestCache(ResponseCacheOptions{MaxEntries: 10, MaxTTL: time.Minute, NegativeTTL: 10 * time.Second})
+	req := question("www.example.com.", dns.TypeA)
+	withRcode := func(rcode int) *dns.Msg {
+		resp := answer(req, 30)
+		resp.Rcode = rcode
+		return resp
+	}
+	withSOA := func(ttl, minTTL uint32) *dns.Msg {
+		resp := withRcode(dns.RcodeNameError)
+		resp.Ns = []dns.RR{&dns.SOA{
+			Hdr:    dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+			Ns:     "ns.example.com.",
+			Mbox:   "admin.example.com.",
+			Minttl: minTTL,
+		}}
+		return resp
+	}
+	truncated := answer(req, 30, "1.1.1.1")
+	truncated.Truncated = true
+
+	cases := []struct {
+		name     string
+		response *dns.Msg
+		want     time.Duration
+	}{
+		{"minimum TTL of the records", answer(req, 30, "1.1.1.1", "2.2.2.2"), 30 * time.Second},
+		{"capped", answer(req, 300, "1.1.1.1"), time.Minute},
+		{"zero TTL", answer(req, 0, "1.1.1.1"), 0},
+		{"no answer", withRcode(dns.RcodeSuccess), 10 * time.Second},
+		{"nxdomain", withRcode(dns.RcodeNameError), 10 * time.Second},
+		{"nxdomain with SOA", withSOA(5, 60), 5 * time.Second},
+		{"nxdomain with SOA minimum", withSOA(60, 3), 3 * time.Second},
+		{"nxdomain with capped SOA", withSOA(60, 60), 10 * time.Second},
+		{"server failure", withRcode(dns.RcodeServerFailure), 0},
+		{"refused", withRcode(dns.RcodeRefused), 0},
+		{"truncated", truncated, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ttl, ok := c.ttl(tc.response)
+			assert.Equal(t, ok, tc.want > 0)
+			if ok {
+				assert.Equal(t, ttl, tc.want)
+			}
+		})
+	}
+
+	noNegative, _ := newTestCache(ResponseCacheOptions{MaxEntries: 10, MaxTTL: time.Minute})
+	_, ok := noNegative.ttl(withRcode(dns.RcodeNameError))
+	assert.Equal(t, ok, false)
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	c, _ := newTestCache(ResponseCacheOptions{MaxEntries: 2, MaxTTL: time.Minute})
+	first, second, third := question("first.com.", dns.TypeA), question("second.com.", dns.TypeA), question("third.com.", dns.TypeA)
+	c.add(first, answer(first, 30, "1.1.1.1"))
+	c.add(second, answer(second, 30, "2.2.2.2"))
+	// Using the first entry makes the second one the least recently used.
+	c.get(first)
+	c.add(third, answer(third, 30, "3.3.3.3"))
+
+	for req, want := range map[*dns.Msg]bool{first: true, second: false, third: true} {
+		got, _ := c.get(req)
+		assert.Equal(t, got != nil, want)
+	}
+}
+
+func TestResponseCachePrefetch(t *testing.T) {
+	c, now := newTestCache(ResponseCacheOptions{MaxEntries: 10, MaxTTL: time.Minute, Prefetch: true})
+	req := question("www.example.com.", dns.TypeA)
+	c.add(req, answer(req, 100, "1.1.1.1"))
+
+	// Entries are not prefetched before the end of their TTL...
+	_, prefetch := c.get(req)
+	assert.Equal(t, prefetch, false)
+	*now = now.Add(55 * time.Second)
+	// ... and only once.
+	_, prefetch = c.get(req)
+	assert.Equal(t, prefetch, true)
+	_, prefetch = c.get(req)
+	assert.Equal(t, prefetch, false)
+
+	// Entries requested once are not prefetched.
+	other := question("other.example.com.", dns.TypeA)
+	c.add(other, answer(other, 100, "1.1.1.1"))
+	*now = now.Add(55 * time.Second)
+	_, prefetch = c.get(other)
+	assert.Equal(t, prefetch, false)
+}
+
+func TestDNSResponseCache(t *testing.T) {
+	var upstreamRequests atomic.Int32
+	up := makeUpstream(t, map[string]string{"www.bing.com.": "1.1.1.1"})
+	countingUpstream := func(w dns.ResponseWriter, req *dns.Msg) {
+		upstreamRequests.Inc()
+		resp, err := dns.Exchange(req, up)
+		if err != nil {
+			resp = serverFailure(req)
+		}
+		_ = w.WriteMsg(resp)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{Addr: "127.0.0.1:0", Net: "udp", Handler: dns.HandlerFunc(countingUpstream), NotifyStartedFunc: func() { close(started) }}
+	go func() { _ = server.ListenAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0",
+		WithResponseCache(ResponseCacheOptions{MaxEntries: 10, MaxTTL: time.Minute, NegativeTTL: time.Minute}))
+	assert.NoError(t, err)
+	d.resolvConfServers = []string{server.PacketConn.LocalAddr().String()}
+	d.StartDNS()
+	fillTable(d)
+	t.Cleanup(d.Close)
+
+	client := dns.Client{Net: "udp"}
+	query := func(name string, wantRcode int) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			res, _, err := client.Exchange(question(name, dns.TypeA), d.dnsProxies[0].Address())
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, res.Rcode, wantRcode)
+			return nil
+		})
+	}
+	for range 3 {
+		query("www.bing.com.", dns.RcodeSuccess)
+		query("nxdomain.bing.com.", dns.RcodeNameError)
+		// Mesh hosts are not forwarded upstream.
+		query("productpage.ns1.svc.cluster.local.", dns.RcodeSuccess)
+	}
+	assert.Equal(t, upstreamRequests.Load(), int32(2))
+}
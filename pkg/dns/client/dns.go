@@ -61,6 +61,16 @@ type LocalDNSServer struct {
 	respondBeforeSync         bool
 	forwardToUpstreamParallel bool
 	upstreamTimeout           time.Duration
+
+	// cache holds the upstream responses, if enabled.
+	cache *responseCache
+
+	// encryptedUpstreams, if set, are queried instead of the resolv.conf servers.
+	encryptedUpstreamOptions EncryptedUpstreamOptions
+	encryptedUpstreams       []upstreamServer
+
+	// queryLog records the queries, if enabled.
+	queryLog *queryLog
 }
 
 // LookupTable is borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hostsfile.go
@@ -80,6 +90,10 @@ type LookupTable struct {
 	// The cname records here (comprised of different variants of the hosts above,
 	// expanded by the search namespaces) pointing to the actual host.
 	cname map[string][]dns.RR
+	// The srv records of the named ports of the hosts, keyed by _<port name>._<protocol>.<host>.
+	srv map[string][]dns.RR
+	// The ptr records of the addresses of the hosts, keyed by the reverse lookup name (like 4.3.2.1.in-addr.arpa.).
+	ptr map[string][]dns.RR
 }
 
 const (
@@ -170,7 +184,13 @@ func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, opts ...
 		h.searchNamespaces = dnsConfig.Search
 	}
 
-	log.WithLabels("search", h.searchNamespaces, "servers", h.resolvConfServers).Debugf("initialized DNS")
+	if len(h.encryptedUpstreamOptions.Servers) > 0 {
+		if h.encryptedUpstreams, err = newEncryptedUpstreams(h.encryptedUpstreamOptions, h.upstreamTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	log.WithLabels("search", h.searchNamespaces, "servers", h.resolvConfServers, "encrypted", h.encryptedUpstreams).Debugf("initialized DNS")
 
 	if addr == "" {
 		addr = constants.DefaultDNSProxyAddr
@@ -219,8 +239,25 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 		name4:    map[string][]dns.RR{},
 		name6:    map[string][]dns.RR{},
 		cname:    map[string][]dns.RR{},
+		srv:      map[string][]dns.RR{},
+		ptr:      map[string][]dns.RR{},
+	}
+	endpointHosts := headlessEndpointHosts(nt)
+	h.forEachHost(nt, func(hostname string, ni *dnsProto.NameTable_NameInfo, altHosts sets.String, ipv4, ipv6 []netip.Addr) {
+		lookupTable.buildDNSAnswers(altHosts, ipv4, ipv6, h.searchNamespaces)
+		if ni.Headless {
+			lookupTable.buildHeadlessSRVAnswers(hostname, altHosts, ni.Ports, ipv4, ipv6, endpointHosts[hostname])
+		} else {
+			lookupTable.buildSRVAnswers(hostname, altHosts, ni.Ports)
+		}
+		lookupTable.buildPTRAnswers(hostname, ipv4, ipv6)
+	})
+	// An address may belong to several hosts, keep their order stable.
+	for _, records := range lookupTable.ptr {
+		slices.SortFunc(records, func(a, b dns.RR) int {
+			return strings.Compare(a.(*dns.PTR).Ptr, b.(*dns.PTR).Ptr)
+		})
 	}
-	h.BuildAlternateHosts(nt, lookupTable.buildDNSAnswers)
 	h.lookupTable.Store(lookupTable)
 	h.nameTable.Store(nt)
 	log.Debugf("updated lookup table with %d hosts", len(lookupTable.allHosts))
@@ -230,6 +267,15 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 // calls the passed in function with the built alternate hosts.
 func (h *LocalDNSServer) BuildAlternateHosts(nt *dnsProto.NameTable,
 	apply func(map[string]struct{}, []netip.Addr, []netip.Addr, []string),
+) {
+	h.forEachHost(nt, func(_ string, _ *dnsProto.NameTable_NameInfo, altHosts sets.String, ipv4, ipv6 []netip.Addr) {
+		apply(altHosts, ipv4, ipv6, h.searchNamespaces)
+	})
+}
+
+// forEachHost calls the passed in function with each host of the name table, its alternate hosts and addresses.
+func (h *LocalDNSServer) forEachHost(nt *dnsProto.NameTable,
+	apply func(string, *dnsProto.NameTable_NameInfo, sets.String, []netip.Addr, []netip.Addr),
 ) {
 	for hostname, ni := range nt.Table {
 		// Given a host
@@ -250,12 +296,22 @@ func (h *LocalDNSServer) BuildAlternateHosts(nt *dnsProto.NameTable,
 			// malformed ips
 			continue
 		}
-		apply(altHosts, ipv4, ipv6, h.searchNamespaces)
+		apply(hostname, ni, altHosts, ipv4, ipv6)
 	}
 }
 
-// upstream sends the request to the upstream server, with associated logs and metrics
-func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string) *dns.Msg {
+// upstream sends the request to the upstream server, with associated logs and metrics. It also returns whether the
+// response came from the cache or the upstream server.
+func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string) (*dns.Msg, querySource) {
+	if h.cache != nil {
+		if response, prefetch := h.cache.get(req); response != nil {
+			log.Debugf("response for hostname %q found in the upstream response cache", hostname)
+			if prefetch {
+				go h.prefetch(proxy, req.Copy(), hostname)
+			}
+			return response, sourceCache
+		}
+	}
 	upstreamRequests.Increment()
 	start := time.Now()
 	// We did not find the host in our internal cache. Query upstream and return the response as is.
@@ -263,7 +319,18 @@ func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string
 	response := h.queryUpstream(proxy.upstreamClient, req, log)
 	requestDuration.Record(time.Since(start).Seconds())
 	log.Debugf("upstream response for hostname %q : %v", hostname, response)
-	return response
+	if h.cache != nil {
+		h.cache.add(req, response)
+	}
+	return response, sourceUpstream
+}
+
+// prefetch refreshes the cached response to a frequently requested hostname before it expires.
+func (h *LocalDNSServer) prefetch(proxy *dnsProxy, req *dns.Msg, hostname string) {
+	cachePrefetches.Increment()
+	upstreamRequests.Increment()
+	log.Debugf("prefetching upstream response for hostname %q", hostname)
+	h.cache.add(req, h.queryUpstream(proxy.upstreamClient, req, log))
 }
 
 // ServeDNS is the implementation of DNS interface
@@ -285,11 +352,17 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 		return
 	}
 
+	start := time.Now()
+	source := sourceLocal
+	defer func() {
+		h.observeQuery(proxy, req, response, source, start)
+	}()
+
 	lp := h.lookupTable.Load()
 	hostname := strings.ToLower(req.Question[0].Name)
 	if lp == nil {
 		if h.respondBeforeSync {
-			response = h.upstream(proxy, req, hostname)
+			response, source = h.upstream(proxy, req, hostname)
 			response.Truncate(size(proxy.protocol, req))
 			_ = w.WriteMsg(response)
 		} else {
@@ -319,6 +392,9 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 		// a client (ie curl, see https://github.com/istio/istio/issues/31250) sending parallel
 		// requests for A and AAAA may get NXDOMAIN for AAAA and treat the entire thing as a NXDOMAIN
 		response.Answer = answers
+		if req.Question[0].Qtype == dns.TypeSRV {
+			response.Extra = lookupTable.srvAdditionals(answers)
+		}
 		// Randomize the responses; this ensures for things like headless services we can do DNS-LB
 		// This matches standard kube-dns behavior. We only do this for cached responses as the
 		// upstream DNS server would already round robin if desired.
@@ -327,7 +403,7 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 		}
 		log.Debugf("response for hostname %q (found=true): %v", hostname, response)
 	} else {
-		response = h.upstream(proxy, req, hostname)
+		response, source = h.upstream(proxy, req, hostname)
 	}
 	// Compress the response - we don't know if the incoming response was compressed or not. If it was,
 	// but we don't compress on the outbound, we will run into issues. For example, if the compressed
@@ -418,29 +494,47 @@ func (h *LocalDNSServer) Close() {
 }
 
 func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
+	servers := h.encryptedUpstreams
+	if len(servers) == 0 {
+		servers = plainUpstreams(h.resolvConfServers)
+	}
+	response := h.queryUpstreamServers(upstreamClient, servers, req, scope)
+	if response == nil && len(h.encryptedUpstreams) > 0 && h.encryptedUpstreamOptions.Fallback == FallbackResolvConf {
+		scope.Infof("encrypted upstream failure, falling back to resolv.conf servers")
+		response = h.queryUpstreamServers(upstreamClient, plainUpstreams(h.resolvConfServers), req, scope)
+	}
+
+	if response == nil {
+		response = serverFailure(req)
+	}
+	return response
+}
+
+// queryUpstreamServers returns the response of the first of the servers to answer, or nil if none did.
+func (h *LocalDNSServer) queryUpstreamServers(upstreamClient *dns.Client, servers []upstreamServer, req *dns.Msg,
+	scope *istiolog.Scope,
+) *dns.Msg {
+	if len(servers) == 0 {
+		return nil
+	}
 	if h.forwardToUpstreamParallel {
-		return h.queryUpstreamParallel(upstreamClient, req, scope)
+		return h.queryUpstreamParallel(upstreamClient, servers, req, scope)
 	}
 
-	var response *dns.Msg
-	servers := slices.Clone(h.resolvConfServers)
+	servers = slices.Clone(servers)
 	roundRobinShuffle(servers)
 	for _, upstream := range servers {
-		cResponse, _, err := upstreamClient.Exchange(req, upstream)
+		response, err := upstream.exchange(context.Background(), upstreamClient, req)
 		if err == nil {
-			response = cResponse
-			break
+			return response
 		}
 		scope.Infof("upstream failure: %v", err)
 	}
-
-	if response == nil {
-		response = serverFailure(req)
-	}
-	return response
+	return nil
 }
 
-// queryUpstreamParallel will send parallel queries to all nameservers and return first successful response immediately.
+// queryUpstreamParallel will send parallel queries to all nameservers and return first successful response immediately,
+// or nil if all of them failed.
 // The overall approach of parallel resolution is likely not widespread, but there are already some widely used
 // clients support it:
 //
@@ -450,7 +544,9 @@ func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg,
 //     response—or defer to the operating system, which we have no control over.
 //   - systemd-resolved: which is used as a default resolver in many Linux distributions nowadays also performs parallel
 //     lookups for multiple DNS servers and returns the first successful response.
-func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
+func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, servers []upstreamServer, req *dns.Msg,
+	scope *istiolog.Scope,
+) *dns.Msg {
 	// Guarantee that the ctx we use below is done when this function returns.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -458,9 +554,9 @@ func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *
 	responseCh := make(chan *dns.Msg)
 	errCh := make(chan error)
 
-	queryOne := func(upstream string) {
+	queryOne := func(upstream upstreamServer) {
 		// Note: After DialContext in ExchangeContext is called, this function cannot be cancelled by context.
-		cResponse, _, err := upstreamClient.ExchangeContext(ctx, req, upstream)
+		cResponse, err := upstream.exchange(ctx, upstreamClient, req)
 		if err == nil {
 			// Only reserve first response and ignore others.
 			select {
@@ -476,7 +572,7 @@ func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *
 		}
 	}
 
-	for _, upstream := range h.resolvConfServers {
+	for _, upstream := range servers {
 		go queryOne(upstream)
 	}
 
@@ -489,9 +585,9 @@ func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *
 		case <-errCh:
 			errorsCount++
 			// All servers returned error - return failure.
-			if errorsCount == len(h.resolvConfServers) {
+			if errorsCount == len(servers) {
 				scope.Infof("all upstream failed")
-				return serverFailure(req)
+				return nil
 			}
 		}
 	}
@@ -540,6 +636,16 @@ func generateAltHosts(hostname string, nameinfo *dnsProto.NameTable_NameInfo, pr
 // If it is not part of the registry, return nil so that caller queries upstream. If it is part
 // of registry, we will look it up in one of our tables, failing which we will return NXDOMAIN.
 func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, bool) {
+	// SRV and PTR records are only known for some names of the hosts, let the upstream server answer for the others.
+	switch qtype {
+	case dns.TypeSRV:
+		answers, found := table.srv[hostname]
+		return answers, found
+	case dns.TypePTR:
+		answers, found := table.ptr[hostname]
+		return answers, found
+	}
+
 	question := string(host.Name(hostname))
 	wildcard := false
 	// First check if host exists in all hosts.
@@ -585,7 +691,6 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 	case dns.TypeAAAA:
 		ipAnswers = table.name6[hostname]
 	default:
-		// TODO: handle PTR records for reverse dns lookups
 		return nil, false
 	}
 
@@ -668,6 +773,118 @@ func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []n
 	}
 }
 
+// buildSRVAnswers stores the SRV records of the named ports of a host, for each of its alternate hosts. Like
+// Kubernetes DNS does for services, the records point to the host itself, which resolves to its addresses.
+func (table *LookupTable) buildSRVAnswers(hostname string, altHosts map[string]struct{}, ports []*dnsProto.NameTable_Port) {
+	if strings.HasPrefix(hostname, "*") {
+		return
+	}
+	target := dns.Fqdn(strings.ToLower(hostname))
+	for h := range altHosts {
+		h = strings.ToLower(h)
+		for _, port := range ports {
+			if port.Name == "" || port.Protocol == "" {
+				continue
+			}
+			name := "_" + strings.ToLower(port.Name) + "._" + port.Protocol + "." + h
+			table.srv[name] = append(table.srv[name], srv(name, target, port.Number))
+		}
+	}
+}
+
+// buildHeadlessSRVAnswers stores the SRV records of the named ports of a headless service, for each of its alternate
+// hosts. Like Kubernetes DNS does for headless services, there is a record per endpoint pointing to the endpoint:
+// <hostname>.<service host> for the endpoints with a hostname, and otherwise a name made of the address of the
+// endpoint with dashes, such as 10-0-0-1.<service host>, which is added to the table to resolve to the endpoint.
+func (table *LookupTable) buildHeadlessSRVAnswers(hostname string, altHosts map[string]struct{},
+	ports []*dnsProto.NameTable_Port, ipv4, ipv6 []netip.Addr, endpointHosts map[netip.Addr]string,
+) {
+	if strings.HasPrefix(hostname, "*") {
+		return
+	}
+	host := dns.Fqdn(strings.ToLower(hostname))
+	targets := make([]string, 0, len(ipv4)+len(ipv6))
+	for _, ip := range append(slices.Clone(ipv4), ipv6...) {
+		target, f := endpointHosts[ip]
+		if !f {
+			target = strings.NewReplacer(".", "-", ":", "-").Replace(ip.String()) + "." + host
+			if ip.Is4() {
+				table.name4[target] = a(target, []netip.Addr{ip})
+			} else {
+				table.name6[target] = aaaa(target, []netip.Addr{ip})
+			}
+			table.allHosts.Insert(target)
+		}
+		targets = append(targets, target)
+	}
+	for h := range altHosts {
+		h = strings.ToLower(h)
+		for _, port := range ports {
+			if port.Name == "" || port.Protocol == "" {
+				continue
+			}
+			name := "_" + strings.ToLower(port.Name) + "._" + port.Protocol + "." + h
+			for _, target := range targets {
+				table.srv[name] = append(table.srv[name], srv(name, target, port.Number))
+			}
+		}
+	}
+}
+
+// headlessEndpointHosts returns the hosts of the endpoints with a hostname of the headless services of the name table,
+// keyed by the service host and the address of the endpoint. These hosts, such as mysql-0.mysql.default.svc.cluster.local,
+// are in the name table as well.
+func headlessEndpointHosts(nt *dnsProto.NameTable) map[string]map[netip.Addr]string {
+	out := map[string]map[netip.Addr]string{}
+	for hostname, ni := range nt.Table {
+		_, service, found := strings.Cut(hostname, ".")
+		if !found || !nt.Table[service].GetHeadless() {
+			continue
+		}
+		for _, ip := range ni.Ips {
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				continue
+			}
+			if out[service] == nil {
+				out[service] = map[netip.Addr]string{}
+			}
+			out[service][addr] = dns.Fqdn(strings.ToLower(hostname))
+		}
+	}
+	return out
+}
+
+// srvAdditionals returns the address records of the targets of the SRV records, for the additional section of the
+// response, so that clients do not have to resolve them.
+func (table *LookupTable) srvAdditionals(answers []dns.RR) []dns.RR {
+	var out []dns.RR
+	for _, answer := range answers {
+		record, ok := answer.(*dns.SRV)
+		if !ok {
+			continue
+		}
+		out = append(out, table.name4[record.Target]...)
+		out = append(out, table.name6[record.Target]...)
+	}
+	return out
+}
+
+// buildPTRAnswers stores the PTR records of the addresses of a host, for reverse lookups.
+func (table *LookupTable) buildPTRAnswers(hostname string, ipv4 []netip.Addr, ipv6 []netip.Addr) {
+	if strings.HasPrefix(hostname, "*") {
+		return
+	}
+	target := dns.Fqdn(strings.ToLower(hostname))
+	for _, ip := range append(slices.Clone(ipv4), ipv6...) {
+		name, err := dns.ReverseAddr(ip.String())
+		if err != nil {
+			continue
+		}
+		table.ptr[name] = append(table.ptr[name], ptr(name, target))
+	}
+}
+
 // Borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hosts.go
 // a takes a slice of ip string and returns a slice of A RRs.
 func a(host string, ips []netip.Addr) []dns.RR {
@@ -705,6 +922,33 @@ func cname(host string, targetHost string) []dns.RR {
 	return []dns.RR{answer}
 }
 
+func srv(name string, target string, port uint32) dns.RR {
+	answer := new(dns.SRV)
+	answer.Hdr = dns.RR_Header{
+		Name:   name,
+		Rrtype: dns.TypeSRV,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLInSeconds,
+	}
+	answer.Priority = 0
+	answer.Weight = 100
+	answer.Port = uint16(port)
+	answer.Target = target
+	return answer
+}
+
+func ptr(name string, target string) dns.RR {
+	answer := new(dns.PTR)
+	answer.Hdr = dns.RR_Header{
+		Name:   name,
+		Rrtype: dns.TypePTR,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLInSeconds,
+	}
+	answer.Ptr = target
+	return answer
+}
+
 // Size returns if buffer size *advertised* in the requests OPT record.
 // Or when the request was over TCP, we return the maximum allowed size of 64K.
 func size(proto string, r *dns.Msg) int {
@@ -125,12 +125,62 @@ func TestBuildAlternateHosts(t *testing.T) {
 	}
 }
 
+func TestHeadlessSRVAnswers(t *testing.T) {
+	d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.UpdateLookupTable(&dnsProto.NameTable{
+		Table: map[string]*dnsProto.NameTable_NameInfo{
+			"mysql.ns1.svc.cluster.local": {
+				Ips:       []string{"20.0.0.1", "20.0.0.2", "2001:db8::3"},
+				Registry:  "Kubernetes",
+				Namespace: "ns1",
+				Shortname: "mysql",
+				Ports:     []*dnsProto.NameTable_Port{{Name: "mysql", Number: 3306, Protocol: "tcp"}},
+				Headless:  true,
+			},
+			"mysql-0.mysql.ns1.svc.cluster.local": {
+				Ips:       []string{"20.0.0.1"},
+				Registry:  "Kubernetes",
+				Namespace: "ns1",
+				Shortname: "mysql-0.mysql",
+			},
+		},
+	})
+	table := d.lookupTable.Load().(*LookupTable)
+
+	name := "_mysql._tcp.mysql.ns1.svc.cluster.local."
+	answers := table.srv[name]
+	expected := []dns.RR{
+		srv(name, "mysql-0.mysql.ns1.svc.cluster.local.", 3306),
+		srv(name, "20-0-0-2.mysql.ns1.svc.cluster.local.", 3306),
+		srv(name, "2001-db8--3.mysql.ns1.svc.cluster.local.", 3306),
+	}
+	if !equalsDNSrecords(answers, expected) {
+		t.Errorf("srv records do not match.\n got %v\nwant %v", answers, expected)
+	}
+	if got := table.srv["_mysql._tcp.mysql."]; len(got) != len(expected) {
+		t.Errorf("expected %d srv records for the short name, got %v", len(expected), got)
+	}
+
+	extra := table.srvAdditionals(answers)
+	expectedExtra := append(append(
+		a("mysql-0.mysql.ns1.svc.cluster.local.", []netip.Addr{netip.MustParseAddr("20.0.0.1")}),
+		a("20-0-0-2.mysql.ns1.svc.cluster.local.", []netip.Addr{netip.MustParseAddr("20.0.0.2")})...),
+		aaaa("2001-db8--3.mysql.ns1.svc.cluster.local.", []netip.Addr{netip.MustParseAddr("2001:db8::3")})...)
+	if !equalsDNSrecords(extra, expectedExtra) {
+		t.Errorf("additional records do not match.\n got %v\nwant %v", extra, expectedExtra)
+	}
+}
+
 func testDNS(t *testing.T, d *LocalDNSServer) {
 	testCases := []struct {
 		name                     string
 		host                     string
 		id                       int
 		queryAAAA                bool
+		qtype                    uint16
 		expected                 []dns.RR
 		expectResolutionFailure  int
 		expectExternalResolution bool
@@ -341,6 +391,54 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 			host:     "example.localhost.",
 			expected: a("example.localhost.", []netip.Addr{netip.MustParseAddr("3.3.3.3")}),
 		},
+		{
+			name:     "success: SRV query for a named port of a k8s host",
+			host:     "_http._tcp.productpage.ns1.svc.cluster.local.",
+			qtype:    dns.TypeSRV,
+			expected: []dns.RR{srv("_http._tcp.productpage.ns1.svc.cluster.local.", "productpage.ns1.svc.cluster.local.", 9080)},
+		},
+		{
+			name:     "success: SRV query for a named port of a k8s host - shortname",
+			host:     "_http._tcp.productpage.",
+			qtype:    dns.TypeSRV,
+			expected: []dns.RR{srv("_http._tcp.productpage.", "productpage.ns1.svc.cluster.local.", 9080)},
+		},
+		{
+			name:                    "failure: SRV query for an unknown port is forwarded upstream",
+			host:                    "_grpc._tcp.productpage.ns1.svc.cluster.local.",
+			qtype:                   dns.TypeSRV,
+			expectResolutionFailure: dns.RcodeNameError,
+		},
+		{
+			name:     "success: PTR query for the address of a k8s host",
+			host:     "9.9.9.9.in-addr.arpa.",
+			qtype:    dns.TypePTR,
+			expected: []dns.RR{ptr("9.9.9.9.in-addr.arpa.", "productpage.ns1.svc.cluster.local.")},
+		},
+		{
+			name:  "success: PTR query for an address shared by several hosts",
+			host:  "2.2.2.2.in-addr.arpa.",
+			qtype: dns.TypePTR,
+			expected: []dns.RR{
+				ptr("2.2.2.2.in-addr.arpa.", "dual.localhost."),
+				ptr("2.2.2.2.in-addr.arpa.", "ipv4.localhost."),
+			},
+		},
+		{
+			name:  "success: PTR query for an IPv6 address",
+			host:  "9.2.3.8.2.4.0.0.0.0.f.f.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+			qtype: dns.TypePTR,
+			expected: []dns.RR{
+				ptr("9.2.3.8.2.4.0.0.0.0.f.f.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "dual.localhost."),
+				ptr("9.2.3.8.2.4.0.0.0.0.f.f.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "ipv6.localhost."),
+			},
+		},
+		{
+			name:                    "failure: PTR query for the address of a wildcard host is forwarded upstream",
+			host:                    "3.2.1.10.in-addr.arpa.",
+			qtype:                   dns.TypePTR,
+			expectResolutionFailure: dns.RcodeNameError,
+		},
 	}
 
 	clients := []dns.Client{
@@ -377,6 +475,9 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 				if tt.queryAAAA {
 					q = dns.TypeAAAA
 				}
+				if tt.qtype != 0 {
+					q = tt.qtype
+				}
 				m.SetQuestion(tt.host, q)
 				if tt.modifyReq != nil {
 					tt.modifyReq(m)
@@ -617,6 +718,7 @@ func fillTable(server *LocalDNSServer) {
 				Registry:  "Kubernetes",
 				Namespace: "ns1",
 				Shortname: "productpage",
+				Ports:     []*dnsProto.NameTable_Port{{Name: "http", Number: 9080, Protocol: "tcp"}},
 			},
 			"example.ns2.svc.cluster.local": {
 				Ips:       []string{"10.10.10.10"},
@@ -39,4 +39,42 @@ var (
 		"Total time in seconds Istio takes to get DNS response from upstream.",
 		[]float64{.001, .005, 0.01, 0.1, 1, 5},
 	)
+
+	resultLabel = monitoring.CreateLabel("result")
+
+	cacheLookups = monitoring.NewSum(
+		"dns_cache_lookups_total",
+		"Total number of lookups of the upstream response cache, by result (hit or miss).",
+	)
+
+	cachePrefetches = monitoring.NewSum(
+		"dns_cache_prefetches_total",
+		"Total number of upstream responses refreshed before their expiry.",
+	)
+
+	cacheEvictions = monitoring.NewSum(
+		"dns_cache_evictions_total",
+		"Total number of upstream responses evicted from the full cache.",
+	)
+
+	cacheEntries = monitoring.NewGauge(
+		"dns_cache_entries",
+		"Number of upstream responses in the cache.",
+	)
+
+	typeLabel   = monitoring.CreateLabel("type")
+	sourceLabel = monitoring.CreateLabel("source")
+	rcodeLabel  = monitoring.CreateLabel("rcode")
+	hostLabel   = monitoring.CreateLabel("host")
+
+	queries = monitoring.NewSum(
+		"dns_queries_total",
+		"Total number of DNS queries answered, by type, source (local, cache or upstream) and response code.",
+	)
+
+	hostQueries = monitoring.NewSum(
+		"dns_host_queries_total",
+		"Total number of DNS queries answered from the cache or upstream, by host. Only a bounded number of hosts is "+
+			"recorded, the further ones have the host \"other\".",
+	)
 )
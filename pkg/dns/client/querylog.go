@@ -0,0 +1,189 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/util/sets"
+)
+
+var queryLogScope = istiolog.RegisterScope("dnsquery", "Queries answered by the Istio DNS proxy")
+
+// querySource tells where the answer to a query came from.
+type querySource string
+
+const (
+	// sourceLocal queries were answered from the name table.
+	sourceLocal querySource = "local"
+	// sourceCache queries were answered from the upstream response cache.
+	sourceCache querySource = "cache"
+	// sourceUpstream queries were forwarded to the upstream servers.
+	sourceUpstream querySource = "upstream"
+
+	// otherHost is the host label of the upstream queries beyond QueryLogOptions.MaxHosts distinct names.
+	otherHost = "other"
+)
+
+// QueryLogOptions configures the observability of the queries received by the DNS proxy.
+type QueryLogOptions struct {
+	// Log enables logging every query, with its name, type, source, response code and latency, to the dnsquery scope.
+	Log bool
+	// HistorySize is the number of recent queries kept for debugging. Zero disables the history.
+	HistorySize int
+	// MaxHosts is the maximum number of distinct names resolved upstream recorded in the per host metrics. The
+	// queries for further names are recorded with the "other" host. Zero disables the per host metrics.
+	MaxHosts int
+}
+
+// WithQueryLog enables the logging, history and per host metrics of the queries.
+func WithQueryLog(opts QueryLogOptions) LocalDNSServerOption {
+	return func(s *LocalDNSServer) {
+		s.queryLog = newQueryLog(opts)
+	}
+}
+
+// QueryRecord describes a query answered by the DNS proxy.
+type QueryRecord struct {
+	Time     time.Time `json:"time"`
+	Protocol string    `json:"protocol"`
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	// Source is where the answer came from: local (the name table), cache or upstream.
+	Source string `json:"source"`
+	Rcode  string `json:"rcode"`
+	// Answers is the number of records in the answer section of the response.
+	Answers int `json:"answers"`
+	// Latency is the time spent answering the query, in nanoseconds.
+	Latency time.Duration `json:"latency"`
+}
+
+// queryLog records the queries received by the DNS proxy.
+type queryLog struct {
+	opts QueryLogOptions
+
+	mu sync.Mutex
+	// history is a ring buffer of the recent queries, next is the index of the oldest one once it is full.
+	history []QueryRecord
+	next    int
+	// hosts are the names with their own host label.
+	hosts sets.String
+}
+
+func newQueryLog(opts QueryLogOptions) *queryLog {
+	return &queryLog{
+		opts:    opts,
+		history: make([]QueryRecord, 0, max(opts.HistorySize, 0)),
+		hosts:   sets.New[string](),
+	}
+}
+
+// observeQuery records the metrics, and if enabled the log and history, of an answered query.
+func (h *LocalDNSServer) observeQuery(proxy *dnsProxy, req, response *dns.Msg, source querySource, start time.Time) {
+	q := req.Question[0]
+	qtype := typeName(q.Qtype)
+	rcode := rcodeName(response.Rcode)
+	queries.With(typeLabel.Value(qtype), sourceLabel.Value(string(source)), rcodeLabel.Value(rcode)).Increment()
+
+	ql := h.queryLog
+	if ql == nil {
+		return
+	}
+	record := QueryRecord{
+		Time:     start,
+		Protocol: proxy.protocol,
+		Name:     strings.ToLower(q.Name),
+		Type:     qtype,
+		Source:   string(source),
+		Rcode:    rcode,
+		Answers:  len(response.Answer),
+		Latency:  time.Since(start),
+	}
+	if ql.opts.Log {
+		queryLogScope.WithLabels("name", record.Name, "type", record.Type, "source", record.Source,
+			"rcode", record.Rcode, "answers", record.Answers, "latency", record.Latency).Info("dns query")
+	}
+	if source != sourceLocal && ql.opts.MaxHosts > 0 {
+		hostQueries.With(hostLabel.Value(ql.host(record.Name)), sourceLabel.Value(string(source))).Increment()
+	}
+	ql.add(record)
+}
+
+// host returns the host label of the name, bounding the cardinality of the per host metrics.
+func (ql *queryLog) host(name string) string {
+	name = strings.TrimSuffix(name, ".")
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+	if ql.hosts.Contains(name) {
+		return name
+	}
+	if ql.hosts.Len() >= ql.opts.MaxHosts {
+		return otherHost
+	}
+	ql.hosts.Insert(name)
+	return name
+}
+
+func (ql *queryLog) add(record QueryRecord) {
+	if ql.opts.HistorySize <= 0 {
+		return
+	}
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+	if len(ql.history) < ql.opts.HistorySize {
+		ql.history = append(ql.history, record)
+		return
+	}
+	ql.history[ql.next] = record
+	ql.next = (ql.next + 1) % len(ql.history)
+}
+
+// recent returns the recent queries, the oldest first.
+func (ql *queryLog) recent() []QueryRecord {
+	ql.mu.Lock()
+	defer ql.mu.Unlock()
+	records := make([]QueryRecord, 0, len(ql.history))
+	records = append(records, ql.history[ql.next:]...)
+	return append(records, ql.history[:ql.next]...)
+}
+
+// RecentQueries returns the queries recently answered by the DNS proxy, the oldest first, or nil if the history is
+// disabled.
+func (h *LocalDNSServer) RecentQueries() []QueryRecord {
+	if h.queryLog == nil || h.queryLog.opts.HistorySize <= 0 {
+		return nil
+	}
+	return h.queryLog.recent()
+}
+
+// typeName returns the name of the query type, or "other" for unknown types, to bound the cardinality of the metrics.
+func typeName(qtype uint16) string {
+	if t, ok := dns.TypeToString[qtype]; ok {
+		return t
+	}
+	return "other"
+}
+
+func rcodeName(rcode int) string {
+	if r, ok := dns.RcodeToString[rcode]; ok {
+		return r
+	}
+	return "other"
+}
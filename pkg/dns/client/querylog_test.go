@@ -0,0 +1,82 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestQueryLog(t *testing.T) {
+	mt := monitortest.New(t)
+	up := makeUpstream(t, map[string]string{"www.bing.com.": "1.1.1.1"})
+	d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0",
+		WithQueryLog(QueryLogOptions{Log: true, HistorySize: 3, MaxHosts: 1}))
+	assert.NoError(t, err)
+	d.resolvConfServers = []string{up}
+	d.StartDNS()
+	fillTable(d)
+	t.Cleanup(d.Close)
+
+	client := dns.Client{Net: "udp"}
+	query := func(name string, qtype uint16) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			_, _, err := client.Exchange(question(name, qtype), d.dnsProxies[0].Address())
+			return err
+		})
+	}
+	query("productpage.ns1.svc.cluster.local.", dns.TypeA)
+	query("www.bing.com.", dns.TypeA)
+	query("nxdomain.bing.com.", dns.TypeAAAA)
+	query("WWW.bing.com.", dns.TypeA)
+
+	// The queries are recorded once answered, wait for the last one.
+	retry.UntilOrFail(t, func() bool {
+		recent := d.RecentQueries()
+		return len(recent) == 3 && recent[0].Source == "upstream"
+	})
+	// Only the most recent queries are kept, the oldest first.
+	recent := d.RecentQueries()
+	assert.Equal(t, slices.Map(recent, func(r QueryRecord) string {
+		return r.Name + " " + r.Type + " " + r.Source + " " + r.Rcode
+	}), []string{
+		"www.bing.com. A upstream NOERROR",
+		"nxdomain.bing.com. AAAA upstream NXDOMAIN",
+		"www.bing.com. A upstream NOERROR",
+	})
+	assert.Equal(t, recent[0].Answers, 1)
+	assert.Equal(t, recent[0].Protocol, "udp")
+
+	mt.Assert(queries.Name(), map[string]string{"type": "A", "source": "local", "rcode": "NOERROR"}, monitortest.Exactly(1))
+	mt.Assert(queries.Name(), map[string]string{"type": "A", "source": "upstream", "rcode": "NOERROR"}, monitortest.Exactly(2))
+	mt.Assert(queries.Name(), map[string]string{"type": "AAAA", "source": "upstream", "rcode": "NXDOMAIN"}, monitortest.Exactly(1))
+	// The names beyond MaxHosts share a host label.
+	mt.Assert(hostQueries.Name(), map[string]string{"host": "www.bing.com"}, monitortest.Exactly(2))
+	mt.Assert(hostQueries.Name(), map[string]string{"host": "other"}, monitortest.Exactly(1))
+}
+
+func TestQueryLogDisabled(t *testing.T) {
+	d := initDNS(t, false)
+	_, _, err := new(dns.Client).Exchange(question("www.bing.com.", dns.TypeA), d.dnsProxies[0].Address())
+	assert.NoError(t, err)
+	assert.Equal(t, d.RecentQueries(), nil)
+}
@@ -0,0 +1,263 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// UpstreamFallbackPolicy decides what happens when none of the encrypted upstream servers answers.
+type UpstreamFallbackPolicy string
+
+const (
+	// FallbackNone fails the queries, so that names are never resolved in clear text.
+	FallbackNone UpstreamFallbackPolicy = "none"
+	// FallbackResolvConf forwards the queries to the resolv.conf servers over plain UDP/TCP.
+	FallbackResolvConf UpstreamFallbackPolicy = "resolv-conf"
+
+	dohContentType = "application/dns-message"
+	// maxDoHResponseSize is the maximum size of a DNS message.
+	maxDoHResponseSize = 65535
+	// maxIdleDoTConns is the number of connections to each DNS-over-TLS server kept open for reuse.
+	maxIdleDoTConns = 4
+)
+
+// EncryptedUpstreamOptions configures encrypted upstream servers, used instead of the resolv.conf servers.
+type EncryptedUpstreamOptions struct {
+	// Servers are the URLs of the upstream servers: tls://host[:port] for DNS-over-TLS (RFC 7858), port 853 by
+	// default, or https://host[:port]/path for DNS-over-HTTPS (RFC 8484). The TLS server name defaults to the
+	// host, and can be set with the servername query parameter, e.g. tls://1.1.1.1?servername=one.one.one.one.
+	Servers []string
+	// CAFile is the path of the PEM encoded certificates of the CAs trusted to issue the certificates of the servers.
+	// When set, the system roots are not trusted, pinning the CAs of the servers.
+	CAFile string
+	// Fallback decides what happens when none of the servers answers. Defaults to FallbackNone.
+	Fallback UpstreamFallbackPolicy
+}
+
+// WithEncryptedUpstreams forwards the queries to DNS-over-TLS or DNS-over-HTTPS servers instead of the resolv.conf
+// servers.
+func WithEncryptedUpstreams(opts EncryptedUpstreamOptions) LocalDNSServerOption {
+	return func(s *LocalDNSServer) {
+		s.encryptedUpstreamOptions = opts
+	}
+}
+
+// upstreamServer sends queries to an upstream DNS server.
+type upstreamServer interface {
+	// exchange sends the request to the server. client is the client of the DNS proxy which received the request,
+	// used for plain DNS.
+	exchange(ctx context.Context, client *dns.Client, req *dns.Msg) (*dns.Msg, error)
+	String() string
+}
+
+// plainUpstream is a resolv.conf server, queried over the protocol of the request.
+type plainUpstream string
+
+func (u plainUpstream) exchange(ctx context.Context, client *dns.Client, req *dns.Msg) (*dns.Msg, error) {
+	response, _, err := client.ExchangeContext(ctx, req, string(u))
+	return response, err
+}
+
+func (u plainUpstream) String() string {
+	return string(u)
+}
+
+func plainUpstreams(servers []string) []upstreamServer {
+	upstreams := make([]upstreamServer, 0, len(servers))
+	for _, s := range servers {
+		upstreams = append(upstreams, plainUpstream(s))
+	}
+	return upstreams
+}
+
+// dotUpstream is a DNS-over-TLS server. Connections are reused, to avoid a TLS handshake per query.
+type dotUpstream struct {
+	url    string
+	addr   string
+	client *dns.Client
+	idle   chan *dns.Conn
+}
+
+func (u *dotUpstream) exchange(ctx context.Context, _ *dns.Client, req *dns.Msg) (*dns.Msg, error) {
+	select {
+	case conn := <-u.idle:
+		if response, err := u.exchangeWithConn(ctx, conn, req); err == nil {
+			return response, nil
+		}
+		// The server may have closed the idle connection, retry on a new one.
+	default:
+	}
+	conn, err := u.client.DialContext(ctx, u.addr)
+	if err != nil {
+		return nil, err
+	}
+	return u.exchangeWithConn(ctx, conn, req)
+}
+
+func (u *dotUpstream) exchangeWithConn(ctx context.Context, conn *dns.Conn, req *dns.Msg) (*dns.Msg, error) {
+	response, _, err := u.client.ExchangeWithConnContext(ctx, req, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	select {
+	case u.idle <- conn:
+	default:
+		_ = conn.Close()
+	}
+	return response, nil
+}
+
+func (u *dotUpstream) String() string {
+	return u.url
+}
+
+// dohUpstream is a DNS-over-HTTPS server.
+type dohUpstream struct {
+	url    string
+	client *http.Client
+}
+
+func (u *dohUpstream) exchange(ctx context.Context, _ *dns.Client, req *dns.Msg) (*dns.Msg, error) {
+	// RFC 8484 section 4.1: the ID should be zero, for the HTTP caches.
+	query := req.Copy()
+	query.Id = 0
+	b, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", dohContentType)
+	httpReq.Header.Set("Accept", dohContentType)
+	resp, err := u.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %s", u.url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDoHResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	response := new(dns.Msg)
+	if err := response.Unpack(body); err != nil {
+		return nil, fmt.Errorf("%s: invalid response: %v", u.url, err)
+	}
+	response.Id = req.Id
+	return response, nil
+}
+
+func (u *dohUpstream) String() string {
+	return u.url
+}
+
+// newEncryptedUpstreams parses the encrypted upstream servers.
+func newEncryptedUpstreams(opts EncryptedUpstreamOptions, timeout time.Duration) ([]upstreamServer, error) {
+	var roots *x509.CertPool
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the DNS upstream CA file: %v", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the DNS upstream CA file %s", opts.CAFile)
+		}
+	}
+	var upstreams []upstreamServer
+	for _, server := range opts.Servers {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		u, err := url.Parse(server)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DNS upstream %q: %v", server, err)
+		}
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid DNS upstream %q: missing host", server)
+		}
+		serverName := u.Hostname()
+		if sn := u.Query().Get("servername"); sn != "" {
+			serverName = sn
+		}
+		tlsConfig := &tls.Config{
+			RootCAs:    roots,
+			ServerName: serverName,
+			MinVersion: tls.VersionTLS12,
+		}
+		switch u.Scheme {
+		case "tls":
+			port := u.Port()
+			if port == "" {
+				port = "853"
+			}
+			upstreams = append(upstreams, &dotUpstream{
+				url:  server,
+				addr: net.JoinHostPort(u.Hostname(), port),
+				client: &dns.Client{
+					Net:          "tcp-tls",
+					TLSConfig:    tlsConfig,
+					DialTimeout:  timeout,
+					ReadTimeout:  timeout,
+					WriteTimeout: timeout,
+				},
+				idle: make(chan *dns.Conn, maxIdleDoTConns),
+			})
+		case "https":
+			q := u.Query()
+			q.Del("servername")
+			u.RawQuery = q.Encode()
+			upstreams = append(upstreams, &dohUpstream{
+				url: u.String(),
+				client: &http.Client{
+					Timeout: timeout,
+					Transport: &http.Transport{
+						TLSClientConfig:   tlsConfig,
+						ForceAttemptHTTP2: true,
+						IdleConnTimeout:   90 * time.Second,
+					},
+				},
+			})
+		default:
+			return nil, fmt.Errorf("invalid DNS upstream %q: unsupported scheme %q, must be tls or https", server, u.Scheme)
+		}
+	}
+	switch opts.Fallback {
+	case "", FallbackNone, FallbackResolvConf:
+	default:
+		return nil, fmt.Errorf("invalid DNS upstream fallback policy %q, must be %s or %s", opts.Fallback, FallbackNone, FallbackResolvConf)
+	}
+	return upstreams, nil
+}
@@ -0,0 +1,202 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// answerWith answers A queries with the given address.
+func answerWith(ip string) func(req *dns.Msg) *dns.Msg {
+	return func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = a(req.Question[0].Name, []netip.Addr{netip.MustParseAddr(ip)})
+		return resp
+	}
+}
+
+// makeEncryptedUpstreams starts a DNS-over-HTTPS and a DNS-over-TLS server answering with the given addresses,
+// sharing a certificate for 127.0.0.1 and example.com, and returns their URLs and a file with their CA.
+func makeEncryptedUpstreams(t *testing.T, dohIP, dotIP string) (doh string, dot string, caFile string) {
+	dohServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dohContentType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := new(dns.Msg)
+		if err := req.Unpack(body); err != nil || req.Id != 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := answerWith(dohIP)(req).Pack()
+		w.Header().Set("Content-Type", dohContentType)
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(dohServer.Close)
+
+	started := make(chan struct{})
+	dotServer := &dns.Server{
+		Addr:              "127.0.0.1:0",
+		Net:               "tcp-tls",
+		TLSConfig:         &tls.Config{Certificates: dohServer.TLS.Certificates},
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			_ = w.WriteMsg(answerWith(dotIP)(req))
+		}),
+	}
+	go func() { _ = dotServer.ListenAndServe() }()
+	select {
+	case <-started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("setup timeout")
+	}
+	t.Cleanup(func() { _ = dotServer.Shutdown() })
+
+	caFile = filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: dohServer.Certificate().Raw})
+	assert.NoError(t, os.WriteFile(caFile, ca, 0o644))
+	return dohServer.URL + "/dns-query", "tls://" + dotServer.Listener.Addr().String(), caFile
+}
+
+// writeOtherCA writes a CA which did not issue the certificate of the test servers.
+func writeOtherCA(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	caFile := filepath.Join(t.TempDir(), "other.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	return caFile
+}
+
+func TestEncryptedUpstreams(t *testing.T) {
+	doh, dot, caFile := makeEncryptedUpstreams(t, "2.2.2.2", "3.3.3.3")
+	otherCA := writeOtherCA(t)
+
+	cases := []struct {
+		name     string
+		opts     EncryptedUpstreamOptions
+		parallel bool
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "dns over https",
+			opts: EncryptedUpstreamOptions{Servers: []string{doh}, CAFile: caFile},
+			want: "2.2.2.2",
+		},
+		{
+			name: "dns over tls",
+			opts: EncryptedUpstreamOptions{Servers: []string{dot}, CAFile: caFile},
+			want: "3.3.3.3",
+		},
+		{
+			name: "server name",
+			opts: EncryptedUpstreamOptions{Servers: []string{dot + "?servername=example.com"}, CAFile: caFile},
+			want: "3.3.3.3",
+		},
+		{
+			name:     "parallel",
+			opts:     EncryptedUpstreamOptions{Servers: []string{dot, dot}, CAFile: caFile},
+			parallel: true,
+			want:     "3.3.3.3",
+		},
+		{
+			name: "pinned CA mismatch",
+			opts: EncryptedUpstreamOptions{Servers: []string{doh, dot}, CAFile: otherCA},
+		},
+		{
+			name: "wrong server name",
+			opts: EncryptedUpstreamOptions{Servers: []string{dot + "?servername=other.com"}, CAFile: caFile},
+		},
+		{
+			name: "fallback to resolv.conf",
+			opts: EncryptedUpstreamOptions{Servers: []string{doh, dot}, CAFile: otherCA, Fallback: FallbackResolvConf},
+			want: "1.1.1.1",
+		},
+		{
+			name:    "unsupported scheme",
+			opts:    EncryptedUpstreamOptions{Servers: []string{"udp://1.1.1.1"}},
+			wantErr: `unsupported scheme "udp"`,
+		},
+		{
+			name:    "invalid fallback",
+			opts:    EncryptedUpstreamOptions{Servers: []string{dot}, Fallback: "always"},
+			wantErr: "invalid DNS upstream fallback policy",
+		},
+		{
+			name:    "missing CA file",
+			opts:    EncryptedUpstreamOptions{Servers: []string{dot}, CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+			wantErr: "failed to read the DNS upstream CA file",
+		},
+	}
+	plain := makeUpstream(t, map[string]string{"www.bing.com.": "1.1.1.1"})
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0",
+				WithEncryptedUpstreams(tc.opts), WithParallelForwarding(tc.parallel), WithUpstreamTimeout(2*time.Second))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			t.Cleanup(d.Close)
+			d.resolvConfServers = []string{plain}
+
+			for range 2 {
+				response := d.queryUpstream(d.dnsProxies[0].upstreamClient, question("www.bing.com.", dns.TypeA), log)
+				if tc.want == "" {
+					assert.Equal(t, response.Rcode, dns.RcodeServerFailure)
+					continue
+				}
+				assert.Equal(t, response.Rcode, dns.RcodeSuccess)
+				assert.Equal(t, response.Answer[0].(*dns.A).A.String(), tc.want)
+			}
+		})
+	}
+}
@@ -95,7 +95,13 @@ type NameTable_NameInfo struct {
 	// Deprecated. Was added for experimentation only.
 	//
 	// Deprecated: Marked as deprecated in dns/proto/nds.proto.
-	AltHosts      []string `protobuf:"bytes,5,rep,name=alt_hosts,json=altHosts,proto3" json:"alt_hosts,omitempty"`
+	AltHosts []string `protobuf:"bytes,5,rep,name=alt_hosts,json=altHosts,proto3" json:"alt_hosts,omitempty"`
+	// The named ports of the service, used to answer SRV queries for
+	// `_<port name>._<protocol>.<host>`.
+	Ports []*NameTable_Port `protobuf:"bytes,6,rep,name=ports,proto3" json:"ports,omitempty"`
+	// Whether the host is a headless service, whose ips are the addresses of its endpoints.
+	// SRV queries are then answered with a record per endpoint.
+	Headless      bool `protobuf:"varint,7,opt,name=headless,proto3" json:"headless,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -166,19 +172,103 @@ func (x *NameTable_NameInfo) GetAltHosts() []string {
 	return nil
 }
 
+func (x *NameTable_NameInfo) GetPorts() []*NameTable_Port {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+func (x *NameTable_NameInfo) GetHeadless() bool {
+	if x != nil {
+		return x.Headless
+	}
+	return false
+}
+
+// A named port of a service.
+type NameTable_Port struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The name of the port, e.g. `http`.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The port number.
+	Number uint32 `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	// The transport protocol of the port, `tcp` or `udp`.
+	Protocol      string `protobuf:"bytes,3,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NameTable_Port) Reset() {
+	*x = NameTable_Port{}
+	mi := &file_dns_proto_nds_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NameTable_Port) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NameTable_Port) ProtoMessage() {}
+
+func (x *NameTable_Port) ProtoReflect() protoreflect.Message {
+	mi := &file_dns_proto_nds_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NameTable_Port.ProtoReflect.Descriptor instead.
+func (*NameTable_Port) Descriptor() ([]byte, []int) {
+	return file_dns_proto_nds_proto_rawDescGZIP(), []int{0, 1}
+}
+
+func (x *NameTable_Port) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NameTable_Port) GetNumber() uint32 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *NameTable_Port) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
 var File_dns_proto_nds_proto protoreflect.FileDescriptor
 
 const file_dns_proto_nds_proto_rawDesc = "" +
 	"\n" +
-	"\x13dns/proto/nds.proto\x12\x17istio.networking.nds.v1\"\xcf\x02\n" +
+	"\x13dns/proto/nds.proto\x12\x17istio.networking.nds.v1\"\xfa\x03\n" +
 	"\tNameTable\x12C\n" +
-	"\x05table\x18\x01 \x03(\v2-.istio.networking.nds.v1.NameTable.TableEntryR\x05table\x1a\x95\x01\n" +
+	"\x05table\x18\x01 \x03(\v2-.istio.networking.nds.v1.NameTable.TableEntryR\x05table\x1a\xf0\x01\n" +
 	"\bNameInfo\x12\x10\n" +
 	"\x03ips\x18\x01 \x03(\tR\x03ips\x12\x1a\n" +
 	"\bregistry\x18\x02 \x01(\tR\bregistry\x12\x1c\n" +
 	"\tshortname\x18\x03 \x01(\tR\tshortname\x12\x1c\n" +
 	"\tnamespace\x18\x04 \x01(\tR\tnamespace\x12\x1f\n" +
-	"\talt_hosts\x18\x05 \x03(\tB\x02\x18\x01R\baltHosts\x1ae\n" +
+	"\talt_hosts\x18\x05 \x03(\tB\x02\x18\x01R\baltHosts\x12=\n" +
+	"\x05ports\x18\x06 \x03(\v2'.istio.networking.nds.v1.NameTable.PortR\x05ports\x12\x1a\n" +
+	"\bheadless\x18\a \x01(\bR\bheadless\x1aN\n" +
+	"\x04Port\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06number\x18\x02 \x01(\rR\x06number\x12\x1a\n" +
+	"\bprotocol\x18\x03 \x01(\tR\bprotocol\x1ae\n" +
 	"\n" +
 	"TableEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12A\n" +
@@ -196,20 +286,22 @@ func file_dns_proto_nds_proto_rawDescGZIP() []byte {
 	return file_dns_proto_nds_proto_rawDescData
 }
 
-var file_dns_proto_nds_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_dns_proto_nds_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_dns_proto_nds_proto_goTypes = []any{
 	(*NameTable)(nil),          // 0: istio.networking.nds.v1.NameTable
 	(*NameTable_NameInfo)(nil), // 1: istio.networking.nds.v1.NameTable.NameInfo
-	nil,                        // 2: istio.networking.nds.v1.NameTable.TableEntry
+	(*NameTable_Port)(nil),     // 2: istio.networking.nds.v1.NameTable.Port
+	nil,                        // 3: istio.networking.nds.v1.NameTable.TableEntry
 }
 var file_dns_proto_nds_proto_depIdxs = []int32{
-	2, // 0: istio.networking.nds.v1.NameTable.table:type_name -> istio.networking.nds.v1.NameTable.TableEntry
-	1, // 1: istio.networking.nds.v1.NameTable.TableEntry.value:type_name -> istio.networking.nds.v1.NameTable.NameInfo
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	3, // 0: istio.networking.nds.v1.NameTable.table:type_name -> istio.networking.nds.v1.NameTable.TableEntry
+	2, // 1: istio.networking.nds.v1.NameTable.NameInfo.ports:type_name -> istio.networking.nds.v1.NameTable.Port
+	1, // 2: istio.networking.nds.v1.NameTable.TableEntry.value:type_name -> istio.networking.nds.v1.NameTable.NameInfo
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_dns_proto_nds_proto_init() }
@@ -223,7 +315,7 @@ func file_dns_proto_nds_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_dns_proto_nds_proto_rawDesc), len(file_dns_proto_nds_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -22,6 +22,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/protocol"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 	netutil "istio.io/istio/pkg/util/net"
 	"istio.io/istio/pkg/util/sets"
@@ -157,6 +158,8 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 				nameInfo := &dnsProto.NameTable_NameInfo{
 					Ips:      addressList,
 					Registry: string(svc.Attributes.ServiceRegistry),
+					Ports:    namedPorts(svc),
+					Headless: headless,
 				}
 				if svc.Attributes.ServiceRegistry == provider.Kubernetes &&
 					!strings.HasSuffix(hostName.String(), "."+constants.DefaultClusterSetLocalDomain) {
@@ -176,6 +179,8 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 				if svc.Attributes.ServiceRegistry == provider.Kubernetes {
 					ni.Ips = addressList
 					ni.Registry = string(provider.Kubernetes)
+					ni.Ports = namedPorts(svc)
+					ni.Headless = headless
 					if !strings.HasSuffix(hostName.String(), "."+constants.DefaultClusterSetLocalDomain) {
 						ni.Namespace = svc.Attributes.Namespace
 						ni.Shortname = svc.Attributes.Name
@@ -188,3 +193,23 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 	}
 	return out
 }
+
+// namedPorts returns the named ports of the service, which the agent uses to answer SRV queries.
+func namedPorts(svc *model.Service) []*dnsProto.NameTable_Port {
+	var ports []*dnsProto.NameTable_Port
+	for _, port := range svc.Ports {
+		if port.Name == "" {
+			continue
+		}
+		transport := "tcp"
+		if port.Protocol == protocol.UDP {
+			transport = "udp"
+		}
+		ports = append(ports, &dnsProto.NameTable_Port{
+			Name:     port.Name,
+			Number:   uint32(port.Port),
+			Protocol: transport,
+		})
+	}
+	return ports
+}
@@ -230,6 +230,13 @@ func TestNameTable(t *testing.T) {
 		},
 	}
 
+	headlessPorts := []*dnsProto.NameTable_Port{{Name: "tcp-port", Number: 9000, Protocol: "tcp"}}
+	wildcardPorts := []*dnsProto.NameTable_Port{
+		{Name: "tcp-port", Number: 9000, Protocol: "tcp"},
+		{Name: "http-port", Number: 8000, Protocol: "tcp"},
+	}
+	mysqlPorts := []*dnsProto.NameTable_Port{{Name: "tcp", Number: 3306, Protocol: "tcp"}}
+
 	push := model.NewPushContext()
 	push.Mesh = mesh
 	push.AddPublicServices([]*model.Service{headlessService})
@@ -324,6 +331,8 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
+						Headless:  true,
 					},
 				},
 			},
@@ -357,6 +366,8 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
+						Headless:  true,
 					},
 				},
 			},
@@ -396,6 +407,8 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
+						Headless:  true,
 					},
 				},
 			},
@@ -436,6 +449,8 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
+						Headless:  true,
 					},
 				},
 			},
@@ -458,6 +473,8 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
+						Headless:  true,
 					},
 				},
 			},
@@ -473,6 +490,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "wildcard-svc",
 						Namespace: "testns",
+						Ports:     wildcardPorts,
 					},
 				},
 			},
@@ -507,6 +525,8 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
+						Headless:  true,
 					},
 				},
 			},
@@ -522,6 +542,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "wildcard-svc",
 						Namespace: "testns",
+						Ports:     wildcardPorts,
 					},
 				},
 			},
@@ -543,6 +564,8 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "9.6.7.8", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
+						Headless: true,
 					},
 				},
 			},
@@ -561,6 +584,7 @@ func TestNameTable(t *testing.T) {
 					"dual.foo.bar": {
 						Ips:      []string{"2001:2::", "10.0.0.8"},
 						Registry: "External",
+						Ports:    mysqlPorts,
 					},
 				},
 			},
@@ -574,6 +598,8 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
+						Headless: true,
 					},
 				},
 			},
@@ -587,6 +613,33 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
+						Headless: true,
+					},
+				},
+			},
+		},
+		{
+			name:  "udp and unnamed ports",
+			proxy: proxy,
+			push: func() *model.PushContext {
+				svc := serviceWithVIP1.ShallowCopy()
+				svc.Hostname = "dns.foo.bar"
+				svc.Ports = model.PortList{
+					{Name: "dns", Port: 53, Protocol: protocol.UDP},
+					{Port: 8080, Protocol: protocol.HTTP},
+				}
+				push := model.NewPushContext()
+				push.Mesh = mesh
+				push.AddPublicServices([]*model.Service{svc})
+				return push
+			}(),
+			expectedNameTable: &dnsProto.NameTable{
+				Table: map[string]*dnsProto.NameTable_NameInfo{
+					"dns.foo.bar": {
+						Ips:      []string{serviceWithVIP1.DefaultAddress},
+						Registry: provider.External.String(),
+						Ports:    []*dnsProto.NameTable_Port{{Name: "dns", Number: 53, Protocol: "udp"}},
 					},
 				},
 			},
@@ -605,6 +658,7 @@ func TestNameTable(t *testing.T) {
 					serviceWithVIP1.Hostname.String(): {
 						Ips:      []string{serviceWithVIP1.DefaultAddress, serviceWithVIP2.DefaultAddress},
 						Registry: provider.External.String(),
+						Ports:    mysqlPorts,
 					},
 				},
 			},
@@ -625,6 +679,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  provider.Kubernetes.String(),
 						Shortname: decoratedService.Attributes.Name,
 						Namespace: decoratedService.Attributes.Namespace,
+						Ports:     mysqlPorts,
 					},
 				},
 			},
@@ -645,6 +700,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  provider.Kubernetes.String(),
 						Shortname: decoratedService.Attributes.Name,
 						Namespace: decoratedService.Attributes.Namespace,
+						Ports:     mysqlPorts,
 					},
 				},
 			},
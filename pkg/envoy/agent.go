@@ -21,6 +21,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
@@ -39,6 +40,7 @@ var activeConnectionCheckDelay = 1 * time.Second
 // NewAgent creates a new proxy agent for the proxy start-up and clean-up functions.
 func NewAgent(proxy Proxy, terminationDrainDuration, minDrainDuration time.Duration, localhost string,
 	adminPort, statusPort, prometheusPort, secureMetricsPort, secureMergedMetricsPort int, exitOnZeroActiveConnections bool,
+	drainMode DrainMode,
 ) *Agent {
 	knownIstioListeners := sets.New(
 		fmt.Sprintf("listener.0.0.0.0_%d.downstream_cx_active", statusPort),
@@ -52,6 +54,14 @@ func NewAgent(proxy Proxy, terminationDrainDuration, minDrainDuration time.Durat
 	if secureMergedMetricsPort != 0 {
 		knownIstioListeners.Insert(fmt.Sprintf("listener.0.0.0.0_%d.downstream_cx_active", secureMergedMetricsPort))
 	}
+	switch drainMode {
+	case DrainModeDuration, DrainModeRequests:
+	default:
+		if drainMode != "" {
+			log.Warnf("Unknown drain mode %q, using %q", drainMode, DrainModeDuration)
+		}
+		drainMode = DrainModeDuration
+	}
 	return &Agent{
 		proxy:                       proxy,
 		statusCh:                    make(chan exitStatus, 1), // context might stop drainage
@@ -63,6 +73,8 @@ func NewAgent(proxy Proxy, terminationDrainDuration, minDrainDuration time.Durat
 		localhost:                   localhost,
 		knownIstioListeners:         knownIstioListeners,
 		skipDrain:                   atomic.NewBool(false),
+		drainMode:                   drainMode,
+		drainStatus:                 DrainStatus{Mode: drainMode, State: DrainNotStarted},
 	}
 }
 
@@ -102,6 +114,10 @@ type Agent struct {
 	exitOnZeroActiveConnections bool
 
 	skipDrain *atomic.Bool
+
+	drainMode   DrainMode
+	drainMu     sync.Mutex
+	drainStatus DrainStatus
 }
 
 type exitStatus struct {
@@ -152,6 +168,10 @@ func (a *Agent) DrainNow() {
 	// If we drained now, skip draining + waiting later
 	// When we terminate, we will instead exit immediately
 	a.DisableDraining()
+	if a.drainMode == DrainModeRequests && a.startDrain() {
+		// Only report the progress, the proxy is terminated when the agent is.
+		go a.waitForInflightRequests(false)
+	}
 }
 
 // terminate starts exiting the process.
@@ -166,10 +186,17 @@ func (a *Agent) terminate() {
 	if e != nil {
 		log.Warnf("Error in invoking drain listeners endpoint: %v", e)
 	}
+	// In the requests drain mode, wait until the HTTP listeners have no in-flight request.
 	// If exitOnZeroActiveConnections is enabled, always sleep minimumDrainDuration then exit
 	// after min(all connections close, terminationGracePeriodSeconds-minimumDrainDuration).
 	// exitOnZeroActiveConnections is disabled (default), retain the existing behavior.
-	if a.exitOnZeroActiveConnections {
+	if a.drainMode == DrainModeRequests {
+		a.startDrain()
+		if !a.waitForInflightRequests(true) {
+			return
+		}
+		a.abortCh <- errAbort
+	} else if a.exitOnZeroActiveConnections {
 		log.Infof("Agent draining proxy for %v, then waiting for active connections to terminate...", a.minDrainDuration)
 		time.Sleep(a.minDrainDuration)
 		log.Infof("Checking for active connections...")
@@ -0,0 +1,332 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/http"
+	"istio.io/istio/pkg/log"
+)
+
+const (
+	crashBundlePrefix = "crash-"
+	// crashBundleTimeFormat sorts the bundles by time.
+	crashBundleTimeFormat = "2006-01-02T15-04-05.000000000Z"
+
+	// maxDiagnosticsLineLength bounds the size of the stderr lines and NACK messages kept.
+	maxDiagnosticsLineLength = 4096
+	// maxConfigDumpSize bounds the size of the config dump kept.
+	maxConfigDumpSize = 32 << 20
+)
+
+// DiagnosticsOptions configures the crash diagnostics of Envoy. When Envoy exits abnormally, a bundle with the last
+// lines of its stderr, its bootstrap, its config dump at the last successful start and the recent xDS NACKs is
+// written, for post-mortems after the restart of the container.
+type DiagnosticsOptions struct {
+	// Dir is the directory of the bundles. It should outlive the container, e.g. be an emptyDir volume.
+	// Empty disables the diagnostics.
+	Dir string
+	// MaxBundles is the number of bundles kept, the oldest are removed.
+	MaxBundles int
+	// StderrLines is the number of last lines of the Envoy stderr kept.
+	StderrLines int
+	// MaxNACKs is the number of recent xDS NACKs kept.
+	MaxNACKs int
+}
+
+// NACK is an xDS response rejected by Envoy.
+type NACK struct {
+	Time    time.Time `json:"time"`
+	TypeURL string    `json:"typeUrl"`
+	Nonce   string    `json:"nonce"`
+	Message string    `json:"message"`
+}
+
+// CrashBundle lists the diagnostics of an abnormal exit of Envoy.
+type CrashBundle struct {
+	Name string `json:"name"`
+	// Files are the names of the files of the bundle, read with OpenBundleFile.
+	Files []string `json:"files"`
+}
+
+// Diagnostics records the diagnostics of Envoy. A nil Diagnostics records nothing.
+type Diagnostics struct {
+	opts   DiagnosticsOptions
+	stderr *lineBuffer
+
+	mu         sync.Mutex
+	nacks      []NACK
+	nextNACK   int
+	configDump []byte
+}
+
+// NewDiagnostics returns the diagnostics of Envoy, or nil if they are disabled.
+func NewDiagnostics(opts DiagnosticsOptions) *Diagnostics {
+	if opts.Dir == "" || opts.MaxBundles <= 0 {
+		return nil
+	}
+	return &Diagnostics{
+		opts:   opts,
+		stderr: &lineBuffer{max: opts.StderrLines},
+	}
+}
+
+// RecordNACK records an xDS response rejected by Envoy.
+func (d *Diagnostics) RecordNACK(typeURL, nonce, message string) {
+	if d == nil || d.opts.MaxNACKs <= 0 {
+		return
+	}
+	nack := NACK{Time: time.Now(), TypeURL: typeURL, Nonce: nonce, Message: truncate(message)}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.nacks) < d.opts.MaxNACKs {
+		d.nacks = append(d.nacks, nack)
+		return
+	}
+	d.nacks[d.nextNACK] = nack
+	d.nextNACK = (d.nextNACK + 1) % len(d.nacks)
+}
+
+// recentNACKs returns the recent NACKs, the oldest first.
+func (d *Diagnostics) recentNACKs() []NACK {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	nacks := make([]NACK, 0, len(d.nacks))
+	nacks = append(nacks, d.nacks[d.nextNACK:]...)
+	return append(nacks, d.nacks[:d.nextNACK]...)
+}
+
+// captureConfigDump waits for Envoy to be live, then keeps its config dump. It gives up once stop is closed.
+func (d *Diagnostics) captureConfigDump(adminPort int32, stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ready, err := http.DoHTTPGetWithTimeout(fmt.Sprintf("http://localhost:%d/ready", adminPort), time.Second)
+		if err != nil || strings.TrimSpace(ready.String()) != "LIVE" {
+			continue
+		}
+		dump, err := http.DoHTTPGetWithTimeout(fmt.Sprintf("http://localhost:%d/config_dump", adminPort), 10*time.Second)
+		if err != nil {
+			log.Warnf("Failed to capture the config dump of Envoy for the crash diagnostics: %v", err)
+			return
+		}
+		if dump.Len() > maxConfigDumpSize {
+			log.Warnf("Config dump of Envoy is too large for the crash diagnostics: %d bytes", dump.Len())
+			return
+		}
+		d.mu.Lock()
+		d.configDump = dump.Bytes()
+		d.mu.Unlock()
+		return
+	}
+}
+
+// writeBundle writes the diagnostics of an abnormal exit of Envoy, and removes the oldest bundles.
+func (d *Diagnostics) writeBundle(exitErr error, bootstrapPath string) (string, error) {
+	now := time.Now().UTC()
+	dir := filepath.Join(d.opts.Dir, crashBundlePrefix+now.Format(crashBundleTimeFormat))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	nacks, err := json.MarshalIndent(d.recentNACKs(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	configDump := d.configDump
+	d.mu.Unlock()
+	files := map[string][]byte{
+		"exit.txt":         fmt.Appendf(nil, "time: %s\nerror: %v\n", now.Format(time.RFC3339), exitErr),
+		"stderr.log":       d.stderr.bytes(),
+		"nacks.json":       nacks,
+		"config_dump.json": configDump,
+	}
+	if bootstrap, err := os.ReadFile(bootstrapPath); err == nil {
+		files["bootstrap.json"] = bootstrap
+	} else {
+		log.Warnf("Failed to read the bootstrap of Envoy for the crash diagnostics: %v", err)
+	}
+	for name, content := range files {
+		if len(content) == 0 {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	bundles, err := d.bundleNames()
+	if err != nil {
+		return dir, err
+	}
+	for len(bundles) > d.opts.MaxBundles {
+		if err := os.RemoveAll(filepath.Join(d.opts.Dir, bundles[0])); err != nil {
+			return dir, err
+		}
+		bundles = bundles[1:]
+	}
+	return dir, nil
+}
+
+// bundleNames returns the names of the bundles, the oldest first.
+func (d *Diagnostics) bundleNames() ([]string, error) {
+	entries, err := os.ReadDir(d.opts.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), crashBundlePrefix) {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Bundles lists the crash bundles written, including by previous runs of the agent, the oldest first.
+func (d *Diagnostics) Bundles() ([]CrashBundle, error) {
+	if d == nil {
+		return nil, nil
+	}
+	names, err := d.bundleNames()
+	if err != nil {
+		return nil, err
+	}
+	bundles := make([]CrashBundle, 0, len(names))
+	for _, name := range names {
+		entries, err := os.ReadDir(filepath.Join(d.opts.Dir, name))
+		if err != nil {
+			return nil, err
+		}
+		bundle := CrashBundle{Name: name, Files: []string{}}
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				bundle.Files = append(bundle.Files, e.Name())
+			}
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, nil
+}
+
+// OpenBundleFile opens a file of a crash bundle, for streaming it. It fails with os.ErrNotExist if the diagnostics
+// are disabled, or if the names are not those of a file of a bundle.
+func (d *Diagnostics) OpenBundleFile(bundle, file string) (*os.File, error) {
+	if d == nil || !strings.HasPrefix(bundle, crashBundlePrefix) || !isBaseName(bundle) || !isBaseName(file) {
+		return nil, os.ErrNotExist
+	}
+	f, err := os.Open(filepath.Join(d.opts.Dir, bundle, file))
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
+
+// isBaseName returns whether name is the name of an entry of a directory, rather than a path.
+func isBaseName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
+}
+
+// lineBuffer is a writer keeping the last lines written.
+type lineBuffer struct {
+	max int
+
+	mu      sync.Mutex
+	lines   []string
+	next    int
+	partial []byte
+}
+
+func (b *lineBuffer) Write(p []byte) (int, error) {
+	if b.max <= 0 {
+		return len(p), nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			b.appendPartial(p)
+			break
+		}
+		b.appendPartial(p[:i])
+		b.add(string(b.partial))
+		b.partial = b.partial[:0]
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (b *lineBuffer) appendPartial(p []byte) {
+	if room := maxDiagnosticsLineLength - len(b.partial); room < len(p) {
+		p = p[:max(room, 0)]
+	}
+	b.partial = append(b.partial, p...)
+}
+
+func (b *lineBuffer) add(line string) {
+	if len(b.lines) < b.max {
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+}
+
+// bytes returns the lines kept, the oldest first, including the last line even if it is incomplete.
+func (b *lineBuffer) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out bytes.Buffer
+	for _, lines := range [][]string{b.lines[b.next:], b.lines[:b.next]} {
+		for _, l := range lines {
+			out.WriteString(l)
+			out.WriteByte('\n')
+		}
+	}
+	out.Write(b.partial)
+	return out.Bytes()
+}
+
+func truncate(s string) string {
+	if len(s) > maxDiagnosticsLineLength {
+		return s[:maxDiagnosticsLineLength]
+	}
+	return s
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestLineBuffer(t *testing.T) {
+	b := &lineBuffer{max: 3}
+	for _, w := range []string{"one\ntw", "o\nthree\n", "four\nfive\nsi", "x"} {
+		n, err := b.Write([]byte(w))
+		assert.NoError(t, err)
+		assert.Equal(t, n, len(w))
+	}
+	// The last complete lines are kept, with the incomplete one.
+	assert.Equal(t, string(b.bytes()), "three\nfour\nfive\nsix")
+
+	long := &lineBuffer{max: 1}
+	_, _ = long.Write([]byte(strings.Repeat("a", maxDiagnosticsLineLength+10) + "\n"))
+	assert.Equal(t, len(long.bytes()), maxDiagnosticsLineLength+1)
+}
+
+func TestDiagnosticsDisabled(t *testing.T) {
+	var d *Diagnostics
+	assert.Equal(t, NewDiagnostics(DiagnosticsOptions{MaxBundles: 3}), d)
+	d.RecordNACK("type", "nonce", "rejected")
+	bundles, err := d.Bundles()
+	assert.NoError(t, err)
+	assert.Equal(t, bundles, nil)
+	_, err = d.OpenBundleFile("crash-1", "stderr.log")
+	assert.Equal(t, errors.Is(err, os.ErrNotExist), true)
+}
+
+func TestDiagnosticsBundles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "diagnostics")
+	bootstrap := filepath.Join(t.TempDir(), "envoy-rev.json")
+	assert.NoError(t, os.WriteFile(bootstrap, []byte(`{"node":{}}`), 0o644))
+
+	d := NewDiagnostics(DiagnosticsOptions{Dir: dir, MaxBundles: 2, StderrLines: 2, MaxNACKs: 2})
+	bundles, err := d.Bundles()
+	assert.NoError(t, err)
+	assert.Equal(t, bundles, []CrashBundle{})
+
+	_, _ = d.stderr.Write([]byte("starting\ninvalid listener\ncrashed\n"))
+	d.RecordNACK("type.googleapis.com/envoy.config.cluster.v3.Cluster", "1", "first")
+	d.RecordNACK("type.googleapis.com/envoy.config.listener.v3.Listener", "2", "second")
+	d.RecordNACK("type.googleapis.com/envoy.config.listener.v3.Listener", "3", "third")
+	d.configDump = []byte(`{"configs":[]}`)
+	for range 3 {
+		_, err := d.writeBundle(errors.New("exit status 1"), bootstrap)
+		assert.NoError(t, err)
+	}
+
+	// Only the most recent bundles are kept.
+	bundles, err = d.Bundles()
+	assert.NoError(t, err)
+	assert.Equal(t, len(bundles), 2)
+	assert.Equal(t, bundles[1].Files, []string{"bootstrap.json", "config_dump.json", "exit.txt", "nacks.json", "stderr.log"})
+	read := func(file string) string {
+		f, err := d.OpenBundleFile(bundles[1].Name, file)
+		assert.NoError(t, err)
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		assert.NoError(t, err)
+		return string(b)
+	}
+	assert.Equal(t, read("stderr.log"), "invalid listener\ncrashed\n")
+	assert.Equal(t, read("bootstrap.json"), `{"node":{}}`)
+	assert.Equal(t, read("config_dump.json"), `{"configs":[]}`)
+	assert.Equal(t, strings.Contains(read("exit.txt"), "error: exit status 1"), true)
+	var nacks []NACK
+	assert.NoError(t, json.Unmarshal([]byte(read("nacks.json")), &nacks))
+	assert.Equal(t, slices.Map(nacks, func(n NACK) string { return n.Message }), []string{"second", "third"})
+
+	// Only the files of the bundles are served.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.txt"), []byte("other"), 0o644))
+	for _, c := range [][2]string{
+		{bundles[1].Name, "missing.log"},
+		{bundles[1].Name, "../other.txt"},
+		{bundles[1].Name, "."},
+		{"..", "other.txt"},
+		{".", "other.txt"},
+		{bundles[1].Name + "/..", "other.txt"},
+	} {
+		_, err := d.OpenBundleFile(c[0], c[1])
+		assert.Equal(t, errors.Is(err, os.ErrNotExist), true)
+	}
+}
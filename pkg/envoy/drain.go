@@ -0,0 +1,199 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"istio.io/istio/pkg/http"
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// DrainMode decides when the agent terminates the proxy once draining started.
+type DrainMode string
+
+const (
+	// DrainModeDuration waits for the termination drain duration, or until there is no active connection when
+	// exitOnZeroActiveConnections is enabled.
+	DrainModeDuration DrainMode = "duration"
+	// DrainModeRequests waits until the HTTP listeners have no in-flight request, for at most the termination drain
+	// duration. Draining makes Envoy send HTTP/2 GOAWAY frames and HTTP/1.1 "Connection: close" headers, so that
+	// the clients of long-lived gRPC streams and WebSockets move to other endpoints once their requests complete.
+	DrainModeRequests DrainMode = "requests"
+)
+
+// DrainState is the progress of the drain of the proxy.
+type DrainState string
+
+const (
+	DrainNotStarted DrainState = "not_started"
+	DrainInProgress DrainState = "draining"
+	DrainComplete   DrainState = "complete"
+)
+
+// DrainStatus reports the progress of the drain of the proxy.
+type DrainStatus struct {
+	Mode  DrainMode  `json:"mode"`
+	State DrainState `json:"state"`
+	// Started is when draining started, and Deadline when the proxy is drained regardless of the in-flight requests.
+	Started  time.Time `json:"started,omitzero"`
+	Deadline time.Time `json:"deadline,omitzero"`
+	// Listeners are the in-flight requests of the HTTP listeners, by stat prefix, e.g. inbound_0.0.0.0_8080.
+	// Only reported in the requests mode.
+	Listeners map[string]uint64 `json:"listeners,omitempty"`
+	// TimedOut is set when the drain completed at the deadline with in-flight requests.
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// knownIstioHTTPListeners are the stat prefixes of the HTTP listeners serving Istio, rather than the application.
+var knownIstioHTTPListeners = sets.New("admin", "agent")
+
+// DrainStatus returns the progress of the drain of the proxy.
+func (a *Agent) DrainStatus() DrainStatus {
+	a.drainMu.Lock()
+	defer a.drainMu.Unlock()
+	status := a.drainStatus
+	status.Listeners = maps.Clone(status.Listeners)
+	return status
+}
+
+// startDrain records that draining started, unless it already did, and returns whether it did.
+func (a *Agent) startDrain() bool {
+	a.drainMu.Lock()
+	defer a.drainMu.Unlock()
+	if a.drainStatus.State != DrainNotStarted {
+		return false
+	}
+	now := time.Now()
+	a.drainStatus.State = DrainInProgress
+	a.drainStatus.Started = now
+	a.drainStatus.Deadline = now.Add(a.terminationDrainDuration)
+	return true
+}
+
+// completeDrain records that the proxy is drained.
+func (a *Agent) completeDrain(timedOut bool) {
+	a.drainMu.Lock()
+	defer a.drainMu.Unlock()
+	a.drainStatus.State = DrainComplete
+	a.drainStatus.TimedOut = timedOut
+}
+
+// waitForInflightRequests polls the in-flight requests of the HTTP listeners until there are none, after at least the
+// minimum drain duration, or until the deadline. It returns false if the proxy exited first, when watchExit is set.
+func (a *Agent) waitForInflightRequests(watchExit bool) bool {
+	deadline := a.DrainStatus().Deadline
+	log.Infof("Agent draining proxy for at least %v, then waiting for in-flight requests to complete until %v...",
+		a.minDrainDuration, deadline.Format(time.RFC3339))
+	minDrained := time.Now().Add(a.minDrainDuration)
+	ticker := time.NewTicker(activeConnectionCheckDelay)
+	defer ticker.Stop()
+	for {
+		if watchExit {
+			select {
+			case status := <-a.statusCh:
+				log.Infof("Envoy exited with status %v", status.err)
+				log.Infof("Graceful termination logic ended prematurely, envoy process terminated early")
+				a.completeDrain(false)
+				return false
+			case <-ticker.C:
+			}
+		} else {
+			<-ticker.C
+		}
+
+		listeners, err := a.inflightRequests()
+		if err != nil {
+			log.Warnf("Failed to get in-flight requests, retrying: %v", err)
+		} else {
+			a.drainMu.Lock()
+			a.drainStatus.Listeners = listeners
+			a.drainMu.Unlock()
+		}
+		now := time.Now()
+		if err == nil && now.After(minDrained) && inflightTotal(listeners) == 0 {
+			log.Info("There are no more in-flight requests, proxy is drained")
+			a.completeDrain(false)
+			return true
+		}
+		if now.After(deadline) {
+			log.Warnf("Drain deadline reached with in-flight requests: %v", listeners)
+			a.completeDrain(true)
+			return true
+		}
+		if err == nil {
+			log.Debugf("There are still %d in-flight requests", inflightTotal(listeners))
+		}
+	}
+}
+
+func inflightTotal(listeners map[string]uint64) uint64 {
+	var total uint64
+	for _, n := range listeners {
+		total += n
+	}
+	return total
+}
+
+// inflightRequests returns the in-flight requests of the HTTP listeners serving the application, by stat prefix.
+// Upgraded requests, such as WebSockets, and gRPC streams are in-flight until they are closed. It fails when Envoy
+// has no stats of the HTTP listeners at all.
+func (a *Agent) inflightRequests() (map[string]uint64, error) {
+	adminHost := net.JoinHostPort(a.localhost, strconv.Itoa(a.adminPort))
+	statsURL := fmt.Sprintf("http://%s/stats?usedonly&filter=downstream_rq_active$", adminHost)
+	stats, err := http.DoHTTPGetWithTimeout(statsURL, 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get HTTP listener stats from Envoy: %v", err)
+	}
+	listeners := map[string]uint64{}
+	found := false
+	for stats.Len() > 0 {
+		line, _ := stats.ReadString('\n')
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		prefix, ok := strings.CutPrefix(name, "http.")
+		if !ok {
+			continue
+		}
+		prefix, ok = strings.CutSuffix(prefix, ".downstream_rq_active")
+		if !ok {
+			continue
+		}
+		found = true
+		if knownIstioHTTPListeners.Contains(prefix) {
+			continue
+		}
+		val, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			log.Warnf("failed parsing Envoy stat %s (error: %s) line: %s", name, err.Error(), line)
+			continue
+		}
+		listeners[prefix] = val
+	}
+	// Envoy has the stats of its admin listener, serving this request, unless they are rejected by the stats matcher.
+	// The in-flight requests are then unknown rather than zero.
+	if !found {
+		return nil, fmt.Errorf("no downstream_rq_active stats of the HTTP listeners in Envoy, check the stats matcher")
+	}
+	return listeners, nil
+}
@@ -0,0 +1,121 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/status/testserver"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+var downstreamRqActiveStats = "http.admin.downstream_rq_active: 1 \n" +
+	"http.agent.downstream_rq_active: 1 \n" +
+	"http.inbound_0.0.0.0_8080.downstream_rq_active: 2 \n" +
+	"http.outbound_0.0.0.0_9080.downstream_rq_active: 0 \n" +
+	"listener.0.0.0.0_15006.downstream_cx_active: 3"
+
+func TestInflightRequests(t *testing.T) {
+	server := testserver.CreateAndStartServer(downstreamRqActiveStats)
+	defer server.Close()
+
+	agent := NewAgent(TestProxy{}, 0, 0, "localhost", server.Listener.Addr().(*net.TCPAddr).Port, 15021, 15009, 0, 0, false, DrainModeRequests)
+	listeners, err := agent.inflightRequests()
+	assert.NoError(t, err)
+	assert.Equal(t, listeners, map[string]uint64{"inbound_0.0.0.0_8080": 2, "outbound_0.0.0.0_9080": 0})
+}
+
+func TestInflightRequestsNoStats(t *testing.T) {
+	server := testserver.CreateAndStartServer("listener.0.0.0.0_15006.downstream_cx_active: 3")
+	defer server.Close()
+
+	agent := NewAgent(TestProxy{}, 0, 0, "localhost", server.Listener.Addr().(*net.TCPAddr).Port, 15021, 15009, 0, 0, false, DrainModeRequests)
+	_, err := agent.inflightRequests()
+	assert.Error(t, err)
+}
+
+func TestDrainModeRequests(t *testing.T) {
+	defer func(d time.Duration) { activeConnectionCheckDelay = d }(activeConnectionCheckDelay)
+	activeConnectionCheckDelay = 10 * time.Millisecond
+
+	inflight := atomic.NewString("http.inbound_0.0.0.0_8080.downstream_rq_active: 1\n")
+	stats := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(inflight.Load()))
+	}))
+	defer stats.Close()
+
+	aborted := make(chan struct{})
+	proxy := TestProxy{
+		run: func(abort <-chan error) error {
+			return <-abort
+		},
+		cleanup:      func() { close(aborted) },
+		blockChannel: make(chan any, 1),
+	}
+	agent := NewAgent(proxy, time.Minute, 0, "localhost", stats.Listener.Addr().(*net.TCPAddr).Port, 15021, 15009, 0, 0, false,
+		DrainModeRequests)
+	assert.Equal(t, agent.DrainStatus().State, DrainNotStarted)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go agent.Run(ctx)
+	cancel()
+
+	// The proxy is not terminated while a request is in-flight.
+	retry.UntilOrFail(t, func() bool {
+		return agent.DrainStatus().Listeners["inbound_0.0.0.0_8080"] == 1
+	})
+	assert.Equal(t, agent.DrainStatus().State, DrainInProgress)
+	select {
+	case <-aborted:
+		t.Fatal("proxy terminated with in-flight requests")
+	default:
+	}
+
+	inflight.Store("http.inbound_0.0.0.0_8080.downstream_rq_active: 0\n")
+	select {
+	case <-aborted:
+	case <-time.After(10 * time.Second):
+		t.Fatal("proxy not terminated once drained")
+	}
+	status := agent.DrainStatus()
+	assert.Equal(t, status.State, DrainComplete)
+	assert.Equal(t, status.TimedOut, false)
+}
+
+func TestDrainModeRequestsDeadline(t *testing.T) {
+	defer func(d time.Duration) { activeConnectionCheckDelay = d }(activeConnectionCheckDelay)
+	activeConnectionCheckDelay = 10 * time.Millisecond
+
+	server := testserver.CreateAndStartServer(downstreamRqActiveStats)
+	defer server.Close()
+
+	agent := NewAgent(TestProxy{}, 50*time.Millisecond, 0, "localhost", server.Listener.Addr().(*net.TCPAddr).Port, 15021, 15009, 0, 0,
+		false, DrainModeRequests)
+	assert.Equal(t, agent.startDrain(), true)
+	assert.Equal(t, agent.startDrain(), false)
+	assert.Equal(t, agent.waitForInflightRequests(false), true)
+	status := agent.DrainStatus()
+	assert.Equal(t, status.State, DrainComplete)
+	assert.Equal(t, status.TimedOut, true)
+	assert.Equal(t, status.Listeners["inbound_0.0.0.0_8080"], uint64(2))
+}
@@ -17,6 +17,7 @@ package envoy
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -58,6 +59,9 @@ type ProxyConfig struct {
 	// For unit testing, in combination with NoEnvoy prevents agent.Run from blocking
 	TestOnly    bool
 	AgentIsRoot bool
+
+	// Diagnostics records the crash diagnostics of Envoy, if enabled.
+	Diagnostics *Diagnostics
 }
 
 // NewProxy creates an instance of the proxy control commands
@@ -218,6 +222,9 @@ func (e *envoy) Run(abort <-chan error) error {
 	}
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if e.Diagnostics != nil {
+		cmd.Stderr = io.MultiWriter(os.Stderr, e.Diagnostics.stderr)
+	}
 	if e.AgentIsRoot {
 		cmd.SysProcAttr = &syscall.SysProcAttr{}
 		cmd.SysProcAttr.Credential = &syscall.Credential{
@@ -233,6 +240,11 @@ func (e *envoy) Run(abort <-chan error) error {
 	go func() {
 		done <- cmd.Wait()
 	}()
+	if e.Diagnostics != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go e.Diagnostics.captureConfigDump(e.AdminPort, stop)
+	}
 
 	select {
 	case err := <-abort:
@@ -242,6 +254,13 @@ func (e *envoy) Run(abort <-chan error) error {
 		}
 		return err
 	case err := <-done:
+		if err != nil && e.Diagnostics != nil {
+			if dir, errWrite := e.Diagnostics.writeBundle(err, e.ConfigPath); errWrite != nil {
+				log.Warnf("Failed to write the crash diagnostics of Envoy: %v", errWrite)
+			} else {
+				log.Infof("Wrote the crash diagnostics of Envoy to %s", dir)
+			}
+		}
 		return err
 	}
 }
@@ -163,6 +163,12 @@ type AgentOptions struct {
 	DNSForwardParallel bool
 	// DNSForwardTimeout is the timeout for upstream DNS queries.
 	DNSForwardTimeout time.Duration
+	// DNSCache configures the cache of upstream DNS responses.
+	DNSCache dnsClient.ResponseCacheOptions
+	// DNSEncryptedUpstreams configures encrypted upstream DNS servers, used instead of the resolv.conf servers.
+	DNSEncryptedUpstreams dnsClient.EncryptedUpstreamOptions
+	// DNSQueryLog configures the logging, history and per host metrics of the DNS queries.
+	DNSQueryLog dnsClient.QueryLogOptions
 	// ProxyType is the type of proxy we are configured to handle
 	ProxyType model.NodeType
 	// ProxyNamespace to use for local dns resolution
@@ -213,6 +219,16 @@ type AgentOptions struct {
 
 	ExitOnZeroActiveConnections bool
 
+	// ClusterHealthStats enables the Envoy stats of the healthy members of the clusters, needed by the upstream
+	// conditions of the composite app probes.
+	ClusterHealthStats bool
+
+	// DrainMode decides when the proxy is terminated once draining started.
+	DrainMode envoy.DrainMode
+
+	// EnvoyDiagnostics configures the crash diagnostics of Envoy.
+	EnvoyDiagnostics envoy.DiagnosticsOptions
+
 	// Cloud platform
 	Platform platform.Environment
 
@@ -245,6 +261,7 @@ type AgentOptions struct {
 // associated clients to sign certificates (when not using files), and the local XDS proxy (including
 // health checking for VMs and DNS proxying).
 func NewAgent(proxyConfig *mesh.ProxyConfig, agentOpts *AgentOptions, sopts *security.Options, eopts envoy.ProxyConfig) *Agent {
+	eopts.Diagnostics = envoy.NewDiagnostics(agentOpts.EnvoyDiagnostics)
 	return &Agent{
 		proxyConfig: proxyConfig,
 		cfg:         agentOpts,
@@ -295,6 +312,8 @@ func (a *Agent) generateNodeMetadata() (*model.Node, error) {
 		EnvoySecureMergedMetricsPort: a.cfg.EnvoySecureMergedMetricsPort,
 		EnvoyStatusPort:              a.cfg.EnvoyStatusPort,
 		ExitOnZeroActiveConnections:  a.cfg.ExitOnZeroActiveConnections,
+		ClusterHealthStats:           a.cfg.ClusterHealthStats,
+		DrainRequests:                a.cfg.DrainMode == envoy.DrainModeRequests,
 		XDSRootCert:                  a.cfg.XDSRootCerts,
 		MetadataDiscovery:            a.cfg.MetadataDiscovery,
 		EnvoySkipDeprecatedLogs:      a.cfg.EnvoySkipDeprecatedLogs,
@@ -352,7 +371,7 @@ func (a *Agent) initializeEnvoyAgent(_ context.Context) error {
 	}
 	a.envoyAgent = envoy.NewAgent(envoyProxy, drainDuration, a.cfg.MinimumDrainDuration, localHostAddr,
 		int(a.proxyConfig.ProxyAdminPort), a.cfg.EnvoyStatusPort, a.cfg.EnvoyPrometheusPort,
-		a.cfg.EnvoySecureMetricsPort, a.cfg.EnvoySecureMergedMetricsPort, a.cfg.ExitOnZeroActiveConnections)
+		a.cfg.EnvoySecureMetricsPort, a.cfg.EnvoySecureMergedMetricsPort, a.cfg.ExitOnZeroActiveConnections, a.cfg.DrainMode)
 	return nil
 }
 
@@ -557,6 +576,9 @@ func (a *Agent) initLocalDNSServer() (err error) {
 			a.cfg.DNSAddr,
 			dnsClient.WithParallelForwarding(a.cfg.DNSForwardParallel),
 			dnsClient.WithUpstreamTimeout(a.cfg.DNSForwardTimeout),
+			dnsClient.WithResponseCache(a.cfg.DNSCache),
+			dnsClient.WithEncryptedUpstreams(a.cfg.DNSEncryptedUpstreams),
+			dnsClient.WithQueryLog(a.cfg.DNSQueryLog),
 		); err != nil {
 			return err
 		}
@@ -609,6 +631,24 @@ func (a *Agent) isDNSServerEnabled() bool {
 	return (a.cfg.DNSCapture && a.cfg.ProxyType == model.SidecarProxy) || a.cfg.DNSAtGateway
 }
 
+// GetDNSQueries returns the DNS queries recently answered by the agent, used in debugging interface.
+func (a *Agent) GetDNSQueries() []dnsClient.QueryRecord {
+	if a.localDNSServer == nil {
+		return nil
+	}
+	return a.localDNSServer.RecentQueries()
+}
+
+// GetEnvoyCrashes lists the crash diagnostics of Envoy, used in debugging interface.
+func (a *Agent) GetEnvoyCrashes() ([]envoy.CrashBundle, error) {
+	return a.envoyOpts.Diagnostics.Bundles()
+}
+
+// OpenEnvoyCrashFile opens a file of the crash diagnostics of Envoy, used in debugging interface.
+func (a *Agent) OpenEnvoyCrashFile(bundle, file string) (*os.File, error) {
+	return a.envoyOpts.Diagnostics.OpenBundleFile(bundle, file)
+}
+
 // GetDNSTable builds DNS table used in debugging interface.
 func (a *Agent) GetDNSTable() *dnsProto.NameTable {
 	if a.localDNSServer != nil && a.localDNSServer.NameTable() != nil {
@@ -840,3 +880,12 @@ func (a *Agent) GRPCBootstrapPath() string {
 func (a *Agent) DrainNow() {
 	a.envoyAgent.DrainNow()
 }
+
+// GetDrainStatus returns the progress of the drain of the proxy, or nil if there is no proxy.
+func (a *Agent) GetDrainStatus() *envoy.DrainStatus {
+	if a.envoyAgent == nil {
+		return nil
+	}
+	status := a.envoyAgent.DrainStatus()
+	return &status
+}
@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"go.uber.org/atomic"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
@@ -174,6 +175,20 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 			}
 			return ia.secretCache.UpdateConfigTrustBundle(trustBundle)
 		}
+		proxy.handlers[model.TrustDomainBundleType] = func(resp *anypb.Any) error {
+			cfg := &tlsv3.SPIFFECertValidatorConfig{}
+			if err := resp.UnmarshalTo(cfg); err != nil {
+				log.Errorf("failed to unmarshal trust domain bundles: %v", err)
+				return err
+			}
+			trustDomainBundles := make(map[string][]byte, len(cfg.GetTrustDomains()))
+			for _, td := range cfg.GetTrustDomains() {
+				trustDomainBundles[td.GetName()] = []byte(td.GetTrustBundle().GetInlineString())
+			}
+			log.Debugf("received trust anchors for %d trust domains", len(trustDomainBundles))
+			ia.secretCache.UpdateTrustDomainBundles(trustDomainBundles)
+			return nil
+		}
 	}
 
 	proxyLog.Infof("Initializing with upstream address %q and cluster %q", proxy.istiodAddress, proxy.clusterID)
@@ -430,6 +445,12 @@ func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 						TypeUrl: model.ProxyConfigType,
 					})
 				}
+				// fire off an initial trust domain bundle request
+				if _, f := p.handlers[model.TrustDomainBundleType]; f {
+					con.sendRequest(&discovery.DiscoveryRequest{
+						TypeUrl: model.TrustDomainBundleType,
+					})
+				}
 				// set flag before sending the initial request to prevent race.
 				initialRequestsSent.Store(true)
 				// Fire of a configured initial request, if there is one
@@ -460,6 +481,9 @@ func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 				}
 				p.ecdsLastNonce.Store(req.ResponseNonce)
 			}
+			if req.ErrorDetail != nil {
+				p.ia.envoyOpts.Diagnostics.RecordNACK(req.TypeUrl, req.ResponseNonce, req.ErrorDetail.Message)
+			}
 			if err := con.upstream.Send(req); err != nil {
 				err = fmt.Errorf("send error for type url %s: %v", req.TypeUrl, err)
 				upstreamErr(con, err)
@@ -156,6 +156,12 @@ func (p *XdsProxy) handleUpstreamDeltaRequest(con *ProxyConnection) {
 						TypeUrl: model.ProxyConfigType,
 					})
 				}
+				// fire off an initial trust domain bundle request
+				if _, f := p.handlers[model.TrustDomainBundleType]; f {
+					con.sendDeltaRequest(&discovery.DeltaDiscoveryRequest{
+						TypeUrl: model.TrustDomainBundleType,
+					})
+				}
 				// set flag before sending the initial request to prevent race.
 				initialRequestsSent.Store(true)
 				// Fire of a configured initial request, if there is one
@@ -191,6 +197,9 @@ func (p *XdsProxy) handleUpstreamDeltaRequest(con *ProxyConnection) {
 			if req.TypeUrl == model.ExtensionConfigurationType {
 				p.ecdsLastNonce.Store(req.ResponseNonce)
 			}
+			if req.ErrorDetail != nil {
+				p.ia.envoyOpts.Diagnostics.RecordNACK(req.TypeUrl, req.ResponseNonce, req.ErrorDetail.Message)
+			}
 
 			if err := con.upstreamDeltas.Send(req); err != nil {
 				err = fmt.Errorf("send error for type url %s: %v", req.TypeUrl, err)
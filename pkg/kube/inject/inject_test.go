@@ -171,6 +171,11 @@ func TestInjection(t *testing.T) {
 			want:     "hello-never.yaml.injected",
 			setFlags: []string{"values.global.imagePullPolicy=Never"},
 		},
+		{
+			in:       "hello.yaml",
+			want:     "hello-wasm-node-cache.yaml.injected",
+			setFlags: []string{"values.global.proxy.wasmNodeCacheHostPath=/var/lib/istio/wasm"},
+		},
 		{
 			in:   "hello.yaml",
 			want: "hello-old-version.yaml.injected",
@@ -27,8 +27,10 @@ import (
 	"istio.io/api/annotation"
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/validation/agent"
+	"istio.io/istio/pkg/spiffe"
 	netutil "istio.io/istio/pkg/util/net"
 	"istio.io/istio/pkg/util/protomarshal"
 )
@@ -57,6 +59,7 @@ var (
 		annotation.SidecarProxyMemory.Name:                        validateResourceQuantity,
 		annotation.SidecarProxyCPULimit.Name:                      validateResourceQuantity,
 		annotation.SidecarProxyMemoryLimit.Name:                   validateResourceQuantity,
+		constants.WorkloadIdentitiesAnnotation:                    validateWorkloadIdentities,
 	}
 )
 
@@ -208,3 +211,9 @@ func parsePorts(portsString string) ([]int, error) {
 func ValidateExcludeInterfaces(interfaces string) error {
 	return netutil.ValidateInterfaceNames(interfaces)
 }
+
+// validateWorkloadIdentities validates the workloadIdentities annotation
+func validateWorkloadIdentities(identities string) error {
+	_, err := spiffe.ParseNamedIdentities(identities)
+	return err
+}
@@ -123,6 +123,20 @@ func TestValidateAnnotations(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid workload identities",
+			annotations: map[string]string{
+				"security.istio.io/workloadIdentities": "legacy=spiffe://legacy.example/ns/foo/sa/bar",
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed workload identities",
+			annotations: map[string]string{
+				"security.istio.io/workloadIdentities": "legacy:spiffe://legacy.example/ns/foo/sa/bar",
+			},
+			wantErr: true,
+		},
 		{
 			name: "control character in proxyMemoryLimit",
 			annotations: map[string]string{
@@ -171,6 +171,14 @@ type BootstrapNodeMetadata struct {
 	// XDSRootCert defines the root cert to use for XDS connections
 	XDSRootCert string `json:"-"`
 
+	// ClusterHealthStats enables the stats of the healthy members of the clusters, checked by the upstream
+	// conditions of the composite app probes.
+	ClusterHealthStats bool `json:"-"`
+
+	// DrainRequests is set when the proxy is drained until its HTTP listeners have no in-flight request, read from
+	// their downstream_rq_active stats.
+	DrainRequests bool `json:"-"`
+
 	// OutlierLogPath is the cluster manager outlier event log path.
 	OutlierLogPath string `json:"OUTLIER_LOG_PATH,omitempty"`
 
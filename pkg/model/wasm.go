@@ -25,6 +25,16 @@ const (
 	WasmPolicyEnv = "ISTIO_META_WASM_IMAGE_PULL_POLICY"
 	// name of environment variable at Wasm VM, which will carry the resource version of WasmPlugin.
 	WasmResourceVersionEnv = "ISTIO_META_WASM_PLUGIN_RESOURCE_VERSION"
+	// name of environment variable at Wasm VM, which will carry the signers required on the Wasm image.
+	WasmRequiredSignersEnv = "ISTIO_META_WASM_REQUIRED_SIGNERS"
+
+	// WasmRequiredSignersAnnotation is the annotation of WasmPlugin and TrafficExtension resources listing, as JSON,
+	// the signers which must all have signed the Wasm image before the proxy loads it, e.g.
+	//
+	//	[{"publicKey": "-----BEGIN PUBLIC KEY-----\n..."}]
+	//
+	// Keyless signers are not supported yet, as the transparency log is not verified.
+	WasmRequiredSignersAnnotation = "extensions.istio.io/required-signers"
 
 	WasmHTTPFilterType    = APITypePrefix + wellknown.HTTPWasm
 	WasmNetworkFilterType = APITypePrefix + "envoy.extensions.filters.network.wasm.v3.Wasm"
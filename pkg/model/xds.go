@@ -32,6 +32,8 @@ const (
 	NameTableType   = APITypePrefix + "istio.networking.nds.v1.NameTable"
 	HealthInfoType  = APITypePrefix + "istio.v1.HealthInformation"
 	ProxyConfigType = APITypePrefix + "istio.mesh.v1alpha1.ProxyConfig"
+	// TrustDomainBundleType carries the trust anchors of each trust domain to the agent.
+	TrustDomainBundleType = APITypePrefix + "envoy.extensions.transport_sockets.tls.v3.SPIFFECertValidatorConfig"
 	// DebugType requests debug info from istio, a secured implementation for istio debug interface.
 	DebugType                 = "istio.io/debug"
 	BootstrapType             = APITypePrefix + "envoy.config.bootstrap.v3.Bootstrap"
@@ -60,6 +62,8 @@ func GetShortType(typeURL string) string {
 		return "NDS"
 	case ProxyConfigType:
 		return "PCDS"
+	case TrustDomainBundleType:
+		return "TBDS"
 	case ExtensionConfigurationType:
 		return "ECDS"
 	case AddressType, WorkloadType:
@@ -88,6 +92,8 @@ func GetMetricType(typeURL string) string {
 		return "nds"
 	case ProxyConfigType:
 		return "pcds"
+	case TrustDomainBundleType:
+		return "tbds"
 	case ExtensionConfigurationType:
 		return "ecds"
 	case BootstrapType:
@@ -119,6 +125,8 @@ func GetResourceType(shortType string) string {
 		return NameTableType
 	case "PCDS":
 		return ProxyConfigType
+	case "TBDS":
+		return TrustDomainBundleType
 	case "ECDS":
 		return ExtensionConfigurationType
 	case "WDS":
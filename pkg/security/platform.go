@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// AWSCallerIdentityTokenPrefix prefixes bearer tokens carrying a signed AWS STS GetCallerIdentity request,
+	// distinguishing them from JWTs.
+	AWSCallerIdentityTokenPrefix = "aws-sts."
+	// AWSAudienceHeader is the signed header binding an AWS STS GetCallerIdentity request to an audience, so that it
+	// only authenticates to the Istiod instances expecting that audience.
+	AWSAudienceHeader = "X-Istio-Audience"
+	// AWSNonceHeader is the signed header carrying a random nonce, so that requests signed in the same second, the
+	// resolution of X-Amz-Date, have distinct signatures and are not mistaken for replays.
+	AWSNonceHeader = "X-Istio-Nonce"
+	// AWSCallerIdentityBody is the body of the STS GetCallerIdentity requests.
+	AWSCallerIdentityBody = "Action=GetCallerIdentity&Version=2011-06-15"
+)
+
+// AWSCallerIdentityRequest is an STS GetCallerIdentity request signed with AWS Signature Version 4 by the
+// credentials of an EC2 instance role. It is a short-lived credential, as STS rejects requests signed more than
+// 15 minutes ago: the verifier authenticates the signer by sending the request to STS.
+type AWSCallerIdentityRequest struct {
+	// Headers are the headers of the request, including its Authorization, Host and X-Amz-Date headers.
+	Headers map[string]string `json:"headers"`
+	// Body is the body of the request, AWSCallerIdentityBody.
+	Body string `json:"body"`
+}
+
+// AWSSTSHost returns the host of the STS endpoint of a region.
+func AWSSTSHost(region string) string {
+	if strings.HasPrefix(region, "cn-") {
+		return "sts." + region + ".amazonaws.com.cn"
+	}
+	return "sts." + region + ".amazonaws.com"
+}
+
+// Token encodes the request as a bearer token.
+func (a AWSCallerIdentityRequest) Token() (string, error) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return AWSCallerIdentityTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ParseAWSCallerIdentityToken decodes a bearer token produced by AWSCallerIdentityRequest.Token.
+func ParseAWSCallerIdentityToken(token string) (AWSCallerIdentityRequest, error) {
+	res := AWSCallerIdentityRequest{}
+	encoded, ok := strings.CutPrefix(token, AWSCallerIdentityTokenPrefix)
+	if !ok {
+		return res, fmt.Errorf("not an AWS caller identity token")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return res, fmt.Errorf("invalid AWS caller identity token encoding: %v", err)
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return res, fmt.Errorf("invalid AWS caller identity token: %v", err)
+	}
+	return res, nil
+}
@@ -72,6 +72,10 @@ const (
 	// SDSExternalCredentialPrefix is the prefix for the credentialName which will utilize external SDS connections defined via CredentialNameSocketPath
 	SDSExternalCredentialPrefix = "sds://"
 
+	// WorkloadIdentityResourcePrefix is the prefix of the SDS resource names, and credentialNames, referencing an
+	// additional identity of the workload, such as identity://legacy.
+	WorkloadIdentityResourcePrefix = "identity://"
+
 	// WorkloadIdentityCredentialsPath is the well-known path to a folder with workload certificate files.
 	WorkloadIdentityCredentialsPath = "./var/run/secrets/workload-spiffe-credentials"
 
@@ -111,6 +115,12 @@ const (
 	// JWT is a Credential fetcher type that reads from a JWT token file
 	JWT = "JWT"
 
+	// AWS is Credential fetcher type of the AWS EC2 instance role plugin
+	AWS = "AWSEC2"
+
+	// Azure is Credential fetcher type of the Azure managed identity plugin
+	Azure = "AzureVM"
+
 	// Mock is Credential fetcher type of mock plugin
 	Mock = "Mock" // testing only
 
@@ -153,6 +163,10 @@ const (
 	// This is constrained to only allow identities in CATrustedNodeAccounts, and only to impersonate identities
 	// on their node.
 	ImpersonatedIdentity = "ImpersonatedIdentity"
+
+	// RequestedIdentity declares an additional identity of the workload we are requesting a certificate for,
+	// instead of its default identity.
+	RequestedIdentity = "RequestedIdentity"
 )
 
 type ImpersonatedIdentityContextKey struct{}
@@ -272,6 +286,10 @@ type Options struct {
 
 	// Extra headers to add to the CA connection.
 	CAHeaders map[string]string
+
+	// WorkloadIdentities are the additional identities of the workload, keyed by name. A certificate for each
+	// is served under the SDS resource name WorkloadIdentityResourcePrefix + name.
+	WorkloadIdentities map[string]string
 }
 
 // Client interface defines the clients need to implement to talk to CA for CSR.
@@ -285,6 +303,13 @@ type Client interface {
 	GetRootCertBundle() ([]string, error)
 }
 
+// IdentityClient is implemented by CA clients which can request certificates for an additional identity of
+// the workload.
+type IdentityClient interface {
+	// CSRSignForIdentity is like CSRSign, but requests a certificate for the given SPIFFE identity.
+	CSRSignForIdentity(csrPEM []byte, certValidTTLInSec int64, identity string) ([]string, error)
+}
+
 // SecretManager defines secrets management interface which is used by SDS.
 type SecretManager interface {
 	// GenerateSecret generates new secret for the given resource.
@@ -303,6 +328,10 @@ type SecretItem struct {
 
 	RootCert []byte
 
+	// TrustDomainBundles holds the PEM encoded trust anchors keyed by trust domain. When set, peers are only
+	// trusted if their certificate chains to the anchors of the trust domain of their SPIFFE identity.
+	TrustDomainBundles map[string][]byte
+
 	// ResourceName passed from envoy SDS discovery request.
 	// "ROOTCA" for root cert request, "default" for key/cert request.
 	ResourceName string
@@ -331,6 +360,17 @@ const (
 	AuthSourceIDToken
 )
 
+func (a AuthSource) String() string {
+	switch a {
+	case AuthSourceClientCertificate:
+		return "ClientCertificate"
+	case AuthSourceIDToken:
+		return "IDToken"
+	default:
+		return fmt.Sprintf("AuthSource(%d)", int(a))
+	}
+}
+
 const (
 	authorizationMeta = "authorization"
 )
@@ -81,12 +81,54 @@ func (i Identity) String() string {
 	return URIPrefix + i.TrustDomain + "/ns/" + i.Namespace + "/sa/" + i.ServiceAccount
 }
 
+// ParseNamedIdentities parses a comma separated list of name=spiffe-identity pairs, as used to request
+// additional identities for a workload.
+func ParseNamedIdentities(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	res := map[string]string{}
+	for _, entry := range strings.Split(s, ",") {
+		name, identity, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected name=identity", entry)
+		}
+		if _, f := res[name]; f {
+			return nil, fmt.Errorf("duplicate identity name %q", name)
+		}
+		if _, err := ParseIdentity(identity); err != nil {
+			return nil, fmt.Errorf("invalid entry %q: %v", entry, err)
+		}
+		res[name] = identity
+	}
+	return res, nil
+}
+
 type bundleDoc struct {
 	jose.JSONWebKeySet
 	Sequence    uint64 `json:"spiffe_sequence,omitempty"`
 	RefreshHint int    `json:"spiffe_refresh_hint,omitempty"`
 }
 
+// MarshalBundle encodes the trust anchors of a trust domain as a SPIFFE bundle, as served by a SPIFFE bundle
+// endpoint. The sequence must increase whenever the set of anchors changes; refreshHint advises consumers how
+// often to poll the endpoint, and is omitted when zero.
+func MarshalBundle(certs []*x509.Certificate, sequence uint64, refreshHint time.Duration) ([]byte, error) {
+	doc := bundleDoc{
+		Sequence:    sequence,
+		RefreshHint: int(refreshHint.Seconds()),
+	}
+	doc.Keys = make([]jose.JSONWebKey, 0, len(certs))
+	for _, cert := range certs {
+		doc.Keys = append(doc.Keys, jose.JSONWebKey{
+			Key:          cert.PublicKey,
+			Certificates: []*x509.Certificate{cert},
+			Use:          "x509-svid",
+		})
+	}
+	return json.Marshal(doc)
+}
+
 func sanitizeTrustDomain(td string) string {
 	return strings.Replace(td, "@", ".", -1)
 }
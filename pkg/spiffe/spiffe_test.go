@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
@@ -303,6 +304,39 @@ func TestRetrieveSpiffeBundleRootCerts(t *testing.T) {
 	}
 }
 
+func TestMarshalBundle(t *testing.T) {
+	h := &handler{statusCode: http.StatusOK}
+	s := httptest.NewTLSServer(h)
+	defer s.Close()
+
+	block, _ := pem.Decode(util.ReadFile(t, validRootCertFile1))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.body, err = MarshalBundle([]*x509.Certificate{cert}, 7, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := bundleDoc{}
+	if err := json.Unmarshal(h.body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Sequence != 7 || doc.RefreshHint != 300 {
+		t.Errorf("got sequence %d and refresh hint %d; wanted 7 and 300", doc.Sequence, doc.RefreshHint)
+	}
+
+	caCertPool := x509.NewCertPool()
+	caCertPool.AddCert(s.Certificate())
+	rootCertMap, err := RetrieveSpiffeBundleRootCerts(map[string]string{"foo": s.Listener.Addr().String()}, caCertPool, time.Millisecond*50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rootCertMap["foo"]; len(got) != 1 || !got[0].Equal(cert) {
+		t.Errorf("got certs %v; wanted %v", got, cert)
+	}
+}
+
 // TestVerifyPeerCert tests VerifyPeerCert is effective at the client side, using a TLS server.
 func TestGetGeneralCertPoolAndVerifyPeerCert(t *testing.T) {
 	validRootCert := string(util.ReadFile(t, validRootCertFile1))
@@ -612,3 +646,41 @@ func TestIdentity(t *testing.T) {
 		})
 	}
 }
+
+func TestParseNamedIdentities(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{name: "empty"},
+		{
+			name:  "multiple",
+			input: "legacy=spiffe://legacy.example/ns/foo/sa/bar, app=spiffe://cluster.local/ns/foo/sa/app",
+			expected: map[string]string{
+				"legacy": "spiffe://legacy.example/ns/foo/sa/bar",
+				"app":    "spiffe://cluster.local/ns/foo/sa/app",
+			},
+		},
+		{name: "missing name", input: "=spiffe://cluster.local/ns/foo/sa/bar", wantErr: true},
+		{name: "missing identity", input: "legacy", wantErr: true},
+		{name: "invalid identity", input: "legacy=foo", wantErr: true},
+		{
+			name:    "duplicate name",
+			input:   "legacy=spiffe://legacy.example/ns/foo/sa/bar,legacy=spiffe://cluster.local/ns/foo/sa/bar",
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNamedIdentities(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -58,6 +58,8 @@ type LocalFileCache struct {
 	checksums map[string]*checksumEntry
 	// http fetcher fetches Wasm module with HTTP get.
 	httpFetcher *HTTPFetcher
+	// nodeCache is the cache shared by the agents of the node, if any.
+	nodeCache *NodeCache
 
 	// directory path used to store Wasm module.
 	dir string
@@ -105,6 +107,8 @@ type cacheEntry struct {
 	last time.Time
 	// Set of URLs referencing this entry
 	referencingURLs sets.String
+	// Set of signature policies the module has been verified against.
+	verifiedPolicies sets.String
 }
 
 type cacheOptions struct {
@@ -133,6 +137,9 @@ func (o cacheOptions) sanitize() cacheOptions {
 	if o.HTTPRequestMaxRetries != 0 {
 		ret.HTTPRequestMaxRetries = o.HTTPRequestMaxRetries
 	}
+	if o.NodeCacheTagTTL != 0 {
+		ret.NodeCacheTagTTL = o.NodeCacheTagTTL
+	}
 
 	return ret
 }
@@ -154,6 +161,13 @@ func NewLocalFileCache(dir string, options Options) *LocalFileCache {
 		cacheOptions: cacheOptions.sanitize(),
 		stopChan:     make(chan struct{}),
 	}
+	if options.NodeCacheDir != "" {
+		nodeCache, err := NewNodeCache(options.NodeCacheDir, options.NodeCacheMaxBytes)
+		if err != nil {
+			wasmLog.Errorf("failed to create Wasm node cache, modules will be fetched from the network: %v", err)
+		}
+		cache.nodeCache = nodeCache
+	}
 
 	go func() {
 		cache.purge()
@@ -222,9 +236,25 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 		return nil, fmt.Errorf("fail to parse Wasm module fetch url: %s, error: %v", key.downloadURL, err)
 	}
 
+	if opts.SignaturePolicy != nil && u.Scheme != "oci" {
+		wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+		return nil, fmt.Errorf("%w for %s: only OCI images can be verified", errSignatureVerification, key.downloadURL)
+	}
+	insecure := c.allowInsecure(u.Host)
+	ctx, cancel := context.WithTimeout(context.Background(), opts.RequestTimeout)
+	defer cancel()
+
 	// First check if the cache entry is already downloaded and policy does not require to pull always.
 	ce, checksum := c.getEntry(key, shouldIgnoreResourceVersion(opts.PullPolicy, u))
 	if ce != nil {
+		if opts.SignaturePolicy != nil && !c.verified(ce, opts.SignaturePolicy) {
+			// The module was fetched without this policy, verify its signatures before using it.
+			fetcher := NewImageFetcher(ctx, imageFetcherOption(insecure, opts))
+			if err := c.verifySignatures(fetcher, key.downloadURL, u, checksum, opts.SignaturePolicy); err != nil {
+				return nil, err
+			}
+			c.setVerified(ce, opts.SignaturePolicy)
+		}
 		return ce, nil
 	}
 	key.checksum = checksum
@@ -232,28 +262,34 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 	var b []byte         // Byte array of Wasm binary.
 	var dChecksum string // Hex-Encoded sha256 checksum of binary.
 	var binaryFetcher func() ([]byte, error)
-	insecure := c.allowInsecure(u.Host)
 
-	ctx, cancel := context.WithTimeout(context.Background(), opts.RequestTimeout)
-	defer cancel()
 	switch u.Scheme {
 	case "http", "https":
-		// Download the Wasm module with http fetcher.
-		b, err = c.httpFetcher.Fetch(ctx, key.downloadURL, insecure)
-		if err != nil {
-			wasmRemoteFetchCount.With(resultTag.Value(downloadFailure)).Increment()
-			return nil, err
+		// Modules can only be looked up in the node cache by their checksum.
+		if c.nodeCache != nil && key.checksum != "" {
+			b = c.nodeCache.Get(key.checksum)
+		}
+		if b == nil {
+			// Download the Wasm module with http fetcher.
+			b, err = c.httpFetcher.Fetch(ctx, key.downloadURL, insecure)
+			if err != nil {
+				wasmRemoteFetchCount.With(resultTag.Value(downloadFailure)).Increment()
+				return nil, err
+			}
 		}
 
 		// Get sha256 checksum and check if it is the same as provided one.
 		sha := sha256.Sum256(b)
 		dChecksum = hex.EncodeToString(sha[:])
-	case "oci":
-		imgFetcherOps := ImageFetcherOption{
-			Insecure: insecure,
+		if c.nodeCache != nil && isValidWasmBinary(b) {
+			c.nodeCache.Put(dChecksum, b)
 		}
-		if opts.PullSecret != nil {
-			imgFetcherOps.PullSecret = opts.PullSecret
+	case "oci":
+		imgFetcherOps := imageFetcherOption(insecure, opts)
+		imgFetcherOps.NodeCache = c.nodeCache
+		if shouldIgnoreResourceVersion(opts.PullPolicy, u) {
+			// Tags are resolved with the registry when the pull policy asks for the latest image.
+			imgFetcherOps.NodeCacheTagTTL = c.NodeCacheTagTTL
 		}
 		wasmLog.Debugf("fetching oci image from %s with options: %v", key.downloadURL, imgFetcherOps)
 		fetcher := NewImageFetcher(ctx, imgFetcherOps)
@@ -262,6 +298,11 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 			wasmRemoteFetchCount.With(resultTag.Value(manifestFailure)).Increment()
 			return nil, fmt.Errorf("could not fetch Wasm OCI image: %v", err)
 		}
+		if opts.SignaturePolicy != nil {
+			if err := c.verifySignatures(fetcher, key.downloadURL, u, dChecksum, opts.SignaturePolicy); err != nil {
+				return nil, err
+			}
+		}
 	default:
 		return nil, fmt.Errorf("unsupported Wasm module downloading URL scheme: %v", u.Scheme)
 	}
@@ -270,6 +311,7 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 		key.checksum = dChecksum
 		// check again if the cache is having the checksum.
 		if ce, _ := c.getEntry(key, true); ce != nil {
+			c.setVerified(ce, opts.SignaturePolicy)
 			return ce, nil
 		}
 	} else if dChecksum != key.checksum {
@@ -293,7 +335,51 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 	wasmRemoteFetchCount.With(resultTag.Value(fetchSuccess)).Increment()
 
 	key.checksum = dChecksum
-	return c.addEntry(key, b)
+	ce, err = c.addEntry(key, b)
+	if err != nil {
+		return nil, err
+	}
+	c.setVerified(ce, opts.SignaturePolicy)
+	return ce, nil
+}
+
+func imageFetcherOption(insecure bool, opts GetOptions) ImageFetcherOption {
+	imgFetcherOps := ImageFetcherOption{
+		Insecure: insecure,
+	}
+	if opts.PullSecret != nil {
+		imgFetcherOps.PullSecret = opts.PullSecret
+	}
+	return imgFetcherOps
+}
+
+// verifySignatures checks the signatures of the module with the given checksum against the policy.
+func (c *LocalFileCache) verifySignatures(fetcher *ImageFetcher, downloadURL string, u *url.URL, checksum string, policy *SignaturePolicy) error {
+	if err := fetcher.VerifySignatures(u.Host+u.Path, checksum, policy); err != nil {
+		wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+		return fmt.Errorf("%w for %s: %v", errSignatureVerification, downloadURL, err)
+	}
+	return nil
+}
+
+// verified returns true if the module has been verified against the signature policy.
+func (c *LocalFileCache) verified(ce *cacheEntry, policy *SignaturePolicy) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return ce.verifiedPolicies.Contains(policy.key)
+}
+
+// setVerified records that the module has been verified against the signature policy, if any.
+func (c *LocalFileCache) setVerified(ce *cacheEntry, policy *SignaturePolicy) {
+	if policy == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if ce.verifiedPolicies == nil {
+		ce.verifiedPolicies = sets.New[string]()
+	}
+	ce.verifiedPolicies.Insert(policy.key)
 }
 
 // Cleanup closes background Wasm module purge routine.
@@ -425,6 +511,9 @@ func (c *LocalFileCache) purge() {
 			}
 			wasmCacheEntries.Record(float64(len(c.modules)))
 			c.mux.Unlock()
+			if c.nodeCache != nil {
+				c.nodeCache.purge(c.ModuleExpiry)
+			}
 		case <-c.stopChan:
 			// Currently this will only happen in test.
 			return
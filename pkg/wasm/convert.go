@@ -15,6 +15,7 @@
 package wasm
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -345,6 +346,7 @@ func convertNetworkWasmConfigFromRemoteToLocal(ec *core.TypedExtensionConfig, wa
 func rewriteVMConfig(resourceName string, vm *wasmextensions.VmConfig, status *string, cache Cache, configName string) error {
 	envs := vm.GetEnvironmentVariables()
 	var pullSecret []byte
+	var signaturePolicy *SignaturePolicy
 	pullPolicy := Unspecified
 	resourceVersion := ""
 	if envs != nil {
@@ -363,6 +365,15 @@ func rewriteVMConfig(resourceName string, vm *wasmextensions.VmConfig, status *s
 		}
 		resourceVersion = envs.KeyValues[model.WasmResourceVersionEnv]
 
+		if signers, found := envs.KeyValues[model.WasmRequiredSignersEnv]; found {
+			p, err := ParseSignaturePolicy(signers)
+			if err != nil {
+				*status = signatureFailure
+				return fmt.Errorf("cannot fetch Wasm module %v: %v", configName, err)
+			}
+			signaturePolicy = p
+		}
+
 		// Strip all internal env variables(with ISTIO_META) from VM env variable.
 		// These env variables are added by Istio control plane and meant to be consumed by the
 		// agent for image pulling control should not be leaked to Envoy or the Wasm extension runtime.
@@ -405,9 +416,13 @@ func rewriteVMConfig(resourceName string, vm *wasmextensions.VmConfig, status *s
 		RequestTimeout:  timeout,
 		PullSecret:      pullSecret,
 		PullPolicy:      pullPolicy,
+		SignaturePolicy: signaturePolicy,
 	})
 	if err != nil {
 		*status = fetchFailure
+		if errors.Is(err, errSignatureVerification) {
+			*status = signatureFailure
+		}
 		return fmt.Errorf("cannot fetch Wasm module %v: %w", remote.GetHttpUri().GetUri(), err)
 	}
 
@@ -54,6 +54,9 @@ func (c *mockCache) Get(downloadURL string, opts GetOptions) (string, error) {
 	if errMsg != "" {
 		err = errors.New(errMsg)
 	}
+	if sigErrMsg := query.Get("signature-error"); sigErrMsg != "" {
+		err = fmt.Errorf("%w: %s", errSignatureVerification, sigErrMsg)
+	}
 	if c.wantSecret != nil && !reflect.DeepEqual(c.wantSecret, opts.PullSecret) {
 		return "", fmt.Errorf("wrong secret for %v, got %q want %q", downloadURL, string(opts.PullSecret), c.wantSecret)
 	}
@@ -339,6 +342,59 @@ func TestWasmConvert(t *testing.T) {
 	}
 }
 
+func TestRewriteVMConfigStatus(t *testing.T) {
+	cases := []struct {
+		name       string
+		uri        string
+		signers    string
+		wantStatus string
+	}{
+		{
+			name:       "success",
+			uri:        "http://test?module=test.wasm",
+			wantStatus: conversionSuccess,
+		},
+		{
+			name:       "fetch failure",
+			uri:        "http://test?module=test.wasm&error=download-error",
+			wantStatus: fetchFailure,
+		},
+		{
+			name:       "signature verification failure",
+			uri:        "http://test?module=test.wasm&signature-error=unsigned",
+			wantStatus: signatureFailure,
+		},
+		{
+			name:       "keyless signature policy",
+			uri:        "http://test?module=test.wasm",
+			signers:    `[{"issuer": "https://accounts.google.com", "subject": "dev@example.com"}]`,
+			wantStatus: signatureFailure,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			vm := &v3.VmConfig{
+				Code: &core.AsyncDataSource{Specifier: &core.AsyncDataSource_Remote{
+					Remote: &core.RemoteDataSource{HttpUri: &core.HttpUri{Uri: c.uri}},
+				}},
+			}
+			if c.signers != "" {
+				vm.EnvironmentVariables = &v3.EnvironmentVariables{
+					KeyValues: map[string]string{model.WasmRequiredSignersEnv: c.signers},
+				}
+			}
+			status := conversionSuccess
+			err := rewriteVMConfig("resource", vm, &status, &mockCache{}, "config")
+			if (err != nil) != (c.wantStatus != conversionSuccess) {
+				t.Fatalf("got error %v", err)
+			}
+			if status != c.wantStatus {
+				t.Fatalf("got status %q, want %q", status, c.wantStatus)
+			}
+		})
+	}
+}
+
 func buildTypedStructExtensionConfig(name string, wasm *wasm.Wasm) *core.TypedExtensionConfig {
 	ws, _ := protomarshal.MessageToStructSlow(wasm)
 	return &core.TypedExtensionConfig{
@@ -28,6 +28,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/docker/cli/cli/config/configfile"
 	dtypes "github.com/docker/cli/cli/config/types"
@@ -46,9 +47,13 @@ import (
 // Basically, this supports fetching and unpackaging three types of container images containing a Wasm binary.
 
 type ImageFetcherOption struct {
-	// TODO(mathetake) Add signature verification stuff.
 	PullSecret []byte
 	Insecure   bool
+	// NodeCache, if set, is looked up for the image before fetching it from the registry.
+	NodeCache *NodeCache
+	// NodeCacheTagTTL is how long the resolution of a tag recorded in the node cache is used without fetching the
+	// manifest from the registry. Zero always resolves tags with the registry.
+	NodeCacheTagTTL time.Duration
 }
 
 func (o *ImageFetcherOption) useDefaultKeyChain() bool {
@@ -63,7 +68,9 @@ func (o ImageFetcherOption) String() string {
 }
 
 type ImageFetcher struct {
-	fetchOpts []remote.Option
+	fetchOpts       []remote.Option
+	nodeCache       *NodeCache
+	nodeCacheTagTTL time.Duration
 }
 
 // ssrfProtectionTransport wraps http.RoundTripper to block SSRF via bearer realm
@@ -187,7 +194,9 @@ func NewImageFetcher(ctx context.Context, opt ImageFetcherOption) *ImageFetcher
 	fetchOpts = append(fetchOpts, remote.WithTransport(&ssrfProtectionTransport{inner: transport}))
 
 	return &ImageFetcher{
-		fetchOpts: append(fetchOpts, remote.WithContext(ctx)),
+		fetchOpts:       append(fetchOpts, remote.WithContext(ctx)),
+		nodeCache:       opt.NodeCache,
+		nodeCacheTagTTL: opt.NodeCacheTagTTL,
 	}
 }
 
@@ -195,35 +204,31 @@ func NewImageFetcher(ctx context.Context, opt ImageFetcherOption) *ImageFetcher
 // Wasm binary is not fetched immediately, but returned by `binaryFetcher` function, which is returned by PrepareFetch.
 // By this way, we can have another chance to check cache with `actualDigest` without downloading the OCI image.
 func (o *ImageFetcher) PrepareFetch(url string) (binaryFetcher func() ([]byte, error), actualDigest string, err error) {
-	ref, err := name.ParseReference(url)
-	if err != nil {
-		err = fmt.Errorf("could not parse url in image reference: %v", err)
-		return binaryFetcher, actualDigest, err
-	}
-	wasmLog.Infof("fetching image %s from registry %s with tag %s", ref.Context().RepositoryStr(),
-		ref.Context().RegistryStr(), ref.Identifier())
-
-	// fallback to http based request, inspired by [helm](https://github.com/helm/helm/blob/12f1bc0acdeb675a8c50a78462ed3917fb7b2e37/pkg/registry/client.go#L594)
-	// only deal with https fallback instead of attributing all other type of errors to URL parsing error
-	desc, err := remote.Get(ref, o.fetchOpts...)
-	if err != nil && strings.Contains(err.Error(), "server gave HTTP response") {
-		wasmLog.Infof("fetching image with plain text from %s", url)
-		ref, err = name.ParseReference(url, name.Insecure)
-		if err == nil {
-			desc, err = remote.Get(ref, o.fetchOpts...)
+	var img v1.Image
+	if o.nodeCache != nil {
+		if digest := o.nodeCache.resolve(url, o.nodeCacheTagTTL); digest != "" {
+			img = o.nodeCache.image(digest)
 		}
 	}
+	if img != nil {
+		wasmLog.Debugf("using image %s from the node cache", url)
+	} else {
+		_, desc, err := o.get(url)
+		if err != nil {
+			err = fmt.Errorf("could not fetch manifest: %v", err)
+			return binaryFetcher, actualDigest, err
+		}
 
-	if err != nil {
-		err = fmt.Errorf("could not fetch manifest: %v", err)
-		return binaryFetcher, actualDigest, err
-	}
-
-	// Fetch image.
-	img, err := desc.Image()
-	if err != nil {
-		err = fmt.Errorf("could not fetch image: %v", err)
-		return binaryFetcher, actualDigest, err
+		// Fetch image.
+		img, err = desc.Image()
+		if err != nil {
+			err = fmt.Errorf("could not fetch image: %v", err)
+			return binaryFetcher, actualDigest, err
+		}
+		if o.nodeCache != nil {
+			o.nodeCache.putImage(url, img)
+			img = nodeCachedImage{Image: img, cache: o.nodeCache}
+		}
 	}
 
 	// Check Manifest's digest if expManifestDigest is not empty.
@@ -269,6 +274,28 @@ func (o *ImageFetcher) PrepareFetch(url string) (binaryFetcher func() ([]byte, e
 	return binaryFetcher, actualDigest, err
 }
 
+// get fetches the descriptor of the image, and returns it with the reference it was fetched with.
+func (o *ImageFetcher) get(url string) (name.Reference, *remote.Descriptor, error) {
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse url in image reference: %v", err)
+	}
+	wasmLog.Infof("fetching image %s from registry %s with tag %s", ref.Context().RepositoryStr(),
+		ref.Context().RegistryStr(), ref.Identifier())
+
+	// fallback to http based request, inspired by [helm](https://github.com/helm/helm/blob/12f1bc0acdeb675a8c50a78462ed3917fb7b2e37/pkg/registry/client.go#L594)
+	// only deal with https fallback instead of attributing all other type of errors to URL parsing error
+	desc, err := remote.Get(ref, o.fetchOpts...)
+	if err != nil && strings.Contains(err.Error(), "server gave HTTP response") {
+		wasmLog.Infof("fetching image with plain text from %s", url)
+		ref, err = name.ParseReference(url, name.Insecure)
+		if err == nil {
+			desc, err = remote.Get(ref, o.fetchOpts...)
+		}
+	}
+	return ref, desc, err
+}
+
 // extractDockerImage extracts the Wasm binary from the
 // *compat* variant Wasm image with the standard Docker media type: application/vnd.docker.image.rootfs.diff.tar.gzip.
 // https://github.com/solo-io/wasm/blob/master/spec/spec-compat.md#specification
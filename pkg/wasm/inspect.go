@@ -0,0 +1,394 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// This file implements a static inspection of Wasm modules, checking that the proxy can load them: the module must
+// be a valid Wasm binary exporting a proxy-wasm ABI version supported by Envoy, and only import the host functions
+// Envoy provides. Only the import and export sections are decoded; the code itself is not validated.
+
+// Wasm section ids, see https://webassembly.github.io/spec/core/binary/modules.html#sections.
+const (
+	wasmImportSection = 2
+	wasmExportSection = 7
+)
+
+// Kinds of imports and exports.
+const (
+	ExternalFunction = "func"
+	ExternalTable    = "table"
+	ExternalMemory   = "memory"
+	ExternalGlobal   = "global"
+	ExternalTag      = "tag"
+)
+
+var externalKinds = []string{ExternalFunction, ExternalTable, ExternalMemory, ExternalGlobal, ExternalTag}
+
+// supportedABIVersions are the proxy-wasm ABI versions supported by Envoy, exported by modules as functions.
+var supportedABIVersions = []string{"proxy_abi_version_0_1_0", "proxy_abi_version_0_2_0", "proxy_abi_version_0_2_1"}
+
+// proxyHostFunctions are the proxy-wasm host functions Envoy provides in the "env" module.
+var proxyHostFunctions = sets.New(
+	"proxy_log", "proxy_get_log_level", "proxy_get_status", "proxy_set_tick_period_milliseconds",
+	"proxy_get_current_time_nanoseconds", "proxy_get_property", "proxy_set_property",
+	"proxy_continue_stream", "proxy_close_stream", "proxy_continue_request", "proxy_continue_response",
+	"proxy_send_local_response", "proxy_clear_route_cache", "proxy_get_configuration",
+	"proxy_get_header_map_pairs", "proxy_set_header_map_pairs", "proxy_get_header_map_value",
+	"proxy_add_header_map_value", "proxy_replace_header_map_value", "proxy_remove_header_map_value",
+	"proxy_get_header_map_size", "proxy_get_buffer_bytes", "proxy_get_buffer_status", "proxy_set_buffer_bytes",
+	"proxy_http_call", "proxy_grpc_call", "proxy_grpc_stream", "proxy_grpc_send", "proxy_grpc_cancel", "proxy_grpc_close",
+	"proxy_define_metric", "proxy_increment_metric", "proxy_record_metric", "proxy_get_metric",
+	"proxy_register_shared_queue", "proxy_resolve_shared_queue", "proxy_enqueue_shared_queue", "proxy_dequeue_shared_queue",
+	"proxy_get_shared_data", "proxy_set_shared_data", "proxy_set_effective_context", "proxy_done",
+	"proxy_call_foreign_function",
+)
+
+// wasiModules are the WASI modules Envoy partially implements, and wasiFunctions the functions it implements.
+var (
+	wasiModules   = sets.New("wasi_snapshot_preview1", "wasi_unstable")
+	wasiFunctions = sets.New(
+		"fd_write", "fd_read", "fd_seek", "fd_close", "fd_fdstat_get", "fd_fdstat_set_flags", "fd_prestat_get",
+		"fd_prestat_dir_name", "environ_get", "environ_sizes_get", "args_get", "args_sizes_get", "clock_time_get",
+		"clock_res_get", "random_get", "proc_exit", "sched_yield", "poll_oneoff", "path_open",
+	)
+)
+
+// ModuleImport is an import of a Wasm module.
+type ModuleImport struct {
+	Module string
+	Name   string
+	Kind   string
+}
+
+func (i ModuleImport) String() string {
+	return i.Module + "." + i.Name
+}
+
+// ModuleExport is an export of a Wasm module.
+type ModuleExport struct {
+	Name string
+	Kind string
+}
+
+// ModuleInfo describes the imports and exports of a Wasm module.
+type ModuleInfo struct {
+	Imports []ModuleImport
+	Exports []ModuleExport
+}
+
+// ABIVersions returns the proxy-wasm ABI versions exported by the module.
+func (m *ModuleInfo) ABIVersions() []string {
+	var versions []string
+	for _, e := range m.Exports {
+		if e.Kind == ExternalFunction && strings.HasPrefix(e.Name, "proxy_abi_version_") {
+			versions = append(versions, e.Name)
+		}
+	}
+	return versions
+}
+
+func (m *ModuleInfo) exports(kind, name string) bool {
+	return slices.ContainsFunc(m.Exports, func(e ModuleExport) bool {
+		return e.Kind == kind && e.Name == name
+	})
+}
+
+// FindingSeverity is the severity of a finding of the module inspection.
+type FindingSeverity string
+
+const (
+	// FindingError is a problem preventing the proxy from loading the module.
+	FindingError FindingSeverity = "Error"
+	// FindingWarning is a potential problem, which may fail when the module runs.
+	FindingWarning FindingSeverity = "Warning"
+)
+
+// ModuleFinding is a problem found by the inspection of a Wasm module.
+type ModuleFinding struct {
+	Severity FindingSeverity
+	Message  string
+}
+
+func (f ModuleFinding) String() string {
+	return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+}
+
+// InspectModule decodes the imports and exports of the Wasm binary.
+func InspectModule(b []byte) (*ModuleInfo, error) {
+	if !isValidWasmBinary(b) {
+		return nil, errors.New("not a Wasm binary: invalid header")
+	}
+	if !bytes.Equal(b[4:8], []byte{0x01, 0x00, 0x00, 0x00}) {
+		return nil, fmt.Errorf("unsupported Wasm binary version %v", b[4:8])
+	}
+	info := &ModuleInfo{}
+	r := &wasmReader{b: b[8:]}
+	for !r.done() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, fmt.Errorf("invalid section %d: %v", id, err)
+		}
+		payload, err := r.bytes(int(size))
+		if err != nil {
+			return nil, fmt.Errorf("invalid section %d: %v", id, err)
+		}
+		section := &wasmReader{b: payload}
+		switch id {
+		case wasmImportSection:
+			if info.Imports, err = readImports(section); err != nil {
+				return nil, fmt.Errorf("invalid import section: %v", err)
+			}
+		case wasmExportSection:
+			if info.Exports, err = readExports(section); err != nil {
+				return nil, fmt.Errorf("invalid export section: %v", err)
+			}
+		}
+	}
+	return info, nil
+}
+
+// CheckModule inspects the Wasm binary and returns the problems preventing the proxy from loading it, or
+// likely to make it fail. An error is returned if the binary cannot be decoded.
+func CheckModule(b []byte) ([]ModuleFinding, error) {
+	info, err := InspectModule(b)
+	if err != nil {
+		return nil, err
+	}
+	return info.Check(), nil
+}
+
+// Check returns the problems of the module, see CheckModule.
+func (m *ModuleInfo) Check() []ModuleFinding {
+	var findings []ModuleFinding
+	add := func(severity FindingSeverity, format string, args ...any) {
+		findings = append(findings, ModuleFinding{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	versions := m.ABIVersions()
+	if !slices.ContainsFunc(versions, func(v string) bool { return slices.Contains(supportedABIVersions, v) }) {
+		if len(versions) == 0 {
+			add(FindingError, "the module does not export a proxy-wasm ABI version, expected one of %s",
+				strings.Join(supportedABIVersions, ", "))
+		} else {
+			add(FindingError, "the module exports unsupported proxy-wasm ABI versions %s, expected one of %s",
+				strings.Join(versions, ", "), strings.Join(supportedABIVersions, ", "))
+		}
+	}
+	if !m.exports(ExternalMemory, "memory") {
+		add(FindingError, "the module does not export its memory")
+	}
+	if !m.exports(ExternalFunction, "proxy_on_memory_allocate") && !m.exports(ExternalFunction, "malloc") {
+		add(FindingError, "the module does not export a memory allocation function (proxy_on_memory_allocate or malloc)")
+	}
+
+	for _, i := range m.Imports {
+		switch {
+		case i.Module == "env" && strings.HasPrefix(i.Name, "proxy_"):
+			if i.Kind != ExternalFunction || !proxyHostFunctions.Contains(i.Name) {
+				add(FindingError, "the module imports %s, which is not a host function of the proxy", i)
+			}
+		case i.Module == "env":
+			add(FindingWarning, "the module imports %s, which may not be provided by the proxy", i)
+		case wasiModules.Contains(i.Module):
+			if i.Kind != ExternalFunction || !wasiFunctions.Contains(i.Name) {
+				add(FindingWarning, "the module imports %s, which is not implemented by the proxy", i)
+			}
+		default:
+			add(FindingError, "the module imports %s from the unsupported module %q", i, i.Module)
+		}
+	}
+	return findings
+}
+
+// HasErrors returns true if any of the findings prevents the proxy from loading the module.
+func HasErrors(findings []ModuleFinding) bool {
+	return slices.ContainsFunc(findings, func(f ModuleFinding) bool {
+		return f.Severity == FindingError
+	})
+}
+
+func readImports(r *wasmReader) ([]ModuleImport, error) {
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	// The count is not trusted for the allocation: each import takes at least one byte of the section.
+	imports := make([]ModuleImport, 0, min(int(count), len(r.b)))
+	for range count {
+		var i ModuleImport
+		if i.Module, err = r.name(); err != nil {
+			return nil, err
+		}
+		if i.Name, err = r.name(); err != nil {
+			return nil, err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if int(kind) >= len(externalKinds) {
+			return nil, fmt.Errorf("invalid import kind %d of %s.%s", kind, i.Module, i.Name)
+		}
+		i.Kind = externalKinds[kind]
+		if err := r.skipImportDesc(i.Kind); err != nil {
+			return nil, err
+		}
+		imports = append(imports, i)
+	}
+	return imports, nil
+}
+
+func readExports(r *wasmReader) ([]ModuleExport, error) {
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	// The count is not trusted for the allocation: each export takes at least one byte of the section.
+	exports := make([]ModuleExport, 0, min(int(count), len(r.b)))
+	for range count {
+		var e ModuleExport
+		if e.Name, err = r.name(); err != nil {
+			return nil, err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if int(kind) >= len(externalKinds) {
+			return nil, fmt.Errorf("invalid export kind %d of %s", kind, e.Name)
+		}
+		e.Kind = externalKinds[kind]
+		if _, err := r.u32(); err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, nil
+}
+
+// wasmReader decodes the Wasm binary format.
+type wasmReader struct {
+	b []byte
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of the module")
+
+func (r *wasmReader) done() bool {
+	return len(r.b) == 0
+}
+
+func (r *wasmReader) byte() (byte, error) {
+	if len(r.b) == 0 {
+		return 0, errUnexpectedEnd
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v, nil
+}
+
+func (r *wasmReader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, errUnexpectedEnd
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v, nil
+}
+
+// leb128 decodes an unsigned LEB128 integer of at most bits bits.
+func (r *wasmReader) leb128(bits uint) (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < bits; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("invalid LEB128 integer")
+}
+
+func (r *wasmReader) u32() (uint32, error) {
+	v, err := r.leb128(32)
+	return uint32(v), err
+}
+
+func (r *wasmReader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	return string(b), err
+}
+
+func (r *wasmReader) limits() error {
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	// Bit 0 flags the presence of a maximum, bit 2 64 bits memories.
+	bits := uint(32)
+	if flags&0x04 != 0 {
+		bits = 64
+	}
+	if _, err := r.leb128(bits); err != nil {
+		return err
+	}
+	if flags&0x01 != 0 {
+		_, err = r.leb128(bits)
+	}
+	return err
+}
+
+func (r *wasmReader) skipImportDesc(kind string) error {
+	switch kind {
+	case ExternalFunction:
+		_, err := r.u32()
+		return err
+	case ExternalTable:
+		if _, err := r.byte(); err != nil {
+			return err
+		}
+		return r.limits()
+	case ExternalMemory:
+		return r.limits()
+	case ExternalGlobal:
+		_, err := r.bytes(2)
+		return err
+	default:
+		if _, err := r.byte(); err != nil {
+			return err
+		}
+		_, err := r.u32()
+		return err
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// testModule assembles a Wasm binary with the given imports and exports.
+func testModule(imports []ModuleImport, exports []ModuleExport) []byte {
+	kind := func(k string) byte {
+		for i, e := range externalKinds {
+			if e == k {
+				return byte(i)
+			}
+		}
+		panic("unknown kind " + k)
+	}
+	name := func(s string) []byte {
+		return append([]byte{byte(len(s))}, s...)
+	}
+	section := func(id byte, payload []byte) []byte {
+		b := []byte{id}
+		for n := len(payload); ; n >>= 7 {
+			if n < 0x80 {
+				b = append(b, byte(n))
+				break
+			}
+			b = append(b, byte(n&0x7f)|0x80)
+		}
+		return append(b, payload...)
+	}
+
+	b := append([]byte{}, wasmHeader...)
+	// A custom section, ignored.
+	b = append(b, section(0, name("producers"))...)
+	if len(imports) > 0 {
+		payload := []byte{byte(len(imports))}
+		for _, i := range imports {
+			payload = append(payload, name(i.Module)...)
+			payload = append(payload, name(i.Name)...)
+			payload = append(payload, kind(i.Kind))
+			switch i.Kind {
+			case ExternalMemory:
+				payload = append(payload, 0x01, 0x01, 0x02)
+			case ExternalGlobal:
+				payload = append(payload, 0x7f, 0x00)
+			default:
+				payload = append(payload, 0x00)
+			}
+		}
+		b = append(b, section(wasmImportSection, payload)...)
+	}
+	if len(exports) > 0 {
+		payload := []byte{byte(len(exports))}
+		for i, e := range exports {
+			payload = append(payload, name(e.Name)...)
+			payload = append(payload, kind(e.Kind), byte(i))
+		}
+		b = append(b, section(wasmExportSection, payload)...)
+	}
+	return b
+}
+
+var validExports = []ModuleExport{
+	{Name: "memory", Kind: ExternalMemory},
+	{Name: "proxy_abi_version_0_2_1", Kind: ExternalFunction},
+	{Name: "proxy_on_memory_allocate", Kind: ExternalFunction},
+	{Name: "proxy_on_context_create", Kind: ExternalFunction},
+}
+
+func TestInspectModule(t *testing.T) {
+	imports := []ModuleImport{
+		{Module: "env", Name: "proxy_log", Kind: ExternalFunction},
+		{Module: "env", Name: "memory_base", Kind: ExternalGlobal},
+		{Module: "wasi_snapshot_preview1", Name: "fd_write", Kind: ExternalFunction},
+	}
+	info, err := InspectModule(testModule(imports, validExports))
+	assert.NoError(t, err)
+	assert.Equal(t, info.Imports, imports)
+	assert.Equal(t, info.Exports, validExports)
+	assert.Equal(t, info.ABIVersions(), []string{"proxy_abi_version_0_2_1"})
+
+	for name, b := range map[string][]byte{
+		"empty":              {},
+		"invalid header":     []byte("not a wasm module"),
+		"unsupported":        {0x00, 0x61, 0x73, 0x6d, 0x0d, 0x00, 0x01, 0x00},
+		"truncated section":  append(append([]byte{}, wasmHeader...), wasmExportSection, 0x10, 0x01),
+		"truncated exports":  append(append([]byte{}, wasmHeader...), wasmExportSection, 0x02, 0x01, 0x05),
+		"invalid LEB128":     append(append([]byte{}, wasmHeader...), 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01),
+		"invalid importKind": append(append([]byte{}, wasmHeader...), wasmImportSection, 0x04, 0x01, 0x00, 0x00, 0x09),
+		// Counts are not trusted to preallocate the imports and exports.
+		"huge import count": append(append([]byte{}, wasmHeader...), wasmImportSection, 0x05, 0xff, 0xff, 0xff, 0xff, 0x0f),
+		"huge export count": append(append([]byte{}, wasmHeader...), wasmExportSection, 0x05, 0xff, 0xff, 0xff, 0xff, 0x0f),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := InspectModule(b); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestCheckModule(t *testing.T) {
+	withoutExport := func(name string) []ModuleExport {
+		var exports []ModuleExport
+		for _, e := range validExports {
+			if e.Name != name {
+				exports = append(exports, e)
+			}
+		}
+		return exports
+	}
+	cases := []struct {
+		name    string
+		imports []ModuleImport
+		exports []ModuleExport
+		want    []ModuleFinding
+	}{
+		{
+			name: "valid",
+			imports: []ModuleImport{
+				{Module: "env", Name: "proxy_log", Kind: ExternalFunction},
+				{Module: "env", Name: "proxy_http_call", Kind: ExternalFunction},
+				{Module: "wasi_snapshot_preview1", Name: "fd_write", Kind: ExternalFunction},
+			},
+			exports: validExports,
+		},
+		{
+			name: "malloc allocator",
+			exports: append(withoutExport("proxy_on_memory_allocate"),
+				ModuleExport{Name: "malloc", Kind: ExternalFunction}),
+		},
+		{
+			name:    "no ABI version",
+			exports: withoutExport("proxy_abi_version_0_2_1"),
+			want: []ModuleFinding{{
+				Severity: FindingError,
+				Message: "the module does not export a proxy-wasm ABI version, expected one of " +
+					"proxy_abi_version_0_1_0, proxy_abi_version_0_2_0, proxy_abi_version_0_2_1",
+			}},
+		},
+		{
+			name: "unsupported ABI version",
+			exports: append(withoutExport("proxy_abi_version_0_2_1"),
+				ModuleExport{Name: "proxy_abi_version_0_3_0", Kind: ExternalFunction}),
+			want: []ModuleFinding{{
+				Severity: FindingError,
+				Message: "the module exports unsupported proxy-wasm ABI versions proxy_abi_version_0_3_0, expected one of " +
+					"proxy_abi_version_0_1_0, proxy_abi_version_0_2_0, proxy_abi_version_0_2_1",
+			}},
+		},
+		{
+			name:    "no memory",
+			exports: withoutExport("memory"),
+			want:    []ModuleFinding{{Severity: FindingError, Message: "the module does not export its memory"}},
+		},
+		{
+			name:    "no allocator",
+			exports: withoutExport("proxy_on_memory_allocate"),
+			want: []ModuleFinding{{
+				Severity: FindingError,
+				Message:  "the module does not export a memory allocation function (proxy_on_memory_allocate or malloc)",
+			}},
+		},
+		{
+			name: "unknown imports",
+			imports: []ModuleImport{
+				{Module: "env", Name: "proxy_get_unicorn", Kind: ExternalFunction},
+				{Module: "env", Name: "emscripten_notify_memory_growth", Kind: ExternalFunction},
+				{Module: "wasi_snapshot_preview1", Name: "sock_accept", Kind: ExternalFunction},
+				{Module: "wasi:http/outgoing-handler", Name: "handle", Kind: ExternalFunction},
+			},
+			exports: validExports,
+			want: []ModuleFinding{
+				{Severity: FindingError, Message: "the module imports env.proxy_get_unicorn, which is not a host function of the proxy"},
+				{Severity: FindingWarning, Message: "the module imports env.emscripten_notify_memory_growth, which may not be provided by the proxy"},
+				{Severity: FindingWarning, Message: "the module imports wasi_snapshot_preview1.sock_accept, which is not implemented by the proxy"},
+				{
+					Severity: FindingError,
+					Message:  `the module imports wasi:http/outgoing-handler.handle from the unsupported module "wasi:http/outgoing-handler"`,
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			findings, err := CheckModule(testModule(tc.imports, tc.exports))
+			assert.NoError(t, err)
+			assert.Equal(t, findings, tc.want)
+			assert.Equal(t, HasErrors(findings), len(tc.want) > 0 && tc.want[0].Severity == FindingError)
+		})
+	}
+}
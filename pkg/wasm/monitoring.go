@@ -23,6 +23,7 @@ const (
 	downloadFailure  = "download_failure"
 	manifestFailure  = "manifest_failure"
 	checksumMismatch = "checksum_mismatched"
+	signatureFailure = "signature_failure"
 
 	// For Wasm conversion metric.
 	conversionSuccess   = "success"
@@ -47,14 +48,20 @@ var (
 		"number of Wasm remote fetch cache lookups.",
 	)
 
+	wasmNodeCacheLookupCount = monitoring.NewSum(
+		"wasm_node_cache_lookup_count",
+		"number of Wasm node cache lookups.",
+	)
+
 	wasmRemoteFetchCount = monitoring.NewSum(
 		"wasm_remote_fetch_count",
-		"number of Wasm remote fetches and results, including success, download failure, and checksum mismatch.",
+		"number of Wasm remote fetches and results, including success, download failure, checksum mismatch, and signature verification failure.",
 	)
 
 	wasmConfigConversionCount = monitoring.NewSum(
 		"wasm_config_conversion_count",
-		"number of Wasm config conversions and results, including success, no remote load, marshal failure, remote fetch failure, miss remote fetch hint.",
+		"number of Wasm config conversions and results, including success, no remote load, marshal failure, remote fetch failure, "+
+			"signature verification failure, miss remote fetch hint.",
 	)
 
 	wasmConfigConversionDuration = monitoring.NewDistribution(
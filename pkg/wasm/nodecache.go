@@ -0,0 +1,377 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// NodeCache is a content-addressed store of Wasm module blobs shared by the agents of a node, typically in a
+// hostPath volume, so that a module used by many pods of the node is downloaded once. Blobs are stored under
+// their sha256 digest: the manifests and layers of OCI images under their digest, and modules fetched over
+// http/https under the checksum of the module. As the directory is writable by every agent of the node, the
+// digest of a blob is checked each time it is read, and blobs not matching their digest are discarded.
+//
+// The node cache also records the manifest digest each image reference was last resolved to, so that the agents
+// of the node do not fetch the manifest of an image from the registry for every pod. References by tag are only
+// trusted for the tag TTL, while references by digest always resolve to the same image. Unlike blobs, resolutions
+// cannot be verified, so the directory must only be writable by the proxies of the node.
+type NodeCache struct {
+	dir      string
+	maxBytes int64
+}
+
+// NewNodeCache returns a node cache storing blobs in dir, evicting the least recently used ones beyond maxBytes.
+// maxBytes of zero disables the size based eviction.
+func NewNodeCache(dir string, maxBytes int64) (*NodeCache, error) {
+	for _, d := range []string{filepath.Join(dir, "blobs", "sha256"), filepath.Join(dir, "refs")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &NodeCache{dir: dir, maxBytes: maxBytes}, nil
+}
+
+func (n *NodeCache) blobPath(digest string) string {
+	return filepath.Join(n.dir, "blobs", "sha256", digest)
+}
+
+// refPath is the path of the record of the resolution of an image reference, named after the hash of the reference.
+func (n *NodeCache) refPath(ref string) string {
+	sum := sha256.Sum256([]byte(ref))
+	return filepath.Join(n.dir, "refs", hex.EncodeToString(sum[:]))
+}
+
+// Get returns the blob with the given sha256 digest, or nil if the node cache does not have a valid one.
+func (n *NodeCache) Get(digest string) []byte {
+	hit := false
+	defer func() {
+		wasmNodeCacheLookupCount.With(hitTag.Value(strconv.FormatBool(hit))).Increment()
+	}()
+	if !validDigest(digest) {
+		return nil
+	}
+	path := n.blobPath(digest)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, features.MaxWasmBinarySizeBytes+1))
+	if err != nil {
+		return nil
+	}
+	if sum := sha256.Sum256(b); hex.EncodeToString(sum[:]) != digest {
+		wasmLog.Warnf("discarding Wasm blob %s from the node cache: digest mismatch", digest)
+		_ = os.Remove(path)
+		return nil
+	}
+	// The modification time records the last use of the blob, for the eviction.
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	hit = true
+	return b
+}
+
+// Put stores the blob with the given sha256 digest, if it matches the digest.
+func (n *NodeCache) Put(digest string, b []byte) {
+	if !validDigest(digest) {
+		return
+	}
+	if sum := sha256.Sum256(b); hex.EncodeToString(sum[:]) != digest {
+		return
+	}
+	path := n.blobPath(digest)
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	if err := writeAtomic(path, b); err != nil {
+		wasmLog.Warnf("failed to store Wasm blob %s in the node cache: %v", digest, err)
+	}
+}
+
+// resolve returns the digest of the image manifest the reference was last resolved to, or an empty string if the
+// reference was never resolved, or was resolved by tag more than ttl ago.
+func (n *NodeCache) resolve(ref string, ttl time.Duration) string {
+	path := n.refPath(ref)
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if !pinnedReference(ref) && time.Since(info.ModTime()) > ttl {
+		return ""
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	digest := string(b)
+	if !validDigest(digest) {
+		return ""
+	}
+	return digest
+}
+
+// image returns the image with the given manifest digest, if the node cache has its manifest and all its layers.
+// The config of the image is not stored, as Wasm modules are only extracted from the layers.
+func (n *NodeCache) image(digest string) v1.Image {
+	raw := n.Get(digest)
+	if raw == nil {
+		return nil
+	}
+	manifest, err := v1.ParseManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	for _, l := range manifest.Layers {
+		if l.Digest.Algorithm != "sha256" {
+			return nil
+		}
+		if _, err := os.Stat(n.blobPath(l.Digest.Hex)); err != nil {
+			return nil
+		}
+	}
+	img, err := partial.CompressedToImage(storedImage{raw: raw, manifest: manifest, cache: n})
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// putImage stores the manifest of the image fetched from the registry, and records the resolution of the reference.
+func (n *NodeCache) putImage(ref string, img v1.Image) {
+	d, err := img.Digest()
+	if err != nil || d.Algorithm != "sha256" {
+		return
+	}
+	raw, err := img.RawManifest()
+	if err != nil {
+		return
+	}
+	n.Put(d.Hex, raw)
+	// The record is rewritten even if unchanged, as its modification time is the time of the resolution.
+	if err := writeAtomic(n.refPath(ref), []byte(d.Hex)); err != nil {
+		wasmLog.Warnf("failed to record the resolution of %s in the node cache: %v", ref, err)
+	}
+}
+
+// writeAtomic writes to a temporary file renamed once complete, so that other agents never read a partial file.
+func writeAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// purge removes the blobs not used for the expiry duration, and the least recently used blobs beyond the size limit.
+// Resolutions older than the expiry duration are removed as well.
+// Every agent of the node purges the shared directory, so blobs may disappear concurrently.
+func (n *NodeCache) purge(expiry time.Duration) {
+	if refs, err := os.ReadDir(filepath.Join(n.dir, "refs")); err == nil {
+		for _, e := range refs {
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > expiry {
+				_ = os.Remove(filepath.Join(n.dir, "refs", e.Name()))
+			}
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(n.dir, "blobs", "sha256"))
+	if err != nil {
+		wasmLog.Warnf("failed to list the Wasm node cache: %v", err)
+		return
+	}
+	type blob struct {
+		path string
+		info fs.FileInfo
+	}
+	var blobs []blob
+	var total int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(n.dir, "blobs", "sha256", e.Name())
+		// Leftovers of interrupted writes are removed as well.
+		if time.Since(info.ModTime()) > expiry {
+			if err := os.Remove(path); err == nil || errors.Is(err, fs.ErrNotExist) {
+				wasmLog.Debugf("removed stale Wasm blob %s from the node cache", e.Name())
+			}
+			continue
+		}
+		if strings.HasPrefix(e.Name(), ".") {
+			// Blob being written by another agent.
+			continue
+		}
+		blobs = append(blobs, blob{path: path, info: info})
+		total += info.Size()
+	}
+	if n.maxBytes == 0 || total <= n.maxBytes {
+		return
+	}
+	slices.SortFunc(blobs, func(a, b blob) int {
+		return a.info.ModTime().Compare(b.info.ModTime())
+	})
+	for _, b := range blobs {
+		if total <= n.maxBytes {
+			break
+		}
+		_ = os.Remove(b.path)
+		total -= b.info.Size()
+	}
+}
+
+func validDigest(digest string) bool {
+	b, err := hex.DecodeString(digest)
+	return err == nil && len(b) == sha256.Size
+}
+
+// pinnedReference returns true if the image reference is by digest, so that it always resolves to the same image.
+func pinnedReference(ref string) bool {
+	_, err := name.NewDigest(ref, name.Insecure)
+	return err == nil
+}
+
+// nodeCachedImage is an image whose layers are looked up in the node cache before being fetched from the registry.
+type nodeCachedImage struct {
+	v1.Image
+	cache *NodeCache
+}
+
+func (i nodeCachedImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]v1.Layer, 0, len(layers))
+	for _, l := range layers {
+		ret = append(ret, nodeCachedLayer{Layer: l, cache: i.cache})
+	}
+	return ret, nil
+}
+
+type nodeCachedLayer struct {
+	v1.Layer
+	cache *NodeCache
+}
+
+func (l nodeCachedLayer) Compressed() (io.ReadCloser, error) {
+	d, err := l.Digest()
+	if err != nil || d.Algorithm != "sha256" {
+		return l.Layer.Compressed()
+	}
+	if b := l.cache.Get(d.Hex); b != nil {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	r, err := l.Layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(io.LimitReader(r, features.MaxWasmBinarySizeBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	l.cache.Put(d.Hex, b)
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// storedImage is an image read from the node cache, without fetching anything from the registry.
+type storedImage struct {
+	raw      []byte
+	manifest *v1.Manifest
+	cache    *NodeCache
+}
+
+func (i storedImage) RawManifest() ([]byte, error) {
+	return i.raw, nil
+}
+
+func (i storedImage) MediaType() (types.MediaType, error) {
+	if i.manifest.MediaType != "" {
+		return i.manifest.MediaType, nil
+	}
+	return types.OCIManifestSchema1, nil
+}
+
+func (i storedImage) RawConfigFile() ([]byte, error) {
+	return nil, fmt.Errorf("config %s is not stored in the node cache", i.manifest.Config.Digest)
+}
+
+func (i storedImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	for _, l := range i.manifest.Layers {
+		if l.Digest == h {
+			return storedLayer{desc: l, cache: i.cache}, nil
+		}
+	}
+	return nil, fmt.Errorf("layer %s not found in manifest", h)
+}
+
+type storedLayer struct {
+	desc  v1.Descriptor
+	cache *NodeCache
+}
+
+func (l storedLayer) Digest() (v1.Hash, error) {
+	return l.desc.Digest, nil
+}
+
+func (l storedLayer) Size() (int64, error) {
+	return l.desc.Size, nil
+}
+
+func (l storedLayer) MediaType() (types.MediaType, error) {
+	return l.desc.MediaType, nil
+}
+
+func (l storedLayer) Compressed() (io.ReadCloser, error) {
+	b := l.cache.Get(l.desc.Digest.Hex)
+	if b == nil {
+		return nil, fmt.Errorf("layer %s is not in the node cache", l.desc.Digest)
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
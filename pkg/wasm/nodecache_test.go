@@ -0,0 +1,195 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestNodeCache(t *testing.T) {
+	n, err := NewNodeCache(t.TempDir(), 0)
+	assert.NoError(t, err)
+
+	blob := []byte("wasm blob")
+	digest := digestOf(blob)
+	assert.Equal(t, n.Get(digest) == nil, true)
+
+	n.Put(digest, blob)
+	assert.Equal(t, n.Get(digest), blob)
+
+	// Blobs not matching their digest are neither stored nor returned.
+	other := digestOf([]byte("other"))
+	n.Put(other, blob)
+	assert.Equal(t, n.Get(other) == nil, true)
+	assert.NoError(t, os.WriteFile(n.blobPath(other), blob, 0o644))
+	assert.Equal(t, n.Get(other) == nil, true)
+	if _, err := os.Stat(n.blobPath(other)); !os.IsNotExist(err) {
+		t.Fatalf("corrupted blob was not discarded: %v", err)
+	}
+
+	// Invalid digests do not escape the cache directory.
+	n.Put("../../escape", blob)
+	assert.Equal(t, n.Get("../../escape") == nil, true)
+}
+
+func TestNodeCachePurge(t *testing.T) {
+	n, err := NewNodeCache(t.TempDir(), 20)
+	assert.NoError(t, err)
+
+	put := func(content string, lastUse time.Time) string {
+		digest := digestOf([]byte(content))
+		n.Put(digest, []byte(content))
+		assert.NoError(t, os.Chtimes(n.blobPath(digest), lastUse, lastUse))
+		return digest
+	}
+	now := time.Now()
+	stale := put("stale blob", now.Add(-2*time.Hour))
+	oldest := put("oldest blob", now.Add(-30*time.Minute))
+	older := put("older blob", now.Add(-20*time.Minute))
+	recent := put("recent", now)
+
+	n.purge(time.Hour)
+	exists := func(digest string) bool {
+		_, err := os.Stat(n.blobPath(digest))
+		return err == nil
+	}
+	assert.Equal(t, exists(stale), false)
+	// The least recently used blobs are evicted beyond the size limit.
+	assert.Equal(t, exists(oldest), false)
+	assert.Equal(t, exists(older), true)
+	assert.Equal(t, exists(recent), true)
+}
+
+func TestNodeCacheResolve(t *testing.T) {
+	n, err := NewNodeCache(t.TempDir(), 0)
+	assert.NoError(t, err)
+
+	digest := digestOf([]byte("manifest"))
+	tagged := "registry.example/wasm/plugin:v1"
+	pinned := "registry.example/wasm/plugin@sha256:" + digest
+	for _, ref := range []string{tagged, pinned} {
+		assert.Equal(t, n.resolve(ref, time.Minute), "")
+		assert.NoError(t, writeAtomic(n.refPath(ref), []byte(digest)))
+		assert.Equal(t, n.resolve(ref, time.Minute), digest)
+		old := time.Now().Add(-time.Hour)
+		assert.NoError(t, os.Chtimes(n.refPath(ref), old, old))
+	}
+	// Tags are resolved again with the registry after the TTL, unlike digests.
+	assert.Equal(t, n.resolve(tagged, time.Minute), "")
+	assert.Equal(t, n.resolve(pinned, time.Minute), digest)
+
+	assert.NoError(t, writeAtomic(n.refPath(tagged), []byte("../../escape")))
+	assert.Equal(t, n.resolve(tagged, time.Minute), "")
+}
+
+func TestWasmCacheNodeCache(t *testing.T) {
+	var registryRequests, httpRequests int32
+	reg := registry.New()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/blobs/") || strings.Contains(r.URL.Path, "/manifests/") {
+			atomic.AddInt32(&registryRequests, 1)
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+	ref := fmt.Sprintf("%s/test/shared:v1", u.Host)
+	manifestDigest := pushWasmImage(t, ref, "shared")
+
+	httpModule := append(wasmHeader, []byte("http module")...)
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&httpRequests, 1)
+		w.Write(httpModule)
+	}))
+	defer hs.Close()
+
+	// Two agents of the same node share the node cache.
+	nodeCacheDir := t.TempDir()
+	newCache := func() *LocalFileCache {
+		opts := defaultOptions()
+		opts.NodeCacheDir = nodeCacheDir
+		c := NewLocalFileCache(t.TempDir(), opts)
+		t.Cleanup(func() { close(c.stopChan) })
+		return c
+	}
+	first, second, third, fourth := newCache(), newCache(), newCache(), newCache()
+	getOCI := func(c *LocalFileCache, policy PullPolicy) string {
+		path, err := c.Get("oci://"+ref, GetOptions{
+			ResourceName:   "namespace.resource",
+			RequestTimeout: 10 * time.Second,
+			PullPolicy:     policy,
+		})
+		assert.NoError(t, err)
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return string(b)
+	}
+	getHTTP := func(c *LocalFileCache) {
+		_, err := c.Get(hs.URL+"/module.wasm", GetOptions{
+			ResourceName:   "namespace.resource",
+			RequestTimeout: 10 * time.Second,
+			Checksum:       digestOf(httpModule),
+		})
+		assert.NoError(t, err)
+	}
+
+	want := getOCI(first, Unspecified)
+	requests := atomic.LoadInt32(&registryRequests)
+	// The second agent resolves the tag and reads the image from the node cache, without contacting the registry.
+	assert.Equal(t, getOCI(second, Unspecified), want)
+	assert.Equal(t, atomic.LoadInt32(&registryRequests), requests)
+	// With the Always pull policy the tag is resolved with the registry, but the layers are still read from the
+	// node cache.
+	assert.Equal(t, getOCI(third, Always), want)
+	assert.Equal(t, atomic.LoadInt32(&registryRequests) > requests, true)
+
+	// Images whose layers were evicted from the node cache are fetched from the registry again.
+	manifest, err := v1.ParseManifest(bytes.NewReader(first.nodeCache.Get(manifestDigest)))
+	assert.NoError(t, err)
+	layer := first.nodeCache.blobPath(manifest.Layers[0].Digest.Hex)
+	assert.NoError(t, os.Remove(layer))
+	requests = atomic.LoadInt32(&registryRequests)
+	assert.Equal(t, getOCI(fourth, Unspecified), want)
+	assert.Equal(t, atomic.LoadInt32(&registryRequests) > requests, true)
+	if _, err := os.Stat(layer); err != nil {
+		t.Fatalf("layer was not stored in the node cache again: %v", err)
+	}
+
+	getHTTP(first)
+	getHTTP(second)
+	assert.Equal(t, atomic.LoadInt32(&httpRequests), int32(1))
+}
@@ -25,6 +25,8 @@ const (
 	DefaultModuleExpiry          = 24 * time.Hour
 	DefaultHTTPRequestTimeout    = 15 * time.Second
 	DefaultHTTPRequestMaxRetries = 5
+	DefaultNodeCacheMaxBytes     = 1 << 30
+	DefaultNodeCacheTagTTL       = 5 * time.Minute
 )
 
 // Options contains configurations to create a Cache instance.
@@ -34,6 +36,12 @@ type Options struct {
 	InsecureRegistries    sets.String
 	HTTPRequestTimeout    time.Duration
 	HTTPRequestMaxRetries int
+	// Directory of the node cache shared by the agents of the node, see NodeCache. Empty disables the node cache.
+	NodeCacheDir string
+	// Size limit of the node cache in bytes. Zero means no limit.
+	NodeCacheMaxBytes int64
+	// How long the resolution of an image tag recorded in the node cache is used without fetching the manifest.
+	NodeCacheTagTTL time.Duration
 }
 
 func defaultOptions() Options {
@@ -43,6 +51,7 @@ func defaultOptions() Options {
 		InsecureRegistries:    sets.New[string](),
 		HTTPRequestTimeout:    DefaultHTTPRequestTimeout,
 		HTTPRequestMaxRetries: DefaultHTTPRequestMaxRetries,
+		NodeCacheTagTTL:       DefaultNodeCacheTagTTL,
 	}
 }
 
@@ -68,4 +77,6 @@ type GetOptions struct {
 	RequestTimeout  time.Duration
 	PullSecret      []byte
 	PullPolicy      PullPolicy
+	// SignaturePolicy, if set, lists the signers which must have signed the module. Only OCI images can be verified.
+	SignaturePolicy *SignaturePolicy
 }
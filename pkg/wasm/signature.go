@@ -0,0 +1,311 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/hashicorp/go-multierror"
+)
+
+// This file implements the verification of cosign (https://github.com/sigstore/cosign) signatures of Wasm images.
+// Signatures are stored in the same repository as the image, in an image tagged `sha256-<digest>.sig`, with one
+// "simple signing" payload layer per signature. The signature itself, and the signing certificate if any, are
+// carried in annotations of the layer.
+//
+// Only signatures made with a key pair are supported. Keyless signatures cannot be trusted without checking the
+// inclusion of the signature in the transparency log (Rekor) at a time the short-lived signing certificate was
+// valid, so policies with keyless signers are rejected until that verification is implemented.
+
+const (
+	cosignSignatureTagSuffix = ".sig"
+	cosignPayloadMediaType   = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignPayloadType        = "cosign container image signature"
+
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+
+	// maxSignaturePayloadBytes bounds the size of a signature payload, which is a small JSON document.
+	maxSignaturePayloadBytes = 1 << 20
+)
+
+// errSignatureVerification is wrapped by the errors of Wasm modules rejected by their signature policy.
+var errSignatureVerification = errors.New("signature verification failed")
+
+// Signer is a signer whose signature is required on a Wasm image, identified by its public key.
+type Signer struct {
+	// PEM encoded public key.
+	PublicKey string `json:"publicKey,omitempty"`
+	// OIDC issuer and identity of the signing certificate of a keyless signer. Keyless signers are not supported
+	// yet, see above; the fields are only parsed to reject them explicitly.
+	Issuer  string `json:"issuer,omitempty"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// SignaturePolicy lists the signers which must all have signed a Wasm image before it is used.
+type SignaturePolicy struct {
+	// key identifies the policy in the cache of verification results.
+	key     string
+	signers []signer
+}
+
+type signer struct {
+	publicKey crypto.PublicKey
+}
+
+func (s signer) String() string {
+	der, _ := x509.MarshalPKIXPublicKey(s.publicKey)
+	sum := sha256.Sum256(der)
+	return "key sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// ParseSignaturePolicy parses the JSON list of required signers of a WasmPlugin, e.g.
+//
+//	[{"publicKey": "-----BEGIN PUBLIC KEY-----\n..."},
+//	 {"publicKey": "-----BEGIN PUBLIC KEY-----\n..."}]
+func ParseSignaturePolicy(raw string) (*SignaturePolicy, error) {
+	var signers []Signer
+	if err := json.Unmarshal([]byte(raw), &signers); err != nil {
+		return nil, fmt.Errorf("invalid signature policy: %v", err)
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("invalid signature policy: no signers")
+	}
+	sum := sha256.Sum256([]byte(raw))
+	policy := &SignaturePolicy{key: hex.EncodeToString(sum[:])}
+	for i, s := range signers {
+		switch {
+		case s.Issuer != "" || s.Subject != "":
+			return nil, fmt.Errorf("invalid signer %d: keyless signers are not supported, "+
+				"as the transparency log is not verified", i)
+		case s.PublicKey != "":
+			key, err := parsePublicKey(s.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("invalid signer %d: %v", i, err)
+			}
+			policy.signers = append(policy.signers, signer{publicKey: key})
+		default:
+			return nil, fmt.Errorf("invalid signer %d: publicKey is required", i)
+		}
+	}
+	return policy, nil
+}
+
+func parsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// cosignSignature is a signature attached to an image.
+type cosignSignature struct {
+	payload   []byte
+	signature []byte
+	// Signing certificate, if any.
+	cert *x509.Certificate
+}
+
+// VerifySignatures checks that the image with the given manifest digest in the repository of url
+// has been signed by every signer of the policy.
+func (o *ImageFetcher) VerifySignatures(url, digest string, policy *SignaturePolicy) error {
+	ref, _, err := o.get(url)
+	if err != nil {
+		return fmt.Errorf("could not fetch manifest: %v", err)
+	}
+	sigs, err := o.fetchSignatures(ref.Context(), digest)
+	if err != nil {
+		return err
+	}
+	return verifySignatures(sigs, digest, policy)
+}
+
+func (o *ImageFetcher) fetchSignatures(repo name.Repository, digest string) ([]cosignSignature, error) {
+	tag := repo.Tag("sha256-" + digest + cosignSignatureTagSuffix)
+	img, err := remote.Image(tag, o.fetchOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch signatures %s: %v", tag, err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve manifest of signatures %s: %v", tag, err)
+	}
+	var sigs []cosignSignature
+	for _, desc := range manifest.Layers {
+		if desc.MediaType != cosignPayloadMediaType {
+			continue
+		}
+		sig, err := readSignature(img, desc)
+		if err != nil {
+			// A malformed signature does not invalidate the others.
+			wasmLog.Warnf("ignoring signature %s in %s: %v", desc.Digest, tag, err)
+			continue
+		}
+		sigs = append(sigs, sig)
+	}
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("no signature found in %s", tag)
+	}
+	return sigs, nil
+}
+
+func readSignature(img v1.Image, desc v1.Descriptor) (cosignSignature, error) {
+	var sig cosignSignature
+	layer, err := img.LayerByDigest(desc.Digest)
+	if err != nil {
+		return sig, err
+	}
+	r, err := layer.Compressed()
+	if err != nil {
+		return sig, err
+	}
+	defer r.Close()
+	if sig.payload, err = io.ReadAll(io.LimitReader(r, maxSignaturePayloadBytes)); err != nil {
+		return sig, err
+	}
+	if sig.signature, err = base64.StdEncoding.DecodeString(desc.Annotations[cosignSignatureAnnotation]); err != nil {
+		return sig, fmt.Errorf("invalid signature: %v", err)
+	}
+	if pemCert := desc.Annotations[cosignCertificateAnnotation]; pemCert != "" {
+		certs, err := parseCertificates(pemCert)
+		if err != nil || len(certs) != 1 {
+			return sig, fmt.Errorf("invalid certificate: %v", err)
+		}
+		sig.cert = certs[0]
+	}
+	return sig, nil
+}
+
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs, nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// verifySignatures checks that every signer of the policy has a valid signature of the digest among sigs.
+func verifySignatures(sigs []cosignSignature, digest string, policy *SignaturePolicy) error {
+	var errs *multierror.Error
+	for _, s := range policy.signers {
+		var reasons []string
+		verified := false
+		for _, sig := range sigs {
+			err := sig.verify(s, digest)
+			if err == nil {
+				verified = true
+				break
+			}
+			reasons = append(reasons, err.Error())
+		}
+		if !verified {
+			errs = multierror.Append(errs, fmt.Errorf("no valid signature by %v (%s)", s, strings.Join(reasons, "; ")))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+func (sig cosignSignature) verify(s signer, digest string) error {
+	if sig.cert != nil && !publicKeyEqual(sig.cert.PublicKey, s.publicKey) {
+		return errors.New("signed by another key")
+	}
+	if err := verifyWithKey(s.publicKey, sig.payload, sig.signature); err != nil {
+		return err
+	}
+	return verifyPayload(sig.payload, digest)
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+func verifyWithKey(key crypto.PublicKey, payload, signature []byte) error {
+	sum := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, sum[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, signature) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
+
+// verifyPayload checks that the simple signing payload is about the image with the given manifest digest.
+func verifyPayload(payload []byte, digest string) error {
+	var p struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+			Type string `json:"type"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("invalid payload: %v", err)
+	}
+	if p.Critical.Type != cosignPayloadType {
+		return fmt.Errorf("invalid payload type %q", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != sha256SchemePrefix+digest {
+		return fmt.Errorf("signature is for %s", p.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
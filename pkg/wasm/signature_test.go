@@ -0,0 +1,307 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const (
+	testIssuer  = "https://token.actions.githubusercontent.com"
+	testSubject = "https://github.com/acme/filters/.github/workflows/release.yaml@refs/heads/main"
+)
+
+// newTestCertificate returns a self-signed certificate of the signing key, as attached by cosign to signatures.
+func newTestCertificate(t *testing.T, key *ecdsa.PrivateKey) *x509.Certificate {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert
+}
+
+func pemEncode(t *testing.T, typ string, der []byte) string {
+	t.Helper()
+	return string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+}
+
+func publicKeyPEM(t *testing.T, key crypto.Signer) string {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	assert.NoError(t, err)
+	return pemEncode(t, "PUBLIC KEY", der)
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+func newTestPolicy(t *testing.T, signers ...Signer) *SignaturePolicy {
+	raw, err := json.Marshal(signers)
+	assert.NoError(t, err)
+	policy, err := ParseSignaturePolicy(string(raw))
+	assert.NoError(t, err)
+	return policy
+}
+
+// testSignature is a cosign signature of an image digest, with the signing certificate if any.
+type testSignature struct {
+	key  crypto.Signer
+	cert *x509.Certificate
+	// digest signed, if different from the digest of the image the signature is attached to.
+	digest string
+}
+
+// pushWasmImage pushes a Wasm image to the registry with the given signatures, and returns its digest.
+func pushWasmImage(t *testing.T, ref string, content string, sigs ...testSignature) string {
+	l, err := newMockLayer(types.DockerLayer, map[string][]byte{"plugin.wasm": append(wasmHeader, []byte(content)...)})
+	assert.NoError(t, err)
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: l})
+	assert.NoError(t, err)
+	assert.NoError(t, crane.Push(img, ref))
+	d, err := img.Digest()
+	assert.NoError(t, err)
+	if len(sigs) == 0 {
+		return d.Hex
+	}
+
+	sigImg := empty.Image
+	for _, sig := range sigs {
+		digest := sig.digest
+		if digest == "" {
+			digest = d.Hex
+		}
+		payload := fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":"sha256:%s"},"type":%q},"optional":null}`,
+			ref, digest, cosignPayloadType)
+		sum := sha256.Sum256([]byte(payload))
+		signature, err := sig.key.Sign(rand.Reader, sum[:], crypto.SHA256)
+		assert.NoError(t, err)
+		annotations := map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature)}
+		if sig.cert != nil {
+			annotations[cosignCertificateAnnotation] = pemEncode(t, "CERTIFICATE", sig.cert.Raw)
+		}
+		sigImg, err = mutate.Append(sigImg, mutate.Addendum{
+			Layer:       static.NewLayer([]byte(payload), cosignPayloadMediaType),
+			Annotations: annotations,
+		})
+		assert.NoError(t, err)
+	}
+	repo := ref[:strings.LastIndex(ref, ":")]
+	assert.NoError(t, crane.Push(sigImg, fmt.Sprintf("%s:sha256-%s.sig", repo, d.Hex)))
+	return d.Hex
+}
+
+func TestImageFetcher_VerifySignatures(t *testing.T) {
+	fetcher := ImageFetcher{fetchOpts: []remote.Option{remote.WithAuth(authn.Anonymous)}}
+	s := httptest.NewServer(registry.New())
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+
+	releaseKey := newTestKey(t)
+	otherKey := newTestKey(t)
+
+	releaseSigner := Signer{PublicKey: publicKeyPEM(t, releaseKey)}
+	otherSigner := Signer{PublicKey: publicKeyPEM(t, otherKey)}
+
+	image := func(name string, sigs ...testSignature) (string, string) {
+		ref := fmt.Sprintf("%s/test/signed/%s:v1", u.Host, name)
+		return ref, pushWasmImage(t, ref, name, sigs...)
+	}
+	keyedRef, keyedDigest := image("keyed", testSignature{key: releaseKey})
+	certifiedRef, certifiedDigest := image("certified", testSignature{key: otherKey, cert: newTestCertificate(t, otherKey)})
+	bothRef, bothDigest := image("both", testSignature{key: releaseKey}, testSignature{key: otherKey})
+	unsignedRef, unsignedDigest := image("unsigned")
+	replayedRef, replayedDigest := image("replayed", testSignature{key: releaseKey, digest: keyedDigest})
+
+	cases := []struct {
+		name    string
+		ref     string
+		digest  string
+		signers []Signer
+		wantErr string
+	}{
+		{
+			name:    "signed with key",
+			ref:     keyedRef,
+			digest:  keyedDigest,
+			signers: []Signer{releaseSigner},
+		},
+		{
+			name:    "signed with another key",
+			ref:     keyedRef,
+			digest:  keyedDigest,
+			signers: []Signer{otherSigner},
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "certificate of another key",
+			ref:     certifiedRef,
+			digest:  certifiedDigest,
+			signers: []Signer{releaseSigner},
+			wantErr: "signed by another key",
+		},
+		{
+			name:    "all signers",
+			ref:     bothRef,
+			digest:  bothDigest,
+			signers: []Signer{releaseSigner, otherSigner},
+		},
+		{
+			name:    "missing signer",
+			ref:     keyedRef,
+			digest:  keyedDigest,
+			signers: []Signer{releaseSigner, otherSigner},
+			wantErr: "no valid signature by key sha256:",
+		},
+		{
+			name:    "unsigned",
+			ref:     unsignedRef,
+			digest:  unsignedDigest,
+			signers: []Signer{releaseSigner},
+			wantErr: "could not fetch signatures",
+		},
+		{
+			name:    "signature of another image",
+			ref:     replayedRef,
+			digest:  replayedDigest,
+			signers: []Signer{releaseSigner},
+			wantErr: "signature is for sha256:" + keyedDigest,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fetcher.VerifySignatures(tt.ref, tt.digest, newTestPolicy(t, tt.signers...))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSignaturePolicy(t *testing.T) {
+	key := publicKeyPEM(t, newTestKey(t))
+	cases := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{name: "keyed", raw: fmt.Sprintf(`[{"publicKey": %q}]`, key)},
+		// Keyless signers are rejected until the transparency log is verified.
+		{name: "keyless", raw: fmt.Sprintf(`[{"issuer": %q, "subject": %q}]`, testIssuer, testSubject), wantErr: true},
+		{name: "not a list", raw: fmt.Sprintf(`{"publicKey": %q}`, key), wantErr: true},
+		{name: "no signers", raw: `[]`, wantErr: true},
+		{name: "invalid key", raw: `[{"publicKey": "not a key"}]`, wantErr: true},
+		{name: "no key", raw: `[{}]`, wantErr: true},
+		{name: "key and identity", raw: fmt.Sprintf(`[{"publicKey": %q, "issuer": %q, "subject": %q}]`, key, testIssuer, testSubject), wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSignaturePolicy(tt.raw)
+			assert.Equal(t, err != nil, tt.wantErr)
+		})
+	}
+}
+
+func TestWasmCacheSignatureVerification(t *testing.T) {
+	sigRequests := int32(0)
+	reg := registry.New()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, cosignSignatureTagSuffix) {
+			atomic.AddInt32(&sigRequests, 1)
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+
+	key := newTestKey(t)
+	signedRef := fmt.Sprintf("%s/test/signed:v1", u.Host)
+	pushWasmImage(t, signedRef, "signed", testSignature{key: key})
+	unsignedRef := fmt.Sprintf("%s/test/unsigned:v1", u.Host)
+	pushWasmImage(t, unsignedRef, "unsigned")
+
+	cache := NewLocalFileCache(t.TempDir(), defaultOptions())
+	defer close(cache.stopChan)
+	policy := newTestPolicy(t, Signer{PublicKey: publicKeyPEM(t, key)})
+	get := func(ref string, policy *SignaturePolicy) error {
+		_, err := cache.Get("oci://"+ref, GetOptions{
+			ResourceName:    "namespace.resource",
+			RequestTimeout:  10 * time.Second,
+			SignaturePolicy: policy,
+		})
+		return err
+	}
+
+	// The unsigned module is cached, but cannot be used by a plugin requiring signatures.
+	assert.NoError(t, get(unsignedRef, nil))
+	err = get(unsignedRef, policy)
+	assert.Error(t, err)
+	if !strings.HasPrefix(err.Error(), "signature verification failed for oci://"+unsignedRef) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// The verification result is cached along with the module.
+	assert.NoError(t, get(signedRef, policy))
+	requests := atomic.LoadInt32(&sigRequests)
+	assert.NoError(t, get(signedRef, policy))
+	assert.Equal(t, atomic.LoadInt32(&sigRequests), requests)
+
+	// Signatures can only be verified for OCI images.
+	_, err = cache.Get("https://example.com/plugin.wasm", GetOptions{RequestTimeout: time.Second, SignaturePolicy: policy})
+	assert.Error(t, err)
+}
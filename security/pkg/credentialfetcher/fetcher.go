@@ -22,7 +22,9 @@ import (
 	"istio.io/istio/security/pkg/credentialfetcher/plugin"
 )
 
-func NewCredFetcher(credtype, trustdomain, jwtPath, identityProvider string) (security.CredFetcher, error) {
+// NewCredFetcher creates the credential fetcher of the given type. The audience is the audience of the AWS and Azure
+// platform credentials, and defaults to the trust domain for AWS.
+func NewCredFetcher(credtype, trustdomain, jwtPath, identityProvider, audience string) (security.CredFetcher, error) {
 	switch credtype {
 	case security.GCE:
 		return plugin.CreateGCEPlugin(trustdomain, jwtPath, identityProvider), nil
@@ -32,6 +34,19 @@ func NewCredFetcher(credtype, trustdomain, jwtPath, identityProvider string) (se
 			return nil, nil // no cred fetcher - using certificates only
 		}
 		return plugin.CreateTokenPlugin(jwtPath), nil
+	case security.AWS:
+		if audience == "" {
+			// As with GCE, the trust domain is used as the audience of the platform credential.
+			audience = trustdomain
+		}
+		return plugin.CreateAWSPlugin(plugin.AWSMetadataEndpoint, audience, identityProvider), nil
+	case security.Azure:
+		// The audience is the resource of the token, which must be an application ID URI of the Azure tenant,
+		// so the trust domain cannot be used.
+		if audience == "" {
+			return nil, fmt.Errorf("an audience is required for the %s credential fetcher", credtype)
+		}
+		return plugin.CreateAzurePlugin(plugin.AzureMetadataEndpoint, audience, identityProvider), nil
 	case security.Mock: // for test only
 		return plugin.CreateMockPlugin("test_token"), nil
 	default:
@@ -27,6 +27,7 @@ func TestNewCredFetcher(t *testing.T) {
 		trustdomain      string
 		jwtPath          string
 		identityProvider string
+		audience         string
 		expectedErr      string
 		expectedToken    string
 		expectedIdp      string
@@ -49,6 +50,19 @@ func TestNewCredFetcher(t *testing.T) {
 			expectedToken:    "test_token",
 			expectedIdp:      "fakeIDP",
 		},
+		"azure without audience": {
+			fetcherType:      security.Azure,
+			trustdomain:      "cluster.local",
+			identityProvider: "azure-idp",
+			expectedErr:      "an audience is required for the AzureVM credential fetcher",
+		},
+		"azure test": {
+			fetcherType:      security.Azure,
+			trustdomain:      "cluster.local",
+			identityProvider: "azure-idp",
+			audience:         "api://istio",
+			expectedIdp:      "azure-idp",
+		},
 		"invalid test": {
 			fetcherType:      "foo",
 			trustdomain:      "",
@@ -66,7 +80,7 @@ func TestNewCredFetcher(t *testing.T) {
 		t.Run(id, func(t *testing.T) {
 			t.Parallel()
 			cf, err := NewCredFetcher(
-				tc.fetcherType, tc.trustdomain, tc.jwtPath, tc.identityProvider)
+				tc.fetcherType, tc.trustdomain, tc.jwtPath, tc.identityProvider, tc.audience)
 			if cf != nil {
 				defer cf.Stop()
 			}
@@ -0,0 +1,238 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This is AWS plugin of credentialfetcher.
+
+package plugin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/security"
+)
+
+var awscredLog = log.RegisterScope("awscred", "AWS credential fetcher for istio agent")
+
+const (
+	// AWSMetadataEndpoint is the address of the EC2 instance metadata service.
+	AWSMetadataEndpoint = "http://169.254.169.254"
+
+	awsTokenPath       = "/latest/api/token"
+	awsRegionPath      = "/latest/meta-data/placement/region"
+	awsCredentialsPath = "/latest/meta-data/iam/security-credentials/"
+
+	awsTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+	awsTokenHeader    = "X-aws-ec2-metadata-token"
+	// awsSessionTTL is the lifetime requested for IMDSv2 session tokens.
+	awsSessionTTL = 6 * time.Hour
+
+	awsSigningAlgorithm = "AWS4-HMAC-SHA256"
+	awsDateFormat       = "20060102T150405Z"
+)
+
+// imdsTimeout bounds each request to a platform instance metadata service.
+var imdsTimeout = 5 * time.Second
+
+// AWSPlugin signs STS GetCallerIdentity requests with the credentials of the instance role of an EC2 instance,
+// fetched using IMDSv2. A signed request is only valid for 15 minutes, and bound to the audience of the
+// requested certificates.
+type AWSPlugin struct {
+	endpoint         string
+	audience         string
+	identityProvider string
+	client           *http.Client
+
+	// The instance role credentials are cached until they are about to expire.
+	mu          sync.Mutex
+	region      string
+	credentials *awsCredentials
+}
+
+var _ security.CredFetcher = &AWSPlugin{}
+
+// awsCredentials are the temporary credentials of an instance role, as returned by the metadata service.
+type awsCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// CreateAWSPlugin creates an AWS credential fetcher plugin talking to the metadata service at endpoint, signing
+// requests for the given audience.
+func CreateAWSPlugin(endpoint, audience, identityProvider string) *AWSPlugin {
+	return &AWSPlugin{
+		endpoint:         strings.TrimSuffix(endpoint, "/"),
+		audience:         audience,
+		identityProvider: identityProvider,
+		client:           &http.Client{Timeout: imdsTimeout},
+	}
+}
+
+// GetPlatformCredential returns a freshly signed STS GetCallerIdentity request, encoded as a bearer token.
+// Note: this function only works in an EC2 environment with IMDSv2 enabled and an instance role.
+func (p *AWSPlugin) GetPlatformCredential() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.credentials == nil || time.Now().After(p.credentials.Expiration.Add(-gracePeriod)) {
+		if err := p.refreshCredentials(); err != nil {
+			awscredLog.Errorf("Failed to get instance role credentials: %v", err)
+			return "", err
+		}
+	}
+	return security.AWSCallerIdentityRequest{
+		Headers: signAWSCallerIdentityRequest(p.credentials, p.region, p.audience, rand.Text(), time.Now()),
+		Body:    security.AWSCallerIdentityBody,
+	}.Token()
+}
+
+func (p *AWSPlugin) refreshCredentials() error {
+	session, err := p.request(http.MethodPut, awsTokenPath, map[string]string{
+		awsTokenTTLHeader: fmt.Sprint(int(awsSessionTTL.Seconds())),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get IMDSv2 session token: %v", err)
+	}
+	headers := map[string]string{awsTokenHeader: session}
+	region, err := p.request(http.MethodGet, awsRegionPath, headers)
+	if err != nil {
+		return fmt.Errorf("failed to get the instance region: %v", err)
+	}
+	roles, err := p.request(http.MethodGet, awsCredentialsPath, headers)
+	if err != nil {
+		return fmt.Errorf("failed to get the instance role: %v", err)
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(roles), "\n")
+	if role == "" {
+		return fmt.Errorf("the instance has no role")
+	}
+	body, err := p.request(http.MethodGet, awsCredentialsPath+role, headers)
+	if err != nil {
+		return fmt.Errorf("failed to get the credentials of role %s: %v", role, err)
+	}
+	creds := &awsCredentials{}
+	if err := json.Unmarshal([]byte(body), creds); err != nil {
+		return fmt.Errorf("failed to parse the credentials of role %s: %v", role, err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return fmt.Errorf("the credentials of role %s are incomplete", role)
+	}
+	p.region = strings.TrimSpace(region)
+	p.credentials = creds
+	awscredLog.Debugf("Got AWS credentials of role %s in region %s, expiring at %v", role, p.region, creds.Expiration)
+	return nil
+}
+
+func (p *AWSPlugin) request(method, path string, headers map[string]string) (string, error) {
+	return imdsRequest(p.client, method, p.endpoint+path, headers)
+}
+
+// GetIdentityProvider returns the name of the identity provider that can authenticate the workload credential.
+func (p *AWSPlugin) GetIdentityProvider() string {
+	return p.identityProvider
+}
+
+func (p *AWSPlugin) Stop() {}
+
+// signAWSCallerIdentityRequest returns the headers of an STS GetCallerIdentity request to the regional endpoint,
+// signed with AWS Signature Version 4. The audience and nonce headers are signed, so they cannot be changed by a
+// replayer.
+func signAWSCallerIdentityRequest(creds *awsCredentials, region, audience, nonce string, now time.Time) map[string]string {
+	amzDate := now.UTC().Format(awsDateFormat)
+	headers := map[string]string{
+		"Content-Type":             "application/x-www-form-urlencoded; charset=utf-8",
+		"Host":                     security.AWSSTSHost(region),
+		"X-Amz-Date":               amzDate,
+		security.AWSAudienceHeader: audience,
+		security.AWSNonceHeader:    nonce,
+	}
+	if creds.Token != "" {
+		headers["X-Amz-Security-Token"] = creds.Token
+	}
+
+	names := make([]string, 0, len(headers))
+	canonical := map[string]string{}
+	for k, v := range headers {
+		name := strings.ToLower(k)
+		names = append(names, name)
+		canonical[name] = strings.TrimSpace(v)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + canonical[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	bodyHash := sha256.Sum256([]byte(security.AWSCallerIdentityBody))
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost, "/", "", canonicalHeaders.String(), signedHeaders, hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	scope := amzDate[:8] + "/" + region + "/sts/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{awsSigningAlgorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{amzDate[:8], region, "sts", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	headers["Authorization"] = fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigningAlgorithm, creds.AccessKeyID, scope, signedHeaders, signature)
+	return headers
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// imdsRequest makes a request to an instance metadata service and returns the response body.
+func imdsRequest(client *http.Client, method, url string, headers map[string]string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), imdsTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: unexpected status %d: %s", method, url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This is Azure plugin of credentialfetcher.
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/security"
+)
+
+var azurecredLog = log.RegisterScope("azurecred", "Azure credential fetcher for istio agent")
+
+const (
+	// AzureMetadataEndpoint is the address of the Azure instance metadata service.
+	AzureMetadataEndpoint = "http://169.254.169.254"
+
+	azureTokenPath       = "/metadata/identity/oauth2/token"
+	azureTokenAPIVersion = "2018-02-01"
+)
+
+// AzurePlugin fetches a managed identity access token of an Azure VM from the instance metadata service.
+type AzurePlugin struct {
+	endpoint         string
+	resource         string
+	identityProvider string
+	client           *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresOn time.Time
+}
+
+var _ security.CredFetcher = &AzurePlugin{}
+
+// CreateAzurePlugin creates an Azure credential fetcher plugin. The resource is the audience of the
+// requested token and must identify an application the Azure tenant issues tokens for.
+func CreateAzurePlugin(endpoint, resource, identityProvider string) *AzurePlugin {
+	return &AzurePlugin{
+		endpoint:         strings.TrimSuffix(endpoint, "/"),
+		resource:         resource,
+		identityProvider: identityProvider,
+		client:           &http.Client{Timeout: imdsTimeout},
+	}
+}
+
+type azureTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	// ExpiresOn is the token expiry in seconds since the epoch, encoded as a string.
+	ExpiresOn string `json:"expires_on"`
+}
+
+// GetPlatformCredential returns the managed identity access token of the VM, refreshing it when it is
+// about to expire.
+// Note: this function only works in an Azure VM with a managed identity assigned.
+func (p *AzurePlugin) GetPlatformCredential() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.expiresOn.Add(-gracePeriod)) {
+		return p.token, nil
+	}
+
+	q := url.Values{}
+	q.Set("api-version", azureTokenAPIVersion)
+	q.Set("resource", p.resource)
+	body, err := imdsRequest(p.client, http.MethodGet, p.endpoint+azureTokenPath+"?"+q.Encode(), map[string]string{"Metadata": "true"})
+	if err != nil {
+		azurecredLog.Errorf("Failed to get managed identity token from metadata server: %v", err)
+		return "", err
+	}
+	resp := azureTokenResponse{}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return "", fmt.Errorf("failed to parse managed identity token response: %v", err)
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("managed identity token response has no access token")
+	}
+	expiresOn, err := strconv.ParseInt(resp.ExpiresOn, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid managed identity token expiry %q: %v", resp.ExpiresOn, err)
+	}
+	p.token = resp.AccessToken
+	p.expiresOn = time.Unix(expiresOn, 0)
+	azurecredLog.Debugf("Got Azure managed identity token: %d, expires on %v", len(p.token), p.expiresOn)
+	return p.token, nil
+}
+
+// GetIdentityProvider returns the name of the identity provider that can authenticate the workload credential.
+func (p *AzurePlugin) GetIdentityProvider() string {
+	return p.identityProvider
+}
+
+func (p *AzurePlugin) Stop() {}
@@ -0,0 +1,159 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// fakeAWSMetadataServer is a local stand-in for the EC2 instance metadata service, enforcing IMDSv2 sessions.
+func fakeAWSMetadataServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(awsTokenPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get(awsTokenTTLHeader) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("session-token"))
+	})
+	session := func(f func(w http.ResponseWriter)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(awsTokenHeader) != "session-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			f(w)
+		}
+	}
+	mux.HandleFunc(awsRegionPath, session(func(w http.ResponseWriter) {
+		_, _ = w.Write([]byte("us-east-1"))
+	}))
+	mux.HandleFunc(awsCredentialsPath, session(func(w http.ResponseWriter) {
+		_, _ = w.Write([]byte("vm-role\n"))
+	}))
+	mux.HandleFunc(awsCredentialsPath+"vm-role", session(func(w http.ResponseWriter) {
+		n := requests.Inc()
+		_, _ = fmt.Fprintf(w, `{"AccessKeyId":"AKID%d","SecretAccessKey":"secret","Token":"session","Expiration":"%s"}`,
+			n, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestAWSPlugin(t *testing.T) {
+	requests := atomic.NewInt32(0)
+	server := fakeAWSMetadataServer(t, requests)
+	p := CreateAWSPlugin(server.URL, "cluster.local", "aws-idp")
+	defer p.Stop()
+	assert.Equal(t, p.GetIdentityProvider(), "aws-idp")
+
+	token, err := p.GetPlatformCredential()
+	assert.NoError(t, err)
+	req, err := security.ParseAWSCallerIdentityToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, req.Body, security.AWSCallerIdentityBody)
+	assert.Equal(t, req.Headers["Host"], "sts.us-east-1.amazonaws.com")
+	assert.Equal(t, req.Headers[security.AWSAudienceHeader], "cluster.local")
+	assert.Equal(t, req.Headers["X-Amz-Security-Token"], "session")
+	if !strings.HasPrefix(req.Headers["Authorization"], "AWS4-HMAC-SHA256 Credential=AKID1/"+time.Now().UTC().Format("20060102")+
+		"/us-east-1/sts/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-istio-audience;x-istio-nonce, Signature=") {
+		t.Fatalf("unexpected authorization %q", req.Headers["Authorization"])
+	}
+
+	// The role credentials are cached while they are valid, and each request is signed with a new nonce.
+	again, err := p.GetPlatformCredential()
+	assert.NoError(t, err)
+	assert.Equal(t, requests.Load(), int32(1))
+	againReq, err := security.ParseAWSCallerIdentityToken(again)
+	assert.NoError(t, err)
+	assert.Equal(t, againReq.Headers[security.AWSNonceHeader] != req.Headers[security.AWSNonceHeader], true)
+	assert.Equal(t, againReq.Headers["Authorization"] != req.Headers["Authorization"], true)
+
+	// The role credentials are refreshed once they enter the grace period.
+	p.credentials.Expiration = time.Now().Add(gracePeriod / 2)
+	token, err = p.GetPlatformCredential()
+	assert.NoError(t, err)
+	req, err = security.ParseAWSCallerIdentityToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.HasPrefix(req.Headers["Authorization"], "AWS4-HMAC-SHA256 Credential=AKID2/"), true)
+}
+
+func TestSignAWSCallerIdentityRequest(t *testing.T) {
+	creds := &awsCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	headers := signAWSCallerIdentityRequest(creds, "eu-west-1", "cluster.local", "nonce-1", now)
+	assert.Equal(t, headers["Host"], "sts.eu-west-1.amazonaws.com")
+	assert.Equal(t, headers["X-Amz-Date"], "20260101T000000Z")
+	// The audience and the nonce are signed, so changing them changes the signature.
+	other := signAWSCallerIdentityRequest(creds, "eu-west-1", "other.domain", "nonce-1", now)
+	assert.Equal(t, headers["Authorization"] != other["Authorization"], true)
+	other = signAWSCallerIdentityRequest(creds, "eu-west-1", "cluster.local", "nonce-2", now)
+	assert.Equal(t, headers["Authorization"] != other["Authorization"], true)
+	assert.Equal(t, signAWSCallerIdentityRequest(creds, "eu-west-1", "cluster.local", "nonce-1", now), headers)
+}
+
+func TestAWSPluginError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	p := CreateAWSPlugin(server.URL, "cluster.local", "")
+	if _, err := p.GetPlatformCredential(); err == nil {
+		t.Fatal("expected error when metadata service is unavailable")
+	}
+}
+
+func TestAzurePlugin(t *testing.T) {
+	requests := atomic.NewInt32(0)
+	expiresOn := time.Now().Add(time.Hour).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != azureTokenPath || r.Header.Get("Metadata") != "true" ||
+			r.URL.Query().Get("resource") != "api://istio" || r.URL.Query().Get("api-version") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := requests.Inc()
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","expires_on":"%s"}`, n, strconv.FormatInt(expiresOn, 10))
+	}))
+	defer server.Close()
+	p := CreateAzurePlugin(server.URL, "api://istio", "azure-idp")
+	defer p.Stop()
+
+	token, err := p.GetPlatformCredential()
+	assert.NoError(t, err)
+	assert.Equal(t, token, "token-1")
+	assert.Equal(t, p.GetIdentityProvider(), "azure-idp")
+
+	// Cached while the token is valid.
+	token, err = p.GetPlatformCredential()
+	assert.NoError(t, err)
+	assert.Equal(t, token, "token-1")
+
+	// Refreshed once the token enters the grace period.
+	p.expiresOn = time.Now().Add(gracePeriod / 2)
+	token, err = p.GetPlatformCredential()
+	assert.NoError(t, err)
+	assert.Equal(t, token, "token-2")
+}
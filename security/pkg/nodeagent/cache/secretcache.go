@@ -113,6 +113,8 @@ type SecretManagerClient struct {
 	configTrustBundleMutex sync.RWMutex
 	// Dynamically configured Trust Bundle
 	configTrustBundle []byte
+	// Dynamically configured trust anchors of each trust domain, keyed by trust domain
+	trustDomainBundles map[string][]byte
 
 	// queue maintains all certificate rotation events that need to be triggered when they are about to expire
 	queue queue.Delayed
@@ -125,6 +127,8 @@ type secretCache struct {
 	mu       sync.RWMutex
 	workload *security.SecretItem
 	certRoot []byte
+	// identities holds the certificates of the additional workload identities, keyed by identity name.
+	identities map[string]*security.SecretItem
 }
 
 // GetRoot returns cached root cert and cert expiration time. This method is thread safe.
@@ -156,6 +160,34 @@ func (s *secretCache) SetWorkload(value *security.SecretItem) {
 	s.workload = value
 }
 
+func (s *secretCache) GetIdentity(name string) *security.SecretItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.identities[name]
+}
+
+func (s *secretCache) SetIdentity(name string, value *security.SecretItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == nil {
+		delete(s.identities, name)
+		return
+	}
+	if s.identities == nil {
+		s.identities = map[string]*security.SecretItem{}
+	}
+	s.identities[name] = value
+}
+
+// ClearIdentities removes all cached identity certificates, returning the names of the removed identities.
+func (s *secretCache) ClearIdentities() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := maps.Keys(s.identities)
+	s.identities = nil
+	return names
+}
+
 var _ security.SecretManager = &SecretManagerClient{}
 
 // FileCert stores a reference to a certificate on disk
@@ -220,12 +252,27 @@ func (sc *SecretManagerClient) getCachedSecret(resourceName string) (secret *sec
 	var rootCertBundle []byte
 	var ns *security.SecretItem
 
+	if name, ok := strings.CutPrefix(resourceName, security.WorkloadIdentityResourcePrefix); ok {
+		if c := sc.cache.GetIdentity(name); c != nil {
+			cacheLog.WithLabels("identity", name, "ttl", time.Until(c.ExpireTime)).Info("returned workload identity certificate from cache")
+			return &security.SecretItem{
+				ResourceName:     resourceName,
+				CertificateChain: c.CertificateChain,
+				PrivateKey:       c.PrivateKey,
+				ExpireTime:       c.ExpireTime,
+				CreatedTime:      c.CreatedTime,
+			}
+		}
+		return nil
+	}
+
 	if c := sc.cache.GetWorkload(); c != nil {
 		if resourceName == security.RootCertReqResourceName {
 			rootCertBundle = sc.mergeTrustAnchorBytes(c.RootCert)
 			ns = &security.SecretItem{
-				ResourceName: resourceName,
-				RootCert:     rootCertBundle,
+				ResourceName:       resourceName,
+				RootCert:           rootCertBundle,
+				TrustDomainBundles: sc.mergeTrustDomainBundles(c.RootCert),
 			}
 			cacheLog.WithLabels("ttl", time.Until(c.ExpireTime)).Info("returned workload trust anchor from cache")
 
@@ -304,9 +351,14 @@ func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *secu
 	}
 
 	// Store the new secret in the secretCache and trigger the periodic rotation for workload certificate
-	sc.registerSecret(*ns)
+	if name, ok := strings.CutPrefix(resourceName, security.WorkloadIdentityResourcePrefix); ok {
+		sc.registerIdentitySecret(name, *ns)
+	} else {
+		sc.registerSecret(*ns)
+	}
 
 	if resourceName == security.RootCertReqResourceName {
+		ns.TrustDomainBundles = sc.mergeTrustDomainBundles(ns.RootCert)
 		ns.RootCert = sc.mergeTrustAnchorBytes(ns.RootCert)
 	} else {
 		// If periodic cert refresh resulted in discovery of a new root, trigger a ROOTCA request to refresh trust anchor
@@ -721,6 +773,7 @@ func (sc *SecretManagerClient) generateFileSecret(resourceName string) (bool, *s
 		sdsFromFile = true
 		if sitem, err = sc.generateRootCertFromExistingFile(cf.CaCertificatePath, resourceName, true); err == nil {
 			// If retrieving workload trustBundle, then merge other configured trustAnchors in ProxyConfig
+			sitem.TrustDomainBundles = sc.mergeTrustDomainBundles(sitem.RootCert)
 			sitem.RootCert = sc.mergeTrustAnchorBytes(sitem.RootCert)
 			sc.addFileWatcher(cf.CaCertificatePath, resourceName)
 		}
@@ -785,15 +838,30 @@ func (sc *SecretManagerClient) generateNewSecret(resourceName string) (*security
 	t0 := time.Now()
 	logPrefix := cacheLogPrefix(resourceName)
 
-	csrHostName := &spiffe.Identity{
+	csrHostName := (&spiffe.Identity{
 		TrustDomain:    sc.configOptions.TrustDomain,
 		Namespace:      sc.configOptions.WorkloadNamespace,
 		ServiceAccount: sc.configOptions.ServiceAccount,
+	}).String()
+	csrSign := sc.caClient.CSRSign
+	if name, ok := strings.CutPrefix(resourceName, security.WorkloadIdentityResourcePrefix); ok {
+		identity, found := sc.configOptions.WorkloadIdentities[name]
+		if !found {
+			return nil, fmt.Errorf("unknown workload identity %q", name)
+		}
+		identityClient, ok := sc.caClient.(security.IdentityClient)
+		if !ok {
+			return nil, fmt.Errorf("CA client does not support requesting workload identity %q", name)
+		}
+		csrHostName = identity
+		csrSign = func(csrPEM []byte, ttl int64) ([]string, error) {
+			return identityClient.CSRSignForIdentity(csrPEM, ttl, identity)
+		}
 	}
 
-	cacheLog.Debugf("%s constructed host name for CSR: %s", logPrefix, csrHostName.String())
+	cacheLog.Debugf("%s constructed host name for CSR: %s", logPrefix, csrHostName)
 	options := pkiutil.CertOptions{
-		Host:       csrHostName.String(),
+		Host:       csrHostName,
 		RSAKeySize: sc.configOptions.WorkloadRSAKeySize,
 		PKCS8Key:   sc.configOptions.Pkcs8Keys,
 		ECSigAlg:   pkiutil.SupportedECSignatureAlgorithms(sc.configOptions.ECCSigAlg),
@@ -809,7 +877,7 @@ func (sc *SecretManagerClient) generateNewSecret(resourceName string) (*security
 
 	numOutgoingRequests.With(RequestType.Value(monitoring.CSR)).Increment()
 	timeBeforeCSR := time.Now()
-	certChainPEM, err := sc.caClient.CSRSign(csrPEM, int64(sc.configOptions.SecretTTL.Seconds()))
+	certChainPEM, err := csrSign(csrPEM, int64(sc.configOptions.SecretTTL.Seconds()))
 	if err == nil {
 		trustBundlePEM, err = sc.caClient.GetRootCertBundle()
 	}
@@ -896,6 +964,28 @@ func (sc *SecretManagerClient) registerSecret(item security.SecretItem) {
 	}, delay)
 }
 
+// registerIdentitySecret caches the certificate of an additional workload identity and schedules its rotation.
+func (sc *SecretManagerClient) registerIdentitySecret(name string, item security.SecretItem) {
+	delay := rotateTime(item, sc.configOptions.SecretRotationGracePeriodRatio, sc.configOptions.SecretRotationGracePeriodRatioJitter)
+	item.ResourceName = security.WorkloadIdentityResourcePrefix + name
+	if sc.cache.GetIdentity(name) != nil {
+		resourceLog(item.ResourceName).Infof("skip scheduling certificate rotation, already scheduled")
+		return
+	}
+	sc.cache.SetIdentity(name, &item)
+	resourceLog(item.ResourceName).Debugf("scheduled certificate for rotation in %v", delay)
+	certExpirySeconds.ValueFrom(func() float64 { return time.Until(item.ExpireTime).Seconds() }, ResourceName.Value(item.ResourceName))
+	sc.queue.PushDelayed(func() error {
+		// Check if this is a stale scheduled rotating task.
+		if cached := sc.cache.GetIdentity(name); cached != nil && cached.CreatedTime == item.CreatedTime {
+			resourceLog(item.ResourceName).Debugf("rotating certificate")
+			sc.cache.SetIdentity(name, nil)
+			sc.OnSecretUpdate(item.ResourceName)
+		}
+		return nil
+	}, delay)
+}
+
 func (sc *SecretManagerClient) handleFileWatch() {
 	for {
 		select {
@@ -1130,9 +1220,46 @@ func (sc *SecretManagerClient) UpdateConfigTrustBundle(trustBundle []byte) error
 	sc.OnSecretUpdate(security.RootCertReqResourceName)
 	sc.cache.SetWorkload(nil)
 	sc.OnSecretUpdate(security.WorkloadKeyCertResourceName)
+	for _, name := range sc.cache.ClearIdentities() {
+		sc.OnSecretUpdate(security.WorkloadIdentityResourcePrefix + name)
+	}
 	return nil
 }
 
+// UpdateTrustDomainBundles : Update the trust anchors of each trust domain in the secret Manager client.
+// An empty map disables validating peers against the anchors of their trust domain.
+func (sc *SecretManagerClient) UpdateTrustDomainBundles(bundles map[string][]byte) {
+	sc.configTrustBundleMutex.Lock()
+	if maps.EqualFunc(sc.trustDomainBundles, bundles, bytes.Equal) {
+		sc.configTrustBundleMutex.Unlock()
+		return
+	}
+	sc.trustDomainBundles = bundles
+	sc.configTrustBundleMutex.Unlock()
+	cacheLog.Debugf("update trust domain bundles")
+	sc.OnSecretUpdate(security.RootCertReqResourceName)
+}
+
+// mergeTrustDomainBundles: returns the configured trust anchors of each trust domain, with the workload root
+// certs added to the trust domain of the workload. Returns nil if no trust domain bundles are configured.
+func (sc *SecretManagerClient) mergeTrustDomainBundles(rootCerts []byte) map[string][]byte {
+	sc.configTrustBundleMutex.RLock()
+	defer sc.configTrustBundleMutex.RUnlock()
+	if len(sc.trustDomainBundles) == 0 {
+		return nil
+	}
+	bundles := maps.Clone(sc.trustDomainBundles)
+	td := sc.configOptions.TrustDomain
+	anchors := sets.New(pkiutil.PemCertBytestoString(bundles[td])...)
+	anchors.InsertAll(pkiutil.PemCertBytestoString(rootCerts)...)
+	anchorBytes := []byte{}
+	for _, cert := range sets.SortedList(anchors) {
+		anchorBytes = pkiutil.AppendCertByte(anchorBytes, []byte(cert))
+	}
+	bundles[td] = anchorBytes
+	return bundles
+}
+
 // mergeTrustAnchorBytes: Merge cert bytes with the cached TrustAnchors.
 func (sc *SecretManagerClient) mergeTrustAnchorBytes(caCerts []byte) []byte {
 	return sc.mergeConfigTrustBundle(pkiutil.PemCertBytestoString(caCerts))
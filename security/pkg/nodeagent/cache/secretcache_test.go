@@ -818,6 +818,112 @@ func TestProxyConfigAnchorsTriggerWorkloadCertUpdate(t *testing.T) {
 	u.Expect(map[string]int{security.RootCertReqResourceName: 2, security.WorkloadKeyCertResourceName: 1})
 }
 
+func TestTrustDomainBundles(t *testing.T) {
+	fakeCACli, err := mock.NewMockCAClient(time.Hour, false)
+	if err != nil {
+		t.Fatalf("Error creating Mock CA client: %v", err)
+	}
+	u := NewUpdateTracker(t)
+	sc := createCache(t, fakeCACli, u.Callback, security.Options{WorkloadRSAKeySize: 2048, TrustDomain: "cluster.local"})
+	_, err = sc.GenerateSecret(security.WorkloadKeyCertResourceName)
+	if err != nil {
+		t.Fatalf("failed to generate certificate for trust domain bundle test case")
+	}
+	u.Expect(map[string]int{security.RootCertReqResourceName: 1})
+	u.Reset()
+
+	got, err := sc.GenerateSecret(security.RootCertReqResourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TrustDomainBundles != nil {
+		t.Fatalf("expected no trust domain bundles, got %v", got.TrustDomainBundles)
+	}
+
+	rootCert, err := os.ReadFile(filepath.Join("./testdata", "root-cert.pem"))
+	if err != nil {
+		t.Fatalf("Error reading the root cert file: %v", err)
+	}
+	sc.UpdateTrustDomainBundles(map[string][]byte{"remote.domain": rootCert})
+	// Only the trust anchors change, so the workload certificate is kept.
+	u.Expect(map[string]int{security.RootCertReqResourceName: 1})
+	u.Reset()
+
+	got, err = sc.GenerateSecret(security.RootCertReqResourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caClientRootCert := []byte(strings.TrimRight(fakeCACli.GeneratedCerts[0][2], "\n"))
+	expected := map[string][]byte{
+		"cluster.local": pkiutil.AppendCertByte([]byte{}, caClientRootCert),
+		"remote.domain": rootCert,
+	}
+	if !reflect.DeepEqual(got.TrustDomainBundles, expected) {
+		t.Fatalf("unexpected trust domain bundles: %v", got.TrustDomainBundles)
+	}
+
+	// Updating with the same bundles is a no-op.
+	sc.UpdateTrustDomainBundles(map[string][]byte{"remote.domain": rootCert})
+	u.Expect(map[string]int{})
+}
+
+func TestWorkloadIdentityGenerateSecret(t *testing.T) {
+	fakeCACli, err := mock.NewMockCAClient(time.Hour, false)
+	if err != nil {
+		t.Fatalf("Error creating Mock CA client: %v", err)
+	}
+	u := NewUpdateTracker(t)
+	legacy := "spiffe://legacy.example/ns/default/sa/app"
+	sc := createCache(t, fakeCACli, u.Callback, security.Options{
+		WorkloadRSAKeySize: 2048,
+		TrustDomain:        "cluster.local",
+		WorkloadIdentities: map[string]string{"legacy": legacy},
+	})
+	resourceName := security.WorkloadIdentityResourcePrefix + "legacy"
+	got, err := sc.GenerateSecret(resourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ResourceName != resourceName {
+		t.Fatalf("expected resource name %v, got %v", resourceName, got.ResourceName)
+	}
+	cert, err := pkiutil.ParsePemEncodedCertificate(got.CertificateChain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := pkiutil.ExtractIDs(cert.Extensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != legacy {
+		t.Fatalf("expected identity %v, got %v", legacy, ids)
+	}
+
+	// The identity certificate is cached separately from the workload certificate.
+	cached, err := sc.GenerateSecret(resourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cached.CertificateChain, got.CertificateChain) || fakeCACli.SignInvokeCount != 1 {
+		t.Fatalf("expected identity certificate to be served from cache")
+	}
+
+	if _, err := sc.GenerateSecret(security.WorkloadIdentityResourcePrefix + "unknown"); err == nil {
+		t.Fatalf("expected error for unknown identity")
+	}
+
+	// A trust bundle change reissues identity certificates too.
+	u.Reset()
+	if err := sc.UpdateConfigTrustBundle([]byte("fake")); err != nil {
+		t.Fatal(err)
+	}
+	u.Expect(map[string]int{
+		security.RootCertReqResourceName:     1,
+		security.WorkloadKeyCertResourceName: 1,
+		resourceName:                         1,
+	})
+}
+
 func TestOSCACertGenerateSecret(t *testing.T) {
 	fakeCACli, err := mock.NewMockCAClient(time.Hour, false)
 	if err != nil {
@@ -46,6 +46,8 @@ type CitadelClient struct {
 	opts     *security.Options
 }
 
+var _ security.IdentityClient = &CitadelClient{}
+
 type TLSOptions struct {
 	RootCert string
 	Key      string
@@ -78,6 +80,15 @@ func (c *CitadelClient) Close() {
 
 // CSRSign calls Citadel to sign a CSR.
 func (c *CitadelClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) (res []string, err error) {
+	return c.csrSign(csrPEM, certValidTTLInSec, "")
+}
+
+// CSRSignForIdentity requests a certificate for an additional identity of the workload.
+func (c *CitadelClient) CSRSignForIdentity(csrPEM []byte, certValidTTLInSec int64, identity string) ([]string, error) {
+	return c.csrSign(csrPEM, certValidTTLInSec, identity)
+}
+
+func (c *CitadelClient) csrSign(csrPEM []byte, certValidTTLInSec int64, identity string) (res []string, err error) {
 	crMetaStruct := &structpb.Struct{
 		Fields: map[string]*structpb.Value{
 			security.CertSigner: {
@@ -85,6 +96,9 @@ func (c *CitadelClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) (res []s
 			},
 		},
 	}
+	if identity != "" {
+		crMetaStruct.Fields[security.RequestedIdentity] = structpb.NewStringValue(identity)
+	}
 	req := &pb.IstioCertificateRequest{
 		Csr:              string(csrPEM),
 		ValidityDuration: certValidTTLInSec,
@@ -63,13 +63,18 @@ func (c *CAClient) Close() {}
 
 // CSRSign returns the certificate or errors depending on the settings.
 func (c *CAClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) ([]string, error) {
+	return c.CSRSignForIdentity(csrPEM, certValidTTLInSec, "test")
+}
+
+// CSRSignForIdentity returns a certificate for the requested identity.
+func (c *CAClient) CSRSignForIdentity(csrPEM []byte, certValidTTLInSec int64, identity string) ([]string, error) {
 	atomic.AddUint64(&c.SignInvokeCount, 1)
 	signingCert, signingKey, certChain, rootCert := c.bundle.GetAll()
 	csr, err := util.ParsePemEncodedCSR(csrPEM)
 	if err != nil {
 		return nil, fmt.Errorf("csr sign error: %v", err)
 	}
-	subjectIDs := []string{"test"}
+	subjectIDs := []string{identity}
 	certBytes, err := util.GenCertFromCSR(csr, signingCert, csr.PublicKey, *signingKey, subjectIDs, c.certLifetime, false)
 	if err != nil {
 		return nil, fmt.Errorf("csr sign error: %v", err)
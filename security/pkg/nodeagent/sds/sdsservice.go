@@ -42,6 +42,7 @@ import (
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/istio/pkg/backoff"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/model"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/util/sets"
@@ -288,6 +289,25 @@ func (s *sdsservice) Close() {
 	close(s.stop)
 }
 
+// spiffeValidatorConfig builds a SPIFFE certificate validator trusting each trust domain's own anchors.
+func spiffeValidatorConfig(bundles map[string][]byte) *core.TypedExtensionConfig {
+	cfg := &tls.SPIFFECertValidatorConfig{}
+	for td, bundle := range maps.SeqStable(bundles) {
+		cfg.TrustDomains = append(cfg.TrustDomains, &tls.SPIFFECertValidatorConfig_TrustDomain{
+			Name: td,
+			TrustBundle: &core.DataSource{
+				Specifier: &core.DataSource_InlineBytes{
+					InlineBytes: bundle,
+				},
+			},
+		})
+	}
+	return &core.TypedExtensionConfig{
+		Name:        "envoy.tls.cert_validator.spiffe",
+		TypedConfig: protoconv.MessageToAny(cfg),
+	}
+}
+
 // toEnvoySecret converts a security.SecretItem to an Envoy tls.Secret
 func toEnvoySecret(s *security.SecretItem, caRootPath string, pkpConf *mesh.PrivateKeyProvider) *tls.Secret {
 	secret := &tls.Secret{
@@ -310,6 +330,12 @@ func toEnvoySecret(s *security.SecretItem, caRootPath string, pkpConf *mesh.Priv
 				},
 			},
 		}
+		if len(s.TrustDomainBundles) > 0 {
+			// Validate peers against the anchors of the trust domain of their SPIFFE identity only, so that
+			// a federated trust domain cannot issue identities of another one.
+			secretValidationContext.ValidationContext.TrustedCa = nil
+			secretValidationContext.ValidationContext.CustomValidatorConfig = spiffeValidatorConfig(s.TrustDomainBundles)
+		}
 
 		if features.EnableCACRL {
 			// Check if the plugged-in CA CRL file is present and update the secretValidationContext accordingly.
@@ -348,3 +348,30 @@ func setupConnection(socket string) (*grpc.ClientConn, error) {
 
 	return conn, nil
 }
+
+func TestToEnvoySecretTrustDomainBundles(t *testing.T) {
+	secret := toEnvoySecret(&ca2.SecretItem{
+		ResourceName: ca2.RootCertReqResourceName,
+		RootCert:     fakeRootCert,
+		TrustDomainBundles: map[string][]byte{
+			"remote.domain": {0o6},
+			"cluster.local": fakeRootCert,
+		},
+	}, "", nil)
+	vc := secret.GetValidationContext()
+	if vc.GetTrustedCa() != nil {
+		t.Fatalf("expected trusted CA to be replaced by the SPIFFE validator, got %v", vc.GetTrustedCa())
+	}
+	cfg := &tlsv3.SPIFFECertValidatorConfig{}
+	if err := vc.GetCustomValidatorConfig().GetTypedConfig().UnmarshalTo(cfg); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, td := range cfg.GetTrustDomains() {
+		got = append(got, td.GetName()+"="+string(td.GetTrustBundle().GetInlineBytes()))
+	}
+	want := []string{"cluster.local=" + string(fakeRootCert), "remote.domain=" + string([]byte{0o6})}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected trust domains: %v", diff)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records the outcome of every certificate signing request handled by the Istio CA.
+// Records are written as JSON lines so they can be shipped by standard log collectors, and can be
+// queried back with a Filter.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lj "gopkg.in/natefinch/lumberjack.v2"
+
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/slices"
+)
+
+var auditLog = log.RegisterScope("caaudit", "CA audit log")
+
+// backupTimeFormat is the format of the timestamp lumberjack adds to the name of rotated files.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Outcome is the result of a certificate request.
+type Outcome string
+
+const (
+	OutcomeIssued               Outcome = "Issued"
+	OutcomeAuthenticationFailed Outcome = "AuthenticationFailed"
+	OutcomeDenied               Outcome = "Denied"
+	OutcomeSigningFailed        Outcome = "SigningFailed"
+)
+
+// Record describes a single certificate request and its outcome.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Outcome Outcome   `json:"outcome"`
+	// Reason explains a non successful outcome.
+	Reason string `json:"reason,omitempty"`
+	// Serial is the hex encoded serial number of the issued certificate.
+	Serial string `json:"serial,omitempty"`
+	// SANs are the identities requested for (and, if issued, placed in) the certificate.
+	SANs []string `json:"sans,omitempty"`
+	// Requester holds the authenticated identities of the caller.
+	Requester []string `json:"requester,omitempty"`
+	// RequesterPod is the namespace/name of the calling pod, when known.
+	RequesterPod string `json:"requesterPod,omitempty"`
+	// Authenticator is the source the caller was authenticated from.
+	Authenticator string `json:"authenticator,omitempty"`
+	// Node is the node of the caller, set when a node proxy requested a certificate on behalf of a workload.
+	Node string `json:"node,omitempty"`
+	// Client is the network address of the caller.
+	Client string `json:"client,omitempty"`
+	// TTLSeconds is the requested certificate lifetime.
+	TTLSeconds int64 `json:"ttlSeconds,omitempty"`
+	// NotAfter is the expiry of the issued certificate.
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+}
+
+// Sink receives audit records.
+type Sink interface {
+	Write(r *Record)
+}
+
+// FileSink writes records as JSON lines to a size-rotated file.
+type FileSink struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+var _ Sink = &FileSink{}
+
+// NewFileSink returns a Sink appending to path. Once the file exceeds maxSizeMB it is rotated,
+// keeping at most maxBackups old files. ReadFile queries the file along with its rotated backups.
+func NewFileSink(path string, maxSizeMB, maxBackups int) *FileSink {
+	return NewWriterSink(&lj.Logger{
+		Filename:   path,
+		MaxSize:    maxSizeMB,
+		MaxBackups: maxBackups,
+	})
+}
+
+// NewWriterSink returns a Sink writing JSON lines to w.
+func NewWriterSink(w io.WriteCloser) *FileSink {
+	return &FileSink{w: w}
+}
+
+// Write implements Sink. Failures are logged rather than returned, as auditing must not block issuance.
+func (f *FileSink) Write(r *Record) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		auditLog.Errorf("failed to marshal audit record: %v", err)
+		return
+	}
+	b = append(b, '\n')
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.w.Write(b); err != nil {
+		auditLog.Errorf("failed to write audit record: %v", err)
+	}
+}
+
+// Close closes the underlying file.
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.w.Close()
+}
+
+// Filter selects audit records. Zero valued fields match everything.
+type Filter struct {
+	// SAN matches records containing this SAN, or a SAN with this prefix if it ends with "*".
+	SAN string
+	// Serial matches the certificate serial number, ignoring case.
+	Serial string
+	// Requester matches records whose caller has this identity.
+	Requester string
+	Outcome   Outcome
+	Since     time.Time
+	// Limit returns at most this many of the most recent matching records.
+	Limit int
+}
+
+// Matches returns true if r is selected by the filter.
+func (f Filter) Matches(r *Record) bool {
+	if f.SAN != "" && !slices.ContainsFunc(r.SANs, func(s string) bool { return matchPattern(f.SAN, s) }) {
+		return false
+	}
+	if f.Serial != "" && !strings.EqualFold(f.Serial, r.Serial) {
+		return false
+	}
+	if f.Requester != "" && !slices.ContainsFunc(r.Requester, func(s string) bool { return matchPattern(f.Requester, s) }) {
+		return false
+	}
+	if f.Outcome != "" && f.Outcome != r.Outcome {
+		return false
+	}
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+func matchPattern(pattern, s string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(s, prefix)
+	}
+	return pattern == s
+}
+
+// Query encodes the filter as URL query parameters, the inverse of ParseFilter.
+func (f Filter) Query() url.Values {
+	q := url.Values{}
+	set := func(k, v string) {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	set("san", f.SAN)
+	set("serial", f.Serial)
+	set("requester", f.Requester)
+	set("outcome", string(f.Outcome))
+	if !f.Since.IsZero() {
+		q.Set("since", f.Since.UTC().Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return q
+}
+
+// ParseFilter decodes a filter from URL query parameters.
+func ParseFilter(q url.Values) (Filter, error) {
+	f := Filter{
+		SAN:       q.Get("san"),
+		Serial:    q.Get("serial"),
+		Requester: q.Get("requester"),
+		Outcome:   Outcome(q.Get("outcome")),
+	}
+	if s := q.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return f, fmt.Errorf("invalid since %q: %v", s, err)
+		}
+		f.Since = t
+	}
+	if s := q.Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l < 0 {
+			return f, fmt.Errorf("invalid limit %q", s)
+		}
+		f.Limit = l
+	}
+	return f, nil
+}
+
+// Read returns the records in r selected by the filter, oldest first. Lines that are not valid
+// records are skipped, so a partially written trailing line does not fail the query.
+func Read(r io.Reader, f Filter) ([]*Record, error) {
+	var res []*Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		rec := &Record{}
+		if err := json.Unmarshal(line, rec); err != nil {
+			continue
+		}
+		if !f.Matches(rec) {
+			continue
+		}
+		res = append(res, rec)
+		if f.Limit > 0 && len(res) > f.Limit {
+			res = res[1:]
+		}
+	}
+	return res, scanner.Err()
+}
+
+// ReadFile returns the records selected by the filter from the audit log at path and from the backups the FileSink
+// rotated, oldest first. The history is therefore bounded by the number of retained backups.
+func ReadFile(path string, f Filter) ([]*Record, error) {
+	backups, err := backupFiles(path, f.Since)
+	if err != nil {
+		return nil, err
+	}
+	var res []*Record
+	for _, name := range append(backups, path) {
+		file, err := os.Open(name)
+		if err != nil {
+			// A backup may have been removed by a concurrent rotation.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		records, err := Read(file, f)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, records...)
+	}
+	if f.Limit > 0 && len(res) > f.Limit {
+		res = res[len(res)-f.Limit:]
+	}
+	return res, nil
+}
+
+// backupFiles returns the backups of the audit log at path, oldest first, skipping those rotated before since as
+// they only hold older records.
+func backupFiles(path string, since time.Time) ([]string, error) {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	type backup struct {
+		name    string
+		rotated time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		ts, ok := strings.CutPrefix(e.Name(), prefix)
+		if e.IsDir() || !ok {
+			continue
+		}
+		ts, ok = strings.CutSuffix(ts, ext)
+		if !ok {
+			continue
+		}
+		rotated, err := time.Parse(backupTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		if !since.IsZero() && rotated.Before(since) {
+			continue
+		}
+		backups = append(backups, backup{name: filepath.Join(dir, e.Name()), rotated: rotated})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].rotated.Before(backups[j].rotated) })
+	return slices.Map(backups, func(b backup) string { return b.name }), nil
+}
@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestFileSinkRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink := NewFileSink(path, 1, 1)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []*Record{
+		{
+			Time:          base,
+			Outcome:       OutcomeIssued,
+			Serial:        "abc123",
+			SANs:          []string{"spiffe://cluster.local/ns/a/sa/a"},
+			Requester:     []string{"spiffe://cluster.local/ns/a/sa/a"},
+			Authenticator: "IDToken",
+			TTLSeconds:    3600,
+		},
+		{
+			Time:      base.Add(time.Minute),
+			Outcome:   OutcomeDenied,
+			Reason:    "denied by rule",
+			SANs:      []string{"spiffe://cluster.local/ns/b/sa/b"},
+			Requester: []string{"spiffe://cluster.local/ns/istio-system/sa/ztunnel"},
+			Node:      "node-1",
+		},
+		{
+			Time:      base.Add(2 * time.Minute),
+			Outcome:   OutcomeIssued,
+			Serial:    "DEF456",
+			SANs:      []string{"spiffe://cluster.local/ns/a/sa/other"},
+			Requester: []string{"spiffe://cluster.local/ns/a/sa/other"},
+		},
+	}
+	for _, r := range records {
+		sink.Write(r)
+	}
+	assert.NoError(t, sink.Close())
+
+	// Simulate a partially written trailing line, which must be ignored.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	_, _ = f.WriteString(`{"time":"2026-01-01T00:03:00Z","outc`)
+	assert.NoError(t, f.Close())
+
+	cases := []struct {
+		name    string
+		filter  Filter
+		serials []string
+	}{
+		{name: "all", filter: Filter{}, serials: []string{"abc123", "", "DEF456"}},
+		{name: "san prefix", filter: Filter{SAN: "spiffe://cluster.local/ns/a/*"}, serials: []string{"abc123", "DEF456"}},
+		{name: "san exact", filter: Filter{SAN: "spiffe://cluster.local/ns/a/sa/a"}, serials: []string{"abc123"}},
+		{name: "serial ignores case", filter: Filter{Serial: "def456"}, serials: []string{"DEF456"}},
+		{name: "requester", filter: Filter{Requester: "spiffe://cluster.local/ns/istio-system/sa/ztunnel"}, serials: []string{""}},
+		{name: "outcome", filter: Filter{Outcome: OutcomeIssued}, serials: []string{"abc123", "DEF456"}},
+		{name: "since", filter: Filter{Since: base.Add(30 * time.Second)}, serials: []string{"", "DEF456"}},
+		{name: "limit keeps most recent", filter: Filter{Limit: 2}, serials: []string{"", "DEF456"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(path)
+			assert.NoError(t, err)
+			defer f.Close()
+			got, err := Read(f, tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, slices.Map(got, func(r *Record) string { return r.Serial }), tt.serials)
+		})
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	f := Filter{
+		SAN:       "spiffe://cluster.local/ns/a/*",
+		Serial:    "abc",
+		Requester: "spiffe://cluster.local/ns/istio-system/sa/ztunnel",
+		Outcome:   OutcomeDenied,
+		Since:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Limit:     10,
+	}
+	got, err := ParseFilter(f.Query())
+	assert.NoError(t, err)
+	assert.Equal(t, got, f)
+
+	_, err = ParseFilter(map[string][]string{"since": {"yesterday"}})
+	if err == nil || !strings.Contains(err.Error(), "invalid since") {
+		t.Fatalf("expected invalid since error, got %v", err)
+	}
+	_, err = ParseFilter(map[string][]string{"limit": {"-1"}})
+	if err == nil {
+		t.Fatalf("expected invalid limit error")
+	}
+}
+
+func TestReadFileIncludesBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(name, serial string, at time.Duration) {
+		sink := NewWriterSink(mustCreate(t, filepath.Join(dir, name)))
+		sink.Write(&Record{Time: base.Add(at), Outcome: OutcomeIssued, Serial: serial})
+		assert.NoError(t, sink.Close())
+	}
+	// Backups are named by lumberjack after their rotation time.
+	write("audit-2026-01-01T02-00-00.000.log", "b", time.Hour+30*time.Minute)
+	write("audit-2026-01-01T01-00-00.000.log", "a", 30*time.Minute)
+	write("audit.log", "c", 3*time.Hour)
+	write("other-2026-01-01T00-00-00.000.log", "x", 0)
+
+	cases := []struct {
+		name    string
+		filter  Filter
+		serials []string
+	}{
+		{name: "all", filter: Filter{}, serials: []string{"a", "b", "c"}},
+		{name: "limit", filter: Filter{Limit: 2}, serials: []string{"b", "c"}},
+		{name: "since", filter: Filter{Since: base.Add(time.Hour)}, serials: []string{"b", "c"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFile(path, tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, slices.Map(got, func(r *Record) string { return r.Serial }), tt.serials)
+		})
+	}
+}
+
+func mustCreate(t *testing.T, path string) *os.File {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	return f
+}
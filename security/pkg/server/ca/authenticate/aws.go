@@ -0,0 +1,231 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	AWSAuthenticatorType = "AWSCallerIdentityAuthenticator"
+
+	// awsMaxRequestAge bounds the age of the signed requests accepted, including clock skew. STS itself rejects
+	// requests signed more than 15 minutes ago.
+	awsMaxRequestAge = 5 * time.Minute
+	awsDateFormat    = "20060102T150405Z"
+	awsSTSTimeout    = 10 * time.Second
+	// awsMinNonceLength is the minimum length of the nonces, the credential fetcher sends 26 random characters.
+	awsMinNonceLength = 16
+)
+
+var (
+	awsRegionPattern   = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+	awsCredentialScope = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=[^/,]+/[0-9]{8}/([^/,]+)/sts/aws4_request, ` +
+		`SignedHeaders=([a-z0-9;-]+), Signature=[0-9a-f]+$`)
+	// awsAssumedRoleARN matches the ARN of the STS session of an instance role, whose session name is the instance ID.
+	awsAssumedRoleARN = regexp.MustCompile(`^arn:aws[a-z-]*:sts::([0-9]+):assumed-role/([^/]+)/([^/]+)$`)
+)
+
+// AWSAuthenticator authenticates EC2 instances by an STS GetCallerIdentity request signed with the credentials of
+// their instance role, and maps them to a mesh identity with a PlatformIdentityMapping. The request must be recent
+// and bound to the audience of the authenticator, and each request is only accepted once: the requests carry a
+// signed nonce, so that requests signed in the same second have distinct signatures.
+type AWSAuthenticator struct {
+	meshHolder mesh.Holder
+	audience   string
+	mapping    PlatformIdentityMapping
+	client     *http.Client
+	// stsURL returns the URL of the STS endpoint of a host, overridden in tests.
+	stsURL func(host string) string
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+var _ security.Authenticator = &AWSAuthenticator{}
+
+// NewAWSAuthenticator creates an AWSAuthenticator accepting requests signed for the given audience.
+func NewAWSAuthenticator(audience string, mapping PlatformIdentityMapping, meshHolder mesh.Holder) *AWSAuthenticator {
+	return &AWSAuthenticator{
+		meshHolder: meshHolder,
+		audience:   audience,
+		mapping:    mapping,
+		client:     &http.Client{Timeout: awsSTSTimeout},
+		stsURL:     func(host string) string { return "https://" + host + "/" },
+		seen:       map[string]time.Time{},
+	}
+}
+
+// awsCallerIdentityResponse is the response of STS GetCallerIdentity.
+type awsCallerIdentityResponse struct {
+	Result struct {
+		Arn     string `xml:"Arn"`
+		Account string `xml:"Account"`
+	} `xml:"GetCallerIdentityResult"`
+}
+
+func (a *AWSAuthenticator) AuthenticatorType() string {
+	return AWSAuthenticatorType
+}
+
+func (a *AWSAuthenticator) Authenticate(authRequest security.AuthContext) (*security.Caller, error) {
+	var token string
+	var err error
+	ctx := context.Background()
+	if authRequest.GrpcContext != nil {
+		token, err = security.ExtractBearerToken(authRequest.GrpcContext)
+		ctx = authRequest.GrpcContext
+	} else if authRequest.Request != nil {
+		token, err = security.ExtractRequestToken(authRequest.Request)
+		ctx = authRequest.Request.Context()
+	} else {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("caller identity extraction error: %v", err)
+	}
+	return a.authenticate(ctx, token)
+}
+
+func (a *AWSAuthenticator) authenticate(ctx context.Context, token string) (*security.Caller, error) {
+	req, err := security.ParseAWSCallerIdentityToken(token)
+	if err != nil {
+		return nil, err
+	}
+	host, region, err := a.validate(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	arn, err := a.callerIdentity(ctx, host, req)
+	if err != nil {
+		return nil, err
+	}
+	m := awsAssumedRoleARN.FindStringSubmatch(arn)
+	if m == nil {
+		return nil, fmt.Errorf("caller %s is not an instance role session", arn)
+	}
+	id := PlatformIdentity{
+		Platform:  "aws",
+		Account:   m[1],
+		Region:    region,
+		Instance:  m[3],
+		Principal: m[2],
+	}
+	ns, sa, found := a.mapping.Lookup(id)
+	if !found {
+		return nil, fmt.Errorf("no mesh identity is mapped to instance %s of role %s in account %s", id.Instance, id.Principal, id.Account)
+	}
+	return &security.Caller{
+		AuthSource: security.AuthSourceIDToken,
+		Identities: []string{spiffe.MustGenSpiffeURI(a.meshHolder.Mesh(), ns, sa)},
+	}, nil
+}
+
+// validate checks the signed request is a recent GetCallerIdentity request to an STS endpoint, signed for the
+// audience of the authenticator and not seen before, and returns the host and region of its STS endpoint.
+func (a *AWSAuthenticator) validate(req security.AWSCallerIdentityRequest, now time.Time) (string, string, error) {
+	if req.Body != security.AWSCallerIdentityBody {
+		return "", "", fmt.Errorf("not a GetCallerIdentity request")
+	}
+	headers := map[string]string{}
+	for k, v := range req.Headers {
+		headers[strings.ToLower(k)] = v
+	}
+	m := awsCredentialScope.FindStringSubmatch(headers["authorization"])
+	if m == nil {
+		return "", "", fmt.Errorf("invalid AWS signature")
+	}
+	region, signed := m[1], strings.Split(m[2], ";")
+	for _, h := range []string{"host", "x-amz-date", strings.ToLower(security.AWSAudienceHeader), strings.ToLower(security.AWSNonceHeader)} {
+		if !slices.Contains(signed, h) {
+			return "", "", fmt.Errorf("header %s is not signed", h)
+		}
+	}
+	if !awsRegionPattern.MatchString(region) {
+		return "", "", fmt.Errorf("invalid AWS region %q", region)
+	}
+	host := security.AWSSTSHost(region)
+	if headers["host"] != host {
+		return "", "", fmt.Errorf("request is not for the STS endpoint %s", host)
+	}
+	if aud := headers[strings.ToLower(security.AWSAudienceHeader)]; aud != a.audience {
+		return "", "", fmt.Errorf("request audience %q does not match %q", aud, a.audience)
+	}
+	if len(headers[strings.ToLower(security.AWSNonceHeader)]) < awsMinNonceLength {
+		return "", "", fmt.Errorf("request nonce is too short")
+	}
+	signedAt, err := time.Parse(awsDateFormat, headers["x-amz-date"])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid request date: %v", err)
+	}
+	if signedAt.Before(now.Add(-awsMaxRequestAge)) || signedAt.After(now.Add(awsMaxRequestAge)) {
+		return "", "", fmt.Errorf("request signed at %v is stale", signedAt)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for signature, expiry := range a.seen {
+		if now.After(expiry) {
+			delete(a.seen, signature)
+		}
+	}
+	if _, f := a.seen[headers["authorization"]]; f {
+		return "", "", fmt.Errorf("request was already used")
+	}
+	a.seen[headers["authorization"]] = signedAt.Add(awsMaxRequestAge)
+	return host, region, nil
+}
+
+// callerIdentity sends the signed request to STS, and returns the ARN of the signer.
+func (a *AWSAuthenticator) callerIdentity(ctx context.Context, host string, req security.AWSCallerIdentityRequest) (string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.stsURL(host), strings.NewReader(req.Body))
+	if err != nil {
+		return "", err
+	}
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpReq.Host = host
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to call STS: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the STS response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("STS rejected the request with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	identity := awsCallerIdentityResponse{}
+	if err := xml.Unmarshal(body, &identity); err != nil {
+		return "", fmt.Errorf("invalid STS response: %v", err)
+	}
+	return identity.Result.Arn, nil
+}
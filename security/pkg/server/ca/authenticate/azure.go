@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	oidc "github.com/coreos/go-oidc/v3/oidc"
+
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	AzureAuthenticatorType = "AzureManagedIdentityAuthenticator"
+)
+
+// AzureAuthenticator authenticates Azure VMs by their managed identity access token, and maps them to
+// a mesh identity with a PlatformIdentityMapping.
+type AzureAuthenticator struct {
+	meshHolder mesh.Holder
+	verifier   *oidc.IDTokenVerifier
+	mapping    PlatformIdentityMapping
+}
+
+var _ security.Authenticator = &AzureAuthenticator{}
+
+// NewAzureAuthenticator creates an AzureAuthenticator accepting managed identity tokens issued by the given
+// tenant for the given audience. The signing keys are discovered from the tenant's OIDC configuration.
+func NewAzureAuthenticator(tenantID, audience string, mapping PlatformIdentityMapping, meshHolder mesh.Holder) (*AzureAuthenticator, error) {
+	issuer := fmt.Sprintf("https://sts.windows.net/%s/", tenantID)
+	provider, err := oidc.NewProvider(context.Background(), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed at creating an OIDC provider for %v: %v", issuer, err)
+	}
+	return newAzureAuthenticator(provider.Verifier(&oidc.Config{ClientID: audience}), mapping, meshHolder), nil
+}
+
+func newAzureAuthenticator(verifier *oidc.IDTokenVerifier, mapping PlatformIdentityMapping, meshHolder mesh.Holder) *AzureAuthenticator {
+	return &AzureAuthenticator{
+		meshHolder: meshHolder,
+		verifier:   verifier,
+		mapping:    mapping,
+	}
+}
+
+// azureClaims holds the managed identity token claims used for authentication.
+type azureClaims struct {
+	// ResourceID is the Azure resource ID of the managed identity, or of the VM for system assigned identities.
+	ResourceID string `json:"xms_mirid"`
+	ObjectID   string `json:"oid"`
+}
+
+func (a *AzureAuthenticator) AuthenticatorType() string {
+	return AzureAuthenticatorType
+}
+
+func (a *AzureAuthenticator) Authenticate(authRequest security.AuthContext) (*security.Caller, error) {
+	if authRequest.GrpcContext != nil {
+		bearerToken, err := security.ExtractBearerToken(authRequest.GrpcContext)
+		if err != nil {
+			return nil, fmt.Errorf("ID token extraction error: %v", err)
+		}
+		return a.authenticate(authRequest.GrpcContext, bearerToken)
+	}
+	if authRequest.Request != nil {
+		bearerToken, err := security.ExtractRequestToken(authRequest.Request)
+		if err != nil {
+			return nil, fmt.Errorf("target JWT extraction error: %v", err)
+		}
+		return a.authenticate(authRequest.Request.Context(), bearerToken)
+	}
+	return nil, nil
+}
+
+func (a *AzureAuthenticator) authenticate(ctx context.Context, bearerToken string) (*security.Caller, error) {
+	idToken, err := a.verifier.Verify(ctx, bearerToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the managed identity token (error %v)", err)
+	}
+	claims := azureClaims{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to extract claims from managed identity token: %v", err)
+	}
+	if claims.ResourceID == "" {
+		return nil, fmt.Errorf("managed identity token has no resource ID")
+	}
+	id := PlatformIdentity{
+		Platform:  "azure",
+		Account:   azureSubscription(claims.ResourceID),
+		Instance:  claims.ResourceID,
+		Principal: claims.ObjectID,
+	}
+	ns, sa, found := a.mapping.Lookup(id)
+	if !found {
+		return nil, fmt.Errorf("no mesh identity is mapped to managed identity %s", claims.ResourceID)
+	}
+	return &security.Caller{
+		AuthSource: security.AuthSourceIDToken,
+		Identities: []string{spiffe.MustGenSpiffeURI(a.meshHolder.Mesh(), ns, sa)},
+	}, nil
+}
+
+// azureSubscription extracts the subscription ID from an Azure resource ID of the form
+// /subscriptions/<id>/resourceGroups/...
+func azureSubscription(resourceID string) string {
+	parts := strings.Split(strings.TrimPrefix(resourceID, "/"), "/")
+	if len(parts) >= 2 && strings.EqualFold(parts[0], "subscriptions") {
+		return parts[1]
+	}
+	return ""
+}
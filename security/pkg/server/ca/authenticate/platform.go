@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PlatformIdentity holds the attributes of a verified cloud platform identity, such as a VM instance.
+type PlatformIdentity struct {
+	// Platform is the name of the cloud platform, e.g. "aws" or "azure".
+	Platform string
+	// Account is the AWS account ID or Azure subscription ID.
+	Account string
+	// Region is the AWS region or Azure location, if known.
+	Region string
+	// Instance is the AWS instance ID or Azure resource ID of the identity. The AWS instance ID is the session
+	// name of the instance role, so the trust policy of the role must only allow EC2 to assume it.
+	Instance string
+	// Principal is the AWS instance role name or Azure managed identity object ID.
+	Principal string
+}
+
+// PlatformIdentityRule maps platform identities to a mesh identity. All non-empty match fields
+// must match, either exactly or, if the field ends with "*", by prefix.
+type PlatformIdentityRule struct {
+	Platform  string `json:"platform,omitempty"`
+	Account   string `json:"account,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Principal string `json:"principal,omitempty"`
+
+	// Namespace and ServiceAccount form the SPIFFE identity granted to matching platform identities.
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"serviceAccount"`
+}
+
+// PlatformIdentityMapping is an ordered list of rules; the first matching rule wins.
+type PlatformIdentityMapping []PlatformIdentityRule
+
+// LoadPlatformIdentityMapping reads a YAML or JSON list of PlatformIdentityRule from a file.
+func LoadPlatformIdentityMapping(file string) (PlatformIdentityMapping, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlatformIdentityMapping(b)
+}
+
+// ParsePlatformIdentityMapping parses a YAML or JSON list of PlatformIdentityRule.
+func ParsePlatformIdentityMapping(b []byte) (PlatformIdentityMapping, error) {
+	m := PlatformIdentityMapping{}
+	if err := yaml.UnmarshalStrict(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse platform identity mapping: %v", err)
+	}
+	for i, r := range m {
+		if r.Namespace == "" || r.ServiceAccount == "" {
+			return nil, fmt.Errorf("rule %d: namespace and serviceAccount are required", i)
+		}
+	}
+	return m, nil
+}
+
+// Lookup returns the namespace and service account mapped to the platform identity.
+func (m PlatformIdentityMapping) Lookup(id PlatformIdentity) (namespace, serviceAccount string, found bool) {
+	for _, r := range m {
+		if matchField(r.Platform, id.Platform) &&
+			matchField(r.Account, id.Account) &&
+			matchField(r.Region, id.Region) &&
+			matchField(r.Instance, id.Instance) &&
+			matchField(r.Principal, id.Principal) {
+			return r.Namespace, r.ServiceAccount, true
+		}
+	}
+	return "", "", false
+}
+
+func matchField(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
@@ -0,0 +1,299 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	oidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"google.golang.org/grpc/metadata"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/mesh/meshwatcher"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+)
+
+const testPlatformMapping = `
+- platform: aws
+  account: "123456789012"
+  instance: i-0123*
+  principal: vm-role
+  namespace: vm
+  serviceAccount: aws-vm
+- platform: aws
+  account: "123456789012"
+  region: eu-west-1
+  principal: eu-role
+  namespace: vm
+  serviceAccount: aws-eu-vm
+- platform: azure
+  instance: /subscriptions/sub-1/resourceGroups/rg/*
+  namespace: vm
+  serviceAccount: azure-vm
+`
+
+func TestParsePlatformIdentityMapping(t *testing.T) {
+	m, err := ParsePlatformIdentityMapping([]byte(testPlatformMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		id    PlatformIdentity
+		found bool
+		sa    string
+	}{
+		{id: PlatformIdentity{Platform: "aws", Account: "123456789012", Instance: "i-0123abc", Principal: "vm-role"}, found: true, sa: "aws-vm"},
+		{id: PlatformIdentity{Platform: "aws", Account: "123456789012", Instance: "i-0123abc", Principal: "other-role"}},
+		{id: PlatformIdentity{Platform: "aws", Account: "000000000000", Instance: "i-0123abc"}},
+		{id: PlatformIdentity{Platform: "azure", Account: "123456789012", Instance: "i-0123abc"}},
+		{id: PlatformIdentity{Platform: "azure", Instance: "/subscriptions/sub-1/resourceGroups/rg/providers/vm"}, found: true, sa: "azure-vm"},
+	}
+	for _, tt := range cases {
+		ns, sa, found := m.Lookup(tt.id)
+		if found != tt.found || sa != tt.sa || (found && ns != "vm") {
+			t.Errorf("Lookup(%+v) = %v/%v %v, want %v %v", tt.id, ns, sa, found, tt.sa, tt.found)
+		}
+	}
+
+	if _, err := ParsePlatformIdentityMapping([]byte("- account: foo")); err == nil {
+		t.Errorf("expected error for rule without identity")
+	}
+	if _, err := ParsePlatformIdentityMapping([]byte("- acount: foo\n  namespace: a\n  serviceAccount: b")); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
+
+func bearerContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.MD{"authorization": []string{bearerTokenPrefix + token}})
+}
+
+func TestAWSAuthenticate(t *testing.T) {
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != security.AWSCallerIdentityBody || !strings.HasPrefix(r.Host, "sts.") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// The fake STS identifies the signer by its access key.
+		arn, found := map[string]string{
+			"ROLE":     "arn:aws:sts::123456789012:assumed-role/vm-role/i-0123456789abcdef0",
+			"EU_ROLE":  "arn:aws:sts::123456789012:assumed-role/eu-role/i-0123456789abcdef0",
+			"UNMAPPED": "arn:aws:sts::000000000000:assumed-role/vm-role/i-0123456789abcdef0",
+			"USER":     "arn:aws:iam::123456789012:user/admin",
+		}[strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="), "/")[0]]
+		if !found {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("<ErrorResponse><Error><Code>SignatureDoesNotMatch</Code></Error></ErrorResponse>"))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult><Arn>%s</Arn><UserId>AROA:i-0123456789abcdef0</UserId><Account>123456789012</Account></GetCallerIdentityResult>
+</GetCallerIdentityResponse>`, arn)
+	}))
+	defer sts.Close()
+
+	mapping, err := ParsePlatformIdentityMapping([]byte(testPlatformMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := NewAWSAuthenticator("cluster.local", mapping, meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{TrustDomain: "cluster.local"}))
+	authenticator.stsURL = func(string) string { return sts.URL }
+
+	signature := 0
+	token := func(accessKey string, modify func(h map[string]string), body string) string {
+		signature++
+		headers := map[string]string{
+			"Host":                     "sts.us-east-1.amazonaws.com",
+			"X-Amz-Date":               time.Now().UTC().Format(awsDateFormat),
+			security.AWSAudienceHeader: "cluster.local",
+			security.AWSNonceHeader:    "5GZQ3YJ2KB7TMX4WNRD6HCVPAE",
+			"Authorization": fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/20260101/us-east-1/sts/aws4_request, "+
+				"SignedHeaders=content-type;host;x-amz-date;x-istio-audience;x-istio-nonce, Signature=%064x", accessKey, signature),
+		}
+		if modify != nil {
+			modify(headers)
+		}
+		tok, err := security.AWSCallerIdentityRequest{Headers: headers, Body: body}.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	valid := token("ROLE", nil, security.AWSCallerIdentityBody)
+	inRegion := func(region string) func(h map[string]string) {
+		return func(h map[string]string) {
+			h["Host"] = "sts." + region + ".amazonaws.com"
+			h["Authorization"] = strings.Replace(h["Authorization"], "/us-east-1/", "/"+region+"/", 1)
+		}
+	}
+	cases := []struct {
+		name       string
+		token      string
+		expectedID string
+		err        string
+	}{
+		{
+			name:       "valid",
+			token:      valid,
+			expectedID: spiffe.MustGenSpiffeURIForTrustDomain("cluster.local", "vm", "aws-vm"),
+		},
+		{name: "replayed", token: valid, err: "already used"},
+		{
+			// Signed in the same second as the valid request, with another nonce.
+			name:       "same second",
+			token:      token("ROLE", nil, security.AWSCallerIdentityBody),
+			expectedID: spiffe.MustGenSpiffeURIForTrustDomain("cluster.local", "vm", "aws-vm"),
+		},
+		{
+			name:       "region-scoped rule",
+			token:      token("EU_ROLE", inRegion("eu-west-1"), security.AWSCallerIdentityBody),
+			expectedID: spiffe.MustGenSpiffeURIForTrustDomain("cluster.local", "vm", "aws-eu-vm"),
+		},
+		{
+			name:  "region-scoped rule in another region",
+			token: token("EU_ROLE", inRegion("us-west-2"), security.AWSCallerIdentityBody),
+			err:   "no mesh identity is mapped",
+		},
+		{
+			name: "nonce not signed",
+			token: token("ROLE", func(h map[string]string) {
+				h["Authorization"] = strings.Replace(h["Authorization"], ";x-istio-nonce", "", 1)
+			}, security.AWSCallerIdentityBody),
+			err: "header x-istio-nonce is not signed",
+		},
+		{
+			name:  "short nonce",
+			token: token("ROLE", func(h map[string]string) { h[security.AWSNonceHeader] = "1" }, security.AWSCallerIdentityBody),
+			err:   "nonce is too short",
+		},
+		{name: "other action", token: token("ROLE", nil, "Action=GetSessionToken&Version=2011-06-15"), err: "not a GetCallerIdentity request"},
+		{
+			name:  "wrong audience",
+			token: token("ROLE", func(h map[string]string) { h[security.AWSAudienceHeader] = "other.domain" }, security.AWSCallerIdentityBody),
+			err:   "does not match",
+		},
+		{
+			name: "audience not signed",
+			token: token("ROLE", func(h map[string]string) {
+				h["Authorization"] = strings.Replace(h["Authorization"], ";x-istio-audience", "", 1)
+			}, security.AWSCallerIdentityBody),
+			err: "header x-istio-audience is not signed",
+		},
+		{
+			name: "stale",
+			token: token("ROLE", func(h map[string]string) {
+				h["X-Amz-Date"] = time.Now().Add(-10 * time.Minute).UTC().Format(awsDateFormat)
+			}, security.AWSCallerIdentityBody),
+			err: "stale",
+		},
+		{
+			name:  "not for sts",
+			token: token("ROLE", func(h map[string]string) { h["Host"] = "attacker.example.com" }, security.AWSCallerIdentityBody),
+			err:   "not for the STS endpoint",
+		},
+		{name: "rejected by sts", token: token("FORGED", nil, security.AWSCallerIdentityBody), err: "STS rejected the request with status 403"},
+		{name: "not a role", token: token("USER", nil, security.AWSCallerIdentityBody), err: "not an instance role session"},
+		{name: "unmapped instance", token: token("UNMAPPED", nil, security.AWSCallerIdentityBody), err: "no mesh identity is mapped"},
+		{name: "not an aws token", token: "eyJhbGciOiJSUzI1NiJ9.e30.c2ln", err: "not an AWS caller identity token"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, err := authenticator.Authenticate(security.AuthContext{GrpcContext: bearerContext(tt.token)})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error %q, got %v (caller %v)", tt.err, err, caller)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(caller.Identities) != 1 || caller.Identities[0] != tt.expectedID {
+				t.Fatalf("expected identity %v, got %v", tt.expectedID, caller.Identities)
+			}
+		})
+	}
+}
+
+func TestAzureAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate a private key: %v", err)
+	}
+	key := jose.JSONWebKey{Algorithm: string(jose.RS256), Key: rsaKey}
+	keySet := jose.JSONWebKeySet{}
+	keySet.Keys = append(keySet.Keys, key.Public())
+	server := httptest.NewServer(&jwksServer{key: keySet, t: t})
+	defer server.Close()
+
+	mapping, err := ParsePlatformIdentityMapping([]byte(testPlatformMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := oidc.NewVerifier(server.URL, oidc.NewRemoteKeySet(context.Background(), server.URL), &oidc.Config{ClientID: "api://istio"})
+	authenticator := newAzureAuthenticator(verifier, mapping, meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{TrustDomain: "cluster.local"}))
+
+	exp := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	token := func(aud, resourceID string) string {
+		claims := `{"iss": "` + server.URL + `", "aud": "` + aud + `", "xms_mirid": "` + resourceID + `", "oid": "oid-1", "exp": ` + exp + `}`
+		tok, err := generateJWT(&key, []byte(claims))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	vm := "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1"
+	cases := map[string]struct {
+		token      string
+		expectedID string
+	}{
+		"valid":             {token: token("api://istio", vm), expectedID: spiffe.MustGenSpiffeURIForTrustDomain("cluster.local", "vm", "azure-vm")},
+		"wrong audience":    {token: token("api://other", vm)},
+		"unmapped identity": {token: token("api://istio", "/subscriptions/sub-2/resourceGroups/rg/providers/vm")},
+		"no resource id":    {token: token("api://istio", "")},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			caller, err := authenticator.Authenticate(security.AuthContext{GrpcContext: bearerContext(tt.token)})
+			if tt.expectedID == "" {
+				if err == nil {
+					t.Fatalf("expected authentication to fail, got %v", caller)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(caller.Identities) != 1 || caller.Identities[0] != tt.expectedID {
+				t.Fatalf("expected identity %v, got %v", tt.expectedID, caller.Identities)
+			}
+		})
+	}
+	if got := azureSubscription(vm); got != "sub-1" {
+		t.Errorf("expected subscription sub-1, got %q", got)
+	}
+}
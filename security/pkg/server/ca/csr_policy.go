@@ -0,0 +1,350 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"crypto/x509"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"go.uber.org/atomic"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	// CSRPolicyConfigMapKey is the key in the policy ConfigMap holding the CSR policy.
+	CSRPolicyConfigMapKey = "policy.yaml"
+
+	// Reasons a CSR may be denied by policy. These are used as metric labels, so must remain bounded.
+	policyDenyIdentity  = "identity"
+	policyDenyTTL       = "ttl"
+	policyDenyKeyType   = "key_type"
+	policyDenyCondition = "condition"
+	policyDenyDefault   = "default"
+)
+
+// Supported key types for CSRPolicyRule.KeyTypes.
+const (
+	KeyTypeRSA     = "RSA"
+	KeyTypeECDSA   = "ECDSA"
+	KeyTypeED25519 = "ED25519"
+)
+
+// CSRAuthorizer decides whether an authenticated caller may be issued the requested certificate.
+type CSRAuthorizer interface {
+	// Authorize returns nil if the request is allowed, or a *CSRPolicyDenial describing why it was denied.
+	Authorize(req *CSRPolicyRequest) error
+}
+
+// CSRPolicyRequest holds the attributes of a certificate request that a CSR policy is evaluated against.
+type CSRPolicyRequest struct {
+	// Namespace and ServiceAccount of the workload the certificate is requested for.
+	Namespace      string
+	ServiceAccount string
+	// Identities are the SANs that will be placed in the certificate.
+	Identities []string
+	// TTL is the lifetime of the certificate to be issued: the requested one, or the CA default if none was requested.
+	TTL time.Duration
+	// KeyType is the public key algorithm of the CSR, one of KeyTypeRSA, KeyTypeECDSA or KeyTypeED25519.
+	KeyType string
+	// Authenticator is the source the caller was authenticated from, e.g. "ClientCertificate" or "IDToken".
+	Authenticator string
+}
+
+// CSRPolicyDenial is returned by a CSRAuthorizer when a request is rejected.
+type CSRPolicyDenial struct {
+	// Rule is the name of the rule that denied the request, empty if the default action applied.
+	Rule string
+	// Reason is a bounded identifier of the failed check.
+	Reason string
+	// Message is a human readable explanation. It is only logged, never returned to the client.
+	Message string
+}
+
+func (d *CSRPolicyDenial) Error() string {
+	if d.Rule == "" {
+		return fmt.Sprintf("denied by default policy: %s", d.Message)
+	}
+	return fmt.Sprintf("denied by rule %q: %s", d.Rule, d.Message)
+}
+
+// CSRPolicyAction is the action taken when no rule matches a request.
+type CSRPolicyAction string
+
+const (
+	CSRPolicyAllow CSRPolicyAction = "ALLOW"
+	CSRPolicyDeny  CSRPolicyAction = "DENY"
+)
+
+// CSRPolicy is a set of rules constraining which certificates may be issued. The first rule
+// matching the requesting namespace and service account is evaluated; if none matches,
+// DefaultAction applies.
+//
+// Example:
+//
+//	defaultAction: ALLOW
+//	rules:
+//	- name: payments
+//	  namespaces: [payments]
+//	  allowedIdentities: ["spiffe://cluster.local/ns/payments/sa/*"]
+//	  maxTTL: 12h
+//	  keyTypes: [ECDSA]
+//	  condition: "serviceAccount != 'default'"
+type CSRPolicy struct {
+	DefaultAction CSRPolicyAction `json:"defaultAction,omitempty"`
+	Rules         []CSRPolicyRule `json:"rules,omitempty"`
+}
+
+// CSRPolicyRule constrains certificate requests for a set of namespaces and service accounts.
+type CSRPolicyRule struct {
+	Name string `json:"name,omitempty"`
+	// Namespaces and ServiceAccounts select the requests this rule applies to. Entries are
+	// path.Match patterns; an empty list matches everything.
+	Namespaces      []string `json:"namespaces,omitempty"`
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	// AllowedIdentities are path.Match patterns every requested SAN must match. Empty allows any.
+	AllowedIdentities []string `json:"allowedIdentities,omitempty"`
+	// MaxTTL is the largest certificate lifetime that may be requested. Zero means unbounded.
+	MaxTTL metav1.Duration `json:"maxTTL,omitempty"`
+	// KeyTypes restricts the CSR public key algorithm. Empty allows any.
+	KeyTypes []string `json:"keyTypes,omitempty"`
+	// Condition is an optional CEL expression that must evaluate to true. The variables
+	// namespace, serviceAccount, identities, ttlSeconds, keyType and authenticator are available.
+	Condition string `json:"condition,omitempty"`
+
+	program cel.Program
+}
+
+var csrPolicyEnv = newCSRPolicyEnv()
+
+func newCSRPolicyEnv() *cel.Env {
+	env, err := cel.NewEnv(
+		cel.Variable("namespace", cel.StringType),
+		cel.Variable("serviceAccount", cel.StringType),
+		cel.Variable("identities", cel.ListType(cel.StringType)),
+		cel.Variable("ttlSeconds", cel.IntType),
+		cel.Variable("keyType", cel.StringType),
+		cel.Variable("authenticator", cel.StringType),
+	)
+	if err != nil {
+		// The declarations are static, so this can only be a programming error.
+		panic(fmt.Sprintf("failed to create the CSR policy CEL environment: %v", err))
+	}
+	return env
+}
+
+// ParseCSRPolicy parses and validates a YAML encoded CSRPolicy.
+func ParseCSRPolicy(data []byte) (*CSRPolicy, error) {
+	p := &CSRPolicy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse CSR policy: %v", err)
+	}
+	switch p.DefaultAction {
+	case "":
+		p.DefaultAction = CSRPolicyAllow
+	case CSRPolicyAllow, CSRPolicyDeny:
+	default:
+		return nil, fmt.Errorf("invalid defaultAction %q", p.DefaultAction)
+	}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i)
+		}
+		for _, pattern := range slices.Flatten([][]string{r.Namespaces, r.ServiceAccounts, r.AllowedIdentities}) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %q: invalid pattern %q: %v", r.Name, pattern, err)
+			}
+		}
+		for _, kt := range r.KeyTypes {
+			if kt != KeyTypeRSA && kt != KeyTypeECDSA && kt != KeyTypeED25519 {
+				return nil, fmt.Errorf("rule %q: unsupported key type %q", r.Name, kt)
+			}
+		}
+		if r.Condition != "" {
+			ast, iss := csrPolicyEnv.Compile(r.Condition)
+			if iss.Err() != nil {
+				return nil, fmt.Errorf("rule %q: invalid condition: %v", r.Name, iss.Err())
+			}
+			if ast.OutputType() != cel.BoolType {
+				return nil, fmt.Errorf("rule %q: condition must evaluate to a bool, got %v", r.Name, ast.OutputType())
+			}
+			prg, err := csrPolicyEnv.Program(ast)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid condition: %v", r.Name, err)
+			}
+			r.program = prg
+		}
+	}
+	return p, nil
+}
+
+// Authorize implements CSRAuthorizer.
+func (p *CSRPolicy) Authorize(req *CSRPolicyRequest) error {
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !matchAny(r.Namespaces, req.Namespace) || !matchAny(r.ServiceAccounts, req.ServiceAccount) {
+			continue
+		}
+		return r.authorize(req)
+	}
+	if p.DefaultAction == CSRPolicyDeny {
+		return &CSRPolicyDenial{Reason: policyDenyDefault, Message: "no rule matched the request"}
+	}
+	return nil
+}
+
+func (r *CSRPolicyRule) authorize(req *CSRPolicyRequest) error {
+	deny := func(reason, format string, args ...any) error {
+		return &CSRPolicyDenial{Rule: r.Name, Reason: reason, Message: fmt.Sprintf(format, args...)}
+	}
+	if len(r.AllowedIdentities) > 0 {
+		for _, id := range req.Identities {
+			if !matchAny(r.AllowedIdentities, id) {
+				return deny(policyDenyIdentity, "identity %q is not allowed", id)
+			}
+		}
+	}
+	if r.MaxTTL.Duration > 0 {
+		if req.TTL <= 0 {
+			// The CA default TTL is unknown, so it cannot be checked against the maximum.
+			return deny(policyDenyTTL, "certificate TTL is unknown, maximum is %v", r.MaxTTL.Duration)
+		}
+		if req.TTL > r.MaxTTL.Duration {
+			return deny(policyDenyTTL, "certificate TTL %v exceeds maximum %v", req.TTL, r.MaxTTL.Duration)
+		}
+	}
+	if len(r.KeyTypes) > 0 && !slices.Contains(r.KeyTypes, req.KeyType) {
+		return deny(policyDenyKeyType, "key type %q is not allowed", req.KeyType)
+	}
+	if r.program != nil {
+		out, _, err := r.program.Eval(map[string]any{
+			"namespace":      req.Namespace,
+			"serviceAccount": req.ServiceAccount,
+			"identities":     req.Identities,
+			"ttlSeconds":     int64(req.TTL.Seconds()),
+			"keyType":        req.KeyType,
+			"authenticator":  req.Authenticator,
+		})
+		if err != nil {
+			return deny(policyDenyCondition, "condition evaluation failed: %v", err)
+		}
+		if allowed, ok := out.Value().(bool); !ok || !allowed {
+			return deny(policyDenyCondition, "condition %q is not satisfied", r.Condition)
+		}
+	}
+	return nil
+}
+
+// matchAny returns true if patterns is empty or s matches any of the patterns.
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DynamicCSRPolicy is a CSRAuthorizer whose policy can be replaced at runtime, typically from a ConfigMap.
+// Until a policy is set, all requests are allowed.
+type DynamicCSRPolicy struct {
+	policy atomic.Pointer[CSRPolicy]
+}
+
+// NewDynamicCSRPolicy returns an empty DynamicCSRPolicy.
+func NewDynamicCSRPolicy() *DynamicCSRPolicy {
+	return &DynamicCSRPolicy{}
+}
+
+// Authorize implements CSRAuthorizer.
+func (d *DynamicCSRPolicy) Authorize(req *CSRPolicyRequest) error {
+	p := d.policy.Load()
+	if p == nil {
+		return nil
+	}
+	return p.Authorize(req)
+}
+
+// Set replaces the active policy. A nil policy allows all requests.
+func (d *DynamicCSRPolicy) Set(p *CSRPolicy) {
+	d.policy.Store(p)
+}
+
+// UpdateFromConfigMap loads the policy from the given ConfigMap. A missing ConfigMap clears the policy;
+// an invalid one keeps the previously active policy in place.
+func (d *DynamicCSRPolicy) UpdateFromConfigMap(cm *v1.ConfigMap) {
+	if cm == nil {
+		serverCaLog.Infof("CSR policy ConfigMap removed, allowing all requests")
+		d.Set(nil)
+		return
+	}
+	p, err := ParseCSRPolicy([]byte(cm.Data[CSRPolicyConfigMapKey]))
+	if err != nil {
+		serverCaLog.Errorf("failed to load CSR policy from ConfigMap %s/%s, keeping previous policy: %v", cm.Namespace, cm.Name, err)
+		return
+	}
+	serverCaLog.Infof("loaded CSR policy from ConfigMap %s/%s with %d rules", cm.Namespace, cm.Name, len(p.Rules))
+	d.Set(p)
+}
+
+// csrPolicyRequest builds the policy request for the given caller and certificate request. Policies are expressed in
+// terms of workload namespaces and service accounts, so requests for non SPIFFE identities, or for identities of
+// several workloads, are rejected with a *CSRPolicyDenial, along with the partial request for logging.
+func csrPolicyRequest(sans []string, ttl time.Duration, csrPEM []byte, authenticator string) (*CSRPolicyRequest, error) {
+	req := &CSRPolicyRequest{
+		Identities:    sans,
+		TTL:           ttl,
+		Authenticator: authenticator,
+	}
+	if len(sans) == 0 {
+		return req, &CSRPolicyDenial{Reason: policyDenyIdentity, Message: "no identity requested"}
+	}
+	// The namespace and service account are derived from the identity being issued, so that
+	// impersonated requests are evaluated against the impersonated workload.
+	for i, san := range sans {
+		id, err := spiffe.ParseIdentity(san)
+		if err != nil {
+			return req, &CSRPolicyDenial{Reason: policyDenyIdentity, Message: fmt.Sprintf("identity %q is not a SPIFFE identity", san)}
+		}
+		if i > 0 && (id.Namespace != req.Namespace || id.ServiceAccount != req.ServiceAccount) {
+			return req, &CSRPolicyDenial{Reason: policyDenyIdentity, Message: fmt.Sprintf("identities %v belong to several workloads", sans)}
+		}
+		req.Namespace = id.Namespace
+		req.ServiceAccount = id.ServiceAccount
+	}
+	if csr, err := util.ParsePemEncodedCSR(csrPEM); err == nil {
+		switch csr.PublicKeyAlgorithm {
+		case x509.RSA:
+			req.KeyType = KeyTypeRSA
+		case x509.ECDSA:
+			req.KeyType = KeyTypeECDSA
+		case x509.Ed25519:
+			req.KeyType = KeyTypeED25519
+		}
+	}
+	return req, nil
+}
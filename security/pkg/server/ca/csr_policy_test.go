@@ -0,0 +1,300 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pb "istio.io/api/security/v1alpha1"
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/security"
+	mockca "istio.io/istio/security/pkg/pki/ca/mock"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const testCSRPolicy = `
+defaultAction: DENY
+rules:
+- name: payments
+  namespaces: [payments]
+  allowedIdentities: ["spiffe://cluster.local/ns/payments/sa/*"]
+  maxTTL: 12h
+  keyTypes: [ECDSA]
+- name: no-default-sa
+  namespaces: ["team-*"]
+  condition: "serviceAccount != 'default' && ttlSeconds <= 3600"
+`
+
+func TestParseCSRPolicy(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy string
+		err    bool
+	}{
+		{name: "valid", policy: testCSRPolicy},
+		{name: "empty", policy: ""},
+		{name: "invalid action", policy: "defaultAction: MAYBE", err: true},
+		{name: "unknown field", policy: "rules:\n- namespace: foo", err: true},
+		{name: "invalid key type", policy: "rules:\n- keyTypes: [DSA]", err: true},
+		{name: "invalid pattern", policy: "rules:\n- namespaces: ['[']", err: true},
+		{name: "invalid condition", policy: "rules:\n- condition: 'namespace =='", err: true},
+		{name: "non bool condition", policy: "rules:\n- condition: 'namespace'", err: true},
+		{name: "unknown variable", policy: "rules:\n- condition: 'pod == \"a\"'", err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSRPolicy([]byte(tt.policy))
+			if (err != nil) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCSRPolicyAuthorize(t *testing.T) {
+	policy, err := ParseCSRPolicy([]byte(testCSRPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		req    CSRPolicyRequest
+		reason string
+		rule   string
+	}{
+		{
+			name: "allowed",
+			req: CSRPolicyRequest{
+				Namespace:      "payments",
+				ServiceAccount: "api",
+				Identities:     []string{"spiffe://cluster.local/ns/payments/sa/api"},
+				TTL:            time.Hour,
+				KeyType:        KeyTypeECDSA,
+			},
+		},
+		{
+			name: "identity not allowed",
+			req: CSRPolicyRequest{
+				Namespace:      "payments",
+				ServiceAccount: "api",
+				Identities:     []string{"spiffe://other.domain/ns/payments/sa/api"},
+				KeyType:        KeyTypeECDSA,
+			},
+			reason: policyDenyIdentity,
+			rule:   "payments",
+		},
+		{
+			name: "ttl too long",
+			req: CSRPolicyRequest{
+				Namespace:      "payments",
+				ServiceAccount: "api",
+				Identities:     []string{"spiffe://cluster.local/ns/payments/sa/api"},
+				TTL:            24 * time.Hour,
+				KeyType:        KeyTypeECDSA,
+			},
+			reason: policyDenyTTL,
+			rule:   "payments",
+		},
+		{
+			name: "ttl unknown",
+			req: CSRPolicyRequest{
+				Namespace:      "payments",
+				ServiceAccount: "api",
+				Identities:     []string{"spiffe://cluster.local/ns/payments/sa/api"},
+				KeyType:        KeyTypeECDSA,
+			},
+			reason: policyDenyTTL,
+			rule:   "payments",
+		},
+		{
+			name: "key type not allowed",
+			req: CSRPolicyRequest{
+				Namespace:      "payments",
+				ServiceAccount: "api",
+				Identities:     []string{"spiffe://cluster.local/ns/payments/sa/api"},
+				TTL:            time.Hour,
+				KeyType:        KeyTypeRSA,
+			},
+			reason: policyDenyKeyType,
+			rule:   "payments",
+		},
+		{
+			name:   "condition not satisfied",
+			req:    CSRPolicyRequest{Namespace: "team-a", ServiceAccount: "default"},
+			reason: policyDenyCondition,
+			rule:   "no-default-sa",
+		},
+		{
+			name: "condition satisfied",
+			req:  CSRPolicyRequest{Namespace: "team-a", ServiceAccount: "worker", TTL: time.Hour},
+		},
+		{
+			name:   "default deny",
+			req:    CSRPolicyRequest{Namespace: "other", ServiceAccount: "default"},
+			reason: policyDenyDefault,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(&tt.req)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("expected request to be allowed, got %v", err)
+				}
+				return
+			}
+			denial, ok := err.(*CSRPolicyDenial)
+			if !ok {
+				t.Fatalf("expected a policy denial, got %v", err)
+			}
+			if denial.Reason != tt.reason || denial.Rule != tt.rule {
+				t.Fatalf("expected denial (%q, %q), got (%q, %q)", tt.rule, tt.reason, denial.Rule, denial.Reason)
+			}
+		})
+	}
+}
+
+func TestDynamicCSRPolicy(t *testing.T) {
+	d := NewDynamicCSRPolicy()
+	req := &CSRPolicyRequest{Namespace: "other"}
+	if err := d.Authorize(req); err != nil {
+		t.Fatalf("expected empty policy to allow, got %v", err)
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr-policy", Namespace: "istio-system"},
+		Data:       map[string]string{CSRPolicyConfigMapKey: testCSRPolicy},
+	}
+	d.UpdateFromConfigMap(cm)
+	if err := d.Authorize(req); err == nil {
+		t.Fatalf("expected loaded policy to deny")
+	}
+	// An invalid update keeps the previous policy.
+	d.UpdateFromConfigMap(&v1.ConfigMap{Data: map[string]string{CSRPolicyConfigMapKey: "defaultAction: MAYBE"}})
+	if err := d.Authorize(req); err == nil {
+		t.Fatalf("expected previous policy to still deny")
+	}
+	d.UpdateFromConfigMap(nil)
+	if err := d.Authorize(req); err != nil {
+		t.Fatalf("expected removed policy to allow, got %v", err)
+	}
+}
+
+func TestCreateCertificateWithCSRPolicy(t *testing.T) {
+	mt := monitortest.New(t)
+	csr, _, err := util.GenCSR(util.CertOptions{Host: "spiffe://cluster.local/ns/payments/sa/api", RSAKeySize: 2048})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := ParseCSRPolicy([]byte(testCSRPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		ca: &mockca.FakeCA{
+			SignedCert:    []byte(testCert),
+			KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte(testCertChain), []byte(testRootCert), nil),
+		},
+		Authenticators: []security.Authenticator{&mockAuthenticator{
+			identities: []string{"spiffe://cluster.local/ns/payments/sa/api"},
+		}},
+		monitoring:     newMonitoringMetrics(),
+		CSRAuthorizer:  policy,
+		DefaultCertTTL: time.Hour,
+	}
+	p := &peer.Peer{Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)}, AuthInfo: credentials.TLSInfo{}}
+	ctx := peer.NewContext(context.Background(), p)
+	// The policy only allows ECDSA keys for the payments namespace.
+	_, err = server.CreateCertificate(ctx, &pb.IstioCertificateRequest{Csr: string(csr)})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	mt.Assert(policyDenialCounts.Name(), map[string]string{reasonlabel: policyDenyKeyType}, monitortest.Exactly(1))
+
+	csr, _, err = util.GenCSR(util.CertOptions{Host: "spiffe://cluster.local/ns/payments/sa/api", ECSigAlg: util.EcdsaSigAlg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.CreateCertificate(ctx, &pb.IstioCertificateRequest{Csr: string(csr)}); err != nil {
+		t.Fatalf("expected request to be allowed, got %v", err)
+	}
+
+	// The maximum TTL applies to the CA default TTL when no TTL is requested.
+	server.DefaultCertTTL = 24 * time.Hour
+	_, err = server.CreateCertificate(ctx, &pb.IstioCertificateRequest{Csr: string(csr)})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	mt.Assert(policyDenialCounts.Name(), map[string]string{reasonlabel: policyDenyTTL}, monitortest.Exactly(1))
+}
+
+func TestCSRPolicyRequest(t *testing.T) {
+	cases := []struct {
+		name    string
+		sans    []string
+		ns      string
+		sa      string
+		message string
+	}{
+		{
+			name: "spiffe identity",
+			sans: []string{"spiffe://cluster.local/ns/payments/sa/api", "spiffe://alias.domain/ns/payments/sa/api"},
+			ns:   "payments",
+			sa:   "api",
+		},
+		{
+			name:    "no identity",
+			message: "no identity requested",
+		},
+		{
+			name:    "non spiffe identity",
+			sans:    []string{"spiffe://cluster.local/ns/payments/sa/api", "api.payments.svc"},
+			message: `identity "api.payments.svc" is not a SPIFFE identity`,
+		},
+		{
+			name:    "several workloads",
+			sans:    []string{"spiffe://cluster.local/ns/payments/sa/api", "spiffe://cluster.local/ns/payments/sa/admin"},
+			message: "belong to several workloads",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := csrPolicyRequest(tt.sans, time.Hour, nil, "ClientCertificate")
+			if tt.message == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if req.Namespace != tt.ns || req.ServiceAccount != tt.sa {
+					t.Fatalf("expected %s/%s, got %s/%s", tt.ns, tt.sa, req.Namespace, req.ServiceAccount)
+				}
+				return
+			}
+			denial, ok := err.(*CSRPolicyDenial)
+			if !ok || denial.Reason != policyDenyIdentity || !strings.Contains(denial.Message, tt.message) {
+				t.Fatalf("expected identity denial %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
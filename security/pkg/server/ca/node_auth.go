@@ -51,11 +51,17 @@ func NewMulticlusterNodeAuthenticator(
 	return m
 }
 
-func (m *MulticlusterNodeAuthorizor) authenticateImpersonation(ctx context.Context, caller security.KubernetesInfo, requestedIdentityString string) error {
+// authenticateImpersonation validates the caller may impersonate the requested identity, returning the node of the caller
+// once it has been looked up.
+func (m *MulticlusterNodeAuthorizor) authenticateImpersonation(
+	ctx context.Context,
+	caller security.KubernetesInfo,
+	requestedIdentityString string,
+) (string, error) {
 	clusterID := kubeauth.ExtractClusterID(ctx)
 	na := m.component.ForCluster(clusterID)
 	if na == nil {
-		return fmt.Errorf("no node authorizer for cluster %v", clusterID)
+		return "", fmt.Errorf("no node authorizer for cluster %v", clusterID)
 	}
 	return (*na).authenticateImpersonation(caller, requestedIdentityString)
 }
@@ -108,35 +114,35 @@ func (na *ClusterNodeAuthorizer) HasSynced() bool {
 	return na.pods.HasSynced()
 }
 
-func (na *ClusterNodeAuthorizer) authenticateImpersonation(caller security.KubernetesInfo, requestedIdentityString string) error {
+func (na *ClusterNodeAuthorizer) authenticateImpersonation(caller security.KubernetesInfo, requestedIdentityString string) (string, error) {
 	callerSa := types.NamespacedName{
 		Namespace: caller.PodNamespace,
 		Name:      caller.PodServiceAccount,
 	}
 	// First, make sure the caller is allowed to impersonate, in general
 	if _, f := na.trustedNodeAccounts[callerSa]; !f {
-		return fmt.Errorf("caller (%v) is not allowed to impersonate", caller)
+		return "", fmt.Errorf("caller (%v) is not allowed to impersonate", caller)
 	}
 	// Next, make sure the identity they want to impersonate is valid, in general
 	requestedIdentity, err := spiffe.ParseIdentity(requestedIdentityString)
 	if err != nil {
-		return fmt.Errorf("failed to validate impersonated identity %v", requestedIdentityString)
+		return "", fmt.Errorf("failed to validate impersonated identity %v", requestedIdentityString)
 	}
 
 	// Finally, we validate the requested identity is running on the same node the caller is on
 	callerPod := na.pods.Get(caller.PodName, caller.PodNamespace)
 	if callerPod == nil {
-		return fmt.Errorf("pod %v/%v not found", caller.PodNamespace, caller.PodName)
+		return "", fmt.Errorf("pod %v/%v not found", caller.PodNamespace, caller.PodName)
 	}
 	// Make sure UID is still valid for our current state
 	if callerPod.UID != types.UID(caller.PodUID) {
 		// This would only happen if a pod is re-created with the same name, and the CSR client is not in sync on which is current;
 		// this is fine and should be eventually consistent. Client is expected to retry in this case.
-		return fmt.Errorf("pod found, but UID does not match: %v vs %v", callerPod.UID, caller.PodUID)
+		return "", fmt.Errorf("pod found, but UID does not match: %v vs %v", callerPod.UID, caller.PodUID)
 	}
 	if callerPod.Spec.ServiceAccountName != caller.PodServiceAccount {
 		// This should never happen, but just in case add an additional check
-		return fmt.Errorf("pod found, but ServiceAccount does not match: %v vs %v", callerPod.Spec.ServiceAccountName, caller.PodServiceAccount)
+		return "", fmt.Errorf("pod found, but ServiceAccount does not match: %v vs %v", callerPod.Spec.ServiceAccountName, caller.PodServiceAccount)
 	}
 	// We want to find out if there is any pod running with the requested identity on the callers node.
 	// The indexer (previously setup) creates a lookup table for a {Node, SA} pair, which we can lookup
@@ -150,8 +156,8 @@ func (na *ClusterNodeAuthorizer) authenticateImpersonation(caller security.Kuber
 	// We don't care what pods are part of the index, only that there is at least one. If there is one,
 	// it is appropriate for the caller to request this identity.
 	if len(res) == 0 {
-		return fmt.Errorf("no instances of %q found on node %q", k.ServiceAccount, k.Node)
+		return k.Node, fmt.Errorf("no instances of %q found on node %q", k.ServiceAccount, k.Node)
 	}
 	serverCaLog.Debugf("Node caller %v impersonated %v", caller, requestedIdentityString)
-	return nil
+	return k.Node, nil
 }
@@ -180,7 +180,7 @@ func TestSingleClusterNodeAuthorization(t *testing.T) {
 			c.RunAndWait(test.NewStop(t))
 			kube.WaitForCacheSync("test", test.NewStop(t), na.pods.HasSynced)
 
-			err := na.authenticateImpersonation(tt.caller, tt.requestedIdentityString)
+			_, err := na.authenticateImpersonation(tt.caller, tt.requestedIdentityString)
 			if tt.wantErr == "" && err != nil {
 				t.Fatalf("wanted no error, got %v", err)
 			}
@@ -341,7 +341,7 @@ func TestMultiClusterNodeAuthorization(t *testing.T) {
 			ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{
 				"clusterid": []string{string(tt.callerClusterID)},
 			})
-			err := mNa.authenticateImpersonation(ctx, tt.caller, tt.requestedIdentityString)
+			_, err := mNa.authenticateImpersonation(ctx, tt.caller, tt.requestedIdentityString)
 			if tt.wantErr == "" && err != nil {
 				t.Fatalf("wanted no error, got %v", err)
 			}
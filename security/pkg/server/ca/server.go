@@ -16,6 +16,7 @@ package ca
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"google.golang.org/grpc"
@@ -25,12 +26,16 @@ import (
 
 	pb "istio.io/api/security/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/kube/multicluster"
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/security/pkg/pki/ca"
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
+	"istio.io/istio/security/pkg/server/ca/audit"
 )
 
 var serverCaLog = log.RegisterScope("serverca", "Citadel server log")
@@ -55,6 +60,14 @@ type Server struct {
 	serverCertTTL  time.Duration
 
 	nodeAuthorizer *MulticlusterNodeAuthorizor
+	// CSRAuthorizer, if set, is consulted after authentication to restrict the certificates a caller may obtain.
+	CSRAuthorizer CSRAuthorizer
+	// DefaultCertTTL is the lifetime the CA gives to certificates requested without one, checked by the CSRAuthorizer.
+	DefaultCertTTL time.Duration
+	// Auditor, if set, receives a record of every certificate request and its outcome.
+	Auditor audit.Sink
+	// MeshHolder, if set, allows workloads to request their own identity in the trust domain aliases of the mesh.
+	MeshHolder mesh.Holder
 }
 
 type SaNode struct {
@@ -76,11 +89,23 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	*pb.IstioCertificateResponse, error,
 ) {
 	s.monitoring.CSR.Increment()
+	rec := &audit.Record{
+		Time:       time.Now(),
+		Client:     security.GetConnectionAddress(ctx),
+		TTLSeconds: request.ValidityDuration,
+	}
+	defer s.audit(rec)
 	caller, err := security.Authenticate(ctx, s.Authenticators)
 	if caller == nil || err != nil {
 		s.monitoring.AuthnError.Increment()
+		rec.Outcome, rec.Reason = audit.OutcomeAuthenticationFailed, "request authenticate failure"
 		return nil, status.Error(codes.Unauthenticated, "request authenticate failure")
 	}
+	rec.Requester = caller.Identities
+	rec.Authenticator = caller.AuthSource.String()
+	if caller.KubernetesInfo.PodName != "" {
+		rec.RequesterPod = caller.KubernetesInfo.PodNamespace + "/" + caller.KubernetesInfo.PodName
+	}
 
 	serverCaLog := serverCaLog.WithLabels("client", security.GetConnectionAddress(ctx))
 	// By default, we will use the callers identity for the certificate
@@ -95,17 +120,55 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 			s.monitoring.AuthnError.Increment()
 			// Return an opaque error (for security purposes) but log the full reason
 			serverCaLog.Warnf("impersonation not allowed, as node authorizer (CA_TRUSTED_NODE_ACCOUNTS) is not configured")
+			rec.SANs = []string{impersonatedIdentity}
+			rec.Outcome, rec.Reason = audit.OutcomeAuthenticationFailed, "impersonation not allowed"
 			return nil, status.Error(codes.Unauthenticated, "request impersonation authentication failure")
 
 		}
-		if err := s.nodeAuthorizer.authenticateImpersonation(ctx, caller.KubernetesInfo, impersonatedIdentity); err != nil {
+		node, err := s.nodeAuthorizer.authenticateImpersonation(ctx, caller.KubernetesInfo, impersonatedIdentity)
+		rec.SANs, rec.Node = []string{impersonatedIdentity}, node
+		if err != nil {
 			s.monitoring.AuthnError.Increment()
 			// Return an opaque error (for security purposes) but log the full reason
 			serverCaLog.Warnf("impersonation failed for identity %s, error: %v", impersonatedIdentity, err)
+			rec.Outcome, rec.Reason = audit.OutcomeAuthenticationFailed, fmt.Sprintf("impersonation failed: %v", err)
 			return nil, status.Error(codes.Unauthenticated, "request impersonation authentication failure")
 		}
 		// Node is authorized to impersonate; overwrite the SAN to the impersonated identity.
 		sans = []string{impersonatedIdentity}
+	} else if requestedIdentity := crMetadata[security.RequestedIdentity].GetStringValue(); requestedIdentity != "" {
+		rec.SANs = []string{requestedIdentity}
+		if err := s.authorizeRequestedIdentity(caller.Identities, requestedIdentity); err != nil {
+			// Return an opaque error (for security purposes) but log the full reason
+			serverCaLog.Warnf("requested identity %s not allowed for %v: %v", requestedIdentity, caller.Identities, err)
+			rec.Outcome, rec.Reason = audit.OutcomeDenied, fmt.Sprintf("requested identity not allowed: %v", err)
+			return nil, status.Error(codes.PermissionDenied, "requested identity not allowed")
+		}
+		sans = []string{requestedIdentity}
+	}
+	rec.SANs = sans
+	if s.CSRAuthorizer != nil {
+		ttl := time.Duration(request.ValidityDuration) * time.Second
+		if ttl <= 0 {
+			ttl = s.DefaultCertTTL
+		}
+		policyReq, err := csrPolicyRequest(sans, ttl, []byte(request.Csr), caller.AuthSource.String())
+		if err == nil {
+			err = s.CSRAuthorizer.Authorize(policyReq)
+		}
+		if err != nil {
+			reason := policyDenyDefault
+			if denial, ok := err.(*CSRPolicyDenial); ok {
+				reason = denial.Reason
+			}
+			s.monitoring.GetPolicyDenial(reason).Increment()
+			// Return an opaque error (for security purposes) but log the full reason
+			serverCaLog.WithLabels("audit", "csr_policy", "namespace", policyReq.Namespace, "serviceAccount", policyReq.ServiceAccount,
+				"authenticator", policyReq.Authenticator, "caller", caller.Identities).
+				Warnf("CSR denied by policy, sans: %v, ttl: %v, key type: %q: %v", sans, policyReq.TTL, policyReq.KeyType, err)
+			rec.Outcome, rec.Reason = audit.OutcomeDenied, err.Error()
+			return nil, status.Error(codes.PermissionDenied, "request denied by CSR policy")
+		}
 	}
 	serverCaLog.Debugf("generating a certificate, sans: %v, requested ttl: %s", sans, time.Duration(request.ValidityDuration*int64(time.Second)))
 	certSigner := crMetadata[security.CertSigner].GetStringValue()
@@ -128,6 +191,7 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	if signErr != nil {
 		serverCaLog.Errorf("CSR signing error: %v", signErr.Error())
 		s.monitoring.GetCertSignError(signErr.(*caerror.Error).ErrorType()).Increment()
+		rec.Outcome, rec.Reason = audit.OutcomeSigningFailed, signErr.Error()
 		return nil, status.Errorf(signErr.(*caerror.Error).HTTPErrorCode(), "CSR signing error (%v)", signErr.(*caerror.Error))
 	}
 	if certSigner == "" {
@@ -154,12 +218,46 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 		response.CertChain = append(response.CertChain, string(rootCertBytes))
 	}
 
+	rec.Outcome = audit.OutcomeIssued
+	if len(respCertChain) > 0 {
+		if leaf, err := util.ParsePemEncodedCertificate([]byte(respCertChain[0])); err == nil {
+			rec.Serial = leaf.SerialNumber.Text(16)
+			rec.NotAfter = &leaf.NotAfter
+		}
+	}
+
 	serverCaLog.Debugf("Responding with cert chain, %q", response.CertChain)
 	s.monitoring.Success.Increment()
 	serverCaLog.Debugf("CSR successfully signed, sans %v.", sans)
 	return response, nil
 }
 
+// authorizeRequestedIdentity checks that a caller may obtain a certificate for an identity other than its
+// default one. Callers may only request their own service account identity, in a trust domain alias of the mesh.
+func (s *Server) authorizeRequestedIdentity(callerIdentities []string, requestedIdentity string) error {
+	requested, err := spiffe.ParseIdentity(requestedIdentity)
+	if err != nil {
+		return err
+	}
+	if s.MeshHolder == nil || !slices.Contains(s.MeshHolder.Mesh().GetTrustDomainAliases(), requested.TrustDomain) {
+		return fmt.Errorf("trust domain %s is not a trust domain alias", requested.TrustDomain)
+	}
+	for _, id := range callerIdentities {
+		caller, err := spiffe.ParseIdentity(id)
+		if err == nil && caller.Namespace == requested.Namespace && caller.ServiceAccount == requested.ServiceAccount {
+			return nil
+		}
+	}
+	return fmt.Errorf("caller does not own service account %s/%s", requested.Namespace, requested.ServiceAccount)
+}
+
+// audit emits the audit record of a certificate request, if auditing is enabled.
+func (s *Server) audit(rec *audit.Record) {
+	if s.Auditor != nil {
+		s.Auditor.Write(rec)
+	}
+}
+
 // RecordCertsExpiry updates the certificate-expiration related metrics given a new keycertbundle
 func RecordCertsExpiry(keyCertBundle *util.KeyCertBundle) {
 	// Expiry of the first root cert in trust bundle
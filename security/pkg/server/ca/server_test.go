@@ -34,9 +34,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	pb "istio.io/api/security/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/mesh/meshwatcher"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/multicluster"
 	"istio.io/istio/pkg/monitoring/monitortest"
@@ -46,6 +48,7 @@ import (
 	mockca "istio.io/istio/security/pkg/pki/ca/mock"
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
+	"istio.io/istio/security/pkg/server/ca/audit"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
 )
 
@@ -650,3 +653,116 @@ func TestRecordCertsExipryMetrics(t *testing.T) {
 		mt.Assert(certChainExpirySeconds.Name(), nil, monitortest.AlmostEquals(certTTL.Seconds(), eps))
 	})
 }
+
+type fakeAuditSink struct {
+	records []*audit.Record
+}
+
+func (f *fakeAuditSink) Write(r *audit.Record) {
+	f.records = append(f.records, r)
+}
+
+func TestCreateCertificateAudit(t *testing.T) {
+	p := &peer.Peer{Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)}, AuthInfo: credentials.TLSInfo{}}
+	ctx := peer.NewContext(context.Background(), p)
+	cases := []struct {
+		name          string
+		authenticator *mockAuthenticator
+		ca            CertificateAuthority
+		outcome       audit.Outcome
+	}{
+		{
+			name:          "authentication failure",
+			authenticator: &mockAuthenticator{errMsg: "not authorized"},
+			ca:            &mockca.FakeCA{},
+			outcome:       audit.OutcomeAuthenticationFailed,
+		},
+		{
+			name:          "signing failure",
+			authenticator: &mockAuthenticator{identities: []string{"test-identity"}},
+			ca: &mockca.FakeCA{
+				SignErr:       caerror.NewError(caerror.CSRError, fmt.Errorf("cannot sign")),
+				KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte(testCertChain), []byte(testRootCert), nil),
+			},
+			outcome: audit.OutcomeSigningFailed,
+		},
+		{
+			name: "issued",
+			authenticator: &mockAuthenticator{
+				identities:     []string{"test-identity"},
+				kubernetesInfo: security.KubernetesInfo{PodName: "pod", PodNamespace: "ns"},
+			},
+			ca: &mockca.FakeCA{
+				SignedCert:    []byte(testCert),
+				KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte(testCertChain), []byte(testRootCert), nil),
+			},
+			outcome: audit.OutcomeIssued,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &fakeAuditSink{}
+			server := &Server{
+				ca:             tt.ca,
+				Authenticators: []security.Authenticator{tt.authenticator},
+				monitoring:     newMonitoringMetrics(),
+				Auditor:        sink,
+			}
+			_, _ = server.CreateCertificate(ctx, &pb.IstioCertificateRequest{Csr: "dumb CSR", ValidityDuration: 60})
+			if len(sink.records) != 1 {
+				t.Fatalf("expected exactly one audit record, got %d", len(sink.records))
+			}
+			rec := sink.records[0]
+			if rec.Outcome != tt.outcome {
+				t.Fatalf("expected outcome %v, got %v", tt.outcome, rec.Outcome)
+			}
+			if rec.Client != "192.168.1.1" || rec.TTLSeconds != 60 {
+				t.Fatalf("unexpected audit record %+v", rec)
+			}
+			if tt.outcome == audit.OutcomeIssued && (rec.RequesterPod != "ns/pod" || len(rec.SANs) != 1 || rec.SANs[0] != "test-identity") {
+				t.Fatalf("unexpected audit record %+v", rec)
+			}
+		})
+	}
+}
+
+func TestCreateCertificateWithRequestedIdentity(t *testing.T) {
+	p := &peer.Peer{Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)}, AuthInfo: credentials.TLSInfo{}}
+	caller := "spiffe://cluster.local/ns/foo/sa/bar"
+	cases := []struct {
+		name      string
+		requested string
+		code      codes.Code
+	}{
+		{name: "own identity in alias", requested: "spiffe://legacy.example/ns/foo/sa/bar", code: codes.OK},
+		{name: "other service account", requested: "spiffe://legacy.example/ns/foo/sa/other", code: codes.PermissionDenied},
+		{name: "unknown trust domain", requested: "spiffe://other.example/ns/foo/sa/bar", code: codes.PermissionDenied},
+		{name: "invalid identity", requested: "foo", code: codes.PermissionDenied},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeCA := &mockca.FakeCA{
+				SignedCert:    []byte(testCert),
+				KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte(testCertChain), []byte(testRootCert), nil),
+			}
+			server := &Server{
+				ca:             fakeCA,
+				Authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{caller}}},
+				monitoring:     newMonitoringMetrics(),
+				MeshHolder: meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{
+					TrustDomain:        "cluster.local",
+					TrustDomainAliases: []string{"legacy.example"},
+				}),
+			}
+			reqMeta, _ := structpb.NewStruct(map[string]any{security.RequestedIdentity: tt.requested})
+			_, err := server.CreateCertificate(peer.NewContext(context.Background(), p),
+				&pb.IstioCertificateRequest{Csr: "dumb CSR", Metadata: reqMeta})
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("expected code %v, got %v: %v", tt.code, code, err)
+			}
+			if tt.code == codes.OK && (len(fakeCA.ReceivedIDs) != 1 || fakeCA.ReceivedIDs[0] != tt.requested) {
+				t.Fatalf("expected certificate for %v, got %v", tt.requested, fakeCA.ReceivedIDs)
+			}
+		})
+	}
+}
@@ -348,6 +348,7 @@ func gatherInfo(runner *kubectlcmd.Runner, config *config.BugReportConfig, resou
 				}
 				if !config.SkipProxyDebug {
 					getFromCluster(content.GetProxyInfo, cp.SetProxyAdminPort(config.ProxyAdminPort), archive.ProxyOutputPath(tempDir, namespace, pod), &optionalWg)
+					getFromCluster(content.GetProxyCrashes, cp, filepath.Join(proxyDir, "crashes"), &optionalWg)
 				}
 				getProxyLogs(runner, config, resources, p, namespace, pod, container, &optionalWg)
 			} else {
@@ -114,7 +114,7 @@ func addFlags(cmd *cobra.Command, args *config2.BugReportConfig) {
 	cmd.PersistentFlags().BoolVar(&args.SkipAnalyze, "skip-analyze", false,
 		"Skip running istioctl analyze.")
 	cmd.PersistentFlags().BoolVar(&args.SkipProxyDebug, "skip-proxy-debug", false,
-		"Skip fetching envoy admin debug info from proxy pods.")
+		"Skip fetching envoy admin debug info and crash diagnostics from proxy pods.")
 	cmd.PersistentFlags().BoolVar(&args.SkipNetstat, "skip-netstat", false,
 		"Skip running netstat in proxy containers.")
 	cmd.PersistentFlags().BoolVar(&args.SkipCoredumps, "skip-coredumps", false,
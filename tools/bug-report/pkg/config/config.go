@@ -188,7 +188,7 @@ type BugReportConfig struct {
 	SkipClusterDump bool `json:"skipClusterDump,omitempty"`
 	// SkipAnalyze skips running istioctl analyze.
 	SkipAnalyze bool `json:"skipAnalyze,omitempty"`
-	// SkipProxyDebug skips fetching envoy admin debug info from proxy pods.
+	// SkipProxyDebug skips fetching envoy admin debug info and crash diagnostics from proxy pods.
 	SkipProxyDebug bool `json:"skipProxyDebug,omitempty"`
 	// SkipNetstat skips running netstat in proxy containers.
 	SkipNetstat bool `json:"skipNetstat,omitempty"`
@@ -15,7 +15,9 @@
 package content
 
 import (
+	"encoding/json"
 	"fmt"
+	"path"
 	"strings"
 	"time"
 
@@ -26,6 +28,7 @@ import (
 	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/local"
 	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/envoy"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/util/istiomultierror"
@@ -35,6 +38,8 @@ import (
 
 const (
 	coredumpDir = "/var/lib/istio"
+	// proxyStatusPort is the port of the status server of the agent.
+	proxyStatusPort = 15020
 )
 
 // Params contains parameters for running a kubectl fetch command.
@@ -257,6 +262,38 @@ func GetProxyInfo(p *Params) (map[string]string, error) {
 	return ret, nil
 }
 
+// GetProxyCrashes returns the crash diagnostics of Envoy kept by the agent, by bundle and file name.
+func GetProxyCrashes(p *Params) (map[string]string, error) {
+	if p.Namespace == "" || p.Pod == "" {
+		return nil, fmt.Errorf("getProxyCrashes requires namespace and pod")
+	}
+	out, err := p.Runner.EnvoyGet(p.Namespace, p.Pod, "debug/crashz", p.DryRun, proxyStatusPort)
+	if err != nil {
+		// Older agents do not keep crash diagnostics, and they may be disabled.
+		log.Warnf("Skipping the crash diagnostics of %s/%s: %v", p.Namespace, p.Pod, err)
+		return nil, nil
+	}
+	if p.DryRun {
+		return nil, nil
+	}
+	var bundles []envoy.CrashBundle
+	if err := json.Unmarshal([]byte(out), &bundles); err != nil {
+		return nil, fmt.Errorf("failed to decode the crash diagnostics of %s/%s: %v", p.Namespace, p.Pod, err)
+	}
+	ret := make(map[string]string)
+	for _, b := range bundles {
+		for _, name := range b.Files {
+			file := path.Join(b.Name, name)
+			content, err := p.Runner.EnvoyGet(p.Namespace, p.Pod, "debug/crashz/"+file, p.DryRun, proxyStatusPort)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the crash diagnostics file %s of %s/%s: %v", file, p.Namespace, p.Pod, err)
+			}
+			ret[file] = content
+		}
+	}
+	return ret, nil
+}
+
 func GetZtunnelInfo(p *Params) (map[string]string, error) {
 	if p.Namespace == "" || p.Pod == "" {
 		return nil, fmt.Errorf("getZtunnelInfo requires namespace and pod")
@@ -0,0 +1,247 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// maxJumpDepth bounds chain jumps, so a jump loop in a ruleset cannot hang the trace.
+const maxJumpDepth = 32
+
+type TargetKind int
+
+const (
+	// Continue evaluates the next rule of the chain, e.g. after setting a mark.
+	Continue TargetKind = iota
+	Jump
+	Return
+	Accept
+	Drop
+	Redirect
+	TProxy
+)
+
+// Target is what happens to a packet once all matches of a rule succeed and its actions are applied.
+type Target struct {
+	Kind TargetKind
+	// Chain is the chain jumped to.
+	Chain string
+	// Port is the port the packet is redirected to.
+	Port string
+}
+
+// Rule is a rule of a chain, parsed by a backend into matches, actions and a target.
+type Rule struct {
+	Text    string
+	Matches []Match
+	Actions []Action
+	Target  Target
+	// Unsupported holds the part of the rule that could not be parsed. Such a rule is reported and never matches.
+	Unsupported string
+}
+
+// ChainRef identifies a chain within a table.
+type ChainRef struct {
+	Table string
+	Chain string
+}
+
+func (c ChainRef) String() string {
+	return c.Table + "/" + c.Chain
+}
+
+// Ruleset is a set of chains along with the base chains a packet traverses, in order, for each direction.
+type Ruleset struct {
+	Chains map[ChainRef][]Rule
+	Hooks  map[Direction][]ChainRef
+	// Notes are reported along with every trace of the ruleset.
+	Notes []string
+}
+
+// Append adds a rule at the end of a chain.
+func (r *Ruleset) Append(chain ChainRef, rule Rule) {
+	if r.Chains == nil {
+		r.Chains = map[ChainRef][]Rule{}
+	}
+	r.Chains[chain] = append(r.Chains[chain], rule)
+}
+
+// Insert adds a rule at the given 0-based position of a chain, or at its end if the position is out of range.
+func (r *Ruleset) Insert(chain ChainRef, pos int, rule Rule) {
+	rules := r.Chains[chain]
+	if pos < 0 || pos >= len(rules) {
+		r.Append(chain, rule)
+		return
+	}
+	r.Chains[chain] = slices.Insert(rules, pos, rule)
+}
+
+type Verdict string
+
+const (
+	// VerdictRedirect means the packet is redirected to a local port, with REDIRECT or TPROXY.
+	VerdictRedirect Verdict = "redirect"
+	// VerdictReturn means the packet went through all the rules without being redirected or accepted.
+	VerdictReturn Verdict = "return"
+	// VerdictAccept means a rule explicitly accepted the packet.
+	VerdictAccept Verdict = "accept"
+	// VerdictDrop means a rule dropped the packet.
+	VerdictDrop Verdict = "drop"
+)
+
+// Step is a chain entered, or a rule evaluated, while tracing a packet.
+type Step struct {
+	Chain ChainRef
+	// Depth is the number of jumps from the base chain.
+	Depth int
+	// Rule is empty when the step enters Chain.
+	Rule    string
+	Matched bool
+	// Detail is the condition that did not match, or the actions applied by a matching rule.
+	Detail string
+}
+
+// Trace is the result of tracing a packet through a ruleset.
+type Trace struct {
+	Packet  Packet
+	Steps   []Step
+	Verdict Verdict
+	// Port is the port the packet is redirected to.
+	Port string
+	// By is the rule that decided the verdict, if any.
+	By    string
+	Notes []string
+}
+
+// Explain traces the packet through the base chains of its direction. Tracing stops once the packet
+// is redirected or dropped; an accept or return only ends the current base chain.
+func (r *Ruleset) Explain(p Packet) *Trace {
+	t := &Trace{Packet: p, Verdict: VerdictReturn, Notes: slices.Clone(r.Notes)}
+	pkt := p
+	for _, hook := range r.Hooks[p.Direction] {
+		if _, ok := r.Chains[hook]; !ok {
+			continue
+		}
+		t.Steps = append(t.Steps, Step{Chain: hook})
+		target, by := r.run(t, &pkt, hook, 0)
+		switch target.Kind {
+		case Accept:
+			t.Verdict, t.By = VerdictAccept, by
+		case Drop:
+			t.Verdict, t.By = VerdictDrop, by
+			return t
+		case Redirect, TProxy:
+			t.Verdict, t.Port, t.By = VerdictRedirect, target.Port, by
+			return t
+		}
+	}
+	return t
+}
+
+func (r *Ruleset) run(t *Trace, p *Packet, chain ChainRef, depth int) (Target, string) {
+	if depth > maxJumpDepth {
+		t.Notes = append(t.Notes, fmt.Sprintf("jump depth exceeded in %v, assuming return", chain))
+		return Target{Kind: Return}, ""
+	}
+	for _, rule := range r.Chains[chain] {
+		step := Step{Chain: chain, Depth: depth, Rule: rule.Text}
+		matched, failed := rule.matches(p)
+		switch {
+		case rule.Unsupported != "":
+			step.Detail = "not evaluated, unsupported: " + rule.Unsupported
+		case !matched:
+			step.Detail = "no match on: " + failed
+		default:
+			step.Matched = true
+			var applied []string
+			for _, a := range rule.Actions {
+				if a.Apply != nil {
+					a.Apply(p)
+				}
+				applied = append(applied, a.Text)
+			}
+			step.Detail = strings.Join(applied, ", ")
+		}
+		t.Steps = append(t.Steps, step)
+		if !step.Matched {
+			continue
+		}
+		switch rule.Target.Kind {
+		case Continue:
+			continue
+		case Jump:
+			next := ChainRef{Table: chain.Table, Chain: rule.Target.Chain}
+			t.Steps = append(t.Steps, Step{Chain: next, Depth: depth + 1})
+			target, by := r.run(t, p, next, depth+1)
+			if target.Kind != Return {
+				return target, by
+			}
+		case Return:
+			return rule.Target, rule.Text
+		default:
+			return rule.Target, chain.String() + ": " + rule.Text
+		}
+	}
+	return Target{Kind: Return}, ""
+}
+
+// matches reports whether all conditions of the rule match, and otherwise the first one that failed.
+func (r Rule) matches(p *Packet) (bool, string) {
+	if r.Unsupported != "" {
+		return false, r.Unsupported
+	}
+	for _, m := range r.Matches {
+		if !m.Matches(p) {
+			return false, m.Text
+		}
+	}
+	return true, ""
+}
+
+// Write prints the trace in a human readable form.
+func (t *Trace) Write(w io.Writer) {
+	fmt.Fprintf(w, "Packet: %v\n\n", t.Packet)
+	for _, s := range t.Steps {
+		indent := strings.Repeat("  ", s.Depth)
+		if s.Rule == "" {
+			fmt.Fprintf(w, "%s%v\n", indent, s.Chain)
+			continue
+		}
+		status := "[no match]"
+		if s.Matched {
+			status = "[match]   "
+		}
+		fmt.Fprintf(w, "%s  %s %s\n", indent, status, s.Rule)
+		if s.Detail != "" {
+			fmt.Fprintf(w, "%s             (%s)\n", indent, s.Detail)
+		}
+	}
+	fmt.Fprintln(w)
+	for _, n := range t.Notes {
+		fmt.Fprintf(w, "Note: %s\n", n)
+	}
+	switch t.Verdict {
+	case VerdictRedirect:
+		fmt.Fprintf(w, "Verdict: redirect to port %s by %s\n", t.Port, t.By)
+	case VerdictReturn:
+		fmt.Fprintln(w, "Verdict: return, the packet is not captured")
+	default:
+		fmt.Fprintf(w, "Verdict: %s by %s\n", t.Verdict, t.By)
+	}
+}
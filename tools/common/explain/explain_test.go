@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"bytes"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestParsePacket(t *testing.T) {
+	p, err := ParsePacket("direction=inbound,protocol=udp,src=10.1.1.1:1234,dst=10.0.0.5:53,mark=0x539,ctstate=established")
+	assert.NoError(t, err)
+	assert.Equal(t, p.String(), "inbound udp 10.1.1.1:1234 -> 10.0.0.5:53 iif eth0 mark 0x539 ctmark 0x0 ctstate established")
+
+	p, err = ParsePacket("dst=[::1]:80,gid=1337")
+	assert.NoError(t, err)
+	assert.Equal(t, p.Src == netip.IPv6Unspecified(), true)
+	assert.Equal(t, p.OutInterface, "lo")
+	assert.Equal(t, p.UID, "1337")
+
+	for _, desc := range []string{
+		"",
+		"src=10.0.0.1",
+		"dst=10.0.0.1,direction=sideways",
+		"dst=10.0.0.1,protocol=icmp",
+		"dst=10.0.0.1,src=::1",
+		"dst=10.0.0.1,direction=inbound,uid=1000",
+		"dst=10.0.0.1,port=80",
+	} {
+		_, err := ParsePacket(desc)
+		assert.Error(t, err)
+	}
+}
+
+func TestRulesetExplain(t *testing.T) {
+	mustPort := func(spec string, negate bool) Match {
+		m, err := MatchPort(spec, spec, false, negate)
+		assert.NoError(t, err)
+		return m
+	}
+	mustMark := func(spec string) Match {
+		m, err := MatchMark("mark "+spec, spec, false, false)
+		assert.NoError(t, err)
+		return m
+	}
+	output := ChainRef{Table: "nat", Chain: "OUTPUT"}
+	mangle := ChainRef{Table: "mangle", Chain: "OUTPUT"}
+	rs := &Ruleset{Hooks: map[Direction][]ChainRef{Outbound: {mangle, output}}}
+	rs.Append(mangle, Rule{Text: "mark", Matches: []Match{mustPort("9000", false)}, Actions: []Action{SetMark("mark set 1", 1, 0xffffffff)}})
+	rs.Append(output, Rule{Text: "jump", Target: Target{Kind: Jump, Chain: "CAPTURE"}})
+	rs.Append(output, Rule{Text: "drop", Target: Target{Kind: Drop}})
+	capture := ChainRef{Table: "nat", Chain: "CAPTURE"}
+	rs.Append(capture, Rule{Text: "redirect", Matches: []Match{mustPort("80,443", false)}, Target: Target{Kind: Redirect, Port: "15001"}})
+	rs.Append(capture, Rule{Text: "marked", Matches: []Match{mustMark("1")}, Target: Target{Kind: Accept}})
+	// Inserted ahead of the redirect, so port 15008 is never redirected.
+	rs.Insert(capture, 0, Rule{Text: "skip", Matches: []Match{mustPort("15000:15010", false)}, Target: Target{Kind: Return}})
+	rs.Append(capture, Rule{Text: "unsupported", Unsupported: "--foo"})
+
+	cases := []struct {
+		packet  string
+		verdict Verdict
+		by      string
+	}{
+		{packet: "dst=10.0.0.1:80", verdict: VerdictRedirect, by: "nat/CAPTURE: redirect"},
+		{packet: "dst=10.0.0.1:9000", verdict: VerdictAccept, by: "nat/CAPTURE: marked"},
+		{packet: "dst=10.0.0.1:15008", verdict: VerdictDrop, by: "nat/OUTPUT: drop"},
+		{packet: "dst=10.0.0.1:22", verdict: VerdictDrop, by: "nat/OUTPUT: drop"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.packet, func(t *testing.T) {
+			p, err := ParsePacket(tc.packet)
+			assert.NoError(t, err)
+			trace := rs.Explain(p)
+			assert.Equal(t, trace.Verdict, tc.verdict)
+			assert.Equal(t, trace.By, tc.by)
+		})
+	}
+
+	p, _ := ParsePacket("dst=10.0.0.1:22")
+	var out bytes.Buffer
+	rs.Explain(p).Write(&out)
+	for _, want := range []string{"nat/CAPTURE", "(not evaluated, unsupported: --foo)", "Verdict: drop by nat/OUTPUT: drop"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Match is a single condition of a rule.
+type Match struct {
+	// Text is the rule fragment the condition was parsed from.
+	Text    string
+	Matches func(p *Packet) bool
+}
+
+// Action is a non-terminating statement of a rule, applied when the rule matches.
+type Action struct {
+	Text  string
+	Apply func(p *Packet)
+}
+
+// MatchProtocol matches the L4 protocol.
+func MatchProtocol(text, proto string, negate bool) Match {
+	return Match{Text: text, Matches: func(p *Packet) bool {
+		return (p.Protocol == proto) != negate
+	}}
+}
+
+// MatchFamily matches IPv4 or IPv6 packets.
+func MatchFamily(text string, ipv6 bool) Match {
+	return Match{Text: text, Matches: func(p *Packet) bool {
+		return p.Dst.Is6() == ipv6
+	}}
+}
+
+// MatchAddr matches the source or destination address against an address or prefix.
+// A packet of the other IP family never matches, even when negated.
+func MatchAddr(text, spec string, src, negate bool) (Match, error) {
+	prefix, err := netip.ParsePrefix(spec)
+	if err != nil {
+		addr, aerr := netip.ParseAddr(spec)
+		if aerr != nil {
+			return Match{}, fmt.Errorf("invalid address %q", spec)
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return Match{Text: text, Matches: func(p *Packet) bool {
+		addr := p.Dst
+		if src {
+			addr = p.Src
+		}
+		if addr.Is6() != prefix.Addr().Is6() {
+			return false
+		}
+		return prefix.Contains(addr) != negate
+	}}, nil
+}
+
+// MatchInterface matches the input or output interface name. A trailing "+" matches any name with that prefix.
+func MatchInterface(text, name string, in, negate bool) Match {
+	return Match{Text: text, Matches: func(p *Packet) bool {
+		iface := p.OutInterface
+		if in {
+			iface = p.InInterface
+		}
+		if iface == "" {
+			return false
+		}
+		var ok bool
+		if prefix, wildcard := strings.CutSuffix(name, "+"); wildcard {
+			ok = strings.HasPrefix(iface, prefix)
+		} else {
+			ok = iface == name
+		}
+		return ok != negate
+	}}
+}
+
+// MatchPort matches the source or destination port against a port spec: a single port, a range written as
+// "a:b" or "a-b", or a list written as "a,b" or "{ a, b }". Packets other than TCP and UDP never match.
+func MatchPort(text, spec string, src, negate bool) (Match, error) {
+	type portRange struct{ from, to uint16 }
+	var ranges []portRange
+	spec = strings.Trim(spec, "{} ")
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, ":")
+		if !isRange {
+			from, to, isRange = strings.Cut(part, "-")
+		}
+		if !isRange {
+			to = from
+		}
+		f, err := strconv.ParseUint(from, 10, 16)
+		if err != nil {
+			return Match{}, fmt.Errorf("invalid port %q", part)
+		}
+		t, err := strconv.ParseUint(to, 10, 16)
+		if err != nil {
+			return Match{}, fmt.Errorf("invalid port %q", part)
+		}
+		ranges = append(ranges, portRange{uint16(f), uint16(t)})
+	}
+	return Match{Text: text, Matches: func(p *Packet) bool {
+		if p.Protocol != "tcp" && p.Protocol != "udp" {
+			return false
+		}
+		port := p.DstPort
+		if src {
+			port = p.SrcPort
+		}
+		for _, r := range ranges {
+			if port >= r.from && port <= r.to {
+				return !negate
+			}
+		}
+		return negate
+	}}, nil
+}
+
+// MatchOwner matches the UID or GID of the socket owner. noSocket is the result for packets
+// without a local socket, which differs between backends.
+func MatchOwner(text, id string, gid, negate, noSocket bool) Match {
+	return Match{Text: text, Matches: func(p *Packet) bool {
+		owner := p.UID
+		if gid {
+			owner = p.GID
+		}
+		if owner == "" {
+			return noSocket
+		}
+		return (owner == id) != negate
+	}}
+}
+
+// MatchMark matches the packet mark, or the connection mark if ct is set, against "value" or "value/mask".
+func MatchMark(text, spec string, ct, negate bool) (Match, error) {
+	value, mask, err := ParseMark(spec)
+	if err != nil {
+		return Match{}, err
+	}
+	return Match{Text: text, Matches: func(p *Packet) bool {
+		mark := p.Mark
+		if ct {
+			mark = p.CtMark
+		}
+		return (mark&mask == value) != negate
+	}}, nil
+}
+
+// MatchCtState matches the conntrack state against a comma separated list of states.
+func MatchCtState(text, states string, negate bool) Match {
+	set := strings.Split(strings.ToLower(states), ",")
+	return Match{Text: text, Matches: func(p *Packet) bool {
+		for _, s := range set {
+			if strings.TrimSpace(s) == p.CtState {
+				return !negate
+			}
+		}
+		return negate
+	}}
+}
+
+// SetMark sets the packet mark, keeping the bits outside of mask.
+func SetMark(text string, value, mask uint32) Action {
+	return Action{Text: text, Apply: func(p *Packet) {
+		p.Mark = p.Mark&^mask | value
+	}}
+}
+
+// SaveMark copies the packet mark to the connection mark.
+func SaveMark(text string) Action {
+	return Action{Text: text, Apply: func(p *Packet) { p.CtMark = p.Mark }}
+}
+
+// RestoreMark copies the connection mark to the packet mark.
+func RestoreMark(text string) Action {
+	return Action{Text: text, Apply: func(p *Packet) { p.Mark = p.CtMark }}
+}
+
+// ParseMark parses a mark written as "value" or "value/mask", in decimal or hex.
+func ParseMark(spec string) (value, mask uint32, err error) {
+	v, m, hasMask := strings.Cut(spec, "/")
+	if value, err = parseUint32(v); err != nil {
+		return 0, 0, fmt.Errorf("invalid mark %q", spec)
+	}
+	mask = 0xffffffff
+	if hasMask {
+		if mask, err = parseUint32(m); err != nil {
+			return 0, 0, fmt.Errorf("invalid mark %q", spec)
+		}
+	}
+	return value, mask, nil
+}
@@ -0,0 +1,196 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package explain traces a packet through the rules generated by istio-iptables, for either backend,
+// and reports which chains and rules it hits and what happens to it.
+package explain
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+type Direction string
+
+const (
+	// Inbound packets arrive on a pod interface and traverse the PREROUTING hook.
+	Inbound Direction = "inbound"
+	// Outbound packets are sent by a local process and traverse the OUTPUT hook.
+	Outbound Direction = "outbound"
+)
+
+// Packet describes the packet to trace through the rules.
+type Packet struct {
+	Direction Direction
+	// Protocol is the L4 protocol, "tcp" or "udp".
+	Protocol string
+	Src      netip.Addr
+	SrcPort  uint16
+	Dst      netip.Addr
+	DstPort  uint16
+	// UID and GID of the socket owner. Empty when the packet has no local socket, as is the case inbound.
+	UID string
+	GID string
+	// Mark is the packet mark (fwmark), CtMark the mark of its connection.
+	Mark   uint32
+	CtMark uint32
+	// CtState is the conntrack state: new, established, related or invalid.
+	CtState      string
+	InInterface  string
+	OutInterface string
+}
+
+// ParsePacket parses a packet description made of comma separated key=value pairs, e.g.
+// "direction=outbound,protocol=tcp,dst=10.96.0.1:80,uid=1000".
+//
+// Supported keys are direction, protocol, src, dst (an IP with an optional port), uid, gid, mark, ctmark,
+// ctstate, iif and oif. Only dst is required; interfaces default to lo for loopback addresses and eth0 otherwise,
+// and a missing uid or gid of an outbound packet defaults to the other one, or to 0.
+func ParsePacket(desc string) (Packet, error) {
+	p := Packet{Direction: Outbound, Protocol: "tcp", CtState: "new"}
+	var err error
+	for _, kv := range strings.Split(desc, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return Packet{}, fmt.Errorf("invalid packet field %q: expected key=value", kv)
+		}
+		switch key {
+		case "direction":
+			p.Direction = Direction(value)
+			if p.Direction != Inbound && p.Direction != Outbound {
+				return Packet{}, fmt.Errorf("invalid direction %q: expected %s or %s", value, Inbound, Outbound)
+			}
+		case "protocol", "proto":
+			if value != "tcp" && value != "udp" {
+				return Packet{}, fmt.Errorf("invalid protocol %q: expected tcp or udp", value)
+			}
+			p.Protocol = value
+		case "src":
+			p.Src, p.SrcPort, err = parseEndpoint(value)
+		case "dst":
+			p.Dst, p.DstPort, err = parseEndpoint(value)
+		case "uid":
+			p.UID, err = parseID(value)
+		case "gid":
+			p.GID, err = parseID(value)
+		case "mark":
+			p.Mark, err = parseUint32(value)
+		case "ctmark":
+			p.CtMark, err = parseUint32(value)
+		case "ctstate":
+			switch value {
+			case "new", "established", "related", "invalid":
+				p.CtState = value
+			default:
+				return Packet{}, fmt.Errorf("invalid ctstate %q", value)
+			}
+		case "iif":
+			p.InInterface = value
+		case "oif":
+			p.OutInterface = value
+		default:
+			return Packet{}, fmt.Errorf("unknown packet field %q", key)
+		}
+		if err != nil {
+			return Packet{}, fmt.Errorf("invalid packet field %q: %v", kv, err)
+		}
+	}
+	if !p.Dst.IsValid() {
+		return Packet{}, fmt.Errorf("packet destination (dst) is required")
+	}
+	if !p.Src.IsValid() {
+		p.Src = netip.IPv4Unspecified()
+		if p.Dst.Is6() {
+			p.Src = netip.IPv6Unspecified()
+		}
+	}
+	if p.Src.Is6() != p.Dst.Is6() {
+		return Packet{}, fmt.Errorf("packet source %v and destination %v are of different IP families", p.Src, p.Dst)
+	}
+	if p.Direction == Inbound {
+		if p.UID != "" || p.GID != "" {
+			return Packet{}, fmt.Errorf("uid and gid only apply to outbound packets")
+		}
+		if p.InInterface == "" {
+			p.InInterface = defaultInterface(p.Src)
+		}
+	} else {
+		// Locally generated packets always have an owner; a missing id defaults to the other one, or to root.
+		switch {
+		case p.UID == "" && p.GID == "":
+			p.UID, p.GID = "0", "0"
+		case p.UID == "":
+			p.UID = p.GID
+		case p.GID == "":
+			p.GID = p.UID
+		}
+		if p.OutInterface == "" {
+			p.OutInterface = defaultInterface(p.Dst)
+		}
+	}
+	return p, nil
+}
+
+func (p Packet) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s -> %s", p.Direction, p.Protocol,
+		netip.AddrPortFrom(p.Src, p.SrcPort), netip.AddrPortFrom(p.Dst, p.DstPort))
+	if p.InInterface != "" {
+		fmt.Fprintf(&sb, " iif %s", p.InInterface)
+	}
+	if p.OutInterface != "" {
+		fmt.Fprintf(&sb, " oif %s", p.OutInterface)
+	}
+	if p.UID != "" {
+		fmt.Fprintf(&sb, " uid %s", p.UID)
+	}
+	if p.GID != "" {
+		fmt.Fprintf(&sb, " gid %s", p.GID)
+	}
+	fmt.Fprintf(&sb, " mark %#x ctmark %#x ctstate %s", p.Mark, p.CtMark, p.CtState)
+	return sb.String()
+}
+
+func defaultInterface(addr netip.Addr) string {
+	if addr.IsLoopback() {
+		return "lo"
+	}
+	return "eth0"
+}
+
+func parseEndpoint(s string) (netip.Addr, uint16, error) {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr(), ap.Port(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	return addr, 0, err
+}
+
+func parseID(s string) (string, error) {
+	if _, err := strconv.ParseUint(s, 10, 32); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func parseUint32(s string) (uint32, error) {
+	v, err := strconv.ParseUint(s, 0, 32)
+	return uint32(v), err
+}
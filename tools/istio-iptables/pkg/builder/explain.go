@@ -0,0 +1,213 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/tools/common/explain"
+)
+
+// iptablesHooks lists the built-in chains traversed by locally generated and incoming packets, in table order.
+var iptablesHooks = map[explain.Direction][]explain.ChainRef{
+	explain.Outbound: {
+		{Table: "raw", Chain: "OUTPUT"},
+		{Table: "mangle", Chain: "OUTPUT"},
+		{Table: "nat", Chain: "OUTPUT"},
+		{Table: "filter", Chain: "OUTPUT"},
+		{Table: "mangle", Chain: "POSTROUTING"},
+		{Table: "nat", Chain: "POSTROUTING"},
+	},
+	explain.Inbound: {
+		{Table: "raw", Chain: "PREROUTING"},
+		{Table: "mangle", Chain: "PREROUTING"},
+		{Table: "nat", Chain: "PREROUTING"},
+		{Table: "mangle", Chain: "INPUT"},
+		{Table: "filter", Chain: "INPUT"},
+	},
+}
+
+// Explain traces the packet through the rules built so far, using the IPv4 or IPv6 rules depending on its family.
+func (rb *IptablesRuleBuilder) Explain(p explain.Packet) *explain.Trace {
+	return rb.Ruleset(p.Dst.Is6()).Explain(p)
+}
+
+// Ruleset parses the rules built so far into an explain.Ruleset.
+func (rb *IptablesRuleBuilder) Ruleset(ipv6 bool) *explain.Ruleset {
+	rs := &explain.Ruleset{Chains: map[explain.ChainRef][]explain.Rule{}, Hooks: iptablesHooks}
+	rules := rb.rules.rulesv4
+	if ipv6 {
+		rules = rb.rules.rulesv6
+		if !rb.cfg.EnableIPv6 {
+			rs.Notes = append(rs.Notes, "IPv6 is not enabled, no IPv6 rules are generated")
+		}
+	}
+	for _, r := range rules {
+		chain := explain.ChainRef{Table: r.table, Chain: r.chain}
+		if len(r.params) > 2 && r.params[0] == "-I" {
+			pos, err := strconv.Atoi(r.params[2])
+			if err != nil {
+				pos = 1
+			}
+			rs.Insert(chain, pos-1, parseRule(strings.Join(r.params, " "), r.params[3:]))
+		} else {
+			rs.Append(chain, parseRule(strings.Join(r.params, " "), r.params[2:]))
+		}
+	}
+	return rs
+}
+
+// parseRule parses iptables rule options, e.g. "-p tcp ! --dport 15008 -j ISTIO_REDIRECT".
+func parseRule(text string, params []string) explain.Rule {
+	rule := explain.Rule{Text: text}
+	negate := false
+	module := ""
+	for i := 0; i < len(params); i++ {
+		opt := params[i]
+		if opt == "!" {
+			negate = true
+			continue
+		}
+		if opt == "-j" || opt == "--jump" {
+			if err := parseTarget(&rule, params[i+1:]); err != nil {
+				rule.Unsupported = err.Error()
+			}
+			return rule
+		}
+		if i+1 >= len(params) {
+			rule.Unsupported = opt
+			return rule
+		}
+		i++
+		value := params[i]
+		frag := opt + " " + value
+		if negate {
+			frag = "! " + frag
+		}
+		var m explain.Match
+		var err error
+		switch opt {
+		case "-m", "--match":
+			module = value
+			continue
+		case "-p", "--protocol":
+			m = explain.MatchProtocol(frag, value, negate)
+		case "-s", "--source":
+			m, err = explain.MatchAddr(frag, value, true, negate)
+		case "-d", "--destination":
+			m, err = explain.MatchAddr(frag, value, false, negate)
+		case "-i", "--in-interface":
+			m = explain.MatchInterface(frag, value, true, negate)
+		case "-o", "--out-interface":
+			m = explain.MatchInterface(frag, value, false, negate)
+		case "--sport", "--source-port", "--sports":
+			m, err = explain.MatchPort(frag, value, true, negate)
+		case "--dport", "--destination-port", "--dports":
+			m, err = explain.MatchPort(frag, value, false, negate)
+		case "--uid-owner":
+			// The owner match only succeeds on packets without a local socket when negated.
+			m = explain.MatchOwner(frag, value, false, negate, negate)
+		case "--gid-owner":
+			m = explain.MatchOwner(frag, value, true, negate, negate)
+		case "--mark":
+			m, err = explain.MatchMark(frag, value, module == "connmark", negate)
+		case "--ctstate", "--state":
+			m = explain.MatchCtState(frag, value, negate)
+		default:
+			err = fmt.Errorf("%s", opt)
+		}
+		if err != nil {
+			rule.Unsupported = err.Error()
+			return rule
+		}
+		rule.Matches = append(rule.Matches, m)
+		negate = false
+	}
+	// Rules without a target only count packets.
+	return rule
+}
+
+// parseTarget parses a target along with its options, e.g. "REDIRECT --to-ports 15001".
+func parseTarget(rule *explain.Rule, params []string) error {
+	if len(params) == 0 {
+		return fmt.Errorf("missing target")
+	}
+	target, opts := params[0], params[1:]
+	option := func(names ...string) (string, bool) {
+		for i := 0; i+1 < len(opts); i++ {
+			for _, n := range names {
+				if opts[i] == n {
+					return opts[i+1], true
+				}
+			}
+		}
+		return "", false
+	}
+	switch target {
+	case "RETURN":
+		rule.Target = explain.Target{Kind: explain.Return}
+	case "ACCEPT":
+		rule.Target = explain.Target{Kind: explain.Accept}
+	case "DROP":
+		rule.Target = explain.Target{Kind: explain.Drop}
+	case "REDIRECT":
+		port, ok := option("--to-ports", "--to-port")
+		if !ok {
+			return fmt.Errorf("REDIRECT without --to-ports")
+		}
+		rule.Target = explain.Target{Kind: explain.Redirect, Port: port}
+	case "TPROXY":
+		port, ok := option("--on-port")
+		if !ok {
+			return fmt.Errorf("TPROXY without --on-port")
+		}
+		if mark, ok := option("--tproxy-mark"); ok {
+			value, mask, err := explain.ParseMark(mark)
+			if err != nil {
+				return err
+			}
+			rule.Actions = append(rule.Actions, explain.SetMark("mark set to "+mark, value, mask))
+		}
+		rule.Target = explain.Target{Kind: explain.TProxy, Port: port}
+	case "MARK":
+		mark, ok := option("--set-mark", "--set-xmark")
+		if !ok {
+			return fmt.Errorf("MARK without --set-mark")
+		}
+		value, mask, err := explain.ParseMark(mark)
+		if err != nil {
+			return err
+		}
+		rule.Actions = append(rule.Actions, explain.SetMark("mark set to "+mark, value, mask))
+	case "CONNMARK":
+		switch {
+		case len(opts) > 0 && opts[0] == "--save-mark":
+			rule.Actions = append(rule.Actions, explain.SaveMark("mark saved to connection"))
+		case len(opts) > 0 && opts[0] == "--restore-mark":
+			rule.Actions = append(rule.Actions, explain.RestoreMark("mark restored from connection"))
+		default:
+			return fmt.Errorf("CONNMARK %s", strings.Join(opts, " "))
+		}
+	case "CT":
+		// Conntrack zones do not affect the rules a packet hits.
+		zone, _ := option("--zone")
+		rule.Actions = append(rule.Actions, explain.Action{Text: "conntrack zone set to " + zone})
+	default:
+		rule.Target = explain.Target{Kind: explain.Jump, Chain: target}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/tools/common/config"
+	"istio.io/istio/tools/common/explain"
+	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
+)
+
+func TestExplain(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  func(cfg *config.Config)
+		packet  string
+		verdict explain.Verdict
+		port    string
+	}{
+		{
+			name:    "outbound captured",
+			config:  func(cfg *config.Config) { cfg.OutboundIPRangesInclude = "*" },
+			packet:  "dst=10.96.0.1:80,uid=1000",
+			verdict: explain.VerdictRedirect,
+			port:    "15001",
+		},
+		{
+			name:    "outbound from proxy",
+			config:  func(cfg *config.Config) { cfg.OutboundIPRangesInclude = "*" },
+			packet:  "dst=10.96.0.1:80,uid=1337",
+			verdict: explain.VerdictReturn,
+		},
+		{
+			name: "outbound excluded range",
+			config: func(cfg *config.Config) {
+				cfg.OutboundIPRangesInclude = "*"
+				cfg.OutboundIPRangesExclude = "10.96.0.0/16"
+			},
+			packet:  "dst=10.96.0.1:80,uid=1000",
+			verdict: explain.VerdictReturn,
+		},
+		{
+			name: "outbound dns",
+			config: func(cfg *config.Config) {
+				cfg.RedirectDNS = true
+				cfg.DNSServersV4 = []string{"10.96.0.10"}
+			},
+			packet:  "protocol=udp,dst=10.96.0.10:53,uid=1000",
+			verdict: explain.VerdictRedirect,
+			port:    "15053",
+		},
+		{
+			name:    "inbound captured",
+			config:  func(cfg *config.Config) { cfg.InboundPortsInclude = "*" },
+			packet:  "direction=inbound,src=10.1.1.1:40000,dst=10.0.0.5:8080",
+			verdict: explain.VerdictRedirect,
+			port:    "15006",
+		},
+		{
+			name:    "inbound tunnel port",
+			config:  func(cfg *config.Config) { cfg.InboundPortsInclude = "*" },
+			packet:  "direction=inbound,src=10.1.1.1:40000,dst=10.0.0.5:15008",
+			verdict: explain.VerdictReturn,
+		},
+		{
+			name: "inbound tproxy",
+			config: func(cfg *config.Config) {
+				cfg.InboundPortsInclude = "*"
+				cfg.InboundInterceptionMode = "TPROXY"
+			},
+			packet:  "direction=inbound,src=10.1.1.1:40000,dst=10.0.0.5:8080",
+			verdict: explain.VerdictRedirect,
+			port:    "15006",
+		},
+		{
+			name:    "ipv6 disabled",
+			config:  func(cfg *config.Config) { cfg.OutboundIPRangesInclude = "*" },
+			packet:  "dst=[fd00::1]:80,uid=1000",
+			verdict: explain.VerdictReturn,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := constructTestConfig()
+			tc.config(cfg)
+			packet, err := explain.ParsePacket(tc.packet)
+			assert.NoError(t, err)
+			iptConfigurator, err := NewIptablesConfigurator(cfg, &dep.DependenciesStub{})
+			assert.NoError(t, err)
+			trace, err := iptConfigurator.Explain(packet)
+			assert.NoError(t, err)
+			assert.Equal(t, trace.Verdict, tc.verdict)
+			assert.Equal(t, trace.Port, tc.port)
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/tools/common/config"
+	"istio.io/istio/tools/common/explain"
 	"istio.io/istio/tools/istio-iptables/pkg/builder"
 	"istio.io/istio/tools/istio-iptables/pkg/constants"
 	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
@@ -216,6 +217,23 @@ func (cfg *IptablesConfigurator) Run() error {
 		}
 	}()
 
+	cfg.logConfig()
+	if err := cfg.buildRules(); err != nil {
+		return err
+	}
+	return cfg.executeCommands(&cfg.iptV, &cfg.ipt6V)
+}
+
+// Explain builds the rules for the configuration without applying them, and traces the given packet through them.
+func (cfg *IptablesConfigurator) Explain(packet explain.Packet) (*explain.Trace, error) {
+	if err := cfg.buildRules(); err != nil {
+		return nil, err
+	}
+	return cfg.ruleBuilder.Explain(packet), nil
+}
+
+// buildRules populates the rule builder with all the rules for the configuration.
+func (cfg *IptablesConfigurator) buildRules() error {
 	// Since OUTBOUND_IP_RANGES_EXCLUDE could carry ipv4 and ipv6 ranges
 	// need to split them in different arrays one for ipv4 and one for ipv6
 	// in order to not to fail
@@ -240,8 +258,6 @@ func (cfg *IptablesConfigurator) Run() error {
 		redirectDNS = false
 	}
 
-	cfg.logConfig()
-
 	cfg.shortCircuitExcludeInterfaces()
 
 	// Do not capture internal interface.
@@ -444,7 +460,7 @@ func (cfg *IptablesConfigurator) Run() error {
 		cfg.ruleBuilder.InsertRule(constants.ISTIOINBOUND, "mangle", 3,
 			"-p", "tcp", "-i", "lo", "-m", "mark", "!", "--mark", constants.OutboundMark, "-j", "RETURN")
 	}
-	return cfg.executeCommands(&cfg.iptV, &cfg.ipt6V)
+	return nil
 }
 
 // SetupDNSRedir is a helper function to tackle with DNS UDP specific operations.
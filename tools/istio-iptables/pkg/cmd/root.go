@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -23,6 +24,7 @@ import (
 	"istio.io/istio/pkg/flag"
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/tools/common/config"
+	"istio.io/istio/tools/common/explain"
 	"istio.io/istio/tools/common/tproxy"
 	"istio.io/istio/tools/istio-iptables/pkg/capture"
 	"istio.io/istio/tools/istio-iptables/pkg/constants"
@@ -157,6 +159,7 @@ func bindCmdlineFlags(cfg *config.Config, cmd *cobra.Command) {
 
 func GetCommand(logOpts *log.Options) *cobra.Command {
 	cfg := config.DefaultConfig()
+	var explainPacket string
 	cmd := &cobra.Command{
 		Use:   "istio-iptables",
 		Short: "Set up iptables rules for Istio Sidecar",
@@ -174,6 +177,12 @@ func GetCommand(logOpts *log.Options) *cobra.Command {
 			if err := cfg.Validate(); err != nil {
 				handleErrorWithCode(err, 1)
 			}
+			if explainPacket != "" {
+				if err := explainRules(cfg, explainPacket, os.Stdout); err != nil {
+					handleErrorWithCode(err, 1)
+				}
+				return
+			}
 			runMethod := ProgramIptables
 
 			// If nftables is enabled, use nft rules for traffic redirection.
@@ -188,23 +197,60 @@ func GetCommand(logOpts *log.Options) *cobra.Command {
 			if cfg.RunValidation {
 				validator := validation.NewValidator(cfg)
 
-				if err := validator.Run(); err != nil {
+				if results, err := validator.Run(); err != nil {
+					writeTerminationMessage(results.TerminationMessage())
 					// nolint: revive, stylecheck
-					msg := fmt.Errorf(`iptables validation failed; workload is not ready for Istio.
+					msg := fmt.Errorf(`iptables validation failed for %s; workload is not ready for Istio.
 When using Istio CNI, this can occur if a pod is scheduled before the node is ready.
 
 If installed with 'cni.repair.deletePods=true', this pod should automatically be deleted and retry.
 Otherwise, this pod will need to be manually removed so that it is scheduled on a node with istio-cni running, allowing iptables rules to be established.
-`)
+`, validation.FormatFamilies(results.Failed()))
 					handleErrorWithCode(msg, constants.ValidationErrorCode)
 				}
 			}
 		},
 	}
 	bindCmdlineFlags(cfg, cmd)
+	flag.Bind(cmd.Flags(), constants.Explain, "",
+		"Instead of applying the rules, trace a packet through them and print the chains and rules it hits and the final verdict. "+
+			"The packet is described as comma separated key=value pairs, e.g. \"direction=outbound,protocol=tcp,dst=10.96.0.1:80,uid=1000\". "+
+			"Supported keys are direction (inbound or outbound), protocol, src, dst, uid, gid, mark, ctmark, ctstate, iif and oif.",
+		&explainPacket)
 	return cmd
 }
 
+// writeTerminationMessage reports the validation results through the termination message of the container, so the
+// CNI repair controller knows which IP families failed. Nothing is written outside of a Kubernetes container.
+func writeTerminationMessage(msg string) {
+	f, err := os.OpenFile(constants.TerminationMessagePath, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(msg); err != nil {
+		log.Warnf("failed to write termination message: %v", err)
+	}
+}
+
+// explainRules builds the rules for the configuration with the selected backend and traces the packet through them.
+func explainRules(cfg *config.Config, desc string, w io.Writer) error {
+	packet, err := explain.ParsePacket(desc)
+	if err != nil {
+		return err
+	}
+	explainMethod := ExplainIptables
+	if cfg.NativeNftables {
+		explainMethod = nftables.ExplainNftables
+	}
+	trace, err := explainMethod(cfg, packet)
+	if err != nil {
+		return err
+	}
+	trace.Write(w)
+	return nil
+}
+
 type IptablesError struct {
 	Error    error
 	ExitCode int
@@ -237,3 +283,12 @@ func ProgramIptables(cfg *config.Config) error {
 	}
 	return nil
 }
+
+// ExplainIptables traces the packet through the iptables rules for the configuration, without applying them.
+func ExplainIptables(cfg *config.Config, packet explain.Packet) (*explain.Trace, error) {
+	iptConfigurator, err := capture.NewIptablesConfigurator(cfg, &dep.DependenciesStub{})
+	if err != nil {
+		return nil, err
+	}
+	return iptConfigurator.Explain(packet)
+}
@@ -89,6 +89,7 @@ const (
 	ForceApply                = "force-apply"
 	NativeNftables            = "native-nftables"
 	ForceIptablesBinary       = "force-iptables-binary"
+	Explain                   = "explain"
 )
 
 // Environment variables that deliberately have no equivalent command-line flags.
@@ -141,6 +142,8 @@ const (
 const (
 	ValidationContainerName = "istio-validation"
 	ValidationErrorCode     = 126
+	// TerminationMessagePath is where the validation results are written, the default Kubernetes termination message path.
+	TerminationMessagePath = "/dev/termination-log"
 )
 
 // DNS ports
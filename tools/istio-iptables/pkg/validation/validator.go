@@ -22,6 +22,7 @@ import (
 	"net/netip"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"istio.io/istio/pkg/log"
@@ -35,10 +36,12 @@ const (
 )
 
 type Validator struct {
-	Config *Config
+	// Configs holds the configuration of the check of each IP family, run concurrently.
+	Configs []*Config
 }
 
 type Config struct {
+	Family              Family
 	ServerListenAddress []string
 	ServerOriginalPort  uint16
 	ServerOriginalIP    netip.Addr
@@ -54,11 +57,123 @@ type Client struct {
 	Config *Config
 }
 
-func (validator *Validator) Run() error {
+// Family is an IP family whose redirection is validated.
+type Family string
+
+const (
+	IPv4 Family = "IPv4"
+	IPv6 Family = "IPv6"
+)
+
+// Result is the outcome of the validation of an IP family.
+type Result struct {
+	Family Family
+	Err    error
+}
+
+type Results []Result
+
+// Failed returns the families whose validation failed.
+func (r Results) Failed() []Family {
+	var failed []Family
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res.Family)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing the families whose validation failed, or nil if all passed.
+func (r Results) Err() error {
+	var errs []string
+	for _, res := range r {
+		if res.Err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", res.Family, res.Err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("validation failed for %s", strings.Join(errs, "; "))
+}
+
+// terminationMessagePrefix starts the termination message of a failed validation, see TerminationMessage.
+const terminationMessagePrefix = "redirection validation failed for: "
+
+// TerminationMessage summarizes the results in the form parsed by ParseFailedFamilies,
+// e.g. "redirection validation failed for: IPv6; passed: IPv4". It is empty if all families passed.
+func (r Results) TerminationMessage() string {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return ""
+	}
+	var passed []string
+	for _, res := range r {
+		if res.Err == nil {
+			passed = append(passed, string(res.Family))
+		}
+	}
+	msg := terminationMessagePrefix + FormatFamilies(failed)
+	if len(passed) > 0 {
+		msg += "; passed: " + strings.Join(passed, ", ")
+	}
+	return msg
+}
+
+// ParseFailedFamilies returns the families reported as failed by a termination message written from
+// Results.TerminationMessage, or nil if the message is not such a summary.
+func ParseFailedFamilies(msg string) []Family {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(msg), terminationMessagePrefix)
+	if !ok {
+		return nil
+	}
+	rest, _, _ = strings.Cut(rest, ";")
+	var failed []Family
+	for _, f := range strings.Split(rest, ",") {
+		switch fam := Family(strings.TrimSpace(f)); fam {
+		case IPv4, IPv6:
+			failed = append(failed, fam)
+		}
+	}
+	return failed
+}
+
+// FormatFamilies joins families for display, e.g. "IPv4, IPv6".
+func FormatFamilies(families []Family) string {
+	s := make([]string, 0, len(families))
+	for _, f := range families {
+		s = append(s, string(f))
+	}
+	return strings.Join(s, ", ")
+}
+
+// Run validates the redirection of every configured IP family and returns the result of each of them,
+// along with an error if any failed.
+func (validator *Validator) Run() (Results, error) {
 	log.Infof("Starting iptables validation. This check verifies that iptables rules are properly established for the network.")
-	s := Service{
-		validator.Config,
+	results := make(Results, len(validator.Configs))
+	var wg sync.WaitGroup
+	for i, cfg := range validator.Configs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = Result{Family: cfg.Family, Err: runFamily(cfg)}
+		}()
 	}
+	wg.Wait()
+	for _, res := range results {
+		if res.Err == nil {
+			log.Infof("%s validation passed, iptables rules established", res.Family)
+		} else {
+			log.Errorf("%s validation failed: %v", res.Family, res.Err)
+		}
+	}
+	return results, results.Err()
+}
+
+func runFamily(cfg *Config) error {
+	s := Service{cfg}
 	sError := make(chan error, 1)
 	sTimer := time.NewTimer(s.Config.ProbeTimeout)
 	defer sTimer.Stop()
@@ -68,7 +183,7 @@ func (validator *Validator) Run() error {
 
 	// infinite loop
 	go func() {
-		c := Client{Config: validator.Config}
+		c := Client{Config: cfg}
 		<-c.Config.ServerReadyBarrier
 		for {
 			_ = c.Run()
@@ -82,11 +197,6 @@ func (validator *Validator) Run() error {
 	case <-sTimer.C:
 		return fmt.Errorf("validation timeout")
 	case err := <-sError:
-		if err == nil {
-			log.Info("Validation passed, iptables rules established")
-		} else {
-			log.Errorf("Validation failed: %v", err)
-		}
 		return err
 	}
 }
@@ -100,26 +210,41 @@ func genListenerAddress(ip netip.Addr, ports []string) []string {
 	return addresses
 }
 
+// validatedFamilies returns the IP families whose redirection is validated: IPv4 unless the pod is IPv6-only,
+// and IPv6 when IPv6 is enabled, so both are checked in dual-stack pods.
+func validatedFamilies(config *config.Config) []Family {
+	var families []Family
+	if !config.HostIP.Is6() || config.DualStack {
+		families = append(families, IPv4)
+	}
+	if config.EnableIPv6 || config.HostIP.Is6() {
+		families = append(families, IPv6)
+	}
+	return families
+}
+
 func NewValidator(config *config.Config) *Validator {
-	// It's tricky here:
-	// Connect to 127.0.0.6 will redirect to 127.0.0.1
-	// Connect to ::6       will redirect to ::1
-	isIPv6 := config.HostIP.Is6()
-	listenIP, _ := netip.AddrFromSlice([]byte{127, 0, 0, 1})
-	serverIP, _ := netip.AddrFromSlice([]byte{127, 0, 0, 6})
-	if isIPv6 {
-		listenIP, _ = netip.AddrFromSlice([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
-		serverIP, _ = netip.AddrFromSlice([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 6})
-	}
-	return &Validator{
-		Config: &Config{
+	v := &Validator{}
+	for _, family := range validatedFamilies(config) {
+		// It's tricky here:
+		// Connect to 127.0.0.6 will redirect to 127.0.0.1
+		// Connect to ::6       will redirect to ::1
+		listenIP, _ := netip.AddrFromSlice([]byte{127, 0, 0, 1})
+		serverIP, _ := netip.AddrFromSlice([]byte{127, 0, 0, 6})
+		if family == IPv6 {
+			listenIP, _ = netip.AddrFromSlice([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+			serverIP, _ = netip.AddrFromSlice([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 6})
+		}
+		v.Configs = append(v.Configs, &Config{
+			Family:              family,
 			ServerListenAddress: genListenerAddress(listenIP, []string{config.ProxyPort, config.InboundCapturePort}),
 			ServerOriginalPort:  config.IptablesProbePort,
 			ServerOriginalIP:    serverIP,
 			ServerReadyBarrier:  make(chan ReturnCode, 1),
 			ProbeTimeout:        config.ProbeTimeout,
-		},
+		})
 	}
+	return v
 }
 
 // Write human readable response
@@ -159,8 +284,8 @@ func restoreOriginalAddress(l net.Listener, config *Config, c chan<- ReturnCode)
 }
 
 func (s *Service) Run() error {
-	// at most 2 message: ipv4 and ipv6
-	c := make(chan ReturnCode, 2)
+	// at most one message per listener
+	c := make(chan ReturnCode, len(s.Config.ServerListenAddress))
 	hasAtLeastOneListener := false
 	for _, addr := range s.Config.ServerListenAddress {
 		log.Infof("Listening on %v", addr)
@@ -177,7 +302,7 @@ func (s *Service) Run() error {
 	}
 	if hasAtLeastOneListener {
 		s.Config.ServerReadyBarrier <- DONE
-		// bump at least one since we currently support either v4 or v6
+		// one redirected connection is enough, the listeners are all of the same family
 		<-c
 		return nil
 	}
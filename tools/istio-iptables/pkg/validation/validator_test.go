@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/tools/common/config"
+)
+
+func TestNewValidatorFamilies(t *testing.T) {
+	cases := []struct {
+		name      string
+		hostIP    string
+		ipv6      bool
+		dualStack bool
+		want      []Family
+		wantIPs   []string
+	}{
+		{name: "IPv4", hostIP: "10.0.0.1", want: []Family{IPv4}, wantIPs: []string{"127.0.0.6"}},
+		{name: "IPv6-only", hostIP: "fd00::1", ipv6: true, want: []Family{IPv6}, wantIPs: []string{"::6"}},
+		{
+			name:      "dual-stack",
+			hostIP:    "10.0.0.1",
+			ipv6:      true,
+			dualStack: true,
+			want:      []Family{IPv4, IPv6},
+			wantIPs:   []string{"127.0.0.6", "::6"},
+		},
+		{name: "dual-stack without IPv6 address", hostIP: "10.0.0.1", dualStack: true, want: []Family{IPv4}, wantIPs: []string{"127.0.0.6"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(&config.Config{
+				HostIP:             netip.MustParseAddr(tt.hostIP),
+				EnableIPv6:         tt.ipv6,
+				DualStack:          tt.dualStack,
+				ProxyPort:          "15001",
+				InboundCapturePort: "15006",
+				IptablesProbePort:  15002,
+			})
+			var families []Family
+			var ips []string
+			for _, c := range v.Configs {
+				families = append(families, c.Family)
+				ips = append(ips, c.ServerOriginalIP.String())
+			}
+			assert.Equal(t, families, tt.want)
+			assert.Equal(t, ips, tt.wantIPs)
+		})
+	}
+}
+
+func TestResults(t *testing.T) {
+	passed := Results{{Family: IPv4}, {Family: IPv6}}
+	assert.NoError(t, passed.Err())
+	assert.Equal(t, passed.TerminationMessage(), "")
+	assert.Equal(t, len(ParseFailedFamilies(passed.TerminationMessage())), 0)
+
+	ipv6Failed := Results{{Family: IPv4}, {Family: IPv6, Err: fmt.Errorf("validation timeout")}}
+	assert.Error(t, ipv6Failed.Err())
+	assert.Equal(t, ipv6Failed.TerminationMessage(), "redirection validation failed for: IPv6; passed: IPv4")
+	assert.Equal(t, ParseFailedFamilies(ipv6Failed.TerminationMessage()), []Family{IPv6})
+
+	allFailed := Results{{Family: IPv4, Err: fmt.Errorf("timeout")}, {Family: IPv6, Err: fmt.Errorf("timeout")}}
+	assert.Equal(t, allFailed.TerminationMessage(), "redirection validation failed for: IPv4, IPv6")
+	assert.Equal(t, ParseFailedFamilies(allFailed.TerminationMessage()), []Family{IPv4, IPv6})
+
+	assert.Equal(t, len(ParseFailedFamilies("Died for some reason")), 0)
+}
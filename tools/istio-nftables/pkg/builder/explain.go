@@ -0,0 +1,306 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/tools/common/explain"
+	"istio.io/istio/tools/istio-nftables/pkg/constants"
+)
+
+// nftablesHooks lists the base chains traversed by locally generated and incoming packets, in priority order.
+var nftablesHooks = map[explain.Direction][]explain.ChainRef{
+	explain.Outbound: {
+		{Table: constants.IstioProxyRawTable, Chain: constants.OutputChain},
+		{Table: constants.IstioProxyMangleTable, Chain: constants.OutputChain},
+		{Table: constants.IstioProxyNatTable, Chain: constants.OutputChain},
+	},
+	explain.Inbound: {
+		{Table: constants.IstioProxyRawTable, Chain: constants.PreroutingChain},
+		{Table: constants.IstioProxyMangleTable, Chain: constants.PreroutingChain},
+		{Table: constants.IstioProxyNatTable, Chain: constants.PreroutingChain},
+	},
+}
+
+// Explain traces the packet through the rules built so far.
+func (rb *NftablesRuleBuilder) Explain(p explain.Packet) *explain.Trace {
+	return rb.Ruleset().Explain(p)
+}
+
+// Ruleset parses the rules built so far into an explain.Ruleset.
+func (rb *NftablesRuleBuilder) Ruleset() *explain.Ruleset {
+	rs := &explain.Ruleset{Chains: map[explain.ChainRef][]explain.Rule{}, Hooks: nftablesHooks}
+	for _, table := range IstioTableNames {
+		for _, r := range rb.Rules[table] {
+			chain := explain.ChainRef{Table: r.Table, Chain: r.Chain}
+			// Inserts past the end of a chain are applied as appends, see addIstioTableRules.
+			if r.Index != nil {
+				rs.Insert(chain, *r.Index, parseRule(r.Rule))
+			} else {
+				rs.Append(chain, parseRule(r.Rule))
+			}
+		}
+	}
+	return rs
+}
+
+// parseRule parses the subset of the nftables rule syntax used by the rule builder,
+// e.g. "meta l4proto tcp tcp dport != 15008 counter jump istio-redirect".
+func parseRule(text string) explain.Rule {
+	rule := explain.Rule{Text: text}
+	tokens := strings.Fields(text)
+	if err := parseTokens(&rule, tokens); err != nil {
+		rule.Unsupported = err.Error()
+	}
+	return rule
+}
+
+func parseTokens(rule *explain.Rule, tokens []string) error {
+	i := 0
+	next := func() (string, error) {
+		if i >= len(tokens) {
+			return "", fmt.Errorf("unexpected end of rule")
+		}
+		t := tokens[i]
+		i++
+		return t, nil
+	}
+	// operand reads an optional "!=" followed by a value, which may be a set such as "{ 53, 15008 }".
+	operand := func() (string, bool, error) {
+		negate := false
+		if i < len(tokens) && tokens[i] == "!=" {
+			negate = true
+			i++
+		}
+		v, err := next()
+		if err != nil || v != "{" {
+			return strings.Trim(v, `"`), negate, err
+		}
+		var set []string
+		for {
+			v, err := next()
+			if err != nil {
+				return "", false, err
+			}
+			if v == "}" {
+				return strings.Join(set, ","), negate, nil
+			}
+			set = append(set, strings.TrimSuffix(v, ","))
+		}
+	}
+	fragment := func(start int) string {
+		return strings.Join(tokens[start:i], " ")
+	}
+	for i < len(tokens) {
+		start := i
+		tok, _ := next()
+		var m explain.Match
+		var err error
+		switch tok {
+		case "counter", "meta":
+			// "meta" only prefixes keys such as l4proto, mark and skuid.
+			continue
+		case "l4proto":
+			v, negate, oerr := operand()
+			if oerr != nil {
+				return oerr
+			}
+			m = explain.MatchProtocol(fragment(start), v, negate)
+		case "ip", "ip6":
+			field, ferr := next()
+			if ferr != nil {
+				return ferr
+			}
+			v, negate, oerr := operand()
+			if oerr != nil {
+				return oerr
+			}
+			switch field {
+			case "saddr":
+				m, err = explain.MatchAddr(fragment(start), v, true, negate)
+			case "daddr":
+				m, err = explain.MatchAddr(fragment(start), v, false, negate)
+			default:
+				return fmt.Errorf("%s %s", tok, field)
+			}
+		case "tcp", "udp":
+			field, ferr := next()
+			if ferr != nil {
+				return ferr
+			}
+			v, negate, oerr := operand()
+			if oerr != nil {
+				return oerr
+			}
+			// A port match implies the protocol.
+			rule.Matches = append(rule.Matches, explain.MatchProtocol(fragment(start), tok, false))
+			switch field {
+			case "sport":
+				m, err = explain.MatchPort(fragment(start), v, true, negate)
+			case "dport":
+				m, err = explain.MatchPort(fragment(start), v, false, negate)
+			default:
+				return fmt.Errorf("%s %s", tok, field)
+			}
+		case "iifname", "oifname":
+			v, negate, oerr := operand()
+			if oerr != nil {
+				return oerr
+			}
+			// nftables uses "*" as a wildcard where iptables uses "+".
+			m = explain.MatchInterface(fragment(start), strings.Replace(v, "*", "+", 1), tok == "iifname", negate)
+		case "skuid", "skgid":
+			v, negate, oerr := operand()
+			if oerr != nil {
+				return oerr
+			}
+			// skuid and skgid never match packets without a local socket, negated or not.
+			m = explain.MatchOwner(fragment(start), v, tok == "skgid", negate, false)
+		case "mark":
+			if i < len(tokens) && tokens[i] == "set" {
+				i++
+				if err := parseSetMark(rule, tokens, &i, fragment); err != nil {
+					return err
+				}
+				continue
+			}
+			v, negate, oerr := operand()
+			if oerr != nil {
+				return oerr
+			}
+			m, err = explain.MatchMark(fragment(start), v, false, negate)
+		case "ct":
+			key, kerr := next()
+			if kerr != nil {
+				return kerr
+			}
+			switch {
+			case key == "state":
+				v, negate, oerr := operand()
+				if oerr != nil {
+					return oerr
+				}
+				m = explain.MatchCtState(fragment(start), v, negate)
+			case key == "mark" && i < len(tokens) && tokens[i] == "set":
+				i++
+				v, verr := next()
+				if verr != nil || v != "mark" {
+					return fmt.Errorf("%s", fragment(start))
+				}
+				rule.Actions = append(rule.Actions, explain.SaveMark(fragment(start)))
+				continue
+			case key == "mark":
+				v, negate, oerr := operand()
+				if oerr != nil {
+					return oerr
+				}
+				m, err = explain.MatchMark(fragment(start), v, true, negate)
+			case key == "zone":
+				// Conntrack zones do not affect the rules a packet hits.
+				if _, err := next(); err != nil {
+					return err
+				}
+				if _, err := next(); err != nil {
+					return err
+				}
+				rule.Actions = append(rule.Actions, explain.Action{Text: fragment(start)})
+				continue
+			default:
+				return fmt.Errorf("ct %s", key)
+			}
+		case "jump":
+			chain, cerr := next()
+			if cerr != nil {
+				return cerr
+			}
+			rule.Target = explain.Target{Kind: explain.Jump, Chain: chain}
+			continue
+		case "return":
+			rule.Target = explain.Target{Kind: explain.Return}
+			continue
+		case "accept":
+			// tproxy is a statement followed by an explicit accept, keep reporting it as a redirect.
+			if rule.Target.Kind != explain.TProxy {
+				rule.Target = explain.Target{Kind: explain.Accept}
+			}
+			continue
+		case "drop":
+			rule.Target = explain.Target{Kind: explain.Drop}
+			continue
+		case "redirect":
+			if i < len(tokens) && tokens[i] == "to" {
+				i++
+			}
+			port, perr := next()
+			if perr != nil {
+				return perr
+			}
+			rule.Target = explain.Target{Kind: explain.Redirect, Port: strings.TrimPrefix(port, ":")}
+			continue
+		case "tproxy":
+			family, ferr := next()
+			if ferr != nil {
+				return ferr
+			}
+			if family == "ip" || family == "ip6" {
+				rule.Matches = append(rule.Matches, explain.MatchFamily(fragment(start), family == "ip6"))
+				if _, err := next(); err != nil {
+					return err
+				}
+			} else if family != "to" {
+				return fmt.Errorf("tproxy %s", family)
+			}
+			port, perr := next()
+			if perr != nil {
+				return perr
+			}
+			rule.Target = explain.Target{Kind: explain.TProxy, Port: strings.TrimPrefix(port, ":")}
+			continue
+		default:
+			return fmt.Errorf("%s", tok)
+		}
+		if err != nil {
+			return err
+		}
+		rule.Matches = append(rule.Matches, m)
+	}
+	return nil
+}
+
+// parseSetMark parses the value of "meta mark set", either a mark or "ct mark".
+func parseSetMark(rule *explain.Rule, tokens []string, i *int, fragment func(int) string) error {
+	start := *i - 2
+	if *i >= len(tokens) {
+		return fmt.Errorf("unexpected end of rule")
+	}
+	v := tokens[*i]
+	*i++
+	if v == "ct" {
+		if *i >= len(tokens) || tokens[*i] != "mark" {
+			return fmt.Errorf("mark set ct")
+		}
+		*i++
+		rule.Actions = append(rule.Actions, explain.RestoreMark(fragment(start)))
+		return nil
+	}
+	value, mask, err := explain.ParseMark(v)
+	if err != nil {
+		return err
+	}
+	rule.Actions = append(rule.Actions, explain.SetMark(fragment(start), value, mask))
+	return nil
+}
@@ -15,6 +15,10 @@ package builder
 
 import (
 	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
 
 	"sigs.k8s.io/knftables"
 
@@ -22,18 +26,22 @@ import (
 )
 
 // NftablesAPI defines the interface for interacting with nftables.
-// It supports creating a transaction, running it, listing elements, and optionally dumping the config (mainly for testing).
+// It supports creating a transaction, running it, listing elements and rules, and optionally dumping the config (mainly for testing).
 type NftablesAPI interface {
 	NewTransaction() *knftables.Transaction
 	Run(ctx context.Context, tx *knftables.Transaction) error
 	Dump(tx *knftables.Transaction) string
 	// ListElements returns a list of the elements in a set or map. (objectType should be "set" or "map".)
 	ListElements(ctx context.Context, objectType, name string) ([]*knftables.Element, error)
+	// ListRules returns a list of the rules in a chain. (The table is the one the API was created for.)
+	ListRules(ctx context.Context, chain string) ([]*knftables.Rule, error)
 }
 
 // NftImpl is the real implementation of NftablesAPI using the actual knftables backend.
 type NftImpl struct {
-	nft knftables.Interface
+	nft    knftables.Interface
+	family knftables.Family
+	table  string
 }
 
 // Dump is used for logging purposes.
@@ -48,7 +56,7 @@ func NewNftImpl(family knftables.Family, table string) (*NftImpl, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &NftImpl{nft: nft}, nil
+	return &NftImpl{nft: nft, family: family, table: table}, nil
 }
 
 // NewTransaction starts a new transaction using the real knftables backend.
@@ -66,6 +74,52 @@ func (r *NftImpl) ListElements(ctx context.Context, objectType, name string) ([]
 	return r.nft.ListElements(ctx, objectType, name)
 }
 
+// ListRules returns a list of the rules in a chain using the real knftables interface.
+// knftables only returns the handle and the comment of the rules, so their bodies are filled in
+// from the text output of nft, as rendered by nft.
+func (r *NftImpl) ListRules(ctx context.Context, chain string) ([]*knftables.Rule, error) {
+	rules, err := r.nft.ListRules(ctx, chain)
+	if err != nil || len(rules) == 0 {
+		return rules, err
+	}
+	args := []string{"--handle", "list", "table", string(r.family), r.table}
+	if chain != "" {
+		args = []string{"--handle", "list", "chain", string(r.family), r.table, chain}
+	}
+	out, err := exec.CommandContext(ctx, "nft", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run nft: %w", err)
+	}
+	bodies := ParseRuleBodies(string(out))
+	for _, rule := range rules {
+		if rule.Handle == nil {
+			continue
+		}
+		body := bodies[*rule.Handle]
+		if rule.Comment != nil {
+			body = strings.TrimSuffix(body, fmt.Sprintf(" comment %q", *rule.Comment))
+		}
+		rule.Rule = body
+	}
+	return rules, nil
+}
+
+// ParseRuleBodies returns the bodies of the rules listed by "nft --handle list", by rule handle.
+func ParseRuleBodies(out string) map[int]string {
+	bodies := make(map[int]string)
+	for _, line := range strings.Split(out, "\n") {
+		body, handle, found := strings.Cut(strings.TrimSpace(line), " # handle ")
+		// Tables and chains are listed with their handles too, after their opening brace.
+		if !found || strings.HasSuffix(body, "{") {
+			continue
+		}
+		if h, err := strconv.Atoi(handle); err == nil {
+			bodies[h] = body
+		}
+	}
+	return bodies
+}
+
 // MockNftables is a mock implementation of NftablesAPI for use in unit tests.
 // It uses knftables.Fake to simulate nftables behavior without making changes to the system.
 type MockNftables struct {
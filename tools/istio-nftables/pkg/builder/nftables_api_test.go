@@ -0,0 +1,36 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestParseRuleBodies(t *testing.T) {
+	out := `table inet istio-ambient-nat { # handle 12
+	chain postrouting { # handle 1
+		type nat hook postrouting priority srcnat; policy accept;
+		meta l4proto tcp meta skuid 1000 ip daddr @istio-inpod-probes-v4 counter packets 3 bytes 180 snat ip to 169.254.7.127 # handle 4
+		meta l4proto tcp meta skuid 1000 ip6 daddr @istio-inpod-probes-v6 counter packets 0 bytes 0 snat ip6 to fd16:9254:7127:1337:ffff:ffff:ffff:ffff comment "probes" # handle 5
+	}
+}
+`
+	assert.Equal(t, ParseRuleBodies(out), map[int]string{
+		4: "meta l4proto tcp meta skuid 1000 ip daddr @istio-inpod-probes-v4 counter packets 3 bytes 180 snat ip to 169.254.7.127",
+		5: `meta l4proto tcp meta skuid 1000 ip6 daddr @istio-inpod-probes-v6 counter packets 0 bytes 0 snat ip6 to fd16:9254:7127:1337:ffff:ffff:ffff:ffff comment "probes"`,
+	})
+}
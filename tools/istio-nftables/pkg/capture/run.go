@@ -25,6 +25,7 @@ import (
 
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/tools/common/config"
+	"istio.io/istio/tools/common/explain"
 	"istio.io/istio/tools/istio-nftables/pkg/builder"
 	"istio.io/istio/tools/istio-nftables/pkg/constants"
 )
@@ -266,20 +267,37 @@ func (cfg *NftablesConfigurator) shortCircuitExcludeInterfaces() {
 // It handles exclusion and inclusion logic for inbound and outbound traffic, DNS redirection,
 // owner-based filtering, TPROXY mark handling, and finally applies all the rules.
 func (cfg *NftablesConfigurator) Run() (*knftables.Transaction, error) {
+	cfg.logConfig()
+	if err := cfg.buildRules(); err != nil {
+		return nil, err
+	}
+	return cfg.executeCommands()
+}
+
+// Explain builds the rules for the configuration without applying them, and traces the given packet through them.
+func (cfg *NftablesConfigurator) Explain(packet explain.Packet) (*explain.Trace, error) {
+	if err := cfg.buildRules(); err != nil {
+		return nil, err
+	}
+	return cfg.ruleBuilder.Explain(packet), nil
+}
+
+// buildRules populates the rule builder with all the rules for the configuration.
+func (cfg *NftablesConfigurator) buildRules() error {
 	// Since OUTBOUND_IP_RANGES_EXCLUDE could carry ipv4 and ipv6 ranges
 	// need to split them in different arrays one for ipv4 and one for ipv6
 	// in order to not to fail
 	ipv4RangesExclude, ipv6RangesExclude, err := config.SeparateV4V6(cfg.cfg.OutboundIPRangesExclude)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if ipv4RangesExclude.IsWildcard {
-		return nil, fmt.Errorf("invalid value for OUTBOUND_IP_RANGES_EXCLUDE")
+		return fmt.Errorf("invalid value for OUTBOUND_IP_RANGES_EXCLUDE")
 	}
 
 	ipv4RangesInclude, ipv6RangesInclude, err := config.SeparateV4V6(cfg.cfg.OutboundIPRangesInclude)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	redirectDNS := cfg.cfg.RedirectDNS
@@ -289,8 +307,6 @@ func (cfg *NftablesConfigurator) Run() (*knftables.Transaction, error) {
 		redirectDNS = false
 	}
 
-	cfg.logConfig()
-
 	// Add rules to skip specific interfaces from redirection.
 	cfg.shortCircuitExcludeInterfaces()
 	cfg.shortCircuitKubeInternalInterface()
@@ -601,7 +617,7 @@ func (cfg *NftablesConfigurator) Run() (*knftables.Transaction, error) {
 			"return")
 	}
 
-	return cfg.executeCommands()
+	return nil
 }
 
 // SetupDNSRedir is a helper function for supporting DNS redirection use-cases.
@@ -19,6 +19,7 @@ import (
 
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/tools/common/config"
+	"istio.io/istio/tools/common/explain"
 	"istio.io/istio/tools/common/tproxy"
 	"istio.io/istio/tools/istio-nftables/pkg/builder"
 	"istio.io/istio/tools/istio-nftables/pkg/capture"
@@ -47,3 +48,12 @@ func ProgramNftables(cfg *config.Config) error {
 	}
 	return nil
 }
+
+// ExplainNftables traces the packet through the nftables rules for the configuration, without applying them.
+func ExplainNftables(cfg *config.Config, packet explain.Packet) (*explain.Trace, error) {
+	nftConfigurator, err := capture.NewNftablesConfigurator(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	return nftConfigurator.Explain(packet)
+}